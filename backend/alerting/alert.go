@@ -0,0 +1,149 @@
+// Package alerting routes alerts from every producer in the backend (request
+// metrics, health checks, the performance monitor, the security logger and API
+// key usage alerts) through one pipeline that deduplicates, groups, silences
+// and escalates them before they reach the configured notifiers.
+package alerting
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity ranks alerts; routes send an alert to the notifiers whose minimum
+// severity it reaches
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+// String returns the lowercase name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the severity by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name with ParseSeverity
+func (s *Severity) UnmarshalText(text []byte) error {
+	*s = ParseSeverity(string(text))
+	return nil
+}
+
+// ParseSeverity maps the severity names used across the backend onto a
+// Severity: low and medium are the security logger's names for info and
+// warning, high its name for error. Unknown names are warnings.
+func ParseSeverity(name string) Severity {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "info", "low":
+		return SeverityInfo
+	case "error", "high":
+		return SeverityError
+	case "critical":
+		return SeverityCritical
+	default:
+		return SeverityWarning
+	}
+}
+
+// Alert is one occurrence of a problem reported by a producer. Alerts with the
+// same source, name and labels describe the same incident.
+type Alert struct {
+	Source    string            `json:"source"` // producer, e.g. "api", "health_check", "security"
+	Name      string            `json:"name"`   // stable identifier within the source, e.g. "high_memory"
+	Severity  Severity          `json:"severity"`
+	Summary   string            `json:"summary"`
+	Message   string            `json:"message,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     float64           `json:"value,omitempty"`
+	Threshold float64           `json:"threshold,omitempty"`
+	FiredAt   time.Time         `json:"fired_at"`
+
+	// Escalated is set when the router raised Severity because the incident
+	// kept firing
+	Escalated bool `json:"escalated,omitempty"`
+	// Occurrences counts the times the incident fired since it was last sent
+	Occurrences int `json:"occurrences"`
+}
+
+// Fingerprint identifies the incident an alert belongs to
+func (a *Alert) Fingerprint() string {
+	return fingerprint(a.Source, a.Name, a.Labels)
+}
+
+// label returns a label value, with source, name and severity readable as
+// labels too
+func (a *Alert) label(name string) string {
+	switch name {
+	case "source":
+		return a.Source
+	case "name":
+		return a.Name
+	case "severity":
+		return a.Severity.String()
+	}
+	return a.Labels[name]
+}
+
+func fingerprint(source, name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(source)
+	b.WriteByte('/')
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteByte('|')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
+
+// Notification is a batch of alerts from one group sent to a notifier
+type Notification struct {
+	GroupKey string    `json:"group_key"`
+	Severity Severity  `json:"severity"` // highest severity in the batch
+	Alerts   []*Alert  `json:"alerts"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// Title summarizes the notification in one line, for email subjects and chat
+func (n *Notification) Title() string {
+	if len(n.Alerts) == 1 {
+		return "[" + strings.ToUpper(n.Severity.String()) + "] " + n.Alerts[0].Summary
+	}
+	return "[" + strings.ToUpper(n.Severity.String()) + "] " + n.Alerts[0].Summary +
+		" and " + strconv.Itoa(len(n.Alerts)-1) + " more"
+}
+
+// Notifier delivers notifications to a channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, notification *Notification) error
+}
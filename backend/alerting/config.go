@@ -0,0 +1,97 @@
+package alerting
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigFromEnv builds the router configuration from the environment:
+//
+//	ALERT_GROUP_WAIT, ALERT_REPEAT_INTERVAL, ALERT_ESCALATE_AFTER  durations (30s, 1h, 15m by default)
+//	ALERT_WEBHOOK_URL, SLACK_WEBHOOK                                 webhooks, for warning and above
+//	SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD,
+//	ALERT_FROM_EMAIL, ALERT_TO_EMAILS                                email, for error and above
+//	ALERT_FILE                                                       JSON lines file, for every alert
+//	ALERT_WEBHOOK_MIN_SEVERITY, ALERT_EMAIL_MIN_SEVERITY             override the severities above
+//
+// Every alert is also logged.
+func ConfigFromEnv() Config {
+	config := Config{
+		GroupWait:      envDuration("ALERT_GROUP_WAIT", 30*time.Second),
+		RepeatInterval: envDuration("ALERT_REPEAT_INTERVAL", DefaultRepeatInterval),
+		EscalateAfter:  envDuration("ALERT_ESCALATE_AFTER", 15*time.Minute),
+		Routes: []Route{
+			{MinSeverity: SeverityInfo, Notifiers: []Notifier{LogNotifier{}}},
+		},
+	}
+
+	if path := os.Getenv("ALERT_FILE"); path != "" {
+		config.Routes = append(config.Routes, Route{
+			MinSeverity: SeverityInfo,
+			Notifiers:   []Notifier{NewFileNotifier(path)},
+		})
+	}
+
+	var webhooks []Notifier
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		webhooks = append(webhooks, NewWebhookNotifier(url))
+	}
+	if url := os.Getenv("SLACK_WEBHOOK"); url != "" {
+		webhooks = append(webhooks, NewSlackNotifier(url))
+	}
+	if len(webhooks) > 0 {
+		config.Routes = append(config.Routes, Route{
+			MinSeverity: envSeverity("ALERT_WEBHOOK_MIN_SEVERITY", SeverityWarning),
+			Notifiers:   webhooks,
+		})
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			port = 587
+		}
+		var to []string
+		for _, address := range strings.Split(os.Getenv("ALERT_TO_EMAILS"), ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				to = append(to, address)
+			}
+		}
+		config.Routes = append(config.Routes, Route{
+			MinSeverity: envSeverity("ALERT_EMAIL_MIN_SEVERITY", SeverityError),
+			Notifiers: []Notifier{&SMTPNotifier{
+				Host:     host,
+				Port:     port,
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+				From:     os.Getenv("ALERT_FROM_EMAIL"),
+				To:       to,
+			}},
+		})
+	}
+
+	return config
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid %s %q", key, value)
+		return fallback
+	}
+	return d
+}
+
+func envSeverity(key string, fallback Severity) Severity {
+	if value := os.Getenv(key); value != "" {
+		return ParseSeverity(value)
+	}
+	return fallback
+}
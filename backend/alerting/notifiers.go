@@ -0,0 +1,186 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogNotifier writes notifications to the standard logger
+type LogNotifier struct{}
+
+// Name implements Notifier
+func (LogNotifier) Name() string { return "log" }
+
+// Notify implements Notifier
+func (LogNotifier) Notify(ctx context.Context, notification *Notification) error {
+	for _, alert := range notification.Alerts {
+		log.Printf("ALERT [%s] %s/%s: %s (occurrences: %d, labels: %v)",
+			strings.ToUpper(alert.Severity.String()), alert.Source, alert.Name,
+			alert.Summary, alert.Occurrences, alert.Labels)
+	}
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON. With Slack set the body is a
+// Slack incoming-webhook message instead.
+type WebhookNotifier struct {
+	URL    string
+	Slack  bool
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting the notification JSON to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: DefaultNotifyTimeout}}
+}
+
+// NewSlackNotifier creates a notifier posting to a Slack incoming webhook
+func NewSlackNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Slack: true, Client: &http.Client{Timeout: DefaultNotifyTimeout}}
+}
+
+// Name implements Notifier
+func (n *WebhookNotifier) Name() string {
+	if n.Slack {
+		return "slack"
+	}
+	return "webhook"
+}
+
+// Notify implements Notifier
+func (n *WebhookNotifier) Notify(ctx context.Context, notification *Notification) error {
+	var payload interface{} = notification
+	if n.Slack {
+		payload = map[string]interface{}{
+			"text": "*" + notification.Title() + "*\n" + formatAlerts(notification),
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier emails notifications
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Name implements Notifier
+func (n *SMTPNotifier) Name() string { return "smtp" }
+
+// Notify implements Notifier. net/smtp has no context support, so the
+// context's deadline is not enforced.
+func (n *SMTPNotifier) Notify(ctx context.Context, notification *Notification) error {
+	if len(n.To) == 0 {
+		return fmt.Errorf("no email recipients configured")
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n"+
+		"This is an automated alert from the Nutrition Platform monitoring system.\r\n",
+		n.From, strings.Join(n.To, ", "), notification.Title(),
+		strings.ReplaceAll(formatAlerts(notification), "\n", "\r\n"))
+
+	addr := fmt.Sprintf("%s:%d", n.Host, n.Port)
+	if err := smtp.SendMail(addr, auth, n.From, n.To, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send alert email: %w", err)
+	}
+	return nil
+}
+
+// FileNotifier appends each notification to a file as one JSON line. It is
+// meant for tests and for environments without a paging service.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier creates a notifier appending to path
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{Path: path}
+}
+
+// Name implements Notifier
+func (n *FileNotifier) Name() string { return "file" }
+
+// Notify implements Notifier
+func (n *FileNotifier) Notify(ctx context.Context, notification *Notification) error {
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	file, err := os.OpenFile(n.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open alert file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write alert file: %w", err)
+	}
+	return nil
+}
+
+// formatAlerts renders a notification's alerts as plain text
+func formatAlerts(notification *Notification) string {
+	var b strings.Builder
+	for _, alert := range notification.Alerts {
+		fmt.Fprintf(&b, "\n%s (%s/%s)\n", alert.Summary, alert.Source, alert.Name)
+		fmt.Fprintf(&b, "Severity: %s", alert.Severity)
+		if alert.Escalated {
+			b.WriteString(" (escalated)")
+		}
+		b.WriteString("\n")
+		if alert.Message != "" {
+			fmt.Fprintf(&b, "%s\n", alert.Message)
+		}
+		if alert.Threshold != 0 {
+			fmt.Fprintf(&b, "Value: %.2f (threshold %.2f)\n", alert.Value, alert.Threshold)
+		}
+		for name, value := range alert.Labels {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+		fmt.Fprintf(&b, "Occurrences: %d\nTime: %s\n", alert.Occurrences, alert.FiredAt.Format(time.RFC3339))
+	}
+	return b.String()
+}
@@ -0,0 +1,437 @@
+package alerting
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRepeatInterval is how long an incident stays quiet after it was sent
+	DefaultRepeatInterval = time.Hour
+	// DefaultNotifyTimeout bounds each notifier call
+	DefaultNotifyTimeout = 10 * time.Second
+	// maxIncidents bounds the incidents tracked for deduplication
+	maxIncidents = 10000
+)
+
+var (
+	// ErrInvalidSilence is wrapped by errors caused by a silence request
+	ErrInvalidSilence = errors.New("invalid silence")
+	// ErrSilenceNotFound is returned when removing an unknown silence
+	ErrSilenceNotFound = errors.New("silence not found")
+)
+
+// Route sends alerts of at least MinSeverity to its notifiers. Routes with
+// increasing minimums escalate an incident to more channels as it gets worse.
+type Route struct {
+	MinSeverity Severity
+	Notifiers   []Notifier
+}
+
+// Config configures a Router. Zero durations take the documented defaults.
+type Config struct {
+	// GroupBy lists the labels ("source" and "name" included) whose values
+	// put alerts in the same notification; defaults to source and name
+	GroupBy []string
+	// GroupWait is how long the first alert of a group waits for others
+	// before the group is sent; zero sends every alert on its own
+	GroupWait time.Duration
+	// RepeatInterval suppresses an incident after it was sent unless its
+	// severity rises; defaults to DefaultRepeatInterval
+	RepeatInterval time.Duration
+	// ResolveTimeout is how long an incident must stay quiet before firing
+	// again counts as a new incident; defaults to RepeatInterval
+	ResolveTimeout time.Duration
+	// EscalateAfter raises an incident's severity one level each time it
+	// keeps firing this long; zero disables escalation
+	EscalateAfter time.Duration
+	// NotifyTimeout bounds each notifier call; defaults to DefaultNotifyTimeout
+	NotifyTimeout time.Duration
+	Routes        []Route
+}
+
+// Silence mutes alerts whose labels match every matcher between StartsAt and
+// EndsAt. Matchers may also name source, name and severity.
+type Silence struct {
+	ID        string            `json:"id"`
+	Matchers  map[string]string `json:"matchers"`
+	StartsAt  time.Time         `json:"starts_at"`
+	EndsAt    time.Time         `json:"ends_at"`
+	CreatedBy string            `json:"created_by,omitempty"`
+	Comment   string            `json:"comment,omitempty"`
+}
+
+// Matches reports whether the silence applies to an alert, ignoring its schedule
+func (s *Silence) Matches(alert *Alert) bool {
+	for name, value := range s.Matchers {
+		if alert.label(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Active reports whether the silence is in effect at t
+func (s *Silence) Active(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// Stats counts what the router did with the alerts it received
+type Stats struct {
+	Received      int64 `json:"received"`
+	Silenced      int64 `json:"silenced"`
+	Deduplicated  int64 `json:"deduplicated"`
+	Escalated     int64 `json:"escalated"`
+	Notifications int64 `json:"notifications"`
+	Failures      int64 `json:"failures"`
+}
+
+// incident tracks the alerts sharing a fingerprint
+type incident struct {
+	severity      Severity // reported or escalated severity
+	sentSeverity  Severity
+	escalated     bool
+	firstSeen     time.Time
+	lastSeen      time.Time
+	lastSent      time.Time
+	lastEscalated time.Time
+	occurrences   int
+}
+
+// group collects alerts waiting to be sent together
+type group struct {
+	alerts []*Alert
+	timer  *time.Timer
+}
+
+// Router deduplicates, groups, silences and escalates alerts and sends them
+// to the notifiers of the routes they reach
+type Router struct {
+	config    Config
+	mu        sync.Mutex
+	incidents map[string]*incident
+	groups    map[string]*group
+	silences  map[string]*Silence
+	stats     Stats
+	closed    bool
+	inflight  sync.WaitGroup
+}
+
+// NewRouter creates a router
+func NewRouter(config Config) *Router {
+	if len(config.GroupBy) == 0 {
+		config.GroupBy = []string{"source", "name"}
+	}
+	if config.RepeatInterval <= 0 {
+		config.RepeatInterval = DefaultRepeatInterval
+	}
+	if config.ResolveTimeout <= 0 {
+		config.ResolveTimeout = config.RepeatInterval
+	}
+	if config.NotifyTimeout <= 0 {
+		config.NotifyTimeout = DefaultNotifyTimeout
+	}
+
+	return &Router{
+		config:    config,
+		incidents: make(map[string]*incident),
+		groups:    make(map[string]*group),
+		silences:  make(map[string]*Silence),
+	}
+}
+
+// Fire reports an alert. It returns once the alert is queued; notifiers run
+// in the background.
+func (r *Router) Fire(alert Alert) {
+	now := time.Now()
+	if alert.FiredAt.IsZero() {
+		alert.FiredAt = now
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.stats.Received++
+
+	for _, silence := range r.silences {
+		if silence.Active(now) && silence.Matches(&alert) {
+			r.stats.Silenced++
+			return
+		}
+	}
+
+	key := alert.Fingerprint()
+	inc, ok := r.incidents[key]
+	if !ok || now.Sub(inc.lastSeen) > r.config.ResolveTimeout {
+		if !ok && len(r.incidents) >= maxIncidents {
+			r.pruneIncidents(now)
+		}
+		inc = &incident{severity: alert.Severity, firstSeen: now}
+		r.incidents[key] = inc
+	}
+	inc.lastSeen = now
+	inc.occurrences++
+	if alert.Severity > inc.severity {
+		inc.severity = alert.Severity
+	}
+
+	if r.config.EscalateAfter > 0 && inc.severity < SeverityCritical {
+		since := inc.lastEscalated
+		if since.IsZero() {
+			since = inc.firstSeen
+		}
+		if now.Sub(since) >= r.config.EscalateAfter {
+			inc.severity++
+			inc.escalated = true
+			inc.lastEscalated = now
+			r.stats.Escalated++
+		}
+	}
+
+	if !inc.lastSent.IsZero() && now.Sub(inc.lastSent) < r.config.RepeatInterval && inc.severity <= inc.sentSeverity {
+		r.stats.Deduplicated++
+		return
+	}
+
+	alert.Severity = inc.severity
+	alert.Escalated = inc.escalated
+	alert.Occurrences = inc.occurrences
+	inc.lastSent = now
+	inc.sentSeverity = inc.severity
+	inc.occurrences = 0
+
+	r.enqueue(&alert)
+}
+
+// Resolve forgets an incident, so the next alert with this source, name and
+// labels is sent right away
+func (r *Router) Resolve(source, name string, labels map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.incidents, fingerprint(source, name, labels))
+}
+
+// AddSilence starts muting matching alerts. StartsAt defaults to now.
+func (r *Router) AddSilence(silence Silence) (*Silence, error) {
+	if len(silence.Matchers) == 0 {
+		return nil, fmt.Errorf("%w: at least one matcher is required", ErrInvalidSilence)
+	}
+	now := time.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if !silence.EndsAt.After(silence.StartsAt) || !silence.EndsAt.After(now) {
+		return nil, fmt.Errorf("%w: ends_at must be in the future and after starts_at", ErrInvalidSilence)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate silence id: %w", err)
+	}
+	silence.ID = hex.EncodeToString(id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := silence
+	r.silences[silence.ID] = &stored
+	return &silence, nil
+}
+
+// RemoveSilence ends a silence early
+func (r *Router) RemoveSilence(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.silences[id]; !ok {
+		return ErrSilenceNotFound
+	}
+	delete(r.silences, id)
+	return nil
+}
+
+// Silences returns the silences that have not ended, soonest to end first
+func (r *Router) Silences() []*Silence {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	silences := make([]*Silence, 0, len(r.silences))
+	for id, silence := range r.silences {
+		if !now.Before(silence.EndsAt) {
+			delete(r.silences, id)
+			continue
+		}
+		copied := *silence
+		silences = append(silences, &copied)
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].EndsAt.Before(silences[j].EndsAt) })
+	return silences
+}
+
+// Stats returns the router's counters
+func (r *Router) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Flush sends the groups still waiting and waits for every notifier call.
+// It is meant for shutdown and tests.
+func (r *Router) Flush() {
+	r.mu.Lock()
+	for key, g := range r.groups {
+		if g.timer.Stop() {
+			delete(r.groups, key)
+			r.send(key, g.alerts)
+			r.inflight.Done()
+		}
+	}
+	r.mu.Unlock()
+
+	r.inflight.Wait()
+}
+
+// Close flushes the router; alerts fired afterwards are dropped
+func (r *Router) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.Flush()
+}
+
+// enqueue adds an alert to its group, sending it right away without a group
+// wait. Must be called with r.mu held.
+func (r *Router) enqueue(alert *Alert) {
+	key := r.groupKey(alert)
+	if r.config.GroupWait <= 0 {
+		r.send(key, []*Alert{alert})
+		return
+	}
+
+	g, ok := r.groups[key]
+	if !ok {
+		g = &group{}
+		r.groups[key] = g
+		r.inflight.Add(1)
+		g.timer = time.AfterFunc(r.config.GroupWait, func() {
+			defer r.inflight.Done()
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.groups[key] == g {
+				delete(r.groups, key)
+				r.send(key, g.alerts)
+			}
+		})
+	}
+	g.alerts = append(g.alerts, alert)
+}
+
+// send dispatches a notification in the background. Must be called with r.mu held.
+func (r *Router) send(key string, alerts []*Alert) {
+	notification := &Notification{GroupKey: key, Alerts: alerts, SentAt: time.Now()}
+	for _, alert := range alerts {
+		if alert.Severity > notification.Severity {
+			notification.Severity = alert.Severity
+		}
+	}
+
+	notifiers := r.notifiersFor(notification.Severity)
+	if len(notifiers) == 0 {
+		return
+	}
+	r.stats.Notifications++
+
+	r.inflight.Add(1)
+	go func() {
+		defer r.inflight.Done()
+		for _, notifier := range notifiers {
+			ctx, cancel := context.WithTimeout(context.Background(), r.config.NotifyTimeout)
+			err := notifier.Notify(ctx, notification)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to send alert notification %s via %s: %v", key, notifier.Name(), err)
+				r.mu.Lock()
+				r.stats.Failures++
+				r.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// notifiersFor returns the notifiers of every route a severity reaches, each once
+func (r *Router) notifiersFor(severity Severity) []Notifier {
+	var notifiers []Notifier
+	seen := make(map[Notifier]bool)
+	for _, route := range r.config.Routes {
+		if severity < route.MinSeverity {
+			continue
+		}
+		for _, notifier := range route.Notifiers {
+			if !seen[notifier] {
+				seen[notifier] = true
+				notifiers = append(notifiers, notifier)
+			}
+		}
+	}
+	return notifiers
+}
+
+func (r *Router) groupKey(alert *Alert) string {
+	key := ""
+	for i, name := range r.config.GroupBy {
+		if i > 0 {
+			key += "|"
+		}
+		key += name + "=" + alert.label(name)
+	}
+	return key
+}
+
+// pruneIncidents drops incidents that have resolved. Must be called with r.mu held.
+func (r *Router) pruneIncidents(now time.Time) {
+	for key, inc := range r.incidents {
+		if now.Sub(inc.lastSeen) > r.config.ResolveTimeout {
+			delete(r.incidents, key)
+		}
+	}
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultRouter = NewRouter(Config{
+		Routes: []Route{{MinSeverity: SeverityInfo, Notifiers: []Notifier{LogNotifier{}}}},
+	})
+)
+
+// Default returns the process-wide router every producer fires into. Until
+// SetDefault is called it only logs.
+func Default() *Router {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultRouter
+}
+
+// SetDefault replaces the process-wide router
+func SetDefault(router *Router) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultRouter = router
+}
+
+// Fire reports an alert to the default router
+func Fire(alert Alert) {
+	Default().Fire(alert)
+}
+
+// Resolve forgets an incident on the default router
+func Resolve(source, name string, labels map[string]string) {
+	Default().Resolve(source, name, labels)
+}
@@ -1,14 +1,12 @@
-// alerts.go - Alerting system for critical errors and performance issues
+// alerts.go - Alert conditions for critical errors and performance issues
 package main
 
 import (
 	"fmt"
-	"net/smtp"
-	"os"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
+
+	"nutrition-platform/alerting"
 )
 
 // AlertLevel represents different alert severity levels
@@ -37,6 +35,20 @@ func (l AlertLevel) String() string {
 	}
 }
 
+// Severity maps the level onto the alerting router's severities
+func (l AlertLevel) Severity() alerting.Severity {
+	switch l {
+	case AlertInfo:
+		return alerting.SeverityInfo
+	case AlertError:
+		return alerting.SeverityError
+	case AlertCritical:
+		return alerting.SeverityCritical
+	default:
+		return alerting.SeverityWarning
+	}
+}
+
 // Alert represents an alert configuration
 type Alert struct {
 	ID          string            `json:"id"`
@@ -47,21 +59,20 @@ type Alert struct {
 	Threshold   float64           `json:"threshold"`
 	TimeWindow  time.Duration     `json:"time_window"`
 	Cooldown    time.Duration     `json:"cooldown"`
-	Channels    []string          `json:"channels"`
 	Enabled     bool              `json:"enabled"`
 	LastFired   *time.Time        `json:"last_fired,omitempty"`
 	Labels      map[string]string `json:"labels,omitempty"`
 }
 
-// AlertManager manages alerts and notifications
+// AlertManager evaluates the application's alert conditions and fires the
+// alerts that trip through the alerting router
 type AlertManager struct {
 	alerts       map[string]*Alert
 	alertHistory []AlertEvent
-	mu           sync.RWMutex
-	// Alert channels
-	emailConfig  *EmailConfig
-	slackWebhook string
-	webhookURL   string
+	// lastFired holds when each incident, an alert and its labels, last
+	// fired, keyed by the incident's fingerprint
+	lastFired map[string]time.Time
+	mu        sync.RWMutex
 }
 
 // AlertEvent represents a fired alert event
@@ -76,24 +87,12 @@ type AlertEvent struct {
 	Labels    map[string]string `json:"labels,omitempty"`
 }
 
-// EmailConfig holds email configuration
-type EmailConfig struct {
-	SMTPHost    string
-	SMTPPort    int
-	Username    string
-	Password    string
-	FromAddress string
-	ToAddresses []string
-}
-
 // NewAlertManager creates a new alert manager
 func NewAlertManager() *AlertManager {
 	am := &AlertManager{
 		alerts:       make(map[string]*Alert),
 		alertHistory: make([]AlertEvent, 0),
-		emailConfig:  loadEmailConfig(),
-		slackWebhook: getEnvOrDefault("SLACK_WEBHOOK", ""),
-		webhookURL:   getEnvOrDefault("ALERT_WEBHOOK_URL", ""),
+		lastFired:    make(map[string]time.Time),
 	}
 
 	am.loadDefaultAlerts()
@@ -112,7 +111,6 @@ func (am *AlertManager) loadDefaultAlerts() {
 			Threshold:   5.0, // 5%
 			TimeWindow:  5 * time.Minute,
 			Cooldown:    10 * time.Minute,
-			Channels:    []string{"email", "log"},
 			Enabled:     true,
 			Labels:      map[string]string{"component": "api"},
 		},
@@ -125,7 +123,6 @@ func (am *AlertManager) loadDefaultAlerts() {
 			Threshold:   2.0,
 			TimeWindow:  1 * time.Minute,
 			Cooldown:    5 * time.Minute,
-			Channels:    []string{"email", "slack"},
 			Enabled:     true,
 			Labels:      map[string]string{"component": "resilience"},
 		},
@@ -138,7 +135,6 @@ func (am *AlertManager) loadDefaultAlerts() {
 			Threshold:   2000.0, // 2 seconds
 			TimeWindow:  2 * time.Minute,
 			Cooldown:    15 * time.Minute,
-			Channels:    []string{"log"},
 			Enabled:     true,
 			Labels:      map[string]string{"component": "performance"},
 		},
@@ -151,7 +147,6 @@ func (am *AlertManager) loadDefaultAlerts() {
 			Threshold:   3.0,
 			TimeWindow:  5 * time.Minute,
 			Cooldown:    10 * time.Minute,
-			Channels:    []string{"email", "slack"},
 			Enabled:     true,
 			Labels:      map[string]string{"component": "database"},
 		},
@@ -164,7 +159,6 @@ func (am *AlertManager) loadDefaultAlerts() {
 			Threshold:   85.0, // 85%
 			TimeWindow:  1 * time.Minute,
 			Cooldown:    30 * time.Minute,
-			Channels:    []string{"email"},
 			Enabled:     true,
 			Labels:      map[string]string{"component": "system"},
 		},
@@ -182,7 +176,9 @@ func (am *AlertManager) AddAlert(alert *Alert) {
 	am.alerts[alert.ID] = alert
 }
 
-// CheckAlert evaluates an alert condition
+// CheckAlert evaluates an alert condition. Labels identify the incident
+// (e.g. the service whose circuit opened); each incident has its own
+// cooldown, and alerts with the same labels are deduplicated by the router.
 func (am *AlertManager) CheckAlert(alertID string, currentValue float64, labels map[string]string) {
 	am.mu.RLock()
 	alert, exists := am.alerts[alertID]
@@ -192,11 +188,6 @@ func (am *AlertManager) CheckAlert(alertID string, currentValue float64, labels
 		return
 	}
 
-	// Check cooldown period
-	if alert.LastFired != nil && time.Since(*alert.LastFired) < alert.Cooldown {
-		return
-	}
-
 	// Evaluate condition
 	shouldFire := am.evaluateCondition(alert.Condition, currentValue, alert.Threshold)
 
@@ -234,193 +225,48 @@ func (am *AlertManager) evaluateCondition(condition string, value, threshold flo
 	}
 }
 
-// fireAlert fires an alert through configured channels
+// fireAlert records an alert event and routes it through the alerting
+// router, which decides who gets notified. An incident that fired within
+// the alert's cooldown is skipped.
 func (am *AlertManager) fireAlert(alert *Alert, event *AlertEvent) {
-	// Update last fired time
-	now := time.Now()
-	alert.LastFired = &now
-
-	// Add to history
-	am.mu.Lock()
-	am.alertHistory = append(am.alertHistory, *event)
-	// Keep only last 1000 events
-	if len(am.alertHistory) > 1000 {
-		am.alertHistory = am.alertHistory[len(am.alertHistory)-1000:]
+	labels := make(map[string]string, len(alert.Labels)+len(event.Labels))
+	for k, v := range alert.Labels {
+		labels[k] = v
 	}
-	am.mu.Unlock()
-
-	// Log the alert
-	Logger.Warn("Alert fired", map[string]interface{}{
-		"alert_id":   alert.ID,
-		"alert_name": alert.Name,
-		"level":      alert.Level.String(),
-		"value":      event.Value,
-		"threshold":  event.Threshold,
-		"message":    event.Message,
-	})
-
-	// Send through channels
-	for _, channel := range alert.Channels {
-		switch channel {
-		case "email":
-			am.sendEmailAlert(alert, event)
-		case "slack":
-			am.sendSlackAlert(alert, event)
-		case "webhook":
-			am.sendWebhookAlert(alert, event)
-		case "log":
-			// Already logged above
-		}
+	for k, v := range event.Labels {
+		labels[k] = v
 	}
-}
-
-// sendEmailAlert sends an email alert
-func (am *AlertManager) sendEmailAlert(alert *Alert, event *AlertEvent) {
-	if am.emailConfig == nil {
-		Logger.Warn("Email alert not sent - email config not available")
-		return
-	}
-
-	subject := fmt.Sprintf("[%s] %s", alert.Level.String(), alert.Name)
-	body := am.formatAlertMessage(alert, event)
-
-	err := am.sendEmail(subject, body)
-	if err != nil {
-		Logger.Error("Failed to send email alert", err, map[string]interface{}{
-			"alert_id": alert.ID,
-			"subject":  subject,
-		})
+	routed := alerting.Alert{
+		Source:    "api",
+		Name:      alert.ID,
+		Severity:  alert.Level.Severity(),
+		Summary:   alert.Name,
+		Message:   event.Message,
+		Labels:    labels,
+		Value:     event.Value,
+		Threshold: event.Threshold,
+		FiredAt:   event.Timestamp,
 	}
-}
-
-// sendSlackAlert sends a Slack alert
-func (am *AlertManager) sendSlackAlert(alert *Alert, event *AlertEvent) {
-	if am.slackWebhook == "" {
-		Logger.Warn("Slack alert not sent - webhook not configured")
-		return
-	}
-
-	payload := map[string]interface{}{
-		"text": fmt.Sprintf("*%s Alert*: %s", alert.Level.String(), alert.Name),
-		"attachments": []map[string]interface{}{
-			{
-				"color": am.getSlackColor(alert.Level),
-				"fields": []map[string]interface{}{
-					{
-						"title": "Description",
-						"value": alert.Description,
-						"short": false,
-					},
-					{
-						"title": "Value",
-						"value": fmt.Sprintf("%.2f", event.Value),
-						"short": true,
-					},
-					{
-						"title": "Threshold",
-						"value": fmt.Sprintf("%.2f", event.Threshold),
-						"short": true,
-					},
-					{
-						"title": "Time",
-						"value": event.Timestamp.Format(time.RFC3339),
-						"short": true,
-					},
-				},
-			},
-		},
-	}
-
-	err := am.sendSlackMessage(payload)
-	if err != nil {
-		Logger.Error("Failed to send Slack alert", err, map[string]interface{}{
-			"alert_id": alert.ID,
-		})
-	}
-}
+	incident := routed.Fingerprint()
 
-// sendWebhookAlert sends a webhook alert
-func (am *AlertManager) sendWebhookAlert(alert *Alert, event *AlertEvent) {
-	if am.webhookURL == "" {
-		Logger.Warn("Webhook alert not sent - URL not configured")
+	am.mu.Lock()
+	if last, ok := am.lastFired[incident]; ok && event.Timestamp.Sub(last) < alert.Cooldown {
+		am.mu.Unlock()
 		return
 	}
+	am.lastFired[incident] = event.Timestamp
+	firedAt := event.Timestamp
+	alert.LastFired = &firedAt
 
-	payload := map[string]interface{}{
-		"alert_id":   alert.ID,
-		"alert_name": alert.Name,
-		"level":      alert.Level.String(),
-		"message":    event.Message,
-		"value":      event.Value,
-		"threshold":  event.Threshold,
-		"timestamp":  event.Timestamp,
-		"labels":     event.Labels,
-	}
-
-	err := am.sendWebhookMessage(payload)
-	if err != nil {
-		Logger.Error("Failed to send webhook alert", err, map[string]interface{}{
-			"alert_id": alert.ID,
-		})
-	}
-}
-
-// Helper functions
-
-func (am *AlertManager) formatAlertMessage(alert *Alert, event *AlertEvent) string {
-	return fmt.Sprintf(`Alert: %s
-
-Description: %s
-Level: %s
-Value: %.2f
-Threshold: %.2f
-Time: %s
-
-This is an automated alert from the Nutrition Platform monitoring system.
-`, alert.Name, alert.Description, alert.Level.String(), event.Value, event.Threshold, event.Timestamp.Format(time.RFC3339))
-}
-
-func (am *AlertManager) getSlackColor(level AlertLevel) string {
-	switch level {
-	case AlertCritical:
-		return "danger"
-	case AlertError:
-		return "danger"
-	case AlertWarning:
-		return "#ffa500"
-	case AlertInfo:
-		return "good"
-	default:
-		return "#808080"
-	}
-}
-
-func (am *AlertManager) sendEmail(subject, body string) error {
-	if am.emailConfig == nil {
-		return fmt.Errorf("email config not available")
+	// Add to history
+	am.alertHistory = append(am.alertHistory, *event)
+	// Keep only last 1000 events
+	if len(am.alertHistory) > 1000 {
+		am.alertHistory = am.alertHistory[len(am.alertHistory)-1000:]
 	}
+	am.mu.Unlock()
 
-	auth := smtp.PlainAuth("", am.emailConfig.Username, am.emailConfig.Password, am.emailConfig.SMTPHost)
-
-	msg := []byte(fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s",
-		strings.Join(am.emailConfig.ToAddresses, ","), subject, body))
-
-	addr := fmt.Sprintf("%s:%d", am.emailConfig.SMTPHost, am.emailConfig.SMTPPort)
-	err := smtp.SendMail(addr, auth, am.emailConfig.FromAddress, am.emailConfig.ToAddresses, msg)
-
-	return err
-}
-
-func (am *AlertManager) sendSlackMessage(payload map[string]interface{}) error {
-	// Implement Slack webhook sending
-	// This would use HTTP POST to the webhook URL with JSON payload
-	return fmt.Errorf("Slack integration not implemented - configure webhook")
-}
-
-func (am *AlertManager) sendWebhookMessage(payload map[string]interface{}) error {
-	// Implement generic webhook sending
-	// This would use HTTP POST to the configured URL with JSON payload
-	return fmt.Errorf("Webhook integration not implemented - configure URL")
+	alerting.Fire(routed)
 }
 
 func generateAlertEventID() string {
@@ -437,35 +283,6 @@ func randomString(length int) string {
 	return string(b)
 }
 
-func loadEmailConfig() *EmailConfig {
-	host := getEnvOrDefault("SMTP_HOST", "")
-	if host == "" {
-		return nil
-	}
-
-	port, _ := strconv.Atoi(getEnvOrDefault("SMTP_PORT", "587"))
-	username := getEnvOrDefault("SMTP_USERNAME", "")
-	password := getEnvOrDefault("SMTP_PASSWORD", "")
-	from := getEnvOrDefault("ALERT_FROM_EMAIL", "")
-	to := strings.Split(getEnvOrDefault("ALERT_TO_EMAILS", ""), ",")
-
-	return &EmailConfig{
-		SMTPHost:    host,
-		SMTPPort:    port,
-		Username:    username,
-		Password:    password,
-		FromAddress: from,
-		ToAddresses: to,
-	}
-}
-
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
 // Global alert manager instance
 var AlertManagerInstance *AlertManager
 
@@ -483,18 +300,15 @@ func CheckErrorRate(errorCount, totalRequests int) {
 		return
 	}
 
+	// Labels identify the incident, so the counts stay out of them
 	errorRate := float64(errorCount) / float64(totalRequests) * 100
-	AlertManagerInstance.CheckAlert("high_error_rate", errorRate, map[string]string{
-		"error_count":    strconv.Itoa(errorCount),
-		"total_requests": strconv.Itoa(totalRequests),
-	})
+	AlertManagerInstance.CheckAlert("high_error_rate", errorRate, nil)
 }
 
 // CheckCircuitBreakerState checks circuit breaker state
 func CheckCircuitBreakerState(serviceName string, state int) {
 	AlertManagerInstance.CheckAlert("circuit_breaker_open", float64(state), map[string]string{
 		"service": serviceName,
-		"state":   strconv.Itoa(state),
 	})
 }
 
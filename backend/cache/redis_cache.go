@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -255,9 +256,9 @@ func (r *RedisCache) getCacheKey(key string) string {
 func CacheMiddleware(cache *RedisCache, ttl time.Duration, skipPaths []string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Skip caching for specified paths
+			// Skip caching for specified paths; a path ending in "/" covers everything below it
 			for _, path := range skipPaths {
-				if c.Request().URL.Path == path {
+				if c.Request().URL.Path == path || (strings.HasSuffix(path, "/") && strings.HasPrefix(c.Request().URL.Path, path)) {
 					return next(c)
 				}
 			}
@@ -267,6 +268,14 @@ func CacheMiddleware(cache *RedisCache, ttl time.Duration, skipPaths []string) e
 				return next(c)
 			}
 
+			// Skip caching for authenticated requests. This middleware runs
+			// before the auth middlewares set user_id, so a per-user response
+			// would otherwise be cached under its path alone and served to
+			// other callers.
+			if hasCredentials(c.Request()) {
+				return next(c)
+			}
+
 			// Generate cache key
 			cacheKey := generateCacheKey(c)
 
@@ -343,15 +352,13 @@ func (rw *capturingResponseWriter) WriteString(s string) (int, error) {
 // generateCacheKey creates a cache key from request
 func generateCacheKey(c echo.Context) string {
 	// Include relevant request parameters
-	key := fmt.Sprintf("%s:%s",
+	return fmt.Sprintf("%s:%s",
 		c.Request().URL.Path,
 		c.Request().URL.RawQuery,
 	)
+}
 
-	// Add user context if available
-	if userID := c.Get("user_id"); userID != nil {
-		key += fmt.Sprintf(":user:%v", userID)
-	}
-
-	return key
+// hasCredentials reports whether the request carries a bearer token or API key
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("X-API-Key") != ""
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"nutrition-platform/config"
+	"nutrition-platform/services"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	var (
+		file     = flag.String("file", "", "Path to an Open Food Facts CSV or JSONL export, optionally gzipped")
+		format   = flag.String("format", "", "Import format: csv or jsonl (default: from file extension)")
+		source   = flag.String("source", services.DefaultFoodDataSource, "Data source recorded on imported foods")
+		verified = flag.Bool("verified", false, "Mark every imported product as verified")
+	)
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(strings.TrimSuffix(*file, ".gz"))) {
+		case ".jsonl", ".json":
+			*format = services.FoodImportFormatJSONL
+		default:
+			*format = services.FoodImportFormatCSV
+		}
+	}
+
+	// Load configuration
+	cfg := config.LoadConfig()
+
+	// Connect to database
+	db, err := sql.Open("postgres", cfg.GetDatabaseURL())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open import file: %v", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(*file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			log.Fatalf("Failed to read gzip file: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Printf("Importing %s (%s) as %s", *file, *format, *source)
+	result, err := services.NewFoodImportService(db).Import(ctx, r, services.FoodImportOptions{
+		Format:     *format,
+		DataSource: *source,
+		Verified:   *verified,
+	})
+	if result != nil {
+		for _, e := range result.Errors {
+			log.Printf("Skipped line %d (%s): %s", e.Line, e.Code, e.Reason)
+		}
+		log.Printf("Read %d products: %d created, %d updated, %d kept verified, %d skipped",
+			result.Read, result.Created, result.Updated, result.Kept, result.Skipped)
+	}
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"nutrition-platform/config"
+	"nutrition-platform/services"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// jsonStoreMigration creates the tables the JSON store records move into
+const jsonStoreMigration = "020_create_meal_supplement_plan_session_tables.sql"
+
+// runImport copies the meals, supplements, medical plans and sessions kept in
+// ./data/*.json into the database. Rows are keyed by their existing IDs, so it
+// can be run again after a partial import or while old instances still write
+// to the files.
+//
+// The schema migrations are written for SQLite (see
+// 001_initial_schema_sqlite.sql), so only SQLite databases are supported.
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	var (
+		dataDir       = flags.String("data", "./data", "Directory holding meals.json, supplements.json, medical-plans.json and sessions.json")
+		dsn           = flags.String("dsn", "", "SQLite database file (default: the configured database URL)")
+		migrationsDir = flags.String("migrations", "./migrations", "Directory holding "+jsonStoreMigration)
+	)
+	flags.Parse(args)
+
+	if *dsn == "" {
+		*dsn = config.LoadConfig().GetDatabaseURL()
+	}
+	if strings.Contains(*dsn, "://") && !strings.HasPrefix(*dsn, "sqlite3://") {
+		log.Fatal("Unsupported database URL: only SQLite is supported")
+	}
+	*dsn = strings.TrimPrefix(*dsn, "sqlite3://")
+
+	db, err := sql.Open("sqlite3", *dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// The migration only uses CREATE ... IF NOT EXISTS, so applying it again is harmless
+	migration, err := os.ReadFile(filepath.Join(*migrationsDir, jsonStoreMigration))
+	if err != nil {
+		log.Fatalf("Failed to read migration: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, string(migration)); err != nil {
+		log.Fatalf("Failed to create tables: %v", err)
+	}
+
+	log.Printf("Importing JSON stores from %s", *dataDir)
+	results, err := services.ImportJSONStores(ctx, db, *dataDir)
+	for _, result := range results {
+		if result.Missing {
+			log.Printf("%s: not found, nothing to import", result.File)
+			continue
+		}
+		for _, e := range result.Errors {
+			log.Printf("%s: skipped record %d %s: %s", result.File, e.Index, e.ID, e.Reason)
+		}
+		log.Printf("%s: read %d records: %d imported, %d already present, %d skipped",
+			result.File, result.Read, result.Imported, result.Existing, result.Skipped)
+	}
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+}
@@ -15,6 +15,12 @@ import (
 )
 
 func main() {
+	// "migrate import" moves the JSON file store into the database
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImport(os.Args[2:])
+		return
+	}
+
 	var (
 		direction = flag.String("direction", "up", "Migration direction: up or down")
 		steps     = flag.Int("steps", 0, "Number of migration steps (0 for all)")
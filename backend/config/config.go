@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the application configuration
@@ -19,6 +20,10 @@ type Config struct {
 	FileStorage       FileStorageConfig
 	EmailConfig       EmailConfig
 	PushConfig        PushConfig
+	ExportConfig      ExportConfig
+	MFAConfig         MFAConfig
+	WebhookConfig     WebhookConfig
+	ReminderConfig    ReminderConfig
 }
 
 // FileStorageConfig holds file storage configuration
@@ -40,6 +45,8 @@ type EmailConfig struct {
 	SMTPPass   string
 	FromEmail  string
 	FromName   string
+	OutboxPath string // file provider destination, for development and tests
+	ResetURL   string // frontend page that accepts password reset tokens
 }
 
 // PushConfig holds push notification configuration
@@ -50,6 +57,34 @@ type PushConfig struct {
 	APNSTeamID   string
 }
 
+// ExportConfig holds document export configuration
+type ExportConfig struct {
+	PDFFontPath string // TrueType font with Arabic coverage; meal plan PDFs are unavailable without it
+}
+
+// MFAConfig holds two-factor authentication settings
+type MFAConfig struct {
+	Issuer           string // shown next to the account in authenticator apps
+	EncryptionKey    string // encrypts stored TOTP secrets; defaults to JWTSecret
+	RequireForAdmins bool   // admins must sign in with a second factor to use admin routes
+}
+
+// WebhookConfig holds outbound webhook delivery settings
+type WebhookConfig struct {
+	EncryptionKey string        // encrypts stored signing secrets; defaults to JWTSecret
+	MaxAttempts   int           // deliveries are dead-lettered after this many failed attempts
+	Timeout       time.Duration // per-attempt HTTP timeout
+	PollInterval  time.Duration // how often due deliveries are sent
+	// AllowPrivateNetworks permits http URLs and loopback or private
+	// addresses; for local development only
+	AllowPrivateNetworks bool
+}
+
+// ReminderConfig holds reminder scheduler settings
+type ReminderConfig struct {
+	PollInterval time.Duration // how often due reminders are sent
+}
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	config := &Config{
@@ -69,13 +104,15 @@ func LoadConfig() *Config {
 			S3URL:       getEnv("S3_URL", ""),
 		},
 		EmailConfig: EmailConfig{
-			Provider:  getEnv("EMAIL_PROVIDER", "smtp"),
-			SMTPHost:  getEnv("SMTP_HOST", "localhost"),
-			SMTPPort:  getEnvAsInt("SMTP_PORT", 587),
-			SMTPUser:  getEnv("SMTP_USER", ""),
-			SMTPPass:  getEnv("SMTP_PASS", ""),
-			FromEmail: getEnv("FROM_EMAIL", "noreply@nutrition-platform.com"),
-			FromName:  getEnv("FROM_NAME", "Nutrition Platform"),
+			Provider:   getEnv("EMAIL_PROVIDER", "smtp"),
+			SMTPHost:   getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:   getEnvAsInt("SMTP_PORT", 587),
+			SMTPUser:   getEnv("SMTP_USER", ""),
+			SMTPPass:   getEnv("SMTP_PASS", ""),
+			FromEmail:  getEnv("FROM_EMAIL", "noreply@nutrition-platform.com"),
+			FromName:   getEnv("FROM_NAME", "Nutrition Platform"),
+			OutboxPath: getEnv("EMAIL_OUTBOX_PATH", "./data/outbox.log"),
+			ResetURL:   getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
 		},
 		PushConfig: PushConfig{
 			FCMServerKey: getEnv("FCM_SERVER_KEY", ""),
@@ -83,6 +120,30 @@ func LoadConfig() *Config {
 			APNSKeyID:    getEnv("APNS_KEY_ID", ""),
 			APNSTeamID:   getEnv("APNS_TEAM_ID", ""),
 		},
+		ExportConfig: ExportConfig{
+			PDFFontPath: getEnv("PDF_FONT_PATH", ""),
+		},
+		MFAConfig: MFAConfig{
+			Issuer:           getEnv("MFA_ISSUER", "Nutrition Platform"),
+			EncryptionKey:    getEnv("MFA_ENCRYPTION_KEY", ""),
+			RequireForAdmins: getEnvAsBool("MFA_REQUIRE_ADMINS", false),
+		},
+		WebhookConfig: WebhookConfig{
+			EncryptionKey:        getEnv("WEBHOOK_ENCRYPTION_KEY", ""),
+			MaxAttempts:          getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			Timeout:              time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+			PollInterval:         time.Duration(getEnvAsInt("WEBHOOK_POLL_INTERVAL_SECONDS", 15)) * time.Second,
+			AllowPrivateNetworks: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+		},
+		ReminderConfig: ReminderConfig{
+			PollInterval: time.Duration(getEnvAsInt("REMINDER_POLL_INTERVAL_SECONDS", 60)) * time.Second,
+		},
+	}
+	if config.MFAConfig.EncryptionKey == "" {
+		config.MFAConfig.EncryptionKey = config.JWTSecret
+	}
+	if config.WebhookConfig.EncryptionKey == "" {
+		config.WebhookConfig.EncryptionKey = config.JWTSecret
 	}
 
 	// Validate required configuration
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AchievementHandler serves a user's streaks, badges, consistency scores
+// and milestones. Streaks and badges follow from logging; milestones are
+// set here.
+type AchievementHandler struct {
+	achievementService *services.AchievementService
+}
+
+// NewAchievementHandler creates a new AchievementHandler instance
+func NewAchievementHandler(achievementService *services.AchievementService) *AchievementHandler {
+	return &AchievementHandler{achievementService: achievementService}
+}
+
+// GetAchievements lists the user's earned badges, milestones and streaks
+// GET /api/v1/progress/achievements
+func (h *AchievementHandler) GetAchievements(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	overview, err := h.achievementService.GetAchievements(c.Request().Context(), userID)
+	if err != nil {
+		return achievementError(c, err, "Failed to get achievements")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   overview,
+	})
+}
+
+// GetUpcomingAchievements lists the badges and milestones the user is
+// closest to
+// GET /api/v1/progress/achievements/upcoming
+func (h *AchievementHandler) GetUpcomingAchievements(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	upcoming, err := h.achievementService.GetUpcoming(c.Request().Context(), userID)
+	if err != nil {
+		return achievementError(c, err, "Failed to get upcoming achievements")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   upcoming,
+	})
+}
+
+// GetConsistency scores how regularly the user has logged
+// GET /api/v1/progress/consistency?days=30
+func (h *AchievementHandler) GetConsistency(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	days := 0
+	if daysStr := c.QueryParam("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid days",
+			})
+		}
+		days = d
+	}
+
+	consistency, err := h.achievementService.GetConsistency(c.Request().Context(), userID, days)
+	if err != nil {
+		return achievementError(c, err, "Failed to get consistency")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   consistency,
+	})
+}
+
+// GetMilestones lists the user's milestones
+// GET /api/v1/progress/milestones
+func (h *AchievementHandler) GetMilestones(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	milestones, err := h.achievementService.ListMilestones(c.Request().Context(), userID)
+	if err != nil {
+		return achievementError(c, err, "Failed to get milestones")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   milestones,
+	})
+}
+
+// CreateMilestone sets a weight, workout or custom milestone
+// POST /api/v1/progress/milestones
+func (h *AchievementHandler) CreateMilestone(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.CreateMilestoneRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	milestone, err := h.achievementService.CreateMilestone(c.Request().Context(), userID, &req)
+	if err != nil {
+		return achievementError(c, err, "Failed to create milestone")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   milestone,
+	})
+}
+
+// AchieveMilestone marks a custom milestone achieved
+// POST /api/v1/progress/milestones/:id/achieve
+func (h *AchievementHandler) AchieveMilestone(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid milestone ID",
+		})
+	}
+
+	milestone, err := h.achievementService.AchieveMilestone(c.Request().Context(), userID, uint(id))
+	if err != nil {
+		return achievementError(c, err, "Failed to update milestone")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   milestone,
+	})
+}
+
+// DeleteMilestone deletes a milestone
+// DELETE /api/v1/progress/milestones/:id
+func (h *AchievementHandler) DeleteMilestone(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid milestone ID",
+		})
+	}
+
+	if err := h.achievementService.DeleteMilestone(c.Request().Context(), userID, uint(id)); err != nil {
+		return achievementError(c, err, "Failed to delete milestone")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Milestone deleted",
+	})
+}
+
+func achievementError(c echo.Context, err error, message string) error {
+	if errors.Is(err, services.ErrInvalidAchievementRequest) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err.Error() == "milestone not found" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Milestone not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"nutrition-platform/alerting"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxSilenceDuration bounds how long a silence may mute alerts
+const maxSilenceDuration = 7 * 24 * time.Hour
+
+// AlertHandler lets admins inspect the alert router and manage silences
+type AlertHandler struct {
+	router *alerting.Router
+}
+
+// NewAlertHandler creates a new AlertHandler instance
+func NewAlertHandler(router *alerting.Router) *AlertHandler {
+	return &AlertHandler{router: router}
+}
+
+// CreateSilenceRequest mutes alerts matching every matcher, e.g.
+// {"source": "health_check", "check": "redis"}, for duration_minutes
+type CreateSilenceRequest struct {
+	Matchers        map[string]string `json:"matchers"`
+	DurationMinutes int               `json:"duration_minutes"`
+	Comment         string            `json:"comment"`
+}
+
+// GetAlertStats returns what the router did with the alerts it received
+// GET /api/v1/admin/alerts/stats
+func (h *AlertHandler) GetAlertStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   h.router.Stats(),
+	})
+}
+
+// GetSilences lists the silences that have not ended
+// GET /api/v1/admin/alerts/silences
+func (h *AlertHandler) GetSilences(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   h.router.Silences(),
+	})
+}
+
+// CreateSilence mutes matching alerts starting now
+// POST /api/v1/admin/alerts/silences
+func (h *AlertHandler) CreateSilence(c echo.Context) error {
+	var req CreateSilenceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	if duration <= 0 || duration > maxSilenceDuration {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "duration_minutes must be between 1 and 10080",
+		})
+	}
+
+	createdBy, _ := requestUserID(c)
+	now := time.Now()
+	silence, err := h.router.AddSilence(alerting.Silence{
+		Matchers:  req.Matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(duration),
+		CreatedBy: createdBy,
+		Comment:   req.Comment,
+	})
+	if err != nil {
+		if errors.Is(err, alerting.ErrInvalidSilence) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create silence",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   silence,
+	})
+}
+
+// DeleteSilence ends a silence early
+// DELETE /api/v1/admin/alerts/silences/:id
+func (h *AlertHandler) DeleteSilence(c echo.Context) error {
+	if err := h.router.RemoveSilence(c.Param("id")); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Silence not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Silence removed successfully",
+	})
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+
+	"nutrition-platform/models"
 	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
@@ -9,57 +14,327 @@ import (
 
 // APIKeyHandler handles API key-related requests
 type APIKeyHandler struct {
-	apiKeyService *services.APIKeyService
+	apiKeyService    *services.APIKeyService
+	analyticsService *services.AnalyticsService
 }
 
 // NewAPIKeyHandler creates a new APIKeyHandler instance
-func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService, analyticsService *services.AnalyticsService) *APIKeyHandler {
 	return &APIKeyHandler{
-		apiKeyService: apiKeyService,
+		apiKeyService:    apiKeyService,
+		analyticsService: analyticsService,
 	}
 }
 
-// Stub implementations
+// GetAPIKeys lists the user's keys
+// GET /api/v1/api-keys?page=1&limit=20
 func (h *APIKeyHandler) GetAPIKeys(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(c.QueryParam("page")); err == nil && p > 0 {
+		page = p
+	}
+	limit := 20
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request().Context(), userID, page, limit)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to get API keys")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "GetAPIKeys - stub implementation",
+		"status": "success",
+		"data":   keys,
 	})
 }
 
+// CreateAPIKey issues a key; the key itself is only returned in this response
+// POST /api/v1/api-keys
 func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	created, err := h.apiKeyService.CreateAPIKey(c.Request().Context(), userID, &req)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to create API key")
+	}
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"message": "CreateAPIKey - stub implementation",
+		"status": "success",
+		"data":   created,
 	})
 }
 
+// GetAPIKey returns one of the user's keys
+// GET /api/v1/api-keys/:id
 func (h *APIKeyHandler) GetAPIKey(c echo.Context) error {
-	id := c.Param("id")
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	key, err := h.apiKeyService.GetAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to get API key")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "GetAPIKey - stub implementation",
-		"id":      id,
+		"status": "success",
+		"data":   key,
 	})
 }
 
+// UpdateAPIKey changes a key's name, status, scopes, limits or metadata
+// PUT /api/v1/api-keys/:id
 func (h *APIKeyHandler) UpdateAPIKey(c echo.Context) error {
-	id := c.Param("id")
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.UpdateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	key, err := h.apiKeyService.UpdateAPIKey(c.Request().Context(), userID, c.Param("id"), &req)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to update API key")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "UpdateAPIKey - stub implementation",
-		"id":      id,
+		"status": "success",
+		"data":   key,
 	})
 }
 
+// DeleteAPIKey revokes a key; its usage history is kept
+// DELETE /api/v1/api-keys/:id
 func (h *APIKeyHandler) DeleteAPIKey(c echo.Context) error {
-	id := c.Param("id")
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return apiKeyError(c, err, "Failed to revoke API key")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "DeleteAPIKey - stub implementation",
-		"id":      id,
+		"status":  "success",
+		"message": "API key revoked successfully",
 	})
 }
 
+// RegenerateAPIKey rotates a key's secret. The previous secret keeps working
+// for grace_period_hours (24 by default, 0 to disable it immediately).
+// POST /api/v1/api-keys/:id/regenerate
 func (h *APIKeyHandler) RegenerateAPIKey(c echo.Context) error {
-	id := c.Param("id")
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.RotateAPIKeyRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	gracePeriod := services.DefaultAPIKeyGracePeriod
+	if req.GracePeriodHours != nil {
+		if *req.GracePeriodHours < 0 || *req.GracePeriodHours > int(services.MaxAPIKeyGracePeriod.Hours()) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "grace_period_hours must be between 0 and " + strconv.Itoa(int(services.MaxAPIKeyGracePeriod.Hours())),
+			})
+		}
+		gracePeriod = time.Duration(*req.GracePeriodHours) * time.Hour
+	}
+
+	rotated, err := h.apiKeyService.RotateAPIKey(c.Request().Context(), userID, c.Param("id"), gracePeriod)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to regenerate API key")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   rotated,
+	})
+}
+
+// GetAPIKeyUsage returns the key's usage dashboard: totals, latency
+// percentiles and error rates per endpoint, and daily counts
+// GET /api/v1/api-keys/:id/usage?days=7
+func (h *APIKeyHandler) GetAPIKeyUsage(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	days := 7
+	if d := c.QueryParam("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "days must be a number",
+			})
+		}
+		days = parsed
+	}
+
+	key, err := h.apiKeyService.GetAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to get API key usage")
+	}
+	usage, err := h.analyticsService.GetAPIKeyUsage(c.Request().Context(), key, days)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to get API key usage")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   usage,
+	})
+}
+
+// GetAPIKeyAlerts lists the usage alerts set on a key
+// GET /api/v1/api-keys/:id/alerts
+func (h *APIKeyHandler) GetAPIKeyAlerts(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	key, err := h.apiKeyService.GetAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to get usage alerts")
+	}
+	alerts, err := h.analyticsService.GetAlerts(c.Request().Context(), key.ID)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to get usage alerts")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   alerts,
+	})
+}
+
+// CreateAPIKeyAlert adds a usage alert to a key
+// POST /api/v1/api-keys/:id/alerts
+func (h *APIKeyHandler) CreateAPIKeyAlert(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateUsageAlertRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	key, err := h.apiKeyService.GetAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to create usage alert")
+	}
+	alert, err := h.analyticsService.CreateAlert(c.Request().Context(), key.ID, &req)
+	if err != nil {
+		return apiKeyError(c, err, "Failed to create usage alert")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   alert,
+	})
+}
+
+// DeleteAPIKeyAlert removes a usage alert from a key
+// DELETE /api/v1/api-keys/:id/alerts/:alertId
+func (h *APIKeyHandler) DeleteAPIKeyAlert(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	key, err := h.apiKeyService.GetAPIKey(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return apiKeyError(c, err, "Failed to delete usage alert")
+	}
+	if err := h.analyticsService.DeleteAlert(c.Request().Context(), key.ID, c.Param("alertId")); err != nil {
+		return apiKeyError(c, err, "Failed to delete usage alert")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "RegenerateAPIKey - stub implementation",
-		"id":      id,
+		"status":  "success",
+		"message": "Usage alert deleted successfully",
+	})
+}
+
+func apiKeyError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAPIKeyRequest),
+		errors.Is(err, services.ErrInvalidUsageAlert),
+		errors.Is(err, services.ErrInvalidUsageReport):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrAPIKeyLimitReached):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	switch err.Error() {
+	case "api key not found":
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "API key not found",
+		})
+	case "usage alert not found":
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Usage alert not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
 	})
 }
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
 	"nutrition-platform/middleware"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
 	"nutrition-platform/security"
 	"nutrition-platform/services"
 
@@ -13,14 +17,20 @@ import (
 )
 
 type AuthHandler struct {
-	userService *services.UserService
-	jwtManager  *security.JWTManager
+	userService          *services.UserService
+	jwtManager           *security.JWTManager
+	passwordResetService *services.PasswordResetService
+	refreshTokenService  *services.RefreshTokenService
+	mfaService           *services.MFAService
 }
 
-func NewAuthHandler(userService *services.UserService, jwtManager *security.JWTManager) *AuthHandler {
+func NewAuthHandler(userService *services.UserService, jwtManager *security.JWTManager, passwordResetService *services.PasswordResetService, refreshTokenService *services.RefreshTokenService, mfaService *services.MFAService) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		jwtManager:  jwtManager,
+		userService:          userService,
+		jwtManager:           jwtManager,
+		passwordResetService: passwordResetService,
+		refreshTokenService:  refreshTokenService,
+		mfaService:           mfaService,
 	}
 }
 
@@ -51,6 +61,20 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// MFAChallengeResponse is returned by Login instead of tokens when the
+// account has two-factor authentication; MFAToken is exchanged at
+// /auth/login/mfa together with a code
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
 // Register handles user registration
 func (h *AuthHandler) Register(c echo.Context) error {
 	var req RegisterRequest
@@ -94,7 +118,8 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		})
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken("stub-user-id")
+	_, refreshToken, err := h.refreshTokenService.StartSession(c.Request().Context(), "stub-user-id", accessToken,
+		"", c.RealIP(), c.Request().UserAgent(), false)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to generate refresh token",
@@ -137,6 +162,27 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		"password":   "", // Never return password
 	}
 
+	// Accounts with two-factor authentication finish signing in at /auth/login/mfa
+	mfaEnabled, err := h.mfaService.IsEnabled(c.Request().Context(), "stub-user-id")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to check two-factor authentication",
+		})
+	}
+	if mfaEnabled {
+		mfaToken, err := middleware.GenerateMFAPendingToken("stub-user-id", req.Email, "user", false)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate MFA token",
+			})
+		}
+		return c.JSON(http.StatusOK, MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int64(middleware.MFAPendingTokenTTL.Seconds()),
+		})
+	}
+
 	// Generate tokens (stub)
 	accessToken, err := middleware.GenerateToken("stub-user-id", req.Email, "user", false)
 	if err != nil {
@@ -145,7 +191,8 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		})
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken("stub-user-id")
+	_, refreshToken, err := h.refreshTokenService.StartSession(c.Request().Context(), "stub-user-id", accessToken,
+		"", c.RealIP(), c.Request().UserAgent(), false)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to generate refresh token",
@@ -160,6 +207,59 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	})
 }
 
+// LoginMFA completes a login for an account with two-factor authentication
+// by exchanging the MFA token from Login and a TOTP or recovery code for
+// access tokens
+func (h *AuthHandler) LoginMFA(c echo.Context) error {
+	var req LoginMFARequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+	if req.MFAToken == "" || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "mfa_token and code are required",
+		})
+	}
+
+	claims, err := middleware.ParseMFAPendingToken(req.MFAToken)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid or expired MFA token",
+		})
+	}
+
+	if err := h.mfaService.Verify(c.Request().Context(), claims.UserID, req.Code, c.RealIP()); err != nil {
+		return mfaError(c, err)
+	}
+
+	accessToken, err := middleware.GenerateTokenWithMFA(claims.UserID, claims.Email, claims.Role, claims.IsAdmin, true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate access token",
+		})
+	}
+
+	_, refreshToken, err := h.refreshTokenService.StartSession(c.Request().Context(), claims.UserID, accessToken,
+		"", c.RealIP(), c.Request().UserAgent(), true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate refresh token",
+		})
+	}
+
+	return c.JSON(http.StatusOK, AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User: map[string]interface{}{
+			"id":    claims.UserID,
+			"email": claims.Email,
+		},
+		ExpiresIn: 15 * 60, // 15 minutes in seconds
+	})
+}
+
 // Logout handles user logout
 func (h *AuthHandler) Logout(c echo.Context) error {
 	// Get token from Authorization header
@@ -196,36 +296,44 @@ func (h *AuthHandler) RefreshToken(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation
-	fmt.Printf("Refresh token: %s...\n", req.RefreshToken[:10]+"...")
-
-	// Generate new tokens (stub)
-	newAccessToken, err := middleware.GenerateToken("stub-user-id", "refreshed@example.com", "user", false)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate access token",
+	if req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Refresh token is required",
 		})
 	}
 
-	newRefreshToken, err := middleware.GenerateRefreshToken("stub-user-id")
+	// The presented token is consumed; presenting it again revokes the session.
+	// The new access token carries the account's current email, role and
+	// admin flag.
+	ctx := c.Request().Context()
+	var account *models.UserAccount
+	session, newRefreshToken, err := h.refreshTokenService.Rotate(ctx, req.RefreshToken,
+		c.RealIP(), c.Request().UserAgent(), func(session *services.Session) (string, error) {
+			var err error
+			account, err = h.userService.GetAccount(ctx, session.UserID)
+			if err != nil {
+				if errors.Is(err, repositories.ErrUserNotFound) {
+					return "", services.ErrInvalidRefreshToken
+				}
+				return "", err
+			}
+			return middleware.GenerateTokenWithMFA(account.ID, account.Email, account.Role, account.IsAdmin, session.MFAVerified)
+		})
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid or expired refresh token",
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate refresh token",
+			"error": "Failed to refresh token",
 		})
 	}
 
-	// Create stub user response
-	user := map[string]interface{}{
-		"id":         "stub-user-id",
-		"email":      "refreshed@example.com",
-		"first_name": "Refreshed",
-		"last_name":  "User",
-	}
-
 	return c.JSON(http.StatusOK, AuthResponse{
-		AccessToken:  newAccessToken,
+		AccessToken:  session.AccessToken,
 		RefreshToken: newRefreshToken,
-		User:         user,
+		User:         account,
 		ExpiresIn:    15 * 60, // 15 minutes in seconds
 	})
 }
@@ -348,14 +456,17 @@ func (h *AuthHandler) ForgotPassword(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation - in production:
-	// 1. Check if user exists
-	// 2. Generate reset token
-	// 3. Store token with expiration
-	// 4. Send email with reset link
-	// 5. Return success (don't reveal if email exists)
+	if h.passwordResetService == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Password reset is not available",
+		})
+	}
 
-	fmt.Printf("Forgot password request for: %s\n", req.Email)
+	// Delivery failures are logged rather than returned so the response
+	// never reveals whether the address belongs to an account
+	if err := h.passwordResetService.RequestReset(c.Request().Context(), req.Email, c.RealIP()); err != nil {
+		log.Printf("Password reset request failed: %v", err)
+	}
 
 	// Always return success to prevent email enumeration
 	return c.JSON(http.StatusOK, map[string]string{
@@ -382,20 +493,20 @@ func (h *AuthHandler) ResetPassword(c echo.Context) error {
 		})
 	}
 
-	// Stub implementation - in production:
-	// 1. Validate reset token
-	// 2. Check token expiration
-	// 3. Hash new password
-	// 4. Update user password
-	// 5. Invalidate reset token
-	// 6. Invalidate all user sessions
-
-	fmt.Printf("Reset password with token: %s...\n", req.Token[:10]+"...")
+	if h.passwordResetService == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Password reset is not available",
+		})
+	}
 
-	// Check if token is valid (stub - always valid for testing)
-	if len(req.Token) < 10 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid or expired reset token",
+	if err := h.passwordResetService.ResetPassword(c.Request().Context(), req.Token, req.NewPassword); err != nil {
+		if err == services.ErrInvalidResetToken {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid or expired reset token",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to reset password",
 		})
 	}
 
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CoachingHandler handles coach invitations and the permissions clients
+// grant their coaches
+type CoachingHandler struct {
+	coachingService *services.CoachingService
+}
+
+// NewCoachingHandler creates a new CoachingHandler instance
+func NewCoachingHandler(coachingService *services.CoachingService) *CoachingHandler {
+	return &CoachingHandler{coachingService: coachingService}
+}
+
+// CreateInvitation invites a client and returns the one-time token to pass
+// on to them
+// POST /api/v1/coach/invitations
+func (h *CoachingHandler) CreateInvitation(c echo.Context) error {
+	coachID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.CreateCoachInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	invitation, err := h.coachingService.Invite(c.Request().Context(), coachID, req)
+	if err != nil {
+		return coachingError(c, err, "Failed to create invitation")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   invitation,
+	})
+}
+
+// GetClients returns the coach's pending invitations and active clients
+// GET /api/v1/coach/clients
+func (h *CoachingHandler) GetClients(c echo.Context) error {
+	coachID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	clients, err := h.coachingService.ListClients(c.Request().Context(), coachID)
+	if err != nil {
+		return coachingError(c, err, "Failed to get clients")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   clients,
+	})
+}
+
+// AcceptInvitation connects the current user to the inviting coach,
+// granting the requested permissions or the subset listed in the request
+// POST /api/v1/coaching/invitations/accept
+func (h *CoachingHandler) AcceptInvitation(c echo.Context) error {
+	clientID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.AcceptCoachInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	relationship, err := h.coachingService.Accept(c.Request().Context(), clientID, req)
+	if err != nil {
+		return coachingError(c, err, "Failed to accept invitation")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   relationship,
+	})
+}
+
+// GetCoaches returns the coaches the current user is connected to and what
+// each may access
+// GET /api/v1/coaching/coaches
+func (h *CoachingHandler) GetCoaches(c echo.Context) error {
+	clientID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	coaches, err := h.coachingService.ListCoaches(c.Request().Context(), clientID)
+	if err != nil {
+		return coachingError(c, err, "Failed to get coaches")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   coaches,
+	})
+}
+
+// UpdatePermissions replaces the permissions granted to a coach
+// PUT /api/v1/coaching/coaches/:id/permissions
+func (h *CoachingHandler) UpdatePermissions(c echo.Context) error {
+	clientID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.UpdateCoachPermissionsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	relationship, err := h.coachingService.UpdatePermissions(c.Request().Context(), clientID, c.Param("id"), req)
+	if err != nil {
+		return coachingError(c, err, "Failed to update permissions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   relationship,
+	})
+}
+
+// EndRelationship ends a coach/client relationship from either side, or
+// withdraws a pending invitation
+// DELETE /api/v1/coach/clients/:id
+// DELETE /api/v1/coaching/coaches/:id
+func (h *CoachingHandler) EndRelationship(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := h.coachingService.EndRelationship(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return coachingError(c, err, "Failed to end coaching relationship")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Coaching relationship ended",
+	})
+}
+
+// GetAccessLog lists coach accesses to the current user's data
+// GET /api/v1/coaching/access-log?coach_id=&limit=50&offset=0
+func (h *CoachingHandler) GetAccessLog(c echo.Context) error {
+	clientID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	entries, err := h.coachingService.AccessLog(c.Request().Context(), clientID, c.QueryParam("coach_id"), limit, offset)
+	if err != nil {
+		return coachingError(c, err, "Failed to get access log")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   entries,
+	})
+}
+
+func coachingError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidCoachingRequest):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrCoachInvitationInvalid):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrAlreadyCoached):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err.Error() == "coach client relationship not found" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Coaching relationship not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
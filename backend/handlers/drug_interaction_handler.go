@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DrugInteractionHandler handles medications, supplements and the
+// drug–nutrient interaction report
+type DrugInteractionHandler struct {
+	interactionService *services.DrugInteractionService
+}
+
+// NewDrugInteractionHandler creates a new DrugInteractionHandler instance
+func NewDrugInteractionHandler(interactionService *services.DrugInteractionService) *DrugInteractionHandler {
+	return &DrugInteractionHandler{
+		interactionService: interactionService,
+	}
+}
+
+// AddMedication records a medication the user takes
+// POST /api/v1/nutrition/medications
+func (h *DrugInteractionHandler) AddMedication(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateUserMedicationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	medication, interactions, err := h.interactionService.AddMedication(c.Request().Context(), userID, req)
+	if err != nil {
+		return interactionError(c, err, "Failed to add medication")
+	}
+
+	response := map[string]interface{}{
+		"status": "success",
+		"data":   medication,
+	}
+	if len(interactions) > 0 {
+		response["interactions"] = interactions
+		response["disclaimer"] = h.interactionService.Disclaimer(c.QueryParam("lang"))
+	}
+	return c.JSON(http.StatusCreated, response)
+}
+
+// AddSupplement records a supplement the user takes and flags interactions
+// with their active medications
+// POST /api/v1/nutrition/supplements
+func (h *DrugInteractionHandler) AddSupplement(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateUserSupplementRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	supplement, interactions, err := h.interactionService.AddSupplement(c.Request().Context(), userID, req)
+	if err != nil {
+		return interactionError(c, err, "Failed to add supplement")
+	}
+
+	response := map[string]interface{}{
+		"status": "success",
+		"data":   supplement,
+	}
+	if len(interactions) > 0 {
+		response["interactions"] = interactions
+		response["disclaimer"] = h.interactionService.Disclaimer(c.QueryParam("lang"))
+	}
+	return c.JSON(http.StatusCreated, response)
+}
+
+// GetInteractionReport checks the foods logged over the last days and the
+// active supplements against the user's active medications
+// GET /api/v1/nutrition/interactions?days=30&lang=en
+func (h *DrugInteractionHandler) GetInteractionReport(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	days := 0
+	if daysStr := c.QueryParam("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "days must be a number",
+			})
+		}
+		days = d
+	}
+
+	report, err := h.interactionService.GetReport(c.Request().Context(), userID, days, c.QueryParam("lang"))
+	if err != nil {
+		return interactionError(c, err, "Failed to build interaction report")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+func interactionError(c echo.Context, err error, message string) error {
+	if errors.Is(err, services.ErrInvalidMedicationRequest) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EnergyExpenditureHandler serves the adaptive TDEE estimate and sets
+// calorie targets from it
+type EnergyExpenditureHandler struct {
+	energyService *services.EnergyExpenditureService
+}
+
+// NewEnergyExpenditureHandler creates a new EnergyExpenditureHandler instance
+func NewEnergyExpenditureHandler(energyService *services.EnergyExpenditureService) *EnergyExpenditureHandler {
+	return &EnergyExpenditureHandler{energyService: energyService}
+}
+
+// GetTDEE estimates the user's energy expenditure from their logged intake
+// and weight trend
+// GET /api/v1/nutrition/tdee?days=28
+func (h *EnergyExpenditureHandler) GetTDEE(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	days := 0
+	if daysStr := c.QueryParam("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid days",
+			})
+		}
+		days = parsed
+	}
+
+	estimate, err := h.energyService.EstimateTDEE(c.Request().Context(), userID, days)
+	if err != nil {
+		return energyError(c, err, "Failed to estimate energy expenditure")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   estimate,
+	})
+}
+
+// ApplyAdaptiveGoal sets a nutrition goal's calorie target and band from
+// the estimate
+// POST /api/v1/nutrition/goals/adaptive
+func (h *EnergyExpenditureHandler) ApplyAdaptiveGoal(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.AdaptiveGoalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	goal, estimate, err := h.energyService.ApplyToGoal(c.Request().Context(), userID, req)
+	if err != nil {
+		return energyError(c, err, "Failed to update nutrition goal")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"goal":     goal,
+			"estimate": estimate,
+		},
+	})
+}
+
+func energyError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidEnergyRequest):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInsufficientEnergyData):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err.Error() == "nutrition goal not found" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Goal not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
@@ -2,24 +2,30 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"nutrition-platform/models"
 	"nutrition-platform/repositories"
+	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
 )
 
 // FoodHandler handles food CRUD operations
 type FoodHandler struct {
-	foodRepo *repositories.FoodRepository
+	foodRepo       *repositories.FoodRepository
+	foodLogService *services.FoodLogService
+	barcodeService *services.FoodBarcodeService
 }
 
 // NewFoodHandler creates a new food handler
 func NewFoodHandler(db *sql.DB) *FoodHandler {
 	return &FoodHandler{
-		foodRepo: repositories.NewFoodRepository(db),
+		foodRepo:       repositories.NewFoodRepository(db),
+		foodLogService: services.NewFoodLogService(db),
+		barcodeService: services.NewFoodBarcodeService(db),
 	}
 }
 
@@ -147,6 +153,40 @@ func (h *FoodHandler) GetFood(c echo.Context) error {
 	})
 }
 
+// GetFoodByBarcode returns the food for a scanned EAN-13, UPC-A or EAN-8
+// code, preferring the user's own foods over the imported catalog
+// GET /api/v1/nutrition/foods/barcode/:code
+func (h *FoodHandler) GetFoodByBarcode(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	lookup, err := h.barcodeService.LookupBarcode(c.Request().Context(), userID, c.Param("code"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBarcode) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if err.Error() == "food not found" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "No food found for this barcode",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to look up barcode",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   lookup,
+	})
+}
+
 // CreateFood creates a new food
 func (h *FoodHandler) CreateFood(c echo.Context) error {
 	userID := c.Get("user_id")
@@ -186,6 +226,16 @@ func (h *FoodHandler) CreateFood(c echo.Context) error {
 		})
 	}
 
+	if req.Barcode != nil && *req.Barcode != "" {
+		barcode, err := services.NormalizeBarcode(*req.Barcode)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		req.Barcode = &barcode
+	}
+
 	// Convert to Food model - repository expects BarCode, Verified, SourceType
 	food := &models.Food{
 		UserID:       userIDUint,
@@ -376,3 +426,97 @@ func (h *FoodHandler) SearchFoods(c echo.Context) error {
 	return h.GetFoods(c) // Reuse GetFoods which already supports search
 }
 
+// GetFoodPortions returns the household unit weights defined for a food
+// GET /api/v1/nutrition/foods/:id/portions
+func (h *FoodHandler) GetFoodPortions(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	food, err := h.foodRepo.GetFoodByID(c.Param("id"), userID)
+	if err != nil {
+		if err.Error() == "food not found" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Food not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch food: " + err.Error(),
+		})
+	}
+
+	portions, err := h.foodLogService.GetFoodPortions(c.Request().Context(), food.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch food portions",
+		})
+	}
+	if portions == nil {
+		portions = []models.FoodPortion{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   portions,
+	})
+}
+
+// SetFoodPortion defines the gram weight of a household unit for a food,
+// e.g. {"unit": "cup", "grams": 158}
+// PUT /api/v1/nutrition/foods/:id/portions
+func (h *FoodHandler) SetFoodPortion(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req struct {
+		Unit        string  `json:"unit"`
+		Grams       float64 `json:"grams"`
+		Description *string `json:"description"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	food, err := h.foodRepo.GetFoodByID(c.Param("id"), userID)
+	if err != nil {
+		if err.Error() == "food not found" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Food not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch food: " + err.Error(),
+		})
+	}
+
+	portion := &models.FoodPortion{
+		FoodID:      food.ID,
+		Unit:        req.Unit,
+		Grams:       req.Grams,
+		Description: req.Description,
+	}
+	if err := h.foodLogService.SetFoodPortion(c.Request().Context(), portion); err != nil {
+		if errors.Is(err, services.ErrInvalidFoodLog) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to save food portion",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   portion,
+	})
+}
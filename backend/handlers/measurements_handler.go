@@ -9,6 +9,7 @@ import (
 	"nutrition-platform/database"
 	"nutrition-platform/models"
 	"nutrition-platform/repositories"
+	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
 )
@@ -16,6 +17,7 @@ import (
 // MeasurementsHandler handles body measurement operations
 type MeasurementsHandler struct {
 	measurementRepo *repositories.BodyMeasurementRepository
+	composition     *services.BodyCompositionService
 }
 
 // NewMeasurementsHandler creates a new measurements handler
@@ -24,6 +26,7 @@ func NewMeasurementsHandler(db *sql.DB) *MeasurementsHandler {
 	dbWrapper := database.NewDatabase(db)
 	return &MeasurementsHandler{
 		measurementRepo: repositories.NewBodyMeasurementRepository(dbWrapper),
+		composition:     services.NewBodyCompositionService(db),
 	}
 }
 
@@ -119,12 +122,17 @@ func (h *MeasurementsHandler) LogMeasurement(c echo.Context) error {
 		})
 	}
 
+	h.composition.ApplyToMeasurement(userIDUint, measurement)
+
 	err := h.measurementRepo.CreateBodyMeasurement(c.Request().Context(), measurement)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to log measurement: " + err.Error(),
 		})
 	}
+	if measurement.Weight != nil {
+		services.RecordActivity(c.Request().Context(), strconv.FormatUint(uint64(userIDUint), 10), models.ActivityWeighIn, measurement.MeasurementDate, measurement.Weight)
+	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"status":  "success",
@@ -359,6 +367,7 @@ func (h *MeasurementsHandler) UpdateMeasurement(c echo.Context) error {
 	}
 	if req.BodyFatPercentage != nil {
 		existingMeasurement.BodyFatPercentage = req.BodyFatPercentage
+		existingMeasurement.BodyFatMethod = nil
 	}
 	if req.MuscleMass != nil {
 		existingMeasurement.MuscleMass = req.MuscleMass
@@ -411,6 +420,8 @@ func (h *MeasurementsHandler) UpdateMeasurement(c echo.Context) error {
 		})
 	}
 
+	h.composition.ApplyToMeasurement(userIDUint, existingMeasurement)
+
 	err = h.measurementRepo.UpdateBodyMeasurement(c.Request().Context(), existingMeasurement)
 	if err != nil {
 		if err.Error() == "body measurement not found or access denied" {
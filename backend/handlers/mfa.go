@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MFAHandler lets users set up and manage TOTP two-factor authentication
+type MFAHandler struct {
+	mfaService *services.MFAService
+}
+
+// NewMFAHandler creates a new MFAHandler instance
+func NewMFAHandler(mfaService *services.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+// MFACodeRequest carries a TOTP code, or a recovery code where one is
+// accepted
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// GetMFAStatus returns the current user's two-factor settings
+// GET /api/v1/auth/2fa
+func (h *MFAHandler) GetMFAStatus(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+	role, _ := c.Get("role").(string)
+	isAdmin, _ := c.Get("is_admin").(bool)
+
+	status, err := h.mfaService.Status(c.Request().Context(), userID, role, isAdmin)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   status,
+	})
+}
+
+// EnrollMFA starts enrollment and returns the secret and the otpauth://
+// provisioning URI to show as a QR code
+// POST /api/v1/auth/2fa/enroll
+func (h *MFAHandler) EnrollMFA(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+	email, _ := c.Get("email").(string)
+
+	enrollment, err := h.mfaService.BeginEnrollment(c.Request().Context(), userID, email)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   enrollment,
+	})
+}
+
+// ConfirmMFA enables two-factor authentication with a first code and
+// returns the recovery codes, which are not shown again
+// POST /api/v1/auth/2fa/confirm
+func (h *MFAHandler) ConfirmMFA(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+	var req MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "code is required",
+		})
+	}
+
+	codes, err := h.mfaService.ConfirmEnrollment(c.Request().Context(), userID, req.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"recovery_codes": codes,
+		},
+		"message": "Two-factor authentication enabled. Sign in again to use it for this session.",
+	})
+}
+
+// DisableMFA turns two-factor authentication off
+// POST /api/v1/auth/2fa/disable
+func (h *MFAHandler) DisableMFA(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+	var req MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "code is required",
+		})
+	}
+
+	if err := h.mfaService.Disable(c.Request().Context(), userID, req.Code, c.RealIP()); err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes
+// POST /api/v1/auth/2fa/recovery-codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+	var req MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "code is required",
+		})
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request().Context(), userID, req.Code, c.RealIP())
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+func mfaError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrMFALocked):
+		return c.JSON(http.StatusTooManyRequests, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnrolled):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Two-factor authentication failed",
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NotificationHandler serves the in-app notification inbox
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+// NewNotificationHandler creates a new NotificationHandler instance
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// GetNotifications returns a page of the user's inbox, newest first, with
+// the number of unread notifications
+// GET /api/v1/notifications?unread=true&limit=50&offset=0
+func (h *NotificationHandler) GetNotifications(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	unreadOnly, _ := strconv.ParseBool(c.QueryParam("unread"))
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	ctx := c.Request().Context()
+	notifications, err := h.notificationService.ListNotifications(ctx, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return notificationError(c, err, "Failed to get notifications")
+	}
+	unread, err := h.notificationService.UnreadCount(ctx, userID)
+	if err != nil {
+		return notificationError(c, err, "Failed to get notifications")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"notifications": notifications,
+			"unread_count":  unread,
+		},
+	})
+}
+
+// MarkNotificationRead marks one notification as read
+// POST /api/v1/notifications/:id/read
+func (h *NotificationHandler) MarkNotificationRead(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := h.notificationService.MarkRead(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return notificationError(c, err, "Failed to update notification")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Notification marked as read",
+	})
+}
+
+// MarkAllNotificationsRead marks the whole inbox as read
+// POST /api/v1/notifications/read-all
+func (h *NotificationHandler) MarkAllNotificationsRead(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	count, err := h.notificationService.MarkAllRead(c.Request().Context(), userID)
+	if err != nil {
+		return notificationError(c, err, "Failed to update notifications")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]int64{
+			"marked_read": count,
+		},
+	})
+}
+
+// DeleteNotification removes a notification from the inbox
+// DELETE /api/v1/notifications/:id
+func (h *NotificationHandler) DeleteNotification(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := h.notificationService.DeleteNotification(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return notificationError(c, err, "Failed to delete notification")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Notification deleted",
+	})
+}
+
+func notificationError(c echo.Context, err error, message string) error {
+	if err.Error() == "notification not found" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Notification not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
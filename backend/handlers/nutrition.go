@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
 	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
@@ -110,12 +116,42 @@ func (h *NutritionHandler) GenerateMealPlan(c echo.Context) error {
 	})
 }
 
+// GenerateMealPlanPDF exports a stored meal plan as a downloadable PDF
+// GET /api/v1/nutrition/meal-plans/:id/pdf
 func (h *NutritionHandler) GenerateMealPlanPDF(c echo.Context) error {
-	id := c.Param("id")
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "GenerateMealPlanPDF - stub implementation",
-		"id":      id,
-	})
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	planID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid meal plan ID",
+		})
+	}
+
+	pdf, err := h.nutritionService.GenerateMealPlanPDF(c.Request().Context(), planID, userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrMealPlanNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Meal plan not found",
+			})
+		}
+		if errors.Is(err, services.ErrMealPlanPDFFontUnavailable) {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{
+				"error": "Meal plan PDF export is not configured",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate meal plan PDF",
+		})
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="meal-plan-%d.pdf"`, planID))
+	return c.Blob(http.StatusOK, "application/pdf", pdf)
 }
 
 // NutritionPlanHandler handles nutrition plan-related requests
@@ -236,3 +272,142 @@ func (h *NutritionPlanHandler) CreatePersonalizedNutritionPlan(c echo.Context) e
 		"created_at":  "2024-01-01T00:00:00Z",
 	})
 }
+
+// GetMealPlans returns the user's stored meal plans
+// GET /api/v1/nutrition/meal-plans?limit=20&offset=0
+func (h *NutritionHandler) GetMealPlans(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(c.QueryParam("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	plans, err := h.nutritionService.ListMealPlans(c.Request().Context(), userID, limit, offset)
+	if err != nil {
+		return mealPlanError(c, err, "Failed to get meal plans")
+	}
+	if plans == nil {
+		plans = []*models.MealPlan{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   plans,
+	})
+}
+
+// GetMealPlan returns one of the user's stored meal plans
+// GET /api/v1/nutrition/meal-plans/:id
+func (h *NutritionHandler) GetMealPlan(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	planID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid meal plan ID",
+		})
+	}
+
+	plan, err := h.nutritionService.GetMealPlan(c.Request().Context(), planID, userID)
+	if err != nil {
+		return mealPlanError(c, err, "Failed to get meal plan")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   plan,
+	})
+}
+
+// UpdateMealPlan renames, edits the days of, or (de)activates a meal plan
+// PATCH /api/v1/nutrition/meal-plans/:id
+func (h *NutritionHandler) UpdateMealPlan(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	planID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid meal plan ID",
+		})
+	}
+
+	var req models.UpdateMealPlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	plan, err := h.nutritionService.UpdateMealPlan(c.Request().Context(), planID, userID, &req)
+	if err != nil {
+		return mealPlanError(c, err, "Failed to update meal plan")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   plan,
+	})
+}
+
+// DeleteMealPlan deletes one of the user's meal plans
+// DELETE /api/v1/nutrition/meal-plans/:id
+func (h *NutritionHandler) DeleteMealPlan(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	planID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid meal plan ID",
+		})
+	}
+
+	if err := h.nutritionService.DeleteMealPlan(c.Request().Context(), planID, userID); err != nil {
+		return mealPlanError(c, err, "Failed to delete meal plan")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Meal plan deleted successfully",
+	})
+}
+
+func mealPlanError(c echo.Context, err error, message string) error {
+	if errors.Is(err, services.ErrInvalidMealPlanRequest) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, repositories.ErrMealPlanNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Meal plan not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
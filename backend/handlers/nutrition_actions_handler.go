@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"nutrition-platform/models"
 	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
@@ -14,16 +16,31 @@ import (
 // NutritionActionsHandler handles user-facing nutrition actions
 type NutritionActionsHandler struct {
 	nutritionPlanService *services.NutritionPlanService
+	foodLogService       *services.FoodLogService
+	ledgerService        *services.NutritionLedgerService
+	mealPlanOptimizer    *services.MealPlanOptimizer
+	interactionService   *services.DrugInteractionService
 }
 
 func NewNutritionActionsHandler(db *sql.DB) *NutritionActionsHandler {
+	// The halal blacklist is optional; without it the optimizer falls back
+	// to recipes' halal flag and its built-in ingredient list
+	halal, _ := services.NewHalalCompliance(services.DefaultHalalBlacklistPath)
+
 	return &NutritionActionsHandler{
 		nutritionPlanService: services.NewNutritionPlanService(db),
+		foodLogService:       services.NewFoodLogService(db),
+		ledgerService:        services.NewNutritionLedgerService(db),
+		mealPlanOptimizer:    services.NewMealPlanOptimizer(db, halal),
+		interactionService:   services.NewDrugInteractionService(db, nil),
 	}
 }
 
 // GenerateMealPlan - Action: User clicks "Generate Meal Plan" button
 // POST /api/v1/actions/generate-meal-plan
+// Builds a plan (7 days by default) from the recipe and food catalog that
+// meets the daily calorie and macro targets within the tolerance, and stores
+// it as a meal plan. Without target_calories the active nutrition goal is used.
 func (h *NutritionActionsHandler) GenerateMealPlan(c echo.Context) error {
 	userID := c.Get("user_id")
 	if userID == nil {
@@ -32,15 +49,20 @@ func (h *NutritionActionsHandler) GenerateMealPlan(c echo.Context) error {
 		})
 	}
 
-	// Convert userID to string (service expects string)
-	var userIDStr string
+	var userIDInt int
 	switch v := userID.(type) {
 	case uint:
-		userIDStr = strconv.FormatUint(uint64(v), 10)
+		userIDInt = int(v)
 	case int:
-		userIDStr = strconv.Itoa(v)
+		userIDInt = v
 	case string:
-		userIDStr = v
+		if id, err := strconv.Atoi(v); err == nil {
+			userIDInt = id
+		} else {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
 	default:
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid user ID type",
@@ -48,11 +70,19 @@ func (h *NutritionActionsHandler) GenerateMealPlan(c echo.Context) error {
 	}
 
 	var req struct {
-		Goal           string  `json:"goal"`
-		TargetCalories *int    `json:"target_calories"`
-		Duration       int     `json:"duration"` // days
+		Name           string   `json:"name"`
+		TargetCalories *int     `json:"target_calories"`
+		ProteinGrams   *float64 `json:"protein_grams"`
+		CarbsGrams     *float64 `json:"carbs_grams"`
+		FatGrams       *float64 `json:"fat_grams"`
+		Tolerance      float64  `json:"tolerance"`  // 0.1 = ±10%
+		Duration       int      `json:"duration"`   // days
+		StartDate      string   `json:"start_date"` // YYYY-MM-DD format
 		Preferences    []string `json:"preferences"`
 		Restrictions   []string `json:"restrictions"`
+		Allergies      []string `json:"allergies"`
+		Halal          *bool    `json:"halal"`
+		MaxRepeats     int      `json:"max_repeats"`
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -61,36 +91,73 @@ func (h *NutritionActionsHandler) GenerateMealPlan(c echo.Context) error {
 		})
 	}
 
-	// Default duration to 7 days if not provided
-	if req.Duration == 0 {
-		req.Duration = 7
+	planReq := services.MealPlanRequest{
+		Name:         req.Name,
+		Days:         req.Duration,
+		Tolerance:    req.Tolerance,
+		Allergies:    req.Allergies,
+		Restrictions: req.Restrictions,
+		Preferences:  req.Preferences,
+		// Plans are halal unless the user opts out
+		Halal:      req.Halal == nil || *req.Halal,
+		MaxRepeats: req.MaxRepeats,
+	}
+	if req.TargetCalories != nil {
+		planReq.Targets.Calories = float64(*req.TargetCalories)
+	}
+	if req.ProteinGrams != nil {
+		planReq.Targets.Protein = *req.ProteinGrams
+	}
+	if req.CarbsGrams != nil {
+		planReq.Targets.Carbs = *req.CarbsGrams
+	}
+	if req.FatGrams != nil {
+		planReq.Targets.Fat = *req.FatGrams
+	}
+	if req.StartDate != "" {
+		startDate, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid start_date format. Use YYYY-MM-DD",
+			})
+		}
+		planReq.StartDate = startDate
 	}
 
-	// Call service to generate meal plan
-	// Note: This is a simplified version - you can expand based on your service implementation
-	mealPlan := map[string]interface{}{
-		"user_id":         userIDStr,
-		"goal":            req.Goal,
-		"target_calories": req.TargetCalories,
-		"duration_days":   req.Duration,
-		"preferences":     req.Preferences,
-		"restrictions":    req.Restrictions,
-		"generated_at":    time.Now().Format(time.RFC3339),
-		"meals":           []map[string]interface{}{}, // Will be populated by service
+	generated, err := h.mealPlanOptimizer.GeneratePlan(c.Request().Context(), userIDInt, planReq)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMealPlanRequest) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrInsufficientCatalog) {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate meal plan",
+		})
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"status":  "success",
 		"message": "Meal plan generated successfully",
-		"data":    mealPlan,
+		"data":    generated,
 	})
 }
 
 // LogMeal - Action: User clicks "Log Meal" button
 // POST /api/v1/actions/log-meal
+// The quantity may be given in any supported unit (g, kg, oz, lb, ml, cup,
+// tbsp, tsp, piece, serving); it is stored in grams and nutrition is
+// computed from the food's per-100g values. Recipes are logged in servings
+// using their per-serving nutrition. Foods that interact with the user's
+// active medications are listed under interactions.
 func (h *NutritionActionsHandler) LogMeal(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, ok := requestUserIntID(c)
+	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
@@ -98,7 +165,7 @@ func (h *NutritionActionsHandler) LogMeal(c echo.Context) error {
 
 	var req struct {
 		FoodID   *uint   `json:"food_id"`
-		RecipeID *uint   `json:"recipe_id"`
+		RecipeID *string `json:"recipe_id"`
 		MealType string  `json:"meal_type" validate:"required"`
 		Quantity float64 `json:"quantity" validate:"required,gt=0"`
 		Unit     string  `json:"unit" validate:"required"`
@@ -112,37 +179,107 @@ func (h *NutritionActionsHandler) LogMeal(c echo.Context) error {
 		})
 	}
 
-	// Parse date or use current date
-	mealDate := time.Now()
+	if (req.FoodID == nil) == (req.RecipeID == nil) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Exactly one of food_id or recipe_id is required",
+		})
+	}
+	if req.Quantity <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "quantity must be greater than 0",
+		})
+	}
+	if req.Unit == "" {
+		req.Unit = services.UnitGram
+		if req.RecipeID != nil {
+			req.Unit = services.UnitServing
+		}
+	}
+
+	// Parse date or use current time
+	consumedAt := time.Now()
 	if req.Date != "" {
-		if parsedDate, err := time.Parse("2006-01-02", req.Date); err == nil {
-			mealDate = parsedDate
+		parsedDate, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid date format. Use YYYY-MM-DD",
+			})
+		}
+		if parsedDate.Format("2006-01-02") != consumedAt.Format("2006-01-02") {
+			consumedAt = parsedDate
 		}
 	}
 
-	mealLog := map[string]interface{}{
-		"food_id":   req.FoodID,
-		"recipe_id": req.RecipeID,
-		"meal_type": req.MealType,
-		"quantity":  req.Quantity,
-		"unit":      req.Unit,
-		"date":      mealDate.Format("2006-01-02"),
-		"notes":     req.Notes,
-		"logged_at": time.Now().Format(time.RFC3339),
+	var entry *models.UserFoodLog
+	var err error
+	if req.RecipeID != nil {
+		unit, unitErr := services.NormalizeUnit(req.Unit)
+		if unitErr != nil || (unit != services.UnitServing && unit != services.UnitPiece) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Recipes are logged in servings",
+			})
+		}
+		entry, err = h.foodLogService.LogRecipe(c.Request().Context(), userID, services.LogRecipeInput{
+			RecipeID:   *req.RecipeID,
+			MealType:   req.MealType,
+			Servings:   req.Quantity,
+			ConsumedAt: consumedAt,
+			Notes:      req.Notes,
+		})
+	} else {
+		entry, err = h.foodLogService.LogFood(c.Request().Context(), userID, services.LogFoodInput{
+			FoodID:     *req.FoodID,
+			MealType:   req.MealType,
+			Quantity:   req.Quantity,
+			Unit:       req.Unit,
+			ConsumedAt: consumedAt,
+			Notes:      req.Notes,
+		})
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFoodLog) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if err.Error() == "food not found" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Food not found",
+			})
+		}
+		if err.Error() == "recipe not found" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Recipe not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to log meal",
+		})
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
+	services.PublishWebhookEvent(c.Request().Context(), strconv.Itoa(userID), models.WebhookEventMealLogged, entry)
+	services.RecordActivity(c.Request().Context(), strconv.Itoa(userID), models.ActivityMeal, consumedAt, nil)
+
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": "Meal logged successfully",
-		"data":    mealLog,
-	})
+		"data":    entry,
+	}
+
+	// Interactions with the user's medications are reported, never blocking
+	if interactions, err := h.interactionService.CheckFoodLog(c.Request().Context(), userID, entry); err == nil && len(interactions) > 0 {
+		response["interactions"] = interactions
+		response["disclaimer"] = h.interactionService.Disclaimer("")
+	}
+
+	return c.JSON(http.StatusCreated, response)
 }
 
 // GetNutritionSummary - Action: User clicks "View Nutrition Summary" button
 // GET /api/v1/actions/nutrition-summary?days=7
 func (h *NutritionActionsHandler) GetNutritionSummary(c echo.Context) error {
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, ok := requestUserIntID(c)
+	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
@@ -155,25 +292,16 @@ func (h *NutritionActionsHandler) GetNutritionSummary(c echo.Context) error {
 			days = d
 		}
 	}
+	if days > 92 {
+		days = 92
+	}
 
-	summary := map[string]interface{}{
-		"period_days": days,
-		"start_date":  time.Now().AddDate(0, 0, -days).Format("2006-01-02"),
-		"end_date":    time.Now().Format("2006-01-02"),
-		"totals": map[string]interface{}{
-			"calories": 0,
-			"protein":  0,
-			"carbs":    0,
-			"fat":      0,
-			"fiber":    0,
-		},
-		"daily_averages": map[string]interface{}{
-			"calories": 0,
-			"protein":  0,
-			"carbs":    0,
-			"fat":      0,
-		},
-		"meals_logged": 0,
+	// The range ends today in the user's time zone
+	summary, err := h.ledgerService.GetLedgerSummary(c.Request().Context(), userID, time.Time{}, days)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to build nutrition summary",
+		})
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
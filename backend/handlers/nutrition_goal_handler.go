@@ -247,7 +247,13 @@ func (h *NutritionGoalHandler) UpdateGoal(c echo.Context) error {
 
 	// Update fields
 	if req.DailyCalories != nil {
+		// A manual target replaces one set from the adaptive estimate
 		existingGoal.DailyCalories = req.DailyCalories
+		existingGoal.DailyCaloriesLow = nil
+		existingGoal.DailyCaloriesHigh = nil
+		existingGoal.EstimatedTDEE = nil
+		existingGoal.TDEEConfidence = nil
+		existingGoal.TDEEUpdatedAt = nil
 	}
 	if req.ProteinGrams != nil {
 		existingGoal.ProteinGrams = req.ProteinGrams
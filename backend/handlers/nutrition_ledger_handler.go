@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NutritionLedgerHandler serves a user's daily intake against their goals
+type NutritionLedgerHandler struct {
+	ledgerService *services.NutritionLedgerService
+}
+
+// NewNutritionLedgerHandler creates a new NutritionLedgerHandler instance
+func NewNutritionLedgerHandler(db *sql.DB) *NutritionLedgerHandler {
+	return &NutritionLedgerHandler{
+		ledgerService: services.NewNutritionLedgerService(db),
+	}
+}
+
+// GetDailyLedger returns everything eaten and drunk on one day with the
+// remaining budget for each goal target
+// GET /api/v1/nutrition/ledger?date=YYYY-MM-DD
+func (h *NutritionLedgerHandler) GetDailyLedger(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	// A missing date means today in the user's time zone
+	var date time.Time
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid date format. Use YYYY-MM-DD",
+			})
+		}
+		date = parsed
+	}
+
+	ledger, err := h.ledgerService.GetDailyLedger(c.Request().Context(), userID, date)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to build nutrition ledger",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   ledger,
+	})
+}
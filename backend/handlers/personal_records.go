@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PersonalRecordHandler serves the personal records detected in a user's
+// completed workouts
+type PersonalRecordHandler struct {
+	personalRecordService *services.PersonalRecordService
+}
+
+// NewPersonalRecordHandler creates a new PersonalRecordHandler instance
+func NewPersonalRecordHandler(personalRecordService *services.PersonalRecordService) *PersonalRecordHandler {
+	return &PersonalRecordHandler{personalRecordService: personalRecordService}
+}
+
+// GetPersonalRecords lists the user's PR history, newest first, optionally
+// for one exercise or record type and between dates
+// GET /api/v1/fitness/personal-records?exercise_id=squat&record_type=estimated_1rm&start_date=2026-01-01&end_date=2026-07-01&page=1&limit=20
+func (h *PersonalRecordHandler) GetPersonalRecords(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.ListPersonalRecordsRequest
+	if exerciseID := c.QueryParam("exercise_id"); exerciseID != "" {
+		req.ExerciseID = &exerciseID
+	}
+	if recordType := c.QueryParam("record_type"); recordType != "" {
+		req.RecordType = &recordType
+	}
+	if startStr := c.QueryParam("start_date"); startStr != "" {
+		start, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid start_date, expected YYYY-MM-DD",
+			})
+		}
+		req.StartDate = &start
+	}
+	if endStr := c.QueryParam("end_date"); endStr != "" {
+		end, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid end_date, expected YYYY-MM-DD",
+			})
+		}
+		// The end date is included
+		end = end.AddDate(0, 0, 1)
+		req.EndDate = &end
+	}
+	if pageStr := c.QueryParam("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid page",
+			})
+		}
+		req.Page = page
+	}
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid limit",
+			})
+		}
+		req.Limit = limit
+	}
+
+	records, err := h.personalRecordService.ListRecords(c.Request().Context(), userID, req)
+	if err != nil {
+		return personalRecordError(c, err, "Failed to get personal records")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   records,
+	})
+}
+
+// GetPersonalRecordStats returns the user's current bests per exercise,
+// recent records and whether they are still setting new ones
+// GET /api/v1/fitness/personal-records/stats
+func (h *PersonalRecordHandler) GetPersonalRecordStats(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	stats, err := h.personalRecordService.GetStats(c.Request().Context(), userID)
+	if err != nil {
+		return personalRecordError(c, err, "Failed to get personal record stats")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   stats,
+	})
+}
+
+func personalRecordError(c echo.Context, err error, message string) error {
+	if errors.Is(err, services.ErrInvalidPersonalRecordRequest) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
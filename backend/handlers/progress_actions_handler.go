@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,11 +16,13 @@ import (
 // ProgressActionsHandler handles user-facing progress tracking actions
 type ProgressActionsHandler struct {
 	progressService *services.ProgressService
+	forecastService *services.WeightForecastService
 }
 
 func NewProgressActionsHandler(db *sql.DB) *ProgressActionsHandler {
 	return &ProgressActionsHandler{
 		progressService: services.NewProgressService(db),
+		forecastService: services.NewWeightForecastService(db),
 	}
 }
 
@@ -115,6 +118,9 @@ func (h *ProgressActionsHandler) TrackMeasurement(c echo.Context) error {
 			"error": err.Error(),
 		})
 	}
+	if result.Weight != nil {
+		services.RecordActivity(c.Request().Context(), strconv.FormatInt(userIDInt, 10), models.ActivityWeighIn, result.MeasurementDate, result.Weight)
+	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"status":  "success",
@@ -310,6 +316,60 @@ func (h *ProgressActionsHandler) GetProgressCharts(c echo.Context) error {
 	})
 }
 
+// GetWeightForecast - Action: User views "on pace for X by date"
+// GET /api/v1/actions/weight-forecast?days=90&target_weight=80
+func (h *ProgressActionsHandler) GetWeightForecast(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	days := 0
+	if daysStr := c.QueryParam("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid days",
+			})
+		}
+		days = d
+	}
+	var targetWeight *float64
+	if targetStr := c.QueryParam("target_weight"); targetStr != "" {
+		target, err := strconv.ParseFloat(targetStr, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid target weight",
+			})
+		}
+		targetWeight = &target
+	}
+
+	forecast, err := h.forecastService.Forecast(c.Request().Context(), int64(userID), days, targetWeight)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidForecastRequest):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrInsufficientForecastData):
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to forecast weight: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   forecast,
+	})
+}
+
 // CompareMeasurements - Action: User compares measurements between dates
 // POST /api/v1/actions/compare-measurements
 func (h *ProgressActionsHandler) CompareMeasurements(c echo.Context) error {
@@ -442,6 +502,7 @@ func (h *ProgressActionsHandler) UploadProgressPhoto(c echo.Context) error {
 		"notes":         req.Notes,
 		"uploaded_at":   time.Now().Format(time.RFC3339),
 	}
+	services.RecordActivity(c.Request().Context(), strconv.FormatInt(userIDInt, 10), models.ActivityProgressPhoto, req.Date, nil)
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"status":  "success",
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+
+	"nutrition-platform/models"
 	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
@@ -32,33 +35,132 @@ func (h *RecipeHandler) SearchRecipes(c echo.Context) error {
 	})
 }
 
+// GetRecipe returns a single recipe
 func (h *RecipeHandler) GetRecipe(c echo.Context) error {
-	id := c.Param("id")
+	recipe, err := h.recipeService.GetRecipe(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return recipeError(c, err, "Failed to get recipe")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "GetRecipe - stub implementation",
-		"id":      id,
+		"status": "success",
+		"data":   recipe,
 	})
 }
 
+// GetRecipeNutrition computes a recipe's nutrition from its ingredients and
+// reports the ingredients that could not be matched to foods
+func (h *RecipeHandler) GetRecipeNutrition(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	report, err := h.recipeService.CalculateNutrition(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return recipeError(c, err, "Failed to calculate recipe nutrition")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+// CreateRecipe creates a recipe; its nutrition is computed from the ingredients
 func (h *RecipeHandler) CreateRecipe(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateRecipeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	recipe, report, err := h.recipeService.CreateRecipe(c.Request().Context(), userID, req)
+	if err != nil {
+		return recipeError(c, err, "Failed to create recipe")
+	}
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"message": "CreateRecipe - stub implementation",
+		"status": "success",
+		"data": map[string]interface{}{
+			"recipe":    recipe,
+			"nutrition": report,
+		},
 	})
 }
 
+// UpdateRecipe edits a recipe owned by the user and recomputes its nutrition
 func (h *RecipeHandler) UpdateRecipe(c echo.Context) error {
-	id := c.Param("id")
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.UpdateRecipeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	recipe, report, err := h.recipeService.UpdateRecipe(c.Request().Context(), userID, c.Param("id"), req)
+	if err != nil {
+		return recipeError(c, err, "Failed to update recipe")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "UpdateRecipe - stub implementation",
-		"id":      id,
+		"status": "success",
+		"data": map[string]interface{}{
+			"recipe":    recipe,
+			"nutrition": report,
+		},
 	})
 }
 
+// DeleteRecipe deletes a recipe owned by the user
 func (h *RecipeHandler) DeleteRecipe(c echo.Context) error {
-	id := c.Param("id")
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.recipeService.DeleteRecipe(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return recipeError(c, err, "Failed to delete recipe")
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "DeleteRecipe - stub implementation",
-		"id":      id,
+		"status":  "success",
+		"message": "Recipe deleted successfully",
+	})
+}
+
+func recipeError(c echo.Context, err error, message string) error {
+	if errors.Is(err, services.ErrInvalidRecipe) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err.Error() == "recipe not found" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Recipe not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
 	})
 }
 
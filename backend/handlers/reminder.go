@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReminderHandler manages a user's reminders. Quiet hours, the timezone and
+// which reminder types are sent are user preferences.
+type ReminderHandler struct {
+	reminderService *services.ReminderService
+}
+
+// NewReminderHandler creates a new ReminderHandler instance
+func NewReminderHandler(reminderService *services.ReminderService) *ReminderHandler {
+	return &ReminderHandler{reminderService: reminderService}
+}
+
+// GetReminderTypes lists the reminder types users can schedule
+// GET /api/v1/reminders/types
+func (h *ReminderHandler) GetReminderTypes(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   models.ReminderTypes,
+	})
+}
+
+// GetReminders lists the user's custom reminders and those that follow
+// their medications and supplements
+// GET /api/v1/reminders
+func (h *ReminderHandler) GetReminders(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	reminders, err := h.reminderService.ListReminders(c.Request().Context(), userID)
+	if err != nil {
+		return reminderError(c, err, "Failed to get reminders")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   reminders,
+	})
+}
+
+// CreateReminder schedules a custom reminder
+// POST /api/v1/reminders
+func (h *ReminderHandler) CreateReminder(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.CreateReminderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	reminder, err := h.reminderService.CreateReminder(c.Request().Context(), userID, req)
+	if err != nil {
+		return reminderError(c, err, "Failed to create reminder")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   reminder,
+	})
+}
+
+// UpdateReminder changes a custom reminder's title, message, schedule or
+// active flag
+// PUT /api/v1/reminders/:id
+func (h *ReminderHandler) UpdateReminder(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.UpdateReminderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	reminder, err := h.reminderService.UpdateReminder(c.Request().Context(), userID, c.Param("id"), req)
+	if err != nil {
+		return reminderError(c, err, "Failed to update reminder")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   reminder,
+	})
+}
+
+// DeleteReminder deletes a custom reminder
+// DELETE /api/v1/reminders/:id
+func (h *ReminderHandler) DeleteReminder(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := h.reminderService.DeleteReminder(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return reminderError(c, err, "Failed to delete reminder")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Reminder deleted",
+	})
+}
+
+func reminderError(c echo.Context, err error, message string) error {
+	if errors.Is(err, services.ErrInvalidReminderRequest) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err.Error() == "reminder not found" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Reminder not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
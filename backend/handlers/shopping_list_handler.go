@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ShoppingListHandler handles shopping list requests
+type ShoppingListHandler struct {
+	shoppingListService *services.ShoppingListService
+}
+
+// NewShoppingListHandler creates a new ShoppingListHandler instance
+func NewShoppingListHandler(shoppingListService *services.ShoppingListService) *ShoppingListHandler {
+	return &ShoppingListHandler{
+		shoppingListService: shoppingListService,
+	}
+}
+
+// CreateShoppingList builds a list from a meal plan, a date range of planned
+// days and/or individual recipes
+// POST /api/v1/nutrition/shopping-lists
+func (h *ShoppingListHandler) CreateShoppingList(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateShoppingListRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	list, err := h.shoppingListService.CreateShoppingList(c.Request().Context(), userID, req)
+	if err != nil {
+		return shoppingListError(c, err, "Failed to create shopping list")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   list,
+	})
+}
+
+// GetShoppingLists returns the user's lists without their items
+// GET /api/v1/nutrition/shopping-lists?limit=20&offset=0
+func (h *ShoppingListHandler) GetShoppingLists(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(c.QueryParam("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	lists, err := h.shoppingListService.ListShoppingLists(c.Request().Context(), userID, limit, offset)
+	if err != nil {
+		return shoppingListError(c, err, "Failed to get shopping lists")
+	}
+	if lists == nil {
+		lists = []*models.ShoppingList{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   lists,
+	})
+}
+
+// GetShoppingList returns a list with its items grouped by aisle
+// GET /api/v1/nutrition/shopping-lists/:id
+func (h *ShoppingListHandler) GetShoppingList(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid shopping list ID",
+		})
+	}
+
+	list, err := h.shoppingListService.GetShoppingList(c.Request().Context(), id, userID)
+	if err != nil {
+		return shoppingListError(c, err, "Failed to get shopping list")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   list,
+	})
+}
+
+// UpdateShoppingListItem ticks an item off the list or back on
+// PATCH /api/v1/nutrition/shopping-lists/:id/items/:itemId
+func (h *ShoppingListHandler) UpdateShoppingListItem(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid shopping list ID",
+		})
+	}
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid item ID",
+		})
+	}
+
+	var req struct {
+		Checked *bool `json:"checked"`
+	}
+	if err := c.Bind(&req); err != nil || req.Checked == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "checked is required",
+		})
+	}
+
+	if err := h.shoppingListService.SetItemChecked(c.Request().Context(), listID, itemID, userID, *req.Checked); err != nil {
+		return shoppingListError(c, err, "Failed to update shopping list item")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"id":      itemID,
+			"checked": *req.Checked,
+		},
+	})
+}
+
+// ExportShoppingList downloads a list as plain text or CSV
+// GET /api/v1/nutrition/shopping-lists/:id/export?format=text|csv
+func (h *ShoppingListHandler) ExportShoppingList(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid shopping list ID",
+		})
+	}
+
+	format := c.QueryParam("format")
+	content, contentType, err := h.shoppingListService.ExportShoppingList(c.Request().Context(), id, userID, format)
+	if err != nil {
+		return shoppingListError(c, err, "Failed to export shopping list")
+	}
+
+	extension := "txt"
+	if strings.EqualFold(format, services.ShoppingListFormatCSV) {
+		extension = "csv"
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=\"shopping-list-%d.%s\"", id, extension))
+	return c.Blob(http.StatusOK, contentType, content)
+}
+
+// DeleteShoppingList deletes a list
+// DELETE /api/v1/nutrition/shopping-lists/:id
+func (h *ShoppingListHandler) DeleteShoppingList(c echo.Context) error {
+	userID, ok := requestUserIntID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid shopping list ID",
+		})
+	}
+
+	if err := h.shoppingListService.DeleteShoppingList(c.Request().Context(), id, userID); err != nil {
+		return shoppingListError(c, err, "Failed to delete shopping list")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Shopping list deleted successfully",
+	})
+}
+
+func shoppingListError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidShoppingListRequest):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrEmptyShoppingList):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repositories.ErrShoppingListNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Shopping list not found",
+		})
+	case errors.Is(err, repositories.ErrShoppingListItemNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Shopping list item not found",
+		})
+	case errors.Is(err, repositories.ErrMealPlanNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Meal plan not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requestUserID reads the authenticated user's ID as a string. The auth
+// middlewares store it as uint, int, int64 or string depending on whether
+// it came from a JWT, a session or an API key.
+func requestUserID(c echo.Context) (string, bool) {
+	switch v := c.Get("user_id").(type) {
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case string:
+		return v, v != ""
+	default:
+		return "", false
+	}
+}
+
+// requestUserIntID reads the numeric user ID the nutrition tables are
+// keyed by
+func requestUserIntID(c echo.Context) (int, bool) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
@@ -3,53 +3,48 @@ package handlers
 import (
 	"net/http"
 
+	"nutrition-platform/services"
+
 	"github.com/labstack/echo/v4"
 )
 
 // UserPreferencesHandler handles user preferences endpoints
 type UserPreferencesHandler struct {
-	// Add dependencies here (e.g., userService, db)
+	preferencesService *services.UserPreferencesService
 }
 
 // NewUserPreferencesHandler creates a new user preferences handler
-func NewUserPreferencesHandler() *UserPreferencesHandler {
-	return &UserPreferencesHandler{}
+func NewUserPreferencesHandler(preferencesService *services.UserPreferencesService) *UserPreferencesHandler {
+	return &UserPreferencesHandler{
+		preferencesService: preferencesService,
+	}
 }
 
 // GetPreferences returns the current user's preferences
 func (h *UserPreferencesHandler) GetPreferences(c echo.Context) error {
-	// Get user ID from context (set by JWT middleware)
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, ok := requestUserIntID(c)
+	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
 	}
 
-	// Stub implementation - in production, fetch from database
-	preferences := map[string]interface{}{
-		"user_id":                userID,
-		"language":               "en",
-		"timezone":               "UTC",
-		"notifications_enabled":  true,
-		"email_notifications":    true,
-		"push_notifications":     true,
-		"units":                  "metric",
-		"dark_mode":              false,
-		"theme":                  "light",
-		"meal_reminders":         true,
-		"water_reminders":        true,
-		"workout_reminders":      true,
+	preferences, err := h.preferencesService.GetPreferences(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to load preferences",
+		})
 	}
 
+	preferences["user_id"] = userID
 	return c.JSON(http.StatusOK, preferences)
 }
 
-// UpdatePreferences updates the current user's preferences
+// UpdatePreferences applies a partial update to the current user's preferences.
+// Only the keys present in the body are changed; a null value restores the default.
 func (h *UserPreferencesHandler) UpdatePreferences(c echo.Context) error {
-	// Get user ID from context
-	userID := c.Get("user_id")
-	if userID == nil {
+	userID, ok := requestUserIntID(c)
+	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Unauthorized",
 		})
@@ -61,33 +56,22 @@ func (h *UserPreferencesHandler) UpdatePreferences(c echo.Context) error {
 			"error": "Invalid request format",
 		})
 	}
+	// Clients often echo back the full GET response
+	delete(req, "user_id")
 
-	// Stub implementation - in production:
-	// 1. Validate preferences
-	// 2. Update database
-	// 3. Return updated preferences
-
-	// Merge with defaults
-	preferences := map[string]interface{}{
-		"user_id":                userID,
-		"language":               "en",
-		"timezone":               "UTC",
-		"notifications_enabled":  true,
-		"email_notifications":    true,
-		"push_notifications":     true,
-		"units":                  "metric",
-		"dark_mode":              false,
-		"theme":                  "light",
-		"meal_reminders":         true,
-		"water_reminders":        true,
-		"workout_reminders":      true,
+	if err := h.preferencesService.ValidatePreferences(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
 	}
 
-	// Update with request values
-	for k, v := range req {
-		preferences[k] = v
+	preferences, err := h.preferencesService.UpdatePreferences(c.Request().Context(), userID, req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update preferences",
+		})
 	}
 
+	preferences["user_id"] = userID
 	return c.JSON(http.StatusOK, preferences)
 }
-
@@ -6,6 +6,9 @@ import (
 	"strconv"
 	"time"
 
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -85,6 +88,8 @@ func (h *WaterIntakeHandler) LogWater(c echo.Context) error {
 		})
 	}
 
+	services.RecordActivity(c.Request().Context(), userIDStr, models.ActivityWater, req.Date, nil)
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"status":  "success",
 		"message": "Water intake logged successfully",
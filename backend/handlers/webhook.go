@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/middleware"
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookHandler manages webhook subscriptions and their delivery log. The
+// same routes are served to signed-in users and, under /partner, to API
+// keys; subscriptions created with an API key belong to that key.
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler instance
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// GetEventTypes lists the events subscriptions can receive
+// GET /api/v1/webhooks/events
+func (h *WebhookHandler) GetEventTypes(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   models.WebhookEventTypes,
+	})
+}
+
+// CreateWebhook creates a subscription and returns its signing secret,
+// which is not shown again
+// POST /api/v1/webhooks
+func (h *WebhookHandler) CreateWebhook(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	sub, err := h.webhookService.CreateSubscription(c.Request().Context(), userID, apiKeyID, req)
+	if err != nil {
+		return webhookError(c, err, "Failed to create webhook")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   sub,
+	})
+}
+
+// GetWebhooks lists subscriptions
+// GET /api/v1/webhooks
+func (h *WebhookHandler) GetWebhooks(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	subs, err := h.webhookService.ListSubscriptions(c.Request().Context(), userID, apiKeyID)
+	if err != nil {
+		return webhookError(c, err, "Failed to get webhooks")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   subs,
+	})
+}
+
+// GetWebhook returns one subscription
+// GET /api/v1/webhooks/:id
+func (h *WebhookHandler) GetWebhook(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	sub, err := h.webhookService.GetSubscription(c.Request().Context(), userID, apiKeyID, c.Param("id"))
+	if err != nil {
+		return webhookError(c, err, "Failed to get webhook")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   sub,
+	})
+}
+
+// UpdateWebhook changes a subscription's URL, events, description or
+// active flag
+// PUT /api/v1/webhooks/:id
+func (h *WebhookHandler) UpdateWebhook(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	sub, err := h.webhookService.UpdateSubscription(c.Request().Context(), userID, apiKeyID, c.Param("id"), req)
+	if err != nil {
+		return webhookError(c, err, "Failed to update webhook")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   sub,
+	})
+}
+
+// DeleteWebhook deletes a subscription and its delivery log
+// DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := h.webhookService.DeleteSubscription(c.Request().Context(), userID, apiKeyID, c.Param("id")); err != nil {
+		return webhookError(c, err, "Failed to delete webhook")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Webhook deleted",
+	})
+}
+
+// GetDeliveries returns a subscription's delivery log
+// GET /api/v1/webhooks/:id/deliveries?status=dead&limit=50&offset=0
+func (h *WebhookHandler) GetDeliveries(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request().Context(), userID, apiKeyID,
+		c.Param("id"), c.QueryParam("status"), limit, offset)
+	if err != nil {
+		return webhookError(c, err, "Failed to get webhook deliveries")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   deliveries,
+	})
+}
+
+// ReplayDelivery sends a delivered or dead-lettered delivery again
+// POST /api/v1/webhooks/:id/deliveries/:deliveryId/replay
+func (h *WebhookHandler) ReplayDelivery(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	delivery, err := h.webhookService.ReplayDelivery(c.Request().Context(), userID, apiKeyID,
+		c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		return webhookError(c, err, "Failed to replay webhook delivery")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"status": "success",
+		"data":   delivery,
+	})
+}
+
+// ReplayDeadLetters sends every dead-lettered delivery of a subscription
+// again
+// POST /api/v1/webhooks/:id/replay
+func (h *WebhookHandler) ReplayDeadLetters(c echo.Context) error {
+	userID, apiKeyID, ok := webhookOwner(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	count, err := h.webhookService.ReplayDeadLetters(c.Request().Context(), userID, apiKeyID, c.Param("id"))
+	if err != nil {
+		return webhookError(c, err, "Failed to replay webhook deliveries")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"status": "success",
+		"data": map[string]int64{
+			"replayed": count,
+		},
+	})
+}
+
+// webhookOwner returns the user and, for API key requests, the key that
+// subscriptions are managed for
+func webhookOwner(c echo.Context) (string, string, bool) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return "", "", false
+	}
+	if key, ok := c.Get(middleware.APIKeyContextKey).(*models.APIKey); ok {
+		return userID, key.ID, true
+	}
+	return userID, "", true
+}
+
+func webhookError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhookRequest):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrWebhookDeliveryPending):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	switch err.Error() {
+	case "webhook subscription not found":
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Webhook not found",
+		})
+	case "webhook delivery not found":
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Webhook delivery not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"nutrition-platform/database"
 	"nutrition-platform/models"
 	"nutrition-platform/repositories"
+	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
 )
@@ -88,6 +89,11 @@ func (h *WeightHandler) LogWeight(c echo.Context) error {
 		})
 	}
 
+	userIDStr := strconv.FormatUint(uint64(userIDUint), 10)
+	services.PublishWebhookEvent(c.Request().Context(), userIDStr, models.WebhookEventWeightLogged, log)
+	weight := services.WeightInKilograms(log)
+	services.RecordActivity(c.Request().Context(), userIDStr, models.ActivityWeighIn, log.CreatedAt, &weight)
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"status":  "success",
 		"message": "Weight logged successfully",
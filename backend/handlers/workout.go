@@ -2,26 +2,34 @@ package handlers
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"nutrition-platform/database"
 	"nutrition-platform/models"
 	"nutrition-platform/repositories"
+	"nutrition-platform/services"
 
 	"github.com/labstack/echo/v4"
 )
 
 // WorkoutHandler handles workout-related requests
 type WorkoutHandler struct {
-	workoutRepo *repositories.WorkoutRepository
+	workoutRepo     *repositories.WorkoutRepository
+	programService  *services.WorkoutProgramService
+	personalRecords *services.PersonalRecordService
 }
 
-// NewWorkoutHandler creates a new WorkoutHandler instance
-func NewWorkoutHandler(db *sql.DB) *WorkoutHandler {
+// NewWorkoutHandler creates a new WorkoutHandler instance. Completed
+// workouts are checked for personal records with personalRecords.
+func NewWorkoutHandler(db *sql.DB, personalRecords *services.PersonalRecordService) *WorkoutHandler {
 	dbWrapper := database.NewDatabase(db)
 	return &WorkoutHandler{
-		workoutRepo: repositories.NewWorkoutRepository(dbWrapper),
+		workoutRepo:     repositories.NewWorkoutRepository(dbWrapper),
+		programService:  services.NewWorkoutProgramService(db),
+		personalRecords: personalRecords,
 	}
 }
 
@@ -89,6 +97,13 @@ func (h *WorkoutHandler) LogWorkout(c echo.Context) error {
 		})
 	}
 
+	if session.Status == "completed" {
+		services.PublishWebhookEvent(c.Request().Context(), userIDStr, models.WebhookEventWorkoutCompleted, session)
+		services.RecordActivity(c.Request().Context(), userIDStr, models.ActivityWorkout, workoutCompletedAt(session), nil)
+		h.detectPersonalRecords(c, session)
+	}
+	h.recordProgramSession(c, session)
+
 	return c.JSON(http.StatusCreated, session)
 }
 
@@ -280,6 +295,8 @@ func (h *WorkoutHandler) UpdateWorkout(c echo.Context) error {
 	if req.InjuriesReported != nil {
 		existing.InjuriesReported = req.InjuriesReported
 	}
+	previousStatus := existing.Status
+	wasCompleted := previousStatus == "completed"
 	if req.Status != "" {
 		existing.Status = req.Status
 	}
@@ -290,6 +307,20 @@ func (h *WorkoutHandler) UpdateWorkout(c echo.Context) error {
 		})
 	}
 
+	if !wasCompleted && existing.Status == "completed" {
+		services.PublishWebhookEvent(c.Request().Context(), userIDStr, models.WebhookEventWorkoutCompleted, existing)
+		services.RecordActivity(c.Request().Context(), userIDStr, models.ActivityWorkout, workoutCompletedAt(existing), nil)
+		h.detectPersonalRecords(c, existing)
+	} else if wasCompleted && (req.ExercisesCompleted != nil || req.CompletedDate != nil || existing.Status != "completed") {
+		// Edits to a completed session can add, change or void its records
+		if _, err := h.personalRecords.RedetectRecords(c.Request().Context(), existing); err != nil {
+			log.Printf("Failed to re-detect personal records in workout %s: %v", existing.ID, err)
+		}
+	}
+	if existing.Status != previousStatus {
+		h.recordProgramSession(c, existing)
+	}
+
 	return c.JSON(http.StatusOK, existing)
 }
 
@@ -338,8 +369,38 @@ func (h *WorkoutHandler) DeleteWorkout(c echo.Context) error {
 			"error": "Failed to delete workout: " + err.Error(),
 		})
 	}
+	if err := h.personalRecords.DeleteSessionRecords(c.Request().Context(), userIDStr, id); err != nil {
+		log.Printf("Failed to delete personal records of workout %s: %v", id, err)
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Workout deleted successfully",
 	})
 }
+
+// recordProgramSession moves the user's program on after a logged session.
+// The workout is already saved, so a failure here is logged rather than
+// returned.
+func (h *WorkoutHandler) recordProgramSession(c echo.Context, session *models.UserWorkoutSession) {
+	if err := h.programService.RecordSession(c.Request().Context(), session); err != nil {
+		log.Printf("Failed to apply workout %s to program: %v", session.ID, err)
+	}
+}
+
+// detectPersonalRecords stores the personal records a completed session
+// sets. The workout is already saved, so a failure here is logged rather
+// than returned.
+func (h *WorkoutHandler) detectPersonalRecords(c echo.Context, session *models.UserWorkoutSession) {
+	if _, err := h.personalRecords.DetectRecords(c.Request().Context(), session); err != nil {
+		log.Printf("Failed to detect personal records in workout %s: %v", session.ID, err)
+	}
+}
+
+// workoutCompletedAt returns when a session was completed, now if it has no
+// completion date
+func workoutCompletedAt(session *models.UserWorkoutSession) time.Time {
+	if session.CompletedDate != nil {
+		return *session.CompletedDate
+	}
+	return time.Now()
+}
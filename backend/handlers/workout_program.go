@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WorkoutProgramHandler assigns workout programs to users and shows how
+// their prescriptions progress. Logged workouts move a program on through
+// WorkoutHandler.
+type WorkoutProgramHandler struct {
+	programService *services.WorkoutProgramService
+}
+
+// NewWorkoutProgramHandler creates a new WorkoutProgramHandler instance
+func NewWorkoutProgramHandler(programService *services.WorkoutProgramService) *WorkoutProgramHandler {
+	return &WorkoutProgramHandler{programService: programService}
+}
+
+// AssignProgram puts the user on a workout program and schedules its
+// sessions
+// POST /api/v1/fitness/programs/assignments
+func (h *WorkoutProgramHandler) AssignProgram(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req models.AssignProgramRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	detail, err := h.programService.AssignProgram(c.Request().Context(), userID, req)
+	if err != nil {
+		return workoutProgramError(c, err, "Failed to assign program")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   detail,
+	})
+}
+
+// GetAssignments lists the programs the user has been assigned
+// GET /api/v1/fitness/programs/assignments
+func (h *WorkoutProgramHandler) GetAssignments(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	assignments, err := h.programService.ListAssignments(c.Request().Context(), userID)
+	if err != nil {
+		return workoutProgramError(c, err, "Failed to get program assignments")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   assignments,
+	})
+}
+
+// GetAssignment returns an assignment with its current targets and schedule
+// GET /api/v1/fitness/programs/assignments/:id
+func (h *WorkoutProgramHandler) GetAssignment(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	detail, err := h.programService.GetAssignment(c.Request().Context(), userID, c.Param("id"))
+	if err != nil {
+		return workoutProgramError(c, err, "Failed to get program assignment")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   detail,
+	})
+}
+
+// CancelAssignment stops following a program
+// DELETE /api/v1/fitness/programs/assignments/:id
+func (h *WorkoutProgramHandler) CancelAssignment(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := h.programService.CancelAssignment(c.Request().Context(), userID, c.Param("id")); err != nil {
+		return workoutProgramError(c, err, "Failed to cancel program assignment")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Program assignment cancelled",
+	})
+}
+
+// GetAdjustments lists the changes made to an assignment's prescriptions
+// and the rules that made them
+// GET /api/v1/fitness/programs/assignments/:id/adjustments
+func (h *WorkoutProgramHandler) GetAdjustments(c echo.Context) error {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	adjustments, err := h.programService.ListAdjustments(c.Request().Context(), userID, c.Param("id"), limit, offset)
+	if err != nil {
+		return workoutProgramError(c, err, "Failed to get progression adjustments")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   adjustments,
+	})
+}
+
+func workoutProgramError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidProgramAssignment):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrProgramAlreadyAssigned):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	switch err.Error() {
+	case "workout program not found":
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Workout program not found",
+		})
+	case "program assignment not found":
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Program assignment not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message,
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"syscall"
 	"time"
 
+	"nutrition-platform/alerting"
 	"nutrition-platform/cache"
 	config "nutrition-platform/config"
 	"nutrition-platform/database"
@@ -39,9 +40,22 @@ func main() {
 		}
 	}()
 
+	// Initialize structured logging and alerting. Every alert producer fires
+	// into the default router, which notifies the channels configured in the
+	// environment.
+	if err := InitLogging(); err != nil {
+		log.Printf("Error initializing logging: %v", err)
+	}
+	alertRouter := alerting.NewRouter(alerting.ConfigFromEnv())
+	alerting.SetDefault(alertRouter)
+	defer alertRouter.Close()
+	InitAlerts()
+
 	// Initialize services
+	services.InitializeServices(sqlDB) // meals, supplements, medical plans and sessions live in SQL
 	healthService := services.NewHealthService(sqlDB)
 	nutritionPlanService := services.NewNutritionPlanService(sqlDB)
+	userPreferencesService := services.NewUserPreferencesService(sqlDB)
 
 	// Initialize Echo instance
 	e := echo.New()
@@ -89,13 +103,13 @@ func main() {
 
 	// Cache middleware (only if Redis is available)
 	if redisCache != nil {
-		skipPaths := []string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register"}
+		skipPaths := []string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register", "/api/v1/partner/"}
 		e.Use(cache.CacheMiddleware(redisCache, 5*time.Minute, skipPaths))
 		log.Println("✅ Response caching enabled (Redis)")
 	} else {
 		// Use in-memory cache as fallback
 		cacheConfig := customMiddleware.NewCacheConfig()
-		cacheConfig.SkipPaths = []string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register"}
+		cacheConfig.SkipPaths = []string{"/health", "/metrics", "/api/v1/auth/login", "/api/v1/auth/register", "/api/v1/partner/"}
 		cacheConfig.DefaultTTL = 5 * time.Minute
 		responseCache := customMiddleware.NewResponseCache(cacheConfig)
 		e.Use(responseCache.Middleware())
@@ -117,8 +131,45 @@ func main() {
 
 	// Initialize JWT manager and auth handler
 	jwtManager := security.NewJWTManager()
-	authHandler := handlers.NewAuthHandler(nil, jwtManager) // UserService is nil for stub implementation
-	userPreferencesHandler := handlers.NewUserPreferencesHandler()
+	mailer := services.NewMailer(cfg.EmailConfig)
+	passwordResetService := services.NewPasswordResetService(sqlDB, mailer, cfg.EmailConfig)
+
+	// Refresh token reuse is recorded in the security log
+	securityLogPath := os.Getenv("SECURITY_LOG_PATH")
+	if securityLogPath == "" {
+		securityLogPath = "logs/security.log" // Default
+	}
+	var securityEvents services.SecurityEventLogger
+	securityLogger, err := security.NewSecurityLogger(securityLogPath)
+	if err != nil {
+		log.Printf("Warning: security log not available: %v", err)
+	} else {
+		defer securityLogger.Close()
+		securityEvents = securityLogger
+	}
+	refreshTokenService := services.NewRefreshTokenService(sqlDB, securityEvents)
+	mfaService := services.NewMFAService(sqlDB, cfg.MFAConfig, securityEvents)
+	customMiddleware.RequireAdminMFA(cfg.MFAConfig.RequireForAdmins)
+	authHandler := handlers.NewAuthHandler(nil, jwtManager, passwordResetService, refreshTokenService, mfaService) // UserService is nil for stub implementation
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+
+	// Coaches see client health data, so routes serving health data require
+	// them to have signed in with a second factor
+	coachMFA := customMiddleware.RequireMFAForRoles(backendmodels.RoleCoach)
+
+	// Coaches act on a client's data by adding ?client_id= to the routes
+	// wrapped in clientAccess, within what the client granted; writes are
+	// refused unless the permission is edit_meal_plans. Routes serving a
+	// user's own data that no permission covers, such as medications,
+	// notifications and reminders, are wrapped in noClientAccess instead so
+	// client_id is refused rather than ignored.
+	coachingService := services.NewCoachingService(sqlDB)
+	coachingHandler := handlers.NewCoachingHandler(coachingService)
+	clientAccess := func(permission string) echo.MiddlewareFunc {
+		return customMiddleware.ClientAccess(coachingService, permission)
+	}
+	noClientAccess := customMiddleware.RejectClientAccess()
+	userPreferencesHandler := handlers.NewUserPreferencesHandler(userPreferencesService)
 
 	// Routes
 	api := e.Group("/api/v1")
@@ -127,6 +178,7 @@ func main() {
 	auth := api.Group("/auth")
 	auth.POST("/register", authHandler.Register)
 	auth.POST("/login", authHandler.Login)
+	auth.POST("/login/mfa", authHandler.LoginMFA)
 	auth.POST("/refresh", authHandler.RefreshToken)
 	auth.POST("/logout-all", handlers.LogoutAll)
 	auth.POST("/forgot-password", authHandler.ForgotPassword)
@@ -143,75 +195,230 @@ func main() {
 	protectedAuth.PUT("/profile", authHandler.UpdateProfile)
 	protectedAuth.DELETE("/profile", authHandler.DeleteProfile)
 	protectedAuth.POST("/change-password", authHandler.ChangePassword)
+	protectedAuth.GET("/2fa", mfaHandler.GetMFAStatus)
+	protectedAuth.POST("/2fa/enroll", mfaHandler.EnrollMFA)
+	protectedAuth.POST("/2fa/confirm", mfaHandler.ConfirmMFA)
+	protectedAuth.POST("/2fa/disable", mfaHandler.DisableMFA)
+	protectedAuth.POST("/2fa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+
+	// Coach side of coach/client relationships
+	coach := api.Group("/coach")
+	coach.Use(customMiddleware.JWTAuth(), coachMFA, RoleBasedAccessControlMiddleware(backendmodels.RoleCoach))
+	coach.POST("/invitations", coachingHandler.CreateInvitation)
+	coach.GET("/clients", coachingHandler.GetClients)
+	coach.DELETE("/clients/:id", coachingHandler.EndRelationship)
+
+	// Client side: accepting invitations, managing grants, the access log
+	coaching := api.Group("/coaching")
+	coaching.Use(customMiddleware.JWTAuth())
+	coaching.POST("/invitations/accept", coachingHandler.AcceptInvitation)
+	coaching.GET("/coaches", coachingHandler.GetCoaches)
+	coaching.PUT("/coaches/:id/permissions", coachingHandler.UpdatePermissions)
+	coaching.DELETE("/coaches/:id", coachingHandler.EndRelationship)
+	coaching.GET("/access-log", coachingHandler.GetAccessLog)
 
 	// User profile routes (aliases for frontend compatibility)
 	users := api.Group("/users")
-	users.Use(customMiddleware.JWTAuth())
+	users.Use(customMiddleware.JWTAuth(), coachMFA)
 	users.GET("/profile", authHandler.GetProfile)       // Alias for /auth/profile
 	users.PUT("/profile", authHandler.UpdateProfile)    // Alias for /auth/profile
 	users.DELETE("/account", authHandler.DeleteProfile) // Alias for /auth/profile (account deletion)
-	users.GET("/preferences", userPreferencesHandler.GetPreferences)
-	users.PUT("/preferences", userPreferencesHandler.UpdatePreferences)
+	users.GET("/preferences", userPreferencesHandler.GetPreferences, noClientAccess)
+	users.PUT("/preferences", userPreferencesHandler.UpdatePreferences, noClientAccess)
+	users.PATCH("/preferences", userPreferencesHandler.UpdatePreferences, noClientAccess)
+
+	// API key management for partner integrations
+	apiKeyService := services.NewAPIKeyService(sqlDB)
+	analyticsService := services.NewAnalyticsService(sqlDB)
+	defer analyticsService.Stop()
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, analyticsService)
+	apiKeys := api.Group("/api-keys")
+	apiKeys.Use(customMiddleware.JWTAuth())
+	apiKeys.GET("", apiKeyHandler.GetAPIKeys)
+	apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+	apiKeys.GET("/:id", apiKeyHandler.GetAPIKey)
+	apiKeys.PUT("/:id", apiKeyHandler.UpdateAPIKey)
+	apiKeys.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
+	apiKeys.POST("/:id/regenerate", apiKeyHandler.RegenerateAPIKey)
+	apiKeys.GET("/:id/usage", apiKeyHandler.GetAPIKeyUsage)
+	apiKeys.GET("/:id/alerts", apiKeyHandler.GetAPIKeyAlerts)
+	apiKeys.POST("/:id/alerts", apiKeyHandler.CreateAPIKeyAlert)
+	apiKeys.DELETE("/:id/alerts/:alertId", apiKeyHandler.DeleteAPIKeyAlert)
 
 	// Food CRUD endpoints
 	foodHandler := handlers.NewFoodHandler(sqlDB)
 	nutritionAPI := api.Group("/nutrition")
-	nutritionAPI.Use(customMiddleware.JWTAuth())
-	nutritionAPI.GET("/foods", foodHandler.GetFoods)
-	nutritionAPI.GET("/foods/search", foodHandler.SearchFoods)
-	nutritionAPI.GET("/foods/:id", foodHandler.GetFood)
-	nutritionAPI.POST("/foods", foodHandler.CreateFood)
-	nutritionAPI.PUT("/foods/:id", foodHandler.UpdateFood)
-	nutritionAPI.DELETE("/foods/:id", foodHandler.DeleteFood)
+	nutritionAPI.Use(customMiddleware.JWTAuth(), coachMFA)
+	nutritionAPI.GET("/foods", foodHandler.GetFoods, noClientAccess)
+	nutritionAPI.GET("/foods/search", foodHandler.SearchFoods, noClientAccess)
+	nutritionAPI.GET("/foods/barcode/:code", foodHandler.GetFoodByBarcode, noClientAccess)
+	nutritionAPI.GET("/foods/:id", foodHandler.GetFood, noClientAccess)
+	nutritionAPI.POST("/foods", foodHandler.CreateFood, noClientAccess)
+	nutritionAPI.PUT("/foods/:id", foodHandler.UpdateFood, noClientAccess)
+	nutritionAPI.DELETE("/foods/:id", foodHandler.DeleteFood, noClientAccess)
+	nutritionAPI.GET("/foods/:id/portions", foodHandler.GetFoodPortions, noClientAccess)
+	nutritionAPI.PUT("/foods/:id/portions", foodHandler.SetFoodPortion, noClientAccess)
+
+	// Partner access to the food catalog with API keys (X-API-Key header)
+	partner := api.Group("/partner")
+	partner.Use(customMiddleware.APIKeyAuth(apiKeyService, analyticsService, nil))
+	partnerNutrition := partner.Group("/nutrition", customMiddleware.RequireAPIKeyScopes(backendmodels.ScopeNutrition))
+	partnerNutrition.GET("/foods", foodHandler.GetFoods)
+	partnerNutrition.GET("/foods/search", foodHandler.SearchFoods)
+	partnerNutrition.GET("/foods/barcode/:code", foodHandler.GetFoodByBarcode)
+	partnerNutrition.GET("/foods/:id", foodHandler.GetFood)
+
+	// Outbound webhooks for user activity events, managed by users or by
+	// partners with an API key holding the webhooks scope; read-only keys
+	// can list subscriptions and deliveries but not change or replay them
+	webhookService := services.NewWebhookService(sqlDB, cfg.WebhookConfig)
+	services.SetDefaultWebhookService(webhookService)
+	// Logged meals, water, weigh-ins, workouts and photos keep streaks and
+	// award badges
+	achievementService := services.NewAchievementService(sqlDB)
+	services.SetDefaultAchievementService(achievementService)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	webhookService.Start(workerCtx)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	for _, webhooks := range []*echo.Group{
+		api.Group("/webhooks", customMiddleware.JWTAuth()),
+		partner.Group("/webhooks", customMiddleware.RequireAPIKeyScopes(backendmodels.ScopeWebhooks)),
+	} {
+		webhooks.GET("/events", webhookHandler.GetEventTypes)
+		webhooks.GET("", webhookHandler.GetWebhooks)
+		webhooks.POST("", webhookHandler.CreateWebhook)
+		webhooks.GET("/:id", webhookHandler.GetWebhook)
+		webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+		webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+		webhooks.POST("/:id/deliveries/:deliveryId/replay", webhookHandler.ReplayDelivery)
+		webhooks.POST("/:id/replay", webhookHandler.ReplayDeadLetters)
+	}
+
+	// Reminders are sent at the user's local time through the notification
+	// channels: the in-app inbox always, email unless switched off
+	notificationService := services.NewNotificationService(sqlDB)
+	notificationService.RegisterChannel(services.NewEmailChannel(sqlDB, mailer))
+	reminderService := services.NewReminderService(sqlDB, notificationService, cfg.ReminderConfig)
+	reminderService.Start(workerCtx)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	reminderHandler := handlers.NewReminderHandler(reminderService)
+	notifications := api.Group("/notifications", customMiddleware.JWTAuth(), noClientAccess)
+	notifications.GET("", notificationHandler.GetNotifications)
+	notifications.POST("/read-all", notificationHandler.MarkAllNotificationsRead)
+	notifications.POST("/:id/read", notificationHandler.MarkNotificationRead)
+	notifications.DELETE("/:id", notificationHandler.DeleteNotification)
+	reminders := api.Group("/reminders", customMiddleware.JWTAuth(), noClientAccess)
+	reminders.GET("/types", reminderHandler.GetReminderTypes)
+	reminders.GET("", reminderHandler.GetReminders)
+	reminders.POST("", reminderHandler.CreateReminder)
+	reminders.PUT("/:id", reminderHandler.UpdateReminder)
+	reminders.DELETE("/:id", reminderHandler.DeleteReminder)
 
 	// Nutrition Goals endpoints
 	nutritionGoalHandler := handlers.NewNutritionGoalHandler(sqlDB)
-	nutritionAPI.GET("/goals", nutritionGoalHandler.GetGoals)
-	nutritionAPI.GET("/goals/:id", nutritionGoalHandler.GetGoal)
-	nutritionAPI.POST("/goals", nutritionGoalHandler.CreateGoal)
-	nutritionAPI.PUT("/goals/:id", nutritionGoalHandler.UpdateGoal)
-	nutritionAPI.DELETE("/goals/:id", nutritionGoalHandler.DeleteGoal)
+	nutritionAPI.GET("/goals", nutritionGoalHandler.GetGoals, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.GET("/goals/:id", nutritionGoalHandler.GetGoal, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.POST("/goals", nutritionGoalHandler.CreateGoal, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.PUT("/goals/:id", nutritionGoalHandler.UpdateGoal, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.DELETE("/goals/:id", nutritionGoalHandler.DeleteGoal, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
 
 	// Weight tracking endpoints
 	weightHandler := handlers.NewWeightHandler(sqlDB)
-	nutritionAPI.GET("/weight", weightHandler.GetWeightHistory)
-	nutritionAPI.POST("/weight", weightHandler.LogWeight)
-	nutritionAPI.GET("/weight/:id", weightHandler.GetWeightLog)
-	nutritionAPI.PUT("/weight/:id", weightHandler.UpdateWeightLog)
-	nutritionAPI.DELETE("/weight/:id", weightHandler.DeleteWeightLog)
+	nutritionAPI.GET("/weight", weightHandler.GetWeightHistory, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	nutritionAPI.POST("/weight", weightHandler.LogWeight, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	nutritionAPI.GET("/weight/:id", weightHandler.GetWeightLog, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	nutritionAPI.PUT("/weight/:id", weightHandler.UpdateWeightLog, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	nutritionAPI.DELETE("/weight/:id", weightHandler.DeleteWeightLog, clientAccess(backendmodels.CoachPermissionReadMeasurements))
 
 	// Meal endpoints route aliases (frontend expects /nutrition/meals)
-	nutritionAPI.GET("/meals", handlers.GetMealsAPI)
-	nutritionAPI.POST("/meals", handlers.CreateMealAPI)
-	nutritionAPI.GET("/meals/:id", handlers.GetMealAPI)
-	nutritionAPI.PUT("/meals/:id", handlers.UpdateMealAPI)
-	nutritionAPI.DELETE("/meals/:id", handlers.DeleteMealAPI)
+	nutritionAPI.GET("/meals", handlers.GetMealsAPI, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.POST("/meals", handlers.CreateMealAPI, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.GET("/meals/:id", handlers.GetMealAPI, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.PUT("/meals/:id", handlers.UpdateMealAPI, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.DELETE("/meals/:id", handlers.DeleteMealAPI, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
 
 	// Water intake endpoints
 	waterIntakeHandler := handlers.NewWaterIntakeHandler(sqlDB)
-	nutritionAPI.POST("/water", waterIntakeHandler.LogWater)
-	nutritionAPI.GET("/water", waterIntakeHandler.GetWaterIntake)
+	nutritionAPI.POST("/water", waterIntakeHandler.LogWater, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.GET("/water", waterIntakeHandler.GetWaterIntake, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+
+	// Daily nutrition ledger (meals, foods, recipes and water against goals)
+	nutritionLedgerHandler := handlers.NewNutritionLedgerHandler(sqlDB)
+	nutritionAPI.GET("/ledger", nutritionLedgerHandler.GetDailyLedger, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+
+	// Adaptive TDEE from logged intake and the weight trend
+	energyHandler := handlers.NewEnergyExpenditureHandler(services.NewEnergyExpenditureService(sqlDB))
+	nutritionAPI.GET("/tdee", energyHandler.GetTDEE, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	nutritionAPI.POST("/goals/adaptive", energyHandler.ApplyAdaptiveGoal, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+
+	// Recipe endpoints; nutrition is computed from the ingredients on save
+	recipeHandler := handlers.NewRecipeHandler(services.NewRecipeService(sqlDB))
+	nutritionAPI.POST("/recipes", recipeHandler.CreateRecipe, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.GET("/recipes/:id", recipeHandler.GetRecipe, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.PUT("/recipes/:id", recipeHandler.UpdateRecipe, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.DELETE("/recipes/:id", recipeHandler.DeleteRecipe, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.GET("/recipes/:id/nutrition", recipeHandler.GetRecipeNutrition, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+
+	// Shopping lists built from meal plans and recipes
+	shoppingListHandler := handlers.NewShoppingListHandler(services.NewShoppingListService(sqlDB))
+	nutritionAPI.POST("/shopping-lists", shoppingListHandler.CreateShoppingList, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.GET("/shopping-lists", shoppingListHandler.GetShoppingLists, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.GET("/shopping-lists/:id", shoppingListHandler.GetShoppingList, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.PATCH("/shopping-lists/:id/items/:itemId", shoppingListHandler.UpdateShoppingListItem, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.GET("/shopping-lists/:id/export", shoppingListHandler.ExportShoppingList, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.DELETE("/shopping-lists/:id", shoppingListHandler.DeleteShoppingList, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+
+	// Medications, supplements and drug–nutrient interaction checks
+	drugInteractionHandler := handlers.NewDrugInteractionHandler(services.NewDrugInteractionService(sqlDB, nil))
+	nutritionAPI.POST("/medications", drugInteractionHandler.AddMedication, noClientAccess)
+	nutritionAPI.POST("/supplements", drugInteractionHandler.AddSupplement, noClientAccess)
+	nutritionAPI.GET("/interactions", drugInteractionHandler.GetInteractionReport, noClientAccess)
+
+	// Stored meal plans, editable by coaches granted edit_meal_plans
+	nutritionService := services.NewNutritionService(sqlDB, cfg.ExportConfig)
+	nutritionHandler := handlers.NewNutritionHandler(nutritionService)
+	nutritionAPI.GET("/meal-plans", nutritionHandler.GetMealPlans, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.GET("/meal-plans/:id", nutritionHandler.GetMealPlan, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.PATCH("/meal-plans/:id", nutritionHandler.UpdateMealPlan, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.DELETE("/meal-plans/:id", nutritionHandler.DeleteMealPlan, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	nutritionAPI.GET("/meal-plans/:id/pdf", nutritionHandler.GenerateMealPlanPDF, clientAccess(backendmodels.CoachPermissionEditMealPlans))
 
 	// Fitness endpoints (exercises and workouts)
 	exerciseHandler := handlers.NewExerciseHandler(sqlDB)
-	workoutHandler := handlers.NewWorkoutHandler(sqlDB)
+	personalRecordService := services.NewPersonalRecordService(sqlDB, notificationService)
+	workoutHandler := handlers.NewWorkoutHandler(sqlDB, personalRecordService)
 	fitness := api.Group("/fitness")
-	fitness.Use(customMiddleware.JWTAuth())
+	fitness.Use(customMiddleware.JWTAuth(), coachMFA)
 
 	// Exercise CRUD endpoints
-	fitness.GET("/exercises", exerciseHandler.GetExercises)
-	fitness.GET("/exercises/search", exerciseHandler.SearchExercises)
-	fitness.GET("/exercises/:id", exerciseHandler.GetExercise)
-	fitness.POST("/exercises", exerciseHandler.CreateExercise)
-	fitness.PUT("/exercises/:id", exerciseHandler.UpdateExercise)
-	fitness.DELETE("/exercises/:id", exerciseHandler.DeleteExercise)
+	fitness.GET("/exercises", exerciseHandler.GetExercises, noClientAccess)
+	fitness.GET("/exercises/search", exerciseHandler.SearchExercises, noClientAccess)
+	fitness.GET("/exercises/:id", exerciseHandler.GetExercise, noClientAccess)
+	fitness.POST("/exercises", exerciseHandler.CreateExercise, noClientAccess)
+	fitness.PUT("/exercises/:id", exerciseHandler.UpdateExercise, noClientAccess)
+	fitness.DELETE("/exercises/:id", exerciseHandler.DeleteExercise, noClientAccess)
 
 	// Workout logging endpoints
-	fitness.POST("/workouts", workoutHandler.LogWorkout)
-	fitness.GET("/workouts", workoutHandler.GetWorkouts)
-	fitness.GET("/workouts/:id", workoutHandler.GetWorkout)
-	fitness.PUT("/workouts/:id", workoutHandler.UpdateWorkout)
-	fitness.DELETE("/workouts/:id", workoutHandler.DeleteWorkout)
+	fitness.POST("/workouts", workoutHandler.LogWorkout, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.GET("/workouts", workoutHandler.GetWorkouts, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.GET("/workouts/:id", workoutHandler.GetWorkout, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.PUT("/workouts/:id", workoutHandler.UpdateWorkout, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.DELETE("/workouts/:id", workoutHandler.DeleteWorkout, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+
+	// Personal records detected in completed workouts
+	personalRecordHandler := handlers.NewPersonalRecordHandler(personalRecordService)
+	fitness.GET("/personal-records", personalRecordHandler.GetPersonalRecords, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.GET("/personal-records/stats", personalRecordHandler.GetPersonalRecordStats, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+
+	// Program assignments; logged workouts progress them
+	workoutProgramHandler := handlers.NewWorkoutProgramHandler(services.NewWorkoutProgramService(sqlDB))
+	fitness.POST("/programs/assignments", workoutProgramHandler.AssignProgram, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.GET("/programs/assignments", workoutProgramHandler.GetAssignments, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.GET("/programs/assignments/:id", workoutProgramHandler.GetAssignment, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.DELETE("/programs/assignments/:id", workoutProgramHandler.CancelAssignment, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	fitness.GET("/programs/assignments/:id/adjustments", workoutProgramHandler.GetAdjustments, clientAccess(backendmodels.CoachPermissionReadWorkouts))
 
 	// Admin auth routes (require JWT authentication)
 	adminAuth := api.Group("/auth/admin")
@@ -221,9 +428,19 @@ func main() {
 	adminAuth.DELETE("/users/:id", authHandler.DeleteUser)
 	adminAuth.GET("/audit-logs", authHandler.GetAuditLogs)
 
+	// Alert routing administration
+	alertHandler := handlers.NewAlertHandler(alertRouter)
+	adminAlerts := api.Group("/admin/alerts")
+	adminAlerts.Use(customMiddleware.JWTAuth())
+	adminAlerts.Use(customMiddleware.AdminAuth())
+	adminAlerts.GET("/stats", alertHandler.GetAlertStats)
+	adminAlerts.GET("/silences", alertHandler.GetSilences)
+	adminAlerts.POST("/silences", alertHandler.CreateSilence)
+	adminAlerts.DELETE("/silences/:id", alertHandler.DeleteSilence)
+
 	// Protected routes (require JWT authentication)
 	protected := api.Group("")
-	protected.Use(customMiddleware.JWTAuth())
+	protected.Use(customMiddleware.JWTAuth(), coachMFA)
 	protected.GET("/dashboard", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"message": "Welcome to protected dashboard",
@@ -303,43 +520,54 @@ func main() {
 	// Progress tracking endpoints
 	measurementsHandler := handlers.NewMeasurementsHandler(sqlDB)
 	progress := api.Group("/progress")
-	progress.Use(customMiddleware.JWTAuth())
-	progress.GET("/measurements", measurementsHandler.GetMeasurements)
-	progress.POST("/measurements", measurementsHandler.LogMeasurement)
-	progress.GET("/measurements/:id", measurementsHandler.GetMeasurement)
-	progress.PUT("/measurements/:id", measurementsHandler.UpdateMeasurement)
-	progress.DELETE("/measurements/:id", measurementsHandler.DeleteMeasurement)
+	progress.Use(customMiddleware.JWTAuth(), coachMFA)
+	progress.GET("/measurements", measurementsHandler.GetMeasurements, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.POST("/measurements", measurementsHandler.LogMeasurement, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.GET("/measurements/:id", measurementsHandler.GetMeasurement, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.PUT("/measurements/:id", measurementsHandler.UpdateMeasurement, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.DELETE("/measurements/:id", measurementsHandler.DeleteMeasurement, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+
+	// Streaks and badges follow from logging; milestones are set here
+	achievementHandler := handlers.NewAchievementHandler(achievementService)
+	progress.GET("/achievements", achievementHandler.GetAchievements, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.GET("/achievements/upcoming", achievementHandler.GetUpcomingAchievements, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.GET("/consistency", achievementHandler.GetConsistency, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.GET("/milestones", achievementHandler.GetMilestones, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.POST("/milestones", achievementHandler.CreateMilestone, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.POST("/milestones/:id/achieve", achievementHandler.AchieveMilestone, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	progress.DELETE("/milestones/:id", achievementHandler.DeleteMilestone, clientAccess(backendmodels.CoachPermissionReadMeasurements))
 
 	// ============================================
 	// ACTION-ORIENTED API ENDPOINTS
 	// Users interact with these via buttons/actions
 	// ============================================
 	actions := api.Group("/actions")
-	actions.Use(customMiddleware.JWTAuth())
+	actions.Use(customMiddleware.JWTAuth(), coachMFA)
 
 	// Progress tracking actions
 	progressActionsHandler := handlers.NewProgressActionsHandler(sqlDB)
-	actions.POST("/track-measurement", progressActionsHandler.TrackMeasurement)
-	actions.GET("/progress-summary", progressActionsHandler.GetProgressSummary)
-	actions.GET("/measurement-history", progressActionsHandler.GetMeasurementHistory)
-	actions.GET("/progress-charts", progressActionsHandler.GetProgressCharts)
-	actions.POST("/compare-measurements", progressActionsHandler.CompareMeasurements)
-	actions.POST("/upload-progress-photo", progressActionsHandler.UploadProgressPhoto)
-	actions.GET("/photo-history", progressActionsHandler.GetPhotoHistory)
+	actions.POST("/track-measurement", progressActionsHandler.TrackMeasurement, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	actions.GET("/progress-summary", progressActionsHandler.GetProgressSummary, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	actions.GET("/measurement-history", progressActionsHandler.GetMeasurementHistory, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	actions.GET("/progress-charts", progressActionsHandler.GetProgressCharts, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	actions.GET("/weight-forecast", progressActionsHandler.GetWeightForecast, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	actions.POST("/compare-measurements", progressActionsHandler.CompareMeasurements, clientAccess(backendmodels.CoachPermissionReadMeasurements))
+	actions.POST("/upload-progress-photo", progressActionsHandler.UploadProgressPhoto, clientAccess(backendmodels.CoachPermissionViewProgressPhotos))
+	actions.GET("/photo-history", progressActionsHandler.GetPhotoHistory, clientAccess(backendmodels.CoachPermissionViewProgressPhotos))
 
 	// Nutrition actions
 	nutritionActionsHandler := handlers.NewNutritionActionsHandler(sqlDB)
-	actions.POST("/generate-meal-plan", nutritionActionsHandler.GenerateMealPlan)
-	actions.POST("/log-meal", nutritionActionsHandler.LogMeal)
-	actions.GET("/nutrition-summary", nutritionActionsHandler.GetNutritionSummary)
-	actions.GET("/meal-recommendations", nutritionActionsHandler.GetMealRecommendations)
+	actions.POST("/generate-meal-plan", nutritionActionsHandler.GenerateMealPlan, clientAccess(backendmodels.CoachPermissionEditMealPlans))
+	actions.POST("/log-meal", nutritionActionsHandler.LogMeal, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	actions.GET("/nutrition-summary", nutritionActionsHandler.GetNutritionSummary, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
+	actions.GET("/meal-recommendations", nutritionActionsHandler.GetMealRecommendations, clientAccess(backendmodels.CoachPermissionReadFoodLogs))
 
 	// Fitness actions
 	fitnessActionsHandler := handlers.NewFitnessActionsHandler(db)
-	actions.POST("/generate-workout", fitnessActionsHandler.GenerateWorkout)
-	actions.POST("/log-workout", fitnessActionsHandler.LogWorkout)
-	actions.GET("/fitness-summary", fitnessActionsHandler.GetFitnessSummary)
-	actions.GET("/workout-recommendations", fitnessActionsHandler.GetWorkoutRecommendations)
+	actions.POST("/generate-workout", fitnessActionsHandler.GenerateWorkout, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	actions.POST("/log-workout", fitnessActionsHandler.LogWorkout, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	actions.GET("/fitness-summary", fitnessActionsHandler.GetFitnessSummary, clientAccess(backendmodels.CoachPermissionReadWorkouts))
+	actions.GET("/workout-recommendations", fitnessActionsHandler.GetWorkoutRecommendations, clientAccess(backendmodels.CoachPermissionReadWorkouts))
 
 	// Validation endpoints
 	validation := api.Group("/validation")
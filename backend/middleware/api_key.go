@@ -1,3 +1,168 @@
 package middleware
 
-// API Key middleware stub - to be implemented in Priority 2
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyContextKey is the echo context key holding the authenticated *models.APIKey
+const APIKeyContextKey = "api_key"
+
+// APIKeyAuth authenticates requests carrying an API key in the X-API-Key
+// header or as a bearer token. It enforces the key's per-minute rate limit
+// through store (in memory when nil) and its daily quota, and logs each
+// request, including those rejected with 429, with analyticsService for the
+// key's usage dashboard and alerts.
+// The key's owner becomes the request's user_id.
+func APIKeyAuth(apiKeyService *services.APIKeyService, analyticsService *services.AnalyticsService, store RateLimiterStore) echo.MiddlewareFunc {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			rawKey := apiKeyFromRequest(c.Request())
+			if rawKey == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "API key required",
+				})
+			}
+
+			ctx := c.Request().Context()
+			key, err := apiKeyService.AuthenticateAPIKey(ctx, rawKey)
+			if err != nil {
+				switch {
+				case errors.Is(err, services.ErrInvalidAPIKey),
+					errors.Is(err, services.ErrAPIKeyInactive),
+					errors.Is(err, services.ErrAPIKeyExpired):
+					return c.JSON(http.StatusUnauthorized, map[string]string{
+						"error": err.Error(),
+					})
+				}
+				c.Logger().Error("API key authentication failed:", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to authenticate API key",
+				})
+			}
+
+			start := time.Now()
+			recordUsage := func(status int) {
+				// Record the route pattern so /foods/1 and /foods/2 count as one endpoint
+				endpoint := c.Path()
+				if endpoint == "" {
+					endpoint = c.Request().URL.Path
+				}
+				analyticsService.RecordAPIUsage(key.ID, endpoint, c.Request().Method, status,
+					time.Since(start).Milliseconds(), c.RealIP(), c.Request().UserAgent())
+			}
+
+			allowed, count, resetTime, err := store.Allow(ctx, "apikey:"+key.ID, key.RateLimit, time.Minute)
+			if err != nil {
+				// If store fails, log error but allow request
+				c.Logger().Error("Rate limiter store error:", err)
+			} else {
+				remaining := key.RateLimit - count
+				if remaining < 0 {
+					remaining = 0
+				}
+				c.Response().Header().Set("X-RateLimit-Limit", strconv.Itoa(key.RateLimit))
+				c.Response().Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+				c.Response().Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
+				if !allowed {
+					recordUsage(http.StatusTooManyRequests)
+					return c.JSON(http.StatusTooManyRequests, map[string]string{
+						"error": "Rate limit exceeded",
+					})
+				}
+			}
+
+			quota, err := apiKeyService.ConsumeQuota(ctx, key)
+			if err != nil && !errors.Is(err, services.ErrAPIKeyQuotaExceeded) {
+				c.Logger().Error("API key quota error:", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to check API key quota",
+				})
+			}
+			if quota.Limit > 0 {
+				c.Response().Header().Set("X-Quota-Limit", strconv.Itoa(quota.Limit))
+				c.Response().Header().Set("X-Quota-Remaining", strconv.Itoa(quota.Remaining))
+				c.Response().Header().Set("X-Quota-Reset", strconv.FormatInt(quota.ResetAt.Unix(), 10))
+			}
+			if err != nil {
+				retryAfter := int(time.Until(quota.ResetAt).Seconds()) + 1
+				c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				recordUsage(http.StatusTooManyRequests)
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"error": err.Error(),
+				})
+			}
+
+			c.Set(APIKeyContextKey, key)
+			c.Set("api_key_id", key.ID)
+			c.Set("user_id", key.UserID)
+
+			err = next(c)
+
+			status := c.Response().Status
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			} else if err != nil {
+				status = http.StatusInternalServerError
+			}
+			recordUsage(status)
+
+			return err
+		}
+	}
+}
+
+// RequireAPIKeyScopes rejects requests whose API key lacks any of the
+// scopes, or lacks read_write for a write method. It must run after
+// APIKeyAuth.
+func RequireAPIKeyScopes(scopes ...models.APIKeyScope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key, ok := c.Get(APIKeyContextKey).(*models.APIKey)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "API key required",
+				})
+			}
+
+			for _, scope := range scopes {
+				if !key.HasScope(scope) {
+					return c.JSON(http.StatusForbidden, map[string]string{
+						"error": "API key lacks the " + string(scope) + " scope",
+					})
+				}
+			}
+			if !key.AllowsMethod(c.Request().Method) {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "API key does not allow " + c.Request().Method + " requests",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// apiKeyFromRequest reads the key from X-API-Key or an Authorization bearer token
+func apiKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
@@ -14,6 +14,11 @@ type Claims struct {
 	Email   string `json:"email"`
 	Role    string `json:"role"`
 	IsAdmin bool   `json:"is_admin"`
+	// MFA is set when the user signed in with a second factor
+	MFA bool `json:"mfa,omitempty"`
+	// Purpose restricts a token to one step, such as mfaPendingPurpose.
+	// Access tokens have none.
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -53,7 +58,7 @@ func JWTAuth() echo.MiddlewareFunc {
 			}
 
 			claims, ok := token.Claims.(*Claims)
-			if !ok {
+			if !ok || claims.Purpose != "" {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": "Invalid token claims",
 				})
@@ -61,14 +66,18 @@ func JWTAuth() echo.MiddlewareFunc {
 
 			// Set user context
 			c.Set("user_id", claims.UserID)
+			c.Set("email", claims.Email)
+			c.Set("role", claims.Role)
 			c.Set("is_admin", claims.IsAdmin)
+			c.Set("mfa", claims.MFA)
 
 			return next(c)
 		}
 	}
 }
 
-// AdminAuth middleware for admin-only routes
+// AdminAuth middleware for admin-only routes. With RequireAdminMFA set,
+// admins must also have signed in with a second factor.
 func AdminAuth() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -78,6 +87,9 @@ func AdminAuth() echo.MiddlewareFunc {
 					"error": "Admin access required",
 				})
 			}
+			if adminMFARequired.Load() && !mfaVerified(c) {
+				return mfaRequired(c)
+			}
 			return next(c)
 		}
 	}
@@ -85,11 +97,18 @@ func AdminAuth() echo.MiddlewareFunc {
 
 // GenerateToken generates a JWT token for a user
 func GenerateToken(userID, email, role string, isAdmin bool) (string, error) {
+	return GenerateTokenWithMFA(userID, email, role, isAdmin, false)
+}
+
+// GenerateTokenWithMFA generates a JWT token recording whether the user
+// signed in with a second factor
+func GenerateTokenWithMFA(userID, email, role string, isAdmin, mfa bool) (string, error) {
 	claims := &Claims{
 		UserID:  userID,
 		Email:   email,
 		Role:    role,
 		IsAdmin: isAdmin,
+		MFA:     mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
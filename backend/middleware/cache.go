@@ -61,7 +61,7 @@ func NewCacheConfig() *CacheConfig {
 		MaxSize:           1000,
 		SkipMethods:       []string{"POST", "PUT", "DELETE", "PATCH"},
 		SkipPaths:         []string{"/health", "/metrics"},
-		VaryByHeaders:     []string{"Authorization", "Accept-Language"},
+		VaryByHeaders:     []string{"Authorization", "X-API-Key", "Accept-Language"},
 		CompressResponses: false,
 	}
 }
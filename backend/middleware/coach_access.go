@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClientAccessContextKey holds the coach's user ID while a coach is acting
+// on a client's data; user_id is then the client's
+const ClientAccessContextKey = "acting_coach_id"
+
+// ClientAccess lets a coach use a route on behalf of a client by adding
+// ?client_id= to the request. The client must have granted permission to
+// the coach; permissions other than edit_meal_plans only allow reads. Every
+// attempt is written to the client's access log before the handler runs,
+// and the request fails if it cannot be. Requests without client_id are
+// passed through unchanged. It must run after JWTAuth.
+func ClientAccess(coachingService *services.CoachingService, permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			clientID := c.QueryParam("client_id")
+			if clientID == "" {
+				return next(c)
+			}
+
+			coachID, _ := c.Get("user_id").(string)
+			role, _ := c.Get("role").(string)
+			if coachID == "" || role != models.RoleCoach {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Only coaches can access client data",
+				})
+			}
+
+			ctx := c.Request().Context()
+			allowed := false
+			if isReadMethod(c.Request().Method) || permission == models.CoachPermissionEditMealPlans {
+				var err error
+				allowed, err = coachingService.HasPermission(ctx, coachID, clientID, permission)
+				if err != nil {
+					c.Logger().Error("Coach permission check failed:", err)
+					return c.JSON(http.StatusInternalServerError, map[string]string{
+						"error": "Failed to check client access",
+					})
+				}
+			}
+
+			entry := &models.CoachAccessLogEntry{
+				CoachID:    coachID,
+				ClientID:   clientID,
+				Permission: permission,
+				Method:     c.Request().Method,
+				Path:       c.Request().URL.Path,
+				Allowed:    allowed,
+				IPAddress:  c.RealIP(),
+			}
+			if err := coachingService.RecordAccess(ctx, entry); err != nil {
+				c.Logger().Error("Failed to record coach access:", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to check client access",
+				})
+			}
+
+			if !allowed {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error":      "Client has not granted this permission",
+					"permission": permission,
+				})
+			}
+
+			c.Set(ClientAccessContextKey, coachID)
+			c.Set("user_id", clientID)
+			return next(c)
+		}
+	}
+}
+
+// RejectClientAccess refuses requests carrying ?client_id= on routes that
+// no coach permission covers, so a coach is never silently served their own
+// data instead of the client's.
+func RejectClientAccess() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.QueryParam("client_id") != "" {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Client data is not available on this route",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
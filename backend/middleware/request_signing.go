@@ -2,8 +2,6 @@ package middleware
 
 import (
 	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"nutrition-platform/security"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -129,24 +129,7 @@ func RequestSigningMiddleware(config RequestSigningConfig) echo.MiddlewareFunc {
 
 // generateSignature creates HMAC-SHA256 signature for the request
 func generateSignature(secretKey, method, path, timestamp, body string, headers map[string]string) string {
-	// Create canonical string
-	var canonicalParts []string
-	canonicalParts = append(canonicalParts, method)
-	canonicalParts = append(canonicalParts, path)
-	canonicalParts = append(canonicalParts, timestamp)
-	canonicalParts = append(canonicalParts, body)
-
-	// Add headers in sorted order
-	for key, value := range headers {
-		canonicalParts = append(canonicalParts, fmt.Sprintf("%s:%s", key, value))
-	}
-
-	canonicalString := strings.Join(canonicalParts, "\n")
-
-	// Generate HMAC-SHA256
-	h := hmac.New(sha256.New, []byte(secretKey))
-	h.Write([]byte(canonicalString))
-	return hex.EncodeToString(h.Sum(nil))
+	return security.SignRequest(secretKey, method, path, timestamp, body, headers)
 }
 
 // extractRequiredHeaders extracts specified headers from request
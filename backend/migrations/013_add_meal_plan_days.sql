@@ -0,0 +1,3 @@
+-- Migration: Store per-day meals and Arabic name on meal plans
+ALTER TABLE meal_plans ADD COLUMN name_ar TEXT;
+ALTER TABLE meal_plans ADD COLUMN days TEXT NOT NULL DEFAULT '[]';
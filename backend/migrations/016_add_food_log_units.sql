@@ -0,0 +1,23 @@
+-- Migration: Unit-aware food logging
+-- user_food_logs.quantity is always grams; the entered amount is kept alongside
+ALTER TABLE user_food_logs ADD COLUMN input_quantity REAL;
+ALTER TABLE user_food_logs ADD COLUMN input_unit TEXT;
+ALTER TABLE user_food_logs ADD COLUMN fiber REAL;
+ALTER TABLE user_food_logs ADD COLUMN sugar REAL;
+ALTER TABLE user_food_logs ADD COLUMN sodium REAL;
+ALTER TABLE user_food_logs ADD COLUMN notes TEXT;
+
+-- Gram weights of household units for individual foods
+CREATE TABLE IF NOT EXISTS food_portions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    food_id INTEGER NOT NULL,
+    unit TEXT NOT NULL,
+    grams REAL NOT NULL,
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (food_id, unit),
+    FOREIGN KEY (food_id) REFERENCES foods (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_food_portions_food_id ON food_portions(food_id);
+CREATE INDEX IF NOT EXISTS idx_user_food_logs_consumed_at ON user_food_logs(consumed_at);
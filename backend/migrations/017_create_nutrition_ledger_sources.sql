@@ -0,0 +1,36 @@
+-- Migration: Tables read by the daily nutrition ledger
+-- water_intake and nutrition_goals were previously created outside the migration set
+CREATE TABLE IF NOT EXISTS water_intake (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    amount_ml INTEGER NOT NULL,
+    date DATETIME NOT NULL,
+    notes TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_water_intake_user_date ON water_intake(user_id, date);
+
+CREATE TABLE IF NOT EXISTS nutrition_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    daily_calories INTEGER,
+    protein_grams REAL,
+    carbs_grams REAL,
+    fat_grams REAL,
+    fiber_grams REAL,
+    sugar_grams REAL,
+    sodium_mg INTEGER,
+    water_ml INTEGER,
+    is_active BOOLEAN NOT NULL DEFAULT 1,
+    start_date DATETIME,
+    end_date DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_nutrition_goals_user_active ON nutrition_goals(user_id, is_active);
+
+-- Recipes can be logged by the serving alongside individual foods
+ALTER TABLE user_food_logs ADD COLUMN recipe_id TEXT;
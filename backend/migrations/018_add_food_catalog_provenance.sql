@@ -0,0 +1,9 @@
+-- Migration: Imported product catalog
+-- Foods imported from product databases (e.g. Open Food Facts) use
+-- source_type 'catalog', record where they came from and stay unverified
+-- unless the source checked them
+ALTER TABLE foods ADD COLUMN data_source TEXT;
+ALTER TABLE foods ADD COLUMN imported_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_foods_bar_code ON foods(bar_code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_foods_catalog_bar_code ON foods(bar_code) WHERE source_type = 'catalog';
@@ -0,0 +1,108 @@
+-- Migration: Create meals, supplements, medical_plans and sessions tables
+-- These replace the JSON file store; ids are the UUIDs it generated so imported rows keep them
+-- Types are limited to ones SQLite and PostgreSQL both accept.
+CREATE TABLE IF NOT EXISTS meals (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    calories INTEGER NOT NULL DEFAULT 0,
+    protein DOUBLE PRECISION NOT NULL DEFAULT 0,
+    carbs DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fat DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fiber DOUBLE PRECISION NOT NULL DEFAULT 0,
+    sugar DOUBLE PRECISION NOT NULL DEFAULT 0,
+    sodium DOUBLE PRECISION NOT NULL DEFAULT 0,
+    meal_type TEXT NOT NULL DEFAULT 'snack',
+    ingredients TEXT NOT NULL DEFAULT '[]',
+    is_halal BOOLEAN NOT NULL DEFAULT TRUE,
+    tags TEXT NOT NULL DEFAULT '[]',
+    image_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_meals_user_created ON meals(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS supplements (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    brand TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL DEFAULT 'other',
+    form TEXT NOT NULL DEFAULT 'tablet',
+    dosage TEXT NOT NULL DEFAULT '',
+    frequency TEXT NOT NULL DEFAULT '',
+    timing TEXT NOT NULL DEFAULT '',
+    ingredients TEXT NOT NULL DEFAULT '[]',
+    benefits TEXT NOT NULL DEFAULT '[]',
+    side_effects TEXT NOT NULL DEFAULT '[]',
+    warnings TEXT NOT NULL DEFAULT '[]',
+    is_halal BOOLEAN NOT NULL DEFAULT TRUE,
+    is_vegetarian BOOLEAN NOT NULL DEFAULT TRUE,
+    is_vegan BOOLEAN NOT NULL DEFAULT TRUE,
+    is_organic BOOLEAN NOT NULL DEFAULT FALSE,
+    price DOUBLE PRECISION NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '[]',
+    notes TEXT NOT NULL DEFAULT '',
+    start_date TIMESTAMP,
+    end_date TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplements_user ON supplements(user_id, is_active);
+
+CREATE TABLE IF NOT EXISTS medical_plans (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL DEFAULT 'other',
+    category TEXT NOT NULL DEFAULT 'general_health',
+    duration INTEGER NOT NULL DEFAULT 0,
+    start_date TIMESTAMP,
+    end_date TIMESTAMP,
+    goals TEXT NOT NULL DEFAULT '[]',
+    restrictions TEXT NOT NULL DEFAULT '[]',
+    recommendations TEXT NOT NULL DEFAULT '[]',
+    meal_plan TEXT,
+    exercise_plan TEXT,
+    supplement_plan TEXT,
+    monitoring_metrics TEXT NOT NULL DEFAULT '[]',
+    checkpoints TEXT NOT NULL DEFAULT '[]',
+    notes TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    tags TEXT NOT NULL DEFAULT '[]',
+    rating DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rating_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_medical_plans_user ON medical_plans(user_id);
+CREATE INDEX IF NOT EXISTS idx_medical_plans_public ON medical_plans(is_public, category);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    access_token TEXT NOT NULL,
+    refresh_token TEXT NOT NULL,
+    device_info TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id, is_active);
+CREATE INDEX IF NOT EXISTS idx_sessions_access_token ON sessions(access_token);
+CREATE INDEX IF NOT EXISTS idx_sessions_refresh_token ON sessions(refresh_token);
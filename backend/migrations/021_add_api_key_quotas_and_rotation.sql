@@ -0,0 +1,17 @@
+-- Migration: API key daily quotas and rotation
+-- daily_quota of 0 means unlimited. A rotated key's previous hash keeps
+-- authenticating until previous_key_expires_at.
+ALTER TABLE api_keys ADD COLUMN daily_quota INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_keys ADD COLUMN previous_key_hash TEXT;
+ALTER TABLE api_keys ADD COLUMN previous_key_expires_at TIMESTAMP;
+ALTER TABLE api_keys ADD COLUMN rotated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_previous_key_hash ON api_keys(previous_key_hash);
+
+-- Requests counted against each key's quota, per UTC day (YYYY-MM-DD)
+CREATE TABLE IF NOT EXISTS api_key_daily_usage (
+    api_key_id TEXT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    usage_date TEXT NOT NULL,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, usage_date)
+);
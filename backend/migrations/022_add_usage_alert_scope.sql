@@ -0,0 +1,10 @@
+-- Migration: Per-key usage alerts
+-- usage_alerts without an api_key_id apply to every key. An alert fires when
+-- its condition exceeds the threshold over the last window_minutes, at most
+-- once per cooldown_minutes.
+ALTER TABLE usage_alerts ADD COLUMN api_key_id TEXT REFERENCES api_keys(id) ON DELETE CASCADE;
+ALTER TABLE usage_alerts ADD COLUMN window_minutes INTEGER NOT NULL DEFAULT 60;
+ALTER TABLE usage_alerts ADD COLUMN cooldown_minutes INTEGER NOT NULL DEFAULT 60;
+
+CREATE INDEX IF NOT EXISTS idx_usage_alerts_api_key_id ON usage_alerts(api_key_id);
+CREATE INDEX IF NOT EXISTS idx_api_key_usage_key_timestamp ON api_key_usage(api_key_id, timestamp);
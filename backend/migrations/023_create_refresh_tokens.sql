@@ -0,0 +1,22 @@
+-- Migration: Refresh token families
+-- Every refresh token issued for a session is kept, by hash only, so a token
+-- that was already rotated away can be recognised when it is presented again.
+-- The session is the token family: reuse revokes the session and all of its
+-- tokens.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
+
+-- sessions.refresh_token now holds the SHA-256 hash of the current token.
+-- Existing values are plaintext and cannot be hashed portably, so those
+-- sessions have to sign in again.
+UPDATE sessions SET refresh_token = '';
@@ -0,0 +1,31 @@
+-- Migration: TOTP two-factor authentication
+-- secret_ciphertext is the TOTP secret encrypted with MFA_ENCRYPTION_KEY; it
+-- has to be recoverable to check codes, so it cannot be hashed. A row with
+-- enabled = FALSE is an enrollment awaiting its first code. last_used_step
+-- is the last accepted TOTP time step, so a code cannot be used twice.
+-- Consecutive wrong codes are counted and lock verification until
+-- locked_until.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id TEXT PRIMARY KEY,
+    secret_ciphertext TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    enabled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One-time recovery codes, stored as hashes
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    code_hash TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES user_mfa(user_id) ON DELETE CASCADE,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(user_id);
+
+-- Whether the session signed in with a second factor; refreshed access
+-- tokens carry it forward
+ALTER TABLE sessions ADD COLUMN mfa_verified BOOLEAN NOT NULL DEFAULT FALSE;
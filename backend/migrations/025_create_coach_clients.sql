@@ -0,0 +1,46 @@
+-- Migration: Coach/client sharing
+-- A coach invites a client with a one-time token; only its hash is stored.
+-- client_id is set when the client accepts. Status moves from pending to
+-- active, and from active to revoked when either side ends it.
+CREATE TABLE IF NOT EXISTS coach_clients (
+    id TEXT PRIMARY KEY,
+    coach_id TEXT NOT NULL,
+    client_id TEXT,
+    invite_email TEXT,
+    invite_token_hash TEXT NOT NULL UNIQUE,
+    requested_permissions TEXT NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    invite_expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coach_clients_coach ON coach_clients(coach_id);
+CREATE INDEX IF NOT EXISTS idx_coach_clients_client ON coach_clients(client_id);
+
+-- Permissions the client granted on a relationship, one row each
+CREATE TABLE IF NOT EXISTS coach_client_grants (
+    relationship_id TEXT NOT NULL REFERENCES coach_clients(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    granted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (relationship_id, permission)
+);
+
+-- Every time a coach reads or changes a client's data, including denied
+-- attempts. Entries are written before the request is handled, so an
+-- access is never served without one.
+CREATE TABLE IF NOT EXISTS coach_access_log (
+    id TEXT PRIMARY KEY,
+    coach_id TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coach_access_log_client ON coach_access_log(client_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_coach_access_log_coach ON coach_access_log(coach_id, created_at);
@@ -0,0 +1,46 @@
+-- Migration: Reminders and the in-app notification inbox
+-- times holds local "HH:MM" clock times and days_of_week the weekdays they
+-- apply to (0 = Sunday); an empty list means every day. Times are read in
+-- the user's timezone preference when the scheduler runs.
+CREATE TABLE IF NOT EXISTS reminders (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    title TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    times TEXT NOT NULL DEFAULT '[]',
+    days_of_week TEXT NOT NULL DEFAULT '[]',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_user ON reminders(user_id);
+CREATE INDEX IF NOT EXISTS idx_reminders_active ON reminders(active);
+
+-- One row per reminder occurrence that has been sent. The scheduler claims
+-- an occurrence by inserting its key, so overlapping runs and multiple
+-- instances send it once.
+CREATE TABLE IF NOT EXISTS reminder_dispatches (
+    user_id TEXT NOT NULL,
+    dedupe_key TEXT NOT NULL,
+    scheduled_for TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, dedupe_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminder_dispatches_scheduled ON reminder_dispatches(scheduled_for);
+
+-- The in-app inbox, written by the default notification channel
+CREATE TABLE IF NOT EXISTS notifications (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    data TEXT NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at);
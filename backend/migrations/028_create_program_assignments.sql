@@ -0,0 +1,73 @@
+-- Migration: Workout program assignments and progression
+-- A program (workout_programs with its workout_sessions) is assigned to a
+-- user for a number of weeks; its sessions are laid out over the user's
+-- training days.
+CREATE TABLE IF NOT EXISTS program_assignments (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    program_id TEXT NOT NULL REFERENCES workout_programs(id),
+    start_date TEXT NOT NULL,
+    weeks INTEGER NOT NULL,
+    training_days TEXT NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_assignments_user ON program_assignments(user_id, status);
+
+-- The current prescription for each exercise of an assignment. Logged
+-- sessions move it forward; regressions lists the easier variants injuries
+-- step through, taken from the program's exercise modifications.
+CREATE TABLE IF NOT EXISTS program_exercise_targets (
+    assignment_id TEXT NOT NULL REFERENCES program_assignments(id) ON DELETE CASCADE,
+    exercise_id TEXT NOT NULL,
+    exercise_name TEXT NOT NULL,
+    variant TEXT NOT NULL DEFAULT '',
+    sets INTEGER NOT NULL,
+    rep_min INTEGER NOT NULL,
+    rep_max INTEGER NOT NULL,
+    target_reps INTEGER NOT NULL,
+    load DOUBLE PRECISION NOT NULL DEFAULT 0,
+    load_increment DOUBLE PRECISION NOT NULL DEFAULT 0,
+    failed_sessions INTEGER NOT NULL DEFAULT 0,
+    regression_level INTEGER NOT NULL DEFAULT 0,
+    regressions TEXT NOT NULL DEFAULT '[]',
+    target_muscles TEXT NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (assignment_id, exercise_id)
+);
+
+CREATE TABLE IF NOT EXISTS program_scheduled_sessions (
+    id TEXT PRIMARY KEY,
+    assignment_id TEXT NOT NULL REFERENCES program_assignments(id) ON DELETE CASCADE,
+    workout_session_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    week INTEGER NOT NULL,
+    scheduled_date TEXT NOT NULL,
+    deload BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    user_workout_session_id TEXT,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_scheduled_sessions_assignment ON program_scheduled_sessions(assignment_id, scheduled_date);
+
+-- Why each target changed, for the user and their coach
+CREATE TABLE IF NOT EXISTS program_adjustments (
+    id TEXT PRIMARY KEY,
+    assignment_id TEXT NOT NULL REFERENCES program_assignments(id) ON DELETE CASCADE,
+    exercise_id TEXT NOT NULL,
+    user_workout_session_id TEXT,
+    rule VARCHAR(30) NOT NULL,
+    description TEXT NOT NULL,
+    sets_before INTEGER NOT NULL,
+    sets_after INTEGER NOT NULL,
+    reps_before INTEGER NOT NULL,
+    reps_after INTEGER NOT NULL,
+    load_before DOUBLE PRECISION NOT NULL,
+    load_after DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_adjustments_assignment ON program_adjustments(assignment_id, created_at);
@@ -0,0 +1,9 @@
+-- Migration: Adaptive calorie targets on nutrition goals
+-- Goals whose daily_calories come from the adaptive energy expenditure
+-- estimate keep the band around the target and the estimate it came from.
+-- A manual daily_calories clears them.
+ALTER TABLE nutrition_goals ADD COLUMN daily_calories_low INTEGER;
+ALTER TABLE nutrition_goals ADD COLUMN daily_calories_high INTEGER;
+ALTER TABLE nutrition_goals ADD COLUMN estimated_tdee INTEGER;
+ALTER TABLE nutrition_goals ADD COLUMN tdee_confidence VARCHAR(10);
+ALTER TABLE nutrition_goals ADD COLUMN tdee_updated_at TIMESTAMP;
@@ -0,0 +1,36 @@
+-- Migration: Create body_measurements table
+-- Tape and scale measurements logged by users. Body fat is either entered
+-- by the user or derived from the tape measurements; body_fat_method says
+-- which, and the lean mass, fat mass, ratios and FFMI are derived alongside.
+CREATE TABLE IF NOT EXISTS body_measurements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    measurement_date DATETIME NOT NULL,
+    weight REAL,
+    height REAL,
+    body_fat_percentage REAL,
+    neck REAL,
+    chest REAL,
+    waist REAL,
+    hips REAL,
+    left_bicep REAL,
+    right_bicep REAL,
+    left_forearm REAL,
+    right_forearm REAL,
+    left_thigh REAL,
+    right_thigh REAL,
+    left_calf REAL,
+    right_calf REAL,
+    body_fat_method VARCHAR(20),
+    lean_mass REAL,
+    fat_mass REAL,
+    waist_to_hip_ratio REAL,
+    waist_to_height_ratio REAL,
+    ffmi REAL,
+    notes TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_body_measurements_user_date ON body_measurements(user_id, measurement_date);
@@ -0,0 +1,82 @@
+-- Migration: Streaks, badges and milestones
+-- activity_days counts a user's logging per activity and local day. The day
+-- is taken in the user's timezone when the activity is logged, so travelling
+-- never splits or merges days already counted. activity 'any' counts every
+-- logged activity.
+CREATE TABLE IF NOT EXISTS activity_days (
+    user_id TEXT NOT NULL,
+    activity VARCHAR(20) NOT NULL,
+    day TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, activity, day)
+);
+
+-- Streak counters as of the last logged day; a streak whose last day is
+-- before yesterday has lapsed
+CREATE TABLE IF NOT EXISTS user_streaks (
+    user_id TEXT NOT NULL,
+    activity VARCHAR(20) NOT NULL,
+    current_streak INTEGER NOT NULL DEFAULT 0,
+    best_streak INTEGER NOT NULL DEFAULT 0,
+    last_day TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, activity)
+);
+
+-- The badge catalog. A streak badge is earned at threshold consecutive
+-- days of the activity, a total badge at threshold activities logged in
+-- all. Badges are configured by adding or deactivating rows.
+CREATE TABLE IF NOT EXISTS badges (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    activity VARCHAR(20) NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    threshold INTEGER NOT NULL,
+    icon TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_badges (
+    user_id TEXT NOT NULL,
+    badge_id TEXT NOT NULL REFERENCES badges(id) ON DELETE CASCADE,
+    awarded_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, badge_id)
+);
+
+-- Milestones are user-set targets. Weight milestones are reached when a
+-- weigh-in crosses target_value coming from start_value, workout milestones
+-- when target_value workouts have been completed.
+CREATE TABLE IF NOT EXISTS milestones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    target_value REAL,
+    start_value REAL,
+    current_value REAL,
+    photo_url TEXT,
+    is_achieved BOOLEAN NOT NULL DEFAULT FALSE,
+    achieved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_milestones_user ON milestones(user_id, is_achieved);
+
+INSERT INTO badges (id, name, description, activity, kind, threshold, icon) VALUES
+    ('first-meal', 'First Bite', 'Logged your first meal', 'meal', 'total', 1, 'utensils'),
+    ('meals-100', 'Century of Meals', 'Logged 100 meals', 'meal', 'total', 100, 'utensils'),
+    ('meal-streak-7', 'Week of Logging', 'Logged meals 7 days in a row', 'meal', 'streak', 7, 'calendar'),
+    ('meal-streak-30', 'Month of Logging', 'Logged meals 30 days in a row', 'meal', 'streak', 30, 'calendar'),
+    ('water-streak-7', 'Hydrated Week', 'Logged water 7 days in a row', 'water', 'streak', 7, 'droplet'),
+    ('first-weigh-in', 'On the Scale', 'Logged your first weigh-in', 'weigh_in', 'total', 1, 'scale'),
+    ('weigh-in-streak-7', 'Daily Weigh-ins', 'Weighed in 7 days in a row', 'weigh_in', 'streak', 7, 'scale'),
+    ('first-workout', 'First Workout', 'Completed your first workout', 'workout', 'total', 1, 'dumbbell'),
+    ('workouts-50', 'Fifty Workouts', 'Completed 50 workouts', 'workout', 'total', 50, 'dumbbell'),
+    ('workout-streak-5', 'Five in a Row', 'Worked out 5 days in a row', 'workout', 'streak', 5, 'flame'),
+    ('first-photo', 'Picture of Progress', 'Uploaded your first progress photo', 'progress_photo', 'total', 1, 'camera'),
+    ('streak-30', '30-Day Streak', 'Logged something 30 days in a row', 'any', 'streak', 30, 'trophy'),
+    ('streak-100', '100-Day Streak', 'Logged something 100 days in a row', 'any', 'streak', 100, 'trophy');
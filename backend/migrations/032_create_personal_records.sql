@@ -0,0 +1,27 @@
+-- Migration: Personal records
+-- A row is kept each time a logged workout beats a best, so the rows for an
+-- exercise and record type are its PR history and the latest is the current
+-- best. reps records are kept per weight and time records per distance.
+-- Exercises logged without an ID are keyed by name.
+CREATE TABLE IF NOT EXISTS personal_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    exercise_id TEXT NOT NULL,
+    exercise_name TEXT NOT NULL DEFAULT '',
+    record_type VARCHAR(20) NOT NULL,
+    value REAL NOT NULL,
+    unit VARCHAR(10) NOT NULL,
+    reps INTEGER,
+    weight REAL,
+    distance REAL,
+    previous_value REAL,
+    date TIMESTAMP NOT NULL,
+    notes TEXT,
+    workout_session_id TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_records_user_exercise ON personal_records(user_id, exercise_id, record_type);
+CREATE INDEX IF NOT EXISTS idx_personal_records_user_date ON personal_records(user_id, date);
+CREATE INDEX IF NOT EXISTS idx_personal_records_session ON personal_records(workout_session_id);
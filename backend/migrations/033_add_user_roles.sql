@@ -0,0 +1,5 @@
+-- Migration: User roles
+-- Access tokens carry the user's role and admin flag, so refreshed tokens
+-- are issued from the account rather than from fixed values.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+// Logging activities that count toward streaks and badges
+const (
+	ActivityMeal          = "meal"
+	ActivityWater         = "water"
+	ActivityWeighIn       = "weigh_in"
+	ActivityWorkout       = "workout"
+	ActivityProgressPhoto = "progress_photo"
+	// ActivityAny is a day with any of the above logged
+	ActivityAny = "any"
+)
+
+// Activities lists the activities that can be logged, without ActivityAny
+var Activities = []string{
+	ActivityMeal,
+	ActivityWater,
+	ActivityWeighIn,
+	ActivityWorkout,
+	ActivityProgressPhoto,
+}
+
+// Kinds of badge
+const (
+	// BadgeKindStreak is earned at Threshold consecutive days
+	BadgeKindStreak = "streak"
+	// BadgeKindTotal is earned at Threshold activities logged in all
+	BadgeKindTotal = "total"
+)
+
+// Milestone types
+const (
+	// MilestoneTypeWeight is reached when a weigh-in crosses the target
+	MilestoneTypeWeight = "weight"
+	// MilestoneTypeWorkout is reached at a number of completed workouts
+	MilestoneTypeWorkout = "workout"
+	// MilestoneTypeCustom is marked achieved by the user
+	MilestoneTypeCustom = "custom"
+)
+
+// Badge is an entry in the badge catalog
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Activity    string `json:"activity"`
+	Kind        string `json:"kind"`
+	Threshold   int    `json:"threshold"`
+	Icon        string `json:"icon,omitempty"`
+}
+
+// EarnedBadge is a badge a user has been awarded
+type EarnedBadge struct {
+	Badge     Badge     `json:"badge"`
+	AwardedAt time.Time `json:"awarded_at"`
+}
+
+// BadgeProgress is how far a user is from a badge not yet earned
+type BadgeProgress struct {
+	Badge           Badge   `json:"badge"`
+	Current         int     `json:"current"`
+	ProgressPercent float64 `json:"progress_percent"`
+}
+
+// Streak counts consecutive local days a user logged an activity. Current
+// is zero once a day has been missed; LastDay is the last day logged.
+type Streak struct {
+	Activity string `json:"activity"`
+	Current  int    `json:"current"`
+	Best     int    `json:"best"`
+	LastDay  string `json:"last_day"`
+}
+
+// ActivityResult is what recording an activity changed
+type ActivityResult struct {
+	Day                string        `json:"day"`
+	Streaks            []Streak      `json:"streaks"`
+	NewBadges          []EarnedBadge `json:"new_badges"`
+	AchievedMilestones []Milestone   `json:"achieved_milestones"`
+}
+
+// AchievementsOverview lists what a user has earned
+type AchievementsOverview struct {
+	Badges     []EarnedBadge        `json:"badges"`
+	Milestones []Milestone          `json:"milestones"`
+	Streaks    []Streak             `json:"streaks"`
+	Analytics  AchievementAnalytics `json:"analytics"`
+}
+
+// UpcomingAchievements lists the badges and milestones a user is working
+// toward, closest first
+type UpcomingAchievements struct {
+	Badges     []BadgeProgress     `json:"badges"`
+	Milestones []MilestoneProgress `json:"milestones"`
+}
+
+// CreateMilestoneRequest sets a new milestone. A weight milestone without a
+// StartValue starts from the latest weigh-in.
+type CreateMilestoneRequest struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Description *string  `json:"description,omitempty"`
+	TargetValue *float64 `json:"target_value,omitempty"`
+	StartValue  *float64 `json:"start_value,omitempty"`
+	PhotoURL    *string  `json:"photo_url,omitempty"`
+}
@@ -31,6 +31,7 @@ const (
 	ScopeMeals       APIKeyScope = "meals"
 	ScopeHealth      APIKeyScope = "health"
 	ScopeSupplements APIKeyScope = "supplements"
+	ScopeWebhooks    APIKeyScope = "webhooks"
 )
 
 // APIKey represents an API key in the system
@@ -42,12 +43,18 @@ type APIKey struct {
 	UserID     string                 `json:"user_id" db:"user_id"`
 	Status     APIKeyStatus           `json:"status" db:"status"`
 	Scopes     []APIKeyScope          `json:"scopes" db:"scopes"`
-	RateLimit  int                    `json:"rate_limit" db:"rate_limit"` // requests per minute
+	RateLimit  int                    `json:"rate_limit" db:"rate_limit"`   // requests per minute
+	DailyQuota int                    `json:"daily_quota" db:"daily_quota"` // requests per UTC day, 0 for unlimited
 	ExpiresAt  *time.Time             `json:"expires_at" db:"expires_at"`
 	LastUsedAt *time.Time             `json:"last_used_at" db:"last_used_at"`
 	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time              `json:"updated_at" db:"updated_at"`
 	Metadata   map[string]interface{} `json:"metadata" db:"metadata"`
+
+	// Rotation keeps the replaced key valid until PreviousKeyExpiresAt
+	PreviousKeyHash      string     `json:"-" db:"previous_key_hash"`
+	PreviousKeyExpiresAt *time.Time `json:"previous_key_expires_at,omitempty" db:"previous_key_expires_at"`
+	RotatedAt            *time.Time `json:"rotated_at,omitempty" db:"rotated_at"`
 }
 
 // APIKeyUsage tracks API key usage statistics
@@ -177,7 +184,11 @@ func (ak *APIKey) CanAccess(endpoint, method string) bool {
 		}
 	}
 
-	// Check read/write permissions
+	return ak.AllowsMethod(method)
+}
+
+// AllowsMethod checks the key's read/write scopes against an HTTP method
+func (ak *APIKey) AllowsMethod(method string) bool {
 	if method == "GET" || method == "HEAD" {
 		return ak.HasScope(ScopeReadOnly) || ak.HasScope(ScopeReadWrite) || ak.HasScope(ScopeAdmin)
 	}
@@ -198,11 +209,27 @@ func (ak *APIKey) UpdateLastUsed() {
 
 // CreateAPIKeyRequest represents a request to create a new API key
 type CreateAPIKeyRequest struct {
-	Name      string                 `json:"name" validate:"required,min=3,max=100"`
-	Scopes    []APIKeyScope          `json:"scopes" validate:"required,min=1"`
-	RateLimit int                    `json:"rate_limit" validate:"min=1,max=10000"`
-	ExpiresIn *int                   `json:"expires_in"` // days from now, nil for no expiration
-	Metadata  map[string]interface{} `json:"metadata"`
+	Name       string                 `json:"name" validate:"required,min=3,max=100"`
+	Scopes     []APIKeyScope          `json:"scopes" validate:"required,min=1"`
+	RateLimit  int                    `json:"rate_limit" validate:"min=1,max=10000"`
+	DailyQuota int                    `json:"daily_quota" validate:"min=0"` // 0 for unlimited
+	ExpiresIn  *int                   `json:"expires_in"`                   // days from now, nil for no expiration
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// UpdateAPIKeyRequest represents a partial update of an API key; nil fields are left unchanged
+type UpdateAPIKeyRequest struct {
+	Name       *string                `json:"name,omitempty"`
+	Status     *APIKeyStatus          `json:"status,omitempty"` // active or inactive
+	Scopes     []APIKeyScope          `json:"scopes,omitempty"`
+	RateLimit  *int                   `json:"rate_limit,omitempty"`
+	DailyQuota *int                   `json:"daily_quota,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// RotateAPIKeyRequest represents a request to replace an API key's secret
+type RotateAPIKeyRequest struct {
+	GracePeriodHours *int `json:"grace_period_hours"` // how long the old key keeps working, nil for the default
 }
 
 // CreateAPIKeyResponse represents the response when creating an API key
@@ -247,6 +274,7 @@ func ValidateScopes(scopes []APIKeyScope) error {
 		ScopeMeals:       true,
 		ScopeHealth:      true,
 		ScopeSupplements: true,
+		ScopeWebhooks:    true,
 	}
 
 	for _, scope := range scopes {
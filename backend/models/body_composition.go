@@ -0,0 +1,50 @@
+package models
+
+// How a measurement's body fat percentage was arrived at
+const (
+	// BodyFatMethodUserEntered is a value the user entered, e.g. from a
+	// smart scale or DEXA scan
+	BodyFatMethodUserEntered = "user_entered"
+	// BodyFatMethodUSNavy uses neck, waist and height, plus hips for women
+	BodyFatMethodUSNavy = "us_navy"
+	// BodyFatMethodRFM is the relative fat mass from height and waist
+	BodyFatMethodRFM = "rfm"
+	// BodyFatMethodYMCA uses waist and weight
+	BodyFatMethodYMCA = "ymca"
+)
+
+// BodyCompositionInput is what body composition is calculated from. Lengths
+// are in centimetres and weight in kilograms; Sex is "male" or "female",
+// anything else leaves out the sex-specific body fat formulas.
+type BodyCompositionInput struct {
+	Sex               string   `json:"sex"`
+	Height            *float64 `json:"height,omitempty"`
+	Weight            *float64 `json:"weight,omitempty"`
+	Neck              *float64 `json:"neck,omitempty"`
+	Waist             *float64 `json:"waist,omitempty"`
+	Hips              *float64 `json:"hips,omitempty"`
+	BodyFatPercentage *float64 `json:"body_fat_percentage,omitempty"`
+}
+
+// BodyCompositionResult is what could be derived from a BodyCompositionInput.
+// BodyFatPercentage is the entered value if there was one, otherwise the
+// first of the estimates; Estimates lists every formula the input allowed.
+type BodyCompositionResult struct {
+	BodyFatPercentage  *float64          `json:"body_fat_percentage,omitempty"`
+	BodyFatMethod      *string           `json:"body_fat_method,omitempty"`
+	Estimates          []BodyFatEstimate `json:"estimates"`
+	LeanMass           *float64          `json:"lean_mass,omitempty"`
+	FatMass            *float64          `json:"fat_mass,omitempty"`
+	WaistToHipRatio    *float64          `json:"waist_to_hip_ratio,omitempty"`
+	WaistToHeightRatio *float64          `json:"waist_to_height_ratio,omitempty"`
+	FFMI               *float64          `json:"ffmi,omitempty"`
+	// NormalizedFFMI adjusts FFMI to a height of 1.8 m so tall and short
+	// people compare fairly
+	NormalizedFFMI *float64 `json:"normalized_ffmi,omitempty"`
+}
+
+// BodyFatEstimate is one formula's body fat percentage
+type BodyFatEstimate struct {
+	Method            string  `json:"method"`
+	BodyFatPercentage float64 `json:"body_fat_percentage"`
+}
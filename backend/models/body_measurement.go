@@ -31,13 +31,20 @@ type BodyMeasurement struct {
 	Neck              *float64  `json:"neck,omitempty" db:"neck"`
 	Thighs            *float64  `json:"thighs,omitempty" db:"thighs"`
 	Hips              *float64  `json:"hips,omitempty" db:"hips"`
-	Calories          *float64  `json:"calories,omitempty" db:"calories"`
-	ActivityLevel     *string   `json:"activity_level,omitempty" db:"activity_level"`
-	Notes             *string   `json:"notes,omitempty" db:"notes"`
-	Photos            PhotoList `json:"photos,omitempty" db:"photos"`
-	Type              string    `json:"type" db:"type"`
-	CreatedAt         time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+	// Derived by BodyCompositionService when the measurement is saved
+	BodyFatMethod      *string   `json:"body_fat_method,omitempty" db:"body_fat_method"`
+	LeanMass           *float64  `json:"lean_mass,omitempty" db:"lean_mass"`
+	FatMass            *float64  `json:"fat_mass,omitempty" db:"fat_mass"`
+	WaistToHipRatio    *float64  `json:"waist_to_hip_ratio,omitempty" db:"waist_to_hip_ratio"`
+	WaistToHeightRatio *float64  `json:"waist_to_height_ratio,omitempty" db:"waist_to_height_ratio"`
+	FFMI               *float64  `json:"ffmi,omitempty" db:"ffmi"`
+	Calories           *float64  `json:"calories,omitempty" db:"calories"`
+	ActivityLevel      *string   `json:"activity_level,omitempty" db:"activity_level"`
+	Notes              *string   `json:"notes,omitempty" db:"notes"`
+	Photos             PhotoList `json:"photos,omitempty" db:"photos"`
+	Type               string    `json:"type" db:"type"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // Validate validates the body measurement
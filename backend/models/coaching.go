@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Permissions a client can grant to a coach
+const (
+	CoachPermissionReadFoodLogs       = "read_food_logs"
+	CoachPermissionReadMeasurements   = "read_measurements"
+	CoachPermissionReadWorkouts       = "read_workouts"
+	CoachPermissionEditMealPlans      = "edit_meal_plans"
+	CoachPermissionViewProgressPhotos = "view_progress_photos"
+)
+
+// CoachPermissions lists every permission a coach can be granted
+var CoachPermissions = []string{
+	CoachPermissionReadFoodLogs,
+	CoachPermissionReadMeasurements,
+	CoachPermissionReadWorkouts,
+	CoachPermissionEditMealPlans,
+	CoachPermissionViewProgressPhotos,
+}
+
+// IsValidCoachPermission reports whether permission is one clients can grant
+func IsValidCoachPermission(permission string) bool {
+	for _, p := range CoachPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Coach/client relationship statuses
+const (
+	CoachClientPending = "pending"
+	CoachClientActive  = "active"
+	CoachClientRevoked = "revoked"
+)
+
+// CoachClient is a coach's relationship with one client. It starts as an
+// invitation and carries the permissions the client has granted once
+// accepted.
+type CoachClient struct {
+	ID                   string     `json:"id"`
+	CoachID              string     `json:"coach_id"`
+	ClientID             string     `json:"client_id,omitempty"`
+	InviteEmail          string     `json:"invite_email,omitempty"`
+	InviteTokenHash      string     `json:"-"`
+	RequestedPermissions []string   `json:"requested_permissions"`
+	Permissions          []string   `json:"permissions"`
+	Status               string     `json:"status"`
+	InviteExpiresAt      time.Time  `json:"invite_expires_at"`
+	AcceptedAt           *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt            *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+}
+
+// CoachInvitation is returned to the coach once, with the token the client
+// needs to accept
+type CoachInvitation struct {
+	Relationship *CoachClient `json:"relationship"`
+	Token        string       `json:"token"`
+}
+
+// CreateCoachInvitationRequest is sent by a coach to invite a client
+type CreateCoachInvitationRequest struct {
+	Email       string   `json:"email"`
+	Permissions []string `json:"permissions"`
+}
+
+// AcceptCoachInvitationRequest is sent by the client. Permissions narrows
+// what the coach asked for; empty grants everything requested.
+type AcceptCoachInvitationRequest struct {
+	Token       string   `json:"token"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateCoachPermissionsRequest replaces the permissions a client grants
+type UpdateCoachPermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// CoachAccessLogEntry records one coach request for a client's data
+type CoachAccessLogEntry struct {
+	ID         string    `json:"id"`
+	CoachID    string    `json:"coach_id"`
+	ClientID   string    `json:"client_id"`
+	Permission string    `json:"permission"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Allowed    bool      `json:"allowed"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,64 @@
+package models
+
+// How an energy expenditure estimate was reached
+const (
+	// TDEEMethodAdaptive uses logged intake and the weight trend only
+	TDEEMethodAdaptive = "adaptive"
+	// TDEEMethodBlended weighs the adaptive estimate against the
+	// Mifflin-St Jeor formula by how certain each is
+	TDEEMethodBlended = "blended"
+	// TDEEMethodFormula falls back to the formula when too little has been
+	// logged
+	TDEEMethodFormula = "formula"
+)
+
+// Confidence levels of an energy expenditure estimate
+const (
+	TDEEConfidenceHigh   = "high"
+	TDEEConfidenceMedium = "medium"
+	TDEEConfidenceLow    = "low"
+)
+
+// EnergyExpenditureEstimate is a user's estimated total daily energy
+// expenditure with a 95% band. Weights are in kilograms.
+type EnergyExpenditureEstimate struct {
+	UserID       int    `json:"user_id"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Days         int    `json:"days"`
+	Method       string `json:"method"`
+	TDEE         int    `json:"tdee"`
+	TDEELow      int    `json:"tdee_low"`
+	TDEEHigh     int    `json:"tdee_high"`
+	Confidence   string `json:"confidence"`
+	AdaptiveTDEE *int   `json:"adaptive_tdee,omitempty"`
+	FormulaTDEE  *int   `json:"formula_tdee,omitempty"`
+	// Intake over the days counted as fully logged
+	AverageIntake float64            `json:"average_intake"`
+	LoggedDays    int                `json:"logged_days"`
+	PartialDays   int                `json:"partial_days"`
+	WeighIns      int                `json:"weigh_ins"`
+	TrendWeight   *float64           `json:"trend_weight,omitempty"`
+	WeeklyChange  *float64           `json:"weekly_change,omitempty"`
+	Daily         []EnergyBalanceDay `json:"daily"`
+}
+
+// EnergyBalanceDay is one day of the estimate's window. Calories is nil on
+// days with nothing logged; Partial marks days logged too sparsely to count.
+type EnergyBalanceDay struct {
+	Date        string   `json:"date"`
+	Calories    *float64 `json:"calories,omitempty"`
+	Partial     bool     `json:"partial,omitempty"`
+	Weight      *float64 `json:"weight,omitempty"`
+	TrendWeight *float64 `json:"trend_weight,omitempty"`
+}
+
+// AdaptiveGoalRequest sets a nutrition goal's calorie target from the
+// adaptive estimate. Adjustment is added to the estimate, negative for a
+// deficit. Without a GoalID the newest active goal is updated, or a new one
+// created.
+type AdaptiveGoalRequest struct {
+	GoalID     *int `json:"goal_id,omitempty"`
+	Adjustment int  `json:"adjustment"`
+	Days       int  `json:"days,omitempty"`
+}
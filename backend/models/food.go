@@ -29,6 +29,7 @@ type Food struct {
 	ServingUnit  string    `json:"serving_unit" db:"serving_unit"`
 	UserID       *uint     `json:"user_id" db:"user_id"`
 	SourceType   string    `json:"source_type" db:"source_type"`
+	DataSource   *string   `json:"data_source,omitempty" db:"data_source"` // Product database an imported food came from
 	IsVerified   bool      `json:"is_verified" db:"is_verified"`
 	Verified     bool      `json:"verified" db:"verified"` // Repository uses Verified
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
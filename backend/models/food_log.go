@@ -9,12 +9,24 @@ import (
 type UserFoodLog struct {
 	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
 	UserID    int       `json:"user_id" gorm:"not null;index" validate:"required,min=1"`
-	FoodID    int       `json:"food_id" gorm:"not null;index" validate:"required,min=1"`
+	FoodID    int       `json:"food_id" gorm:"index" validate:"required_without=RecipeID"`
 	Quantity  float64   `json:"quantity" gorm:"not null" validate:"required,min=0"` // grams
 	MealType  string    `json:"meal_type" gorm:"not null" validate:"required,oneof=breakfast lunch dinner snack"`
 	LoggedAt  time.Time `json:"logged_at" gorm:"not null;index" validate:"required"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// InputQuantity and InputUnit keep what the user entered, e.g. 1.5 cup;
+	// Quantity always holds the equivalent in grams
+	InputQuantity float64       `json:"input_quantity"`
+	InputUnit     string        `json:"input_unit"`
+	Nutrition     NutritionInfo `json:"nutrition"`
+	Notes         *string       `json:"notes,omitempty"`
+
+	// RecipeID is set instead of FoodID when a recipe is logged by the
+	// serving; Quantity and InputQuantity then hold the number of servings
+	RecipeID *string `json:"recipe_id,omitempty"`
+	FoodName string  `json:"food_name,omitempty"`
 }
 
 // TableName returns the table name for the UserFoodLog model
@@ -27,7 +39,7 @@ func (log *UserFoodLog) Validate() error {
 	if log.UserID <= 0 {
 		return errors.ErrInvalidInputError("user_id must be greater than 0")
 	}
-	if log.FoodID <= 0 {
+	if log.RecipeID == nil && log.FoodID <= 0 {
 		return errors.ErrInvalidInputError("food_id must be greater than 0")
 	}
 	if log.Quantity < 0 {
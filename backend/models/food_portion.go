@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// FoodPortion records how many grams one household unit of a food weighs,
+// e.g. 1 cup of cooked rice = 158 g or 1 piece of egg = 50 g. A portion with
+// the unit "ml" gives the food's density in grams per milliliter.
+type FoodPortion struct {
+	ID          int       `json:"id" db:"id"`
+	FoodID      uint      `json:"food_id" db:"food_id"`
+	Unit        string    `json:"unit" db:"unit"`
+	Grams       float64   `json:"grams" db:"grams"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
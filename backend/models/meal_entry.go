@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// MealEntry represents a meal a user recorded through the meals API
+type MealEntry struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Calories    int       `json:"calories"`
+	Protein     float64   `json:"protein"`
+	Carbs       float64   `json:"carbs"`
+	Fat         float64   `json:"fat"`
+	Fiber       float64   `json:"fiber"`
+	Sugar       float64   `json:"sugar"`
+	Sodium      float64   `json:"sodium"`
+	MealType    string    `json:"meal_type"` // breakfast, lunch, dinner, snack
+	Ingredients []string  `json:"ingredients"`
+	IsHalal     bool      `json:"is_halal"`
+	Tags        []string  `json:"tags"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
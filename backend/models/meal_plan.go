@@ -1,11 +1,139 @@
 package models
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
+// MealPlan represents a stored multi-day meal plan for a user
 type MealPlan struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int           `json:"id" db:"id"`
+	UserID        int           `json:"user_id" db:"user_id"`
+	Name          string        `json:"name" db:"name"`
+	NameAr        *string       `json:"name_ar,omitempty" db:"name_ar"`
+	Description   *string       `json:"description,omitempty" db:"description"`
+	StartDate     *time.Time    `json:"start_date,omitempty" db:"start_date"`
+	EndDate       *time.Time    `json:"end_date,omitempty" db:"end_date"`
+	Days          []MealPlanDay `json:"days" db:"days"`
+	TotalCalories float64       `json:"total_calories" db:"total_calories"`
+	TotalProtein  float64       `json:"total_protein" db:"total_protein"`
+	TotalCarbs    float64       `json:"total_carbs" db:"total_carbs"`
+	TotalFat      float64       `json:"total_fat" db:"total_fat"`
+	IsActive      bool          `json:"is_active" db:"is_active"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// UpdateMealPlanRequest is a partial update of a meal plan; nil fields are left unchanged
+type UpdateMealPlanRequest struct {
+	Name        *string       `json:"name,omitempty"`
+	NameAr      *string       `json:"name_ar,omitempty"`
+	Description *string       `json:"description,omitempty"`
+	Days        []MealPlanDay `json:"days,omitempty"`
+	IsActive    *bool         `json:"is_active,omitempty"`
+}
+
+// MealPlanDay holds the meals planned for a single day of a meal plan
+type MealPlanDay struct {
+	DayNumber int           `json:"day_number"`
+	Date      *time.Time    `json:"date,omitempty"`
+	Meals     []PlannedMeal `json:"meals"`
+}
+
+// PlannedMeal is one meal (breakfast, lunch, ...) within a meal plan day
+type PlannedMeal struct {
+	MealType string            `json:"meal_type"` // breakfast, lunch, dinner, snack
+	Name     BilingualText     `json:"name"`
+	RecipeID *string           `json:"recipe_id,omitempty"`
+	Items    []PlannedMealItem `json:"items"`
+}
+
+// PlannedMealItem is a single food or ingredient portion within a planned meal
+type PlannedMealItem struct {
+	FoodID    *uint         `json:"food_id,omitempty"`
+	Name      BilingualText `json:"name"`
+	Quantity  float64       `json:"quantity"`
+	Unit      string        `json:"unit"`
+	Category  string        `json:"category,omitempty"`
+	Nutrition NutritionInfo `json:"nutrition"`
+}
+
+// Totals returns the summed nutrition of all items in the meal
+func (m PlannedMeal) Totals() NutritionInfo {
+	var total NutritionInfo
+	for _, item := range m.Items {
+		total.Add(item.Nutrition)
+	}
+	return total
+}
+
+// Totals returns the summed nutrition of all meals in the day
+func (d MealPlanDay) Totals() NutritionInfo {
+	var total NutritionInfo
+	for _, meal := range d.Meals {
+		total.Add(meal.Totals())
+	}
+	return total
+}
+
+// RecalculateTotals refreshes the plan-level macro totals from its days
+func (p *MealPlan) RecalculateTotals() {
+	var total NutritionInfo
+	for _, day := range p.Days {
+		total.Add(day.Totals())
+	}
+	p.TotalCalories = total.Calories
+	p.TotalProtein = total.Protein
+	p.TotalCarbs = total.Carbohydrates
+	p.TotalFat = total.Fat
+}
+
+// Add accumulates another nutrition block into n
+func (n *NutritionInfo) Add(other NutritionInfo) {
+	n.Calories += other.Calories
+	n.Protein += other.Protein
+	n.Carbohydrates += other.Carbohydrates
+	n.Fat += other.Fat
+	n.Fiber += other.Fiber
+	n.Sugar += other.Sugar
+	n.Sodium += other.Sodium
+	n.Cholesterol += other.Cholesterol
+	n.VitaminC += other.VitaminC
+	n.Calcium += other.Calcium
+	n.Iron += other.Iron
+}
+
+// Scaled returns n with every nutrient multiplied by factor
+func (n NutritionInfo) Scaled(factor float64) NutritionInfo {
+	return NutritionInfo{
+		Calories:      n.Calories * factor,
+		Protein:       n.Protein * factor,
+		Carbohydrates: n.Carbohydrates * factor,
+		Fat:           n.Fat * factor,
+		Fiber:         n.Fiber * factor,
+		Sugar:         n.Sugar * factor,
+		Sodium:        n.Sodium * factor,
+		Cholesterol:   n.Cholesterol * factor,
+		VitaminC:      n.VitaminC * factor,
+		Calcium:       n.Calcium * factor,
+		Iron:          n.Iron * factor,
+	}
+}
+
+// Rounded returns n with every nutrient rounded to one decimal place
+func (n NutritionInfo) Rounded() NutritionInfo {
+	round := func(v float64) float64 { return math.Round(v*10) / 10 }
+	return NutritionInfo{
+		Calories:      round(n.Calories),
+		Protein:       round(n.Protein),
+		Carbohydrates: round(n.Carbohydrates),
+		Fat:           round(n.Fat),
+		Fiber:         round(n.Fiber),
+		Sugar:         round(n.Sugar),
+		Sodium:        round(n.Sodium),
+		Cholesterol:   round(n.Cholesterol),
+		VitaminC:      round(n.VitaminC),
+		Calcium:       round(n.Calcium),
+		Iron:          round(n.Iron),
+	}
 }
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// MedicalPlan represents a medical or health plan
+type MedicalPlan struct {
+	ID                string                 `json:"id"`
+	UserID            string                 `json:"user_id"`
+	Name              string                 `json:"name"`
+	Description       string                 `json:"description"`
+	Type              string                 `json:"type"`     // diet, exercise, medication, therapy, etc.
+	Category          string                 `json:"category"` // weight_loss, muscle_gain, diabetes, heart_health, etc.
+	Duration          int                    `json:"duration"` // duration in days
+	StartDate         *time.Time             `json:"start_date,omitempty"`
+	EndDate           *time.Time             `json:"end_date,omitempty"`
+	Goals             []string               `json:"goals"`
+	Restrictions      []string               `json:"restrictions"`
+	Recommendations   []string               `json:"recommendations"`
+	MealPlan          *MealPlanDetails       `json:"meal_plan,omitempty"`
+	ExercisePlan      *ExercisePlanDetails   `json:"exercise_plan,omitempty"`
+	SupplementPlan    *SupplementPlanDetails `json:"supplement_plan,omitempty"`
+	MonitoringMetrics []string               `json:"monitoring_metrics"`
+	Checkpoints       []Checkpoint           `json:"checkpoints"`
+	Notes             string                 `json:"notes,omitempty"`
+	CreatedBy         string                 `json:"created_by"` // doctor, nutritionist, self, etc.
+	IsActive          bool                   `json:"is_active"`
+	IsPublic          bool                   `json:"is_public"`
+	Tags              []string               `json:"tags"`
+	Rating            float64                `json:"rating"`
+	RatingCount       int                    `json:"rating_count"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// MealPlanDetails represents meal planning details within a medical plan
+type MealPlanDetails struct {
+	CaloriesPerDay int      `json:"calories_per_day"`
+	MealsPerDay    int      `json:"meals_per_day"`
+	ProteinRatio   float64  `json:"protein_ratio"`
+	CarbRatio      float64  `json:"carb_ratio"`
+	FatRatio       float64  `json:"fat_ratio"`
+	FoodCategories []string `json:"food_categories"`
+	AvoidedFoods   []string `json:"avoided_foods"`
+	PreferredFoods []string `json:"preferred_foods"`
+	MealTiming     []string `json:"meal_timing"`
+	HydrationGoal  int      `json:"hydration_goal"` // ml per day
+}
+
+// ExercisePlanDetails represents exercise planning details within a medical plan
+type ExercisePlanDetails struct {
+	WorkoutsPerWeek int      `json:"workouts_per_week"`
+	SessionDuration int      `json:"session_duration"` // minutes
+	IntensityLevel  string   `json:"intensity_level"`  // low, moderate, high
+	ExerciseTypes   []string `json:"exercise_types"`
+	TargetMuscles   []string `json:"target_muscles"`
+	EquipmentNeeded []string `json:"equipment_needed"`
+	RestDays        []string `json:"rest_days"`
+	ProgressionPlan string   `json:"progression_plan"`
+}
+
+// SupplementPlanDetails represents supplement planning details within a medical plan
+type SupplementPlanDetails struct {
+	RecommendedSupplements []RecommendedSupplement `json:"recommended_supplements"`
+	Timing                 string                  `json:"timing"`
+	Duration               int                     `json:"duration"` // days
+	Notes                  string                  `json:"notes"`
+}
+
+// RecommendedSupplement represents a supplement recommendation
+type RecommendedSupplement struct {
+	Name       string `json:"name"`
+	Dosage     string `json:"dosage"`
+	Frequency  string `json:"frequency"`
+	Timing     string `json:"timing"`
+	Purpose    string `json:"purpose"`
+	IsOptional bool   `json:"is_optional"`
+}
+
+// Checkpoint represents a progress checkpoint in a medical plan
+type Checkpoint struct {
+	ID          string                 `json:"id"`
+	Day         int                    `json:"day"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Metrics     map[string]interface{} `json:"metrics"`
+	Completed   bool                   `json:"completed"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	Notes       string                 `json:"notes,omitempty"`
+}
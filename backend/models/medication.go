@@ -173,6 +173,8 @@ type FoodInteraction struct {
 	Food           string `json:"food"`
 	Effect         string `json:"effect"`
 	Recommendation string `json:"recommendation"`
+	Severity       string `json:"severity,omitempty"` // major, moderate, minor
+	Reference      string `json:"reference,omitempty"`
 }
 
 // SupplementInteraction represents an interaction between medication and supplement
@@ -181,6 +183,8 @@ type SupplementInteraction struct {
 	Supplement     string `json:"supplement"`
 	Effect         string `json:"effect"`
 	Recommendation string `json:"recommendation"`
+	Severity       string `json:"severity,omitempty"` // major, moderate, minor
+	Reference      string `json:"reference,omitempty"`
 }
 
 // NutrientDeficiencyAnalysis represents analysis of potential nutrient deficiencies
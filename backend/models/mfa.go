@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Account roles carried in access tokens. Coaches see client health data
+// and must always sign in with a second factor.
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+)
+
+// UserMFA is a user's TOTP enrollment. Until Enabled is set the enrollment
+// is pending confirmation with a first code.
+type UserMFA struct {
+	UserID           string     `json:"user_id"`
+	SecretCiphertext string     `json:"-"`
+	Enabled          bool       `json:"enabled"`
+	LastUsedStep     int64      `json:"-"`
+	FailedAttempts   int        `json:"-"`
+	LockedUntil      *time.Time `json:"locked_until,omitempty"`
+	EnabledAt        *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// IsLocked reports whether too many wrong codes were entered to verify now
+func (m *UserMFA) IsLocked(now time.Time) bool {
+	return m.LockedUntil != nil && now.Before(*m.LockedUntil)
+}
+
+// MFAStatus summarizes a user's two-factor settings
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// MFAEnrollment is returned when enrollment starts. The secret is shown to
+// the user once, for apps that cannot scan ProvisioningURI as a QR code.
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Reminder types. Each is switched off by its own preference, e.g.
+// meal_reminders, in addition to notifications_enabled.
+const (
+	ReminderMeal       = "meal"
+	ReminderWater      = "water"
+	ReminderWeighIn    = "weigh_in"
+	ReminderWorkout    = "workout"
+	ReminderSupplement = "supplement"
+	ReminderMedication = "medication"
+)
+
+// ReminderTypes lists the reminder types users can schedule
+var ReminderTypes = []string{
+	ReminderMeal,
+	ReminderWater,
+	ReminderWeighIn,
+	ReminderWorkout,
+	ReminderSupplement,
+	ReminderMedication,
+}
+
+// IsValidReminderType reports whether reminderType is in ReminderTypes
+func IsValidReminderType(reminderType string) bool {
+	for _, t := range ReminderTypes {
+		if t == reminderType {
+			return true
+		}
+	}
+	return false
+}
+
+// Reminder sources. Custom reminders are created by the user; medication
+// and supplement reminders are derived from a medication's administration
+// times or a supplement's timing and cannot be edited directly.
+const (
+	ReminderSourceCustom     = "custom"
+	ReminderSourceMedication = "medication"
+	ReminderSourceSupplement = "supplement"
+)
+
+// Reminder fires at each of Times, as local "HH:MM" clock times in the
+// user's timezone, on DaysOfWeek (0 = Sunday) or every day when empty
+type Reminder struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Type       string    `json:"type"`
+	Title      string    `json:"title"`
+	Message    string    `json:"message,omitempty"`
+	Times      []string  `json:"times"`
+	DaysOfWeek []int     `json:"days_of_week"`
+	Active     bool      `json:"active"`
+	Source     string    `json:"source"`
+	SourceID   string    `json:"source_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateReminderRequest creates a custom reminder
+type CreateReminderRequest struct {
+	Type       string   `json:"type"`
+	Title      string   `json:"title"`
+	Message    string   `json:"message"`
+	Times      []string `json:"times"`
+	DaysOfWeek []int    `json:"days_of_week"`
+}
+
+// UpdateReminderRequest changes a custom reminder; omitted fields are kept
+type UpdateReminderRequest struct {
+	Title      *string  `json:"title"`
+	Message    *string  `json:"message"`
+	Times      []string `json:"times"`
+	DaysOfWeek *[]int   `json:"days_of_week"`
+	Active     *bool    `json:"active"`
+}
+
+// NotificationTypeReminder marks notifications sent by the reminder
+// scheduler
+const NotificationTypeReminder = "reminder"
+
+// NotificationTypePersonalRecord marks notifications sent when a logged
+// workout beats a personal record
+const NotificationTypePersonalRecord = "personal_record"
+
+// Notification is a message sent to a user through the notification
+// channels; the in-app channel keeps it in the user's inbox
+type Notification struct {
+	ID        string                 `json:"id"`
+	UserID    string                 `json:"user_id"`
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	ReadAt    *time.Time             `json:"read_at,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
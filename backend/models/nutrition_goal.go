@@ -21,4 +21,10 @@ type NutritionGoal struct {
 	EndDate       *time.Time `json:"end_date,omitempty" db:"end_date"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	// Set when DailyCalories comes from the adaptive TDEE estimate
+	DailyCaloriesLow  *int       `json:"daily_calories_low,omitempty" db:"daily_calories_low"`
+	DailyCaloriesHigh *int       `json:"daily_calories_high,omitempty" db:"daily_calories_high"`
+	EstimatedTDEE     *int       `json:"estimated_tdee,omitempty" db:"estimated_tdee"`
+	TDEEConfidence    *string    `json:"tdee_confidence,omitempty" db:"tdee_confidence"`
+	TDEEUpdatedAt     *time.Time `json:"tdee_updated_at,omitempty" db:"tdee_updated_at"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a single-use credential for resetting a password.
+// The raw token is never stored, only its hash.
+type PasswordResetToken struct {
+	ID        int        `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	RequestIP string     `json:"request_ip,omitempty" db:"request_ip"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsUsable reports whether the token is unused and not yet expired
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,125 @@
+package models
+
+import "time"
+
+// Program assignment statuses
+const (
+	ProgramAssignmentActive    = "active"
+	ProgramAssignmentCompleted = "completed"
+	ProgramAssignmentCancelled = "cancelled"
+)
+
+// Scheduled program session statuses
+const (
+	ProgramSessionScheduled = "scheduled"
+	ProgramSessionCompleted = "completed"
+	ProgramSessionSkipped   = "skipped"
+)
+
+// Progression rules recorded with each adjustment
+const (
+	ProgressionRuleDouble           = "double_progression"
+	ProgressionRuleRPE              = "rpe"
+	ProgressionRuleDeload           = "deload"
+	ProgressionRuleInjuryRegression = "injury_regression"
+)
+
+// ProgramAssignment is a workout program a user is following
+type ProgramAssignment struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	ProgramID    string    `json:"program_id"`
+	StartDate    time.Time `json:"start_date"`
+	Weeks        int       `json:"weeks"`
+	TrainingDays []int     `json:"training_days"` // 0 = Sunday
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ProgramExerciseTarget is the current prescription for one exercise of an
+// assignment. Load is in kilograms; 0 means bodyweight.
+type ProgramExerciseTarget struct {
+	AssignmentID    string    `json:"assignment_id"`
+	ExerciseID      string    `json:"exercise_id"`
+	ExerciseName    string    `json:"exercise_name"`
+	Variant         string    `json:"variant,omitempty"`
+	Sets            int       `json:"sets"`
+	RepMin          int       `json:"rep_min"`
+	RepMax          int       `json:"rep_max"`
+	TargetReps      int       `json:"target_reps"`
+	Load            float64   `json:"load"`
+	LoadIncrement   float64   `json:"load_increment"`
+	FailedSessions  int       `json:"failed_sessions"`
+	RegressionLevel int       `json:"regression_level"`
+	Regressions     []string  `json:"regressions"`
+	TargetMuscles   []string  `json:"target_muscles"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ProgramScheduledSession is one program session placed on a date
+type ProgramScheduledSession struct {
+	ID                   string            `json:"id"`
+	AssignmentID         string            `json:"assignment_id"`
+	WorkoutSessionID     string            `json:"workout_session_id"`
+	Name                 string            `json:"name"`
+	Week                 int               `json:"week"`
+	ScheduledDate        time.Time         `json:"scheduled_date"`
+	Deload               bool              `json:"deload"`
+	Status               string            `json:"status"`
+	UserWorkoutSessionID string            `json:"user_workout_session_id,omitempty"`
+	CompletedAt          *time.Time        `json:"completed_at,omitempty"`
+	Exercises            []PlannedExercise `json:"exercises,omitempty"`
+}
+
+// PlannedExercise is what the user is asked to do for an exercise in a
+// scheduled session
+type PlannedExercise struct {
+	ExerciseID   string  `json:"exercise_id"`
+	ExerciseName string  `json:"exercise_name"`
+	Variant      string  `json:"variant,omitempty"`
+	Sets         int     `json:"sets"`
+	Reps         int     `json:"reps"`
+	RepRange     string  `json:"rep_range"`
+	Load         float64 `json:"load"`
+	RestSeconds  int     `json:"rest_seconds"`
+}
+
+// ProgressionAdjustment records a change to an exercise target and the rule
+// that made it
+type ProgressionAdjustment struct {
+	ID                   string    `json:"id"`
+	AssignmentID         string    `json:"assignment_id"`
+	ExerciseID           string    `json:"exercise_id"`
+	UserWorkoutSessionID string    `json:"user_workout_session_id,omitempty"`
+	Rule                 string    `json:"rule"`
+	Description          string    `json:"description"`
+	SetsBefore           int       `json:"sets_before"`
+	SetsAfter            int       `json:"sets_after"`
+	RepsBefore           int       `json:"reps_before"`
+	RepsAfter            int       `json:"reps_after"`
+	LoadBefore           float64   `json:"load_before"`
+	LoadAfter            float64   `json:"load_after"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// AssignProgramRequest assigns a program. StartDate defaults to today,
+// Weeks to the program's duration and TrainingDays to an even spread of
+// the program's days per week. StartingLoads sets the first load per
+// exercise ID, overriding the weight written in the program.
+type AssignProgramRequest struct {
+	ProgramID     string             `json:"program_id"`
+	StartDate     *time.Time         `json:"start_date,omitempty"`
+	Weeks         int                `json:"weeks,omitempty"`
+	TrainingDays  []int              `json:"training_days,omitempty"`
+	StartingLoads map[string]float64 `json:"starting_loads,omitempty"`
+	LoadIncrement float64            `json:"load_increment,omitempty"`
+}
+
+// ProgramAssignmentDetail is an assignment with its targets and schedule
+type ProgramAssignmentDetail struct {
+	*ProgramAssignment
+	Program  *WorkoutProgram            `json:"program"`
+	Targets  []*ProgramExerciseTarget   `json:"targets"`
+	Sessions []*ProgramScheduledSession `json:"sessions"`
+}
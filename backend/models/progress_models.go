@@ -37,17 +37,21 @@ func (s *StringArray) Scan(value interface{}) error {
 
 // Milestone represents a user's milestone achievements
 type Milestone struct {
-	ID          uint      `json:"id" db:"id"`
-	UserID      uint      `json:"user_id" db:"user_id"`
-	Type        string    `json:"type" db:"type"` // "weight", "measurement", "workout", "custom"
-	Title       string    `json:"title" db:"title"`
-	Description *string   `json:"description,omitempty" db:"description"`
-	TargetValue *float64  `json:"target_value,omitempty" db:"target_value"`
-	AchievedAt  time.Time `json:"achieved_at" db:"achieved_at"`
-	PhotoURL    *string   `json:"photo_url,omitempty" db:"photo_url"`
-	IsAchieved  bool      `json:"is_achieved" db:"is_achieved"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          uint     `json:"id" db:"id"`
+	UserID      uint     `json:"user_id" db:"user_id"`
+	Type        string   `json:"type" db:"type"` // "weight", "measurement", "workout", "custom"
+	Title       string   `json:"title" db:"title"`
+	Description *string  `json:"description,omitempty" db:"description"`
+	TargetValue *float64 `json:"target_value,omitempty" db:"target_value"`
+	// StartValue is where a weight milestone started from, which says
+	// whether the target is reached going down or up
+	StartValue   *float64  `json:"start_value,omitempty" db:"start_value"`
+	CurrentValue *float64  `json:"current_value,omitempty" db:"current_value"`
+	AchievedAt   time.Time `json:"achieved_at" db:"achieved_at"`
+	PhotoURL     *string   `json:"photo_url,omitempty" db:"photo_url"`
+	IsAchieved   bool      `json:"is_achieved" db:"is_achieved"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // WeightGoal represents a user's weight goal
@@ -97,34 +101,45 @@ type ProgressSummary struct {
 	LastUpdated          time.Time        `json:"last_updated"`
 }
 
-// PersonalRecord represents a user's personal record for an exercise
+// PersonalRecord represents a user's personal record for an exercise. A
+// record is kept each time a best is beaten, so the records for an
+// exercise and type are its PR history. Reps and Weight are the set that
+// set the record; a reps record is the most reps at Weight. A time record
+// with a Distance is the fastest over it, and without one the longest hold.
 type PersonalRecord struct {
-	ID           uint      `json:"id" db:"id"`
-	UserID       uint      `json:"user_id" db:"user_id"`
-	ExerciseID   uint      `json:"exercise_id" db:"exercise_id"`
-	RecordType   string    `json:"record_type" db:"record_type"` // "weight", "reps", "time", "distance"
-	Value        float64   `json:"value" db:"value"`
-	Unit         string    `json:"unit" db:"unit"`
-	Date         time.Time `json:"date" db:"date"`
-	Notes        *string   `json:"notes,omitempty" db:"notes"`
-	WorkoutLogID *uint     `json:"workout_log_id,omitempty" db:"workout_log_id"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID               uint      `json:"id" db:"id"`
+	UserID           uint      `json:"user_id" db:"user_id"`
+	ExerciseID       string    `json:"exercise_id" db:"exercise_id"`
+	ExerciseName     string    `json:"exercise_name" db:"exercise_name"`
+	RecordType       string    `json:"record_type" db:"record_type"` // "estimated_1rm", "weight", "reps", "time", "distance"
+	Value            float64   `json:"value" db:"value"`
+	Unit             string    `json:"unit" db:"unit"`
+	Reps             *int      `json:"reps,omitempty" db:"reps"`
+	Weight           *float64  `json:"weight,omitempty" db:"weight"`
+	Distance         *float64  `json:"distance,omitempty" db:"distance"`
+	PreviousValue    *float64  `json:"previous_value,omitempty" db:"previous_value"`
+	Date             time.Time `json:"date" db:"date"`
+	Notes            *string   `json:"notes,omitempty" db:"notes"`
+	WorkoutSessionID *string   `json:"workout_session_id,omitempty" db:"workout_session_id"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // PersonalRecordType represents the type of personal record
 type PersonalRecordType string
 
 const (
-	RecordWeight   PersonalRecordType = "weight"
-	RecordReps     PersonalRecordType = "reps"
-	RecordTime     PersonalRecordType = "time"
-	RecordDistance PersonalRecordType = "distance"
+	// RecordEstimated1RM is the best one-rep max estimated from a set
+	RecordEstimated1RM PersonalRecordType = "estimated_1rm"
+	RecordWeight       PersonalRecordType = "weight"
+	RecordReps         PersonalRecordType = "reps"
+	RecordTime         PersonalRecordType = "time"
+	RecordDistance     PersonalRecordType = "distance"
 )
 
 // ListPersonalRecordsRequest represents a request to list personal records
 type ListPersonalRecordsRequest struct {
-	ExerciseID *uint      `json:"exercise_id,omitempty"`
+	ExerciseID *string    `json:"exercise_id,omitempty"`
 	RecordType *string    `json:"record_type,omitempty"`
 	StartDate  *time.Time `json:"start_date,omitempty"`
 	EndDate    *time.Time `json:"end_date,omitempty"`
@@ -294,14 +309,19 @@ type WeightGoalProgress struct {
 	TrendPoints         []WeightTrendPoint `json:"trend_points"`
 }
 
-// WeightGoalPrediction represents weight goal predictions
+// WeightGoalPrediction represents weight goal predictions. TargetDateEarliest
+// and TargetDateLatest bound TargetDate at 95%; the latest is left out when
+// the slower end of the trend never reaches the target.
 type WeightGoalPrediction struct {
-	CurrentWeight   float64            `json:"current_weight"`
-	TargetWeight    float64            `json:"target_weight"`
-	PredictedWeight []WeightTrendPoint `json:"predicted_weight"`
-	TargetDate      *time.Time         `json:"target_date,omitempty"`
-	Confidence      float64            `json:"confidence"`
-	Recommendations []string           `json:"recommendations"`
+	CurrentWeight      float64            `json:"current_weight"`
+	TargetWeight       float64            `json:"target_weight"`
+	PredictedWeight    []WeightTrendPoint `json:"predicted_weight"`
+	TargetDate         *time.Time         `json:"target_date,omitempty"`
+	TargetDateEarliest *time.Time         `json:"target_date_earliest,omitempty"`
+	TargetDateLatest   *time.Time         `json:"target_date_latest,omitempty"`
+	OnPace             bool               `json:"on_pace"`
+	Confidence         float64            `json:"confidence"`
+	Recommendations    []string           `json:"recommendations"`
 }
 
 // WorkoutLog represents a workout log entry
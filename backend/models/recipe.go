@@ -100,7 +100,8 @@ type CreateRecipeRequest struct {
 	PrepTimeMinutes     *int                `json:"prep_time_minutes,omitempty" validate:"omitempty,min=0,max=1440"`
 	CookTimeMinutes     *int                `json:"cook_time_minutes,omitempty" validate:"omitempty,min=0,max=1440"`
 	Servings            *int                `json:"servings,omitempty" validate:"omitempty,min=1,max=50"`
-	Ingredients         []RecipeIngredient  `json:"ingredients" validate:"required,min=1,dive"`
+	Ingredients         []RecipeIngredient  `json:"ingredients" validate:"required_without=IngredientLines,dive"`
+	IngredientLines     []string            `json:"ingredient_lines,omitempty" validate:"omitempty,dive,max=200"`
 	Instructions        []RecipeInstruction `json:"instructions" validate:"required,min=1,dive"`
 	NutritionPerServing *NutritionInfo      `json:"nutrition_per_serving,omitempty"`
 	DietaryTags         []string            `json:"dietary_tags,omitempty"`
@@ -124,6 +125,7 @@ type UpdateRecipeRequest struct {
 	CookTimeMinutes     *int                `json:"cook_time_minutes,omitempty" validate:"omitempty,min=0,max=1440"`
 	Servings            *int                `json:"servings,omitempty" validate:"omitempty,min=1,max=50"`
 	Ingredients         []RecipeIngredient  `json:"ingredients,omitempty" validate:"omitempty,min=1,dive"`
+	IngredientLines     []string            `json:"ingredient_lines,omitempty" validate:"omitempty,dive,max=200"`
 	Instructions        []RecipeInstruction `json:"instructions,omitempty" validate:"omitempty,min=1,dive"`
 	NutritionPerServing *NutritionInfo      `json:"nutrition_per_serving,omitempty"`
 	DietaryTags         []string            `json:"dietary_tags,omitempty"`
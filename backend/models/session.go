@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Session represents a user session. RefreshToken holds the SHA-256 hash of
+// the session's current refresh token; the raw token is only ever returned
+// to the client.
+type Session struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	DeviceInfo   string    `json:"device_info,omitempty"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	IsActive     bool      `json:"is_active"`
+	MFAVerified  bool      `json:"mfa_verified"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+}
+
+// RefreshToken is one refresh token issued for a session. A session's tokens
+// form a family: each rotation marks the presented token used and issues its
+// successor. The raw token is never stored, only its hash.
+type RefreshToken struct {
+	ID        string     `json:"id"`
+	SessionID string     `json:"session_id"`
+	UserID    string     `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsUsable reports whether the token has not been used, revoked or expired
+func (t *RefreshToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
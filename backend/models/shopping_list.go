@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ShoppingList is a grocery list built from a user's meal plans or recipes
+type ShoppingList struct {
+	ID         int                `json:"id" db:"id"`
+	UserID     int                `json:"user_id" db:"user_id"`
+	Name       string             `json:"name" db:"name"`
+	MealPlanID *int               `json:"meal_plan_id,omitempty" db:"meal_plan_id"`
+	StartDate  *time.Time         `json:"start_date,omitempty" db:"start_date"`
+	EndDate    *time.Time         `json:"end_date,omitempty" db:"end_date"`
+	Items      []ShoppingListItem `json:"items"`
+	CreatedAt  time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// ShoppingListItem is one merged ingredient on a shopping list. Quantity is
+// nil for ingredients without an amount, such as "salt to taste".
+type ShoppingListItem struct {
+	ID        int        `json:"id" db:"id"`
+	ListID    int        `json:"list_id" db:"list_id"`
+	Name      string     `json:"name" db:"name"`
+	Quantity  *float64   `json:"quantity,omitempty" db:"quantity"`
+	Unit      string     `json:"unit,omitempty" db:"unit"`
+	Category  string     `json:"category" db:"category"`
+	Checked   bool       `json:"checked" db:"checked"`
+	Optional  bool       `json:"optional" db:"optional"`
+	Sources   []string   `json:"sources" db:"sources"`
+	Position  int        `json:"position" db:"position"`
+	CheckedAt *time.Time `json:"checked_at,omitempty" db:"checked_at"`
+}
+
+// PlannedRecipe is a recipe to shop for outside a meal plan
+type PlannedRecipe struct {
+	RecipeID string  `json:"recipe_id" validate:"required"`
+	Servings float64 `json:"servings"`
+}
+
+// CreateShoppingListRequest selects what a shopping list is built from: a
+// meal plan, every plan day in a date range, a set of recipes, or a mix
+type CreateShoppingListRequest struct {
+	Name       string          `json:"name"`
+	MealPlanID *int            `json:"meal_plan_id,omitempty"`
+	StartDate  string          `json:"start_date,omitempty"`
+	EndDate    string          `json:"end_date,omitempty"`
+	Recipes    []PlannedRecipe `json:"recipes,omitempty"`
+}
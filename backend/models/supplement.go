@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Supplement represents a dietary supplement
+type Supplement struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	Name         string     `json:"name"`
+	Brand        string     `json:"brand"`
+	Description  string     `json:"description"`
+	Category     string     `json:"category"` // vitamin, mineral, protein, herbal, etc.
+	Form         string     `json:"form"`     // tablet, capsule, powder, liquid
+	Dosage       string     `json:"dosage"`
+	Frequency    string     `json:"frequency"` // daily, weekly, as needed
+	Timing       string     `json:"timing"`    // morning, evening, with meals, etc.
+	Ingredients  []string   `json:"ingredients"`
+	Benefits     []string   `json:"benefits"`
+	SideEffects  []string   `json:"side_effects,omitempty"`
+	Warnings     []string   `json:"warnings,omitempty"`
+	IsHalal      bool       `json:"is_halal"`
+	IsVegetarian bool       `json:"is_vegetarian"`
+	IsVegan      bool       `json:"is_vegan"`
+	IsOrganic    bool       `json:"is_organic"`
+	Price        float64    `json:"price,omitempty"`
+	Currency     string     `json:"currency,omitempty"`
+	ImageURL     string     `json:"image_url,omitempty"`
+	Tags         []string   `json:"tags"`
+	Notes        string     `json:"notes,omitempty"`
+	StartDate    *time.Time `json:"start_date,omitempty"`
+	EndDate      *time.Time `json:"end_date,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Usage alert conditions. Each is measured over the alert's window, except
+// quota_usage which covers the current UTC day.
+const (
+	UsageAlertErrorRate    = "error_rate"    // percent of requests answered with a 4xx or 5xx status
+	UsageAlertP95Latency   = "p95_latency"   // 95th percentile response time in milliseconds
+	UsageAlertRequestCount = "request_count" // number of requests
+	UsageAlertQuotaUsage   = "quota_usage"   // percent of the key's daily quota used
+)
+
+// UsageAlert represents an alert condition on API key usage. An alert
+// without an APIKeyID applies to every key.
+type UsageAlert struct {
+	ID              string                 `json:"id"`
+	APIKeyID        string                 `json:"api_key_id,omitempty"`
+	Name            string                 `json:"name"`
+	Condition       string                 `json:"condition"`
+	Threshold       float64                `json:"threshold"`
+	WindowMinutes   int                    `json:"window_minutes"`
+	CooldownMinutes int                    `json:"cooldown_minutes"`
+	Enabled         bool                   `json:"enabled"`
+	LastTriggered   *time.Time             `json:"last_triggered"`
+	Metadata        map[string]interface{} `json:"metadata"`
+	CreatedAt       time.Time              `json:"created_at"`
+}
+
+// CreateUsageAlertRequest represents a request to add an alert to an API key
+type CreateUsageAlertRequest struct {
+	Name            string                 `json:"name"`
+	Condition       string                 `json:"condition"`
+	Threshold       float64                `json:"threshold"`
+	WindowMinutes   int                    `json:"window_minutes"`   // defaults to 60
+	CooldownMinutes int                    `json:"cooldown_minutes"` // defaults to 60
+	Metadata        map[string]interface{} `json:"metadata"`
+}
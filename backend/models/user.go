@@ -49,3 +49,13 @@ func (u *User) Validate() error {
 	}
 	return nil
 }
+
+// UserAccount holds the account details access tokens are issued with
+type UserAccount struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Role      string `json:"role"`
+	IsAdmin   bool   `json:"is_admin"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types
+const (
+	WebhookEventMealLogged        = "meal.logged"
+	WebhookEventWeightLogged      = "weight.logged"
+	WebhookEventWorkoutCompleted  = "workout.completed"
+	WebhookEventMilestoneAchieved = "milestone.achieved"
+	WebhookEventPersonalRecord    = "personal_record.set"
+)
+
+// WebhookEventTypes is the catalog of events a subscription can receive
+var WebhookEventTypes = []string{
+	WebhookEventMealLogged,
+	WebhookEventWeightLogged,
+	WebhookEventWorkoutCompleted,
+	WebhookEventMilestoneAchieved,
+	WebhookEventPersonalRecord,
+}
+
+// IsValidWebhookEvent reports whether eventType is in the catalog
+func IsValidWebhookEvent(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookSubscription sends a user's events of the listed types to URL
+type WebhookSubscription struct {
+	ID               string    `json:"id"`
+	UserID           string    `json:"user_id"`
+	APIKeyID         string    `json:"api_key_id,omitempty"`
+	URL              string    `json:"url"`
+	Events           []string  `json:"events"`
+	Description      string    `json:"description,omitempty"`
+	SecretCiphertext string    `json:"-"`
+	Active           bool      `json:"active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether the subscription receives eventType
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscriptionWithSecret is returned when a subscription is created;
+// the signing secret is only shown then
+type WebhookSubscriptionWithSecret struct {
+	*WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// CreateWebhookRequest creates a subscription
+type CreateWebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+}
+
+// UpdateWebhookRequest changes a subscription; omitted fields are kept
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookEvent is the JSON body posted to subscribers
+type WebhookEvent struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	UserID     string      `json:"user_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to one subscription
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// DueWebhookDelivery is a pending delivery together with where to send it
+type DueWebhookDelivery struct {
+	WebhookDelivery
+	URL              string
+	SecretCiphertext string
+}
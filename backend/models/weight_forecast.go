@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// WeightForecast fits a robust trend to a user's weigh-ins and projects it
+// forward. Weights are in the unit the measurements were logged in and
+// changes are per week.
+type WeightForecast struct {
+	UserID       int64     `json:"user_id"`
+	Days         int       `json:"days"`
+	Measurements int       `json:"measurements"`
+	FirstDate    time.Time `json:"first_date"`
+	LastDate     time.Time `json:"last_date"`
+	LatestWeight float64   `json:"latest_weight"`
+	// TrendWeight is the fitted weight on LastDate, which a single noisy
+	// weigh-in does not move
+	TrendWeight      float64 `json:"trend_weight"`
+	WeeklyChange     float64 `json:"weekly_change"`
+	WeeklyChangeLow  float64 `json:"weekly_change_low"`
+	WeeklyChangeHigh float64 `json:"weekly_change_high"`
+	// Summary is a one-line reading of the forecast, e.g. "On pace for
+	// 80.0 by Mar 2, 2027"
+	Summary     string                `json:"summary"`
+	Plateau     *WeightPlateau        `json:"plateau,omitempty"`
+	Goal        *WeightGoalPrediction `json:"goal,omitempty"`
+	Predictions ProgressPredictions   `json:"predictions"`
+}
+
+// WeightPlateau reports that the most recent weigh-ins show no real change
+type WeightPlateau struct {
+	Since        time.Time `json:"since"`
+	Days         int       `json:"days"`
+	WeeklyChange float64   `json:"weekly_change"`
+}
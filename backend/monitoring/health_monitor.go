@@ -11,6 +11,8 @@ import (
 	"sync"
 	"time"
 
+	"nutrition-platform/alerting"
+
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -119,26 +121,23 @@ type SystemInfo struct {
 	RedisConns    int     `json:"redis_connections"`
 }
 
-// AlertManager handles alert notifications
+// AlertManager keeps the health monitor's alert history and routes alerts
+// through the alerting router
 type AlertManager struct {
 	config       *AlertConfig
-	notifiers    []Notifier
 	alertHistory map[string][]Alert
 	mu           sync.RWMutex
 }
 
 // AlertConfig holds alert configuration
 type AlertConfig struct {
-	Enabled         bool
-	WebhookURL      string
-	EmailRecipients []string
-	SlackChannel    string
-	CooldownPeriod  time.Duration
+	Enabled bool
 }
 
 // Alert represents an alert
 type Alert struct {
 	ID         string                 `json:"id"`
+	Type       string                 `json:"type"` // stable name, e.g. "health_check_failed"
 	Severity   string                 `json:"severity"`
 	Title      string                 `json:"title"`
 	Message    string                 `json:"message"`
@@ -148,13 +147,11 @@ type Alert struct {
 	ResolvedAt *time.Time             `json:"resolved_at,omitempty"`
 }
 
-// Notifier interface for alert notifications
-type Notifier interface {
-	Send(alert Alert) error
-}
-
 var startTime = time.Now()
 
+// maxAlertHistory bounds the alerts kept per type
+const maxAlertHistory = 100
+
 // NewHealthMonitor creates a new health monitor
 func NewHealthMonitor(config *MonitorConfig, db *sql.DB, redisClient RedisClient) *HealthMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -284,10 +281,8 @@ func newSystemMetrics() *SystemMetrics {
 func newAlertManager(config *MonitorConfig) *AlertManager {
 	return &AlertManager{
 		config: &AlertConfig{
-			Enabled:        config.EnableAlerts,
-			CooldownPeriod: 5 * time.Minute,
+			Enabled: config.EnableAlerts,
 		},
-		notifiers:    make([]Notifier, 0),
 		alertHistory: make(map[string][]Alert),
 	}
 }
@@ -414,6 +409,9 @@ func (hm *HealthMonitor) executeHealthCheck(name string, check HealthCheck) {
 	// Check for alerts
 	if err != nil && check.Critical {
 		hm.handleHealthCheckFailure(name, check, err)
+	} else if err == nil && check.Critical {
+		// A recovered check closes its incident so the next failure alerts at once
+		alerting.Resolve("health_check", "health_check_failed", map[string]string{"check": name})
 	}
 }
 
@@ -576,6 +574,7 @@ func (hm *HealthMonitor) handleHealthCheckFailure(name string, check HealthCheck
 	// Create alert
 	alert := Alert{
 		ID:        fmt.Sprintf("%s-%d", name, time.Now().Unix()),
+		Type:      "health_check_failed",
 		Severity:  "critical",
 		Title:     fmt.Sprintf("Health Check Failed: %s", name),
 		Message:   fmt.Sprintf("Health check '%s' failed: %v", name, err),
@@ -663,40 +662,30 @@ func (hm *HealthMonitor) livenessHandler(w http.ResponseWriter, r *http.Request)
 
 // Alert management methods
 
+// FireAlert records an alert and routes it through the alerting router, which
+// deduplicates repeated failures of the same check
 func (am *AlertManager) FireAlert(alert Alert) {
 	am.mu.Lock()
-	defer am.mu.Unlock()
-
-	// Check cooldown period
-	if am.isInCooldown(alert) {
-		return
-	}
-
-	// Store alert
-	am.alertHistory[alert.ID] = append(am.alertHistory[alert.ID], alert)
-
-	// Send notifications
-	for _, notifier := range am.notifiers {
-		go func(n Notifier) {
-			if err := n.Send(alert); err != nil {
-				log.Printf("Failed to send alert notification: %v", err)
-			}
-		}(notifier)
-	}
-
-	log.Printf("Alert fired: %s - %s", alert.Title, alert.Message)
-}
-
-func (am *AlertManager) isInCooldown(alert Alert) bool {
-	// Simple cooldown implementation
-	// In production, you'd want more sophisticated logic
-	return false
-}
-
-func (am *AlertManager) AddNotifier(notifier Notifier) {
-	am.mu.Lock()
-	defer am.mu.Unlock()
-	am.notifiers = append(am.notifiers, notifier)
+	history := append(am.alertHistory[alert.Type], alert)
+	if len(history) > maxAlertHistory {
+		history = history[len(history)-maxAlertHistory:]
+	}
+	am.alertHistory[alert.Type] = history
+	am.mu.Unlock()
+
+	labels := make(map[string]string)
+	if check, ok := alert.Metadata["check_name"].(string); ok {
+		labels["check"] = check
+	}
+	alerting.Fire(alerting.Alert{
+		Source:   "health_check",
+		Name:     alert.Type,
+		Severity: alerting.ParseSeverity(alert.Severity),
+		Summary:  alert.Title,
+		Message:  alert.Message,
+		Labels:   labels,
+		FiredAt:  alert.Timestamp,
+	})
 }
 
 // Stop stops the health monitor
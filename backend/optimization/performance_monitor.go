@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"nutrition-platform/alerting"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -310,11 +312,20 @@ func (pm *PerformanceMonitor) GetHealthStatus() map[string]interface{} {
 
 // AlertManager methods
 
-// TriggerAlert creates a new alert
+// TriggerAlert creates a new alert, or refreshes the active alert of the same
+// type. Either way it is routed through the alerting router, which
+// deduplicates it and escalates it if it stays active.
 func (am *AlertManager) TriggerAlert(alertType, message, severity string) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
+	alerting.Fire(alerting.Alert{
+		Source:   "performance",
+		Name:     alertType,
+		Severity: alerting.ParseSeverity(severity),
+		Summary:  message,
+	})
+
 	// Check if alert already exists and is active
 	for i, alert := range am.alerts {
 		if alert.Type == alertType && !alert.Resolved {
@@ -354,6 +365,7 @@ func (am *AlertManager) ResolveAlert(alertType string) {
 			now := time.Now()
 			am.alerts[i].Resolved = true
 			am.alerts[i].ResolvedAt = &now
+			alerting.Resolve("performance", alertType, nil)
 			log.Printf("Alert resolved: %s", alertType)
 			break
 		}
@@ -0,0 +1,325 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// AchievementRepository handles logged activity days, streak counters, the
+// badge catalog and milestones
+type AchievementRepository struct {
+	db *database.Database
+}
+
+// NewAchievementRepository creates a new achievement repository
+func NewAchievementRepository(db *database.Database) *AchievementRepository {
+	return &AchievementRepository{db: db}
+}
+
+// IncrementActivityDay counts one more of an activity on a user's local day
+func (r *AchievementRepository) IncrementActivityDay(ctx context.Context, userID, activity, day string) error {
+	query := `
+		INSERT INTO activity_days (user_id, activity, day, count)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (user_id, activity, day) DO UPDATE
+		SET count = activity_days.count + 1`
+
+	if _, err := r.db.DB.ExecContext(ctx, query, userID, activity, day); err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
+// ListActivityDays returns the days from from on, oldest first, that a user
+// logged an activity. An empty from lists every day.
+func (r *AchievementRepository) ListActivityDays(ctx context.Context, userID, activity, from string) ([]string, error) {
+	query := `
+		SELECT day FROM activity_days
+		WHERE user_id = $1 AND activity = $2 AND day >= $3
+		ORDER BY day ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, activity, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity days: %w", err)
+	}
+	defer rows.Close()
+
+	days := []string{}
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("failed to scan activity day: %w", err)
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// CountActivity returns how many times a user has logged an activity
+func (r *AchievementRepository) CountActivity(ctx context.Context, userID, activity string) (int, error) {
+	query := `SELECT COALESCE(SUM(count), 0) FROM activity_days WHERE user_id = $1 AND activity = $2`
+
+	var count int
+	if err := r.db.DB.QueryRowContext(ctx, query, userID, activity).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count activity: %w", err)
+	}
+	return count, nil
+}
+
+// SaveStreak stores a user's streak counters for an activity
+func (r *AchievementRepository) SaveStreak(ctx context.Context, userID string, streak models.Streak) error {
+	query := `
+		INSERT INTO user_streaks (user_id, activity, current_streak, best_streak, last_day, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, activity) DO UPDATE
+		SET current_streak = excluded.current_streak, best_streak = excluded.best_streak,
+			last_day = excluded.last_day, updated_at = excluded.updated_at`
+
+	_, err := r.db.DB.ExecContext(ctx, query, userID, streak.Activity, streak.Current, streak.Best, streak.LastDay, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save streak: %w", err)
+	}
+	return nil
+}
+
+// ListStreaks returns a user's streak counters as last saved
+func (r *AchievementRepository) ListStreaks(ctx context.Context, userID string) ([]models.Streak, error) {
+	query := `
+		SELECT activity, current_streak, best_streak, last_day
+		FROM user_streaks WHERE user_id = $1
+		ORDER BY activity ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list streaks: %w", err)
+	}
+	defer rows.Close()
+
+	streaks := []models.Streak{}
+	for rows.Next() {
+		var streak models.Streak
+		if err := rows.Scan(&streak.Activity, &streak.Current, &streak.Best, &streak.LastDay); err != nil {
+			return nil, fmt.Errorf("failed to scan streak: %w", err)
+		}
+		streaks = append(streaks, streak)
+	}
+	return streaks, rows.Err()
+}
+
+// ListBadges returns the active badge catalog
+func (r *AchievementRepository) ListBadges(ctx context.Context) ([]models.Badge, error) {
+	query := `
+		SELECT id, name, description, activity, kind, threshold, icon
+		FROM badges WHERE active = $1
+		ORDER BY activity ASC, threshold ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list badges: %w", err)
+	}
+	defer rows.Close()
+
+	badges := []models.Badge{}
+	for rows.Next() {
+		var badge models.Badge
+		if err := rows.Scan(&badge.ID, &badge.Name, &badge.Description, &badge.Activity, &badge.Kind, &badge.Threshold, &badge.Icon); err != nil {
+			return nil, fmt.Errorf("failed to scan badge: %w", err)
+		}
+		badges = append(badges, badge)
+	}
+	return badges, rows.Err()
+}
+
+// AwardBadge gives a user a badge, reporting false if they already had it
+func (r *AchievementRepository) AwardBadge(ctx context.Context, userID, badgeID string, awardedAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO user_badges (user_id, badge_id, awarded_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, badge_id) DO NOTHING`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, badgeID, awardedAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to award badge: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListEarnedBadges returns a user's badges, most recent first
+func (r *AchievementRepository) ListEarnedBadges(ctx context.Context, userID string) ([]models.EarnedBadge, error) {
+	query := `
+		SELECT b.id, b.name, b.description, b.activity, b.kind, b.threshold, b.icon, ub.awarded_at
+		FROM user_badges ub
+		JOIN badges b ON b.id = ub.badge_id
+		WHERE ub.user_id = $1
+		ORDER BY ub.awarded_at DESC, b.threshold DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list earned badges: %w", err)
+	}
+	defer rows.Close()
+
+	earned := []models.EarnedBadge{}
+	for rows.Next() {
+		var badge models.EarnedBadge
+		if err := rows.Scan(&badge.Badge.ID, &badge.Badge.Name, &badge.Badge.Description, &badge.Badge.Activity,
+			&badge.Badge.Kind, &badge.Badge.Threshold, &badge.Badge.Icon, &badge.AwardedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan earned badge: %w", err)
+		}
+		earned = append(earned, badge)
+	}
+	return earned, rows.Err()
+}
+
+const milestoneColumns = `id, user_id, type, title, description, target_value, start_value, current_value,
+	photo_url, is_achieved, achieved_at, created_at, updated_at`
+
+// CreateMilestone stores a new milestone and sets its ID
+func (r *AchievementRepository) CreateMilestone(ctx context.Context, userID string, milestone *models.Milestone) error {
+	query := `
+		INSERT INTO milestones (user_id, type, title, description, target_value, start_value, current_value,
+			photo_url, is_achieved, achieved_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`
+
+	err := r.db.DB.QueryRowContext(ctx, query,
+		userID,
+		milestone.Type,
+		milestone.Title,
+		milestone.Description,
+		milestone.TargetValue,
+		milestone.StartValue,
+		milestone.CurrentValue,
+		milestone.PhotoURL,
+		milestone.IsAchieved,
+		milestoneAchievedAt(milestone),
+		milestone.CreatedAt.UTC(),
+		milestone.UpdatedAt.UTC(),
+	).Scan(&milestone.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create milestone: %w", err)
+	}
+	return nil
+}
+
+// GetMilestone retrieves one of a user's milestones
+func (r *AchievementRepository) GetMilestone(ctx context.Context, id uint, userID string) (*models.Milestone, error) {
+	query := `SELECT ` + milestoneColumns + ` FROM milestones WHERE id = $1 AND user_id = $2`
+
+	milestone, err := scanMilestone(r.db.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("milestone not found")
+		}
+		return nil, fmt.Errorf("failed to get milestone: %w", err)
+	}
+	return milestone, nil
+}
+
+// ListMilestones returns a user's milestones, oldest first
+func (r *AchievementRepository) ListMilestones(ctx context.Context, userID string) ([]*models.Milestone, error) {
+	query := `SELECT ` + milestoneColumns + ` FROM milestones WHERE user_id = $1 ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestones: %w", err)
+	}
+	defer rows.Close()
+
+	milestones := []*models.Milestone{}
+	for rows.Next() {
+		milestone, err := scanMilestone(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan milestone: %w", err)
+		}
+		milestones = append(milestones, milestone)
+	}
+	return milestones, rows.Err()
+}
+
+// UpdateMilestoneProgress saves a milestone's start and current values and
+// whether it has been achieved
+func (r *AchievementRepository) UpdateMilestoneProgress(ctx context.Context, milestone *models.Milestone) error {
+	query := `
+		UPDATE milestones
+		SET start_value = $1, current_value = $2, is_achieved = $3, achieved_at = $4, updated_at = $5
+		WHERE id = $6`
+
+	milestone.UpdatedAt = time.Now().UTC()
+	_, err := r.db.DB.ExecContext(ctx, query,
+		milestone.StartValue,
+		milestone.CurrentValue,
+		milestone.IsAchieved,
+		milestoneAchievedAt(milestone),
+		milestone.UpdatedAt,
+		milestone.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update milestone: %w", err)
+	}
+	return nil
+}
+
+// DeleteMilestone removes one of a user's milestones
+func (r *AchievementRepository) DeleteMilestone(ctx context.Context, id uint, userID string) error {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM milestones WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete milestone: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("milestone not found")
+	}
+	return nil
+}
+
+func milestoneAchievedAt(milestone *models.Milestone) interface{} {
+	if !milestone.IsAchieved {
+		return nil
+	}
+	return milestone.AchievedAt.UTC()
+}
+
+func scanMilestone(row rowScanner) (*models.Milestone, error) {
+	var milestone models.Milestone
+	var userID string
+	var achievedAt sql.NullTime
+	err := row.Scan(
+		&milestone.ID,
+		&userID,
+		&milestone.Type,
+		&milestone.Title,
+		&milestone.Description,
+		&milestone.TargetValue,
+		&milestone.StartValue,
+		&milestone.CurrentValue,
+		&milestone.PhotoURL,
+		&milestone.IsAchieved,
+		&achievedAt,
+		&milestone.CreatedAt,
+		&milestone.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if id, err := strconv.ParseUint(userID, 10, 64); err == nil {
+		milestone.UserID = uint(id)
+	}
+	if achievedAt.Valid {
+		milestone.AchievedAt = achievedAt.Time
+	}
+	return &milestone, nil
+}
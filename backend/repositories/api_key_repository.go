@@ -0,0 +1,499 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// APIKeyRepository handles partner API keys and their usage
+type APIKeyRepository struct {
+	db *database.Database
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *database.Database) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, key_hash, prefix, user_id, status, scopes, rate_limit, daily_quota,
+		expires_at, last_used_at, created_at, updated_at, metadata,
+		previous_key_hash, previous_key_expires_at, rotated_at`
+
+// CreateAPIKey stores a new API key. The ID, hash and timestamps are set by the caller.
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	scopes, metadata, err := encodeAPIKeyLists(key)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO api_keys (id, name, key_hash, prefix, user_id, status, scopes, rate_limit,
+			daily_quota, expires_at, created_at, updated_at, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err = r.db.DB.ExecContext(ctx, query,
+		key.ID,
+		key.Name,
+		key.KeyHash,
+		key.Prefix,
+		key.UserID,
+		key.Status,
+		scopes,
+		key.RateLimit,
+		key.DailyQuota,
+		utcOrNil(key.ExpiresAt),
+		key.CreatedAt.UTC(),
+		key.UpdatedAt.UTC(),
+		metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByID retrieves one of a user's API keys
+func (r *APIKeyRepository) GetAPIKeyByID(ctx context.Context, id, userID string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1 AND user_id = $2`
+
+	key, err := scanAPIKey(r.db.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash retrieves the key whose current hash matches, or whose
+// previous hash matches and is still within its rotation grace period at now
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string, now time.Time) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys
+		WHERE key_hash = $1 OR (previous_key_hash = $2 AND previous_key_expires_at > $3)`
+
+	key, err := scanAPIKey(r.db.DB.QueryRowContext(ctx, query, keyHash, keyHash, now.UTC()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeysByUserID retrieves a page of a user's API keys, newest first, and
+// the total number of keys the user has
+func (r *APIKeyRepository) GetAPIKeysByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.APIKey, int, error) {
+	var total int
+	if err := r.db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM api_keys WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count api keys: %w", err)
+	}
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, total, rows.Err()
+}
+
+// CountUsableAPIKeys counts a user's keys that are not revoked
+func (r *APIKeyRepository) CountUsableAPIKeys(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND status <> $2`,
+		userID, models.APIKeyStatusRevoked,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count api keys: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateAPIKey stores a key's name, status, scopes, limits and metadata
+func (r *APIKeyRepository) UpdateAPIKey(ctx context.Context, key *models.APIKey) error {
+	scopes, metadata, err := encodeAPIKeyLists(key)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE api_keys
+		SET name = $1, status = $2, scopes = $3, rate_limit = $4, daily_quota = $5,
+			metadata = $6, updated_at = $7
+		WHERE id = $8 AND user_id = $9`
+
+	return r.execAPIKeyUpdate(ctx, query,
+		key.Name, key.Status, scopes, key.RateLimit, key.DailyQuota, metadata, key.UpdatedAt.UTC(), key.ID, key.UserID)
+}
+
+// RotateAPIKey replaces a key's hash and prefix. The current hash becomes the
+// previous hash and keeps authenticating until previousExpiresAt.
+func (r *APIKeyRepository) RotateAPIKey(ctx context.Context, id, userID, keyHash, prefix string, previousExpiresAt, now time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET previous_key_hash = key_hash, previous_key_expires_at = $1,
+			key_hash = $2, prefix = $3, rotated_at = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7`
+
+	return r.execAPIKeyUpdate(ctx, query, previousExpiresAt.UTC(), keyHash, prefix, now.UTC(), now.UTC(), id, userID)
+}
+
+// RevokeAPIKey permanently disables a key, including any key it replaced
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id, userID string, now time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET status = $1, previous_key_hash = NULL, previous_key_expires_at = NULL, updated_at = $2
+		WHERE id = $3 AND user_id = $4`
+
+	return r.execAPIKeyUpdate(ctx, query, models.APIKeyStatusRevoked, now.UTC(), id, userID)
+}
+
+// TouchAPIKey records that a key was used
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id string, now time.Time) error {
+	return r.execAPIKeyUpdate(ctx, `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, now.UTC(), id)
+}
+
+func (r *APIKeyRepository) execAPIKeyUpdate(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	return nil
+}
+
+// ConsumeDailyQuota counts one request against a key on day (YYYY-MM-DD,
+// UTC). With a quota above zero the request is only counted while the day's
+// count is below it. It reports whether the request was counted and the
+// day's count afterwards.
+func (r *APIKeyRepository) ConsumeDailyQuota(ctx context.Context, id, day string, quota int) (bool, int, error) {
+	_, err := r.db.DB.ExecContext(ctx, `
+		INSERT INTO api_key_daily_usage (api_key_id, usage_date, request_count)
+		VALUES ($1, $2, 0)
+		ON CONFLICT (api_key_id, usage_date) DO NOTHING`, id, day)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to record api key usage: %w", err)
+	}
+
+	query := `UPDATE api_key_daily_usage SET request_count = request_count + 1
+		WHERE api_key_id = $1 AND usage_date = $2`
+	args := []interface{}{id, day}
+	if quota > 0 {
+		query += ` AND request_count < $3`
+		args = append(args, quota)
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to record api key usage: %w", err)
+	}
+	counted, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	count, err := r.GetDailyUsage(ctx, id, day)
+	if err != nil {
+		return false, 0, err
+	}
+	return counted > 0, count, nil
+}
+
+// GetDailyQuota returns a key's daily quota, 0 for unlimited
+func (r *APIKeyRepository) GetDailyQuota(ctx context.Context, id string) (int, error) {
+	var quota int
+	err := r.db.DB.QueryRowContext(ctx, `SELECT daily_quota FROM api_keys WHERE id = $1`, id).Scan(&quota)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("api key not found")
+		}
+		return 0, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return quota, nil
+}
+
+// GetDailyUsage returns how many requests a key made on day (YYYY-MM-DD, UTC)
+func (r *APIKeyRepository) GetDailyUsage(ctx context.Context, id, day string) (int, error) {
+	var count int
+	err := r.db.DB.QueryRowContext(ctx,
+		`SELECT request_count FROM api_key_daily_usage WHERE api_key_id = $1 AND usage_date = $2`,
+		id, day,
+	).Scan(&count)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get api key usage: %w", err)
+	}
+	return count, nil
+}
+
+// CreateAPIKeyUsage logs requests made with keys, keyed by the row ID to
+// store each under, in one transaction
+func (r *APIKeyRepository) CreateAPIKeyUsage(ctx context.Context, usage map[string]*models.APIKeyUsage) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO api_key_usage (id, api_key_id, endpoint, method, status_code, response_time,
+			ip_address, user_agent, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare api key usage insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for id, entry := range usage {
+		_, err := stmt.ExecContext(ctx,
+			id,
+			entry.APIKeyID,
+			entry.Endpoint,
+			entry.Method,
+			entry.StatusCode,
+			entry.ResponseTime,
+			entry.IPAddress,
+			entry.UserAgent,
+			entry.Timestamp.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to log api key usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit api key usage: %w", err)
+	}
+	return nil
+}
+
+// APIKeyUsageGroup totals a key's requests to one endpoint with one status
+// code on one UTC day
+type APIKeyUsageGroup struct {
+	Day             string
+	Method          string
+	Endpoint        string
+	StatusCode      int
+	Requests        int64
+	TotalLatency    int64
+	MinResponseTime int64
+	MaxResponseTime int64
+}
+
+// GetAPIKeyUsageGroups totals the requests logged for a key at or after since
+// by UTC day, endpoint and status code
+func (r *APIKeyRepository) GetAPIKeyUsageGroups(ctx context.Context, id string, since time.Time) ([]*APIKeyUsageGroup, error) {
+	query := `
+		SELECT date(timestamp) AS day, method, endpoint, status_code, COUNT(*),
+			   SUM(response_time), MIN(response_time), MAX(response_time)
+		FROM api_key_usage
+		WHERE api_key_id = $1 AND timestamp >= $2
+		GROUP BY day, method, endpoint, status_code`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, id, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key usage: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*APIKeyUsageGroup
+	for rows.Next() {
+		var group APIKeyUsageGroup
+		err := rows.Scan(
+			&group.Day,
+			&group.Method,
+			&group.Endpoint,
+			&group.StatusCode,
+			&group.Requests,
+			&group.TotalLatency,
+			&group.MinResponseTime,
+			&group.MaxResponseTime,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key usage: %w", err)
+		}
+		groups = append(groups, &group)
+	}
+
+	return groups, rows.Err()
+}
+
+// APIKeyLatencyCount counts a key's requests to one endpoint that took
+// ResponseTime milliseconds
+type APIKeyLatencyCount struct {
+	Method       string
+	Endpoint     string
+	ResponseTime int64
+	Requests     int64
+}
+
+// GetAPIKeyLatencyCounts counts the requests logged for a key at or after
+// since by endpoint and response time, so percentiles can be computed
+// without loading every request
+func (r *APIKeyRepository) GetAPIKeyLatencyCounts(ctx context.Context, id string, since time.Time) ([]*APIKeyLatencyCount, error) {
+	query := `
+		SELECT method, endpoint, response_time, COUNT(*)
+		FROM api_key_usage
+		WHERE api_key_id = $1 AND timestamp >= $2
+		GROUP BY method, endpoint, response_time`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, id, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key latency: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []*APIKeyLatencyCount
+	for rows.Next() {
+		var count APIKeyLatencyCount
+		if err := rows.Scan(&count.Method, &count.Endpoint, &count.ResponseTime, &count.Requests); err != nil {
+			return nil, fmt.Errorf("failed to scan api key latency: %w", err)
+		}
+		counts = append(counts, &count)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetAPIKeyUsageSince retrieves the requests logged for a key at or after since, oldest first
+func (r *APIKeyRepository) GetAPIKeyUsageSince(ctx context.Context, id string, since time.Time) ([]*models.APIKeyUsage, error) {
+	query := `
+		SELECT api_key_id, endpoint, method, status_code, response_time, ip_address, user_agent, timestamp
+		FROM api_key_usage
+		WHERE api_key_id = $1 AND timestamp >= $2
+		ORDER BY timestamp`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, id, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []*models.APIKeyUsage
+	for rows.Next() {
+		var entry models.APIKeyUsage
+		var ipAddress, userAgent sql.NullString
+		err := rows.Scan(
+			&entry.APIKeyID,
+			&entry.Endpoint,
+			&entry.Method,
+			&entry.StatusCode,
+			&entry.ResponseTime,
+			&ipAddress,
+			&userAgent,
+			&entry.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key usage: %w", err)
+		}
+		entry.IPAddress = ipAddress.String
+		entry.UserAgent = userAgent.String
+		usage = append(usage, &entry)
+	}
+
+	return usage, rows.Err()
+}
+
+func encodeAPIKeyLists(key *models.APIKey) (string, string, error) {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []models.APIKeyScope{}
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode scopes: %w", err)
+	}
+
+	metadata := key.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	return string(scopesJSON), string(metadataJSON), nil
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var metadata, previousHash sql.NullString
+	var expiresAt, lastUsedAt, previousExpiresAt, rotatedAt sql.NullTime
+
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.KeyHash,
+		&key.Prefix,
+		&key.UserID,
+		&key.Status,
+		&scopes,
+		&key.RateLimit,
+		&key.DailyQuota,
+		&expiresAt,
+		&lastUsedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+		&metadata,
+		&previousHash,
+		&previousExpiresAt,
+		&rotatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode scopes: %w", err)
+	}
+	key.Metadata = map[string]interface{}{}
+	if metadata.Valid && metadata.String != "" {
+		if err := json.Unmarshal([]byte(metadata.String), &key.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata: %w", err)
+		}
+	}
+	key.PreviousKeyHash = previousHash.String
+	key.ExpiresAt = timeOrNil(expiresAt)
+	key.LastUsedAt = timeOrNil(lastUsedAt)
+	key.PreviousKeyExpiresAt = timeOrNil(previousExpiresAt)
+	key.RotatedAt = timeOrNil(rotatedAt)
+
+	return &key, nil
+}
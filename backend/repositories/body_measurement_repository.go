@@ -25,9 +25,11 @@ func (r *BodyMeasurementRepository) CreateBodyMeasurement(ctx context.Context, m
 			user_id, measurement_date, weight, height, body_fat_percentage,
 			neck, chest, waist, hips, left_bicep, right_bicep,
 			left_forearm, right_forearm, left_thigh, right_thigh,
-			left_calf, right_calf, notes, created_at, updated_at
+			left_calf, right_calf, body_fat_method, lean_mass, fat_mass,
+			waist_to_hip_ratio, waist_to_height_ratio, ffmi, notes, created_at, updated_at
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
-			$12, $13, $14, $15, $16, $17, $18, $19, $20)
+			$12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22,
+			$23, $24, $25, $26)
 		RETURNING id, created_at, updated_at`
 
 	now := time.Now()
@@ -49,6 +51,12 @@ func (r *BodyMeasurementRepository) CreateBodyMeasurement(ctx context.Context, m
 		measurement.RightThigh,
 		measurement.LeftCalf,
 		measurement.RightCalf,
+		measurement.BodyFatMethod,
+		measurement.LeanMass,
+		measurement.FatMass,
+		measurement.WaistToHipRatio,
+		measurement.WaistToHeightRatio,
+		measurement.FFMI,
 		measurement.Notes,
 		now,
 		now,
@@ -67,7 +75,8 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementByID(ctx context.Context,
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
 			   left_forearm, right_forearm, left_thigh, right_thigh,
-			   left_calf, right_calf, notes, created_at, updated_at
+			   left_calf, right_calf, body_fat_method, lean_mass, fat_mass,
+			   waist_to_hip_ratio, waist_to_height_ratio, ffmi, notes, created_at, updated_at
 		FROM body_measurements
 		WHERE id = $1`
 
@@ -91,6 +100,12 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementByID(ctx context.Context,
 		&measurement.RightThigh,
 		&measurement.LeftCalf,
 		&measurement.RightCalf,
+		&measurement.BodyFatMethod,
+		&measurement.LeanMass,
+		&measurement.FatMass,
+		&measurement.WaistToHipRatio,
+		&measurement.WaistToHeightRatio,
+		&measurement.FFMI,
 		&measurement.Notes,
 		&measurement.CreatedAt,
 		&measurement.UpdatedAt,
@@ -112,7 +127,8 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementsByUserID(ctx context.Cont
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
 			   left_forearm, right_forearm, left_thigh, right_thigh,
-			   left_calf, right_calf, notes, created_at, updated_at
+			   left_calf, right_calf, body_fat_method, lean_mass, fat_mass,
+			   waist_to_hip_ratio, waist_to_height_ratio, ffmi, notes, created_at, updated_at
 		FROM body_measurements
 		WHERE user_id = $1
 		ORDER BY measurement_date DESC, created_at DESC
@@ -146,6 +162,12 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementsByUserID(ctx context.Cont
 			&measurement.RightThigh,
 			&measurement.LeftCalf,
 			&measurement.RightCalf,
+			&measurement.BodyFatMethod,
+			&measurement.LeanMass,
+			&measurement.FatMass,
+			&measurement.WaistToHipRatio,
+			&measurement.WaistToHeightRatio,
+			&measurement.FFMI,
 			&measurement.Notes,
 			&measurement.CreatedAt,
 			&measurement.UpdatedAt,
@@ -169,8 +191,10 @@ func (r *BodyMeasurementRepository) UpdateBodyMeasurement(ctx context.Context, m
 			neck = $5, chest = $6, waist = $7, hips = $8, left_bicep = $9,
 			right_bicep = $10, left_forearm = $11, right_forearm = $12,
 			left_thigh = $13, right_thigh = $14, left_calf = $15, right_calf = $16,
-			notes = $17, updated_at = $18
-		WHERE id = $19 AND user_id = $20
+			body_fat_method = $17, lean_mass = $18, fat_mass = $19,
+			waist_to_hip_ratio = $20, waist_to_height_ratio = $21, ffmi = $22,
+			notes = $23, updated_at = $24
+		WHERE id = $25 AND user_id = $26
 		RETURNING updated_at`
 
 	now := time.Now()
@@ -191,6 +215,12 @@ func (r *BodyMeasurementRepository) UpdateBodyMeasurement(ctx context.Context, m
 		measurement.RightThigh,
 		measurement.LeftCalf,
 		measurement.RightCalf,
+		measurement.BodyFatMethod,
+		measurement.LeanMass,
+		measurement.FatMass,
+		measurement.WaistToHipRatio,
+		measurement.WaistToHeightRatio,
+		measurement.FFMI,
 		measurement.Notes,
 		now,
 		measurement.ID,
@@ -234,7 +264,8 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementsByDateRange(ctx context.C
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
 			   left_forearm, right_forearm, left_thigh, right_thigh,
-			   left_calf, right_calf, notes, created_at, updated_at
+			   left_calf, right_calf, body_fat_method, lean_mass, fat_mass,
+			   waist_to_hip_ratio, waist_to_height_ratio, ffmi, notes, created_at, updated_at
 		FROM body_measurements
 		WHERE user_id = $1 AND measurement_date BETWEEN $2 AND $3
 		ORDER BY measurement_date DESC, created_at DESC
@@ -268,6 +299,12 @@ func (r *BodyMeasurementRepository) GetBodyMeasurementsByDateRange(ctx context.C
 			&measurement.RightThigh,
 			&measurement.LeftCalf,
 			&measurement.RightCalf,
+			&measurement.BodyFatMethod,
+			&measurement.LeanMass,
+			&measurement.FatMass,
+			&measurement.WaistToHipRatio,
+			&measurement.WaistToHeightRatio,
+			&measurement.FFMI,
 			&measurement.Notes,
 			&measurement.CreatedAt,
 			&measurement.UpdatedAt,
@@ -289,7 +326,8 @@ func (r *BodyMeasurementRepository) GetLatestBodyMeasurement(ctx context.Context
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
 			   left_forearm, right_forearm, left_thigh, right_thigh,
-			   left_calf, right_calf, notes, created_at, updated_at
+			   left_calf, right_calf, body_fat_method, lean_mass, fat_mass,
+			   waist_to_hip_ratio, waist_to_height_ratio, ffmi, notes, created_at, updated_at
 		FROM body_measurements
 		WHERE user_id = $1
 		ORDER BY measurement_date DESC, created_at DESC
@@ -315,6 +353,12 @@ func (r *BodyMeasurementRepository) GetLatestBodyMeasurement(ctx context.Context
 		&measurement.RightThigh,
 		&measurement.LeftCalf,
 		&measurement.RightCalf,
+		&measurement.BodyFatMethod,
+		&measurement.LeanMass,
+		&measurement.FatMass,
+		&measurement.WaistToHipRatio,
+		&measurement.WaistToHeightRatio,
+		&measurement.FFMI,
 		&measurement.Notes,
 		&measurement.CreatedAt,
 		&measurement.UpdatedAt,
@@ -351,11 +395,14 @@ func (r *BodyMeasurementRepository) GetWeightTrend(ctx context.Context, userID i
 			weight as value
 		FROM body_measurements
 		WHERE user_id = $1 
-			AND measurement_date >= NOW() - INTERVAL '%d days'
+			AND measurement_date >= $2
+			AND weight IS NOT NULL
 		ORDER BY measurement_date ASC`
 
-	query = fmt.Sprintf(query, days)
-	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	// The cutoff is bound rather than computed with NOW() - INTERVAL so the
+	// query also runs on SQLite
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, cutoff)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get weight trend: %w", err)
 	}
@@ -368,6 +415,7 @@ func (r *BodyMeasurementRepository) GetWeightTrend(ctx context.Context, userID i
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan weight trend point: %w", err)
 		}
+		point.Weight = point.Value
 		trendPoints = append(trendPoints, &point)
 	}
 
@@ -550,7 +598,8 @@ func (r *BodyMeasurementRepository) SearchBodyMeasurements(ctx context.Context,
 		SELECT id, user_id, measurement_date, weight, height, body_fat_percentage,
 			   neck, chest, waist, hips, left_bicep, right_bicep,
 			   left_forearm, right_forearm, left_thigh, right_thigh,
-			   left_calf, right_calf, notes, created_at, updated_at
+			   left_calf, right_calf, body_fat_method, lean_mass, fat_mass,
+			   waist_to_hip_ratio, waist_to_height_ratio, ffmi, notes, created_at, updated_at
 		FROM body_measurements
 		WHERE user_id = $1 AND notes ILIKE $2
 		ORDER BY measurement_date DESC, created_at DESC
@@ -584,6 +633,12 @@ func (r *BodyMeasurementRepository) SearchBodyMeasurements(ctx context.Context,
 			&measurement.RightThigh,
 			&measurement.LeftCalf,
 			&measurement.RightCalf,
+			&measurement.BodyFatMethod,
+			&measurement.LeanMass,
+			&measurement.FatMass,
+			&measurement.WaistToHipRatio,
+			&measurement.WaistToHeightRatio,
+			&measurement.FFMI,
 			&measurement.Notes,
 			&measurement.CreatedAt,
 			&measurement.UpdatedAt,
@@ -0,0 +1,401 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// CoachClientRepository handles coach/client relationships, the permissions
+// clients grant on them and the coach access log
+type CoachClientRepository struct {
+	db *database.Database
+}
+
+// NewCoachClientRepository creates a new coach/client repository
+func NewCoachClientRepository(db *database.Database) *CoachClientRepository {
+	return &CoachClientRepository{db: db}
+}
+
+const coachClientColumns = `id, coach_id, client_id, invite_email, invite_token_hash, requested_permissions,
+	status, invite_expires_at, accepted_at, revoked_at, created_at`
+
+// CreateInvitation stores a pending relationship
+func (r *CoachClientRepository) CreateInvitation(ctx context.Context, rel *models.CoachClient) error {
+	requested, err := encodeStringList(rel.RequestedPermissions)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO coach_clients (id, coach_id, invite_email, invite_token_hash, requested_permissions,
+			status, invite_expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = r.db.DB.ExecContext(ctx, query,
+		rel.ID,
+		rel.CoachID,
+		nullIfEmpty(rel.InviteEmail),
+		rel.InviteTokenHash,
+		requested,
+		rel.Status,
+		rel.InviteExpiresAt.UTC(),
+		rel.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create coach invitation: %w", err)
+	}
+	return nil
+}
+
+// GetByTokenHash retrieves the relationship an invitation token belongs to
+func (r *CoachClientRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.CoachClient, error) {
+	query := `SELECT ` + coachClientColumns + ` FROM coach_clients WHERE invite_token_hash = $1`
+
+	rel, err := scanCoachClient(r.db.DB.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("coach invitation not found")
+		}
+		return nil, fmt.Errorf("failed to get coach invitation: %w", err)
+	}
+	return rel, nil
+}
+
+// GetRelationship retrieves a relationship with its granted permissions
+func (r *CoachClientRepository) GetRelationship(ctx context.Context, id string) (*models.CoachClient, error) {
+	query := `SELECT ` + coachClientColumns + ` FROM coach_clients WHERE id = $1`
+
+	rel, err := scanCoachClient(r.db.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("coach client relationship not found")
+		}
+		return nil, fmt.Errorf("failed to get coach client relationship: %w", err)
+	}
+	if rel.Permissions, err = r.getGrants(ctx, rel.ID); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// GetActiveRelationship retrieves the active relationship between a coach
+// and a client
+func (r *CoachClientRepository) GetActiveRelationship(ctx context.Context, coachID, clientID string) (*models.CoachClient, error) {
+	query := `SELECT ` + coachClientColumns + `
+		FROM coach_clients
+		WHERE coach_id = $1 AND client_id = $2 AND status = $3`
+
+	rel, err := scanCoachClient(r.db.DB.QueryRowContext(ctx, query, coachID, clientID, models.CoachClientActive))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("coach client relationship not found")
+		}
+		return nil, fmt.Errorf("failed to get coach client relationship: %w", err)
+	}
+	if rel.Permissions, err = r.getGrants(ctx, rel.ID); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// ListForCoach returns a coach's pending and active relationships
+func (r *CoachClientRepository) ListForCoach(ctx context.Context, coachID string) ([]*models.CoachClient, error) {
+	query := `SELECT ` + coachClientColumns + `
+		FROM coach_clients
+		WHERE coach_id = $1 AND status IN ($2, $3)
+		ORDER BY created_at DESC`
+
+	return r.list(ctx, query, coachID, models.CoachClientPending, models.CoachClientActive)
+}
+
+// ListForClient returns the client's active relationships
+func (r *CoachClientRepository) ListForClient(ctx context.Context, clientID string) ([]*models.CoachClient, error) {
+	query := `SELECT ` + coachClientColumns + `
+		FROM coach_clients
+		WHERE client_id = $1 AND status = $2
+		ORDER BY accepted_at DESC`
+
+	return r.list(ctx, query, clientID, models.CoachClientActive)
+}
+
+// AcceptInvitation activates a pending, unexpired invitation for clientID
+// and stores the granted permissions in one transaction. It reports false
+// when the invitation was already used, revoked or has expired.
+func (r *CoachClientRepository) AcceptInvitation(ctx context.Context, id, clientID string, permissions []string, now time.Time) (bool, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	accepted, err := execAffected(ctx, tx, `
+		UPDATE coach_clients SET client_id = $1, status = $2, accepted_at = $3
+		WHERE id = $4 AND status = $5 AND invite_expires_at > $6`,
+		clientID, models.CoachClientActive, now.UTC(), id, models.CoachClientPending, now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to accept coach invitation: %w", err)
+	}
+	if !accepted {
+		return false, nil
+	}
+	if err := insertCoachGrants(ctx, tx, id, permissions, now); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit coach invitation: %w", err)
+	}
+	return true, nil
+}
+
+// ReplaceGrants replaces the permissions on an active relationship of
+// clientID. It reports false when there is no such relationship.
+func (r *CoachClientRepository) ReplaceGrants(ctx context.Context, id, clientID string, permissions []string, now time.Time) (bool, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx,
+		"SELECT 1 FROM coach_clients WHERE id = $1 AND client_id = $2 AND status = $3",
+		id, clientID, models.CoachClientActive).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get coach client relationship: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM coach_client_grants WHERE relationship_id = $1", id); err != nil {
+		return false, fmt.Errorf("failed to delete coach grants: %w", err)
+	}
+	if err := insertCoachGrants(ctx, tx, id, permissions, now); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit coach grants: %w", err)
+	}
+	return true, nil
+}
+
+// Revoke ends a pending or active relationship and drops its grants. It
+// reports false when the relationship had already ended.
+func (r *CoachClientRepository) Revoke(ctx context.Context, id string, now time.Time) (bool, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	revoked, err := execAffected(ctx, tx,
+		"UPDATE coach_clients SET status = $1, revoked_at = $2 WHERE id = $3 AND status IN ($4, $5)",
+		models.CoachClientRevoked, now.UTC(), id, models.CoachClientPending, models.CoachClientActive)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke coach client relationship: %w", err)
+	}
+	if !revoked {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM coach_client_grants WHERE relationship_id = $1", id); err != nil {
+		return false, fmt.Errorf("failed to delete coach grants: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit coach client revocation: %w", err)
+	}
+	return true, nil
+}
+
+// HasGrant reports whether coachID has an active relationship with clientID
+// that grants permission
+func (r *CoachClientRepository) HasGrant(ctx context.Context, coachID, clientID, permission string) (bool, error) {
+	query := `
+		SELECT 1
+		FROM coach_clients c
+		JOIN coach_client_grants g ON g.relationship_id = c.id
+		WHERE c.coach_id = $1 AND c.client_id = $2 AND c.status = $3 AND g.permission = $4`
+
+	var found int
+	err := r.db.DB.QueryRowContext(ctx, query, coachID, clientID, models.CoachClientActive, permission).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check coach grant: %w", err)
+	}
+	return true, nil
+}
+
+// LogAccess records a coach request for a client's data
+func (r *CoachClientRepository) LogAccess(ctx context.Context, entry *models.CoachAccessLogEntry) error {
+	query := `
+		INSERT INTO coach_access_log (id, coach_id, client_id, permission, method, path, allowed,
+			ip_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		entry.ID,
+		entry.CoachID,
+		entry.ClientID,
+		entry.Permission,
+		entry.Method,
+		entry.Path,
+		entry.Allowed,
+		nullIfEmpty(entry.IPAddress),
+		entry.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log coach access: %w", err)
+	}
+	return nil
+}
+
+// ListAccessLog returns coach accesses to a client's data, newest first.
+// An empty coachID includes every coach.
+func (r *CoachClientRepository) ListAccessLog(ctx context.Context, clientID, coachID string, limit, offset int) ([]*models.CoachAccessLogEntry, error) {
+	query := `
+		SELECT id, coach_id, client_id, permission, method, path, allowed, ip_address, created_at
+		FROM coach_access_log
+		WHERE client_id = $1 AND ($2 = '' OR coach_id = $3)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, clientID, coachID, coachID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coach access log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.CoachAccessLogEntry{}
+	for rows.Next() {
+		var entry models.CoachAccessLogEntry
+		var ip sql.NullString
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.CoachID,
+			&entry.ClientID,
+			&entry.Permission,
+			&entry.Method,
+			&entry.Path,
+			&entry.Allowed,
+			&ip,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan coach access log entry: %w", err)
+		}
+		entry.IPAddress = ip.String
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate coach access log: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *CoachClientRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.CoachClient, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coach client relationships: %w", err)
+	}
+	defer rows.Close()
+
+	relationships := []*models.CoachClient{}
+	for rows.Next() {
+		rel, err := scanCoachClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coach client relationship: %w", err)
+		}
+		relationships = append(relationships, rel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate coach client relationships: %w", err)
+	}
+	rows.Close()
+
+	for _, rel := range relationships {
+		if rel.Permissions, err = r.getGrants(ctx, rel.ID); err != nil {
+			return nil, err
+		}
+	}
+	return relationships, nil
+}
+
+func (r *CoachClientRepository) getGrants(ctx context.Context, relationshipID string) ([]string, error) {
+	rows, err := r.db.DB.QueryContext(ctx,
+		"SELECT permission FROM coach_client_grants WHERE relationship_id = $1 ORDER BY permission",
+		relationshipID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coach grants: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("failed to scan coach grant: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate coach grants: %w", err)
+	}
+	return permissions, nil
+}
+
+func insertCoachGrants(ctx context.Context, tx *sql.Tx, relationshipID string, permissions []string, now time.Time) error {
+	for _, permission := range permissions {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO coach_client_grants (relationship_id, permission, granted_at) VALUES ($1, $2, $3)",
+			relationshipID, permission, now.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to grant coach permission: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanCoachClient(row rowScanner) (*models.CoachClient, error) {
+	var rel models.CoachClient
+	var clientID, inviteEmail, requested sql.NullString
+	var acceptedAt, revokedAt sql.NullTime
+
+	err := row.Scan(
+		&rel.ID,
+		&rel.CoachID,
+		&clientID,
+		&inviteEmail,
+		&rel.InviteTokenHash,
+		&requested,
+		&rel.Status,
+		&rel.InviteExpiresAt,
+		&acceptedAt,
+		&revokedAt,
+		&rel.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rel.ClientID = clientID.String
+	rel.InviteEmail = inviteEmail.String
+	if rel.RequestedPermissions, err = decodeStringList(requested); err != nil {
+		return nil, err
+	}
+	rel.Permissions = []string{}
+	rel.AcceptedAt = timeOrNil(acceptedAt)
+	rel.RevokedAt = timeOrNil(revokedAt)
+	return &rel, nil
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// FoodSourceCatalog marks foods imported from an external product database.
+// Catalog foods have no owner and are visible to every user.
+const FoodSourceCatalog = "catalog"
+
+// FoodCatalogRepository handles foods imported from product databases
+type FoodCatalogRepository struct {
+	db *database.Database
+}
+
+// NewFoodCatalogRepository creates a new food catalog repository
+func NewFoodCatalogRepository(db *database.Database) *FoodCatalogRepository {
+	return &FoodCatalogRepository{db: db}
+}
+
+// GetCatalogFoodByBarcode retrieves the imported food stored under any of the
+// given barcode spellings
+func (r *FoodCatalogRepository) GetCatalogFoodByBarcode(ctx context.Context, barcodes ...string) (*models.Food, error) {
+	if len(barcodes) == 0 {
+		return nil, fmt.Errorf("food not found")
+	}
+
+	placeholders := make([]string, len(barcodes))
+	args := []interface{}{FoodSourceCatalog}
+	for i, code := range barcodes {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args = append(args, code)
+	}
+
+	query := `
+		SELECT id, name, brand, bar_code, serving_size,
+			calories, protein, carbs, fat, saturated_fat, fiber, sugar, sodium, cholesterol,
+			potassium, source_type, data_source, verified, created_at, updated_at
+		FROM foods
+		WHERE source_type = $1 AND bar_code IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY verified DESC
+		LIMIT 1`
+
+	var food models.Food
+	var brand, barcode, servingSize, dataSource sql.NullString
+
+	err := r.db.DB.QueryRowContext(ctx, query, args...).Scan(
+		&food.ID,
+		&food.Name,
+		&brand,
+		&barcode,
+		&servingSize,
+		&food.Calories,
+		&food.Protein,
+		&food.Carbs,
+		&food.Fat,
+		&food.SaturatedFat,
+		&food.Fiber,
+		&food.Sugar,
+		&food.Sodium,
+		&food.Cholesterol,
+		&food.Potassium,
+		&food.SourceType,
+		&dataSource,
+		&food.Verified,
+		&food.CreatedAt,
+		&food.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("food not found")
+		}
+		return nil, fmt.Errorf("failed to get catalog food: %w", err)
+	}
+
+	if brand.Valid {
+		food.Brand = &brand.String
+	}
+	if barcode.Valid {
+		food.BarCode = &barcode.String
+		food.Barcode = &barcode.String
+	}
+	if dataSource.Valid {
+		food.DataSource = &dataSource.String
+	}
+	food.ServingSize = servingSize.String
+	food.IsVerified = food.Verified
+
+	return &food, nil
+}
+
+// CreateCatalogFood inserts an imported food
+func (r *FoodCatalogRepository) CreateCatalogFood(ctx context.Context, food *models.Food) error {
+	query := `
+		INSERT INTO foods (name, brand, bar_code, serving_size,
+			calories, protein, carbs, fat, saturated_fat, fiber, sugar, sodium, cholesterol,
+			potassium, source_type, data_source, verified, imported_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		RETURNING id`
+
+	now := time.Now()
+	err := r.db.DB.QueryRowContext(ctx, query,
+		food.Name,
+		food.Brand,
+		food.BarCode,
+		food.ServingSize,
+		food.Calories,
+		food.Protein,
+		food.Carbs,
+		food.Fat,
+		food.SaturatedFat,
+		food.Fiber,
+		food.Sugar,
+		food.Sodium,
+		food.Cholesterol,
+		food.Potassium,
+		FoodSourceCatalog,
+		food.DataSource,
+		food.Verified,
+		now,
+		now,
+		now,
+	).Scan(&food.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create catalog food: %w", err)
+	}
+
+	food.SourceType = FoodSourceCatalog
+	food.CreatedAt = now
+	food.UpdatedAt = now
+	return nil
+}
+
+// UpdateCatalogFood replaces the product data of an imported food
+func (r *FoodCatalogRepository) UpdateCatalogFood(ctx context.Context, food *models.Food) error {
+	query := `
+		UPDATE foods
+		SET name = $2, brand = $3, bar_code = $4, serving_size = $5,
+			calories = $6, protein = $7, carbs = $8, fat = $9, saturated_fat = $10,
+			fiber = $11, sugar = $12, sodium = $13, cholesterol = $14, potassium = $15,
+			data_source = $16, verified = $17, imported_at = $18, updated_at = $18
+		WHERE id = $1 AND source_type = $19`
+
+	now := time.Now()
+	_, err := r.db.DB.ExecContext(ctx, query,
+		food.ID,
+		food.Name,
+		food.Brand,
+		food.BarCode,
+		food.ServingSize,
+		food.Calories,
+		food.Protein,
+		food.Carbs,
+		food.Fat,
+		food.SaturatedFat,
+		food.Fiber,
+		food.Sugar,
+		food.Sodium,
+		food.Cholesterol,
+		food.Potassium,
+		food.DataSource,
+		food.Verified,
+		now,
+		FoodSourceCatalog,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update catalog food: %w", err)
+	}
+
+	food.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// FoodLogRepository handles food log and food portion database operations
+type FoodLogRepository struct {
+	db *database.Database
+}
+
+// NewFoodLogRepository creates a new food log repository
+func NewFoodLogRepository(db *database.Database) *FoodLogRepository {
+	return &FoodLogRepository{db: db}
+}
+
+// CreateFoodLog stores a food log entry. Quantity must already be in grams,
+// except for recipe entries which are stored in servings without a food_id.
+func (r *FoodLogRepository) CreateFoodLog(ctx context.Context, log *models.UserFoodLog) error {
+	query := `
+		INSERT INTO user_food_logs (
+			user_id, food_id, recipe_id, quantity, unit, input_quantity, input_unit, meal_type,
+			consumed_at, calories, protein, carbs, fat, fiber, sugar, sodium, notes, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING id`
+
+	unit := "g"
+	var foodID sql.NullInt64
+	if log.FoodID > 0 {
+		foodID = sql.NullInt64{Int64: int64(log.FoodID), Valid: true}
+	}
+	if log.RecipeID != nil {
+		unit = "serving"
+	}
+
+	now := time.Now()
+	err := r.db.DB.QueryRowContext(ctx, query,
+		log.UserID,
+		foodID,
+		log.RecipeID,
+		log.Quantity,
+		unit,
+		log.InputQuantity,
+		log.InputUnit,
+		log.MealType,
+		log.LoggedAt,
+		log.Nutrition.Calories,
+		log.Nutrition.Protein,
+		log.Nutrition.Carbohydrates,
+		log.Nutrition.Fat,
+		log.Nutrition.Fiber,
+		log.Nutrition.Sugar,
+		log.Nutrition.Sodium,
+		log.Notes,
+		now,
+	).Scan(&log.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create food log: %w", err)
+	}
+
+	log.CreatedAt = now
+	log.UpdatedAt = now
+	return nil
+}
+
+// GetFoodLogsByDateRange retrieves a user's food logs consumed in [from, to)
+func (r *FoodLogRepository) GetFoodLogsByDateRange(ctx context.Context, userID int, from, to time.Time) ([]*models.UserFoodLog, error) {
+	query := `
+		SELECT l.id, l.user_id, l.food_id, l.recipe_id, COALESCE(f.name, r.name, ''),
+			   l.quantity, l.input_quantity, l.input_unit, l.meal_type, l.consumed_at,
+			   l.calories, l.protein, l.carbs, l.fat, l.fiber, l.sugar, l.sodium, l.notes, l.created_at
+		FROM user_food_logs l
+		LEFT JOIN foods f ON f.id = l.food_id
+		LEFT JOIN recipes r ON r.id = l.recipe_id
+		WHERE l.user_id = $1 AND l.consumed_at >= $2 AND l.consumed_at < $3
+		ORDER BY l.consumed_at ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get food logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*models.UserFoodLog
+	for rows.Next() {
+		var log models.UserFoodLog
+		var foodID sql.NullInt64
+		var inputQuantity, calories, protein, carbs, fat, fiber, sugar, sodium sql.NullFloat64
+		var inputUnit, mealType sql.NullString
+
+		err := rows.Scan(
+			&log.ID,
+			&log.UserID,
+			&foodID,
+			&log.RecipeID,
+			&log.FoodName,
+			&log.Quantity,
+			&inputQuantity,
+			&inputUnit,
+			&mealType,
+			&log.LoggedAt,
+			&calories,
+			&protein,
+			&carbs,
+			&fat,
+			&fiber,
+			&sugar,
+			&sodium,
+			&log.Notes,
+			&log.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan food log: %w", err)
+		}
+
+		log.FoodID = int(foodID.Int64)
+		log.InputQuantity = inputQuantity.Float64
+		log.InputUnit = inputUnit.String
+		// Rows logged before unit support recorded grams only
+		if !inputQuantity.Valid {
+			log.InputQuantity = log.Quantity
+			log.InputUnit = "g"
+		}
+		log.MealType = mealType.String
+		log.Nutrition = models.NutritionInfo{
+			Calories:      calories.Float64,
+			Protein:       protein.Float64,
+			Carbohydrates: carbs.Float64,
+			Fat:           fat.Float64,
+			Fiber:         fiber.Float64,
+			Sugar:         sugar.Float64,
+			Sodium:        sodium.Float64,
+		}
+		log.UpdatedAt = log.CreatedAt
+		logs = append(logs, &log)
+	}
+
+	return logs, rows.Err()
+}
+
+// GetFoodPortions retrieves the household unit weights defined for a food
+func (r *FoodLogRepository) GetFoodPortions(ctx context.Context, foodID uint) ([]models.FoodPortion, error) {
+	query := `
+		SELECT id, food_id, unit, grams, description, created_at
+		FROM food_portions
+		WHERE food_id = $1
+		ORDER BY unit`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, foodID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get food portions: %w", err)
+	}
+	defer rows.Close()
+
+	var portions []models.FoodPortion
+	for rows.Next() {
+		var p models.FoodPortion
+		if err := rows.Scan(&p.ID, &p.FoodID, &p.Unit, &p.Grams, &p.Description, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan food portion: %w", err)
+		}
+		portions = append(portions, p)
+	}
+
+	return portions, rows.Err()
+}
+
+// UpsertFoodPortion creates or replaces the gram weight of a unit for a food
+func (r *FoodLogRepository) UpsertFoodPortion(ctx context.Context, portion *models.FoodPortion) error {
+	query := `
+		INSERT INTO food_portions (food_id, unit, grams, description, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (food_id, unit) DO UPDATE
+		SET grams = excluded.grams, description = excluded.description
+		RETURNING id`
+
+	portion.CreatedAt = time.Now()
+	err := r.db.DB.QueryRowContext(ctx, query,
+		portion.FoodID,
+		portion.Unit,
+		portion.Grams,
+		portion.Description,
+		portion.CreatedAt,
+	).Scan(&portion.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save food portion: %w", err)
+	}
+
+	return nil
+}
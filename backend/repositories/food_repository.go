@@ -65,7 +65,7 @@ func (r *FoodRepository) GetFoodByID(id, userID string) (*models.Food, error) {
 			calories, protein, carbs, fat, saturated_fat, fiber, sugar, sodium, cholesterol,
 			potassium, source_type, verified, created_at, updated_at
 		FROM foods 
-		WHERE id = $1 AND (user_id = $2 OR source_type = 'global')`
+		WHERE id = $1 AND (user_id = $2 OR source_type IN ('global', 'catalog'))`
 
 	var food models.Food
 	var sourceType sql.NullString
@@ -110,7 +110,7 @@ func (r *FoodRepository) GetFoodByID(id, userID string) (*models.Food, error) {
 
 // SearchFoods searches for foods based on query and filters
 func (r *FoodRepository) SearchFoods(userID, query string, filters models.FoodSearchFilters, limit, offset int) ([]*models.Food, error) {
-	whereClauses := []string{"(user_id = $1 OR source_type = 'global')"}
+	whereClauses := []string{"(user_id = $1 OR source_type IN ('global', 'catalog'))"}
 	args := []interface{}{userID}
 	argIndex := 2
 
@@ -369,3 +369,103 @@ func (r *FoodRepository) GetUserFoods(userID string, limit, offset int) ([]*mode
 
 	return foods, nil
 }
+
+// FindFoodsByNameTerms retrieves foods visible to the user whose name contains
+// any of the given lowercase terms, shortest (most generic) names first. It
+// uses LIKE rather than ILIKE so it works on both SQLite and PostgreSQL.
+func (r *FoodRepository) FindFoodsByNameTerms(userID string, terms []string, limit int) ([]*models.Food, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{userID}
+	conditions := make([]string, len(terms))
+	for i, term := range terms {
+		conditions[i] = fmt.Sprintf("LOWER(name) LIKE $%d", i+2)
+		args = append(args, "%"+term+"%")
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, name, brand, description, bar_code, serving_size,
+			calories, protein, carbs, fat, saturated_fat, fiber, sugar, sodium, cholesterol,
+			potassium, source_type, verified, created_at, updated_at
+		FROM foods
+		WHERE (user_id = $1 OR source_type IN ('global', 'catalog')) AND (%s)
+		ORDER BY LENGTH(name) ASC
+		LIMIT $%d`, strings.Join(conditions, " OR "), len(terms)+2)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find foods: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFoodRows(rows)
+}
+
+// ListPlannableFoods returns foods with energy values that the user may plan
+// meals with: their own foods and the global and catalog ones, verified first
+func (r *FoodRepository) ListPlannableFoods(userID string, limit int) ([]*models.Food, error) {
+	query := `
+		SELECT id, user_id, name, brand, description, bar_code, serving_size,
+			calories, protein, carbs, fat, saturated_fat, fiber, sugar, sodium, cholesterol,
+			potassium, source_type, verified, created_at, updated_at
+		FROM foods
+		WHERE (user_id = $1 OR source_type IN ('global', 'catalog')) AND calories > 0
+		ORDER BY verified DESC, id ASC
+		LIMIT $2`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foods: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFoodRows(rows)
+}
+
+// scanFoodRows reads rows selected with the column list of FindFoodsByNameTerms
+func scanFoodRows(rows *sql.Rows) ([]*models.Food, error) {
+	var foods []*models.Food
+	for rows.Next() {
+		var food models.Food
+		var sourceType sql.NullString
+
+		err := rows.Scan(
+			&food.ID,
+			&food.UserID,
+			&food.Name,
+			&food.Brand,
+			&food.Description,
+			&food.BarCode,
+			&food.ServingSize,
+			&food.Calories,
+			&food.Protein,
+			&food.Carbs,
+			&food.Fat,
+			&food.SaturatedFat,
+			&food.Fiber,
+			&food.Sugar,
+			&food.Sodium,
+			&food.Cholesterol,
+			&food.Potassium,
+			&sourceType,
+			&food.Verified,
+			&food.CreatedAt,
+			&food.UpdatedAt,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan food row: %w", err)
+		}
+
+		if sourceType.Valid {
+			food.SourceType = sourceType.String
+		}
+
+		foods = append(foods, &food)
+	}
+
+	return foods, rows.Err()
+}
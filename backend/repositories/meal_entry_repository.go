@@ -0,0 +1,257 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// MealEntryRepository handles the meals users record through the meals API
+type MealEntryRepository struct {
+	db *database.Database
+}
+
+// NewMealEntryRepository creates a new meal entry repository
+func NewMealEntryRepository(db *database.Database) *MealEntryRepository {
+	return &MealEntryRepository{db: db}
+}
+
+const mealEntryColumns = `id, user_id, name, description, calories, protein, carbs, fat, fiber, sugar, sodium,
+		meal_type, ingredients, is_halal, tags, image_url, created_at, updated_at`
+
+// CreateMealEntry stores a meal. The ID and timestamps are set by the caller.
+func (r *MealEntryRepository) CreateMealEntry(ctx context.Context, meal *models.MealEntry) error {
+	if _, err := r.insertMealEntry(ctx, meal, ""); err != nil {
+		return fmt.Errorf("failed to create meal: %w", err)
+	}
+	return nil
+}
+
+// ImportMealEntry stores a meal unless one with the same ID exists and
+// reports whether it was inserted
+func (r *MealEntryRepository) ImportMealEntry(ctx context.Context, meal *models.MealEntry) (bool, error) {
+	inserted, err := r.insertMealEntry(ctx, meal, "ON CONFLICT (id) DO NOTHING")
+	if err != nil {
+		return false, fmt.Errorf("failed to import meal: %w", err)
+	}
+	return inserted, nil
+}
+
+func (r *MealEntryRepository) insertMealEntry(ctx context.Context, meal *models.MealEntry, onConflict string) (bool, error) {
+	ingredients, err := encodeStringList(meal.Ingredients)
+	if err != nil {
+		return false, err
+	}
+	tags, err := encodeStringList(meal.Tags)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO meals (` + mealEntryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) ` + onConflict
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		meal.ID,
+		meal.UserID,
+		meal.Name,
+		meal.Description,
+		meal.Calories,
+		meal.Protein,
+		meal.Carbs,
+		meal.Fat,
+		meal.Fiber,
+		meal.Sugar,
+		meal.Sodium,
+		meal.MealType,
+		ingredients,
+		meal.IsHalal,
+		tags,
+		meal.ImageURL,
+		meal.CreatedAt.UTC(),
+		meal.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetMealEntryByID retrieves a meal by ID
+func (r *MealEntryRepository) GetMealEntryByID(ctx context.Context, id string) (*models.MealEntry, error) {
+	query := `SELECT ` + mealEntryColumns + ` FROM meals WHERE id = $1`
+
+	meal, err := scanMealEntry(r.db.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("meal not found")
+		}
+		return nil, fmt.Errorf("failed to get meal: %w", err)
+	}
+	return meal, nil
+}
+
+// GetMealEntriesByUserID retrieves a user's meals, oldest first
+func (r *MealEntryRepository) GetMealEntriesByUserID(ctx context.Context, userID string) ([]*models.MealEntry, error) {
+	query := `SELECT ` + mealEntryColumns + ` FROM meals WHERE user_id = $1 ORDER BY created_at ASC`
+	return r.queryMealEntries(ctx, query, userID)
+}
+
+// GetMealEntriesByDateRange retrieves a user's meals created in [from, to)
+func (r *MealEntryRepository) GetMealEntriesByDateRange(ctx context.Context, userID string, from, to time.Time) ([]*models.MealEntry, error) {
+	query := `SELECT ` + mealEntryColumns + ` FROM meals
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at ASC`
+	return r.queryMealEntries(ctx, query, userID, from.UTC(), to.UTC())
+}
+
+func (r *MealEntryRepository) queryMealEntries(ctx context.Context, query string, args ...interface{}) ([]*models.MealEntry, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meals: %w", err)
+	}
+	defer rows.Close()
+
+	var meals []*models.MealEntry
+	for rows.Next() {
+		meal, err := scanMealEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan meal: %w", err)
+		}
+		meals = append(meals, meal)
+	}
+
+	return meals, rows.Err()
+}
+
+// UpdateMealEntry replaces a meal's fields; the owner and created_at are kept
+func (r *MealEntryRepository) UpdateMealEntry(ctx context.Context, meal *models.MealEntry) error {
+	ingredients, err := encodeStringList(meal.Ingredients)
+	if err != nil {
+		return err
+	}
+	tags, err := encodeStringList(meal.Tags)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE meals
+		SET name = $1, description = $2, calories = $3, protein = $4, carbs = $5, fat = $6,
+			fiber = $7, sugar = $8, sodium = $9, meal_type = $10, ingredients = $11,
+			is_halal = $12, tags = $13, image_url = $14, updated_at = $15
+		WHERE id = $16`
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		meal.Name,
+		meal.Description,
+		meal.Calories,
+		meal.Protein,
+		meal.Carbs,
+		meal.Fat,
+		meal.Fiber,
+		meal.Sugar,
+		meal.Sodium,
+		meal.MealType,
+		ingredients,
+		meal.IsHalal,
+		tags,
+		meal.ImageURL,
+		meal.UpdatedAt.UTC(),
+		meal.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update meal: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("meal not found")
+	}
+
+	return nil
+}
+
+// DeleteMealEntry deletes a meal owned by the user
+func (r *MealEntryRepository) DeleteMealEntry(ctx context.Context, id, userID string) error {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM meals WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete meal: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("meal not found")
+	}
+
+	return nil
+}
+
+func scanMealEntry(row rowScanner) (*models.MealEntry, error) {
+	var meal models.MealEntry
+	var ingredients, tags sql.NullString
+
+	err := row.Scan(
+		&meal.ID,
+		&meal.UserID,
+		&meal.Name,
+		&meal.Description,
+		&meal.Calories,
+		&meal.Protein,
+		&meal.Carbs,
+		&meal.Fat,
+		&meal.Fiber,
+		&meal.Sugar,
+		&meal.Sodium,
+		&meal.MealType,
+		&ingredients,
+		&meal.IsHalal,
+		&tags,
+		&meal.ImageURL,
+		&meal.CreatedAt,
+		&meal.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if meal.Ingredients, err = decodeStringList(ingredients); err != nil {
+		return nil, err
+	}
+	if meal.Tags, err = decodeStringList(tags); err != nil {
+		return nil, err
+	}
+	return &meal, nil
+}
+
+// encodeStringList stores a string slice as a JSON array, never as null
+func encodeStringList(values []string) (string, error) {
+	data, err := json.Marshal(emptyIfNil(values))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode list: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeStringList(raw sql.NullString) ([]string, error) {
+	values := []string{}
+	if raw.Valid && raw.String != "" {
+		if err := json.Unmarshal([]byte(raw.String), &values); err != nil {
+			return nil, fmt.Errorf("failed to decode list: %w", err)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+const mealPlanDateLayout = "2006-01-02"
+
+// ErrMealPlanNotFound is returned when a meal plan does not exist or belongs
+// to another user
+var ErrMealPlanNotFound = errors.New("meal plan not found")
+
+// MealPlanRepository handles meal plan database operations
+type MealPlanRepository struct {
+	db *database.Database
+}
+
+// NewMealPlanRepository creates a new meal plan repository
+func NewMealPlanRepository(db *database.Database) *MealPlanRepository {
+	return &MealPlanRepository{db: db}
+}
+
+// CreateMealPlan stores a new meal plan together with its days
+func (r *MealPlanRepository) CreateMealPlan(ctx context.Context, plan *models.MealPlan) error {
+	query := `
+		INSERT INTO meal_plans (
+			user_id, name, name_ar, description, start_date, end_date, days,
+			total_calories, total_protein, total_carbs, total_fat, is_active,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`
+
+	plan.RecalculateTotals()
+	daysJSON, err := json.Marshal(plan.Days)
+	if err != nil {
+		return fmt.Errorf("failed to encode meal plan days: %w", err)
+	}
+
+	now := time.Now()
+	err = r.db.DB.QueryRowContext(ctx, query,
+		plan.UserID,
+		plan.Name,
+		plan.NameAr,
+		plan.Description,
+		formatPlanDate(plan.StartDate),
+		formatPlanDate(plan.EndDate),
+		string(daysJSON),
+		plan.TotalCalories,
+		plan.TotalProtein,
+		plan.TotalCarbs,
+		plan.TotalFat,
+		plan.IsActive,
+		now,
+		now,
+	).Scan(&plan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create meal plan: %w", err)
+	}
+
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+	return nil
+}
+
+// GetMealPlanByID retrieves a meal plan owned by the given user
+func (r *MealPlanRepository) GetMealPlanByID(ctx context.Context, id, userID int) (*models.MealPlan, error) {
+	query := `
+		SELECT id, user_id, name, name_ar, description, start_date, end_date, days,
+			   total_calories, total_protein, total_carbs, total_fat, is_active,
+			   created_at, updated_at
+		FROM meal_plans
+		WHERE id = $1 AND user_id = $2`
+
+	plan, err := scanMealPlan(r.db.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMealPlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get meal plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// GetMealPlansByUserID retrieves a user's meal plans, newest first
+func (r *MealPlanRepository) GetMealPlansByUserID(ctx context.Context, userID, limit, offset int) ([]*models.MealPlan, error) {
+	query := `
+		SELECT id, user_id, name, name_ar, description, start_date, end_date, days,
+			   total_calories, total_protein, total_carbs, total_fat, is_active,
+			   created_at, updated_at
+		FROM meal_plans
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meal plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []*models.MealPlan
+	for rows.Next() {
+		plan, err := scanMealPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan meal plan: %w", err)
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, rows.Err()
+}
+
+// UpdateMealPlan replaces the stored contents of a meal plan
+func (r *MealPlanRepository) UpdateMealPlan(ctx context.Context, plan *models.MealPlan) error {
+	query := `
+		UPDATE meal_plans
+		SET name = $1, name_ar = $2, description = $3, start_date = $4, end_date = $5,
+			days = $6, total_calories = $7, total_protein = $8, total_carbs = $9,
+			total_fat = $10, is_active = $11, updated_at = $12
+		WHERE id = $13 AND user_id = $14`
+
+	plan.RecalculateTotals()
+	daysJSON, err := json.Marshal(plan.Days)
+	if err != nil {
+		return fmt.Errorf("failed to encode meal plan days: %w", err)
+	}
+
+	plan.UpdatedAt = time.Now()
+	result, err := r.db.DB.ExecContext(ctx, query,
+		plan.Name,
+		plan.NameAr,
+		plan.Description,
+		formatPlanDate(plan.StartDate),
+		formatPlanDate(plan.EndDate),
+		string(daysJSON),
+		plan.TotalCalories,
+		plan.TotalProtein,
+		plan.TotalCarbs,
+		plan.TotalFat,
+		plan.IsActive,
+		plan.UpdatedAt,
+		plan.ID,
+		plan.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update meal plan: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrMealPlanNotFound
+	}
+
+	return nil
+}
+
+// DeleteMealPlan deletes a meal plan owned by the given user
+func (r *MealPlanRepository) DeleteMealPlan(ctx context.Context, id, userID int) error {
+	result, err := r.db.DB.ExecContext(ctx, "DELETE FROM meal_plans WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete meal plan: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrMealPlanNotFound
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMealPlan(row rowScanner) (*models.MealPlan, error) {
+	var plan models.MealPlan
+	var startDate, endDate, daysJSON sql.NullString
+	var calories, protein, carbs, fat sql.NullFloat64
+
+	err := row.Scan(
+		&plan.ID,
+		&plan.UserID,
+		&plan.Name,
+		&plan.NameAr,
+		&plan.Description,
+		&startDate,
+		&endDate,
+		&daysJSON,
+		&calories,
+		&protein,
+		&carbs,
+		&fat,
+		&plan.IsActive,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.StartDate = parsePlanDate(startDate)
+	plan.EndDate = parsePlanDate(endDate)
+	plan.TotalCalories = calories.Float64
+	plan.TotalProtein = protein.Float64
+	plan.TotalCarbs = carbs.Float64
+	plan.TotalFat = fat.Float64
+
+	plan.Days = []models.MealPlanDay{}
+	if daysJSON.Valid && daysJSON.String != "" {
+		if err := json.Unmarshal([]byte(daysJSON.String), &plan.Days); err != nil {
+			return nil, fmt.Errorf("failed to decode meal plan days: %w", err)
+		}
+	}
+
+	return &plan, nil
+}
+
+func formatPlanDate(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format(mealPlanDateLayout)
+}
+
+func parsePlanDate(s sql.NullString) *time.Time {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	t, err := time.Parse(mealPlanDateLayout, s.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,392 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// MedicalPlanRepository handles medical and health plans
+type MedicalPlanRepository struct {
+	db *database.Database
+}
+
+// NewMedicalPlanRepository creates a new medical plan repository
+func NewMedicalPlanRepository(db *database.Database) *MedicalPlanRepository {
+	return &MedicalPlanRepository{db: db}
+}
+
+const medicalPlanColumns = `id, user_id, name, description, type, category, duration, start_date, end_date,
+		goals, restrictions, recommendations, meal_plan, exercise_plan, supplement_plan,
+		monitoring_metrics, checkpoints, notes, created_by, is_active, is_public, tags,
+		rating, rating_count, created_at, updated_at`
+
+// medicalPlanJSON holds the JSON-encoded columns of a medical plan
+type medicalPlanJSON struct {
+	goals, restrictions, recommendations, monitoringMetrics, checkpoints, tags string
+	mealPlan, exercisePlan, supplementPlan                                     interface{}
+}
+
+func encodeMedicalPlanColumns(plan *models.MedicalPlan) (*medicalPlanJSON, error) {
+	var columns medicalPlanJSON
+	var err error
+	if columns.goals, err = encodeStringList(plan.Goals); err != nil {
+		return nil, err
+	}
+	if columns.restrictions, err = encodeStringList(plan.Restrictions); err != nil {
+		return nil, err
+	}
+	if columns.recommendations, err = encodeStringList(plan.Recommendations); err != nil {
+		return nil, err
+	}
+	if columns.monitoringMetrics, err = encodeStringList(plan.MonitoringMetrics); err != nil {
+		return nil, err
+	}
+	if columns.tags, err = encodeStringList(plan.Tags); err != nil {
+		return nil, err
+	}
+
+	checkpoints := plan.Checkpoints
+	if checkpoints == nil {
+		checkpoints = []models.Checkpoint{}
+	}
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+	columns.checkpoints = string(data)
+
+	// The detail sections are optional and stay NULL when absent
+	sections := []struct {
+		value interface{}
+		isNil bool
+		dest  *interface{}
+	}{
+		{plan.MealPlan, plan.MealPlan == nil, &columns.mealPlan},
+		{plan.ExercisePlan, plan.ExercisePlan == nil, &columns.exercisePlan},
+		{plan.SupplementPlan, plan.SupplementPlan == nil, &columns.supplementPlan},
+	}
+	for _, section := range sections {
+		if section.isNil {
+			continue
+		}
+		data, err := json.Marshal(section.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode medical plan: %w", err)
+		}
+		*section.dest = string(data)
+	}
+
+	return &columns, nil
+}
+
+// CreateMedicalPlan stores a plan. The ID and timestamps are set by the caller.
+func (r *MedicalPlanRepository) CreateMedicalPlan(ctx context.Context, plan *models.MedicalPlan) error {
+	if _, err := r.insertMedicalPlan(ctx, plan, ""); err != nil {
+		return fmt.Errorf("failed to create medical plan: %w", err)
+	}
+	return nil
+}
+
+// ImportMedicalPlan stores a plan unless one with the same ID exists and
+// reports whether it was inserted
+func (r *MedicalPlanRepository) ImportMedicalPlan(ctx context.Context, plan *models.MedicalPlan) (bool, error) {
+	inserted, err := r.insertMedicalPlan(ctx, plan, "ON CONFLICT (id) DO NOTHING")
+	if err != nil {
+		return false, fmt.Errorf("failed to import medical plan: %w", err)
+	}
+	return inserted, nil
+}
+
+func (r *MedicalPlanRepository) insertMedicalPlan(ctx context.Context, plan *models.MedicalPlan, onConflict string) (bool, error) {
+	columns, err := encodeMedicalPlanColumns(plan)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO medical_plans (` + medicalPlanColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+			$19, $20, $21, $22, $23, $24, $25, $26) ` + onConflict
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		plan.ID,
+		plan.UserID,
+		plan.Name,
+		plan.Description,
+		plan.Type,
+		plan.Category,
+		plan.Duration,
+		utcOrNil(plan.StartDate),
+		utcOrNil(plan.EndDate),
+		columns.goals,
+		columns.restrictions,
+		columns.recommendations,
+		columns.mealPlan,
+		columns.exercisePlan,
+		columns.supplementPlan,
+		columns.monitoringMetrics,
+		columns.checkpoints,
+		plan.Notes,
+		plan.CreatedBy,
+		plan.IsActive,
+		plan.IsPublic,
+		columns.tags,
+		plan.Rating,
+		plan.RatingCount,
+		plan.CreatedAt.UTC(),
+		plan.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetMedicalPlanByID retrieves a plan by ID
+func (r *MedicalPlanRepository) GetMedicalPlanByID(ctx context.Context, id string) (*models.MedicalPlan, error) {
+	query := `SELECT ` + medicalPlanColumns + ` FROM medical_plans WHERE id = $1`
+
+	plan, err := scanMedicalPlan(r.db.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("medical plan not found")
+		}
+		return nil, fmt.Errorf("failed to get medical plan: %w", err)
+	}
+	return plan, nil
+}
+
+// GetMedicalPlansByUserID retrieves a user's plans, oldest first
+func (r *MedicalPlanRepository) GetMedicalPlansByUserID(ctx context.Context, userID string) ([]*models.MedicalPlan, error) {
+	query := `SELECT ` + medicalPlanColumns + ` FROM medical_plans WHERE user_id = $1 ORDER BY created_at ASC`
+	return r.queryMedicalPlans(ctx, query, userID)
+}
+
+// GetPublicMedicalPlans retrieves every public plan, oldest first
+func (r *MedicalPlanRepository) GetPublicMedicalPlans(ctx context.Context) ([]*models.MedicalPlan, error) {
+	query := `SELECT ` + medicalPlanColumns + ` FROM medical_plans WHERE is_public = $1 ORDER BY created_at ASC`
+	return r.queryMedicalPlans(ctx, query, true)
+}
+
+// GetVisibleMedicalPlans retrieves a user's own plans and, when
+// includePublic is set, every public plan
+func (r *MedicalPlanRepository) GetVisibleMedicalPlans(ctx context.Context, userID string, includePublic bool) ([]*models.MedicalPlan, error) {
+	if !includePublic {
+		return r.GetMedicalPlansByUserID(ctx, userID)
+	}
+	query := `SELECT ` + medicalPlanColumns + ` FROM medical_plans
+		WHERE user_id = $1 OR is_public = $2
+		ORDER BY created_at ASC`
+	return r.queryMedicalPlans(ctx, query, userID, true)
+}
+
+func (r *MedicalPlanRepository) queryMedicalPlans(ctx context.Context, query string, args ...interface{}) ([]*models.MedicalPlan, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get medical plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []*models.MedicalPlan
+	for rows.Next() {
+		plan, err := scanMedicalPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan medical plan: %w", err)
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, rows.Err()
+}
+
+// UpdateMedicalPlan replaces a plan's fields; the owner, rating and
+// created_at are kept
+func (r *MedicalPlanRepository) UpdateMedicalPlan(ctx context.Context, plan *models.MedicalPlan) error {
+	columns, err := encodeMedicalPlanColumns(plan)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE medical_plans
+		SET name = $1, description = $2, type = $3, category = $4, duration = $5,
+			start_date = $6, end_date = $7, goals = $8, restrictions = $9, recommendations = $10,
+			meal_plan = $11, exercise_plan = $12, supplement_plan = $13, monitoring_metrics = $14,
+			checkpoints = $15, notes = $16, created_by = $17, is_active = $18, is_public = $19,
+			tags = $20, updated_at = $21
+		WHERE id = $22`
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		plan.Name,
+		plan.Description,
+		plan.Type,
+		plan.Category,
+		plan.Duration,
+		utcOrNil(plan.StartDate),
+		utcOrNil(plan.EndDate),
+		columns.goals,
+		columns.restrictions,
+		columns.recommendations,
+		columns.mealPlan,
+		columns.exercisePlan,
+		columns.supplementPlan,
+		columns.monitoringMetrics,
+		columns.checkpoints,
+		plan.Notes,
+		plan.CreatedBy,
+		plan.IsActive,
+		plan.IsPublic,
+		columns.tags,
+		plan.UpdatedAt.UTC(),
+		plan.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update medical plan: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("medical plan not found")
+	}
+
+	return nil
+}
+
+// AddMedicalPlanRating folds a rating into the plan's running average in a
+// single statement, so concurrent ratings are not lost
+func (r *MedicalPlanRepository) AddMedicalPlanRating(ctx context.Context, id string, rating float64) error {
+	query := `
+		UPDATE medical_plans
+		SET rating = (rating * rating_count + $1) / (rating_count + 1),
+			rating_count = rating_count + 1,
+			updated_at = $2
+		WHERE id = $3`
+
+	result, err := r.db.DB.ExecContext(ctx, query, rating, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to rate medical plan: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("medical plan not found")
+	}
+
+	return nil
+}
+
+// DeleteMedicalPlan deletes a plan owned by the user
+func (r *MedicalPlanRepository) DeleteMedicalPlan(ctx context.Context, id, userID string) error {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM medical_plans WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete medical plan: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("medical plan not found")
+	}
+
+	return nil
+}
+
+func scanMedicalPlan(row rowScanner) (*models.MedicalPlan, error) {
+	var plan models.MedicalPlan
+	var goals, restrictions, recommendations, monitoringMetrics, checkpoints, tags sql.NullString
+	var mealPlan, exercisePlan, supplementPlan sql.NullString
+	var startDate, endDate sql.NullTime
+
+	err := row.Scan(
+		&plan.ID,
+		&plan.UserID,
+		&plan.Name,
+		&plan.Description,
+		&plan.Type,
+		&plan.Category,
+		&plan.Duration,
+		&startDate,
+		&endDate,
+		&goals,
+		&restrictions,
+		&recommendations,
+		&mealPlan,
+		&exercisePlan,
+		&supplementPlan,
+		&monitoringMetrics,
+		&checkpoints,
+		&plan.Notes,
+		&plan.CreatedBy,
+		&plan.IsActive,
+		&plan.IsPublic,
+		&tags,
+		&plan.Rating,
+		&plan.RatingCount,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []struct {
+		raw  sql.NullString
+		dest *[]string
+	}{
+		{goals, &plan.Goals},
+		{restrictions, &plan.Restrictions},
+		{recommendations, &plan.Recommendations},
+		{monitoringMetrics, &plan.MonitoringMetrics},
+		{tags, &plan.Tags},
+	}
+	for _, list := range lists {
+		if *list.dest, err = decodeStringList(list.raw); err != nil {
+			return nil, err
+		}
+	}
+
+	plan.Checkpoints = []models.Checkpoint{}
+	if checkpoints.Valid && checkpoints.String != "" {
+		if err := json.Unmarshal([]byte(checkpoints.String), &plan.Checkpoints); err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoints: %w", err)
+		}
+	}
+
+	if mealPlan.Valid {
+		plan.MealPlan = &models.MealPlanDetails{}
+		if err := json.Unmarshal([]byte(mealPlan.String), plan.MealPlan); err != nil {
+			return nil, fmt.Errorf("failed to decode meal plan: %w", err)
+		}
+	}
+	if exercisePlan.Valid {
+		plan.ExercisePlan = &models.ExercisePlanDetails{}
+		if err := json.Unmarshal([]byte(exercisePlan.String), plan.ExercisePlan); err != nil {
+			return nil, fmt.Errorf("failed to decode exercise plan: %w", err)
+		}
+	}
+	if supplementPlan.Valid {
+		plan.SupplementPlan = &models.SupplementPlanDetails{}
+		if err := json.Unmarshal([]byte(supplementPlan.String), plan.SupplementPlan); err != nil {
+			return nil, fmt.Errorf("failed to decode supplement plan: %w", err)
+		}
+	}
+
+	plan.StartDate = timeOrNil(startDate)
+	plan.EndDate = timeOrNil(endDate)
+	return &plan, nil
+}
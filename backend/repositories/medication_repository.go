@@ -0,0 +1,301 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// MedicationRepository handles the medication catalog and the medications
+// and supplements users take
+type MedicationRepository struct {
+	db *database.Database
+}
+
+// NewMedicationRepository creates a new medication repository
+func NewMedicationRepository(db *database.Database) *MedicationRepository {
+	return &MedicationRepository{db: db}
+}
+
+// GetMedicationByID retrieves a catalog medication
+func (r *MedicationRepository) GetMedicationByID(ctx context.Context, id string) (*models.Medication, error) {
+	query := `
+		SELECT id, name, generic_name, brand_names, drug_class, category
+		FROM medications
+		WHERE id = $1`
+
+	var medication models.Medication
+	var brandNames sql.NullString
+	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
+		&medication.ID,
+		&medication.Name,
+		&medication.GenericName,
+		&brandNames,
+		&medication.DrugClass,
+		&medication.Category,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("medication not found")
+		}
+		return nil, fmt.Errorf("failed to get medication: %w", err)
+	}
+
+	medication.BrandNames = []string{}
+	if brandNames.Valid && brandNames.String != "" {
+		if err := json.Unmarshal([]byte(brandNames.String), &medication.BrandNames); err != nil {
+			return nil, fmt.Errorf("failed to decode medication brand names: %w", err)
+		}
+	}
+
+	return &medication, nil
+}
+
+// CreateUserMedication stores a medication a user takes
+func (r *MedicationRepository) CreateUserMedication(ctx context.Context, medication *models.UserMedication) error {
+	query := `
+		INSERT INTO user_medications (
+			id, user_id, medication_id, custom_medication_name, dosage, frequency,
+			administration_time, start_date, end_date, prescribed_by, reason_for_taking,
+			side_effects_experienced, is_active, adherence_notes, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+
+	administrationTime, err := json.Marshal(emptyIfNil(medication.AdministrationTime))
+	if err != nil {
+		return fmt.Errorf("failed to encode administration time: %w", err)
+	}
+	sideEffects, err := json.Marshal(emptyIfNil(medication.SideEffectsExperienced))
+	if err != nil {
+		return fmt.Errorf("failed to encode side effects: %w", err)
+	}
+
+	now := time.Now()
+	_, err = r.db.DB.ExecContext(ctx, query,
+		medication.ID,
+		medication.UserID,
+		medication.MedicationID,
+		medication.CustomMedicationName,
+		medication.Dosage,
+		medication.Frequency,
+		string(administrationTime),
+		formatPlanDate(medication.StartDate),
+		formatPlanDate(medication.EndDate),
+		medication.PrescribedBy,
+		medication.ReasonForTaking,
+		string(sideEffects),
+		medication.IsActive,
+		medication.AdherenceNotes,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user medication: %w", err)
+	}
+
+	medication.CreatedAt = now
+	medication.UpdatedAt = now
+	return nil
+}
+
+// GetActiveUserMedications retrieves the medications a user marked active
+func (r *MedicationRepository) GetActiveUserMedications(ctx context.Context, userID int) ([]*models.UserMedication, error) {
+	query := `
+		SELECT id, user_id, medication_id, custom_medication_name, dosage, frequency,
+			   start_date, end_date, is_active, created_at, updated_at
+		FROM user_medications
+		WHERE user_id = $1 AND is_active = 1
+		ORDER BY created_at ASC`
+
+	// user_medications stores user IDs as text
+	rows, err := r.db.DB.QueryContext(ctx, query, strconv.Itoa(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user medications: %w", err)
+	}
+	defer rows.Close()
+
+	var medications []*models.UserMedication
+	for rows.Next() {
+		var medication models.UserMedication
+		var dosage, frequency, startDate, endDate sql.NullString
+
+		err := rows.Scan(
+			&medication.ID,
+			&medication.UserID,
+			&medication.MedicationID,
+			&medication.CustomMedicationName,
+			&dosage,
+			&frequency,
+			&startDate,
+			&endDate,
+			&medication.IsActive,
+			&medication.CreatedAt,
+			&medication.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user medication: %w", err)
+		}
+
+		medication.Dosage = dosage.String
+		medication.Frequency = frequency.String
+		medication.StartDate = parsePlanDate(startDate)
+		medication.EndDate = parsePlanDate(endDate)
+		medications = append(medications, &medication)
+	}
+
+	return medications, rows.Err()
+}
+
+// ListScheduledUserMedications returns the active medications with
+// administration times of one user, or of every user when userID is empty.
+// CustomMedicationName is filled with the catalog
+// name when the user did not enter one.
+func (r *MedicationRepository) ListScheduledUserMedications(ctx context.Context, userID string) ([]*models.UserMedication, error) {
+	query := `
+		SELECT um.id, um.user_id, COALESCE(um.custom_medication_name, m.name, ''), um.dosage,
+			   um.administration_time, um.start_date, um.end_date
+		FROM user_medications um
+		LEFT JOIN medications m ON m.id = um.medication_id
+		WHERE um.is_active = 1 AND um.administration_time IS NOT NULL AND um.administration_time != '[]'
+			AND ($1 = '' OR um.user_id = $2)
+		ORDER BY um.user_id, um.created_at`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled user medications: %w", err)
+	}
+	defer rows.Close()
+
+	var medications []*models.UserMedication
+	for rows.Next() {
+		var medication models.UserMedication
+		var name string
+		var dosage, administrationTime, startDate, endDate sql.NullString
+
+		err := rows.Scan(
+			&medication.ID,
+			&medication.UserID,
+			&name,
+			&dosage,
+			&administrationTime,
+			&startDate,
+			&endDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user medication: %w", err)
+		}
+
+		if medication.AdministrationTime, err = decodeStringList(administrationTime); err != nil {
+			return nil, err
+		}
+		medication.CustomMedicationName = &name
+		medication.Dosage = dosage.String
+		medication.StartDate = parsePlanDate(startDate)
+		medication.EndDate = parsePlanDate(endDate)
+		medication.IsActive = true
+		medications = append(medications, &medication)
+	}
+
+	return medications, rows.Err()
+}
+
+// CreateUserSupplement stores a supplement a user takes
+func (r *MedicationRepository) CreateUserSupplement(ctx context.Context, supplement *models.UserSupplement) error {
+	query := `
+		INSERT INTO user_supplements (
+			id, user_id, vitamin_mineral_id, supplement_name, brand, dosage, form, frequency,
+			taken_with_meals, start_date, end_date, reason_for_taking, prescribed_by,
+			cost_per_month, effectiveness_rating, side_effects, is_active, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`
+
+	now := time.Now()
+	_, err := r.db.DB.ExecContext(ctx, query,
+		supplement.ID,
+		supplement.UserID,
+		supplement.VitaminMineralID,
+		supplement.SupplementName,
+		supplement.Brand,
+		supplement.Dosage,
+		supplement.Form,
+		supplement.Frequency,
+		supplement.TakenWithMeals,
+		formatPlanDate(supplement.StartDate),
+		formatPlanDate(supplement.EndDate),
+		supplement.ReasonForTaking,
+		supplement.PrescribedBy,
+		supplement.CostPerMonth,
+		supplement.EffectivenessRating,
+		supplement.SideEffects,
+		supplement.IsActive,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user supplement: %w", err)
+	}
+
+	supplement.CreatedAt = now
+	supplement.UpdatedAt = now
+	return nil
+}
+
+// GetActiveUserSupplements retrieves the supplements a user marked active
+func (r *MedicationRepository) GetActiveUserSupplements(ctx context.Context, userID int) ([]*models.UserSupplement, error) {
+	query := `
+		SELECT id, user_id, vitamin_mineral_id, supplement_name, brand, dosage, frequency,
+			   start_date, end_date, is_active, created_at, updated_at
+		FROM user_supplements
+		WHERE user_id = $1 AND is_active = 1
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, strconv.Itoa(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user supplements: %w", err)
+	}
+	defer rows.Close()
+
+	var supplements []*models.UserSupplement
+	for rows.Next() {
+		var supplement models.UserSupplement
+		var name, dosage, frequency, startDate, endDate sql.NullString
+
+		err := rows.Scan(
+			&supplement.ID,
+			&supplement.UserID,
+			&supplement.VitaminMineralID,
+			&name,
+			&supplement.Brand,
+			&dosage,
+			&frequency,
+			&startDate,
+			&endDate,
+			&supplement.IsActive,
+			&supplement.CreatedAt,
+			&supplement.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user supplement: %w", err)
+		}
+
+		supplement.SupplementName = name.String
+		supplement.Dosage = dosage.String
+		supplement.Frequency = frequency.String
+		supplement.StartDate = parsePlanDate(startDate)
+		supplement.EndDate = parsePlanDate(endDate)
+		supplements = append(supplements, &supplement)
+	}
+
+	return supplements, rows.Err()
+}
+
+func emptyIfNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
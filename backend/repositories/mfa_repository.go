@@ -0,0 +1,229 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// MFARepository handles TOTP enrollments and recovery codes
+type MFARepository struct {
+	db *database.Database
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *database.Database) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// SavePendingSecret starts or restarts a user's enrollment with a new
+// secret. It reports false, changing nothing, when the user already has
+// two-factor authentication enabled.
+func (r *MFARepository) SavePendingSecret(ctx context.Context, userID, secretCiphertext string, now time.Time) (bool, error) {
+	query := `
+		INSERT INTO user_mfa (user_id, secret_ciphertext, enabled, last_used_step, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_ciphertext = excluded.secret_ciphertext, last_used_step = excluded.last_used_step,
+			created_at = excluded.created_at
+		WHERE user_mfa.enabled = $6`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, secretCiphertext, false, 0, now.UTC(), false)
+	if err != nil {
+		return false, fmt.Errorf("failed to save MFA secret: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// GetMFA retrieves a user's enrollment, pending or enabled
+func (r *MFARepository) GetMFA(ctx context.Context, userID string) (*models.UserMFA, error) {
+	query := `
+		SELECT user_id, secret_ciphertext, enabled, last_used_step, failed_attempts, locked_until,
+			enabled_at, created_at
+		FROM user_mfa
+		WHERE user_id = $1`
+
+	var mfa models.UserMFA
+	var lockedUntil, enabledAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.SecretCiphertext,
+		&mfa.Enabled,
+		&mfa.LastUsedStep,
+		&mfa.FailedAttempts,
+		&lockedUntil,
+		&enabledAt,
+		&mfa.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("mfa settings not found")
+		}
+		return nil, fmt.Errorf("failed to get MFA settings: %w", err)
+	}
+	mfa.LockedUntil = timeOrNil(lockedUntil)
+	mfa.EnabledAt = timeOrNil(enabledAt)
+	return &mfa, nil
+}
+
+// EnableMFA confirms a pending enrollment, records the step of the code that
+// confirmed it and replaces the user's recovery codes, in one transaction.
+// It reports false when there is no pending enrollment.
+func (r *MFARepository) EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string, now time.Time) (bool, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	enabled, err := execAffected(ctx, tx,
+		"UPDATE user_mfa SET enabled = $1, enabled_at = $2, last_used_step = $3 WHERE user_id = $4 AND enabled = $5",
+		true, now.UTC(), step, userID, false)
+	if err != nil {
+		return false, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	if !enabled {
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes, now); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit MFA enrollment: %w", err)
+	}
+	return true, nil
+}
+
+// UseTimeStep records that a code for step was accepted and clears the
+// failed attempts. It reports false when that step or a later one was
+// already used, so each code works once.
+func (r *MFARepository) UseTimeStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result, err := r.db.DB.ExecContext(ctx, `
+		UPDATE user_mfa SET last_used_step = $1, failed_attempts = $2, locked_until = NULL
+		WHERE user_id = $3 AND enabled = $4 AND last_used_step < $5`,
+		step, 0, userID, true, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record MFA code use: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// UseRecoveryCode consumes an unused recovery code and reports whether one
+// matched
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID, codeHash string, now time.Time) (bool, error) {
+	result, err := r.db.DB.ExecContext(ctx,
+		"UPDATE mfa_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL",
+		now.UTC(), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// RecordFailure counts a wrong code. Reaching maxAttempts consecutive
+// failures locks verification until lockedUntil and starts the count again.
+func (r *MFARepository) RecordFailure(ctx context.Context, userID string, maxAttempts int, lockedUntil time.Time) error {
+	query := `
+		UPDATE user_mfa
+		SET locked_until = CASE WHEN failed_attempts + 1 >= $1 THEN $2 ELSE locked_until END,
+			failed_attempts = CASE WHEN failed_attempts + 1 >= $3 THEN 0 ELSE failed_attempts + 1 END
+		WHERE user_id = $4`
+
+	if _, err := r.db.DB.ExecContext(ctx, query, maxAttempts, lockedUntil.UTC(), maxAttempts, userID); err != nil {
+		return fmt.Errorf("failed to record MFA failure: %w", err)
+	}
+	return nil
+}
+
+// ResetFailures clears a user's failed attempts after a correct code
+func (r *MFARepository) ResetFailures(ctx context.Context, userID string) error {
+	if _, err := r.db.DB.ExecContext(ctx,
+		"UPDATE user_mfa SET failed_attempts = $1, locked_until = NULL WHERE user_id = $2",
+		0, userID); err != nil {
+		return fmt.Errorf("failed to reset MFA failures: %w", err)
+	}
+	return nil
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes a user has left
+func (r *MFARepository) CountUnusedRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+		userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes and stores new ones
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodeHashes []string, now time.Time) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return nil
+}
+
+// DeleteMFA removes a user's enrollment and recovery codes
+func (r *MFARepository) DeleteMFA(ctx context.Context, userID string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_mfa WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete MFA settings: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit MFA removal: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, recoveryCodeHashes []string, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO mfa_recovery_codes (code_hash, user_id, created_at) VALUES ($1, $2, $3)",
+			hash, userID, now.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// PasswordResetRepository handles password reset token database operations
+type PasswordResetRepository struct {
+	db *database.Database
+}
+
+// NewPasswordResetRepository creates a new password reset repository
+func NewPasswordResetRepository(db *database.Database) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// CreateToken stores a new reset token
+func (r *PasswordResetRepository) CreateToken(ctx context.Context, token *models.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, request_ip, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	token.CreatedAt = time.Now()
+	err := r.db.DB.QueryRowContext(ctx, query,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.RequestIP,
+		token.CreatedAt,
+	).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return nil
+}
+
+// GetTokenByHash retrieves a reset token by the hash of its value
+func (r *PasswordResetRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, request_ip, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1`
+
+	var token models.PasswordResetToken
+	var usedAt sql.NullTime
+	var requestIP sql.NullString
+	err := r.db.DB.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&usedAt,
+		&requestIP,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("password reset token not found")
+		}
+		return nil, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	token.RequestIP = requestIP.String
+
+	return &token, nil
+}
+
+// RedeemToken consumes a token, stores the user's new password hash and
+// signs the user out everywhere, refresh tokens included, in one transaction. It reports
+// false, changing nothing, when the token was already used, so concurrent
+// requests cannot both redeem it.
+func (r *PasswordResetRepository) RedeemToken(ctx context.Context, token *models.PasswordResetToken, passwordHash string, now time.Time) (bool, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	consumed, err := execAffected(ctx, tx,
+		"UPDATE password_reset_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL",
+		now, token.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark password reset token used: %w", err)
+	}
+	if !consumed {
+		return false, nil
+	}
+
+	updated, err := execAffected(ctx, tx,
+		"UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3",
+		passwordHash, now, token.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update password: %w", err)
+	}
+	if !updated {
+		return false, fmt.Errorf("user not found")
+	}
+
+	if _, err := invalidateUserSessions(ctx, tx, token.UserID, now); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit password reset: %w", err)
+	}
+	return true, nil
+}
+
+// InvalidateUserTokens consumes every outstanding token for a user
+func (r *PasswordResetRepository) InvalidateUserTokens(ctx context.Context, userID string) error {
+	_, err := r.db.DB.ExecContext(ctx,
+		"UPDATE password_reset_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL",
+		time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredTokens removes tokens that expired before the given time
+func (r *PasswordResetRepository) DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired password reset tokens: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetUserIDByEmail looks up the account a reset was requested for
+func (r *PasswordResetRepository) GetUserIDByEmail(ctx context.Context, email string) (string, error) {
+	var userID string
+	err := r.db.DB.QueryRowContext(ctx, "SELECT id FROM users WHERE lower(email) = lower($1)", email).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("user not found")
+		}
+		return "", fmt.Errorf("failed to get user by email: %w", err)
+	}
+	return userID, nil
+}
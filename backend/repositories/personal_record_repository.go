@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// PersonalRecordRepository handles personal record history
+type PersonalRecordRepository struct {
+	db *database.Database
+}
+
+// NewPersonalRecordRepository creates a new personal record repository
+func NewPersonalRecordRepository(db *database.Database) *PersonalRecordRepository {
+	return &PersonalRecordRepository{db: db}
+}
+
+const personalRecordColumns = `id, user_id, exercise_id, exercise_name, record_type, value, unit, reps, weight, distance,
+	previous_value, date, notes, workout_session_id, created_at, updated_at`
+
+// CreatePersonalRecord stores a new personal record and sets its ID
+func (r *PersonalRecordRepository) CreatePersonalRecord(ctx context.Context, userID string, record *models.PersonalRecord) error {
+	query := `
+		INSERT INTO personal_records (user_id, exercise_id, exercise_name, record_type, value, unit, reps, weight,
+			distance, previous_value, date, notes, workout_session_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id`
+
+	err := r.db.DB.QueryRowContext(ctx, query,
+		userID,
+		record.ExerciseID,
+		record.ExerciseName,
+		record.RecordType,
+		record.Value,
+		record.Unit,
+		record.Reps,
+		record.Weight,
+		record.Distance,
+		record.PreviousValue,
+		record.Date.UTC(),
+		record.Notes,
+		record.WorkoutSessionID,
+		record.CreatedAt.UTC(),
+		record.UpdatedAt.UTC(),
+	).Scan(&record.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create personal record: %w", err)
+	}
+	return nil
+}
+
+// ListExerciseRecords returns every record a user holds or held for an
+// exercise, oldest first
+func (r *PersonalRecordRepository) ListExerciseRecords(ctx context.Context, userID, exerciseID string) ([]*models.PersonalRecord, error) {
+	query := `SELECT ` + personalRecordColumns + ` FROM personal_records
+		WHERE user_id = $1 AND exercise_id = $2
+		ORDER BY date ASC, id ASC`
+
+	return r.queryRecords(ctx, query, userID, exerciseID)
+}
+
+// ListUserRecords returns a user's records set from since on, oldest first
+func (r *PersonalRecordRepository) ListUserRecords(ctx context.Context, userID string, since time.Time) ([]*models.PersonalRecord, error) {
+	query := `SELECT ` + personalRecordColumns + ` FROM personal_records
+		WHERE user_id = $1 AND date >= $2
+		ORDER BY date ASC, id ASC`
+
+	return r.queryRecords(ctx, query, userID, since.UTC())
+}
+
+// ListPersonalRecords returns a page of a user's PR history, newest first,
+// and how many records match the filters
+func (r *PersonalRecordRepository) ListPersonalRecords(ctx context.Context, userID string, req models.ListPersonalRecordsRequest) ([]*models.PersonalRecord, int, error) {
+	whereClause := "WHERE user_id = $1"
+	args := []interface{}{userID}
+	argIndex := 2
+
+	if req.ExerciseID != nil {
+		whereClause += fmt.Sprintf(" AND exercise_id = $%d", argIndex)
+		args = append(args, *req.ExerciseID)
+		argIndex++
+	}
+	if req.RecordType != nil {
+		whereClause += fmt.Sprintf(" AND record_type = $%d", argIndex)
+		args = append(args, *req.RecordType)
+		argIndex++
+	}
+	if req.StartDate != nil {
+		whereClause += fmt.Sprintf(" AND date >= $%d", argIndex)
+		args = append(args, req.StartDate.UTC())
+		argIndex++
+	}
+	if req.EndDate != nil {
+		whereClause += fmt.Sprintf(" AND date < $%d", argIndex)
+		args = append(args, req.EndDate.UTC())
+		argIndex++
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM personal_records " + whereClause
+	if err := r.db.DB.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count personal records: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM personal_records %s
+		ORDER BY date DESC, id DESC
+		LIMIT $%d OFFSET $%d`, personalRecordColumns, whereClause, argIndex, argIndex+1)
+	args = append(args, req.Limit, (req.Page-1)*req.Limit)
+
+	records, err := r.queryRecords(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// ListSessionRecords returns the records a workout session set, oldest first
+func (r *PersonalRecordRepository) ListSessionRecords(ctx context.Context, userID, sessionID string) ([]*models.PersonalRecord, error) {
+	query := `SELECT ` + personalRecordColumns + ` FROM personal_records
+		WHERE user_id = $1 AND workout_session_id = $2
+		ORDER BY date ASC, id ASC`
+
+	return r.queryRecords(ctx, query, userID, sessionID)
+}
+
+// DeleteSessionRecords removes the records a workout session set, so the
+// bests before it apply again
+func (r *PersonalRecordRepository) DeleteSessionRecords(ctx context.Context, userID, sessionID string) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx,
+		`DELETE FROM personal_records WHERE user_id = $1 AND workout_session_id = $2`, userID, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete personal records: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+func (r *PersonalRecordRepository) queryRecords(ctx context.Context, query string, args ...interface{}) ([]*models.PersonalRecord, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*models.PersonalRecord{}
+	for rows.Next() {
+		record, err := scanPersonalRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan personal record: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func scanPersonalRecord(row rowScanner) (*models.PersonalRecord, error) {
+	var record models.PersonalRecord
+	var userID string
+	err := row.Scan(
+		&record.ID,
+		&userID,
+		&record.ExerciseID,
+		&record.ExerciseName,
+		&record.RecordType,
+		&record.Value,
+		&record.Unit,
+		&record.Reps,
+		&record.Weight,
+		&record.Distance,
+		&record.PreviousValue,
+		&record.Date,
+		&record.Notes,
+		&record.WorkoutSessionID,
+		&record.CreatedAt,
+		&record.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if id, err := strconv.ParseUint(userID, 10, 64); err == nil {
+		record.UserID = uint(id)
+	}
+	return &record, nil
+}
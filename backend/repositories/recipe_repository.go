@@ -0,0 +1,323 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// RecipeRepository handles recipe database operations
+type RecipeRepository struct {
+	db *database.Database
+}
+
+// NewRecipeRepository creates a new recipe repository
+func NewRecipeRepository(db *database.Database) *RecipeRepository {
+	return &RecipeRepository{db: db}
+}
+
+// GetRecipeByID retrieves a recipe with its JSON columns decoded
+func (r *RecipeRepository) GetRecipeByID(ctx context.Context, id string) (*models.Recipe, error) {
+	query := `
+		SELECT id, name, name_ar, description, description_ar, cuisine, country,
+			   difficulty_level, prep_time_minutes, cook_time_minutes, total_time_minutes,
+			   servings, ingredients, instructions, nutrition_per_serving, dietary_tags,
+			   allergens, is_halal, is_kosher, image_url, video_url, rating, rating_count,
+			   created_by, verified, created_at, updated_at
+		FROM recipes
+		WHERE id = $1`
+
+	recipe, err := scanRecipe(r.db.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("recipe not found")
+		}
+		return nil, fmt.Errorf("failed to get recipe: %w", err)
+	}
+
+	return recipe, nil
+}
+
+// ListPlannableRecipes returns recipes with per-serving nutrition that the
+// user may plan meals with: system recipes, verified ones and their own
+func (r *RecipeRepository) ListPlannableRecipes(ctx context.Context, userID string, limit int) ([]*models.Recipe, error) {
+	query := `
+		SELECT id, name, name_ar, description, description_ar, cuisine, country,
+			   difficulty_level, prep_time_minutes, cook_time_minutes, total_time_minutes,
+			   servings, ingredients, instructions, nutrition_per_serving, dietary_tags,
+			   allergens, is_halal, is_kosher, image_url, video_url, rating, rating_count,
+			   created_by, verified, created_at, updated_at
+		FROM recipes
+		WHERE (created_by IS NULL OR created_by = $1 OR verified = $2)
+		  AND nutrition_per_serving IS NOT NULL AND nutrition_per_serving <> '{}'
+		ORDER BY rating DESC, id ASC
+		LIMIT $3`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, true, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recipes: %w", err)
+	}
+	defer rows.Close()
+
+	var recipes []*models.Recipe
+	for rows.Next() {
+		recipe, err := scanRecipe(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recipe: %w", err)
+		}
+		recipes = append(recipes, recipe)
+	}
+
+	return recipes, rows.Err()
+}
+
+func scanRecipe(row rowScanner) (*models.Recipe, error) {
+	var recipe models.Recipe
+	var ingredients, instructions, nutrition, dietaryTags, allergens sql.NullString
+	var rating sql.NullFloat64
+	var ratingCount sql.NullInt64
+	var isHalal, isKosher, verified sql.NullBool
+
+	err := row.Scan(
+		&recipe.ID,
+		&recipe.Name,
+		&recipe.NameAr,
+		&recipe.Description,
+		&recipe.DescriptionAr,
+		&recipe.Cuisine,
+		&recipe.Country,
+		&recipe.DifficultyLevel,
+		&recipe.PrepTimeMinutes,
+		&recipe.CookTimeMinutes,
+		&recipe.TotalTimeMinutes,
+		&recipe.Servings,
+		&ingredients,
+		&instructions,
+		&nutrition,
+		&dietaryTags,
+		&allergens,
+		&isHalal,
+		&isKosher,
+		&recipe.ImageURL,
+		&recipe.VideoURL,
+		&rating,
+		&ratingCount,
+		&recipe.CreatedBy,
+		&verified,
+		&recipe.CreatedAt,
+		&recipe.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	recipe.Rating = rating.Float64
+	recipe.RatingCount = int(ratingCount.Int64)
+	recipe.IsHalal = isHalal.Bool
+	recipe.IsKosher = isKosher.Bool
+	recipe.Verified = verified.Bool
+
+	recipe.Ingredients = []models.RecipeIngredient{}
+	recipe.Instructions = []models.RecipeInstruction{}
+	recipe.DietaryTags = []string{}
+	recipe.Allergens = []string{}
+
+	columns := []struct {
+		raw  sql.NullString
+		dest interface{}
+	}{
+		{ingredients, &recipe.Ingredients},
+		{instructions, &recipe.Instructions},
+		{dietaryTags, &recipe.DietaryTags},
+		{allergens, &recipe.Allergens},
+	}
+	for _, col := range columns {
+		if col.raw.Valid && col.raw.String != "" {
+			if err := json.Unmarshal([]byte(col.raw.String), col.dest); err != nil {
+				return nil, fmt.Errorf("failed to decode recipe %s: %w", recipe.ID, err)
+			}
+		}
+	}
+
+	// An empty object means nutrition has not been calculated yet
+	if nutrition.Valid && nutrition.String != "" && nutrition.String != "{}" {
+		var info models.NutritionInfo
+		if err := json.Unmarshal([]byte(nutrition.String), &info); err != nil {
+			return nil, fmt.Errorf("failed to decode recipe %s nutrition: %w", recipe.ID, err)
+		}
+		recipe.NutritionPerServing = &info
+	}
+
+	return &recipe, nil
+}
+
+// CreateRecipe inserts a recipe, encoding its list and nutrition fields as JSON
+func (r *RecipeRepository) CreateRecipe(ctx context.Context, recipe *models.Recipe) error {
+	columns, err := encodeRecipeColumns(recipe)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO recipes (
+			id, name, name_ar, description, description_ar, cuisine, country,
+			difficulty_level, prep_time_minutes, cook_time_minutes, total_time_minutes,
+			servings, ingredients, instructions, nutrition_per_serving, dietary_tags,
+			allergens, is_halal, is_kosher, image_url, video_url, created_by, verified,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+			$17, $18, $19, $20, $21, $22, $23, $24, $25)`
+
+	now := time.Now()
+	_, err = r.db.DB.ExecContext(ctx, query,
+		recipe.ID,
+		recipe.Name,
+		recipe.NameAr,
+		recipe.Description,
+		recipe.DescriptionAr,
+		recipe.Cuisine,
+		recipe.Country,
+		recipe.DifficultyLevel,
+		recipe.PrepTimeMinutes,
+		recipe.CookTimeMinutes,
+		recipe.TotalTimeMinutes,
+		recipe.Servings,
+		columns.ingredients,
+		columns.instructions,
+		columns.nutrition,
+		columns.dietaryTags,
+		columns.allergens,
+		recipe.IsHalal,
+		recipe.IsKosher,
+		recipe.ImageURL,
+		recipe.VideoURL,
+		recipe.CreatedBy,
+		recipe.Verified,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create recipe: %w", err)
+	}
+
+	recipe.CreatedAt = now
+	recipe.UpdatedAt = now
+	return nil
+}
+
+// UpdateRecipe replaces the editable fields of a recipe owned by its creator
+func (r *RecipeRepository) UpdateRecipe(ctx context.Context, recipe *models.Recipe) error {
+	columns, err := encodeRecipeColumns(recipe)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE recipes
+		SET name = $1, name_ar = $2, description = $3, description_ar = $4, cuisine = $5,
+			country = $6, difficulty_level = $7, prep_time_minutes = $8, cook_time_minutes = $9,
+			total_time_minutes = $10, servings = $11, ingredients = $12, instructions = $13,
+			nutrition_per_serving = $14, dietary_tags = $15, allergens = $16, is_halal = $17,
+			is_kosher = $18, image_url = $19, video_url = $20, updated_at = $21
+		WHERE id = $22 AND created_by = $23`
+
+	now := time.Now()
+	result, err := r.db.DB.ExecContext(ctx, query,
+		recipe.Name,
+		recipe.NameAr,
+		recipe.Description,
+		recipe.DescriptionAr,
+		recipe.Cuisine,
+		recipe.Country,
+		recipe.DifficultyLevel,
+		recipe.PrepTimeMinutes,
+		recipe.CookTimeMinutes,
+		recipe.TotalTimeMinutes,
+		recipe.Servings,
+		columns.ingredients,
+		columns.instructions,
+		columns.nutrition,
+		columns.dietaryTags,
+		columns.allergens,
+		recipe.IsHalal,
+		recipe.IsKosher,
+		recipe.ImageURL,
+		recipe.VideoURL,
+		now,
+		recipe.ID,
+		recipe.CreatedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update recipe: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update recipe: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("recipe not found")
+	}
+
+	recipe.UpdatedAt = now
+	return nil
+}
+
+// DeleteRecipe removes a recipe owned by userID
+func (r *RecipeRepository) DeleteRecipe(ctx context.Context, id, userID string) error {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM recipes WHERE id = $1 AND created_by = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recipe: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete recipe: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("recipe not found")
+	}
+
+	return nil
+}
+
+type recipeJSONColumns struct {
+	ingredients, instructions, nutrition, dietaryTags, allergens string
+}
+
+func encodeRecipeColumns(recipe *models.Recipe) (*recipeJSONColumns, error) {
+	encode := func(v interface{}, empty string) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode recipe: %w", err)
+		}
+		if string(data) == "null" {
+			return empty, nil
+		}
+		return string(data), nil
+	}
+
+	var columns recipeJSONColumns
+	var err error
+	if columns.ingredients, err = encode(recipe.Ingredients, "[]"); err != nil {
+		return nil, err
+	}
+	if columns.instructions, err = encode(recipe.Instructions, "[]"); err != nil {
+		return nil, err
+	}
+	if columns.nutrition, err = encode(recipe.NutritionPerServing, "{}"); err != nil {
+		return nil, err
+	}
+	if columns.dietaryTags, err = encode(recipe.DietaryTags, "[]"); err != nil {
+		return nil, err
+	}
+	if columns.allergens, err = encode(recipe.Allergens, "[]"); err != nil {
+		return nil, err
+	}
+	return &columns, nil
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// RefreshTokenRepository handles refresh token families. A family is the
+// set of tokens issued for one session.
+type RefreshTokenRepository struct {
+	db *database.Database
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(db *database.Database) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// CreateFamily stores a new session together with its first refresh token
+func (r *RefreshTokenRepository) CreateFamily(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, insertSessionQuery, sessionArgs(session)...); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	if err := insertRefreshToken(ctx, tx, token); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	return nil
+}
+
+// GetTokenByHash retrieves a refresh token by the hash of its value,
+// whether or not it is still usable
+func (r *RefreshTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, session_id, user_id, token_hash, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	var token models.RefreshToken
+	var usedAt, revokedAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.SessionID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&usedAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("refresh token not found")
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	token.UsedAt = timeOrNil(usedAt)
+	token.RevokedAt = timeOrNil(revokedAt)
+	return &token, nil
+}
+
+// RotateToken consumes the used token, stores next as its successor and
+// moves the session to next and the new access token, all in one
+// transaction. It reports false, changing nothing, when the used token was
+// already consumed or revoked or the session is no longer active, so two
+// requests presenting the same token cannot both rotate it.
+func (r *RefreshTokenRepository) RotateToken(ctx context.Context, usedID string, next *models.RefreshToken, accessToken string, now time.Time) (bool, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	consumed, err := execAffected(ctx, tx,
+		"UPDATE refresh_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL AND revoked_at IS NULL",
+		now.UTC(), usedID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	if !consumed {
+		return false, nil
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return false, err
+	}
+
+	updated, err := execAffected(ctx, tx, `
+		UPDATE sessions
+		SET access_token = $1, refresh_token = $2, expires_at = $3, last_used_at = $4
+		WHERE id = $5 AND is_active = $6`,
+		accessToken, next.TokenHash, next.ExpiresAt.UTC(), now.UTC(), next.SessionID, true)
+	if err != nil {
+		return false, fmt.Errorf("failed to update session: %w", err)
+	}
+	if !updated {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return true, nil
+}
+
+// RevokeFamily deactivates a session and revokes every refresh token
+// issued for it
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, sessionID string, now time.Time) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE sessions SET is_active = $1, last_used_at = $2 WHERE id = $3",
+		false, now.UTC(), sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE session_id = $2 AND revoked_at IS NULL",
+		now.UTC(), sessionID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refresh token revocation: %w", err)
+	}
+	return nil
+}
+
+func insertRefreshToken(ctx context.Context, tx *sql.Tx, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, session_id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := tx.ExecContext(ctx, query,
+		token.ID,
+		token.SessionID,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt.UTC(),
+		token.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+func execAffected(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (bool, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// ReminderRepository handles users' custom reminders and the record of
+// reminder occurrences already sent
+type ReminderRepository struct {
+	db *database.Database
+}
+
+// NewReminderRepository creates a new reminder repository
+func NewReminderRepository(db *database.Database) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+const reminderColumns = `id, user_id, type, title, message, times, days_of_week, active, created_at, updated_at`
+
+// CreateReminder stores a new reminder
+func (r *ReminderRepository) CreateReminder(ctx context.Context, reminder *models.Reminder) error {
+	times, err := encodeStringList(reminder.Times)
+	if err != nil {
+		return err
+	}
+	days, err := encodeDaysOfWeek(reminder.DaysOfWeek)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO reminders (` + reminderColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = r.db.DB.ExecContext(ctx, query,
+		reminder.ID,
+		reminder.UserID,
+		reminder.Type,
+		reminder.Title,
+		reminder.Message,
+		times,
+		days,
+		reminder.Active,
+		reminder.CreatedAt.UTC(),
+		reminder.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create reminder: %w", err)
+	}
+	return nil
+}
+
+// GetReminder retrieves one of a user's reminders
+func (r *ReminderRepository) GetReminder(ctx context.Context, id, userID string) (*models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE id = $1 AND user_id = $2`
+
+	reminder, err := scanReminder(r.db.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("reminder not found")
+		}
+		return nil, fmt.Errorf("failed to get reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// ListReminders returns a user's reminders, oldest first
+func (r *ReminderRepository) ListReminders(ctx context.Context, userID string) ([]*models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE user_id = $1 ORDER BY created_at ASC`
+	return r.listReminders(ctx, query, userID)
+}
+
+// ListActiveReminders returns every user's active reminders
+func (r *ReminderRepository) ListActiveReminders(ctx context.Context) ([]*models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE active = $1 ORDER BY user_id, created_at`
+	return r.listReminders(ctx, query, true)
+}
+
+func (r *ReminderRepository) listReminders(ctx context.Context, query string, args ...interface{}) ([]*models.Reminder, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []*models.Reminder{}
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
+
+// UpdateReminder saves a reminder's title, message, schedule and active flag
+func (r *ReminderRepository) UpdateReminder(ctx context.Context, reminder *models.Reminder) error {
+	times, err := encodeStringList(reminder.Times)
+	if err != nil {
+		return err
+	}
+	days, err := encodeDaysOfWeek(reminder.DaysOfWeek)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE reminders
+		SET title = $1, message = $2, times = $3, days_of_week = $4, active = $5, updated_at = $6
+		WHERE id = $7 AND user_id = $8`
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		reminder.Title,
+		reminder.Message,
+		times,
+		days,
+		reminder.Active,
+		reminder.UpdatedAt.UTC(),
+		reminder.ID,
+		reminder.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update reminder: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("reminder not found")
+	}
+	return nil
+}
+
+// DeleteReminder deletes one of a user's reminders
+func (r *ReminderRepository) DeleteReminder(ctx context.Context, id, userID string) error {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM reminders WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("reminder not found")
+	}
+	return nil
+}
+
+// ClaimDispatch records that the occurrence identified by dedupeKey is being
+// sent and reports whether this call claimed it; false means it was already
+// sent
+func (r *ReminderRepository) ClaimDispatch(ctx context.Context, userID, dedupeKey string, scheduledFor time.Time) (bool, error) {
+	query := `
+		INSERT INTO reminder_dispatches (user_id, dedupe_key, scheduled_for, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, dedupe_key) DO NOTHING`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, dedupeKey, scheduledFor.UTC(), time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder dispatch: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// DeleteDispatchesBefore forgets occurrences scheduled before cutoff
+func (r *ReminderRepository) DeleteDispatchesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM reminder_dispatches WHERE scheduled_for < $1`, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete reminder dispatches: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func scanReminder(row rowScanner) (*models.Reminder, error) {
+	var reminder models.Reminder
+	var times, days sql.NullString
+
+	err := row.Scan(
+		&reminder.ID,
+		&reminder.UserID,
+		&reminder.Type,
+		&reminder.Title,
+		&reminder.Message,
+		&times,
+		&days,
+		&reminder.Active,
+		&reminder.CreatedAt,
+		&reminder.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	reminder.Source = models.ReminderSourceCustom
+	if reminder.Times, err = decodeStringList(times); err != nil {
+		return nil, err
+	}
+	reminder.DaysOfWeek = []int{}
+	if days.Valid && days.String != "" {
+		if err := json.Unmarshal([]byte(days.String), &reminder.DaysOfWeek); err != nil {
+			return nil, fmt.Errorf("failed to decode reminder days: %w", err)
+		}
+	}
+	return &reminder, nil
+}
+
+func encodeDaysOfWeek(days []int) (string, error) {
+	if days == nil {
+		days = []int{}
+	}
+	data, err := json.Marshal(days)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode reminder days: %w", err)
+	}
+	return string(data), nil
+}
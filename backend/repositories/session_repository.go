@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// SessionRepository handles login sessions
+type SessionRepository struct {
+	db *database.Database
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *database.Database) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// SessionCounts summarizes the session table at a point in time
+type SessionCounts struct {
+	Total       int
+	Active      int
+	Expired     int
+	Inactive    int
+	UniqueUsers int
+}
+
+const sessionColumns = `id, user_id, access_token, refresh_token, device_info, ip_address, user_agent,
+		is_active, mfa_verified, expires_at, created_at, last_used_at`
+
+// insertSessionQuery inserts sessionArgs
+const insertSessionQuery = `
+		INSERT INTO sessions (` + sessionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+// CreateSession stores a session. The ID and timestamps are set by the caller.
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	if _, err := r.insertSession(ctx, session, ""); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// ImportSession stores a session unless one with the same ID exists and
+// reports whether it was inserted
+func (r *SessionRepository) ImportSession(ctx context.Context, session *models.Session) (bool, error) {
+	inserted, err := r.insertSession(ctx, session, "ON CONFLICT (id) DO NOTHING")
+	if err != nil {
+		return false, fmt.Errorf("failed to import session: %w", err)
+	}
+	return inserted, nil
+}
+
+func (r *SessionRepository) insertSession(ctx context.Context, session *models.Session, onConflict string) (bool, error) {
+	result, err := r.db.DB.ExecContext(ctx, insertSessionQuery+" "+onConflict, sessionArgs(session)...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetSessionByRefreshToken retrieves an active session that has not expired at now
+func (r *SessionRepository) GetSessionByRefreshToken(ctx context.Context, refreshToken string, now time.Time) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+		WHERE refresh_token = $1 AND is_active = $2 AND expires_at > $3`
+	return r.getSession(ctx, query, refreshToken, true, now.UTC())
+}
+
+// GetSessionByAccessToken retrieves an active session that has not expired at now
+func (r *SessionRepository) GetSessionByAccessToken(ctx context.Context, accessToken string, now time.Time) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+		WHERE access_token = $1 AND is_active = $2 AND expires_at > $3`
+	return r.getSession(ctx, query, accessToken, true, now.UTC())
+}
+
+func (r *SessionRepository) getSession(ctx context.Context, query string, args ...interface{}) (*models.Session, error) {
+	session, err := scanSession(r.db.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found or expired")
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// GetActiveSessionsByUserID retrieves a user's active sessions that have not
+// expired at now, newest first
+func (r *SessionRepository) GetActiveSessionsByUserID(ctx context.Context, userID string, now time.Time) ([]*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+		WHERE user_id = $1 AND is_active = $2 AND expires_at > $3
+		ORDER BY last_used_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, true, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// UpdateSessionTokens stores a session's new token pair and expiry
+func (r *SessionRepository) UpdateSessionTokens(ctx context.Context, id, accessToken, refreshToken string, expiresAt, now time.Time) error {
+	query := `
+		UPDATE sessions
+		SET access_token = $1, refresh_token = $2, expires_at = $3, last_used_at = $4
+		WHERE id = $5`
+
+	return r.execSessionUpdate(ctx, query, accessToken, refreshToken, expiresAt.UTC(), now.UTC(), id)
+}
+
+// TouchSession records that the active session holding the access token was used
+func (r *SessionRepository) TouchSession(ctx context.Context, accessToken string, now time.Time) error {
+	query := `UPDATE sessions SET last_used_at = $1 WHERE access_token = $2 AND is_active = $3`
+	return r.execSessionUpdate(ctx, query, now.UTC(), accessToken, true)
+}
+
+// InvalidateSession deactivates a session by ID
+func (r *SessionRepository) InvalidateSession(ctx context.Context, id string, now time.Time) error {
+	query := `UPDATE sessions SET is_active = $1, last_used_at = $2 WHERE id = $3`
+	return r.execSessionUpdate(ctx, query, false, now.UTC(), id)
+}
+
+// InvalidateSessionByAccessToken deactivates the session holding the access token
+func (r *SessionRepository) InvalidateSessionByAccessToken(ctx context.Context, accessToken string, now time.Time) error {
+	query := `UPDATE sessions SET is_active = $1, last_used_at = $2 WHERE access_token = $3`
+	return r.execSessionUpdate(ctx, query, false, now.UTC(), accessToken)
+}
+
+func (r *SessionRepository) execSessionUpdate(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found")
+	}
+
+	return nil
+}
+
+// InvalidateUserSessions deactivates every active session of a user, revokes
+// their refresh token families and returns how many sessions were deactivated
+func (r *SessionRepository) InvalidateUserSessions(ctx context.Context, userID string, now time.Time) (int64, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := invalidateUserSessions(ctx, tx, userID, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit session invalidation: %w", err)
+	}
+	return rows, nil
+}
+
+// invalidateUserSessions signs a user out everywhere inside tx, so callers
+// such as a password reset can do it atomically with their own writes
+func invalidateUserSessions(ctx context.Context, tx *sql.Tx, userID string, now time.Time) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE sessions SET is_active = $1, last_used_at = $2 WHERE user_id = $3 AND is_active = $4",
+		false, now.UTC(), userID, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL",
+		now.UTC(), userID); err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return rows, nil
+}
+
+// DeleteStaleSessions removes active sessions that expired before now and
+// inactive sessions last used before inactiveBefore
+func (r *SessionRepository) DeleteStaleSessions(ctx context.Context, now, inactiveBefore time.Time) (int64, error) {
+	query := `
+		DELETE FROM sessions
+		WHERE (is_active = $1 AND expires_at <= $2)
+		   OR (is_active = $3 AND last_used_at < $4)`
+
+	result, err := r.db.DB.ExecContext(ctx, query, true, now.UTC(), false, inactiveBefore.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows, nil
+}
+
+// CountSessions summarizes sessions as of now
+func (r *SessionRepository) CountSessions(ctx context.Context, now time.Time) (*SessionCounts, error) {
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN is_active = $1 AND expires_at > $2 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN is_active = $3 AND expires_at <= $4 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN is_active = $5 THEN 1 ELSE 0 END), 0),
+			COUNT(DISTINCT user_id)
+		FROM sessions`
+
+	var counts SessionCounts
+	err := r.db.DB.QueryRowContext(ctx, query, true, now.UTC(), true, now.UTC(), false).Scan(
+		&counts.Total,
+		&counts.Active,
+		&counts.Expired,
+		&counts.Inactive,
+		&counts.UniqueUsers,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	return &counts, nil
+}
+
+// sessionArgs returns the session's values in sessionColumns order
+func sessionArgs(session *models.Session) []interface{} {
+	return []interface{}{
+		session.ID,
+		session.UserID,
+		session.AccessToken,
+		session.RefreshToken,
+		session.DeviceInfo,
+		session.IPAddress,
+		session.UserAgent,
+		session.IsActive,
+		session.MFAVerified,
+		session.ExpiresAt.UTC(),
+		session.CreatedAt.UTC(),
+		session.LastUsedAt.UTC(),
+	}
+}
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	var session models.Session
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.AccessToken,
+		&session.RefreshToken,
+		&session.DeviceInfo,
+		&session.IPAddress,
+		&session.UserAgent,
+		&session.IsActive,
+		&session.MFAVerified,
+		&session.ExpiresAt,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
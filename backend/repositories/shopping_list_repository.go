@@ -0,0 +1,286 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// Errors returned when a list or item does not exist or belongs to another user
+var (
+	ErrShoppingListNotFound     = errors.New("shopping list not found")
+	ErrShoppingListItemNotFound = errors.New("shopping list item not found")
+)
+
+// ShoppingListRepository handles shopping list database operations
+type ShoppingListRepository struct {
+	db *database.Database
+}
+
+// NewShoppingListRepository creates a new shopping list repository
+func NewShoppingListRepository(db *database.Database) *ShoppingListRepository {
+	return &ShoppingListRepository{db: db}
+}
+
+// CreateShoppingList stores a list and its items in a single transaction
+func (r *ShoppingListRepository) CreateShoppingList(ctx context.Context, list *models.ShoppingList) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO shopping_lists (user_id, name, meal_plan_id, start_date, end_date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		list.UserID,
+		list.Name,
+		list.MealPlanID,
+		formatPlanDate(list.StartDate),
+		formatPlanDate(list.EndDate),
+		now,
+		now,
+	).Scan(&list.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create shopping list: %w", err)
+	}
+
+	insertItem := `
+		INSERT INTO shopping_list_items (list_id, name, quantity, unit, category, checked, optional, sources, position, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`
+
+	for i := range list.Items {
+		item := &list.Items[i]
+		item.ListID = list.ID
+		item.Position = i
+		sources, err := json.Marshal(item.Sources)
+		if err != nil {
+			return fmt.Errorf("failed to encode shopping list item sources: %w", err)
+		}
+		err = tx.QueryRowContext(ctx, insertItem,
+			list.ID,
+			item.Name,
+			item.Quantity,
+			item.Unit,
+			item.Category,
+			item.Checked,
+			item.Optional,
+			string(sources),
+			item.Position,
+			now,
+		).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create shopping list item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit shopping list: %w", err)
+	}
+
+	list.CreatedAt = now
+	list.UpdatedAt = now
+	return nil
+}
+
+// GetShoppingList retrieves a list owned by the given user with its items
+func (r *ShoppingListRepository) GetShoppingList(ctx context.Context, id, userID int) (*models.ShoppingList, error) {
+	query := `
+		SELECT id, user_id, name, meal_plan_id, start_date, end_date, created_at, updated_at
+		FROM shopping_lists
+		WHERE id = $1 AND user_id = $2`
+
+	list, err := scanShoppingList(r.db.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrShoppingListNotFound
+		}
+		return nil, fmt.Errorf("failed to get shopping list: %w", err)
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, `
+		SELECT id, list_id, name, quantity, unit, category, checked, optional, sources, position, checked_at
+		FROM shopping_list_items
+		WHERE list_id = $1
+		ORDER BY position, id`, list.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shopping list items: %w", err)
+	}
+	defer rows.Close()
+
+	list.Items = []models.ShoppingListItem{}
+	for rows.Next() {
+		item, err := scanShoppingListItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shopping list item: %w", err)
+		}
+		list.Items = append(list.Items, *item)
+	}
+
+	return list, rows.Err()
+}
+
+// GetShoppingListsByUserID retrieves a user's lists without their items,
+// newest first
+func (r *ShoppingListRepository) GetShoppingListsByUserID(ctx context.Context, userID, limit, offset int) ([]*models.ShoppingList, error) {
+	query := `
+		SELECT id, user_id, name, meal_plan_id, start_date, end_date, created_at, updated_at
+		FROM shopping_lists
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shopping lists: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []*models.ShoppingList
+	for rows.Next() {
+		list, err := scanShoppingList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shopping list: %w", err)
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+// SetItemChecked ticks an item on or off a list owned by the given user
+func (r *ShoppingListRepository) SetItemChecked(ctx context.Context, listID, itemID, userID int, checked bool) error {
+	var checkedAt interface{}
+	now := time.Now()
+	if checked {
+		checkedAt = now
+	}
+
+	query := `
+		UPDATE shopping_list_items
+		SET checked = $1, checked_at = $2
+		WHERE id = $3 AND list_id = $4
+		  AND list_id IN (SELECT id FROM shopping_lists WHERE user_id = $5)`
+
+	result, err := r.db.DB.ExecContext(ctx, query, checked, checkedAt, itemID, listID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update shopping list item: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrShoppingListItemNotFound
+	}
+
+	if _, err := r.db.DB.ExecContext(ctx, "UPDATE shopping_lists SET updated_at = $1 WHERE id = $2", now, listID); err != nil {
+		return fmt.Errorf("failed to update shopping list: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteShoppingList deletes a list owned by the given user and its items
+func (r *ShoppingListRepository) DeleteShoppingList(ctx context.Context, id, userID int) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM shopping_lists WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete shopping list: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrShoppingListNotFound
+	}
+
+	// SQLite only cascades when foreign keys are enabled on the connection
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shopping_list_items WHERE list_id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete shopping list items: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit shopping list deletion: %w", err)
+	}
+
+	return nil
+}
+
+func scanShoppingList(row rowScanner) (*models.ShoppingList, error) {
+	var list models.ShoppingList
+	var mealPlanID sql.NullInt64
+	var startDate, endDate sql.NullString
+
+	err := row.Scan(
+		&list.ID,
+		&list.UserID,
+		&list.Name,
+		&mealPlanID,
+		&startDate,
+		&endDate,
+		&list.CreatedAt,
+		&list.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if mealPlanID.Valid {
+		id := int(mealPlanID.Int64)
+		list.MealPlanID = &id
+	}
+	list.StartDate = parsePlanDate(startDate)
+	list.EndDate = parsePlanDate(endDate)
+	return &list, nil
+}
+
+func scanShoppingListItem(row rowScanner) (*models.ShoppingListItem, error) {
+	var item models.ShoppingListItem
+	var quantity sql.NullFloat64
+	var unit, sources sql.NullString
+	var checkedAt sql.NullTime
+
+	err := row.Scan(
+		&item.ID,
+		&item.ListID,
+		&item.Name,
+		&quantity,
+		&unit,
+		&item.Category,
+		&item.Checked,
+		&item.Optional,
+		&sources,
+		&item.Position,
+		&checkedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity.Valid {
+		q := quantity.Float64
+		item.Quantity = &q
+	}
+	item.Unit = unit.String
+	if checkedAt.Valid {
+		item.CheckedAt = &checkedAt.Time
+	}
+
+	item.Sources = []string{}
+	if sources.Valid && sources.String != "" {
+		if err := json.Unmarshal([]byte(sources.String), &item.Sources); err != nil {
+			return nil, fmt.Errorf("failed to decode shopping list item sources: %w", err)
+		}
+	}
+	return &item, nil
+}
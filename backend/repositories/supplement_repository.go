@@ -0,0 +1,350 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// SupplementRepository handles the supplements users track
+type SupplementRepository struct {
+	db *database.Database
+}
+
+// NewSupplementRepository creates a new supplement repository
+func NewSupplementRepository(db *database.Database) *SupplementRepository {
+	return &SupplementRepository{db: db}
+}
+
+const supplementColumns = `id, user_id, name, brand, description, category, form, dosage, frequency, timing,
+		ingredients, benefits, side_effects, warnings, is_halal, is_vegetarian, is_vegan, is_organic,
+		price, currency, image_url, tags, notes, start_date, end_date, is_active, created_at, updated_at`
+
+// supplementLists holds the JSON-encoded list columns of a supplement
+type supplementLists struct {
+	ingredients, benefits, sideEffects, warnings, tags string
+}
+
+func encodeSupplementLists(supplement *models.Supplement) (*supplementLists, error) {
+	var lists supplementLists
+	var err error
+	if lists.ingredients, err = encodeStringList(supplement.Ingredients); err != nil {
+		return nil, err
+	}
+	if lists.benefits, err = encodeStringList(supplement.Benefits); err != nil {
+		return nil, err
+	}
+	if lists.sideEffects, err = encodeStringList(supplement.SideEffects); err != nil {
+		return nil, err
+	}
+	if lists.warnings, err = encodeStringList(supplement.Warnings); err != nil {
+		return nil, err
+	}
+	if lists.tags, err = encodeStringList(supplement.Tags); err != nil {
+		return nil, err
+	}
+	return &lists, nil
+}
+
+// CreateSupplement stores a supplement. The ID and timestamps are set by the caller.
+func (r *SupplementRepository) CreateSupplement(ctx context.Context, supplement *models.Supplement) error {
+	if _, err := r.insertSupplement(ctx, supplement, ""); err != nil {
+		return fmt.Errorf("failed to create supplement: %w", err)
+	}
+	return nil
+}
+
+// ImportSupplement stores a supplement unless one with the same ID exists
+// and reports whether it was inserted
+func (r *SupplementRepository) ImportSupplement(ctx context.Context, supplement *models.Supplement) (bool, error) {
+	inserted, err := r.insertSupplement(ctx, supplement, "ON CONFLICT (id) DO NOTHING")
+	if err != nil {
+		return false, fmt.Errorf("failed to import supplement: %w", err)
+	}
+	return inserted, nil
+}
+
+func (r *SupplementRepository) insertSupplement(ctx context.Context, supplement *models.Supplement, onConflict string) (bool, error) {
+	lists, err := encodeSupplementLists(supplement)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO supplements (` + supplementColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+			$19, $20, $21, $22, $23, $24, $25, $26, $27, $28) ` + onConflict
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		supplement.ID,
+		supplement.UserID,
+		supplement.Name,
+		supplement.Brand,
+		supplement.Description,
+		supplement.Category,
+		supplement.Form,
+		supplement.Dosage,
+		supplement.Frequency,
+		supplement.Timing,
+		lists.ingredients,
+		lists.benefits,
+		lists.sideEffects,
+		lists.warnings,
+		supplement.IsHalal,
+		supplement.IsVegetarian,
+		supplement.IsVegan,
+		supplement.IsOrganic,
+		supplement.Price,
+		supplement.Currency,
+		supplement.ImageURL,
+		lists.tags,
+		supplement.Notes,
+		utcOrNil(supplement.StartDate),
+		utcOrNil(supplement.EndDate),
+		supplement.IsActive,
+		supplement.CreatedAt.UTC(),
+		supplement.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetSupplementByID retrieves a supplement by ID
+func (r *SupplementRepository) GetSupplementByID(ctx context.Context, id string) (*models.Supplement, error) {
+	query := `SELECT ` + supplementColumns + ` FROM supplements WHERE id = $1`
+
+	supplement, err := scanSupplement(r.db.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("supplement not found")
+		}
+		return nil, fmt.Errorf("failed to get supplement: %w", err)
+	}
+	return supplement, nil
+}
+
+// GetSupplementsByUserID retrieves a user's supplements, oldest first
+func (r *SupplementRepository) GetSupplementsByUserID(ctx context.Context, userID string) ([]*models.Supplement, error) {
+	query := `SELECT ` + supplementColumns + ` FROM supplements WHERE user_id = $1 ORDER BY created_at ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplements: %w", err)
+	}
+	defer rows.Close()
+
+	var supplements []*models.Supplement
+	for rows.Next() {
+		supplement, err := scanSupplement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplement: %w", err)
+		}
+		supplements = append(supplements, supplement)
+	}
+
+	return supplements, rows.Err()
+}
+
+// ListScheduledSupplements returns the active supplements with a timing set
+// of one user, or of every user when userID is empty
+func (r *SupplementRepository) ListScheduledSupplements(ctx context.Context, userID string) ([]*models.Supplement, error) {
+	query := `SELECT ` + supplementColumns + ` FROM supplements
+		WHERE is_active = $1 AND timing != '' AND ($2 = '' OR user_id = $3)
+		ORDER BY user_id, created_at`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, true, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled supplements: %w", err)
+	}
+	defer rows.Close()
+
+	var supplements []*models.Supplement
+	for rows.Next() {
+		supplement, err := scanSupplement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplement: %w", err)
+		}
+		supplements = append(supplements, supplement)
+	}
+
+	return supplements, rows.Err()
+}
+
+// UpdateSupplement replaces a supplement's fields; the owner, active flag and
+// created_at are kept
+func (r *SupplementRepository) UpdateSupplement(ctx context.Context, supplement *models.Supplement) error {
+	lists, err := encodeSupplementLists(supplement)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE supplements
+		SET name = $1, brand = $2, description = $3, category = $4, form = $5, dosage = $6,
+			frequency = $7, timing = $8, ingredients = $9, benefits = $10, side_effects = $11,
+			warnings = $12, is_halal = $13, is_vegetarian = $14, is_vegan = $15, is_organic = $16,
+			price = $17, currency = $18, image_url = $19, tags = $20, notes = $21,
+			start_date = $22, end_date = $23, updated_at = $24
+		WHERE id = $25`
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		supplement.Name,
+		supplement.Brand,
+		supplement.Description,
+		supplement.Category,
+		supplement.Form,
+		supplement.Dosage,
+		supplement.Frequency,
+		supplement.Timing,
+		lists.ingredients,
+		lists.benefits,
+		lists.sideEffects,
+		lists.warnings,
+		supplement.IsHalal,
+		supplement.IsVegetarian,
+		supplement.IsVegan,
+		supplement.IsOrganic,
+		supplement.Price,
+		supplement.Currency,
+		supplement.ImageURL,
+		lists.tags,
+		supplement.Notes,
+		utcOrNil(supplement.StartDate),
+		utcOrNil(supplement.EndDate),
+		supplement.UpdatedAt.UTC(),
+		supplement.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update supplement: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("supplement not found")
+	}
+
+	return nil
+}
+
+// ToggleSupplementActive flips the active flag of a supplement owned by the user
+func (r *SupplementRepository) ToggleSupplementActive(ctx context.Context, id, userID string) error {
+	query := `UPDATE supplements SET is_active = NOT is_active, updated_at = $1 WHERE id = $2 AND user_id = $3`
+
+	result, err := r.db.DB.ExecContext(ctx, query, time.Now().UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update supplement: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("supplement not found or unauthorized")
+	}
+
+	return nil
+}
+
+// DeleteSupplement deletes a supplement owned by the user
+func (r *SupplementRepository) DeleteSupplement(ctx context.Context, id, userID string) error {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM supplements WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete supplement: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("supplement not found")
+	}
+
+	return nil
+}
+
+func scanSupplement(row rowScanner) (*models.Supplement, error) {
+	var supplement models.Supplement
+	var ingredients, benefits, sideEffects, warnings, tags sql.NullString
+	var startDate, endDate sql.NullTime
+
+	err := row.Scan(
+		&supplement.ID,
+		&supplement.UserID,
+		&supplement.Name,
+		&supplement.Brand,
+		&supplement.Description,
+		&supplement.Category,
+		&supplement.Form,
+		&supplement.Dosage,
+		&supplement.Frequency,
+		&supplement.Timing,
+		&ingredients,
+		&benefits,
+		&sideEffects,
+		&warnings,
+		&supplement.IsHalal,
+		&supplement.IsVegetarian,
+		&supplement.IsVegan,
+		&supplement.IsOrganic,
+		&supplement.Price,
+		&supplement.Currency,
+		&supplement.ImageURL,
+		&tags,
+		&supplement.Notes,
+		&startDate,
+		&endDate,
+		&supplement.IsActive,
+		&supplement.CreatedAt,
+		&supplement.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []struct {
+		raw  sql.NullString
+		dest *[]string
+	}{
+		{ingredients, &supplement.Ingredients},
+		{benefits, &supplement.Benefits},
+		{sideEffects, &supplement.SideEffects},
+		{warnings, &supplement.Warnings},
+		{tags, &supplement.Tags},
+	}
+	for _, list := range lists {
+		if *list.dest, err = decodeStringList(list.raw); err != nil {
+			return nil, err
+		}
+	}
+
+	supplement.StartDate = timeOrNil(startDate)
+	supplement.EndDate = timeOrNil(endDate)
+	return &supplement, nil
+}
+
+// utcOrNil converts an optional timestamp for storage
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
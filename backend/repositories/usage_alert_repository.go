@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// UsageAlertRepository handles alert thresholds on API key usage
+type UsageAlertRepository struct {
+	db *database.Database
+}
+
+// NewUsageAlertRepository creates a new usage alert repository
+func NewUsageAlertRepository(db *database.Database) *UsageAlertRepository {
+	return &UsageAlertRepository{db: db}
+}
+
+const usageAlertColumns = `id, api_key_id, name, condition, threshold, window_minutes, cooldown_minutes,
+		enabled, last_triggered, metadata, created_at`
+
+// CreateUsageAlert stores an alert. The ID and created time are set by the caller.
+func (r *UsageAlertRepository) CreateUsageAlert(ctx context.Context, alert *models.UsageAlert) error {
+	metadata := alert.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	var apiKeyID interface{}
+	if alert.APIKeyID != "" {
+		apiKeyID = alert.APIKeyID
+	}
+
+	query := `
+		INSERT INTO usage_alerts (id, api_key_id, name, condition, threshold, window_minutes,
+			cooldown_minutes, enabled, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err = r.db.DB.ExecContext(ctx, query,
+		alert.ID,
+		apiKeyID,
+		alert.Name,
+		alert.Condition,
+		alert.Threshold,
+		alert.WindowMinutes,
+		alert.CooldownMinutes,
+		alert.Enabled,
+		string(metadataJSON),
+		alert.CreatedAt.UTC(),
+		alert.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create usage alert: %w", err)
+	}
+
+	return nil
+}
+
+// GetUsageAlertsByAPIKeyID retrieves the alerts set on one key
+func (r *UsageAlertRepository) GetUsageAlertsByAPIKeyID(ctx context.Context, apiKeyID string) ([]*models.UsageAlert, error) {
+	query := `SELECT ` + usageAlertColumns + ` FROM usage_alerts WHERE api_key_id = $1 ORDER BY created_at`
+	return r.queryUsageAlerts(ctx, query, apiKeyID)
+}
+
+// GetEnabledUsageAlerts retrieves the enabled alerts that apply to a key:
+// its own and those without a key
+func (r *UsageAlertRepository) GetEnabledUsageAlerts(ctx context.Context, apiKeyID string) ([]*models.UsageAlert, error) {
+	query := `SELECT ` + usageAlertColumns + ` FROM usage_alerts
+		WHERE (api_key_id = $1 OR api_key_id IS NULL) AND enabled = $2
+		ORDER BY created_at`
+	return r.queryUsageAlerts(ctx, query, apiKeyID, true)
+}
+
+func (r *UsageAlertRepository) queryUsageAlerts(ctx context.Context, query string, args ...interface{}) ([]*models.UsageAlert, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*models.UsageAlert
+	for rows.Next() {
+		alert, err := scanUsageAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// MarkUsageAlertTriggered records that an alert fired at now, unless it
+// already fired after cooldownBefore. It reports whether the alert was marked,
+// so concurrent evaluations fire it once.
+func (r *UsageAlertRepository) MarkUsageAlertTriggered(ctx context.Context, id string, now, cooldownBefore time.Time) (bool, error) {
+	query := `
+		UPDATE usage_alerts SET last_triggered = $1, updated_at = $2
+		WHERE id = $3 AND (last_triggered IS NULL OR last_triggered <= $4)`
+
+	result, err := r.db.DB.ExecContext(ctx, query, now.UTC(), now.UTC(), id, cooldownBefore.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to update usage alert: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// DeleteUsageAlert removes an alert set on a key
+func (r *UsageAlertRepository) DeleteUsageAlert(ctx context.Context, id, apiKeyID string) error {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM usage_alerts WHERE id = $1 AND api_key_id = $2`, id, apiKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete usage alert: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("usage alert not found")
+	}
+
+	return nil
+}
+
+func scanUsageAlert(row rowScanner) (*models.UsageAlert, error) {
+	var alert models.UsageAlert
+	var apiKeyID, metadata sql.NullString
+	var lastTriggered sql.NullTime
+
+	err := row.Scan(
+		&alert.ID,
+		&apiKeyID,
+		&alert.Name,
+		&alert.Condition,
+		&alert.Threshold,
+		&alert.WindowMinutes,
+		&alert.CooldownMinutes,
+		&alert.Enabled,
+		&lastTriggered,
+		&metadata,
+		&alert.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	alert.APIKeyID = apiKeyID.String
+	alert.LastTriggered = timeOrNil(lastTriggered)
+	alert.Metadata = map[string]interface{}{}
+	if metadata.Valid && metadata.String != "" {
+		if err := json.Unmarshal([]byte(metadata.String), &alert.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata: %w", err)
+		}
+	}
+
+	return &alert, nil
+}
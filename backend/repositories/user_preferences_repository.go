@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+)
+
+// UserPreferencesRepository handles user preference database operations.
+// Values are stored JSON-encoded so booleans, numbers and strings round-trip.
+type UserPreferencesRepository struct {
+	db *database.Database
+}
+
+// NewUserPreferencesRepository creates a new user preferences repository
+func NewUserPreferencesRepository(db *database.Database) *UserPreferencesRepository {
+	return &UserPreferencesRepository{db: db}
+}
+
+// GetPreferences returns the stored preference values for a user keyed by name
+func (r *UserPreferencesRepository) GetPreferences(ctx context.Context, userID int) (map[string]string, error) {
+	query := `SELECT pref_key, value FROM user_preferences WHERE user_id = $1`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan user preference: %w", err)
+		}
+		preferences[key] = value
+	}
+
+	return preferences, rows.Err()
+}
+
+// UpdatePreferences upserts the given values and removes the keys listed in
+// reset, all in a single transaction
+func (r *UserPreferencesRepository) UpdatePreferences(ctx context.Context, userID int, values map[string]string, reset []string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upsert := `
+		INSERT INTO user_preferences (user_id, pref_key, value, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, pref_key) DO UPDATE
+		SET value = excluded.value, updated_at = excluded.updated_at`
+
+	now := time.Now()
+	for key, value := range values {
+		if _, err := tx.ExecContext(ctx, upsert, userID, key, value, now); err != nil {
+			return fmt.Errorf("failed to save user preference %s: %w", key, err)
+		}
+	}
+
+	for _, key := range reset {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_preferences WHERE user_id = $1 AND pref_key = $2", userID, key); err != nil {
+			return fmt.Errorf("failed to reset user preference %s: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user preferences: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePreferences removes all stored preferences for a user
+func (r *UserPreferencesRepository) DeletePreferences(ctx context.Context, userID int) error {
+	if _, err := r.db.DB.ExecContext(ctx, "DELETE FROM user_preferences WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete user preferences: %w", err)
+	}
+	return nil
+}
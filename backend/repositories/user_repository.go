@@ -1,13 +1,18 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"nutrition-platform/database"
 	"nutrition-platform/models"
 )
 
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = errors.New("user not found")
+
 // UserRepository handles user-related database operations
 type UserRepository struct {
 	db *database.Database
@@ -64,7 +69,7 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -95,7 +100,7 @@ func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
@@ -239,7 +244,8 @@ func (r *UserRepository) GetActiveNutritionGoals(userID uint) ([]*models.Nutriti
 	query := `
 		SELECT id, user_id, daily_calories, protein_grams, carbs_grams, fat_grams,
 		       fiber_grams, sugar_grams, sodium_mg, water_ml, is_active,
-		       start_date, end_date, created_at, updated_at
+		       start_date, end_date, created_at, updated_at,
+		       daily_calories_low, daily_calories_high, estimated_tdee, tdee_confidence, tdee_updated_at
 		FROM nutrition_goals
 		WHERE user_id = $1 AND is_active = true
 		ORDER BY created_at DESC
@@ -270,6 +276,11 @@ func (r *UserRepository) GetActiveNutritionGoals(userID uint) ([]*models.Nutriti
 			&goal.EndDate,
 			&goal.CreatedAt,
 			&goal.UpdatedAt,
+			&goal.DailyCaloriesLow,
+			&goal.DailyCaloriesHigh,
+			&goal.EstimatedTDEE,
+			&goal.TDEEConfidence,
+			&goal.TDEEUpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan nutrition goal: %w", err)
@@ -285,11 +296,13 @@ func (r *UserRepository) CreateNutritionGoal(goal *models.NutritionGoal) error {
 	query := `
 		INSERT INTO nutrition_goals (user_id, daily_calories, protein_grams, carbs_grams,
 		                    fat_grams, fiber_grams, sugar_grams, sodium_mg, water_ml,
-		                    is_active, start_date, end_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		                    is_active, start_date, end_date, daily_calories_low,
+		                    daily_calories_high, estimated_tdee, tdee_confidence, tdee_updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id
 	`
 
-	_, err := r.db.Exec(query,
+	err := r.db.QueryRow(query,
 		goal.UserID,
 		goal.DailyCalories,
 		goal.ProteinGrams,
@@ -302,7 +315,12 @@ func (r *UserRepository) CreateNutritionGoal(goal *models.NutritionGoal) error {
 		goal.IsActive,
 		goal.StartDate,
 		goal.EndDate,
-	)
+		goal.DailyCaloriesLow,
+		goal.DailyCaloriesHigh,
+		goal.EstimatedTDEE,
+		goal.TDEEConfidence,
+		goal.TDEEUpdatedAt,
+	).Scan(&goal.ID)
 
 	if err != nil {
 		return fmt.Errorf("failed to create nutrition goal: %w", err)
@@ -313,17 +331,19 @@ func (r *UserRepository) CreateNutritionGoal(goal *models.NutritionGoal) error {
 
 // UpdateNutritionGoal updates an existing nutrition goal
 func (r *UserRepository) UpdateNutritionGoal(goal *models.NutritionGoal) error {
+	// Placeholders are numbered in order of appearance because SQLite binds
+	// them that way
 	query := `
 		UPDATE nutrition_goals
-		SET daily_calories = $2, protein_grams = $3, carbs_grams = $4,
-		    fat_grams = $5, fiber_grams = $6, sugar_grams = $7, sodium_mg = $8,
-		    water_ml = $9, is_active = $10, start_date = $11, end_date = $12,
-		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = $1 AND user_id = $13
+		SET daily_calories = $1, protein_grams = $2, carbs_grams = $3,
+		    fat_grams = $4, fiber_grams = $5, sugar_grams = $6, sodium_mg = $7,
+		    water_ml = $8, is_active = $9, start_date = $10, end_date = $11,
+		    daily_calories_low = $12, daily_calories_high = $13, estimated_tdee = $14,
+		    tdee_confidence = $15, tdee_updated_at = $16, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $17 AND user_id = $18
 	`
 
 	_, err := r.db.Exec(query,
-		goal.ID,
 		goal.DailyCalories,
 		goal.ProteinGrams,
 		goal.CarbsGrams,
@@ -335,6 +355,12 @@ func (r *UserRepository) UpdateNutritionGoal(goal *models.NutritionGoal) error {
 		goal.IsActive,
 		goal.StartDate,
 		goal.EndDate,
+		goal.DailyCaloriesLow,
+		goal.DailyCaloriesHigh,
+		goal.EstimatedTDEE,
+		goal.TDEEConfidence,
+		goal.TDEEUpdatedAt,
+		goal.ID,
 		goal.UserID,
 	)
 
@@ -360,3 +386,33 @@ func (r *UserRepository) DeleteNutritionGoal(goalID int, userID uint) error {
 
 	return nil
 }
+
+// GetUserAccount retrieves the account details access tokens are issued
+// with. Deactivated users are reported as not found.
+func (r *UserRepository) GetUserAccount(ctx context.Context, id string) (*models.UserAccount, error) {
+	query := `
+		SELECT id, email, first_name, last_name, role, is_admin
+		FROM users
+		WHERE id = $1 AND is_active = $2`
+
+	var account models.UserAccount
+	var firstName, lastName sql.NullString
+	err := r.db.DB.QueryRowContext(ctx, query, id, true).Scan(
+		&account.ID,
+		&account.Email,
+		&firstName,
+		&lastName,
+		&account.Role,
+		&account.IsAdmin,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user account: %w", err)
+	}
+	account.FirstName = firstName.String
+	account.LastName = lastName.String
+
+	return &account, nil
+}
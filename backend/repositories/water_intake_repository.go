@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nutrition-platform/database"
+)
+
+// WaterIntakeEntry is a single logged drink
+type WaterIntakeEntry struct {
+	ID       int       `json:"id"`
+	AmountMl int       `json:"amount_ml"`
+	Date     time.Time `json:"date"`
+	Notes    *string   `json:"notes,omitempty"`
+}
+
+// WaterIntakeRepository handles water intake database operations
+type WaterIntakeRepository struct {
+	db *database.Database
+}
+
+// NewWaterIntakeRepository creates a new water intake repository
+func NewWaterIntakeRepository(db *database.Database) *WaterIntakeRepository {
+	return &WaterIntakeRepository{db: db}
+}
+
+// GetWaterIntakeByDateRange retrieves a user's water intake logged in [from, to)
+func (r *WaterIntakeRepository) GetWaterIntakeByDateRange(ctx context.Context, userID int, from, to time.Time) ([]WaterIntakeEntry, error) {
+	query := `
+		SELECT id, amount_ml, date, notes
+		FROM water_intake
+		WHERE user_id = $1 AND date >= $2 AND date < $3
+		ORDER BY date ASC`
+
+	// water_intake stores user IDs as text
+	rows, err := r.db.DB.QueryContext(ctx, query, strconv.Itoa(userID), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get water intake: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []WaterIntakeEntry
+	for rows.Next() {
+		var e WaterIntakeEntry
+		if err := rows.Scan(&e.ID, &e.AmountMl, &e.Date, &e.Notes); err != nil {
+			return nil, fmt.Errorf("failed to scan water intake: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
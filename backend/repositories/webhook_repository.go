@@ -0,0 +1,410 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// WebhookRepository handles webhook subscriptions and their delivery log
+type WebhookRepository struct {
+	db *database.Database
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *database.Database) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookSubscriptionColumns = `id, user_id, api_key_id, url, events, description, secret_ciphertext,
+	active, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_status_code, last_error, delivered_at, created_at, updated_at`
+
+// CreateSubscription stores a new subscription
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	events, err := encodeStringList(sub.Events)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhook_subscriptions (` + webhookSubscriptionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = r.db.DB.ExecContext(ctx, query,
+		sub.ID,
+		sub.UserID,
+		nullIfEmpty(sub.APIKeyID),
+		sub.URL,
+		events,
+		nullIfEmpty(sub.Description),
+		sub.SecretCiphertext,
+		sub.Active,
+		sub.CreatedAt.UTC(),
+		sub.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// GetSubscription retrieves one of a user's subscriptions
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id, userID string) (*models.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1 AND user_id = $2`
+
+	sub, err := scanWebhookSubscription(r.db.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook subscription not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns a user's subscriptions, or only those created
+// with apiKeyID when it is set
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, userID, apiKeyID string) ([]*models.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + `
+		FROM webhook_subscriptions
+		WHERE user_id = $1 AND ($2 = '' OR api_key_id = $3)
+		ORDER BY created_at DESC`
+
+	return r.listSubscriptions(ctx, query, userID, apiKeyID, apiKeyID)
+}
+
+// ListDeliverableSubscriptions returns a user's active subscriptions that
+// were created without an API key or whose key is still active at now
+func (r *WebhookRepository) ListDeliverableSubscriptions(ctx context.Context, userID string, now time.Time) ([]*models.WebhookSubscription, error) {
+	query := `SELECT s.id, s.user_id, s.api_key_id, s.url, s.events, s.description, s.secret_ciphertext,
+			s.active, s.created_at, s.updated_at
+		FROM webhook_subscriptions s
+		LEFT JOIN api_keys k ON k.id = s.api_key_id
+		WHERE s.user_id = $1 AND s.active = $2
+			AND (s.api_key_id IS NULL OR (k.status = $3 AND (k.expires_at IS NULL OR k.expires_at > $4)))`
+
+	return r.listSubscriptions(ctx, query, userID, true, models.APIKeyStatusActive, now.UTC())
+}
+
+// UpdateSubscription saves a subscription's URL, events, description and
+// active flag
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	events, err := encodeStringList(sub.Events)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.DB.ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = $1, events = $2, description = $3, active = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7`,
+		sub.URL, events, nullIfEmpty(sub.Description), sub.Active, sub.UpdatedAt.UTC(), sub.ID, sub.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("webhook subscription not found")
+	}
+	return nil
+}
+
+// DeleteSubscription deletes a subscription and its delivery log
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id, userID string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted, err := execAffected(ctx, tx, "DELETE FROM webhook_subscriptions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("webhook subscription not found")
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE subscription_id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook subscription deletion: %w", err)
+	}
+	return nil
+}
+
+// CreateDeliveries queues deliveries in one transaction
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_deliveries (` + webhookDeliveryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	for _, d := range deliveries {
+		_, err := tx.ExecContext(ctx, query,
+			d.ID,
+			d.SubscriptionID,
+			d.EventID,
+			d.EventType,
+			string(d.Payload),
+			d.Status,
+			d.Attempts,
+			utcOrNil(d.NextAttemptAt),
+			d.LastStatusCode,
+			nullIfEmpty(d.LastError),
+			utcOrNil(d.DeliveredAt),
+			d.CreatedAt.UTC(),
+			d.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// ListDueDeliveries returns up to limit pending deliveries of active
+// subscriptions whose next attempt is due at now, oldest first
+func (r *WebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.DueWebhookDelivery, error) {
+	query := `
+		SELECT d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at,
+			d.created_at, d.updated_at, s.url, s.secret_ciphertext
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = $1 AND d.next_attempt_at <= $2 AND s.active = $3
+		ORDER BY d.next_attempt_at
+		LIMIT $4`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, models.WebhookDeliveryPending, now.UTC(), true, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	due := []*models.DueWebhookDelivery{}
+	for rows.Next() {
+		var d models.DueWebhookDelivery
+		if err := scanWebhookDeliveryInto(rows, &d.WebhookDelivery, &d.URL, &d.SecretCiphertext); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		due = append(due, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+	return due, nil
+}
+
+// ClaimDelivery pushes a due delivery's next attempt to leaseUntil so no
+// other worker sends it meanwhile. It reports false when another worker
+// claimed it first or it is no longer pending.
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, id string, now, leaseUntil time.Time) (bool, error) {
+	result, err := r.db.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET next_attempt_at = $1
+		WHERE id = $2 AND status = $3 AND next_attempt_at <= $4`,
+		leaseUntil.UTC(), id, models.WebhookDeliveryPending, now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// RecordAttempt saves the outcome of a delivery attempt
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, d *models.WebhookDelivery) error {
+	_, err := r.db.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5,
+			delivered_at = $6, updated_at = $7
+		WHERE id = $8`,
+		d.Status, d.Attempts, utcOrNil(d.NextAttemptAt), d.LastStatusCode, nullIfEmpty(d.LastError),
+		utcOrNil(d.DeliveredAt), d.UpdatedAt.UTC(), d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// Requeue puts a delivery that is not pending back in the queue with its
+// attempts reset, due at now. It reports false when the delivery is
+// already pending.
+func (r *WebhookRepository) Requeue(ctx context.Context, id, subscriptionID string, now time.Time) (bool, error) {
+	result, err := r.db.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, updated_at = $4
+		WHERE id = $5 AND subscription_id = $6 AND status <> $7`,
+		models.WebhookDeliveryPending, 0, now.UTC(), now.UTC(), id, subscriptionID, models.WebhookDeliveryPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// RequeueDead puts all of a subscription's dead-lettered deliveries back in
+// the queue and returns how many there were
+func (r *WebhookRepository) RequeueDead(ctx context.Context, subscriptionID string, now time.Time) (int64, error) {
+	result, err := r.db.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, updated_at = $4
+		WHERE subscription_id = $5 AND status = $6`,
+		models.WebhookDeliveryPending, 0, now.UTC(), now.UTC(), subscriptionID, models.WebhookDeliveryDead)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue webhook deliveries: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows, nil
+}
+
+// GetDelivery retrieves one delivery of a subscription
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id, subscriptionID string) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2`
+
+	var d models.WebhookDelivery
+	if err := scanWebhookDeliveryInto(r.db.DB.QueryRowContext(ctx, query, id, subscriptionID), &d); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return &d, nil
+}
+
+// ListDeliveries returns a subscription's deliveries, newest first,
+// optionally only those with status
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $3)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, subscriptionID, status, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDeliveryInto(rows, &d); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) listSubscriptions(ctx context.Context, query string, args ...interface{}) ([]*models.WebhookSubscription, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func scanWebhookSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	var apiKeyID, events, description sql.NullString
+
+	err := row.Scan(
+		&sub.ID,
+		&sub.UserID,
+		&apiKeyID,
+		&sub.URL,
+		&events,
+		&description,
+		&sub.SecretCiphertext,
+		&sub.Active,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.APIKeyID = apiKeyID.String
+	sub.Description = description.String
+	if sub.Events, err = decodeStringList(events); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// scanWebhookDeliveryInto scans the delivery columns into d, followed by
+// any extra columns selected after them
+func scanWebhookDeliveryInto(row rowScanner, d *models.WebhookDelivery, extra ...interface{}) error {
+	var payload string
+	var lastError sql.NullString
+	var nextAttemptAt, deliveredAt sql.NullTime
+
+	dest := []interface{}{
+		&d.ID,
+		&d.SubscriptionID,
+		&d.EventID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&nextAttemptAt,
+		&d.LastStatusCode,
+		&lastError,
+		&deliveredAt,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	d.Payload = []byte(payload)
+	d.LastError = lastError.String
+	d.NextAttemptAt = timeOrNil(nextAttemptAt)
+	d.DeliveredAt = timeOrNil(deliveredAt)
+	return nil
+}
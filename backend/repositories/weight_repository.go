@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -23,7 +24,7 @@ func NewWeightRepository(db *database.Database) *WeightRepository {
 func (r *WeightRepository) CreateWeightLog(weightLog *models.WeightLog) error {
 	query := `
 		INSERT INTO weight_logs (user_id, weight, unit, notes, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 
 	_, err := r.db.Exec(query,
@@ -90,6 +91,43 @@ func (r *WeightRepository) GetWeightLogs(userID int, page, perPage int) ([]*mode
 	return weightLogs, total, nil
 }
 
+// GetWeightLogsByDateRange retrieves a user's weight logs created in
+// [from, to), oldest first
+func (r *WeightRepository) GetWeightLogsByDateRange(ctx context.Context, userID int, from, to time.Time) ([]*models.WeightLog, error) {
+	query := `
+		SELECT id, user_id, weight, unit, notes, created_at, updated_at
+		FROM weight_logs
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weight logs: %w", err)
+	}
+	defer rows.Close()
+
+	var weightLogs []*models.WeightLog
+	for rows.Next() {
+		weightLog := &models.WeightLog{}
+		err := rows.Scan(
+			&weightLog.ID,
+			&weightLog.UserID,
+			&weightLog.Weight,
+			&weightLog.Unit,
+			&weightLog.Notes,
+			&weightLog.CreatedAt,
+			&weightLog.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan weight log: %w", err)
+		}
+		weightLogs = append(weightLogs, weightLog)
+	}
+
+	return weightLogs, rows.Err()
+}
+
 // GetWeightLogByID retrieves a specific weight log by ID
 func (r *WeightRepository) GetWeightLogByID(id, userID int) (*models.WeightLog, error) {
 	query := `
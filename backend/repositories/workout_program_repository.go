@@ -0,0 +1,523 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+)
+
+// WorkoutProgramRepository handles workout programs, the assignments that
+// put users on them and the progression of those assignments
+type WorkoutProgramRepository struct {
+	db *database.Database
+}
+
+// NewWorkoutProgramRepository creates a new workout program repository
+func NewWorkoutProgramRepository(db *database.Database) *WorkoutProgramRepository {
+	return &WorkoutProgramRepository{db: db}
+}
+
+const programAssignmentColumns = `id, user_id, program_id, start_date, weeks, training_days, status, created_at, updated_at`
+
+const programTargetColumns = `assignment_id, exercise_id, exercise_name, variant, sets, rep_min, rep_max, target_reps,
+	load, load_increment, failed_sessions, regression_level, regressions, target_muscles, updated_at`
+
+const programSessionColumns = `id, assignment_id, workout_session_id, name, week, scheduled_date, deload, status,
+	user_workout_session_id, completed_at`
+
+const programAdjustmentColumns = `id, assignment_id, exercise_id, user_workout_session_id, rule, description,
+	sets_before, sets_after, reps_before, reps_after, load_before, load_after, created_at`
+
+// GetProgram retrieves a workout program
+func (r *WorkoutProgramRepository) GetProgram(ctx context.Context, id string) (*models.WorkoutProgram, error) {
+	query := `
+		SELECT id, name, description, program_type, fitness_level, duration_weeks, days_per_week,
+			   session_duration_minutes, equipment_required, target_goals, progression_plan, created_at, updated_at
+		FROM workout_programs
+		WHERE id = $1`
+
+	var program models.WorkoutProgram
+	var equipment, goals, progression sql.NullString
+	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
+		&program.ID,
+		&program.Name,
+		&program.Description,
+		&program.ProgramType,
+		&program.FitnessLevel,
+		&program.DurationWeeks,
+		&program.DaysPerWeek,
+		&program.SessionDurationMinutes,
+		&equipment,
+		&goals,
+		&progression,
+		&program.CreatedAt,
+		&program.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("workout program not found")
+		}
+		return nil, fmt.Errorf("failed to get workout program: %w", err)
+	}
+
+	if program.EquipmentRequired, err = decodeStringList(equipment); err != nil {
+		return nil, err
+	}
+	if program.TargetGoals, err = decodeStringList(goals); err != nil {
+		return nil, err
+	}
+	program.ProgressionPlan = []models.ProgressionStep{}
+	if progression.Valid && progression.String != "" {
+		if err := json.Unmarshal([]byte(progression.String), &program.ProgressionPlan); err != nil {
+			return nil, fmt.Errorf("failed to decode progression plan: %w", err)
+		}
+	}
+	return &program, nil
+}
+
+// ListProgramSessions returns a program's sessions in order
+func (r *WorkoutProgramRepository) ListProgramSessions(ctx context.Context, programID string) ([]*models.WorkoutSession, error) {
+	query := `
+		SELECT id, workout_program_id, session_number, name, main_exercises, modifications
+		FROM workout_sessions
+		WHERE workout_program_id = $1
+		ORDER BY session_number ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, programID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workout sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.WorkoutSession
+	for rows.Next() {
+		var session models.WorkoutSession
+		var sessionNumber sql.NullInt64
+		var exercises, modifications sql.NullString
+
+		if err := rows.Scan(&session.ID, &session.WorkoutProgramID, &sessionNumber, &session.Name, &exercises, &modifications); err != nil {
+			return nil, fmt.Errorf("failed to scan workout session: %w", err)
+		}
+
+		session.SessionNumber = int(sessionNumber.Int64)
+		session.MainExercises = []models.SessionExercise{}
+		if exercises.Valid && exercises.String != "" {
+			if err := json.Unmarshal([]byte(exercises.String), &session.MainExercises); err != nil {
+				return nil, fmt.Errorf("failed to decode session exercises: %w", err)
+			}
+		}
+		session.Modifications = []models.ExerciseModification{}
+		if modifications.Valid && modifications.String != "" {
+			if err := json.Unmarshal([]byte(modifications.String), &session.Modifications); err != nil {
+				return nil, fmt.Errorf("failed to decode session modifications: %w", err)
+			}
+		}
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// CreateAssignment stores an assignment together with its exercise targets
+// and schedule
+func (r *WorkoutProgramRepository) CreateAssignment(ctx context.Context, assignment *models.ProgramAssignment, targets []*models.ProgramExerciseTarget, sessions []*models.ProgramScheduledSession) error {
+	days, err := encodeDaysOfWeek(assignment.TrainingDays)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO program_assignments (`+programAssignmentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		assignment.ID,
+		assignment.UserID,
+		assignment.ProgramID,
+		formatPlanDate(&assignment.StartDate),
+		assignment.Weeks,
+		days,
+		assignment.Status,
+		assignment.CreatedAt.UTC(),
+		assignment.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create program assignment: %w", err)
+	}
+
+	for _, target := range targets {
+		regressions, err := encodeStringList(target.Regressions)
+		if err != nil {
+			return err
+		}
+		muscles, err := encodeStringList(target.TargetMuscles)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO program_exercise_targets (`+programTargetColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			target.AssignmentID,
+			target.ExerciseID,
+			target.ExerciseName,
+			target.Variant,
+			target.Sets,
+			target.RepMin,
+			target.RepMax,
+			target.TargetReps,
+			target.Load,
+			target.LoadIncrement,
+			target.FailedSessions,
+			target.RegressionLevel,
+			regressions,
+			muscles,
+			target.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create program exercise target: %w", err)
+		}
+	}
+
+	for _, session := range sessions {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO program_scheduled_sessions (`+programSessionColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			session.ID,
+			session.AssignmentID,
+			session.WorkoutSessionID,
+			session.Name,
+			session.Week,
+			formatPlanDate(&session.ScheduledDate),
+			session.Deload,
+			session.Status,
+			nullIfEmpty(session.UserWorkoutSessionID),
+			utcOrNil(session.CompletedAt),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create scheduled program session: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit program assignment: %w", err)
+	}
+	return nil
+}
+
+// GetAssignment retrieves one of a user's assignments
+func (r *WorkoutProgramRepository) GetAssignment(ctx context.Context, id, userID string) (*models.ProgramAssignment, error) {
+	query := `SELECT ` + programAssignmentColumns + ` FROM program_assignments WHERE id = $1 AND user_id = $2`
+
+	assignment, err := scanProgramAssignment(r.db.DB.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("program assignment not found")
+		}
+		return nil, fmt.Errorf("failed to get program assignment: %w", err)
+	}
+	return assignment, nil
+}
+
+// GetActiveAssignment retrieves the user's active assignment of a program
+func (r *WorkoutProgramRepository) GetActiveAssignment(ctx context.Context, userID, programID string) (*models.ProgramAssignment, error) {
+	query := `SELECT ` + programAssignmentColumns + `
+		FROM program_assignments
+		WHERE user_id = $1 AND program_id = $2 AND status = $3
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	assignment, err := scanProgramAssignment(r.db.DB.QueryRowContext(ctx, query, userID, programID, models.ProgramAssignmentActive))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("program assignment not found")
+		}
+		return nil, fmt.Errorf("failed to get program assignment: %w", err)
+	}
+	return assignment, nil
+}
+
+// ListAssignments returns a user's assignments, newest first
+func (r *WorkoutProgramRepository) ListAssignments(ctx context.Context, userID string) ([]*models.ProgramAssignment, error) {
+	query := `SELECT ` + programAssignmentColumns + ` FROM program_assignments WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list program assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []*models.ProgramAssignment{}
+	for rows.Next() {
+		assignment, err := scanProgramAssignment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan program assignment: %w", err)
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+// UpdateAssignmentStatus changes the status of one of a user's assignments
+func (r *WorkoutProgramRepository) UpdateAssignmentStatus(ctx context.Context, id, userID, status string) error {
+	result, err := r.db.DB.ExecContext(ctx,
+		`UPDATE program_assignments SET status = $1, updated_at = $2 WHERE id = $3 AND user_id = $4`,
+		status, time.Now().UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update program assignment: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("program assignment not found")
+	}
+	return nil
+}
+
+// ListTargets returns an assignment's exercise targets
+func (r *WorkoutProgramRepository) ListTargets(ctx context.Context, assignmentID string) ([]*models.ProgramExerciseTarget, error) {
+	query := `SELECT ` + programTargetColumns + ` FROM program_exercise_targets WHERE assignment_id = $1 ORDER BY exercise_name`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list program exercise targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []*models.ProgramExerciseTarget{}
+	for rows.Next() {
+		var target models.ProgramExerciseTarget
+		var regressions, muscles sql.NullString
+		err := rows.Scan(
+			&target.AssignmentID,
+			&target.ExerciseID,
+			&target.ExerciseName,
+			&target.Variant,
+			&target.Sets,
+			&target.RepMin,
+			&target.RepMax,
+			&target.TargetReps,
+			&target.Load,
+			&target.LoadIncrement,
+			&target.FailedSessions,
+			&target.RegressionLevel,
+			&regressions,
+			&muscles,
+			&target.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan program exercise target: %w", err)
+		}
+		if target.Regressions, err = decodeStringList(regressions); err != nil {
+			return nil, err
+		}
+		if target.TargetMuscles, err = decodeStringList(muscles); err != nil {
+			return nil, err
+		}
+		targets = append(targets, &target)
+	}
+	return targets, rows.Err()
+}
+
+// ListScheduledSessions returns an assignment's schedule in date order
+func (r *WorkoutProgramRepository) ListScheduledSessions(ctx context.Context, assignmentID string) ([]*models.ProgramScheduledSession, error) {
+	query := `SELECT ` + programSessionColumns + `
+		FROM program_scheduled_sessions
+		WHERE assignment_id = $1
+		ORDER BY scheduled_date ASC, week ASC, id ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled program sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*models.ProgramScheduledSession{}
+	for rows.Next() {
+		var session models.ProgramScheduledSession
+		var scheduledDate, userSessionID sql.NullString
+		var completedAt sql.NullTime
+		err := rows.Scan(
+			&session.ID,
+			&session.AssignmentID,
+			&session.WorkoutSessionID,
+			&session.Name,
+			&session.Week,
+			&scheduledDate,
+			&session.Deload,
+			&session.Status,
+			&userSessionID,
+			&completedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled program session: %w", err)
+		}
+		if date := parsePlanDate(scheduledDate); date != nil {
+			session.ScheduledDate = *date
+		}
+		session.UserWorkoutSessionID = userSessionID.String
+		session.CompletedAt = timeOrNil(completedAt)
+		sessions = append(sessions, &session)
+	}
+	return sessions, rows.Err()
+}
+
+// SaveProgress records the outcome of a logged session in one transaction:
+// the scheduled session it completed, if any, the changed targets and the
+// adjustments that explain them
+func (r *WorkoutProgramRepository) SaveProgress(ctx context.Context, session *models.ProgramScheduledSession, targets []*models.ProgramExerciseTarget, adjustments []*models.ProgressionAdjustment) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if session != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE program_scheduled_sessions
+			SET status = $1, user_workout_session_id = $2, completed_at = $3
+			WHERE id = $4`,
+			session.Status, nullIfEmpty(session.UserWorkoutSessionID), utcOrNil(session.CompletedAt), session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update scheduled program session: %w", err)
+		}
+	}
+
+	for _, target := range targets {
+		regressions, err := encodeStringList(target.Regressions)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE program_exercise_targets
+			SET variant = $1, sets = $2, target_reps = $3, load = $4, failed_sessions = $5,
+				regression_level = $6, regressions = $7, updated_at = $8
+			WHERE assignment_id = $9 AND exercise_id = $10`,
+			target.Variant,
+			target.Sets,
+			target.TargetReps,
+			target.Load,
+			target.FailedSessions,
+			target.RegressionLevel,
+			regressions,
+			target.UpdatedAt.UTC(),
+			target.AssignmentID,
+			target.ExerciseID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update program exercise target: %w", err)
+		}
+	}
+
+	for _, adjustment := range adjustments {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO program_adjustments (`+programAdjustmentColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			adjustment.ID,
+			adjustment.AssignmentID,
+			adjustment.ExerciseID,
+			nullIfEmpty(adjustment.UserWorkoutSessionID),
+			adjustment.Rule,
+			adjustment.Description,
+			adjustment.SetsBefore,
+			adjustment.SetsAfter,
+			adjustment.RepsBefore,
+			adjustment.RepsAfter,
+			adjustment.LoadBefore,
+			adjustment.LoadAfter,
+			adjustment.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record progression adjustment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit program progress: %w", err)
+	}
+	return nil
+}
+
+// ListAdjustments returns a page of an assignment's adjustments, newest
+// first
+func (r *WorkoutProgramRepository) ListAdjustments(ctx context.Context, assignmentID string, limit, offset int) ([]*models.ProgressionAdjustment, error) {
+	query := `SELECT ` + programAdjustmentColumns + `
+		FROM program_adjustments
+		WHERE assignment_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, assignmentID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list progression adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := []*models.ProgressionAdjustment{}
+	for rows.Next() {
+		var adjustment models.ProgressionAdjustment
+		var userSessionID sql.NullString
+		err := rows.Scan(
+			&adjustment.ID,
+			&adjustment.AssignmentID,
+			&adjustment.ExerciseID,
+			&userSessionID,
+			&adjustment.Rule,
+			&adjustment.Description,
+			&adjustment.SetsBefore,
+			&adjustment.SetsAfter,
+			&adjustment.RepsBefore,
+			&adjustment.RepsAfter,
+			&adjustment.LoadBefore,
+			&adjustment.LoadAfter,
+			&adjustment.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan progression adjustment: %w", err)
+		}
+		adjustment.UserWorkoutSessionID = userSessionID.String
+		adjustments = append(adjustments, &adjustment)
+	}
+	return adjustments, rows.Err()
+}
+
+func scanProgramAssignment(row rowScanner) (*models.ProgramAssignment, error) {
+	var assignment models.ProgramAssignment
+	var startDate, days sql.NullString
+
+	err := row.Scan(
+		&assignment.ID,
+		&assignment.UserID,
+		&assignment.ProgramID,
+		&startDate,
+		&assignment.Weeks,
+		&days,
+		&assignment.Status,
+		&assignment.CreatedAt,
+		&assignment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if date := parsePlanDate(startDate); date != nil {
+		assignment.StartDate = *date
+	}
+	assignment.TrainingDays = []int{}
+	if days.Valid && days.String != "" {
+		if err := json.Unmarshal([]byte(days.String), &assignment.TrainingDays); err != nil {
+			return nil, fmt.Errorf("failed to decode training days: %w", err)
+		}
+	}
+	return &assignment, nil
+}
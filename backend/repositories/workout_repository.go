@@ -25,6 +25,7 @@ func (r *WorkoutRepository) CreateUserWorkoutSession(session *models.UserWorkout
 	query := `
 		INSERT INTO user_workout_sessions (user_id, workout_session_id, workout_program_id, scheduled_date, completed_date, duration_minutes, calories_burned, perceived_exertion, mood_before, mood_after, exercises_completed, exercises_skipped, modifications_used, notes, injuries_reported, status, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING id
 	`
 
 	exercisesCompletedJSON, _ := json.Marshal(session.ExercisesCompleted)
@@ -32,7 +33,7 @@ func (r *WorkoutRepository) CreateUserWorkoutSession(session *models.UserWorkout
 	modificationsUsedJSON, _ := json.Marshal(session.ModificationsUsed)
 	injuriesReportedJSON, _ := json.Marshal(session.InjuriesReported)
 
-	_, err := r.db.Exec(query,
+	err := r.db.QueryRow(query,
 		session.UserID,
 		session.WorkoutSessionID,
 		session.WorkoutProgramID,
@@ -51,7 +52,7 @@ func (r *WorkoutRepository) CreateUserWorkoutSession(session *models.UserWorkout
 		session.Status,
 		time.Now(),
 		time.Now(),
-	)
+	).Scan(&session.ID)
 
 	if err != nil {
 		return fmt.Errorf("failed to create user workout session: %w", err)
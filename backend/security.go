@@ -195,7 +195,7 @@ func ValidateJWTToken(token string) (string, error) {
 }
 
 func isValidRole(role string) bool {
-	validRoles := []string{"admin", "user", "nutritionist", "doctor", "guest"}
+	validRoles := []string{"admin", "user", "coach", "nutritionist", "doctor", "guest"}
 	for _, validRole := range validRoles {
 		if role == validRole {
 			return true
@@ -208,8 +208,11 @@ func isValidRole(role string) bool {
 func RoleBasedAccessControlMiddleware(requiredRoles ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Get user role from JWT token context
+			// Get user role from JWT token context; JWTAuth stores it as "role"
 			userRole, ok := c.Get("user_role").(string)
+			if !ok {
+				userRole, ok = c.Get("role").(string)
+			}
 			if !ok {
 				return c.JSON(http.StatusForbidden, map[string]interface{}{
 					"error": "User role not found in token",
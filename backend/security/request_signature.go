@@ -0,0 +1,42 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SignRequest computes the HMAC-SHA256 request signature checked by the
+// request signing middleware and sent with outbound webhooks. The signed
+// string is the method, path, timestamp and body followed by one
+// "name:value" line per header, sorted by name, joined with newlines.
+func SignRequest(secretKey, method, path, timestamp, body string, headers map[string]string) string {
+	canonicalParts := []string{method, path, timestamp, body}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		canonicalParts = append(canonicalParts, fmt.Sprintf("%s:%s", name, headers[name]))
+	}
+
+	h := hmac.New(sha256.New, []byte(secretKey))
+	h.Write([]byte(strings.Join(canonicalParts, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GenerateWebhookSecret returns a random hex-encoded signing secret of
+// length bytes
+func GenerateWebhookSecret(length int) (string, error) {
+	secret := make([]byte, length)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
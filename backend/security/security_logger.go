@@ -6,9 +6,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"nutrition-platform/alerting"
 	"nutrition-platform/errors"
 )
 
@@ -32,10 +34,11 @@ type AlertRule struct {
 	Enabled    bool
 }
 
-// AlertAction defines what to do when an alert is triggered
+// AlertAction defines what to do when an alert is triggered. Every triggered
+// rule is routed through the alerting router, whose routes pick the channels;
+// "block_ip" also blocks the event's IP.
 type AlertAction struct {
-	Type        string // "email", "webhook", "log", "block_ip"
-	Target      string // email address, webhook URL, etc.
+	Type        string // "alert" or "block_ip"
 	Message     string
 	AutoResolve bool
 }
@@ -249,25 +252,30 @@ func (sl *SecurityLogger) getEventCountInWindow(eventType string, window time.Du
 
 // triggerAlert triggers an alert action
 func (sl *SecurityLogger) triggerAlert(rule AlertRule, event *errors.SecurityEvent) {
-	alertMsg := fmt.Sprintf("Security Alert: %s - %s", rule.Name, event.Message)
-
-	switch rule.Action.Type {
-	case "log":
-		log.Printf("SECURITY ALERT: %s", alertMsg)
-	case "email":
-		// In production, integrate with email service
-		log.Printf("EMAIL ALERT to %s: %s", rule.Action.Target, alertMsg)
-	case "webhook":
-		// In production, send webhook
-		log.Printf("WEBHOOK ALERT to %s: %s", rule.Action.Target, alertMsg)
-	case "block_ip":
-		if event.IPAddress != "" {
-			sl.metrics.mutex.Lock()
-			sl.metrics.BlockedIPs[event.IPAddress] = time.Now()
-			sl.metrics.mutex.Unlock()
-			log.Printf("BLOCKED IP: %s due to alert: %s", event.IPAddress, rule.Name)
-		}
+	if rule.Action.Type == "block_ip" && event.IPAddress != "" {
+		sl.metrics.mutex.Lock()
+		sl.metrics.BlockedIPs[event.IPAddress] = time.Now()
+		sl.metrics.mutex.Unlock()
+		log.Printf("BLOCKED IP: %s due to alert: %s", event.IPAddress, rule.Name)
+	}
+
+	severity := rule.Severity
+	if severity == "" {
+		severity = event.Severity
 	}
+	summary := rule.Name
+	if rule.Action.Message != "" {
+		summary = rule.Action.Message
+	}
+	alerting.Fire(alerting.Alert{
+		Source:   "security",
+		Name:     alertName(rule.Name),
+		Severity: alerting.ParseSeverity(severity),
+		Summary:  summary,
+		Message:  fmt.Sprintf("Security Alert: %s - %s", rule.Name, event.Message),
+		Labels:   securityAlertLabels(event),
+		FiredAt:  event.Timestamp,
+	})
 }
 
 // handleHighSeverityEvent handles critical and high severity events
@@ -276,11 +284,15 @@ func (sl *SecurityLogger) handleHighSeverityEvent(event *errors.SecurityEvent) {
 	log.Printf("HIGH SEVERITY SECURITY EVENT: %s - %s (IP: %s)",
 		event.Type, event.Message, event.IPAddress)
 
-	// In production, you might want to:
-	// - Send immediate notifications
-	// - Trigger incident response
-	// - Auto-block suspicious IPs
-	// - Escalate to security team
+	alerting.Fire(alerting.Alert{
+		Source:   "security",
+		Name:     "high_severity_event",
+		Severity: alerting.ParseSeverity(event.Severity),
+		Summary:  "High severity security event: " + event.Type,
+		Message:  event.Message,
+		Labels:   securityAlertLabels(event),
+		FiredAt:  event.Timestamp,
+	})
 }
 
 // GetMetrics returns current security metrics
@@ -384,6 +396,21 @@ func convertDetailsToInterface(details map[string]string) map[string]interface{}
 	return result
 }
 
+// alertName turns a rule name into an alert name, e.g. "Rate Limit Abuse"
+// into "rate_limit_abuse"
+func alertName(ruleName string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(ruleName)), " ", "_")
+}
+
+// securityAlertLabels identifies a security incident by event type and IP
+func securityAlertLabels(event *errors.SecurityEvent) map[string]string {
+	labels := map[string]string{"event_type": event.Type}
+	if event.IPAddress != "" {
+		labels["ip_address"] = event.IPAddress
+	}
+	return labels
+}
+
 func getEnvironment() string {
 	env := os.Getenv("ENVIRONMENT")
 	if env == "" {
@@ -401,7 +428,7 @@ func getDefaultAlertRules() []AlertRule {
 			Threshold:  5,
 			TimeWindow: 5 * time.Minute,
 			Action: AlertAction{
-				Type:    "log",
+				Type:    "alert",
 				Message: "Multiple authentication failures detected",
 			},
 			Enabled: true,
@@ -425,7 +452,7 @@ func getDefaultAlertRules() []AlertRule {
 			Threshold:  10,
 			TimeWindow: time.Minute,
 			Action: AlertAction{
-				Type:    "log",
+				Type:    "alert",
 				Message: "Potential rate limit abuse detected",
 			},
 			Enabled: true,
@@ -0,0 +1,633 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+const (
+	// DefaultConsistencyWindowDays is how far back consistency is scored
+	// unless asked otherwise
+	DefaultConsistencyWindowDays = 30
+	maxConsistencyWindowDays     = 365
+
+	// Workouts a week that score full workout consistency
+	targetWorkoutsPerWeek = 3
+	// A weigh-in in every bucket of this many days scores full measurement
+	// consistency, and likewise a photo for photo consistency
+	measurementBucketDays = 7
+	photoBucketDays       = 14
+
+	// How many achieved milestones the analytics list as recent
+	recentAchievementsLimit = 5
+
+	activityDayLayout = "2006-01-02"
+)
+
+// ErrInvalidAchievementRequest is returned for unknown activities and
+// malformed milestones
+var ErrInvalidAchievementRequest = errors.New("invalid achievement request")
+
+// AchievementService keeps streak counters, awards badges and tracks
+// milestones from the activities users log. Each activity is counted on
+// the user's local day at the time it is logged, so a streak follows the
+// user's calendar rather than UTC. Badges come from the badge catalog: a
+// streak badge is earned at a best streak of its threshold, a total badge
+// at that many activities logged. Weight milestones are reached when a
+// weigh-in crosses the target and workout milestones at a number of
+// completed workouts; both are marked achieved as the activity is recorded.
+type AchievementService struct {
+	repo        *repositories.AchievementRepository
+	weights     *repositories.WeightRepository
+	preferences *UserPreferencesService
+}
+
+// NewAchievementService creates a new AchievementService instance
+func NewAchievementService(db *sql.DB) *AchievementService {
+	wrapped := database.NewDatabase(db)
+	return &AchievementService{
+		repo:        repositories.NewAchievementRepository(wrapped),
+		weights:     repositories.NewWeightRepository(wrapped),
+		preferences: NewUserPreferencesService(db),
+	}
+}
+
+// Record counts an activity logged at at. value is the weigh-in in
+// kilograms for ActivityWeighIn and ignored otherwise.
+func (s *AchievementService) Record(ctx context.Context, userID, activity string, at time.Time, value *float64) (*models.ActivityResult, error) {
+	if !isActivity(activity) {
+		return nil, fmt.Errorf("%w: unknown activity %q", ErrInvalidAchievementRequest, activity)
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	result := &models.ActivityResult{
+		Day:                at.In(s.location(ctx, userID)).Format(activityDayLayout),
+		Streaks:            []models.Streak{},
+		NewBadges:          []models.EarnedBadge{},
+		AchievedMilestones: []models.Milestone{},
+	}
+
+	streaks := map[string]models.Streak{}
+	for _, a := range []string{activity, models.ActivityAny} {
+		if err := s.repo.IncrementActivityDay(ctx, userID, a, result.Day); err != nil {
+			return nil, err
+		}
+		days, err := s.repo.ListActivityDays(ctx, userID, a, "")
+		if err != nil {
+			return nil, err
+		}
+		streak := streakFromDays(a, days)
+		if err := s.repo.SaveStreak(ctx, userID, streak); err != nil {
+			return nil, err
+		}
+		streaks[a] = streak
+		result.Streaks = append(result.Streaks, streak)
+	}
+
+	badges, err := s.repo.ListBadges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, badge := range badges {
+		streak, ok := streaks[badge.Activity]
+		if !ok {
+			continue
+		}
+		current := streak.Best
+		if badge.Kind == models.BadgeKindTotal {
+			if current, err = s.repo.CountActivity(ctx, userID, badge.Activity); err != nil {
+				return nil, err
+			}
+		}
+		if current < badge.Threshold {
+			continue
+		}
+		awarded, err := s.repo.AwardBadge(ctx, userID, badge.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if awarded {
+			earned := models.EarnedBadge{Badge: badge, AwardedAt: now}
+			result.NewBadges = append(result.NewBadges, earned)
+			PublishWebhookEvent(ctx, userID, models.WebhookEventMilestoneAchieved, map[string]interface{}{"badge": earned})
+		}
+	}
+
+	if activity == models.ActivityWeighIn || activity == models.ActivityWorkout {
+		achieved, err := s.updateMilestones(ctx, userID, activity, value)
+		if err != nil {
+			return nil, err
+		}
+		result.AchievedMilestones = achieved
+	}
+
+	return result, nil
+}
+
+// updateMilestones moves the user's open weight or workout milestones on
+// after a weigh-in or workout, returning those now achieved
+func (s *AchievementService) updateMilestones(ctx context.Context, userID, activity string, value *float64) ([]models.Milestone, error) {
+	milestones, err := s.repo.ListMilestones(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	achieved := []models.Milestone{}
+	for _, milestone := range milestones {
+		if milestone.IsAchieved || milestone.TargetValue == nil {
+			continue
+		}
+		switch {
+		case activity == models.ActivityWeighIn && milestone.Type == models.MilestoneTypeWeight && value != nil:
+			s.applyWeight(milestone, *value)
+		case activity == models.ActivityWorkout && milestone.Type == models.MilestoneTypeWorkout:
+			if err := s.applyWorkoutCount(ctx, userID, milestone); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		if err := s.repo.UpdateMilestoneProgress(ctx, milestone); err != nil {
+			return nil, err
+		}
+		if milestone.IsAchieved {
+			achieved = append(achieved, *milestone)
+			PublishWebhookEvent(ctx, userID, models.WebhookEventMilestoneAchieved, map[string]interface{}{"milestone": milestone})
+		}
+	}
+	return achieved, nil
+}
+
+// applyWeight records a weigh-in against a weight milestone. The target is
+// reached going down when the milestone started above it and going up
+// otherwise.
+func (s *AchievementService) applyWeight(milestone *models.Milestone, weight float64) {
+	if milestone.StartValue == nil {
+		start := weight
+		milestone.StartValue = &start
+	}
+	milestone.CurrentValue = &weight
+
+	target := *milestone.TargetValue
+	if *milestone.StartValue >= target && weight <= target || *milestone.StartValue < target && weight >= target {
+		markAchieved(milestone)
+	}
+}
+
+// applyWorkoutCount sets a workout milestone to the number of workouts the
+// user has completed
+func (s *AchievementService) applyWorkoutCount(ctx context.Context, userID string, milestone *models.Milestone) error {
+	count, err := s.repo.CountActivity(ctx, userID, models.ActivityWorkout)
+	if err != nil {
+		return err
+	}
+	current := float64(count)
+	milestone.CurrentValue = &current
+	if current >= *milestone.TargetValue {
+		markAchieved(milestone)
+	}
+	return nil
+}
+
+// GetAchievements lists a user's badges, milestones and streaks
+func (s *AchievementService) GetAchievements(ctx context.Context, userID string) (*models.AchievementsOverview, error) {
+	earned, err := s.repo.ListEarnedBadges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	milestones, err := s.repo.ListMilestones(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	streaks, err := s.streaks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	badges, err := s.repo.ListBadges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &models.AchievementsOverview{
+		Badges:     earned,
+		Milestones: make([]models.Milestone, 0, len(milestones)),
+		Streaks:    streaks,
+		Analytics: models.AchievementAnalytics{
+			TotalAchievements:  len(earned),
+			RecentAchievements: []models.Milestone{},
+			AchievementsByType: map[string]int{},
+			NextMilestones:     []models.MilestoneProgress{},
+		},
+	}
+	if len(earned) > 0 {
+		overview.Analytics.AchievementsByType["badge"] = len(earned)
+	}
+	if len(badges) > 0 {
+		overview.Analytics.AchievementRate = roundTo(float64(len(earned))/float64(len(badges))*100, 1)
+	}
+
+	achieved := []models.Milestone{}
+	for _, milestone := range milestones {
+		overview.Milestones = append(overview.Milestones, *milestone)
+		if milestone.IsAchieved {
+			achieved = append(achieved, *milestone)
+			overview.Analytics.AchievementsByType[milestone.Type]++
+		} else if progress, ok := milestoneProgress(milestone); ok {
+			overview.Analytics.NextMilestones = append(overview.Analytics.NextMilestones, progress)
+		}
+	}
+	overview.Analytics.TotalAchievements += len(achieved)
+	if len(milestones) > 0 {
+		overview.Analytics.CompletionRate = roundTo(float64(len(achieved))/float64(len(milestones))*100, 1)
+	}
+
+	sort.SliceStable(achieved, func(i, j int) bool {
+		return achieved[i].AchievedAt.After(achieved[j].AchievedAt)
+	})
+	if len(achieved) > recentAchievementsLimit {
+		achieved = achieved[:recentAchievementsLimit]
+	}
+	overview.Analytics.RecentAchievements = achieved
+	sortMilestoneProgress(overview.Analytics.NextMilestones)
+
+	return overview, nil
+}
+
+// GetUpcoming lists the badges and milestones the user has yet to earn,
+// closest first. Streak badges count from the current streak, since a
+// lapsed streak has to be rebuilt.
+func (s *AchievementService) GetUpcoming(ctx context.Context, userID string) (*models.UpcomingAchievements, error) {
+	badges, err := s.repo.ListBadges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	earned, err := s.repo.ListEarnedBadges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	streaks, err := s.streaks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	milestones, err := s.repo.ListMilestones(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	have := map[string]bool{}
+	for _, badge := range earned {
+		have[badge.Badge.ID] = true
+	}
+	currentStreaks := map[string]int{}
+	for _, streak := range streaks {
+		currentStreaks[streak.Activity] = streak.Current
+	}
+
+	upcoming := &models.UpcomingAchievements{
+		Badges:     []models.BadgeProgress{},
+		Milestones: []models.MilestoneProgress{},
+	}
+	for _, badge := range badges {
+		if have[badge.ID] || badge.Threshold <= 0 {
+			continue
+		}
+		current := currentStreaks[badge.Activity]
+		if badge.Kind == models.BadgeKindTotal {
+			if current, err = s.repo.CountActivity(ctx, userID, badge.Activity); err != nil {
+				return nil, err
+			}
+		}
+		upcoming.Badges = append(upcoming.Badges, models.BadgeProgress{
+			Badge:           badge,
+			Current:         current,
+			ProgressPercent: roundTo(math.Min(float64(current)/float64(badge.Threshold), 1)*100, 1),
+		})
+	}
+	sort.SliceStable(upcoming.Badges, func(i, j int) bool {
+		return upcoming.Badges[i].ProgressPercent > upcoming.Badges[j].ProgressPercent
+	})
+
+	for _, milestone := range milestones {
+		if milestone.IsAchieved {
+			continue
+		}
+		if progress, ok := milestoneProgress(milestone); ok {
+			upcoming.Milestones = append(upcoming.Milestones, progress)
+		}
+	}
+	sortMilestoneProgress(upcoming.Milestones)
+
+	return upcoming, nil
+}
+
+// GetConsistency scores how regularly the user logged over the last days
+// days, 30 when zero. Nutrition is the share of days with a meal logged,
+// workouts the share of three a week, and measurements and photos the
+// share of weeks and fortnights with a weigh-in and a photo. Overall is
+// their mean.
+func (s *AchievementService) GetConsistency(ctx context.Context, userID string, days int) (*models.ConsistencyAnalytics, error) {
+	if days == 0 {
+		days = DefaultConsistencyWindowDays
+	}
+	if days < 1 || days > maxConsistencyWindowDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidAchievementRequest, maxConsistencyWindowDays)
+	}
+
+	loc := s.location(ctx, userID)
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -(days - 1))
+
+	activityDays := map[string][]time.Time{}
+	for _, activity := range []string{models.ActivityMeal, models.ActivityWorkout, models.ActivityWeighIn, models.ActivityProgressPhoto} {
+		listed, err := s.repo.ListActivityDays(ctx, userID, activity, from.Format(activityDayLayout))
+		if err != nil {
+			return nil, err
+		}
+		for _, day := range listed {
+			if t, err := time.Parse(activityDayLayout, day); err == nil && !t.After(today) {
+				activityDays[activity] = append(activityDays[activity], t)
+			}
+		}
+	}
+
+	workoutTarget := float64(days) * targetWorkoutsPerWeek / 7
+	consistency := &models.ConsistencyAnalytics{
+		NutritionConsistency:   roundTo(float64(len(activityDays[models.ActivityMeal]))/float64(days)*100, 1),
+		WorkoutConsistency:     roundTo(math.Min(float64(len(activityDays[models.ActivityWorkout]))/workoutTarget, 1)*100, 1),
+		MeasurementConsistency: roundTo(bucketCoverage(activityDays[models.ActivityWeighIn], from, days, measurementBucketDays)*100, 1),
+		PhotoConsistency:       roundTo(bucketCoverage(activityDays[models.ActivityProgressPhoto], from, days, photoBucketDays)*100, 1),
+	}
+	consistency.OverallConsistency = roundTo((consistency.NutritionConsistency+consistency.WorkoutConsistency+
+		consistency.MeasurementConsistency+consistency.PhotoConsistency)/4, 1)
+
+	streaks, err := s.streaks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, streak := range streaks {
+		if streak.Activity == models.ActivityAny {
+			consistency.StreakDays = streak.Current
+			consistency.BestStreak = streak.Best
+		}
+	}
+
+	return consistency, nil
+}
+
+// CreateMilestone sets a new milestone. Weight and workout milestones need
+// a positive target; a weight milestone starts from the latest weigh-in
+// unless given a start, and a workout milestone counts the workouts
+// already completed.
+func (s *AchievementService) CreateMilestone(ctx context.Context, userID string, req *models.CreateMilestoneRequest) (*models.Milestone, error) {
+	if req.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidAchievementRequest)
+	}
+	switch req.Type {
+	case models.MilestoneTypeWeight, models.MilestoneTypeWorkout:
+		if req.TargetValue == nil || *req.TargetValue <= 0 {
+			return nil, fmt.Errorf("%w: target_value must be positive", ErrInvalidAchievementRequest)
+		}
+	case models.MilestoneTypeCustom:
+	default:
+		return nil, fmt.Errorf("%w: type must be weight, workout or custom", ErrInvalidAchievementRequest)
+	}
+
+	now := time.Now().UTC()
+	milestone := &models.Milestone{
+		Type:        req.Type,
+		Title:       req.Title,
+		Description: req.Description,
+		TargetValue: req.TargetValue,
+		StartValue:  req.StartValue,
+		PhotoURL:    req.PhotoURL,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if id, err := strconv.ParseUint(userID, 10, 64); err == nil {
+		milestone.UserID = uint(id)
+	}
+
+	switch req.Type {
+	case models.MilestoneTypeWeight:
+		if milestone.StartValue == nil {
+			if id, err := strconv.Atoi(userID); err == nil {
+				if logs, _, err := s.weights.GetWeightLogs(id, 1, 1); err == nil && len(logs) > 0 {
+					start := roundTo(WeightInKilograms(logs[0]), 1)
+					milestone.StartValue = &start
+				}
+			}
+		}
+		if milestone.StartValue != nil {
+			current := *milestone.StartValue
+			milestone.CurrentValue = &current
+		}
+	case models.MilestoneTypeWorkout:
+		if err := s.applyWorkoutCount(ctx, userID, milestone); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.CreateMilestone(ctx, userID, milestone); err != nil {
+		return nil, err
+	}
+	if milestone.IsAchieved {
+		PublishWebhookEvent(ctx, userID, models.WebhookEventMilestoneAchieved, map[string]interface{}{"milestone": milestone})
+	}
+	return milestone, nil
+}
+
+// ListMilestones returns a user's milestones, oldest first
+func (s *AchievementService) ListMilestones(ctx context.Context, userID string) ([]*models.Milestone, error) {
+	return s.repo.ListMilestones(ctx, userID)
+}
+
+// DeleteMilestone removes one of a user's milestones
+func (s *AchievementService) DeleteMilestone(ctx context.Context, userID string, id uint) error {
+	return s.repo.DeleteMilestone(ctx, id, userID)
+}
+
+// AchieveMilestone marks a custom milestone achieved. Weight and workout
+// milestones are achieved by logging.
+func (s *AchievementService) AchieveMilestone(ctx context.Context, userID string, id uint) (*models.Milestone, error) {
+	milestone, err := s.repo.GetMilestone(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if milestone.Type != models.MilestoneTypeCustom {
+		return nil, fmt.Errorf("%w: only custom milestones can be marked achieved", ErrInvalidAchievementRequest)
+	}
+	if milestone.IsAchieved {
+		return milestone, nil
+	}
+
+	markAchieved(milestone)
+	if err := s.repo.UpdateMilestoneProgress(ctx, milestone); err != nil {
+		return nil, err
+	}
+	PublishWebhookEvent(ctx, userID, models.WebhookEventMilestoneAchieved, map[string]interface{}{"milestone": milestone})
+	return milestone, nil
+}
+
+// streaks returns the user's saved streaks with lapsed ones zeroed: a
+// streak is current only while its last day is today or yesterday in the
+// user's time zone
+func (s *AchievementService) streaks(ctx context.Context, userID string) ([]models.Streak, error) {
+	streaks, err := s.repo.ListStreaks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(s.location(ctx, userID))
+	yesterday := now.AddDate(0, 0, -1).Format(activityDayLayout)
+	for i := range streaks {
+		if streaks[i].LastDay < yesterday {
+			streaks[i].Current = 0
+		}
+	}
+	return streaks, nil
+}
+
+// location returns the user's time zone, UTC for users without one
+func (s *AchievementService) location(ctx context.Context, userID string) *time.Location {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return time.UTC
+	}
+	return s.preferences.GetTimezone(ctx, id)
+}
+
+// streakFromDays works out the current and best runs of consecutive days
+// from days logged, oldest first. The current run is the one ending at the
+// last day logged.
+func streakFromDays(activity string, days []string) models.Streak {
+	streak := models.Streak{Activity: activity}
+	var previous time.Time
+	for _, day := range days {
+		t, err := time.Parse(activityDayLayout, day)
+		if err != nil {
+			continue
+		}
+		if !previous.IsZero() && daysBetween(previous, t) == 1 {
+			streak.Current++
+		} else {
+			streak.Current = 1
+		}
+		if streak.Current > streak.Best {
+			streak.Best = streak.Current
+		}
+		previous = t
+		streak.LastDay = day
+	}
+	return streak
+}
+
+// bucketCoverage returns the share of consecutive buckets of size days,
+// counting back from the end of the window, with at least one day logged.
+// A partial bucket at the start of the window counts as a bucket.
+func bucketCoverage(logged []time.Time, from time.Time, days, size int) float64 {
+	buckets := (days + size - 1) / size
+	covered := map[int]bool{}
+	for _, t := range logged {
+		offset := days - 1 - daysBetween(from, t)
+		if offset >= 0 && offset < days {
+			covered[offset/size] = true
+		}
+	}
+	return float64(len(covered)) / float64(buckets)
+}
+
+// milestoneProgress reports how far a milestone with a target has come.
+// Weight milestones measure the distance covered from the start.
+func milestoneProgress(milestone *models.Milestone) (models.MilestoneProgress, bool) {
+	if milestone.TargetValue == nil {
+		return models.MilestoneProgress{}, false
+	}
+	progress := models.MilestoneProgress{
+		MilestoneID:    milestone.ID,
+		MilestoneTitle: milestone.Title,
+		TargetValue:    *milestone.TargetValue,
+		IsCompleted:    milestone.IsAchieved,
+	}
+	if milestone.CurrentValue != nil {
+		progress.CurrentValue = *milestone.CurrentValue
+	}
+
+	var share float64
+	switch {
+	case milestone.IsAchieved:
+		share = 1
+	case milestone.Type == models.MilestoneTypeWeight && milestone.StartValue != nil && milestone.CurrentValue != nil:
+		if distance := *milestone.TargetValue - *milestone.StartValue; distance != 0 {
+			share = (*milestone.CurrentValue - *milestone.StartValue) / distance
+		}
+	case *milestone.TargetValue > 0:
+		share = progress.CurrentValue / *milestone.TargetValue
+	}
+	progress.ProgressPercent = roundTo(math.Max(0, math.Min(share, 1))*100, 1)
+	return progress, true
+}
+
+func sortMilestoneProgress(progress []models.MilestoneProgress) {
+	sort.SliceStable(progress, func(i, j int) bool {
+		return progress[i].ProgressPercent > progress[j].ProgressPercent
+	})
+}
+
+func markAchieved(milestone *models.Milestone) {
+	milestone.IsAchieved = true
+	milestone.AchievedAt = time.Now().UTC()
+}
+
+func isActivity(activity string) bool {
+	for _, a := range models.Activities {
+		if a == activity {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultAchievementsMu sync.RWMutex
+	defaultAchievements   *AchievementService
+)
+
+// SetDefaultAchievementService sets the service RecordActivity records
+// activities with
+func SetDefaultAchievementService(service *AchievementService) {
+	defaultAchievementsMu.Lock()
+	defer defaultAchievementsMu.Unlock()
+	defaultAchievements = service
+}
+
+// RecordActivity records an activity with the default achievement service.
+// Failures are logged rather than returned so that logging never fails
+// because of streaks or badges. It does nothing until
+// SetDefaultAchievementService is called.
+func RecordActivity(ctx context.Context, userID, activity string, at time.Time, value *float64) {
+	defaultAchievementsMu.RLock()
+	service := defaultAchievements
+	defaultAchievementsMu.RUnlock()
+	if service == nil {
+		return
+	}
+
+	if _, err := service.Record(ctx, userID, activity, at, value); err != nil {
+		log.Printf("Failed to record %s activity: %v", activity, err)
+	}
+}
@@ -1,21 +1,72 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"nutrition-platform/alerting"
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxUsageReportDays bounds the days a usage dashboard covers
+	maxUsageReportDays = 90
+	// minAlertSamples is how many requests a window needs before rate and
+	// latency alerts are evaluated
+	minAlertSamples = 10
+	// alertEvaluationInterval throttles alert evaluation per key
+	alertEvaluationInterval = time.Minute
+	// defaultAlertMinutes is the window and cooldown of an alert created without them
+	defaultAlertMinutes = 60
+	// usageBatchSize is how many logged requests trigger an early batch insert
+	usageBatchSize = 100
+	// usageFlushInterval bounds how long a logged request waits to be written
+	usageFlushInterval = time.Second
+)
+
+var (
+	// ErrInvalidUsageAlert is wrapped by errors caused by a usage alert request
+	ErrInvalidUsageAlert = errors.New("invalid usage alert")
+	// ErrInvalidUsageReport is wrapped by errors caused by a usage report request
+	ErrInvalidUsageReport = errors.New("invalid usage report")
 )
 
 // AnalyticsService handles API usage analytics and monitoring
 type AnalyticsService struct {
 	db          *sql.DB
+	usage       *repositories.APIKeyRepository
+	alerts      *repositories.UsageAlertRepository
+	notifier    UsageAlertNotifier
 	metrics     map[string]*APIMetrics
 	mutex       sync.RWMutex
 	flushTicker *time.Ticker
 	stopChan    chan bool
+
+	// lastEvaluated throttles alert evaluation per key
+	lastEvaluated map[string]time.Time
+	evalMutex     sync.Mutex
+
+	// pendingUsage buffers the request log, keyed by row ID, until the next
+	// batch insert. flushMutex is held while a batch is written, so a flush
+	// returns only once everything logged before it is stored.
+	pendingUsage map[string]*models.APIKeyUsage
+	usageMutex   sync.Mutex
+	flushMutex   sync.Mutex
+	usageFull    chan struct{}
 }
 
 // APIMetrics holds real-time metrics for API usage
@@ -34,22 +85,53 @@ type APIMetrics struct {
 }
 
 // UsageAlert represents an alert condition
-type UsageAlert struct {
-	ID            string                 `json:"id"`
-	Name          string                 `json:"name"`
-	Condition     string                 `json:"condition"`
-	Threshold     float64                `json:"threshold"`
-	Enabled       bool                   `json:"enabled"`
-	LastTriggered *time.Time             `json:"last_triggered"`
-	Metadata      map[string]interface{} `json:"metadata"`
+type UsageAlert = models.UsageAlert
+
+// UsageAlertEvent describes a usage alert that fired for a key
+type UsageAlertEvent struct {
+	Alert    *UsageAlert `json:"alert"`
+	APIKeyID string      `json:"api_key_id"`
+	Value    float64     `json:"value"`
+	Message  string      `json:"message"`
+	FiredAt  time.Time   `json:"fired_at"`
+}
+
+// UsageAlertNotifier delivers fired usage alerts
+type UsageAlertNotifier interface {
+	NotifyUsageAlert(ctx context.Context, event *UsageAlertEvent) error
+}
+
+// routedUsageAlertNotifier sends usage alerts through the alerting router
+type routedUsageAlertNotifier struct{}
+
+func (routedUsageAlertNotifier) NotifyUsageAlert(ctx context.Context, event *UsageAlertEvent) error {
+	alerting.Fire(alerting.Alert{
+		Source:    "api_key_usage",
+		Name:      event.Alert.Condition,
+		Severity:  alerting.SeverityWarning,
+		Summary:   event.Alert.Name,
+		Message:   event.Message,
+		Labels:    map[string]string{"api_key_id": event.APIKeyID, "alert_id": event.Alert.ID},
+		Value:     event.Value,
+		Threshold: event.Alert.Threshold,
+		FiredAt:   event.FiredAt,
+	})
+	return nil
 }
 
 // NewAnalyticsService creates a new analytics service
 func NewAnalyticsService(db *sql.DB) *AnalyticsService {
+	wrapped := database.NewDatabase(db)
 	service := &AnalyticsService{
-		db:       db,
-		metrics:  make(map[string]*APIMetrics),
-		stopChan: make(chan bool),
+		db:            db,
+		usage:         repositories.NewAPIKeyRepository(wrapped),
+		alerts:        repositories.NewUsageAlertRepository(wrapped),
+		notifier:      routedUsageAlertNotifier{},
+		metrics:       make(map[string]*APIMetrics),
+		stopChan:      make(chan bool),
+		lastEvaluated: make(map[string]time.Time),
+		pendingUsage:  make(map[string]*models.APIKeyUsage),
+		usageFull:     make(chan struct{}, 1),
 	}
 
 	// Start background metrics flushing
@@ -58,8 +140,69 @@ func NewAnalyticsService(db *sql.DB) *AnalyticsService {
 	return service
 }
 
-// RecordAPIUsage records API usage metrics
+// SetAlertNotifier replaces the alerting router as the destination of fired
+// usage alerts
+func (s *AnalyticsService) SetAlertNotifier(notifier UsageAlertNotifier) {
+	s.notifier = notifier
+}
+
+// RecordAPIUsage records a request made with an API key: it updates the
+// in-memory metrics, buffers the request for the next batch insert into the
+// usage log and evaluates the key's usage alerts in the background
 func (s *AnalyticsService) RecordAPIUsage(apiKeyID, endpoint, method string, statusCode int, responseTime int64, ipAddress, userAgent string) {
+	s.recordMetrics(apiKeyID, endpoint, method, statusCode, responseTime, ipAddress, userAgent)
+
+	usage := &models.APIKeyUsage{
+		APIKeyID:     apiKeyID,
+		Endpoint:     endpoint,
+		Method:       method,
+		StatusCode:   statusCode,
+		ResponseTime: responseTime,
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+		Timestamp:    time.Now(),
+	}
+
+	s.usageMutex.Lock()
+	s.pendingUsage[uuid.New().String()] = usage
+	full := len(s.pendingUsage) >= usageBatchSize
+	s.usageMutex.Unlock()
+	if full {
+		select {
+		case s.usageFull <- struct{}{}:
+		default:
+		}
+	}
+
+	go s.checkAlerts(apiKeyID)
+}
+
+// FlushUsage writes the buffered request log to the database. Reports call
+// it first so they include requests this instance has not written yet.
+func (s *AnalyticsService) FlushUsage(ctx context.Context) error {
+	s.flushMutex.Lock()
+	defer s.flushMutex.Unlock()
+
+	s.usageMutex.Lock()
+	pending := s.pendingUsage
+	s.pendingUsage = make(map[string]*models.APIKeyUsage)
+	s.usageMutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	return s.usage.CreateAPIKeyUsage(ctx, pending)
+}
+
+// flushUsage writes the buffered request log in the background
+func (s *AnalyticsService) flushUsage() {
+	if err := s.FlushUsage(context.Background()); err != nil {
+		log.Printf("Failed to log API key usage: %v", err)
+	}
+}
+
+// recordMetrics updates the in-memory metrics of a key
+func (s *AnalyticsService) recordMetrics(apiKeyID, endpoint, method string, statusCode int, responseTime int64, ipAddress, userAgent string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -124,9 +267,6 @@ func (s *AnalyticsService) RecordAPIUsage(apiKeyID, endpoint, method string, sta
 	}
 
 	metrics.LastUpdated = time.Now()
-
-	// Check for alerts
-	go s.checkAlerts(apiKeyID, metrics)
 }
 
 // GetAPIMetrics retrieves metrics for a specific API key
@@ -293,63 +433,405 @@ func (s *AnalyticsService) GetUsageReport(apiKeyID string, days int) (*UsageRepo
 	return report, nil
 }
 
-// CreateAlert creates a new usage alert
-func (s *AnalyticsService) CreateAlert(alert *UsageAlert) error {
-	query := `
-		INSERT INTO usage_alerts (id, name, condition, threshold, enabled, metadata, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
+// CreateAlert adds an alert on a key's usage
+func (s *AnalyticsService) CreateAlert(ctx context.Context, apiKeyID string, req *models.CreateUsageAlertRequest) (*UsageAlert, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidUsageAlert)
+	}
+	switch req.Condition {
+	case models.UsageAlertErrorRate, models.UsageAlertQuotaUsage:
+		if req.Threshold <= 0 || req.Threshold > 100 {
+			return nil, fmt.Errorf("%w: %s threshold must be a percentage above 0", ErrInvalidUsageAlert, req.Condition)
+		}
+	case models.UsageAlertP95Latency, models.UsageAlertRequestCount:
+		if req.Threshold <= 0 {
+			return nil, fmt.Errorf("%w: threshold must be greater than 0", ErrInvalidUsageAlert)
+		}
+	default:
+		return nil, fmt.Errorf("%w: condition must be one of %s, %s, %s or %s", ErrInvalidUsageAlert,
+			models.UsageAlertErrorRate, models.UsageAlertP95Latency, models.UsageAlertRequestCount, models.UsageAlertQuotaUsage)
+	}
+
+	window := req.WindowMinutes
+	if window == 0 {
+		window = defaultAlertMinutes
+	}
+	cooldown := req.CooldownMinutes
+	if cooldown == 0 {
+		cooldown = defaultAlertMinutes
+	}
+	if window < 1 || window > 24*60 {
+		return nil, fmt.Errorf("%w: window_minutes must be between 1 and 1440", ErrInvalidUsageAlert)
+	}
+	if cooldown < 1 || cooldown > 7*24*60 {
+		return nil, fmt.Errorf("%w: cooldown_minutes must be between 1 and 10080", ErrInvalidUsageAlert)
+	}
+
+	alert := &UsageAlert{
+		ID:              uuid.New().String(),
+		APIKeyID:        apiKeyID,
+		Name:            name,
+		Condition:       req.Condition,
+		Threshold:       req.Threshold,
+		WindowMinutes:   window,
+		CooldownMinutes: cooldown,
+		Enabled:         true,
+		Metadata:        req.Metadata,
+		CreatedAt:       time.Now(),
+	}
+	if alert.Metadata == nil {
+		alert.Metadata = map[string]interface{}{}
+	}
+	if err := s.alerts.CreateUsageAlert(ctx, alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+// GetAlerts returns the alerts set on a key
+func (s *AnalyticsService) GetAlerts(ctx context.Context, apiKeyID string) ([]*UsageAlert, error) {
+	alerts, err := s.alerts.GetUsageAlertsByAPIKeyID(ctx, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if alerts == nil {
+		alerts = []*UsageAlert{}
+	}
+	return alerts, nil
+}
+
+// DeleteAlert removes an alert set on a key
+func (s *AnalyticsService) DeleteAlert(ctx context.Context, apiKeyID, alertID string) error {
+	return s.alerts.DeleteUsageAlert(ctx, alertID, apiKeyID)
+}
+
+// checkAlerts evaluates a key's alerts at most once per alertEvaluationInterval
+func (s *AnalyticsService) checkAlerts(apiKeyID string) {
+	now := time.Now()
+	s.evalMutex.Lock()
+	if last, ok := s.lastEvaluated[apiKeyID]; ok && now.Sub(last) < alertEvaluationInterval {
+		s.evalMutex.Unlock()
+		return
+	}
+	s.lastEvaluated[apiKeyID] = now
+	s.evalMutex.Unlock()
 
-	metadataJSON, err := json.Marshal(alert.Metadata)
+	if _, err := s.EvaluateAlerts(context.Background(), apiKeyID); err != nil {
+		log.Printf("Failed to evaluate usage alerts for API key %s: %v", apiKeyID, err)
+	}
+}
+
+// EvaluateAlerts checks the enabled alerts that apply to a key against its
+// recent usage and sends those over their threshold to the notifier. An
+// alert fires at most once per cooldown; an alert shared by every key fires
+// once per cooldown for whichever key trips it first.
+func (s *AnalyticsService) EvaluateAlerts(ctx context.Context, apiKeyID string) ([]*UsageAlertEvent, error) {
+	alerts, err := s.alerts.GetEnabledUsageAlerts(ctx, apiKeyID)
+	if err != nil || len(alerts) == 0 {
+		return nil, err
+	}
+	if err := s.FlushUsage(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	window := 0
+	for _, alert := range alerts {
+		if alert.WindowMinutes > window {
+			window = alert.WindowMinutes
+		}
+	}
+	usage, err := s.usage.GetAPIKeyUsageSince(ctx, apiKeyID, now.Add(-time.Duration(window)*time.Minute))
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+		return nil, err
+	}
+
+	var fired []*UsageAlertEvent
+	for _, alert := range alerts {
+		value, ok, err := s.measureAlert(ctx, alert, apiKeyID, usage, now)
+		if err != nil {
+			return fired, err
+		}
+		if !ok || value <= alert.Threshold {
+			continue
+		}
+
+		marked, err := s.alerts.MarkUsageAlertTriggered(ctx, alert.ID, now, now.Add(-time.Duration(alert.CooldownMinutes)*time.Minute))
+		if err != nil {
+			return fired, err
+		}
+		if !marked {
+			continue
+		}
+		alert.LastTriggered = &now
+
+		event := &UsageAlertEvent{
+			Alert:    alert,
+			APIKeyID: apiKeyID,
+			Value:    value,
+			Message:  fmt.Sprintf("%s for API key %s: %s is %.2f, above %.2f", alert.Name, apiKeyID, alert.Condition, value, alert.Threshold),
+			FiredAt:  now,
+		}
+		if err := s.notifier.NotifyUsageAlert(ctx, event); err != nil {
+			log.Printf("Failed to send usage alert %s: %v", alert.ID, err)
+		}
+		fired = append(fired, event)
+	}
+
+	return fired, nil
+}
+
+// measureAlert computes an alert's condition over its window. It reports
+// false when there is too little traffic to judge a rate or latency.
+func (s *AnalyticsService) measureAlert(ctx context.Context, alert *UsageAlert, apiKeyID string, usage []*models.APIKeyUsage, now time.Time) (float64, bool, error) {
+	if alert.Condition == models.UsageAlertQuotaUsage {
+		quota, err := s.usage.GetDailyQuota(ctx, apiKeyID)
+		if err != nil || quota == 0 {
+			return 0, false, err
+		}
+		used, err := s.usage.GetDailyUsage(ctx, apiKeyID, now.UTC().Format("2006-01-02"))
+		if err != nil {
+			return 0, false, err
+		}
+		return float64(used) / float64(quota) * 100, true, nil
 	}
 
-	_, err = s.db.Exec(query, alert.ID, alert.Name, alert.Condition, alert.Threshold, alert.Enabled, metadataJSON, time.Now())
-	return err
+	since := now.Add(-time.Duration(alert.WindowMinutes) * time.Minute)
+	var requests, errorCount int64
+	var latencies []int64
+	for _, entry := range usage {
+		if entry.Timestamp.Before(since) {
+			continue
+		}
+		requests++
+		if entry.StatusCode >= 400 {
+			errorCount++
+		}
+		latencies = append(latencies, entry.ResponseTime)
+	}
+
+	switch alert.Condition {
+	case models.UsageAlertRequestCount:
+		return float64(requests), true, nil
+	case models.UsageAlertErrorRate:
+		if requests < minAlertSamples {
+			return 0, false, nil
+		}
+		return float64(errorCount) / float64(requests) * 100, true, nil
+	case models.UsageAlertP95Latency:
+		if requests < minAlertSamples {
+			return 0, false, nil
+		}
+		return float64(summarizeLatency(latencies).P95), true, nil
+	}
+	return 0, false, nil
 }
 
-// checkAlerts checks if any alerts should be triggered
-func (s *AnalyticsService) checkAlerts(apiKeyID string, metrics *APIMetrics) {
-	// This is a simplified alert checking mechanism
-	// In production, you'd want more sophisticated alert conditions
+// GetAPIKeyUsage builds a key's usage dashboard for the last days UTC days,
+// including today
+func (s *AnalyticsService) GetAPIKeyUsage(ctx context.Context, key *models.APIKey, days int) (*APIKeyUsageDashboard, error) {
+	if days < 1 || days > maxUsageReportDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidUsageReport, maxUsageReportDays)
+	}
+
+	if err := s.FlushUsage(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := today.AddDate(0, 0, -(days - 1))
+
+	groups, err := s.usage.GetAPIKeyUsageGroups(ctx, key.ID, start)
+	if err != nil {
+		return nil, err
+	}
+	latencyCounts, err := s.usage.GetAPIKeyLatencyCounts(ctx, key.ID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &APIKeyUsageDashboard{
+		APIKeyID:    key.ID,
+		StartDate:   start,
+		EndDate:     now,
+		StatusCodes: make(map[string]int64),
+		Endpoints:   []EndpointUsage{},
+		DailyStats:  make([]DailyStats, days),
+		GeneratedAt: now,
+	}
+	dayIndex := make(map[string]int, days)
+	for i := range dashboard.DailyStats {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		dashboard.DailyStats[i].Date = date
+		dayIndex[date] = i
+	}
+
+	endpoints := make(map[string]*EndpointUsage)
+	dailyLatency := make([]int64, days)
+	for _, group := range groups {
+		isError := group.StatusCode >= 400
+		dashboard.TotalRequests += group.Requests
+		if isError {
+			dashboard.ErrorRequests += group.Requests
+		}
+		dashboard.StatusCodes[strconv.Itoa(group.StatusCode)] += group.Requests
+
+		name := group.Method + " " + group.Endpoint
+		endpoint, ok := endpoints[name]
+		if !ok {
+			endpoint = &EndpointUsage{Method: group.Method, Endpoint: group.Endpoint}
+			endpoints[name] = endpoint
+		}
+		endpoint.Requests += group.Requests
+		if isError {
+			endpoint.Errors += group.Requests
+		}
+
+		day, ok := dayIndex[group.Day]
+		if !ok {
+			continue
+		}
+		stats := &dashboard.DailyStats[day]
+		if stats.TotalRequests == 0 || group.MinResponseTime < stats.MinResponseTime {
+			stats.MinResponseTime = group.MinResponseTime
+		}
+		if group.MaxResponseTime > stats.MaxResponseTime {
+			stats.MaxResponseTime = group.MaxResponseTime
+		}
+		stats.TotalRequests += group.Requests
+		if isError {
+			stats.ErrorRequests += group.Requests
+		} else {
+			stats.SuccessRequests += group.Requests
+		}
+		dailyLatency[day] += group.TotalLatency
+	}
+
+	for i := range dashboard.DailyStats {
+		if stats := &dashboard.DailyStats[i]; stats.TotalRequests > 0 {
+			stats.AvgResponseTime = float64(dailyLatency[i]) / float64(stats.TotalRequests)
+		}
+	}
+	dashboard.ErrorRate = percentOf(dashboard.ErrorRequests, dashboard.TotalRequests)
+
+	overall := make(latencyHistogram)
+	byEndpoint := make(map[string]latencyHistogram)
+	for _, count := range latencyCounts {
+		name := count.Method + " " + count.Endpoint
+		if byEndpoint[name] == nil {
+			byEndpoint[name] = make(latencyHistogram)
+		}
+		byEndpoint[name][count.ResponseTime] += count.Requests
+		overall[count.ResponseTime] += count.Requests
+	}
+	dashboard.Latency = overall.summarize()
+
+	for name, endpoint := range endpoints {
+		endpoint.ErrorRate = percentOf(endpoint.Errors, endpoint.Requests)
+		endpoint.Latency = byEndpoint[name].summarize()
+		dashboard.Endpoints = append(dashboard.Endpoints, *endpoint)
+	}
+	sort.Slice(dashboard.Endpoints, func(i, j int) bool {
+		a, b := dashboard.Endpoints[i], dashboard.Endpoints[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Method+" "+a.Endpoint < b.Method+" "+b.Endpoint
+	})
 
-	// Example: Check if error rate is too high
-	if metrics.TotalRequests > 0 {
-		errorRate := float64(metrics.ErrorRequests) / float64(metrics.TotalRequests) * 100
-		if errorRate > 10.0 { // 10% error rate threshold
-			s.triggerAlert("high_error_rate", apiKeyID, errorRate)
+	if key.DailyQuota > 0 {
+		used, err := s.usage.GetDailyUsage(ctx, key.ID, today.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
 		}
+		dashboard.Quota = &APIKeyQuota{Limit: key.DailyQuota, Used: used, ResetAt: today.AddDate(0, 0, 1)}
+		if used < key.DailyQuota {
+			dashboard.Quota.Remaining = key.DailyQuota - used
+		}
+	}
+
+	if dashboard.Alerts, err = s.GetAlerts(ctx, key.ID); err != nil {
+		return nil, err
 	}
 
-	// Example: Check if average latency is too high
-	if metrics.AverageLatency > 5000 { // 5 seconds threshold
-		s.triggerAlert("high_latency", apiKeyID, metrics.AverageLatency)
+	return dashboard, nil
+}
+
+// summarizeLatency computes nearest-rank percentiles of response times
+func summarizeLatency(values []int64) LatencyStats {
+	histogram := make(latencyHistogram)
+	for _, v := range values {
+		histogram[v]++
 	}
+	return histogram.summarize()
 }
 
-// triggerAlert triggers an alert
-func (s *AnalyticsService) triggerAlert(alertType, apiKeyID string, value float64) {
-	log.Printf("ALERT: %s for API key %s - Value: %.2f", alertType, apiKeyID, value)
+// latencyHistogram counts requests by response time in milliseconds
+type latencyHistogram map[int64]int64
+
+// summarize computes nearest-rank percentiles of the counted response times
+func (h latencyHistogram) summarize() LatencyStats {
+	var total, sum int64
+	times := make([]int64, 0, len(h))
+	for responseTime, count := range h {
+		times = append(times, responseTime)
+		total += count
+		sum += responseTime * count
+	}
+	if total == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	percentile := func(p float64) int64 {
+		rank := int64(math.Ceil(p / 100 * float64(total)))
+		var seen int64
+		for _, responseTime := range times {
+			seen += h[responseTime]
+			if seen >= rank {
+				return responseTime
+			}
+		}
+		return times[len(times)-1]
+	}
+
+	return LatencyStats{
+		Average: float64(sum) / float64(total),
+		P50:     percentile(50),
+		P90:     percentile(90),
+		P95:     percentile(95),
+		P99:     percentile(99),
+		Max:     times[len(times)-1],
+	}
+}
 
-	// Here you would implement actual alerting mechanisms:
-	// - Send email notifications
-	// - Send Slack/Discord messages
-	// - Create tickets in issue tracking systems
-	// - Store alert in database for dashboard display
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
 }
 
-// startMetricsFlushing starts background metrics flushing to database
+// startMetricsFlushing starts background metrics flushing to database, and
+// writes the request log in batches
 func (s *AnalyticsService) startMetricsFlushing() {
 	s.flushTicker = time.NewTicker(5 * time.Minute) // Flush every 5 minutes
+	usageTicker := time.NewTicker(usageFlushInterval)
 
 	go func() {
 		for {
 			select {
 			case <-s.flushTicker.C:
 				s.flushMetricsToDatabase()
+			case <-usageTicker.C:
+				s.flushUsage()
+			case <-s.usageFull:
+				s.flushUsage()
 			case <-s.stopChan:
 				s.flushTicker.Stop()
+				usageTicker.Stop()
 				return
 			}
 		}
@@ -421,11 +903,13 @@ func (s *AnalyticsService) trimMap(m map[string]int64, n int) {
 // Stop stops the analytics service
 func (s *AnalyticsService) Stop() {
 	close(s.stopChan)
+	s.flushUsage()
 	s.flushMetricsToDatabase() // Final flush
 }
 
 // Supporting types
 
+// UsageReport summarizes a key's requests per day
 type UsageReport struct {
 	APIKeyID       string       `json:"api_key_id"`
 	StartDate      time.Time    `json:"start_date"`
@@ -435,6 +919,7 @@ type UsageReport struct {
 	GeneratedAt    time.Time    `json:"generated_at"`
 }
 
+// DailyStats summarizes one day of a key's requests
 type DailyStats struct {
 	Date            string  `json:"date"`
 	TotalRequests   int64   `json:"total_requests"`
@@ -444,3 +929,40 @@ type DailyStats struct {
 	MinResponseTime int64   `json:"min_response_time"`
 	MaxResponseTime int64   `json:"max_response_time"`
 }
+
+// LatencyStats summarizes response times in milliseconds
+type LatencyStats struct {
+	Average float64 `json:"average"`
+	P50     int64   `json:"p50"`
+	P90     int64   `json:"p90"`
+	P95     int64   `json:"p95"`
+	P99     int64   `json:"p99"`
+	Max     int64   `json:"max"`
+}
+
+// EndpointUsage summarizes the requests made to one route
+type EndpointUsage struct {
+	Method    string       `json:"method"`
+	Endpoint  string       `json:"endpoint"`
+	Requests  int64        `json:"requests"`
+	Errors    int64        `json:"errors"`
+	ErrorRate float64      `json:"error_rate"` // percent
+	Latency   LatencyStats `json:"latency"`
+}
+
+// APIKeyUsageDashboard summarizes a key's requests over a range of days
+type APIKeyUsageDashboard struct {
+	APIKeyID      string           `json:"api_key_id"`
+	StartDate     time.Time        `json:"start_date"`
+	EndDate       time.Time        `json:"end_date"`
+	TotalRequests int64            `json:"total_requests"`
+	ErrorRequests int64            `json:"error_requests"`
+	ErrorRate     float64          `json:"error_rate"` // percent
+	Latency       LatencyStats     `json:"latency"`
+	StatusCodes   map[string]int64 `json:"status_codes"`
+	Endpoints     []EndpointUsage  `json:"endpoints"`
+	DailyStats    []DailyStats     `json:"daily_stats"`
+	Quota         *APIKeyQuota     `json:"quota,omitempty"` // today's quota, for keys with one
+	Alerts        []*UsageAlert    `json:"alerts"`
+	GeneratedAt   time.Time        `json:"generated_at"`
+}
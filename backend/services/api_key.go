@@ -1,17 +1,363 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+	"nutrition-platform/security"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyPrefix starts every key the platform issues
+	apiKeyPrefix = "nk"
+	// apiKeyDisplayLength is how much of a key is kept to identify it in listings
+	apiKeyDisplayLength = len(apiKeyPrefix) + 1 + 8
+	// maxAPIKeysPerUser bounds the keys a user can hold that are not revoked
+	maxAPIKeysPerUser = 20
+	// defaultAPIKeyRateLimit is the requests per minute of a key created without one
+	defaultAPIKeyRateLimit = 100
+	// maxAPIKeyExpiryDays bounds how far ahead a key may expire
+	maxAPIKeyExpiryDays = 3650
+	// DefaultAPIKeyGracePeriod is how long a rotated key keeps working by default
+	DefaultAPIKeyGracePeriod = 24 * time.Hour
+	// MaxAPIKeyGracePeriod bounds how long a rotated key may keep working
+	MaxAPIKeyGracePeriod = 7 * 24 * time.Hour
 )
 
-// APIKeyService handles API key-related operations
+var (
+	// ErrInvalidAPIKeyRequest is wrapped by errors caused by the request
+	ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+	// ErrAPIKeyLimitReached is returned when a user already holds the maximum number of keys
+	ErrAPIKeyLimitReached = errors.New("api key limit reached")
+	// ErrInvalidAPIKey is returned for a key that is malformed or unknown
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyInactive is returned for a key that was deactivated or revoked
+	ErrAPIKeyInactive = errors.New("api key is not active")
+	// ErrAPIKeyExpired is returned for a key past its expiry
+	ErrAPIKeyExpired = errors.New("api key has expired")
+	// ErrAPIKeyQuotaExceeded is returned once a key has used its daily quota
+	ErrAPIKeyQuotaExceeded = errors.New("api key daily quota exceeded")
+)
+
+// APIKeyQuota describes a key's daily quota after a request was counted
+type APIKeyQuota struct {
+	Limit     int       `json:"limit"` // 0 for unlimited
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// APIKeyService issues, rotates and authenticates partner API keys
 type APIKeyService struct {
-	db *sql.DB
+	keys *repositories.APIKeyRepository
 }
 
 // NewAPIKeyService creates a new APIKeyService instance
 func NewAPIKeyService(db *sql.DB) *APIKeyService {
 	return &APIKeyService{
-		db: db,
+		keys: repositories.NewAPIKeyRepository(database.NewDatabase(db)),
+	}
+}
+
+// CreateAPIKey issues a key for the user. The key itself is only returned
+// here; the database keeps its hash.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID string, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if len(name) < 3 || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be between 3 and 100 characters", ErrInvalidAPIKeyRequest)
+	}
+	if err := validateAPIKeyScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+	rateLimit := req.RateLimit
+	if rateLimit == 0 {
+		rateLimit = defaultAPIKeyRateLimit
+	}
+	if err := validateAPIKeyLimits(rateLimit, req.DailyQuota); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var expiresAt *time.Time
+	if req.ExpiresIn != nil {
+		if *req.ExpiresIn < 1 || *req.ExpiresIn > maxAPIKeyExpiryDays {
+			return nil, fmt.Errorf("%w: expires_in must be between 1 and %d days", ErrInvalidAPIKeyRequest, maxAPIKeyExpiryDays)
+		}
+		expiry := now.AddDate(0, 0, *req.ExpiresIn)
+		expiresAt = &expiry
+	}
+
+	count, err := s.keys.CountUsableAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, fmt.Errorf("%w: revoke an unused key first (maximum %d)", ErrAPIKeyLimitReached, maxAPIKeysPerUser)
+	}
+
+	rawKey, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.APIKey{
+		ID:         uuid.New().String(),
+		Name:       name,
+		KeyHash:    security.HashAPIKey(rawKey),
+		Prefix:     rawKey[:apiKeyDisplayLength],
+		UserID:     userID,
+		Status:     models.APIKeyStatusActive,
+		Scopes:     req.Scopes,
+		RateLimit:  rateLimit,
+		DailyQuota: req.DailyQuota,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Metadata:   req.Metadata,
+	}
+	if key.Metadata == nil {
+		key.Metadata = map[string]interface{}{}
+	}
+	if err := s.keys.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{
+		APIKey:  key,
+		Key:     rawKey,
+		Warning: "Store this key securely. It will not be shown again.",
+	}, nil
+}
+
+// ListAPIKeys returns a page of the user's keys, newest first
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string, page, limit int) (*models.APIKeyListResponse, error) {
+	keys, total, err := s.keys.GetAPIKeysByUserID(ctx, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.APIKeyListResponse{
+		APIKeys: make([]models.APIKey, 0, len(keys)),
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	}
+	for _, key := range keys {
+		response.APIKeys = append(response.APIKeys, *withEffectiveStatus(key))
+	}
+	return response, nil
+}
+
+// GetAPIKey returns one of the user's keys
+func (s *APIKeyService) GetAPIKey(ctx context.Context, userID, id string) (*models.APIKey, error) {
+	key, err := s.keys.GetAPIKeyByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return withEffectiveStatus(key), nil
+}
+
+// UpdateAPIKey changes the fields set in req. A key can be switched between
+// active and inactive; revoked and expired keys cannot be changed.
+func (s *APIKeyService) UpdateAPIKey(ctx context.Context, userID, id string, req *models.UpdateAPIKeyRequest) (*models.APIKey, error) {
+	key, err := s.keys.GetAPIKeyByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAPIKeyChangeable(key); err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if len(name) < 3 || len(name) > 100 {
+			return nil, fmt.Errorf("%w: name must be between 3 and 100 characters", ErrInvalidAPIKeyRequest)
+		}
+		key.Name = name
+	}
+	if req.Status != nil {
+		if *req.Status != models.APIKeyStatusActive && *req.Status != models.APIKeyStatusInactive {
+			return nil, fmt.Errorf("%w: status must be active or inactive", ErrInvalidAPIKeyRequest)
+		}
+		key.Status = *req.Status
+	}
+	if req.Scopes != nil {
+		if err := validateAPIKeyScopes(req.Scopes); err != nil {
+			return nil, err
+		}
+		key.Scopes = req.Scopes
+	}
+	if req.RateLimit != nil {
+		key.RateLimit = *req.RateLimit
+	}
+	if req.DailyQuota != nil {
+		key.DailyQuota = *req.DailyQuota
+	}
+	if err := validateAPIKeyLimits(key.RateLimit, key.DailyQuota); err != nil {
+		return nil, err
+	}
+	if req.Metadata != nil {
+		key.Metadata = req.Metadata
+	}
+
+	key.UpdatedAt = time.Now()
+	if err := s.keys.UpdateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// RevokeAPIKey permanently disables a key. Its usage history is kept.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	return s.keys.RevokeAPIKey(ctx, id, userID, time.Now())
+}
+
+// RotateAPIKey issues a new secret for a key, keeping its settings. The old
+// secret keeps working for gracePeriod so partners can deploy the new one;
+// a zero grace period disables it immediately. Rotating again within the
+// grace period ends the earlier secret's grace.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, userID, id string, gracePeriod time.Duration) (*models.CreateAPIKeyResponse, error) {
+	if gracePeriod < 0 || gracePeriod > MaxAPIKeyGracePeriod {
+		return nil, fmt.Errorf("%w: grace period must be between 0 and %d hours", ErrInvalidAPIKeyRequest, int(MaxAPIKeyGracePeriod.Hours()))
+	}
+
+	key, err := s.keys.GetAPIKeyByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAPIKeyChangeable(key); err != nil {
+		return nil, err
+	}
+
+	rawKey, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.keys.RotateAPIKey(ctx, key.ID, userID, security.HashAPIKey(rawKey), rawKey[:apiKeyDisplayLength], now.Add(gracePeriod), now); err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.keys.GetAPIKeyByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	warning := "Store this key securely. It will not be shown again."
+	if gracePeriod > 0 {
+		warning += fmt.Sprintf(" The previous key stops working at %s.", rotated.PreviousKeyExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return &models.CreateAPIKeyResponse{APIKey: rotated, Key: rawKey, Warning: warning}, nil
+}
+
+// AuthenticateAPIKey resolves a key presented by a client. The previous key
+// of a rotated key is accepted until its grace period ends.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	if !models.ValidateAPIKeyFormat(rawKey) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	key, err := s.keys.GetAPIKeyByHash(ctx, security.HashAPIKey(rawKey), now)
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if key.Status != models.APIKeyStatusActive {
+		return nil, ErrAPIKeyInactive
+	}
+	if key.IsExpired() {
+		return nil, ErrAPIKeyExpired
+	}
+
+	if err := s.keys.TouchAPIKey(ctx, key.ID, now); err != nil {
+		return nil, err
+	}
+	key.LastUsedAt = &now
+	return key, nil
+}
+
+// ConsumeQuota counts one request against the key's daily quota, which
+// resets at midnight UTC. ErrAPIKeyQuotaExceeded is returned, together with
+// the quota, once the day's requests are used up.
+func (s *APIKeyService) ConsumeQuota(ctx context.Context, key *models.APIKey) (*APIKeyQuota, error) {
+	now := time.Now().UTC()
+	day := now.Format("2006-01-02")
+
+	counted, used, err := s.keys.ConsumeDailyQuota(ctx, key.ID, day, key.DailyQuota)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &APIKeyQuota{
+		Limit:   key.DailyQuota,
+		Used:    used,
+		ResetAt: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+	}
+	if key.DailyQuota > 0 && used < key.DailyQuota {
+		quota.Remaining = key.DailyQuota - used
+	}
+	if !counted {
+		return quota, ErrAPIKeyQuotaExceeded
+	}
+	return quota, nil
+}
+
+func generateAPIKey() (string, error) {
+	rawKey, _, err := models.GenerateAPIKey(apiKeyPrefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return rawKey, nil
+}
+
+func validateAPIKeyScopes(scopes []models.APIKeyScope) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyRequest)
+	}
+	if err := models.ValidateScopes(scopes); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAPIKeyRequest, err)
+	}
+	return nil
+}
+
+func validateAPIKeyLimits(rateLimit, dailyQuota int) error {
+	if rateLimit < 1 || rateLimit > 10000 {
+		return fmt.Errorf("%w: rate_limit must be between 1 and 10000 requests per minute", ErrInvalidAPIKeyRequest)
+	}
+	if dailyQuota < 0 {
+		return fmt.Errorf("%w: daily_quota cannot be negative", ErrInvalidAPIKeyRequest)
+	}
+	return nil
+}
+
+func checkAPIKeyChangeable(key *models.APIKey) error {
+	if key.Status == models.APIKeyStatusRevoked {
+		return fmt.Errorf("%w: key is revoked", ErrInvalidAPIKeyRequest)
+	}
+	if key.IsExpired() {
+		return fmt.Errorf("%w: key has expired", ErrInvalidAPIKeyRequest)
+	}
+	return nil
+}
+
+// withEffectiveStatus reports keys past their expiry as expired
+func withEffectiveStatus(key *models.APIKey) *models.APIKey {
+	if key.Status == models.APIKeyStatusActive && key.IsExpired() {
+		key.Status = models.APIKeyStatusExpired
 	}
+	return key
 }
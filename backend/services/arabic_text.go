@@ -0,0 +1,201 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// arabicForms lists the presentation forms of an Arabic letter in the order
+// isolated, final, initial, medial. Right-joining letters only have the
+// first two forms.
+type arabicForms [4]rune
+
+// arabicLetters maps base Arabic letters to their Presentation Forms-B glyphs
+var arabicLetters = map[rune]arabicForms{
+	0x0621: {0xFE80, 0, 0, 0},
+	0x0622: {0xFE81, 0xFE82, 0, 0},
+	0x0623: {0xFE83, 0xFE84, 0, 0},
+	0x0624: {0xFE85, 0xFE86, 0, 0},
+	0x0625: {0xFE87, 0xFE88, 0, 0},
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92},
+	0x0629: {0xFE93, 0xFE94, 0, 0},
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98},
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC},
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0},
+	0x0640: {0x0640, 0x0640, 0x0640, 0x0640},
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4},
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8},
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},
+	0x0648: {0xFEED, 0xFEEE, 0, 0},
+	0x0649: {0xFEEF, 0xFEF0, 0, 0},
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},
+}
+
+// lamAlefLigatures maps the alef variant following a lam to the isolated
+// and final ligature forms
+var lamAlefLigatures = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+// ContainsArabic reports whether s contains any Arabic script characters
+func ContainsArabic(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Arabic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// VisualArabic converts logically ordered text into the visual order and
+// contextual glyph forms needed to draw it left-to-right with a font that
+// has no shaping engine. Latin words and numbers inside Arabic text keep
+// their left-to-right order. Harakat (short vowel marks) are dropped
+// because they cannot be positioned without a shaping engine.
+func VisualArabic(s string) string {
+	if !ContainsArabic(s) {
+		return s
+	}
+
+	words := strings.Fields(s)
+	var visual []string
+	var ltrRun []string
+	flush := func() {
+		if len(ltrRun) > 0 {
+			visual = append(visual, strings.Join(ltrRun, " "))
+			ltrRun = nil
+		}
+	}
+
+	// Walk words from the end so the output reads right-to-left
+	for i := len(words) - 1; i >= 0; i-- {
+		word := words[i]
+		if !ContainsArabic(word) {
+			ltrRun = append([]string{word}, ltrRun...)
+			continue
+		}
+		flush()
+		visual = append(visual, reverseRunes(shapeArabic(word)))
+	}
+	flush()
+
+	return strings.Join(visual, " ")
+}
+
+// shapeArabic replaces Arabic letters with their contextual presentation forms
+func shapeArabic(word string) string {
+	var letters []rune
+	for _, r := range word {
+		if r >= 0x064B && r <= 0x0652 {
+			continue
+		}
+		letters = append(letters, r)
+	}
+
+	var out []rune
+	for i := 0; i < len(letters); i++ {
+		r := letters[i]
+		forms, ok := arabicLetters[r]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+
+		joinsPrev := i > 0 && joinsForward(letters[i-1])
+
+		if r == 0x0644 && i+1 < len(letters) {
+			if lig, ok := lamAlefLigatures[letters[i+1]]; ok {
+				if joinsPrev {
+					out = append(out, lig[1])
+				} else {
+					out = append(out, lig[0])
+				}
+				i++
+				continue
+			}
+		}
+
+		joinsNext := forms[2] != 0 && i+1 < len(letters) && isJoiningLetter(letters[i+1])
+
+		form := forms[0]
+		switch {
+		case joinsPrev && joinsNext:
+			form = forms[3]
+		case joinsPrev:
+			form = forms[1]
+		case joinsNext:
+			form = forms[2]
+		}
+		// Hamza has no joined forms and stays isolated after a joining letter
+		if form == 0 {
+			form = forms[0]
+		}
+		out = append(out, form)
+	}
+
+	return string(out)
+}
+
+// joinsForward reports whether a letter connects to the letter after it
+func joinsForward(r rune) bool {
+	forms, ok := arabicLetters[r]
+	return ok && forms[2] != 0
+}
+
+// isJoiningLetter reports whether a letter connects to the letter before it
+func isJoiningLetter(r rune) bool {
+	forms, ok := arabicLetters[r]
+	return ok && forms[1] != 0
+}
+
+// reverseRunes puts a shaped word into visual order. Runs of digits and
+// Latin letters inside it, such as "100" in "100غ", keep their order.
+func reverseRunes(s string) string {
+	runes := []rune(s)
+	out := make([]rune, 0, len(runes))
+	for end := len(runes); end > 0; {
+		start := end - 1
+		if isLeftToRight(runes[start]) {
+			for start > 0 && (isLeftToRight(runes[start-1]) ||
+				(start > 1 && isNumberSeparator(runes[start-1]) && isLeftToRight(runes[start-2]))) {
+				start--
+			}
+		}
+		out = append(out, runes[start:end]...)
+		end = start
+	}
+	return string(out)
+}
+
+// isLeftToRight reports whether r is a digit or a non-Arabic letter
+func isLeftToRight(r rune) bool {
+	return unicode.IsDigit(r) || (unicode.IsLetter(r) && !unicode.Is(unicode.Arabic, r))
+}
+
+// isNumberSeparator reports whether r can sit inside a number, as in 1.5 or 1,000
+func isNumberSeparator(r rune) bool {
+	return r == '.' || r == ','
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidBarcode is returned for codes that are not a valid EAN-13,
+// UPC-A or EAN-8 barcode
+var ErrInvalidBarcode = errors.New("invalid barcode")
+
+// NormalizeBarcode strips separators from a scanned code, verifies its GS1
+// check digit and returns it in canonical form. UPC-A codes are widened to
+// EAN-13 by prefixing a zero so both spellings of a product match.
+func NormalizeBarcode(code string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(code))
+
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: %q contains non-digit characters", ErrInvalidBarcode, code)
+		}
+	}
+
+	switch len(digits) {
+	case 8, 13:
+	case 12:
+		digits = "0" + digits
+	default:
+		return "", fmt.Errorf("%w: %q must have 8, 12 or 13 digits", ErrInvalidBarcode, code)
+	}
+
+	if !ValidBarcodeCheckDigit(digits) {
+		return "", fmt.Errorf("%w: %q has a wrong check digit", ErrInvalidBarcode, code)
+	}
+
+	return digits, nil
+}
+
+// ValidBarcodeCheckDigit reports whether the last digit of a GS1 code
+// (EAN-8, UPC-A, EAN-13) matches the mod-10 checksum of the others
+func ValidBarcodeCheckDigit(digits string) bool {
+	if len(digits) < 2 {
+		return false
+	}
+
+	sum := 0
+	// Weights alternate 3, 1, ... starting from the digit left of the check digit
+	for i := len(digits) - 2; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if (len(digits)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+
+	check := (10 - sum%10) % 10
+	return int(digits[len(digits)-1]-'0') == check
+}
+
+// barcodeVariants lists the spellings a canonical code may be stored under:
+// EAN-13 codes starting with 0 are also UPC-A codes
+func barcodeVariants(canonical string) []string {
+	variants := []string{canonical}
+	if len(canonical) == 13 && canonical[0] == '0' {
+		variants = append(variants, canonical[1:])
+	}
+	return variants
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+const (
+	// Estimates outside this range come from a mistyped tape measurement
+	minPlausibleBodyFat = 2.0
+	maxPlausibleBodyFat = 70.0
+
+	poundsPerKilogram  = 2.20462
+	centimetresPerInch = 2.54
+
+	// FFMI is normalized to this height in metres
+	ffmiReferenceHeight = 1.8
+)
+
+// BodyCompositionService derives body composition from tape measurements.
+// Body fat comes from the first formula the measurements allow, in order of
+// how closely each tracks hydrostatic weighing and DEXA:
+//
+//   - US Navy, from neck, waist and height, plus hips for women
+//   - RFM (relative fat mass), from height and waist
+//   - YMCA, from waist and weight
+//
+// A body fat percentage the user entered always wins over the formulas.
+// Lean mass, fat mass and FFMI follow from body fat and weight; the
+// waist-to-hip and waist-to-height ratios need only the tape.
+type BodyCompositionService struct {
+	users *repositories.UserRepository
+}
+
+// NewBodyCompositionService creates a new BodyCompositionService instance
+func NewBodyCompositionService(db *sql.DB) *BodyCompositionService {
+	return &BodyCompositionService{
+		users: repositories.NewUserRepository(database.NewDatabase(db)),
+	}
+}
+
+// Calculate derives everything the input allows
+func (s *BodyCompositionService) Calculate(input models.BodyCompositionInput) *models.BodyCompositionResult {
+	composition := &models.BodyCompositionResult{Estimates: bodyFatEstimates(input)}
+
+	switch {
+	case input.BodyFatPercentage != nil:
+		bodyFat := *input.BodyFatPercentage
+		method := models.BodyFatMethodUserEntered
+		composition.BodyFatPercentage = &bodyFat
+		composition.BodyFatMethod = &method
+	case len(composition.Estimates) > 0:
+		estimate := composition.Estimates[0]
+		composition.BodyFatPercentage = &estimate.BodyFatPercentage
+		composition.BodyFatMethod = &estimate.Method
+	}
+
+	if positive(input.Waist) && positive(input.Hips) {
+		composition.WaistToHipRatio = roundedPtr(*input.Waist / *input.Hips, 2)
+	}
+	if positive(input.Waist) && positive(input.Height) {
+		composition.WaistToHeightRatio = roundedPtr(*input.Waist / *input.Height, 2)
+	}
+
+	if composition.BodyFatPercentage != nil && positive(input.Weight) {
+		fatMass := *input.Weight * *composition.BodyFatPercentage / 100
+		leanMass := *input.Weight - fatMass
+		composition.FatMass = roundedPtr(fatMass, 1)
+		composition.LeanMass = roundedPtr(leanMass, 1)
+		if positive(input.Height) {
+			metres := *input.Height / 100
+			ffmi := leanMass / (metres * metres)
+			composition.FFMI = roundedPtr(ffmi, 1)
+			composition.NormalizedFFMI = roundedPtr(ffmi+6.1*(ffmiReferenceHeight-metres), 1)
+		}
+	}
+
+	return composition
+}
+
+// ApplyToMeasurement fills a measurement's derived fields. Sex comes from
+// the user's profile, as does height when the measurement has none. A body
+// fat percentage counts as entered unless an earlier calculation set it, so
+// derived values are recalculated when the tape measurements change.
+func (s *BodyCompositionService) ApplyToMeasurement(userID uint, measurement *models.BodyMeasurement) *models.BodyCompositionResult {
+	input := models.BodyCompositionInput{
+		Height: measurement.Height,
+		Weight: measurement.Weight,
+		Neck:   measurement.Neck,
+		Waist:  measurement.Waist,
+		Hips:   measurement.Hips,
+	}
+	if user, err := s.users.GetUserByID(int(userID)); err == nil {
+		input.Sex = user.Gender
+		if input.Height == nil && user.Height > 0 {
+			height := user.Height
+			input.Height = &height
+		}
+	}
+
+	entered := measurement.BodyFatPercentage
+	if entered == nil {
+		entered = measurement.BodyFat
+	}
+	if entered != nil && (measurement.BodyFatMethod == nil || *measurement.BodyFatMethod == models.BodyFatMethodUserEntered) {
+		input.BodyFatPercentage = entered
+	}
+
+	composition := s.Calculate(input)
+	measurement.BodyFatPercentage = composition.BodyFatPercentage
+	measurement.BodyFatMethod = composition.BodyFatMethod
+	measurement.LeanMass = composition.LeanMass
+	measurement.FatMass = composition.FatMass
+	measurement.WaistToHipRatio = composition.WaistToHipRatio
+	measurement.WaistToHeightRatio = composition.WaistToHeightRatio
+	measurement.FFMI = composition.FFMI
+	return composition
+}
+
+// bodyFatEstimates runs every formula the input has measurements for, in
+// order of preference, dropping implausible results
+func bodyFatEstimates(input models.BodyCompositionInput) []models.BodyFatEstimate {
+	estimates := []models.BodyFatEstimate{}
+	male := input.Sex == "male"
+	if !male && input.Sex != "female" {
+		return estimates
+	}
+
+	add := func(method string, bodyFat float64) {
+		if bodyFat >= minPlausibleBodyFat && bodyFat <= maxPlausibleBodyFat {
+			estimates = append(estimates, models.BodyFatEstimate{Method: method, BodyFatPercentage: roundTo(bodyFat, 1)})
+		}
+	}
+
+	if positive(input.Neck) && positive(input.Waist) && positive(input.Height) {
+		height := math.Log10(*input.Height)
+		if male && *input.Waist > *input.Neck {
+			add(models.BodyFatMethodUSNavy, 495/(1.0324-0.19077*math.Log10(*input.Waist-*input.Neck)+0.15456*height)-450)
+		}
+		if !male && positive(input.Hips) && *input.Waist+*input.Hips > *input.Neck {
+			add(models.BodyFatMethodUSNavy, 495/(1.29579-0.35004*math.Log10(*input.Waist+*input.Hips-*input.Neck)+0.22100*height)-450)
+		}
+	}
+
+	if positive(input.Height) && positive(input.Waist) {
+		base := 76.0
+		if male {
+			base = 64
+		}
+		add(models.BodyFatMethodRFM, base-20*(*input.Height / *input.Waist))
+	}
+
+	if positive(input.Waist) && positive(input.Weight) {
+		// The YMCA formula is in inches and pounds
+		waist := *input.Waist / centimetresPerInch
+		weight := *input.Weight * poundsPerKilogram
+		constant := -76.76
+		if male {
+			constant = -98.42
+		}
+		add(models.BodyFatMethodYMCA, (4.15*waist-0.082*weight+constant)/weight*100)
+	}
+
+	return estimates
+}
+
+func positive(v *float64) bool {
+	return v != nil && *v > 0
+}
+
+func roundedPtr(v float64, decimals int) *float64 {
+	rounded := roundTo(v, decimals)
+	return &rounded
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+// CoachInvitationTTL is how long a client has to accept an invitation
+const CoachInvitationTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidCoachingRequest is returned for unknown permissions and
+	// other malformed coaching requests
+	ErrInvalidCoachingRequest = errors.New("invalid coaching request")
+	// ErrCoachInvitationInvalid is returned for unknown, expired, revoked or
+	// already accepted invitation tokens
+	ErrCoachInvitationInvalid = errors.New("invitation is invalid or has expired")
+	// ErrAlreadyCoached is returned when a client accepts a second
+	// invitation from a coach they are already connected to
+	ErrAlreadyCoached = errors.New("already connected to this coach")
+)
+
+// CoachingService manages coach/client relationships. A client accepts a
+// coach's invitation and decides which permissions to grant; coaches can
+// then read or change that client's data only within those grants, and
+// every such access is recorded in the client's access log.
+type CoachingService struct {
+	repo *repositories.CoachClientRepository
+}
+
+// NewCoachingService creates a new CoachingService instance
+func NewCoachingService(db *sql.DB) *CoachingService {
+	return &CoachingService{
+		repo: repositories.NewCoachClientRepository(database.NewDatabase(db)),
+	}
+}
+
+// Invite creates an invitation asking for the given permissions. The token
+// in the result is only returned here; the coach passes it to the client.
+func (s *CoachingService) Invite(ctx context.Context, coachID string, req models.CreateCoachInvitationRequest) (*models.CoachInvitation, error) {
+	permissions, err := normalizeCoachPermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if len(permissions) == 0 {
+		return nil, fmt.Errorf("%w: at least one permission is required", ErrInvalidCoachingRequest)
+	}
+
+	token, err := generateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	rel := &models.CoachClient{
+		ID:                   uuid.New().String(),
+		CoachID:              coachID,
+		InviteEmail:          strings.ToLower(strings.TrimSpace(req.Email)),
+		InviteTokenHash:      hashRefreshToken(token),
+		RequestedPermissions: permissions,
+		Permissions:          []string{},
+		Status:               models.CoachClientPending,
+		InviteExpiresAt:      now.Add(CoachInvitationTTL),
+		CreatedAt:            now,
+	}
+	if err := s.repo.CreateInvitation(ctx, rel); err != nil {
+		return nil, err
+	}
+
+	return &models.CoachInvitation{Relationship: rel, Token: token}, nil
+}
+
+// Accept connects clientID to the coach who issued the invitation. The
+// client grants the requested permissions, or only those listed in
+// req.Permissions, which must be among the requested ones.
+func (s *CoachingService) Accept(ctx context.Context, clientID string, req models.AcceptCoachInvitationRequest) (*models.CoachClient, error) {
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		return nil, ErrCoachInvitationInvalid
+	}
+
+	rel, err := s.repo.GetByTokenHash(ctx, hashRefreshToken(token))
+	if err != nil {
+		if err.Error() == "coach invitation not found" {
+			return nil, ErrCoachInvitationInvalid
+		}
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if rel.Status != models.CoachClientPending || !now.Before(rel.InviteExpiresAt) {
+		return nil, ErrCoachInvitationInvalid
+	}
+	if rel.CoachID == clientID {
+		return nil, fmt.Errorf("%w: coaches cannot accept their own invitation", ErrInvalidCoachingRequest)
+	}
+
+	permissions := rel.RequestedPermissions
+	if len(req.Permissions) > 0 {
+		if permissions, err = normalizeCoachPermissions(req.Permissions); err != nil {
+			return nil, err
+		}
+		for _, permission := range permissions {
+			if !containsString(rel.RequestedPermissions, permission) {
+				return nil, fmt.Errorf("%w: permission %q was not requested", ErrInvalidCoachingRequest, permission)
+			}
+		}
+	}
+
+	if _, err := s.repo.GetActiveRelationship(ctx, rel.CoachID, clientID); err == nil {
+		return nil, ErrAlreadyCoached
+	} else if err.Error() != "coach client relationship not found" {
+		return nil, err
+	}
+
+	accepted, err := s.repo.AcceptInvitation(ctx, rel.ID, clientID, permissions, now)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrCoachInvitationInvalid
+	}
+	return s.repo.GetRelationship(ctx, rel.ID)
+}
+
+// ListClients returns a coach's pending invitations and active clients
+func (s *CoachingService) ListClients(ctx context.Context, coachID string) ([]*models.CoachClient, error) {
+	return s.repo.ListForCoach(ctx, coachID)
+}
+
+// ListCoaches returns the coaches a client is connected to
+func (s *CoachingService) ListCoaches(ctx context.Context, clientID string) ([]*models.CoachClient, error) {
+	return s.repo.ListForClient(ctx, clientID)
+}
+
+// UpdatePermissions replaces what a client grants on one of their
+// relationships. An empty list keeps the relationship but grants nothing.
+func (s *CoachingService) UpdatePermissions(ctx context.Context, clientID, relationshipID string, req models.UpdateCoachPermissionsRequest) (*models.CoachClient, error) {
+	permissions, err := normalizeCoachPermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.ReplaceGrants(ctx, relationshipID, clientID, permissions, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("coach client relationship not found")
+	}
+	return s.repo.GetRelationship(ctx, relationshipID)
+}
+
+// EndRelationship revokes a relationship. Either the coach or the client
+// can end it; coaches can also withdraw a pending invitation.
+func (s *CoachingService) EndRelationship(ctx context.Context, userID, relationshipID string) error {
+	rel, err := s.repo.GetRelationship(ctx, relationshipID)
+	if err != nil {
+		return err
+	}
+	if rel.CoachID != userID && (rel.ClientID == "" || rel.ClientID != userID) {
+		return fmt.Errorf("coach client relationship not found")
+	}
+
+	revoked, err := s.repo.Revoke(ctx, relationshipID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("coach client relationship not found")
+	}
+	return nil
+}
+
+// HasPermission reports whether coachID may use permission on clientID's
+// data
+func (s *CoachingService) HasPermission(ctx context.Context, coachID, clientID, permission string) (bool, error) {
+	if coachID == "" || clientID == "" || coachID == clientID {
+		return false, nil
+	}
+	return s.repo.HasGrant(ctx, coachID, clientID, permission)
+}
+
+// RecordAccess adds an entry to the client's access log
+func (s *CoachingService) RecordAccess(ctx context.Context, entry *models.CoachAccessLogEntry) error {
+	entry.ID = uuid.New().String()
+	entry.CreatedAt = time.Now().UTC()
+	return s.repo.LogAccess(ctx, entry)
+}
+
+// AccessLog returns coach accesses to a client's data, newest first,
+// optionally for a single coach
+func (s *CoachingService) AccessLog(ctx context.Context, clientID, coachID string, limit, offset int) ([]*models.CoachAccessLogEntry, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListAccessLog(ctx, clientID, coachID, limit, offset)
+}
+
+// normalizeCoachPermissions validates and de-duplicates permissions,
+// keeping their order
+func normalizeCoachPermissions(permissions []string) ([]string, error) {
+	normalized := []string{}
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		if !models.IsValidCoachPermission(permission) {
+			return nil, fmt.Errorf("%w: unknown permission %q", ErrInvalidCoachingRequest, permission)
+		}
+		if !containsString(normalized, permission) {
+			normalized = append(normalized, permission)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,627 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Interaction severities, most serious first
+const (
+	InteractionSeverityMajor    = "major"
+	InteractionSeverityModerate = "moderate"
+	InteractionSeverityMinor    = "minor"
+)
+
+const (
+	defaultInteractionReportDays = 30
+	maxInteractionReportDays     = 90
+	// interactionDisclaimerID is the MedicalDisclaimer entry attached to findings
+	interactionDisclaimerID = "medical_critical"
+)
+
+// ErrInvalidMedicationRequest is wrapped by errors caused by the request
+var ErrInvalidMedicationRequest = errors.New("invalid medication request")
+
+// drugNutrientRule is one known drug–food or drug–supplement interaction.
+// Drugs are generic and brand names; foods and supplements are ingredient
+// phrases matched against logged food names and recipe ingredients.
+type drugNutrientRule struct {
+	drugs          []string
+	foods          []string
+	supplements    []string
+	severity       string
+	effect         string
+	recommendation string
+	reference      string
+}
+
+var (
+	warfarinNames         = []string{"warfarin", "coumadin", "jantoven", "acenocoumarol", "sintrom", "phenprocoumon"}
+	serotonergicDrugs     = []string{"sertraline", "zoloft", "fluoxetine", "prozac", "paroxetine", "paxil", "citalopram", "celexa", "escitalopram", "lexapro", "venlafaxine", "effexor", "duloxetine", "cymbalta", "tramadol"}
+	chelatingAntibiotics  = []string{"tetracycline", "doxycycline", "minocycline", "ciprofloxacin", "cipro", "levofloxacin", "levaquin", "moxifloxacin", "ofloxacin"}
+	potassiumRaisingDrugs = []string{"lisinopril", "enalapril", "ramipril", "captopril", "perindopril", "losartan", "valsartan", "irbesartan", "candesartan", "telmisartan",
+		"spironolactone", "aldactone", "eplerenone", "amiloride", "triamterene"}
+)
+
+// drugNutrientRules are the interactions checked against what users log
+var drugNutrientRules = []drugNutrientRule{
+	{
+		drugs: []string{"simvastatin", "zocor", "lovastatin", "mevacor", "atorvastatin", "lipitor", "felodipine", "plendil",
+			"nifedipine", "adalat", "procardia", "cyclosporine", "neoral", "sandimmune", "tacrolimus", "prograf", "buspirone", "amiodarone"},
+		foods:          []string{"grapefruit", "pomelo", "seville orange", "bitter orange"},
+		severity:       InteractionSeverityMajor,
+		effect:         "Grapefruit blocks the enzyme (CYP3A4) that breaks this drug down, raising its blood level and the risk of side effects such as muscle damage or low blood pressure.",
+		recommendation: "Avoid grapefruit, pomelo and Seville orange while taking this medicine, or ask your pharmacist about an alternative.",
+		reference:      "U.S. Food and Drug Administration, Grapefruit Juice and Some Drugs Don't Mix (Consumer Update)",
+	},
+	{
+		drugs: warfarinNames,
+		foods: []string{"kale", "spinach", "collard", "swiss chard", "turnip green", "mustard green", "beet green", "broccoli",
+			"brussels sprout", "parsley", "natto"},
+		severity:       InteractionSeverityModerate,
+		effect:         "Vitamin K counteracts warfarin; sudden changes in how much vitamin K you eat change your INR.",
+		recommendation: "Keep your intake of vitamin K–rich greens consistent from week to week rather than avoiding them, and tell your anticoagulation clinic about large diet changes.",
+		reference:      "NIH Office of Dietary Supplements, Vitamin K Fact Sheet for Health Professionals",
+	},
+	{
+		drugs:          warfarinNames,
+		supplements:    []string{"vitamin k", "phytonadione", "menaquinone", "phylloquinone"},
+		severity:       InteractionSeverityMajor,
+		effect:         "Vitamin K supplements can reduce warfarin's effect and raise the risk of clots.",
+		recommendation: "Do not start or stop a vitamin K supplement unless your prescriber adjusts your dose and checks your INR.",
+		reference:      "NIH Office of Dietary Supplements, Vitamin K Fact Sheet for Health Professionals",
+	},
+	{
+		drugs: concatKeywords(warfarinNames, []string{"apixaban", "eliquis", "rivaroxaban", "xarelto", "dabigatran", "pradaxa",
+			"edoxaban", "clopidogrel", "plavix"}),
+		supplements:    []string{"fish oil", "omega", "ginkgo", "vitamin e", "garlic extract", "turmeric", "curcumin", "dong quai"},
+		severity:       InteractionSeverityModerate,
+		effect:         "May add to the blood-thinning effect and increase the risk of bleeding.",
+		recommendation: "Check with your prescriber before taking this supplement and report unusual bruising or bleeding.",
+		reference:      "NIH National Center for Complementary and Integrative Health, Herb-Drug Interactions",
+	},
+	{
+		drugs: concatKeywords(warfarinNames, serotonergicDrugs, []string{"cyclosporine", "tacrolimus", "digoxin", "lanoxin",
+			"ethinyl estradiol", "levonorgestrel", "norethindrone", "contraceptive"}),
+		supplements:    []string{"st john's wort", "st. john's wort", "hypericum"},
+		severity:       InteractionSeverityMajor,
+		effect:         "St John's wort speeds up the breakdown of many drugs, making them less effective, and with antidepressants can cause serotonin syndrome.",
+		recommendation: "Avoid St John's wort unless your prescriber approves it.",
+		reference:      "NIH National Center for Complementary and Integrative Health, St. John's Wort",
+	},
+	{
+		drugs:          serotonergicDrugs,
+		supplements:    []string{"tryptophan", "5-htp", "hydroxytryptophan"},
+		severity:       InteractionSeverityMajor,
+		effect:         "Adds to the drug's effect on serotonin and may cause serotonin syndrome.",
+		recommendation: "Avoid this supplement unless your prescriber approves it.",
+		reference:      "NIH National Center for Complementary and Integrative Health, Herb-Drug Interactions",
+	},
+	{
+		drugs: []string{"phenelzine", "nardil", "tranylcypromine", "parnate", "isocarboxazid", "marplan", "selegiline", "emsam",
+			"linezolid"},
+		foods: []string{"aged cheese", "cheddar", "blue cheese", "parmesan", "gouda", "camembert", "salami", "pepperoni",
+			"cured meat", "soy sauce", "miso", "sauerkraut", "kimchi", "tap beer", "draft beer", "fava bean", "broad bean",
+			"yeast extract", "marmite"},
+		severity:       InteractionSeverityMajor,
+		effect:         "Tyramine-rich aged, cured and fermented foods can trigger a dangerous rise in blood pressure with MAO inhibitors.",
+		recommendation: "Avoid aged, cured and fermented foods while taking this medicine and for two weeks after stopping it.",
+		reference:      "Mayo Clinic, MAOIs and diet: Is it necessary to restrict tyramine?",
+	},
+	{
+		drugs:          []string{"levothyroxine", "synthroid", "levoxyl", "euthyrox", "eltroxin", "tirosint", "liothyronine"},
+		foods:          []string{"soy", "soybean", "tofu", "edamame", "walnut", "coffee", "espresso"},
+		supplements:    []string{"calcium", "iron", "ferrous", "magnesium", "antacid"},
+		severity:       InteractionSeverityModerate,
+		effect:         "Calcium, iron, soy and coffee reduce how much thyroid hormone is absorbed.",
+		recommendation: "Take the tablet on an empty stomach; wait 30–60 minutes before coffee or soy foods and 4 hours before calcium or iron supplements.",
+		reference:      "MedlinePlus Drug Information, Levothyroxine",
+	},
+	{
+		drugs:          chelatingAntibiotics,
+		foods:          []string{"milk", "yogurt", "yoghurt", "cheese", "kefir", "labneh"},
+		supplements:    []string{"calcium", "iron", "ferrous", "magnesium", "zinc", "antacid"},
+		severity:       InteractionSeverityModerate,
+		effect:         "Calcium, iron, magnesium and zinc bind the antibiotic in the gut and can stop it from working.",
+		recommendation: "Take the antibiotic 2 hours before or 6 hours after dairy foods and mineral supplements.",
+		reference:      "NIH Office of Dietary Supplements, Calcium Fact Sheet for Health Professionals",
+	},
+	{
+		drugs:          potassiumRaisingDrugs,
+		foods:          []string{"salt substitute", "potassium chloride", "lo salt", "nosalt"},
+		supplements:    []string{"potassium"},
+		severity:       InteractionSeverityMajor,
+		effect:         "These drugs raise blood potassium; extra potassium from supplements or salt substitutes can cause dangerous hyperkalaemia.",
+		recommendation: "Do not use potassium supplements or potassium-based salt substitutes unless your doctor prescribed them and monitors your blood potassium.",
+		reference:      "NIH Office of Dietary Supplements, Potassium Fact Sheet for Health Professionals",
+	},
+	{
+		drugs:          []string{"metformin", "glucophage"},
+		foods:          []string{"beer", "wine", "vodka", "whisky", "whiskey", "rum", "gin", "alcohol", "liquor"},
+		severity:       InteractionSeverityModerate,
+		effect:         "Alcohol raises the risk of lactic acidosis and low blood sugar with metformin.",
+		recommendation: "Limit alcohol and never drink it on an empty stomach.",
+		reference:      "MedlinePlus Drug Information, Metformin",
+	},
+	{
+		drugs:          []string{"digoxin", "lanoxin"},
+		foods:          []string{"licorice", "liquorice"},
+		supplements:    []string{"licorice", "liquorice", "glycyrrhiza"},
+		severity:       InteractionSeverityModerate,
+		effect:         "Licorice lowers blood potassium, which increases the risk of digoxin toxicity.",
+		recommendation: "Avoid black licorice and licorice root supplements while taking digoxin.",
+		reference:      "NIH National Center for Complementary and Integrative Health, Licorice Root",
+	},
+}
+
+// DrugInteractionReport lists the interactions between a user's active
+// medications and the foods and supplements they logged over a period
+type DrugInteractionReport struct {
+	models.MedicationInteractionCheck
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Disclaimer  string    `json:"disclaimer"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// DrugInteractionService cross-checks a user's active medications against
+// the foods, recipes and supplements they log
+type DrugInteractionService struct {
+	medications *repositories.MedicationRepository
+	logs        *repositories.FoodLogRepository
+	recipes     *repositories.RecipeRepository
+	disclaimer  *MedicalDisclaimer
+}
+
+// NewDrugInteractionService creates a new DrugInteractionService instance.
+// A nil disclaimer uses the default medical disclaimers.
+func NewDrugInteractionService(db *sql.DB, disclaimer *MedicalDisclaimer) *DrugInteractionService {
+	if disclaimer == nil {
+		disclaimer = NewMedicalDisclaimer()
+	}
+	wrapped := database.NewDatabase(db)
+	return &DrugInteractionService{
+		medications: repositories.NewMedicationRepository(wrapped),
+		logs:        repositories.NewFoodLogRepository(wrapped),
+		recipes:     repositories.NewRecipeRepository(wrapped),
+		disclaimer:  disclaimer,
+	}
+}
+
+// Disclaimer returns the medical disclaimer shown with interaction findings
+func (s *DrugInteractionService) Disclaimer(language string) string {
+	text, _ := s.disclaimer.GetDisclaimerText(interactionDisclaimerID, language)
+	return text
+}
+
+// AddMedication records a medication the user takes and returns its
+// interactions with the supplements they already take
+func (s *DrugInteractionService) AddMedication(ctx context.Context, userID int, req models.CreateUserMedicationRequest) (*models.UserMedication, []models.SupplementInteraction, error) {
+	customName := ""
+	if req.CustomMedicationName != nil {
+		customName = strings.TrimSpace(*req.CustomMedicationName)
+	}
+	if req.MedicationID == nil && customName == "" {
+		return nil, nil, fmt.Errorf("%w: medication_id or custom_medication_name is required", ErrInvalidMedicationRequest)
+	}
+	if strings.TrimSpace(req.Dosage) == "" || strings.TrimSpace(req.Frequency) == "" {
+		return nil, nil, fmt.Errorf("%w: dosage and frequency are required", ErrInvalidMedicationRequest)
+	}
+	if req.StartDate != nil && req.EndDate != nil && req.EndDate.Before(*req.StartDate) {
+		return nil, nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidMedicationRequest)
+	}
+	if req.MedicationID != nil {
+		if _, err := s.medications.GetMedicationByID(ctx, *req.MedicationID); err != nil {
+			if err.Error() == "medication not found" {
+				return nil, nil, fmt.Errorf("%w: unknown medication_id", ErrInvalidMedicationRequest)
+			}
+			return nil, nil, err
+		}
+	}
+
+	medication := &models.UserMedication{
+		ID:                     uuid.New().String(),
+		UserID:                 strconv.Itoa(userID),
+		MedicationID:           req.MedicationID,
+		CustomMedicationName:   req.CustomMedicationName,
+		Dosage:                 req.Dosage,
+		Frequency:              req.Frequency,
+		AdministrationTime:     req.AdministrationTime,
+		StartDate:              req.StartDate,
+		EndDate:                req.EndDate,
+		PrescribedBy:           req.PrescribedBy,
+		ReasonForTaking:        req.ReasonForTaking,
+		SideEffectsExperienced: req.SideEffectsExperienced,
+		IsActive:               true,
+		AdherenceNotes:         req.AdherenceNotes,
+	}
+	if err := s.medications.CreateUserMedication(ctx, medication); err != nil {
+		return nil, nil, err
+	}
+
+	medications, err := s.activeMedications(ctx, userID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	supplements, err := s.activeSupplements(ctx, userID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var added []activeMedication
+	for _, active := range medications {
+		if active.id == medication.ID {
+			added = append(added, active)
+		}
+	}
+
+	interactions := []models.SupplementInteraction{}
+	for _, supplement := range supplements {
+		interactions = append(interactions, matchSupplementInteractions(added, supplement.SupplementName)...)
+	}
+	return medication, interactions, nil
+}
+
+// AddSupplement records a supplement the user takes and returns its
+// interactions with their active medications
+func (s *DrugInteractionService) AddSupplement(ctx context.Context, userID int, req models.CreateUserSupplementRequest) (*models.UserSupplement, []models.SupplementInteraction, error) {
+	if strings.TrimSpace(req.SupplementName) == "" {
+		return nil, nil, fmt.Errorf("%w: supplement_name is required", ErrInvalidMedicationRequest)
+	}
+	if strings.TrimSpace(req.Dosage) == "" || strings.TrimSpace(req.Frequency) == "" {
+		return nil, nil, fmt.Errorf("%w: dosage and frequency are required", ErrInvalidMedicationRequest)
+	}
+	if req.StartDate != nil && req.EndDate != nil && req.EndDate.Before(*req.StartDate) {
+		return nil, nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidMedicationRequest)
+	}
+
+	supplement := &models.UserSupplement{
+		ID:                  uuid.New().String(),
+		UserID:              strconv.Itoa(userID),
+		VitaminMineralID:    req.VitaminMineralID,
+		SupplementName:      strings.TrimSpace(req.SupplementName),
+		Brand:               req.Brand,
+		Dosage:              req.Dosage,
+		Form:                req.Form,
+		Frequency:           req.Frequency,
+		TakenWithMeals:      req.TakenWithMeals,
+		StartDate:           req.StartDate,
+		EndDate:             req.EndDate,
+		ReasonForTaking:     req.ReasonForTaking,
+		PrescribedBy:        req.PrescribedBy,
+		CostPerMonth:        req.CostPerMonth,
+		EffectivenessRating: req.EffectivenessRating,
+		SideEffects:         req.SideEffects,
+		IsActive:            true,
+	}
+	if err := s.medications.CreateUserSupplement(ctx, supplement); err != nil {
+		return nil, nil, err
+	}
+
+	medications, err := s.activeMedications(ctx, userID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	return supplement, matchSupplementInteractions(medications, supplement.SupplementName), nil
+}
+
+// CheckFoodLog returns the interactions between a logged food or recipe and
+// the medications the user was taking on that day
+func (s *DrugInteractionService) CheckFoodLog(ctx context.Context, userID int, entry *models.UserFoodLog) ([]models.FoodInteraction, error) {
+	medications, err := s.activeMedications(ctx, userID, entry.LoggedAt)
+	if err != nil || len(medications) == 0 {
+		return nil, err
+	}
+
+	texts, err := s.foodTexts(ctx, entry, map[string]*models.Recipe{})
+	if err != nil {
+		return nil, err
+	}
+	return matchFoodInteractions(medications, texts, entry.FoodName), nil
+}
+
+// GetReport checks the foods logged over the last days and the active
+// supplements against the user's active medications
+func (s *DrugInteractionService) GetReport(ctx context.Context, userID, days int, language string) (*DrugInteractionReport, error) {
+	if days == 0 {
+		days = defaultInteractionReportDays
+	}
+	if days < 0 || days > maxInteractionReportDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidMedicationRequest, maxInteractionReportDays)
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -days)
+
+	report := &DrugInteractionReport{
+		MedicationInteractionCheck: models.MedicationInteractionCheck{
+			UserMedications:        []string{},
+			Interactions:           []models.MedicationInteraction{},
+			FoodInteractions:       []models.FoodInteraction{},
+			SupplementInteractions: []models.SupplementInteraction{},
+			Warnings:               []string{},
+			Recommendations:        []string{},
+		},
+		From:        from,
+		To:          to,
+		Disclaimer:  s.Disclaimer(language),
+		GeneratedAt: now,
+	}
+
+	medications, err := s.activeMedications(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, medication := range medications {
+		report.UserMedications = append(report.UserMedications, medication.label)
+	}
+	if len(medications) == 0 {
+		return report, nil
+	}
+
+	supplements, err := s.activeSupplements(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, supplement := range supplements {
+		report.SupplementInteractions = append(report.SupplementInteractions,
+			matchSupplementInteractions(medications, supplement.SupplementName)...)
+	}
+
+	logs, err := s.logs.GetFoodLogsByDateRange(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	recipeCache := map[string]*models.Recipe{}
+	seen := map[string]bool{}
+	for _, entry := range logs {
+		texts, err := s.foodTexts(ctx, entry, recipeCache)
+		if err != nil {
+			return nil, err
+		}
+		for _, interaction := range matchFoodInteractions(medications, texts, entry.FoodName) {
+			key := interaction.Medication + "|" + interaction.Food + "|" + interaction.Effect
+			if !seen[key] {
+				seen[key] = true
+				report.FoodInteractions = append(report.FoodInteractions, interaction)
+			}
+		}
+	}
+
+	sort.SliceStable(report.FoodInteractions, func(i, j int) bool {
+		return severityRank(report.FoodInteractions[i].Severity) < severityRank(report.FoodInteractions[j].Severity)
+	})
+	sort.SliceStable(report.SupplementInteractions, func(i, j int) bool {
+		return severityRank(report.SupplementInteractions[i].Severity) < severityRank(report.SupplementInteractions[j].Severity)
+	})
+
+	recommended := map[string]bool{}
+	addRecommendation := func(recommendation string) {
+		if !recommended[recommendation] {
+			recommended[recommendation] = true
+			report.Recommendations = append(report.Recommendations, recommendation)
+		}
+	}
+	for _, interaction := range report.FoodInteractions {
+		if interaction.Severity == InteractionSeverityMajor {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s with %s: %s", interaction.Medication, interaction.Food, interaction.Effect))
+		}
+		addRecommendation(interaction.Recommendation)
+	}
+	for _, interaction := range report.SupplementInteractions {
+		if interaction.Severity == InteractionSeverityMajor {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s with %s: %s", interaction.Medication, interaction.Supplement, interaction.Effect))
+		}
+		addRecommendation(interaction.Recommendation)
+	}
+
+	return report, nil
+}
+
+// activeMedication is a medication the user takes with every name it is
+// known by: the user's own name and the catalog's name, generic name and
+// brand names
+type activeMedication struct {
+	id    string
+	label string
+	names []string
+}
+
+// activeMedications returns the medications active on the given day
+func (s *DrugInteractionService) activeMedications(ctx context.Context, userID int, day time.Time) ([]activeMedication, error) {
+	stored, err := s.medications.GetActiveUserMedications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var medications []activeMedication
+	for _, medication := range stored {
+		if !activeOn(medication.StartDate, medication.EndDate, day) {
+			continue
+		}
+
+		var catalog *models.Medication
+		if medication.MedicationID != nil {
+			catalog, err = s.medications.GetMedicationByID(ctx, *medication.MedicationID)
+			if err != nil && err.Error() != "medication not found" {
+				return nil, err
+			}
+		}
+
+		active := activeMedication{id: medication.ID, label: medicationLabel(medication, catalog)}
+		if medication.CustomMedicationName != nil {
+			active.names = append(active.names, *medication.CustomMedicationName)
+		}
+		if catalog != nil {
+			active.names = append(active.names, catalog.Name)
+			if catalog.GenericName != nil {
+				active.names = append(active.names, *catalog.GenericName)
+			}
+			active.names = append(active.names, catalog.BrandNames...)
+		}
+		medications = append(medications, active)
+	}
+	return medications, nil
+}
+
+func (s *DrugInteractionService) activeSupplements(ctx context.Context, userID int, day time.Time) ([]*models.UserSupplement, error) {
+	stored, err := s.medications.GetActiveUserSupplements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var supplements []*models.UserSupplement
+	for _, supplement := range stored {
+		if activeOn(supplement.StartDate, supplement.EndDate, day) {
+			supplements = append(supplements, supplement)
+		}
+	}
+	return supplements, nil
+}
+
+// foodTexts returns the names a logged entry is checked by: the food or
+// recipe name and, for recipes, every ingredient
+func (s *DrugInteractionService) foodTexts(ctx context.Context, entry *models.UserFoodLog, cache map[string]*models.Recipe) ([]string, error) {
+	texts := []string{entry.FoodName}
+	if entry.RecipeID == nil {
+		return texts, nil
+	}
+
+	recipe, ok := cache[*entry.RecipeID]
+	if !ok {
+		var err error
+		recipe, err = s.recipes.GetRecipeByID(ctx, *entry.RecipeID)
+		if err != nil {
+			if err.Error() != "recipe not found" {
+				return nil, err
+			}
+			recipe = nil
+		}
+		cache[*entry.RecipeID] = recipe
+	}
+	if recipe != nil {
+		texts = append(texts, recipe.Name)
+		for _, ingredient := range recipe.Ingredients {
+			texts = append(texts, ingredient.Name)
+		}
+	}
+	return texts, nil
+}
+
+func matchFoodInteractions(medications []activeMedication, texts []string, food string) []models.FoodInteraction {
+	var interactions []models.FoodInteraction
+	for _, rule := range drugNutrientRules {
+		if !anyPhraseIn(texts, rule.foods) {
+			continue
+		}
+		for _, medication := range medications {
+			if anyPhraseIn(medication.names, rule.drugs) {
+				interactions = append(interactions, models.FoodInteraction{
+					Medication:     medication.label,
+					Food:           food,
+					Effect:         rule.effect,
+					Recommendation: rule.recommendation,
+					Severity:       rule.severity,
+					Reference:      rule.reference,
+				})
+			}
+		}
+	}
+	return interactions
+}
+
+func matchSupplementInteractions(medications []activeMedication, supplement string) []models.SupplementInteraction {
+	var interactions []models.SupplementInteraction
+	for _, rule := range drugNutrientRules {
+		if !anyPhraseIn([]string{supplement}, rule.supplements) {
+			continue
+		}
+		for _, medication := range medications {
+			if anyPhraseIn(medication.names, rule.drugs) {
+				interactions = append(interactions, models.SupplementInteraction{
+					Medication:     medication.label,
+					Supplement:     supplement,
+					Effect:         rule.effect,
+					Recommendation: rule.recommendation,
+					Severity:       rule.severity,
+					Reference:      rule.reference,
+				})
+			}
+		}
+	}
+	return interactions
+}
+
+// anyPhraseIn reports whether any keyword appears as whole words in any
+// text, comparing ingredientTokens so plurals and punctuation do not matter
+func anyPhraseIn(texts, keywords []string) bool {
+	for _, text := range texts {
+		phrase := interactionPhrase(text)
+		for _, keyword := range keywords {
+			kw := strings.TrimSpace(interactionPhrase(keyword))
+			if kw != "" && strings.Contains(phrase, " "+kw+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// interactionPhrase normalizes text to space-padded singular words. Unlike
+// ingredientTokens it keeps single letters and word order, so "vitamin k"
+// does not match "vitamin e".
+func interactionPhrase(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, f := range fields {
+		fields[i] = singularize(f)
+	}
+	return " " + strings.Join(fields, " ") + " "
+}
+
+func medicationLabel(medication *models.UserMedication, catalog *models.Medication) string {
+	if medication.CustomMedicationName != nil && strings.TrimSpace(*medication.CustomMedicationName) != "" {
+		return strings.TrimSpace(*medication.CustomMedicationName)
+	}
+	if catalog != nil {
+		return catalog.Name
+	}
+	return "medication"
+}
+
+// activeOn reports whether a start/end date range includes the day
+func activeOn(start, end *time.Time, day time.Time) bool {
+	date := day.Format("2006-01-02")
+	if start != nil && start.Format("2006-01-02") > date {
+		return false
+	}
+	if end != nil && end.Format("2006-01-02") < date {
+		return false
+	}
+	return true
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case InteractionSeverityMajor:
+		return 0
+	case InteractionSeverityModerate:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+const (
+	// DefaultTDEEWindowDays is how many days of logs an estimate looks at
+	// unless asked otherwise
+	DefaultTDEEWindowDays = 28
+	minTDEEWindowDays     = 14
+
+	// Energy stored in a kilogram of body weight change
+	kcalPerKilogram = 7700.0
+
+	// Days further back count half as much as the most recent day
+	tdeeHalfLifeDays = 14.0
+
+	// Daily smoothing of the displayed weight trend
+	weightTrendSmoothing = 0.1
+
+	// Least data for an estimate from the logs
+	minTDEEWeighIns       = 3
+	minTDEEWeighInSpan    = 7
+	minTDEELoggedDays     = 7
+	partialDayIntakeShare = 0.4
+
+	// The formula is typically within a few hundred calories
+	formulaTDEEStdDev = 300.0
+	// AIPersonalization's default when the activity level is unknown
+	formulaActivityMultiplier = 1.375
+	// Logging is never exact, so the data alone is never trusted more
+	// than this
+	minAdaptiveTDEEStdError = 50.0
+
+	// Calorie targets are never set below this
+	minimumCalorieTarget = 1200
+	maxCalorieAdjustment = 1500
+)
+
+// ErrInsufficientEnergyData is returned when there is too little logged
+// intake and weight to estimate energy expenditure
+var ErrInsufficientEnergyData = errors.New("not enough logged intake and weight to estimate energy expenditure")
+
+// ErrInvalidEnergyRequest is returned for out of range windows and
+// adjustments
+var ErrInvalidEnergyRequest = errors.New("invalid energy expenditure request")
+
+// EnergyExpenditureService estimates how much energy a user actually
+// spends from what they log. Over a window of days it fits an
+// exponentially weighted trend to their weigh-ins, so recent weeks count
+// most and days without a weigh-in are simply missing, and reconciles the
+// trend's rate of change with their logged intake:
+//
+//	TDEE = average intake - 7700 kcal/kg * trend change per day
+//
+// Days with nothing logged are left out rather than counted as zero, and
+// days logged far below the user's usual intake are treated as partly
+// logged and left out too. The result is weighed against the Mifflin-St Jeor
+// formula by how uncertain each is, so a few days of logs lean on the
+// formula and weeks of consistent logs override it.
+type EnergyExpenditureService struct {
+	ledger      *NutritionLedgerService
+	weights     *repositories.WeightRepository
+	users       *repositories.UserRepository
+	preferences *UserPreferencesService
+}
+
+// NewEnergyExpenditureService creates a new EnergyExpenditureService instance
+func NewEnergyExpenditureService(db *sql.DB) *EnergyExpenditureService {
+	wrapped := database.NewDatabase(db)
+	return &EnergyExpenditureService{
+		ledger:      NewNutritionLedgerService(db),
+		weights:     repositories.NewWeightRepository(wrapped),
+		users:       repositories.NewUserRepository(wrapped),
+		preferences: NewUserPreferencesService(db),
+	}
+}
+
+// EstimateTDEE estimates the user's energy expenditure over the given
+// number of days ending today in their time zone
+func (s *EnergyExpenditureService) EstimateTDEE(ctx context.Context, userID int, days int) (*models.EnergyExpenditureEstimate, error) {
+	if days == 0 {
+		days = DefaultTDEEWindowDays
+	}
+	if days < minTDEEWindowDays || days > maxLedgerDays {
+		return nil, fmt.Errorf("%w: days must be between %d and %d", ErrInvalidEnergyRequest, minTDEEWindowDays, maxLedgerDays)
+	}
+
+	summary, err := s.ledger.GetLedgerSummary(ctx, userID, time.Time{}, days)
+	if err != nil {
+		return nil, err
+	}
+	loc := s.preferences.GetTimezone(ctx, userID)
+	first, err := time.ParseInLocation("2006-01-02", summary.StartDate, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ledger date: %w", err)
+	}
+
+	logs, err := s.weights.GetWeightLogsByDateRange(ctx, userID, first.UTC(), first.AddDate(0, 0, days).UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &models.EnergyExpenditureEstimate{
+		UserID:    userID,
+		StartDate: summary.StartDate,
+		EndDate:   summary.EndDate,
+		Days:      days,
+		Daily:     make([]models.EnergyBalanceDay, days),
+	}
+
+	intake := make([]*float64, days)
+	for i, ledger := range summary.Ledgers {
+		estimate.Daily[i].Date = ledger.Date
+		if ledger.Totals.Calories > 0 {
+			calories := ledger.Totals.Calories
+			intake[i] = &calories
+			estimate.Daily[i].Calories = &calories
+		}
+	}
+	markPartialDays(estimate.Daily, intake)
+
+	weights := dailyWeights(logs, first, days)
+	for i, w := range weights {
+		estimate.Daily[i].Weight = w
+	}
+	applyWeightTrend(estimate, weights)
+
+	adaptive, adaptiveErr, ok := adaptiveTDEE(estimate, intake, weights)
+	formula, hasFormula := s.formulaTDEE(userID, estimate.TrendWeight)
+
+	var tdee, stdErr float64
+	switch {
+	case ok && hasFormula:
+		estimate.Method = models.TDEEMethodBlended
+		adaptiveWeight := 1 / (adaptiveErr * adaptiveErr)
+		formulaWeight := 1 / (formulaTDEEStdDev * formulaTDEEStdDev)
+		tdee = (adaptive*adaptiveWeight + formula*formulaWeight) / (adaptiveWeight + formulaWeight)
+		stdErr = math.Sqrt(1 / (adaptiveWeight + formulaWeight))
+	case ok:
+		estimate.Method = models.TDEEMethodAdaptive
+		tdee, stdErr = adaptive, adaptiveErr
+	case hasFormula:
+		estimate.Method = models.TDEEMethodFormula
+		tdee, stdErr = formula, formulaTDEEStdDev
+	default:
+		return nil, ErrInsufficientEnergyData
+	}
+	if ok {
+		rounded := roundCalories(adaptive)
+		estimate.AdaptiveTDEE = &rounded
+	}
+	if hasFormula {
+		rounded := roundCalories(formula)
+		estimate.FormulaTDEE = &rounded
+	}
+
+	margin := 1.96 * stdErr
+	estimate.TDEE = roundCalories(tdee)
+	estimate.TDEELow = roundCalories(tdee - margin)
+	estimate.TDEEHigh = roundCalories(tdee + margin)
+	switch {
+	case margin <= 150:
+		estimate.Confidence = models.TDEEConfidenceHigh
+	case margin <= 300:
+		estimate.Confidence = models.TDEEConfidenceMedium
+	default:
+		estimate.Confidence = models.TDEEConfidenceLow
+	}
+
+	return estimate, nil
+}
+
+// ApplyToGoal sets a nutrition goal's calorie target, and the band around
+// it, from the adaptive estimate plus the requested adjustment
+func (s *EnergyExpenditureService) ApplyToGoal(ctx context.Context, userID int, req models.AdaptiveGoalRequest) (*models.NutritionGoal, *models.EnergyExpenditureEstimate, error) {
+	if req.Adjustment < -maxCalorieAdjustment || req.Adjustment > maxCalorieAdjustment {
+		return nil, nil, fmt.Errorf("%w: adjustment must be between -%d and %d", ErrInvalidEnergyRequest, maxCalorieAdjustment, maxCalorieAdjustment)
+	}
+
+	estimate, err := s.EstimateTDEE(ctx, userID, req.Days)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A target from the formula alone is what the adaptive estimate
+	// replaces
+	if estimate.Method == models.TDEEMethodFormula {
+		return nil, nil, ErrInsufficientEnergyData
+	}
+
+	goals, err := s.users.GetActiveNutritionGoals(uint(userID))
+	if err != nil {
+		return nil, nil, err
+	}
+	var goal *models.NutritionGoal
+	if req.GoalID != nil {
+		for _, candidate := range goals {
+			if candidate.ID == *req.GoalID {
+				goal = candidate
+				break
+			}
+		}
+		if goal == nil {
+			return nil, nil, fmt.Errorf("nutrition goal not found")
+		}
+	} else if len(goals) > 0 {
+		goal = goals[0]
+	}
+
+	target := maxInt(estimate.TDEE+req.Adjustment, minimumCalorieTarget)
+	low := maxInt(estimate.TDEELow+req.Adjustment, minimumCalorieTarget)
+	high := maxInt(estimate.TDEEHigh+req.Adjustment, target)
+	tdee := estimate.TDEE
+	confidence := estimate.Confidence
+	now := time.Now().UTC()
+
+	create := goal == nil
+	if create {
+		goal = &models.NutritionGoal{UserID: uint(userID), IsActive: true}
+	}
+	goal.DailyCalories = &target
+	goal.DailyCaloriesLow = &low
+	goal.DailyCaloriesHigh = &high
+	goal.EstimatedTDEE = &tdee
+	goal.TDEEConfidence = &confidence
+	goal.TDEEUpdatedAt = &now
+
+	if create {
+		err = s.users.CreateNutritionGoal(goal)
+	} else {
+		err = s.users.UpdateNutritionGoal(goal)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return goal, estimate, nil
+}
+
+// formulaTDEE is the Mifflin-St Jeor estimate from the user's profile, at
+// their trend weight when there is one. Profiles without age, height or
+// weight give none.
+func (s *EnergyExpenditureService) formulaTDEE(userID int, trendWeight *float64) (float64, bool) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil || user.Age <= 0 || user.Height <= 0 {
+		return 0, false
+	}
+	weight := user.Weight
+	if trendWeight != nil {
+		weight = *trendWeight
+	}
+	if weight <= 0 {
+		return 0, false
+	}
+
+	bmr := 10*weight + 6.25*user.Height - 5*float64(user.Age)
+	if user.Gender == "male" {
+		bmr += 5
+	} else {
+		bmr -= 161
+	}
+	return bmr * formulaActivityMultiplier, true
+}
+
+// markPartialDays flags logged days far below the user's median intake,
+// which are most likely only partly logged, and drops them from intake
+func markPartialDays(daily []models.EnergyBalanceDay, intake []*float64) {
+	var logged []float64
+	for _, calories := range intake {
+		if calories != nil {
+			logged = append(logged, *calories)
+		}
+	}
+	if len(logged) == 0 {
+		return
+	}
+	sort.Float64s(logged)
+	median := logged[len(logged)/2]
+	if len(logged)%2 == 0 {
+		median = (logged[len(logged)/2-1] + median) / 2
+	}
+
+	for i, calories := range intake {
+		if calories != nil && *calories < partialDayIntakeShare*median {
+			daily[i].Partial = true
+			intake[i] = nil
+		}
+	}
+}
+
+// dailyWeights averages each day's weigh-ins in kilograms. Days without
+// one are nil.
+func dailyWeights(logs []*models.WeightLog, first time.Time, days int) []*float64 {
+	sums := make([]float64, days)
+	counts := make([]int, days)
+	for _, log := range logs {
+		local := log.CreatedAt.In(first.Location())
+		index := daysBetween(first, time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, first.Location()))
+		if index < 0 || index >= days {
+			continue
+		}
+		sums[index] += WeightInKilograms(log)
+		counts[index]++
+	}
+
+	weights := make([]*float64, days)
+	for i := range weights {
+		if counts[i] > 0 {
+			w := sums[i] / float64(counts[i])
+			weights[i] = &w
+		}
+	}
+	return weights
+}
+
+// WeightInKilograms returns a weight log in kilograms. Logs in an unknown
+// unit are taken to be in kilograms already.
+func WeightInKilograms(log *models.WeightLog) float64 {
+	unit, err := NormalizeUnit(log.Unit)
+	if err != nil || !IsMassUnit(unit) {
+		return log.Weight
+	}
+	return log.Weight * gramsPerUnit[unit] / 1000
+}
+
+// applyWeightTrend fills in the smoothed trend weight from the first
+// weigh-in on. Each weigh-in moves the trend by the smoothing of however
+// many days passed since the last one, so gaps neither stall nor jolt it.
+func applyWeightTrend(estimate *models.EnergyExpenditureEstimate, weights []*float64) {
+	var trend float64
+	last := -1
+	for i, w := range weights {
+		if w != nil {
+			estimate.WeighIns++
+			if last < 0 {
+				trend = *w
+			} else {
+				alpha := 1 - math.Pow(1-weightTrendSmoothing, float64(i-last))
+				trend += alpha * (*w - trend)
+			}
+			last = i
+		}
+		if last >= 0 {
+			value := roundTo(trend, 2)
+			estimate.Daily[i].TrendWeight = &value
+		}
+	}
+	if last >= 0 {
+		value := roundTo(trend, 2)
+		estimate.TrendWeight = &value
+	}
+}
+
+// adaptiveTDEE reconciles intake with the weight trend. The rate of change
+// is the slope of an exponentially weighted least-squares line through the
+// weigh-ins, and intake is averaged with the same weights. It returns the
+// estimate and its standard error, or false when too little was logged.
+func adaptiveTDEE(estimate *models.EnergyExpenditureEstimate, intake, weights []*float64) (float64, float64, bool) {
+	days := len(intake)
+	decay := math.Ln2 / tdeeHalfLifeDays
+	dayWeight := func(i int) float64 { return math.Exp(-decay * float64(days-1-i)) }
+
+	var intakeDays []int
+	for i, calories := range intake {
+		if calories != nil {
+			intakeDays = append(intakeDays, i)
+		}
+	}
+	var weighInDays []int
+	for i, w := range weights {
+		if w != nil {
+			weighInDays = append(weighInDays, i)
+		}
+	}
+
+	estimate.LoggedDays = len(intakeDays)
+	for _, day := range estimate.Daily {
+		if day.Partial {
+			estimate.PartialDays++
+		}
+	}
+
+	if len(intakeDays) > 0 {
+		var sum, total float64
+		for _, i := range intakeDays {
+			sum += *intake[i]
+			total++
+		}
+		estimate.AverageIntake = roundTo(sum/total, 0)
+	}
+
+	if len(intakeDays) < minTDEELoggedDays || len(weighInDays) < minTDEEWeighIns ||
+		weighInDays[len(weighInDays)-1]-weighInDays[0] < minTDEEWeighInSpan {
+		return 0, 0, false
+	}
+
+	meanIntake, intakeErr := weightedMean(intakeDays, func(i int) float64 { return *intake[i] }, dayWeight)
+	slope, slopeErr := weightedSlope(weighInDays, func(i int) float64 { return *weights[i] }, dayWeight)
+
+	weekly := roundTo(slope*7, 2)
+	estimate.WeeklyChange = &weekly
+
+	tdee := meanIntake - kcalPerKilogram*slope
+	stdErr := math.Sqrt(intakeErr*intakeErr + kcalPerKilogram*kcalPerKilogram*slopeErr*slopeErr)
+	return tdee, math.Max(stdErr, minAdaptiveTDEEStdError), true
+}
+
+// weightedMean returns the weighted mean of value over the given days and
+// its standard error
+func weightedMean(days []int, value func(int) float64, weight func(int) float64) (float64, float64) {
+	var sumW, sumW2, sumWY float64
+	for _, i := range days {
+		w := weight(i)
+		sumW += w
+		sumW2 += w * w
+		sumWY += w * value(i)
+	}
+	mean := sumWY / sumW
+
+	var sumWR2 float64
+	for _, i := range days {
+		r := value(i) - mean
+		sumWR2 += weight(i) * r * r
+	}
+	effective := sumW * sumW / sumW2
+	if effective <= 1 {
+		return mean, 0
+	}
+	variance := sumWR2 / sumW * effective / (effective - 1)
+	return mean, math.Sqrt(variance / effective)
+}
+
+// weightedSlope fits a weighted least-squares line of value against day and
+// returns its slope per day and the slope's standard error
+func weightedSlope(days []int, value func(int) float64, weight func(int) float64) (float64, float64) {
+	var sumW, sumW2, sumWX, sumWY float64
+	for _, i := range days {
+		w := weight(i)
+		sumW += w
+		sumW2 += w * w
+		sumWX += w * float64(i)
+		sumWY += w * value(i)
+	}
+	meanX := sumWX / sumW
+	meanY := sumWY / sumW
+
+	var sxx, sxy float64
+	for _, i := range days {
+		w := weight(i)
+		dx := float64(i) - meanX
+		sxx += w * dx * dx
+		sxy += w * dx * (value(i) - meanY)
+	}
+	if sxx == 0 {
+		return 0, 0
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sumWR2, spread float64
+	for _, i := range days {
+		w := weight(i)
+		r := value(i) - (intercept + slope*float64(i))
+		sumWR2 += w * r * r
+		dx := float64(i) - meanX
+		spread += w * w * dx * dx
+	}
+	effective := sumW * sumW / sumW2
+	if effective <= 2 {
+		return slope, 0
+	}
+	variance := sumWR2 / sumW * effective / (effective - 2)
+	return slope, math.Sqrt(variance*spread) / sxx
+}
+
+// roundCalories rounds to the nearest 10 kcal; estimates are not more
+// precise than that
+func roundCalories(calories float64) int {
+	return int(math.Round(calories/10) * 10)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -76,13 +76,6 @@ func (s *FitnessService) GenerateWorkoutPlan(userID int64, goal string, duration
 	return workoutPlan, nil
 }
 
-// LogWorkoutSession saves a workout session
-func (s *FitnessService) LogWorkoutSession(userID int64, workoutData map[string]interface{}) error {
-	// For now, just return nil
-	// In a real implementation, this would save to the database
-	return nil
-}
-
 // GetFitnessSummary returns fitness analytics
 func (s *FitnessService) GetFitnessSummary(userID int64, days int) (map[string]interface{}, error) {
 	// For now, return mock data
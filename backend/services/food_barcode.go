@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+// Where a barcode lookup found its food
+const (
+	BarcodeSourceUser    = "user"
+	BarcodeSourceCatalog = "catalog"
+)
+
+// BarcodeLookup is the food matched to a scanned barcode
+type BarcodeLookup struct {
+	Barcode  string       `json:"barcode"`
+	Source   string       `json:"source"`
+	Verified bool         `json:"verified"`
+	Food     *models.Food `json:"food"`
+}
+
+// FoodBarcodeService resolves scanned barcodes to foods
+type FoodBarcodeService struct {
+	foods   *repositories.FoodRepository
+	catalog *repositories.FoodCatalogRepository
+}
+
+// NewFoodBarcodeService creates a new FoodBarcodeService instance
+func NewFoodBarcodeService(db *sql.DB) *FoodBarcodeService {
+	return &FoodBarcodeService{
+		foods:   repositories.NewFoodRepository(db),
+		catalog: repositories.NewFoodCatalogRepository(database.NewDatabase(db)),
+	}
+}
+
+// LookupBarcode validates the code and looks it up first among the user's
+// own foods, which may correct catalog data, then in the imported catalog.
+// It returns ErrInvalidBarcode for malformed codes and a "food not found"
+// error when neither has the product.
+func (s *FoodBarcodeService) LookupBarcode(ctx context.Context, userID, code string) (*BarcodeLookup, error) {
+	barcode, err := NormalizeBarcode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range barcodeVariants(barcode) {
+		food, err := s.foods.GetFoodByBarcode(variant, userID)
+		if err == nil {
+			return &BarcodeLookup{Barcode: barcode, Source: BarcodeSourceUser, Verified: food.Verified, Food: food}, nil
+		}
+		if err.Error() != "food not found" {
+			return nil, err
+		}
+	}
+
+	food, err := s.catalog.GetCatalogFoodByBarcode(ctx, barcodeVariants(barcode)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BarcodeLookup{Barcode: barcode, Source: BarcodeSourceCatalog, Verified: food.Verified, Food: food}, nil
+}
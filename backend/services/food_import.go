@@ -0,0 +1,394 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+// Product dump formats understood by FoodImportService
+const (
+	FoodImportFormatCSV   = "csv"
+	FoodImportFormatJSONL = "jsonl"
+)
+
+// DefaultFoodDataSource is recorded on foods imported without an explicit source
+const DefaultFoodDataSource = "openfoodfacts"
+
+// maxFoodImportErrors bounds how many rejected rows an import reports
+const maxFoodImportErrors = 100
+
+// maxJSONLProductSize is the largest single product line accepted. Open Food
+// Facts records with many images and translations reach a few megabytes.
+const maxJSONLProductSize = 32 * 1024 * 1024
+
+// FoodImportOptions configures a product dump import
+type FoodImportOptions struct {
+	Format     string
+	DataSource string
+	// Verified marks every imported product as verified, for dumps from a
+	// curated source. Otherwise only products the source reports as checked
+	// are verified.
+	Verified bool
+}
+
+// FoodImportError describes a product that was not imported
+type FoodImportError struct {
+	Line   int    `json:"line"`
+	Code   string `json:"code,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// FoodImportResult summarizes an import run
+type FoodImportResult struct {
+	Read    int               `json:"read"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Kept    int               `json:"kept_verified"`
+	Skipped int               `json:"skipped"`
+	Errors  []FoodImportError `json:"errors,omitempty"`
+}
+
+// offProduct is the subset of an Open Food Facts product the importer uses
+type offProduct struct {
+	line        int
+	code        string
+	name        string
+	brands      string
+	servingSize string
+	states      []string
+	nutriments  map[string]float64
+}
+
+// FoodImportService loads Open Food Facts product dumps into the foods table
+// as catalog foods
+type FoodImportService struct {
+	catalog *repositories.FoodCatalogRepository
+}
+
+// NewFoodImportService creates a new FoodImportService instance
+func NewFoodImportService(db *sql.DB) *FoodImportService {
+	return &FoodImportService{
+		catalog: repositories.NewFoodCatalogRepository(database.NewDatabase(db)),
+	}
+}
+
+// Import reads a CSV (comma or tab separated) or JSONL product dump and
+// creates or updates one catalog food per barcode. Re-running an import is
+// safe: existing products are updated in place, and verified products are
+// never overwritten by unverified data.
+func (s *FoodImportService) Import(ctx context.Context, r io.Reader, opts FoodImportOptions) (*FoodImportResult, error) {
+	if opts.DataSource == "" {
+		opts.DataSource = DefaultFoodDataSource
+	}
+
+	result := &FoodImportResult{}
+	handle := func(p offProduct) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result.Read++
+		return s.importProduct(ctx, p, opts, result)
+	}
+
+	var err error
+	switch strings.ToLower(opts.Format) {
+	case FoodImportFormatCSV:
+		err = readOFFCSV(r, handle)
+	case FoodImportFormatJSONL:
+		err = readOFFJSONL(r, handle, result)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", opts.Format)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (s *FoodImportService) importProduct(ctx context.Context, p offProduct, opts FoodImportOptions, result *FoodImportResult) error {
+	food, reason := p.toFood(opts)
+	if reason != "" {
+		result.reject(p.line, p.code, reason)
+		return nil
+	}
+
+	existing, err := s.catalog.GetCatalogFoodByBarcode(ctx, barcodeVariants(*food.BarCode)...)
+	if err != nil && err.Error() != "food not found" {
+		return err
+	}
+
+	if existing == nil {
+		if err := s.catalog.CreateCatalogFood(ctx, food); err != nil {
+			return err
+		}
+		result.Created++
+		return nil
+	}
+
+	if existing.Verified && !food.Verified {
+		result.Kept++
+		return nil
+	}
+
+	food.ID = existing.ID
+	if err := s.catalog.UpdateCatalogFood(ctx, food); err != nil {
+		return err
+	}
+	result.Updated++
+	return nil
+}
+
+func (r *FoodImportResult) reject(line int, code, reason string) {
+	r.Skipped++
+	if len(r.Errors) < maxFoodImportErrors {
+		r.Errors = append(r.Errors, FoodImportError{Line: line, Code: code, Reason: reason})
+	}
+}
+
+// toFood maps the product onto a catalog food with nutrients per 100g. It
+// returns a reason instead of a food when the product cannot be used.
+func (p offProduct) toFood(opts FoodImportOptions) (*models.Food, string) {
+	barcode, err := NormalizeBarcode(p.code)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	name := strings.TrimSpace(p.name)
+	if name == "" {
+		return nil, "product has no name"
+	}
+
+	calories, ok := p.nutriments["energy-kcal_100g"]
+	if !ok {
+		kj, hasKJ := p.nutriments["energy_100g"]
+		if !hasKJ {
+			return nil, "product has no energy value"
+		}
+		calories = kj / 4.184
+	}
+	if calories < 0 || calories > 900 {
+		return nil, fmt.Sprintf("energy of %.0f kcal per 100g is out of range", calories)
+	}
+
+	grams := map[string]float64{}
+	for _, key := range []string{"proteins_100g", "carbohydrates_100g", "fat_100g", "saturated-fat_100g", "fiber_100g", "sugars_100g"} {
+		v := p.nutriments[key]
+		if v < 0 || v > 100 {
+			return nil, fmt.Sprintf("%s of %.1f g is out of range", key, v)
+		}
+		grams[key] = v
+	}
+
+	// Open Food Facts reports minerals in grams; foods store milligrams.
+	// Sodium is derived from salt when only salt is given.
+	sodium, ok := p.nutriments["sodium_100g"]
+	if !ok {
+		sodium = p.nutriments["salt_100g"] / 2.5
+	}
+
+	food := &models.Food{
+		Name:         name,
+		BarCode:      &barcode,
+		Barcode:      &barcode,
+		ServingSize:  strings.TrimSpace(p.servingSize),
+		Calories:     roundTo(calories, 1),
+		Protein:      grams["proteins_100g"],
+		Carbs:        grams["carbohydrates_100g"],
+		Fat:          grams["fat_100g"],
+		SaturatedFat: grams["saturated-fat_100g"],
+		Fiber:        grams["fiber_100g"],
+		Sugar:        grams["sugars_100g"],
+		Sodium:       int(sodium*1000 + 0.5),
+		Cholesterol:  roundTo(p.nutriments["cholesterol_100g"]*1000, 1),
+		Potassium:    roundTo(p.nutriments["potassium_100g"]*1000, 1),
+		SourceType:   repositories.FoodSourceCatalog,
+		DataSource:   &opts.DataSource,
+		Verified:     opts.Verified || p.checked(),
+	}
+	food.IsVerified = food.Verified
+
+	// Several brands are comma separated; the first is the product's own
+	if brand := strings.TrimSpace(strings.Split(p.brands, ",")[0]); brand != "" {
+		food.Brand = &brand
+	}
+
+	return food, ""
+}
+
+// checked reports whether the source's contributors have verified the product
+func (p offProduct) checked() bool {
+	for _, state := range p.states {
+		if strings.TrimSpace(state) == "en:checked" {
+			return true
+		}
+	}
+	return false
+}
+
+// offNutrimentColumns are the per-100g columns read from CSV exports
+var offNutrimentColumns = []string{
+	"energy-kcal_100g", "energy_100g", "proteins_100g", "carbohydrates_100g", "fat_100g",
+	"saturated-fat_100g", "fiber_100g", "sugars_100g", "sodium_100g", "salt_100g",
+	"cholesterol_100g", "potassium_100g",
+}
+
+// readOFFCSV parses the Open Food Facts CSV export, which is tab separated;
+// comma separated files with the same headers are accepted too
+func readOFFCSV(r io.Reader, handle func(offProduct) error) error {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return fmt.Errorf("failed to read import file: %w", err)
+	}
+	firstLine := string(header)
+	if i := strings.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	reader := csv.NewReader(br)
+	if strings.Contains(firstLine, "\t") {
+		reader.Comma = '\t'
+	}
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	columns, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read import header: %w", err)
+	}
+	index := map[string]int{}
+	for i, name := range columns {
+		index[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := index["code"]; !ok {
+		return fmt.Errorf("import header has no code column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read import line %d: %w", line, err)
+		}
+
+		p := offProduct{
+			line:        line,
+			code:        field(record, "code"),
+			name:        field(record, "product_name"),
+			brands:      field(record, "brands"),
+			servingSize: field(record, "serving_size"),
+			nutriments:  map[string]float64{},
+		}
+		if p.name == "" {
+			p.name = field(record, "generic_name")
+		}
+		if states := field(record, "states_tags"); states != "" {
+			p.states = strings.Split(states, ",")
+		}
+		for _, column := range offNutrimentColumns {
+			if v, ok := parseNutriment(field(record, column)); ok {
+				p.nutriments[column] = v
+			}
+		}
+
+		if err := handle(p); err != nil {
+			return err
+		}
+	}
+}
+
+// readOFFJSONL parses the Open Food Facts JSONL export, one product per line
+func readOFFJSONL(r io.Reader, handle func(offProduct) error, result *FoodImportResult) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), maxJSONLProductSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var record struct {
+			Code        json.RawMessage        `json:"code"`
+			ProductName string                 `json:"product_name"`
+			GenericName string                 `json:"generic_name"`
+			Brands      string                 `json:"brands"`
+			ServingSize string                 `json:"serving_size"`
+			StatesTags  []string               `json:"states_tags"`
+			Nutriments  map[string]interface{} `json:"nutriments"`
+		}
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			result.Read++
+			result.reject(line, "", "malformed JSON: "+err.Error())
+			continue
+		}
+
+		// Codes are usually strings but some dumps store them as numbers
+		code := strings.Trim(string(record.Code), `"`)
+
+		p := offProduct{
+			line:        line,
+			code:        code,
+			name:        record.ProductName,
+			brands:      record.Brands,
+			servingSize: record.ServingSize,
+			states:      record.StatesTags,
+			nutriments:  map[string]float64{},
+		}
+		if p.name == "" {
+			p.name = record.GenericName
+		}
+		for key, value := range record.Nutriments {
+			switch v := value.(type) {
+			case float64:
+				p.nutriments[key] = v
+			case string:
+				if f, ok := parseNutriment(v); ok {
+					p.nutriments[key] = f
+				}
+			}
+		}
+
+		if err := handle(p); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read import file: %w", err)
+	}
+	return nil
+}
+
+func parseNutriment(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
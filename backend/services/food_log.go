@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+// maxLoggedGrams caps a single entry, matching models.UserFoodLog validation
+const maxLoggedGrams = 10000
+
+// ErrInvalidFoodLog is wrapped by LogFood errors caused by the request
+// rather than by storage, e.g. an unknown unit
+var ErrInvalidFoodLog = errors.New("invalid food log")
+
+// LogFoodInput is a food entry as entered by the user
+type LogFoodInput struct {
+	FoodID     uint
+	MealType   string
+	Quantity   float64
+	Unit       string
+	ConsumedAt time.Time
+	Notes      *string
+}
+
+// LogRecipeInput is a recipe entry as entered by the user
+type LogRecipeInput struct {
+	RecipeID   string
+	MealType   string
+	Servings   float64
+	ConsumedAt time.Time
+	Notes      *string
+}
+
+// FoodLogService records eaten foods with quantities normalized to grams
+type FoodLogService struct {
+	foods   *repositories.FoodRepository
+	recipes *repositories.RecipeRepository
+	logs    *repositories.FoodLogRepository
+}
+
+// NewFoodLogService creates a new FoodLogService instance
+func NewFoodLogService(db *sql.DB) *FoodLogService {
+	wrapped := database.NewDatabase(db)
+	return &FoodLogService{
+		foods:   repositories.NewFoodRepository(db),
+		recipes: repositories.NewRecipeRepository(wrapped),
+		logs:    repositories.NewFoodLogRepository(wrapped),
+	}
+}
+
+// LogFood converts the entered quantity to grams, computes nutrition from
+// the food's per-100g values and stores the entry
+func (s *FoodLogService) LogFood(ctx context.Context, userID int, input LogFoodInput) (*models.UserFoodLog, error) {
+	if err := validateMealType(input.MealType); err != nil {
+		return nil, err
+	}
+
+	food, err := s.foods.GetFoodByID(strconv.FormatUint(uint64(input.FoodID), 10), strconv.Itoa(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	grams, err := s.ToGrams(ctx, food, input.Quantity, input.Unit)
+	if err != nil {
+		return nil, err
+	}
+	if grams > maxLoggedGrams {
+		return nil, fmt.Errorf("%w: quantity cannot exceed %d grams", ErrInvalidFoodLog, maxLoggedGrams)
+	}
+
+	consumedAt := input.ConsumedAt
+	if consumedAt.IsZero() {
+		consumedAt = time.Now()
+	}
+
+	unit, _ := NormalizeUnit(input.Unit)
+	entry := &models.UserFoodLog{
+		UserID:        userID,
+		FoodID:        int(food.ID),
+		FoodName:      food.Name,
+		Quantity:      grams,
+		MealType:      input.MealType,
+		LoggedAt:      consumedAt,
+		InputQuantity: input.Quantity,
+		InputUnit:     unit,
+		Nutrition:     *food.CalculateNutrition(grams),
+		Notes:         input.Notes,
+	}
+
+	if err := s.logs.CreateFoodLog(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// LogRecipe stores servings of a recipe using its per-serving nutrition
+func (s *FoodLogService) LogRecipe(ctx context.Context, userID int, input LogRecipeInput) (*models.UserFoodLog, error) {
+	if err := validateMealType(input.MealType); err != nil {
+		return nil, err
+	}
+	if input.Servings <= 0 {
+		return nil, fmt.Errorf("%w: servings must be greater than 0", ErrInvalidFoodLog)
+	}
+
+	recipe, err := s.recipes.GetRecipeByID(ctx, input.RecipeID)
+	if err != nil {
+		return nil, err
+	}
+	if recipe.NutritionPerServing == nil {
+		return nil, fmt.Errorf("%w: recipe %s has no nutrition per serving", ErrInvalidFoodLog, recipe.Name)
+	}
+
+	consumedAt := input.ConsumedAt
+	if consumedAt.IsZero() {
+		consumedAt = time.Now()
+	}
+
+	entry := &models.UserFoodLog{
+		UserID:        userID,
+		RecipeID:      &recipe.ID,
+		FoodName:      recipe.Name,
+		Quantity:      input.Servings,
+		MealType:      input.MealType,
+		LoggedAt:      consumedAt,
+		InputQuantity: input.Servings,
+		InputUnit:     UnitServing,
+		Nutrition:     recipe.NutritionPerServing.Scaled(input.Servings),
+		Notes:         input.Notes,
+	}
+
+	if err := s.logs.CreateFoodLog(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// ToGrams converts a quantity of the given food to grams using the food's
+// portion weights
+func (s *FoodLogService) ToGrams(ctx context.Context, food *models.Food, quantity float64, unit string) (float64, error) {
+	portions, err := s.logs.GetFoodPortions(ctx, food.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	grams, err := ConvertToGrams(quantity, unit, food, portions)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidFoodLog, err)
+	}
+
+	return grams, nil
+}
+
+// GetFoodPortions lists the household unit weights defined for a food
+func (s *FoodLogService) GetFoodPortions(ctx context.Context, foodID uint) ([]models.FoodPortion, error) {
+	return s.logs.GetFoodPortions(ctx, foodID)
+}
+
+// SetFoodPortion defines how many grams one unit of a food weighs
+func (s *FoodLogService) SetFoodPortion(ctx context.Context, portion *models.FoodPortion) error {
+	unit, err := NormalizeUnit(portion.Unit)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFoodLog, err)
+	}
+	if IsMassUnit(unit) {
+		return fmt.Errorf("%w: %s is already a weight unit", ErrInvalidFoodLog, unit)
+	}
+	if portion.Grams <= 0 {
+		return fmt.Errorf("%w: grams must be greater than 0", ErrInvalidFoodLog)
+	}
+
+	portion.Unit = unit
+	return s.logs.UpsertFoodPortion(ctx, portion)
+}
+
+func validateMealType(mealType string) error {
+	switch mealType {
+	case "breakfast", "lunch", "dinner", "snack":
+		return nil
+	}
+	return fmt.Errorf("%w: meal_type must be one of breakfast, lunch, dinner, snack", ErrInvalidFoodLog)
+}
@@ -1,12 +1,14 @@
 package services
 
 import (
+	"database/sql"
 	"log"
 )
 
 // InitializeServices initializes all services
-func InitializeServices() {
+func InitializeServices(db *sql.DB) {
 	log.Println("Initializing services...")
 	InitializeStorage()
+	InitializeSQLStorage(db)
 	log.Println("Services initialized successfully")
 }
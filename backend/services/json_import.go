@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+// JSONImportError describes a record ImportJSONStores skipped
+type JSONImportError struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// JSONImportResult summarizes the import of one JSON store file
+type JSONImportResult struct {
+	File     string            `json:"file"`
+	Missing  bool              `json:"missing,omitempty"`
+	Read     int               `json:"read"`
+	Imported int               `json:"imported"`
+	Existing int               `json:"existing"`
+	Skipped  int               `json:"skipped"`
+	Errors   []JSONImportError `json:"errors,omitempty"`
+}
+
+// errSkipRecord marks a record that cannot be imported; the import carries on
+var errSkipRecord = errors.New("record skipped")
+
+// jsonStore is one file of the JSON store and how to import its records
+type jsonStore struct {
+	file         string
+	key          string
+	importRecord func(ctx context.Context, raw json.RawMessage) (id string, inserted bool, err error)
+}
+
+// ImportJSONStores copies meals.json, supplements.json, medical-plans.json
+// and sessions.json from dataDir into the database. Records are keyed by
+// their existing IDs and ones already in the database are left untouched,
+// so the import can be re-run safely. Records that do not decode or have no
+// id or user_id are skipped and reported; a missing file is reported but is
+// not an error.
+func ImportJSONStores(ctx context.Context, db *sql.DB, dataDir string) ([]JSONImportResult, error) {
+	wrapped := database.NewDatabase(db)
+	meals := repositories.NewMealEntryRepository(wrapped)
+	supplements := repositories.NewSupplementRepository(wrapped)
+	plans := repositories.NewMedicalPlanRepository(wrapped)
+	sessions := repositories.NewSessionRepository(wrapped)
+
+	stores := []jsonStore{
+		{file: "meals.json", key: "meals", importRecord: func(ctx context.Context, raw json.RawMessage) (string, bool, error) {
+			var meal models.MealEntry
+			if err := decodeJSONRecord(raw, &meal, &meal.ID, &meal.UserID); err != nil {
+				return meal.ID, false, err
+			}
+			defaultTimestamps(&meal.CreatedAt, &meal.UpdatedAt)
+			inserted, err := meals.ImportMealEntry(ctx, &meal)
+			return meal.ID, inserted, err
+		}},
+		{file: "supplements.json", key: "supplements", importRecord: func(ctx context.Context, raw json.RawMessage) (string, bool, error) {
+			var supplement models.Supplement
+			if err := decodeJSONRecord(raw, &supplement, &supplement.ID, &supplement.UserID); err != nil {
+				return supplement.ID, false, err
+			}
+			defaultTimestamps(&supplement.CreatedAt, &supplement.UpdatedAt)
+			inserted, err := supplements.ImportSupplement(ctx, &supplement)
+			return supplement.ID, inserted, err
+		}},
+		{file: "medical-plans.json", key: "medical_plans", importRecord: func(ctx context.Context, raw json.RawMessage) (string, bool, error) {
+			var plan models.MedicalPlan
+			if err := decodeJSONRecord(raw, &plan, &plan.ID, &plan.UserID); err != nil {
+				return plan.ID, false, err
+			}
+			defaultTimestamps(&plan.CreatedAt, &plan.UpdatedAt)
+			inserted, err := plans.ImportMedicalPlan(ctx, &plan)
+			return plan.ID, inserted, err
+		}},
+		{file: "sessions.json", key: "sessions", importRecord: func(ctx context.Context, raw json.RawMessage) (string, bool, error) {
+			var session models.Session
+			if err := decodeJSONRecord(raw, &session, &session.ID, &session.UserID); err != nil {
+				return session.ID, false, err
+			}
+			defaultTimestamps(&session.CreatedAt, &session.LastUsedAt)
+			// sessions.json held raw refresh tokens; the database only keeps hashes
+			if session.RefreshToken != "" {
+				session.RefreshToken = hashRefreshToken(session.RefreshToken)
+			}
+			inserted, err := sessions.ImportSession(ctx, &session)
+			return session.ID, inserted, err
+		}},
+	}
+
+	var results []JSONImportResult
+	for _, store := range stores {
+		result, err := importJSONStore(ctx, filepath.Join(dataDir, store.file), store)
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func importJSONStore(ctx context.Context, path string, store jsonStore) (JSONImportResult, error) {
+	result := JSONImportResult{File: store.file}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			result.Missing = true
+			return result, nil
+		}
+		return result, fmt.Errorf("failed to read %s: %w", store.file, err)
+	}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return result, fmt.Errorf("failed to parse %s: %w", store.file, err)
+	}
+	var records []json.RawMessage
+	if raw, ok := document[store.key]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &records); err != nil {
+			return result, fmt.Errorf("failed to parse %s: %q is not an array", store.file, store.key)
+		}
+	}
+
+	for i, raw := range records {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Read++
+
+		id, inserted, err := store.importRecord(ctx, raw)
+		switch {
+		case errors.Is(err, errSkipRecord):
+			result.Skipped++
+			result.Errors = append(result.Errors, JSONImportError{Index: i, ID: id, Reason: err.Error()})
+		case err != nil:
+			return result, fmt.Errorf("%s record %d: %w", store.file, i, err)
+		case inserted:
+			result.Imported++
+		default:
+			result.Existing++
+		}
+	}
+
+	return result, nil
+}
+
+// decodeJSONRecord decodes one record and requires its id and user_id
+func decodeJSONRecord(raw json.RawMessage, record interface{}, id, userID *string) error {
+	if err := json.Unmarshal(raw, record); err != nil {
+		return fmt.Errorf("%w: %v", errSkipRecord, err)
+	}
+	if *id == "" {
+		return fmt.Errorf("%w: missing id", errSkipRecord)
+	}
+	if *userID == "" {
+		return fmt.Errorf("%w: missing user_id", errSkipRecord)
+	}
+	return nil
+}
+
+// defaultTimestamps fills timestamps older files left out
+func defaultTimestamps(created, updated *time.Time) {
+	if created.IsZero() {
+		*created = time.Now()
+	}
+	if updated.IsZero() {
+		*updated = *created
+	}
+}
@@ -1,43 +1,26 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"nutrition-platform/models"
+
 	"github.com/google/uuid"
 )
 
 // Meal represents a meal entry
-type Meal struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Calories    int       `json:"calories"`
-	Protein     float64   `json:"protein"`
-	Carbs       float64   `json:"carbs"`
-	Fat         float64   `json:"fat"`
-	Fiber       float64   `json:"fiber"`
-	Sugar       float64   `json:"sugar"`
-	Sodium      float64   `json:"sodium"`
-	MealType    string    `json:"meal_type"` // breakfast, lunch, dinner, snack
-	Ingredients []string  `json:"ingredients"`
-	IsHalal     bool      `json:"is_halal"`
-	Tags        []string  `json:"tags"`
-	ImageURL    string    `json:"image_url,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-}
+type Meal = models.MealEntry
 
-// MealData represents the structure of meals.json
+// MealData represents the structure of meals.json, which ImportJSONStores
+// reads when moving meals into the database
 type MealData struct {
 	Meals    []Meal   `json:"meals"`
 	Metadata Metadata `json:"metadata"`
 }
 
-const mealsFile = "backend/data/meals.json"
-
 // CreateMeal creates a new meal
 func CreateMeal(meal *Meal) error {
 	// Generate ID and timestamps
@@ -59,109 +42,69 @@ func CreateMeal(meal *Meal) error {
 	// Auto-detect halal status based on ingredients
 	meal.IsHalal = isHalalMeal(meal.Ingredients)
 
-	return AppendJSON(mealsFile, meal)
+	return mealStore.CreateMealEntry(context.Background(), meal)
 }
 
 // GetMealsByUserID retrieves all meals for a specific user
 func GetMealsByUserID(userID string) ([]Meal, error) {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
+	meals, err := mealStore.GetMealEntriesByUserID(context.Background(), userID)
 	if err != nil {
 		return nil, err
 	}
-
-	var userMeals []Meal
-	for _, meal := range data.Meals {
-		if meal.UserID == userID {
-			userMeals = append(userMeals, meal)
-		}
-	}
-
-	return userMeals, nil
+	return derefMeals(meals), nil
 }
 
 // GetMealByID retrieves a specific meal by ID
 func GetMealByID(mealID string) (*Meal, error) {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, meal := range data.Meals {
-		if meal.ID == mealID {
-			return &meal, nil
-		}
-	}
-
-	return nil, fmt.Errorf("meal not found")
+	return mealStore.GetMealEntryByID(context.Background(), mealID)
 }
 
 // UpdateMeal updates an existing meal
 func UpdateMeal(mealID string, updatedMeal *Meal) error {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
+	ctx := context.Background()
+	meal, err := mealStore.GetMealEntryByID(ctx, mealID)
 	if err != nil {
 		return err
 	}
 
-	for i, meal := range data.Meals {
-		if meal.ID == mealID {
-			// Preserve original ID and created time
-			updatedMeal.ID = meal.ID
-			updatedMeal.CreatedAt = meal.CreatedAt
-			updatedMeal.UpdatedAt = time.Now()
-
-			// Auto-detect halal status
-			updatedMeal.IsHalal = isHalalMeal(updatedMeal.Ingredients)
+	// Preserve original ID, owner and created time
+	updatedMeal.ID = meal.ID
+	updatedMeal.UserID = meal.UserID
+	updatedMeal.CreatedAt = meal.CreatedAt
+	updatedMeal.UpdatedAt = time.Now()
 
-			data.Meals[i] = *updatedMeal
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(mealsFile, data)
-		}
-	}
+	// Auto-detect halal status
+	updatedMeal.IsHalal = isHalalMeal(updatedMeal.Ingredients)
 
-	return fmt.Errorf("meal not found")
+	return mealStore.UpdateMealEntry(ctx, updatedMeal)
 }
 
 // DeleteMeal deletes a meal
 func DeleteMeal(mealID string, userID string) error {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
+	ctx := context.Background()
+	meal, err := mealStore.GetMealEntryByID(ctx, mealID)
 	if err != nil {
 		return err
 	}
 
-	for i, meal := range data.Meals {
-		if meal.ID == mealID {
-			// Check if user owns this meal
-			if meal.UserID != userID {
-				return fmt.Errorf("unauthorized: meal belongs to another user")
-			}
-
-			// Remove meal from slice
-			data.Meals = append(data.Meals[:i], data.Meals[i+1:]...)
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(mealsFile, data)
-		}
+	// Check if user owns this meal
+	if meal.UserID != userID {
+		return fmt.Errorf("unauthorized: meal belongs to another user")
 	}
 
-	return fmt.Errorf("meal not found")
+	return mealStore.DeleteMealEntry(ctx, mealID, userID)
 }
 
 // GetMealsByType retrieves meals by meal type for a user
 func GetMealsByType(userID, mealType string) ([]Meal, error) {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
+	meals, err := GetMealsByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	var filteredMeals []Meal
-	for _, meal := range data.Meals {
-		if meal.UserID == userID && meal.MealType == mealType {
+	for _, meal := range meals {
+		if meal.MealType == mealType {
 			filteredMeals = append(filteredMeals, meal)
 		}
 	}
@@ -171,60 +114,20 @@ func GetMealsByType(userID, mealType string) ([]Meal, error) {
 
 // GetMealsByDate retrieves meals for a specific date
 func GetMealsByDate(userID string, date time.Time) ([]Meal, error) {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
-	if err != nil {
-		return nil, err
-	}
-
 	// Get start and end of the day
 	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
-	var dayMeals []Meal
-	for _, meal := range data.Meals {
-		if meal.UserID == userID && meal.CreatedAt.After(startOfDay) && meal.CreatedAt.Before(endOfDay) {
-			dayMeals = append(dayMeals, meal)
-		}
-	}
-
-	return dayMeals, nil
-}
-
-// CalculateDailyNutrition calculates total nutrition for a day
-func CalculateDailyNutrition(userID string, date time.Time) (map[string]float64, error) {
-	meals, err := GetMealsByDate(userID, date)
+	meals, err := mealStore.GetMealEntriesByDateRange(context.Background(), userID, startOfDay, endOfDay)
 	if err != nil {
 		return nil, err
 	}
-
-	nutrition := map[string]float64{
-		"calories": 0,
-		"protein":  0,
-		"carbs":    0,
-		"fat":      0,
-		"fiber":    0,
-		"sugar":    0,
-		"sodium":   0,
-	}
-
-	for _, meal := range meals {
-		nutrition["calories"] += float64(meal.Calories)
-		nutrition["protein"] += meal.Protein
-		nutrition["carbs"] += meal.Carbs
-		nutrition["fat"] += meal.Fat
-		nutrition["fiber"] += meal.Fiber
-		nutrition["sugar"] += meal.Sugar
-		nutrition["sodium"] += meal.Sodium
-	}
-
-	return nutrition, nil
+	return derefMeals(meals), nil
 }
 
 // SearchMeals searches meals by name, ingredients, or tags
 func SearchMeals(userID, query string) ([]Meal, error) {
-	var data MealData
-	err := ReadJSON(mealsFile, &data)
+	meals, err := GetMealsByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -232,10 +135,7 @@ func SearchMeals(userID, query string) ([]Meal, error) {
 	var results []Meal
 	queryLower := strings.ToLower(query)
 
-	for _, meal := range data.Meals {
-		if meal.UserID != userID {
-			continue
-		}
+	for _, meal := range meals {
 
 		// Search in name
 		if strings.Contains(strings.ToLower(meal.Name), queryLower) {
@@ -334,3 +234,11 @@ func GetMealStats(userID string) (map[string]interface{}, error) {
 
 	return stats, nil
 }
+
+func derefMeals(meals []*Meal) []Meal {
+	values := make([]Meal, 0, len(meals))
+	for _, meal := range meals {
+		values = append(values, *meal)
+	}
+	return values
+}
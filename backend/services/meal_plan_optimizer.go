@@ -0,0 +1,808 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+// DefaultHalalBlacklistPath is where the halal ingredient blacklist is read from
+const DefaultHalalBlacklistPath = "data/halal_blacklist.json"
+
+const (
+	// DefaultMealPlanTolerance is the allowed relative deviation from each daily target
+	DefaultMealPlanTolerance = 0.10
+	// DefaultMealPlanMaxRepeats is how often a meal may appear in one plan
+	DefaultMealPlanMaxRepeats = 2
+	defaultMealPlanDays       = 7
+	maxMealPlanDays           = 14
+	// mealPlanCatalogLimit bounds how many recipes and foods are considered
+	mealPlanCatalogLimit = 300
+	// maxSidesPerDay bounds the foods added next to the main dishes to close
+	// macro gaps
+	maxSidesPerDay = 4
+)
+
+var (
+	// ErrInvalidMealPlanRequest is wrapped by errors caused by a meal plan
+	// generation or update request
+	ErrInvalidMealPlanRequest = errors.New("invalid meal plan request")
+	// ErrInsufficientCatalog is returned when no recipe or food passes the
+	// user's dietary requirements
+	ErrInsufficientCatalog = errors.New("not enough recipes or foods match the dietary requirements")
+)
+
+// mealPlanSlots are the meals planned every day and their share of the
+// day's targets. Single foods only make a meal on their own as a snack;
+// elsewhere they are sides to a recipe.
+var mealPlanSlots = []struct {
+	mealType    string
+	share       float64
+	foodsAsMain bool
+}{
+	{"breakfast", 0.25, false},
+	{"lunch", 0.35, false},
+	{"dinner", 0.30, false},
+	{"snack", 0.10, true},
+}
+
+// MealPlanTargets are the daily energy and macronutrient targets of a plan
+type MealPlanTargets struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+}
+
+// MealPlanTargetsFromMacros converts a computed macro breakdown into plan targets
+func MealPlanTargetsFromMacros(calories float64, macros MacroBreakdown) MealPlanTargets {
+	return MealPlanTargets{Calories: calories, Protein: macros.Protein, Carbs: macros.Carbs, Fat: macros.Fat}
+}
+
+// MealPlanRequest describes the plan to generate
+type MealPlanRequest struct {
+	Name      string
+	StartDate time.Time
+	// Days defaults to seven
+	Days int
+	// Targets with a zero calorie value are taken from the user's active
+	// nutrition goal; missing macros are derived from the calories
+	Targets MealPlanTargets
+	// Tolerance is the allowed relative deviation, 0.10 meaning ±10%
+	Tolerance    float64
+	Allergies    []string
+	Restrictions []string
+	// Preferences are soft: matching recipe tags or cuisines are favored
+	Preferences []string
+	Halal       bool
+	// MaxRepeats is how often the same main dish may appear in the plan
+	MaxRepeats int
+}
+
+// MealPlanDayFit reports how close a planned day comes to the targets
+type MealPlanDayFit struct {
+	DayNumber int                  `json:"day_number"`
+	Totals    models.NutritionInfo `json:"totals"`
+	// Deviation is the relative difference from each target in percent
+	Deviation       map[string]float64 `json:"deviation"`
+	WithinTolerance bool               `json:"within_tolerance"`
+}
+
+// GeneratedMealPlan is a persisted plan with its fit against the targets
+type GeneratedMealPlan struct {
+	Plan                 *models.MealPlan `json:"plan"`
+	Targets              MealPlanTargets  `json:"targets"`
+	Tolerance            float64          `json:"tolerance"`
+	Days                 []MealPlanDayFit `json:"days"`
+	DaysWithinTolerance  int              `json:"days_within_tolerance"`
+	CandidatesConsidered int              `json:"candidates_considered"`
+}
+
+// planCandidate is a recipe or food that can fill a meal, with nutrition for
+// one base quantity (a serving or 100 g)
+type planCandidate struct {
+	key          string
+	name         models.BilingualText
+	recipeID     *string
+	foodID       *uint
+	unit         string
+	baseQuantity float64
+	step         float64
+	minScale     float64
+	maxScale     float64
+	nutrition    models.NutritionInfo
+	preferred    bool
+}
+
+// plannedItem is a candidate placed in a meal slot at some scale
+type plannedItem struct {
+	candidate *planCandidate
+	slot      int
+	scale     float64
+	side      bool
+}
+
+// MealPlanOptimizer builds meal plans from the recipe and food catalog that
+// meet daily calorie and macro targets
+type MealPlanOptimizer struct {
+	recipes *repositories.RecipeRepository
+	foods   *repositories.FoodRepository
+	plans   *repositories.MealPlanRepository
+	users   *repositories.UserRepository
+	halal   *HalalCompliance
+}
+
+// NewMealPlanOptimizer creates a new MealPlanOptimizer instance. halal may be
+// nil, in which case recipes' own halal flag and a built-in list of haram
+// ingredients are used for halal screening.
+func NewMealPlanOptimizer(db *sql.DB, halal *HalalCompliance) *MealPlanOptimizer {
+	wrapped := database.NewDatabase(db)
+	return &MealPlanOptimizer{
+		recipes: repositories.NewRecipeRepository(wrapped),
+		foods:   repositories.NewFoodRepository(db),
+		plans:   repositories.NewMealPlanRepository(wrapped),
+		users:   repositories.NewUserRepository(wrapped),
+		halal:   halal,
+	}
+}
+
+// GeneratePlan picks a main dish for every meal of every day, scales the
+// portions towards the targets and adds side foods where the mains alone
+// cannot reach them. Dishes are rotated so none appears more than
+// MaxRepeats times unless the catalog leaves no alternative. The plan is
+// stored as a meal plan owned by the user.
+func (o *MealPlanOptimizer) GeneratePlan(ctx context.Context, userID int, req MealPlanRequest) (*GeneratedMealPlan, error) {
+	if err := o.normalizeRequest(userID, &req); err != nil {
+		return nil, err
+	}
+
+	mains, sides, err := o.loadCandidates(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(mains) == 0 {
+		return nil, ErrInsufficientCatalog
+	}
+
+	result := &GeneratedMealPlan{
+		Targets:              req.Targets,
+		Tolerance:            req.Tolerance,
+		CandidatesConsidered: len(mains),
+	}
+
+	start := time.Date(req.StartDate.Year(), req.StartDate.Month(), req.StartDate.Day(), 0, 0, 0, 0, req.StartDate.Location())
+	end := start.AddDate(0, 0, req.Days-1)
+	plan := &models.MealPlan{
+		UserID:    userID,
+		Name:      req.Name,
+		StartDate: &start,
+		EndDate:   &end,
+		Days:      make([]models.MealPlanDay, 0, req.Days),
+		IsActive:  true,
+	}
+	description := fmt.Sprintf("Daily targets: %.0f kcal, %.0f g protein, %.0f g carbs, %.0f g fat (±%.0f%%)",
+		req.Targets.Calories, req.Targets.Protein, req.Targets.Carbs, req.Targets.Fat, req.Tolerance*100)
+	plan.Description = &description
+
+	uses := map[string]int{}
+	previous := make([]string, len(mealPlanSlots))
+	for day := 0; day < req.Days; day++ {
+		items := o.planDay(req, mains, sides, uses, previous)
+		for _, item := range items {
+			if !item.side {
+				uses[item.candidate.key]++
+				previous[item.slot] = item.candidate.key
+			}
+		}
+
+		date := start.AddDate(0, 0, day)
+		planDay := buildPlanDay(day+1, &date, items)
+		plan.Days = append(plan.Days, planDay)
+
+		fit := dayFit(day+1, planDay.Totals(), req.Targets, req.Tolerance)
+		if fit.WithinTolerance {
+			result.DaysWithinTolerance++
+		}
+		result.Days = append(result.Days, fit)
+	}
+
+	if err := o.plans.CreateMealPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	result.Plan = plan
+
+	return result, nil
+}
+
+// normalizeRequest fills defaults, resolves the targets and validates the request
+func (o *MealPlanOptimizer) normalizeRequest(userID int, req *MealPlanRequest) error {
+	if req.Days == 0 {
+		req.Days = defaultMealPlanDays
+	}
+	if req.Days < 1 || req.Days > maxMealPlanDays {
+		return fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidMealPlanRequest, maxMealPlanDays)
+	}
+	if req.Tolerance == 0 {
+		req.Tolerance = DefaultMealPlanTolerance
+	}
+	if req.Tolerance < 0.02 || req.Tolerance > 0.5 {
+		return fmt.Errorf("%w: tolerance must be between 0.02 and 0.5", ErrInvalidMealPlanRequest)
+	}
+	if req.MaxRepeats == 0 {
+		req.MaxRepeats = DefaultMealPlanMaxRepeats
+	}
+	if req.MaxRepeats < 1 {
+		return fmt.Errorf("%w: max repeats must be at least 1", ErrInvalidMealPlanRequest)
+	}
+	if req.StartDate.IsZero() {
+		req.StartDate = time.Now()
+	}
+	for _, restriction := range req.Restrictions {
+		if _, ok := dietaryRestrictionRules[normalizeDietTag(restriction)]; !ok {
+			return fmt.Errorf("%w: unsupported dietary restriction %q", ErrInvalidMealPlanRequest, restriction)
+		}
+	}
+
+	if req.Targets.Calories == 0 {
+		goals, err := o.users.GetActiveNutritionGoals(uint(userID))
+		if err != nil {
+			return err
+		}
+		goal := goalForDay(goals, req.StartDate)
+		if goal == nil || goal.DailyCalories == nil {
+			return fmt.Errorf("%w: a calorie target is required when no nutrition goal is set", ErrInvalidMealPlanRequest)
+		}
+		req.Targets.Calories = float64(*goal.DailyCalories)
+		if goal.ProteinGrams != nil && req.Targets.Protein == 0 {
+			req.Targets.Protein = *goal.ProteinGrams
+		}
+		if goal.CarbsGrams != nil && req.Targets.Carbs == 0 {
+			req.Targets.Carbs = *goal.CarbsGrams
+		}
+		if goal.FatGrams != nil && req.Targets.Fat == 0 {
+			req.Targets.Fat = *goal.FatGrams
+		}
+	}
+	if req.Targets.Calories < 800 || req.Targets.Calories > 6000 {
+		return fmt.Errorf("%w: calorie target must be between 800 and 6000", ErrInvalidMealPlanRequest)
+	}
+	if req.Targets.Protein < 0 || req.Targets.Carbs < 0 || req.Targets.Fat < 0 {
+		return fmt.Errorf("%w: macro targets cannot be negative", ErrInvalidMealPlanRequest)
+	}
+
+	// Missing macros take a balanced 25/45/30 split of the calories
+	if req.Targets.Protein == 0 {
+		req.Targets.Protein = roundTo(req.Targets.Calories*0.25/4, 0)
+	}
+	if req.Targets.Carbs == 0 {
+		req.Targets.Carbs = roundTo(req.Targets.Calories*0.45/4, 0)
+	}
+	if req.Targets.Fat == 0 {
+		req.Targets.Fat = roundTo(req.Targets.Calories*0.30/9, 0)
+	}
+
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("%d-day plan (%.0f kcal)", req.Days, req.Targets.Calories)
+	}
+
+	return nil
+}
+
+// loadCandidates reads the catalog and keeps what the user may eat. Recipes
+// and foods can both be main dishes; only foods are used as sides.
+func (o *MealPlanOptimizer) loadCandidates(ctx context.Context, userID int, req MealPlanRequest) ([]*planCandidate, []*planCandidate, error) {
+	owner := strconv.Itoa(userID)
+	recipes, err := o.recipes.ListPlannableRecipes(ctx, owner, mealPlanCatalogLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	foods, err := o.foods.ListPlannableFoods(owner, mealPlanCatalogLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preferences := map[string]bool{}
+	for _, p := range req.Preferences {
+		preferences[normalizeDietTag(p)] = true
+	}
+
+	var mains, sides []*planCandidate
+	for _, recipe := range recipes {
+		if recipe.NutritionPerServing == nil || recipe.NutritionPerServing.Calories <= 0 {
+			continue
+		}
+		if !o.recipeAllowed(recipe, req) {
+			continue
+		}
+
+		candidate := &planCandidate{
+			key:          "recipe:" + recipe.ID,
+			name:         models.BilingualText{En: recipe.Name},
+			recipeID:     &recipe.ID,
+			unit:         UnitServing,
+			baseQuantity: 1,
+			step:         0.25,
+			minScale:     0.5,
+			maxScale:     2,
+			nutrition:    *recipe.NutritionPerServing,
+		}
+		if recipe.NameAr != nil {
+			candidate.name.Ar = *recipe.NameAr
+		}
+		for _, tag := range recipe.DietaryTags {
+			candidate.preferred = candidate.preferred || preferences[normalizeDietTag(tag)]
+		}
+		if recipe.Cuisine != nil {
+			candidate.preferred = candidate.preferred || preferences[normalizeDietTag(*recipe.Cuisine)]
+		}
+		mains = append(mains, candidate)
+	}
+
+	for _, food := range foods {
+		if !o.textAllowed([]string{food.Name}, nil, req) {
+			continue
+		}
+
+		id := food.ID
+		candidate := &planCandidate{
+			key:          "food:" + strconv.FormatUint(uint64(food.ID), 10),
+			name:         models.BilingualText{En: food.Name},
+			foodID:       &id,
+			unit:         UnitGram,
+			baseQuantity: 100,
+			step:         0.05,
+			minScale:     0.3,
+			maxScale:     2.5,
+			nutrition:    *food.CalculateNutrition(100),
+		}
+		mains = append(mains, candidate)
+		sides = append(sides, candidate)
+	}
+
+	return mains, sides, nil
+}
+
+// recipeAllowed applies the halal, allergy and restriction screens to a recipe
+func (o *MealPlanOptimizer) recipeAllowed(recipe *models.Recipe, req MealPlanRequest) bool {
+	if req.Halal && !recipe.IsHalal {
+		return false
+	}
+
+	for _, allergy := range req.Allergies {
+		for _, allergen := range recipe.Allergens {
+			if normalizeDietTag(allergen) == normalizeDietTag(allergy) {
+				return false
+			}
+		}
+	}
+
+	texts := []string{recipe.Name}
+	for _, ingredient := range recipe.Ingredients {
+		texts = append(texts, ingredient.Name)
+	}
+	return o.textAllowed(texts, recipe.DietaryTags, req) && macrosAllowed(*recipe.NutritionPerServing, req.Restrictions)
+}
+
+// textAllowed screens ingredient or food names. tags are the author's
+// dietary tags, which vouch for a restriction such as "gluten_free".
+func (o *MealPlanOptimizer) textAllowed(texts []string, tags []string, req MealPlanRequest) bool {
+	words := map[string]bool{}
+	for _, text := range texts {
+		for _, word := range ingredientTokens(text) {
+			words[word] = true
+		}
+	}
+	vouched := map[string]bool{}
+	for _, tag := range tags {
+		vouched[normalizeDietTag(tag)] = true
+	}
+	for _, text := range texts {
+		// "Gluten-free bread" vouches for itself
+		lower := normalizeDietTag(text)
+		for restriction := range dietaryRestrictionRules {
+			if strings.Contains(lower, restriction) {
+				vouched[restriction] = true
+			}
+		}
+	}
+
+	for _, allergy := range req.Allergies {
+		keywords, ok := allergenKeywords[normalizeDietTag(allergy)]
+		if !ok {
+			keywords = ingredientTokens(allergy)
+		}
+		if containsAnyWord(words, keywords) {
+			return false
+		}
+	}
+
+	for _, restriction := range req.Restrictions {
+		restriction = normalizeDietTag(restriction)
+		if vouched[restriction] {
+			continue
+		}
+		if containsAnyWord(words, dietaryRestrictionRules[restriction].excluded) {
+			return false
+		}
+	}
+
+	if req.Halal {
+		if o.halal != nil {
+			result, err := o.halal.CheckCompliance(texts, "")
+			if err != nil || !result.IsCompliant {
+				return false
+			}
+		} else if containsAnyWord(words, haramKeywords) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// macrosAllowed applies the carbohydrate limits of low-carb restrictions
+func macrosAllowed(n models.NutritionInfo, restrictions []string) bool {
+	if n.Calories <= 0 {
+		return true
+	}
+	carbShare := n.Carbohydrates * 4 / n.Calories
+	for _, restriction := range restrictions {
+		limit := dietaryRestrictionRules[normalizeDietTag(restriction)].maxCarbShare
+		if limit > 0 && carbShare > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// planDay fills one day: a main dish per slot, then side foods while they
+// bring the day closer to the targets
+func (o *MealPlanOptimizer) planDay(req MealPlanRequest, mains, sides []*planCandidate, uses map[string]int, previous []string) []*plannedItem {
+	var items []*plannedItem
+	usedToday := map[string]bool{}
+	haveRecipes := false
+	for _, candidate := range mains {
+		haveRecipes = haveRecipes || candidate.recipeID != nil
+	}
+
+	for slot, s := range mealPlanSlots {
+		slotTarget := MealPlanTargets{
+			Calories: req.Targets.Calories * s.share,
+			Protein:  req.Targets.Protein * s.share,
+			Carbs:    req.Targets.Carbs * s.share,
+			Fat:      req.Targets.Fat * s.share,
+		}
+
+		var best *plannedItem
+		bestCost := math.Inf(1)
+		for _, candidate := range mains {
+			if usedToday[candidate.key] || (candidate.recipeID == nil && haveRecipes && !s.foodsAsMain) {
+				continue
+			}
+			scale := clampScale(candidate, slotTarget.Calories/candidate.nutrition.Calories)
+			cost := targetError(candidate.nutrition.Scaled(scale), slotTarget)
+			cost += repeatPenalty(candidate.key, uses[candidate.key], req.MaxRepeats, previous[slot])
+			if candidate.preferred {
+				cost -= 0.05
+			}
+			if cost < bestCost {
+				best = &plannedItem{candidate: candidate, slot: slot, scale: scale}
+				bestCost = cost
+			}
+		}
+		if best != nil {
+			items = append(items, best)
+			usedToday[best.candidate.key] = true
+		}
+	}
+
+	fitScales(items, req.Targets)
+	cost := planError(items, req.Targets)
+
+	for added := 0; added < maxSidesPerDay && !withinTolerance(itemsNutrition(items), req.Targets, req.Tolerance); added++ {
+		var bestItems []*plannedItem
+		bestCost := cost
+		for _, side := range sides {
+			if usedToday[side.key] {
+				continue
+			}
+			for slot := range mealPlanSlots {
+				trial := cloneItems(items)
+				trial = append(trial, &plannedItem{candidate: side, slot: slot, scale: side.minScale, side: true})
+				fitScales(trial, req.Targets)
+				if trialCost := planError(trial, req.Targets); trialCost < bestCost-1e-6 {
+					bestItems, bestCost = trial, trialCost
+				}
+			}
+		}
+		if bestItems == nil {
+			break
+		}
+		items, cost = bestItems, bestCost
+		usedToday[items[len(items)-1].candidate.key] = true
+	}
+
+	// Portions are rounded to practical amounts: quarter servings, 5 g
+	for _, item := range items {
+		item.scale = clampScale(item.candidate, math.Round(item.scale/item.candidate.step)*item.candidate.step)
+	}
+
+	return items
+}
+
+// slotBalanceWeight is how strongly each meal is kept near its share of the
+// day's calories, so portions are not shifted into one oversized meal
+const slotBalanceWeight = 0.3
+
+// fitScales adjusts every item's portion to minimize planError. The error is
+// quadratic in each scale, so coordinate descent with the closed-form
+// optimum converges quickly.
+func fitScales(items []*plannedItem, targets MealPlanTargets) {
+	weights, goals := targetVector(targets)
+	for sweep := 0; sweep < 25; sweep++ {
+		for _, item := range items {
+			total := nutritionVector(itemsNutrition(items))
+			own := nutritionVector(item.candidate.nutrition.Scaled(item.scale))
+			per := nutritionVector(item.candidate.nutrition)
+
+			num, den := 0.0, 0.0
+			for k := range goals {
+				if goals[k] <= 0 {
+					continue
+				}
+				rest := total[k] - own[k]
+				w := weights[k] / (goals[k] * goals[k])
+				num += w * per[k] * (goals[k] - rest)
+				den += w * per[k] * per[k]
+			}
+
+			slotGoal := targets.Calories * mealPlanSlots[item.slot].share
+			if slotGoal > 0 {
+				rest := slotCalories(items, item.slot) - own[0]
+				w := slotBalanceWeight / (slotGoal * slotGoal)
+				num += w * per[0] * (slotGoal - rest)
+				den += w * per[0] * per[0]
+			}
+
+			if den > 0 {
+				item.scale = clampScale(item.candidate, num/den)
+			}
+		}
+	}
+}
+
+// planError is the day's targetError plus the imbalance between meals
+func planError(items []*plannedItem, targets MealPlanTargets) float64 {
+	cost := targetError(itemsNutrition(items), targets)
+	for slot, s := range mealPlanSlots {
+		slotGoal := targets.Calories * s.share
+		if slotGoal > 0 {
+			d := (slotCalories(items, slot) - slotGoal) / slotGoal
+			cost += slotBalanceWeight * d * d
+		}
+	}
+	return cost
+}
+
+func slotCalories(items []*plannedItem, slot int) float64 {
+	total := 0.0
+	for _, item := range items {
+		if item.slot == slot {
+			total += item.candidate.nutrition.Calories * item.scale
+		}
+	}
+	return total
+}
+
+// targetError is the weighted sum of squared relative deviations; calories
+// count double
+func targetError(n models.NutritionInfo, targets MealPlanTargets) float64 {
+	weights, goals := targetVector(targets)
+	actual := nutritionVector(n)
+	cost := 0.0
+	for k := range goals {
+		if goals[k] <= 0 {
+			continue
+		}
+		d := (actual[k] - goals[k]) / goals[k]
+		cost += weights[k] * d * d
+	}
+	return cost
+}
+
+func targetVector(targets MealPlanTargets) ([4]float64, [4]float64) {
+	return [4]float64{2, 1, 1, 1}, [4]float64{targets.Calories, targets.Protein, targets.Carbs, targets.Fat}
+}
+
+func nutritionVector(n models.NutritionInfo) [4]float64 {
+	return [4]float64{n.Calories, n.Protein, n.Carbohydrates, n.Fat}
+}
+
+// repeatPenalty discourages serving a dish again, especially in the same
+// meal on consecutive days or beyond the allowed number of repeats
+func repeatPenalty(key string, uses, maxRepeats int, previousInSlot string) float64 {
+	penalty := 0.15 * float64(uses)
+	if key == previousInSlot {
+		penalty += 0.5
+	}
+	if uses >= maxRepeats {
+		penalty += 10 * float64(uses-maxRepeats+1)
+	}
+	return penalty
+}
+
+func withinTolerance(n models.NutritionInfo, targets MealPlanTargets, tolerance float64) bool {
+	_, goals := targetVector(targets)
+	actual := nutritionVector(n)
+	for k := range goals {
+		if goals[k] > 0 && math.Abs(actual[k]-goals[k]) > tolerance*goals[k]+1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func dayFit(dayNumber int, totals models.NutritionInfo, targets MealPlanTargets, tolerance float64) MealPlanDayFit {
+	totals = totals.Rounded()
+	deviation := map[string]float64{}
+	names := [4]string{"calories", "protein", "carbs", "fat"}
+	_, goals := targetVector(targets)
+	actual := nutritionVector(totals)
+	for k, name := range names {
+		if goals[k] > 0 {
+			deviation[name] = roundTo((actual[k]-goals[k])/goals[k]*100, 1)
+		}
+	}
+	return MealPlanDayFit{
+		DayNumber:       dayNumber,
+		Totals:          totals,
+		Deviation:       deviation,
+		WithinTolerance: withinTolerance(totals, targets, tolerance),
+	}
+}
+
+func clampScale(c *planCandidate, scale float64) float64 {
+	if math.IsNaN(scale) || scale < c.minScale {
+		return c.minScale
+	}
+	if scale > c.maxScale {
+		return c.maxScale
+	}
+	return scale
+}
+
+func itemsNutrition(items []*plannedItem) models.NutritionInfo {
+	var total models.NutritionInfo
+	for _, item := range items {
+		total.Add(item.candidate.nutrition.Scaled(item.scale))
+	}
+	return total
+}
+
+func cloneItems(items []*plannedItem) []*plannedItem {
+	cloned := make([]*plannedItem, len(items), len(items)+1)
+	for i, item := range items {
+		copied := *item
+		cloned[i] = &copied
+	}
+	return cloned
+}
+
+// buildPlanDay turns the day's items into meals, the main dish first
+func buildPlanDay(dayNumber int, date *time.Time, items []*plannedItem) models.MealPlanDay {
+	day := models.MealPlanDay{DayNumber: dayNumber, Date: date, Meals: []models.PlannedMeal{}}
+	for slot, s := range mealPlanSlots {
+		meal := models.PlannedMeal{MealType: s.mealType, Items: []models.PlannedMealItem{}}
+		for _, item := range items {
+			if item.slot != slot {
+				continue
+			}
+			c := item.candidate
+			if !item.side {
+				meal.Name = c.name
+				meal.RecipeID = c.recipeID
+			}
+			category := "main"
+			if item.side {
+				category = "side"
+			}
+			meal.Items = append(meal.Items, models.PlannedMealItem{
+				FoodID:    c.foodID,
+				Name:      c.name,
+				Quantity:  roundTo(c.baseQuantity*item.scale, 2),
+				Unit:      c.unit,
+				Category:  category,
+				Nutrition: c.nutrition.Scaled(item.scale).Rounded(),
+			})
+		}
+		if len(meal.Items) > 0 {
+			day.Meals = append(day.Meals, meal)
+		}
+	}
+	return day
+}
+
+// normalizeDietTag lowercases and unifies separators: "Gluten-Free" becomes "gluten_free"
+func normalizeDietTag(s string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// containsAnyWord reports whether any keyword is among words, which hold
+// ingredientTokens output
+func containsAnyWord(words map[string]bool, keywords []string) bool {
+	for _, keyword := range keywords {
+		if words[singularize(keyword)] {
+			return true
+		}
+	}
+	return false
+}
+
+// dietaryRestriction lists the ingredient words a restriction excludes and,
+// for low-carb diets, the largest share of calories from carbohydrates
+type dietaryRestriction struct {
+	excluded     []string
+	maxCarbShare float64
+}
+
+var (
+	meatKeywords      = []string{"chicken", "beef", "lamb", "mutton", "veal", "turkey", "duck", "goat", "meat", "steak", "mince", "sausage", "liver", "kofta", "shawarma"}
+	fishKeywords      = []string{"fish", "salmon", "tuna", "cod", "sardine", "mackerel", "anchovy", "tilapia", "hammour"}
+	shellfishKeywords = []string{"shrimp", "prawn", "crab", "lobster", "mussel", "oyster", "clam", "squid"}
+	seafoodKeywords   = concatKeywords(fishKeywords, shellfishKeywords)
+	dairyKeywords     = []string{"milk", "cheese", "yogurt", "yoghurt", "butter", "cream", "whey", "casein", "labneh", "ghee", "halloumi", "feta", "mozzarella", "paneer"}
+	glutenKeywords    = []string{"wheat", "bread", "pasta", "flour", "barley", "rye", "couscous", "bulgur", "semolina", "freekeh", "noodle", "pita", "tortilla", "cracker", "biscuit", "oat"}
+	eggKeywords       = []string{"egg", "mayonnaise"}
+)
+
+// dietaryRestrictionRules are the restrictions a plan can be generated for
+var dietaryRestrictionRules = map[string]dietaryRestriction{
+	"vegetarian":  {excluded: concatKeywords(meatKeywords, seafoodKeywords)},
+	"pescatarian": {excluded: meatKeywords},
+	"vegan":       {excluded: concatKeywords(meatKeywords, seafoodKeywords, dairyKeywords, eggKeywords, []string{"honey", "gelatin"})},
+	"gluten_free": {excluded: glutenKeywords},
+	"dairy_free":  {excluded: dairyKeywords},
+	"egg_free":    {excluded: eggKeywords},
+	"low_carb":    {maxCarbShare: 0.26},
+	"keto":        {excluded: []string{"bread", "pasta", "rice", "potato", "oat", "sugar"}, maxCarbShare: 0.10},
+}
+
+// allergenKeywords maps common allergies to the ingredient words that carry them
+var allergenKeywords = map[string][]string{
+	"nuts":      {"almond", "walnut", "cashew", "pistachio", "hazelnut", "pecan", "macadamia", "peanut", "nut"},
+	"tree_nuts": {"almond", "walnut", "cashew", "pistachio", "hazelnut", "pecan", "macadamia", "nut"},
+	"peanuts":   {"peanut"},
+	"dairy":     dairyKeywords,
+	"milk":      dairyKeywords,
+	"lactose":   dairyKeywords,
+	"gluten":    glutenKeywords,
+	"wheat":     glutenKeywords,
+	"eggs":      eggKeywords,
+	"egg":       eggKeywords,
+	"fish":      fishKeywords,
+	"seafood":   seafoodKeywords,
+	"shellfish": shellfishKeywords,
+	"soy":       {"soy", "soya", "tofu", "edamame", "tempeh", "miso"},
+	"sesame":    {"sesame", "tahini", "halva"},
+}
+
+// haramKeywords screen ingredients when no halal blacklist is configured
+var haramKeywords = []string{"pork", "bacon", "ham", "lard", "prosciutto", "pancetta", "pepperoni", "chorizo", "salami",
+	"wine", "beer", "rum", "vodka", "whiskey", "brandy", "liqueur", "mirin", "gelatin", "gelatine"}
+
+func concatKeywords(lists ...[]string) []string {
+	var all []string
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
@@ -0,0 +1,420 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/models"
+)
+
+// Layout constants for the meal plan PDF, in points
+const (
+	mealPlanPDFMargin     = 40.0
+	mealPlanPDFHeaderGap  = 28.0
+	mealPlanPDFFooterY    = 24.0
+	mealPlanPDFRowHeight  = 13.0
+	mealPlanPDFBodySize   = 9.0
+	mealPlanPDFHeadSize   = 12.0
+	mealPlanPDFTitleSize  = 18.0
+	mealPlanPDFColArabic  = 340.0
+	mealPlanPDFColQty     = 405.0
+	mealPlanPDFColCal     = 450.0
+	mealPlanPDFColProtein = 490.0
+	mealPlanPDFColCarbs   = 525.0
+)
+
+// mealPlanPDFLabels holds the bilingual static labels printed on meal plans
+var mealPlanPDFLabels = map[string]models.BilingualText{
+	"breakfast":     {En: "Breakfast", Ar: "الفطور"},
+	"lunch":         {En: "Lunch", Ar: "الغداء"},
+	"dinner":        {En: "Dinner", Ar: "العشاء"},
+	"snack":         {En: "Snack", Ar: "وجبة خفيفة"},
+	"day":           {En: "Day", Ar: "اليوم"},
+	"meal_total":    {En: "Meal total", Ar: "إجمالي الوجبة"},
+	"day_total":     {En: "Day total", Ar: "إجمالي اليوم"},
+	"daily_summary": {En: "Daily summary", Ar: "ملخص يومي"},
+	"shopping_list": {En: "Shopping list", Ar: "قائمة التسوق"},
+	"disclaimer":    {En: "Medical disclaimer", Ar: "إخلاء مسؤولية طبية"},
+}
+
+// mealPlanPDFDisclaimers are the MedicalDisclaimer entries printed at the end of every plan
+var mealPlanPDFDisclaimers = []string{"medical_critical", "nutrition_general"}
+
+// ErrMealPlanPDFFontUnavailable is returned when no TrueType font with
+// Arabic coverage is configured, so a bilingual PDF cannot be rendered
+var ErrMealPlanPDFFontUnavailable = errors.New("meal plan PDF font is not configured")
+
+// MealPlanPDFRenderer renders stored meal plans as printable, paginated PDFs
+type MealPlanPDFRenderer struct {
+	font       *TrueTypeFont
+	fontErr    error
+	disclaimer *MedicalDisclaimer
+}
+
+// NewMealPlanPDFRenderer creates a renderer. fontPath must point to a
+// TrueType font with Arabic coverage; when it is empty or cannot be loaded
+// Render fails with ErrMealPlanPDFFontUnavailable rather than dropping the
+// Arabic half of the plan.
+func NewMealPlanPDFRenderer(fontPath string, disclaimer *MedicalDisclaimer) *MealPlanPDFRenderer {
+	renderer := &MealPlanPDFRenderer{disclaimer: disclaimer}
+	if fontPath == "" {
+		renderer.fontErr = ErrMealPlanPDFFontUnavailable
+		log.Printf("Warning: PDF_FONT_PATH is not set, meal plan PDF export is disabled")
+	} else if font, err := LoadTrueTypeFont(fontPath); err != nil {
+		renderer.fontErr = fmt.Errorf("%w: %v", ErrMealPlanPDFFontUnavailable, err)
+		log.Printf("Warning: PDF font not available, meal plan PDF export is disabled: %v", err)
+	} else {
+		renderer.font = font
+	}
+	if renderer.disclaimer == nil {
+		renderer.disclaimer = NewMedicalDisclaimer()
+	}
+	return renderer
+}
+
+// ShoppingListEntry is an aggregated ingredient line in a meal plan export
+type ShoppingListEntry struct {
+	Name     models.BilingualText `json:"name"`
+	Quantity float64              `json:"quantity"`
+	Unit     string               `json:"unit"`
+	Category string               `json:"category"`
+}
+
+// Render produces the PDF bytes for a meal plan
+func (r *MealPlanPDFRenderer) Render(plan *models.MealPlan) ([]byte, error) {
+	if r.font == nil {
+		return nil, r.fontErr
+	}
+
+	layout := &mealPlanPDFLayout{
+		doc:  NewPDFDocument(PDFPageWidthA4, PDFPageHeightA4, r.font),
+		plan: plan,
+	}
+
+	layout.newPage()
+	layout.title()
+
+	for _, day := range plan.Days {
+		layout.day(day)
+	}
+
+	layout.dailySummary()
+	layout.shoppingList(BuildMealPlanShoppingList(plan))
+	layout.disclaimers(r.disclaimer)
+	layout.footers()
+
+	return layout.doc.Bytes()
+}
+
+// BuildMealPlanShoppingList merges identical items across all days of a plan
+func BuildMealPlanShoppingList(plan *models.MealPlan) []ShoppingListEntry {
+	index := make(map[string]int)
+	var entries []ShoppingListEntry
+
+	for _, day := range plan.Days {
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				key := strings.ToLower(strings.TrimSpace(item.Name.En)) + "|" + strings.ToLower(item.Unit)
+				if i, ok := index[key]; ok {
+					entries[i].Quantity += item.Quantity
+					if entries[i].Name.Ar == "" {
+						entries[i].Name.Ar = item.Name.Ar
+					}
+					continue
+				}
+				category := item.Category
+				if category == "" {
+					category = "other"
+				}
+				index[key] = len(entries)
+				entries = append(entries, ShoppingListEntry{
+					Name:     item.Name,
+					Quantity: item.Quantity,
+					Unit:     item.Unit,
+					Category: category,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Category != entries[j].Category {
+			return entries[i].Category < entries[j].Category
+		}
+		return strings.ToLower(entries[i].Name.En) < strings.ToLower(entries[j].Name.En)
+	})
+
+	return entries
+}
+
+// mealPlanPDFLayout tracks the cursor while a plan is laid out onto pages
+type mealPlanPDFLayout struct {
+	doc  *PDFDocument
+	plan *models.MealPlan
+	y    float64
+}
+
+func (l *mealPlanPDFLayout) left() float64  { return mealPlanPDFMargin }
+func (l *mealPlanPDFLayout) right() float64 { return l.doc.PageWidth() - mealPlanPDFMargin }
+
+func (l *mealPlanPDFLayout) newPage() {
+	l.doc.AddPage()
+	top := l.doc.PageHeight() - mealPlanPDFMargin
+
+	l.doc.Text(l.left(), top, mealPlanPDFBodySize, true, l.plan.Name)
+	if l.plan.NameAr != nil {
+		l.arabic(l.right(), top, mealPlanPDFBodySize, true, *l.plan.NameAr)
+	}
+	l.doc.Line(l.left(), top-5, l.right(), top-5, 0.5)
+
+	l.y = top - mealPlanPDFHeaderGap
+}
+
+// ensureSpace starts a new page when fewer than height points remain
+func (l *mealPlanPDFLayout) ensureSpace(height float64) {
+	if l.y-height < mealPlanPDFMargin+mealPlanPDFFooterY {
+		l.newPage()
+	}
+}
+
+// arabic draws right-aligned Arabic text ending at x
+func (l *mealPlanPDFLayout) arabic(x, y, size float64, bold bool, text string) {
+	if text == "" {
+		return
+	}
+	l.doc.TextRight(x, y, size, bold, VisualArabic(text))
+}
+
+// heading draws an English label on the left and its Arabic form on the right
+func (l *mealPlanPDFLayout) heading(label models.BilingualText, size float64, shade bool) {
+	height := size + 8
+	l.ensureSpace(height + mealPlanPDFRowHeight*2)
+	if shade {
+		l.doc.FillRect(l.left()-4, l.y-4, l.right()-l.left()+8, size+6, 0.9)
+	}
+	l.doc.Text(l.left(), l.y, size, true, label.En)
+	l.arabic(l.right(), l.y, size, true, label.Ar)
+	l.y -= height
+}
+
+func (l *mealPlanPDFLayout) title() {
+	l.doc.Text(l.left(), l.y, mealPlanPDFTitleSize, true, l.plan.Name)
+	if l.plan.NameAr != nil {
+		l.arabic(l.right(), l.y, mealPlanPDFTitleSize, true, *l.plan.NameAr)
+	}
+	l.y -= mealPlanPDFTitleSize + 6
+
+	if l.plan.StartDate != nil || l.plan.EndDate != nil {
+		var period []string
+		if l.plan.StartDate != nil {
+			period = append(period, l.plan.StartDate.Format("2 Jan 2006"))
+		}
+		if l.plan.EndDate != nil {
+			period = append(period, l.plan.EndDate.Format("2 Jan 2006"))
+		}
+		l.doc.Text(l.left(), l.y, mealPlanPDFBodySize+1, false, strings.Join(period, " - "))
+		l.y -= mealPlanPDFRowHeight
+	}
+
+	if l.plan.Description != nil && *l.plan.Description != "" {
+		width := l.right() - l.left()
+		for _, line := range l.doc.WrapText(*l.plan.Description, mealPlanPDFBodySize+1, false, width) {
+			l.ensureSpace(mealPlanPDFRowHeight)
+			l.doc.Text(l.left(), l.y, mealPlanPDFBodySize+1, false, line)
+			l.y -= mealPlanPDFRowHeight
+		}
+	}
+
+	l.y -= mealPlanPDFRowHeight / 2
+}
+
+func (l *mealPlanPDFLayout) day(day models.MealPlanDay) {
+	dayLabel := mealPlanPDFLabels["day"]
+	label := models.BilingualText{
+		En: fmt.Sprintf("%s %d", dayLabel.En, day.DayNumber),
+		Ar: fmt.Sprintf("%s %d", dayLabel.Ar, day.DayNumber),
+	}
+	if day.Date != nil {
+		label.En += " - " + day.Date.Format("Monday, 2 Jan 2006")
+	}
+
+	l.y -= mealPlanPDFRowHeight / 2
+	l.heading(label, mealPlanPDFHeadSize, true)
+
+	for _, meal := range day.Meals {
+		l.meal(meal)
+	}
+
+	l.ensureSpace(mealPlanPDFRowHeight)
+	l.totalsRow(mealPlanPDFLabels["day_total"], day.Totals(), true)
+	l.y -= mealPlanPDFRowHeight / 2
+}
+
+func (l *mealPlanPDFLayout) meal(meal models.PlannedMeal) {
+	typeLabel, ok := mealPlanPDFLabels[meal.MealType]
+	if !ok {
+		typeLabel = models.BilingualText{En: meal.MealType}
+	}
+	label := typeLabel
+	if meal.Name.En != "" {
+		label.En = typeLabel.En + ": " + meal.Name.En
+	}
+	if meal.Name.Ar != "" {
+		label.Ar = typeLabel.Ar + ": " + meal.Name.Ar
+	}
+
+	l.heading(label, mealPlanPDFBodySize+1, false)
+	l.columnHeaders()
+
+	for _, item := range meal.Items {
+		l.ensureSpace(mealPlanPDFRowHeight)
+		l.doc.Text(l.left()+8, l.y, mealPlanPDFBodySize, false, item.Name.En)
+		l.arabic(mealPlanPDFColArabic, l.y, mealPlanPDFBodySize, false, item.Name.Ar)
+		l.doc.TextRight(mealPlanPDFColQty, l.y, mealPlanPDFBodySize, false, formatPDFQuantity(item.Quantity, item.Unit))
+		l.macroCells(item.Nutrition, false)
+		l.y -= mealPlanPDFRowHeight
+	}
+
+	l.ensureSpace(mealPlanPDFRowHeight)
+	l.totalsRow(mealPlanPDFLabels["meal_total"], meal.Totals(), false)
+	l.y -= 4
+}
+
+func (l *mealPlanPDFLayout) columnHeaders() {
+	l.ensureSpace(mealPlanPDFRowHeight * 2)
+	size := mealPlanPDFBodySize - 1
+	l.doc.TextRight(mealPlanPDFColQty, l.y, size, true, "Amount")
+	l.doc.TextRight(mealPlanPDFColCal, l.y, size, true, "kcal")
+	l.doc.TextRight(mealPlanPDFColProtein, l.y, size, true, "Protein")
+	l.doc.TextRight(mealPlanPDFColCarbs, l.y, size, true, "Carbs")
+	l.doc.TextRight(l.right(), l.y, size, true, "Fat")
+	l.doc.Line(l.left(), l.y-3, l.right(), l.y-3, 0.3)
+	l.y -= mealPlanPDFRowHeight
+}
+
+func (l *mealPlanPDFLayout) macroCells(n models.NutritionInfo, bold bool) {
+	l.doc.TextRight(mealPlanPDFColCal, l.y, mealPlanPDFBodySize, bold, fmt.Sprintf("%.0f", n.Calories))
+	l.doc.TextRight(mealPlanPDFColProtein, l.y, mealPlanPDFBodySize, bold, fmt.Sprintf("%.1f g", n.Protein))
+	l.doc.TextRight(mealPlanPDFColCarbs, l.y, mealPlanPDFBodySize, bold, fmt.Sprintf("%.1f g", n.Carbohydrates))
+	l.doc.TextRight(l.right(), l.y, mealPlanPDFBodySize, bold, fmt.Sprintf("%.1f g", n.Fat))
+}
+
+func (l *mealPlanPDFLayout) totalsRow(label models.BilingualText, totals models.NutritionInfo, shade bool) {
+	if shade {
+		l.doc.FillRect(l.left()-4, l.y-3, l.right()-l.left()+8, mealPlanPDFRowHeight, 0.95)
+	}
+	l.doc.Text(l.left()+8, l.y, mealPlanPDFBodySize, true, label.En)
+	l.arabic(mealPlanPDFColArabic, l.y, mealPlanPDFBodySize, true, label.Ar)
+	l.macroCells(totals, true)
+	l.y -= mealPlanPDFRowHeight
+}
+
+func (l *mealPlanPDFLayout) dailySummary() {
+	if len(l.plan.Days) == 0 {
+		return
+	}
+
+	l.y -= mealPlanPDFRowHeight / 2
+	l.heading(mealPlanPDFLabels["daily_summary"], mealPlanPDFHeadSize, true)
+	l.columnHeaders()
+
+	dayLabel := mealPlanPDFLabels["day"]
+	for _, day := range l.plan.Days {
+		l.ensureSpace(mealPlanPDFRowHeight)
+		l.doc.Text(l.left()+8, l.y, mealPlanPDFBodySize, false, fmt.Sprintf("%s %d", dayLabel.En, day.DayNumber))
+		l.arabic(mealPlanPDFColArabic, l.y, mealPlanPDFBodySize, false, fmt.Sprintf("%s %d", dayLabel.Ar, day.DayNumber))
+		l.macroCells(day.Totals(), false)
+		l.y -= mealPlanPDFRowHeight
+	}
+
+	days := float64(len(l.plan.Days))
+	average := models.NutritionInfo{
+		Calories:      l.plan.TotalCalories / days,
+		Protein:       l.plan.TotalProtein / days,
+		Carbohydrates: l.plan.TotalCarbs / days,
+		Fat:           l.plan.TotalFat / days,
+	}
+	l.ensureSpace(mealPlanPDFRowHeight)
+	l.totalsRow(models.BilingualText{En: "Daily average", Ar: "المعدل اليومي"}, average, true)
+}
+
+func (l *mealPlanPDFLayout) shoppingList(entries []ShoppingListEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	l.y -= mealPlanPDFRowHeight / 2
+	l.heading(mealPlanPDFLabels["shopping_list"], mealPlanPDFHeadSize, true)
+
+	category := ""
+	for _, entry := range entries {
+		if entry.Category != category {
+			category = entry.Category
+			l.ensureSpace(mealPlanPDFRowHeight * 2)
+			l.doc.Text(l.left(), l.y, mealPlanPDFBodySize, true, strings.Title(category))
+			l.y -= mealPlanPDFRowHeight
+		}
+		l.ensureSpace(mealPlanPDFRowHeight)
+		l.doc.Rect(l.left()+8, l.y-1, 7, 7, 0.5)
+		l.doc.Text(l.left()+22, l.y, mealPlanPDFBodySize, false, entry.Name.En)
+		l.arabic(mealPlanPDFColArabic, l.y, mealPlanPDFBodySize, false, entry.Name.Ar)
+		l.doc.TextRight(mealPlanPDFColQty, l.y, mealPlanPDFBodySize, false, formatPDFQuantity(entry.Quantity, entry.Unit))
+		l.y -= mealPlanPDFRowHeight
+	}
+}
+
+func (l *mealPlanPDFLayout) disclaimers(md *MedicalDisclaimer) {
+	l.y -= mealPlanPDFRowHeight / 2
+	l.heading(mealPlanPDFLabels["disclaimer"], mealPlanPDFHeadSize, true)
+
+	width := l.right() - l.left()
+	size := mealPlanPDFBodySize - 0.5
+	for _, id := range mealPlanPDFDisclaimers {
+		if text, ok := md.GetDisclaimerText(id, "en"); ok {
+			for _, line := range l.doc.WrapText(text, size, false, width) {
+				l.ensureSpace(mealPlanPDFRowHeight)
+				l.doc.Text(l.left(), l.y, size, false, line)
+				l.y -= mealPlanPDFRowHeight - 2
+			}
+			l.y -= 4
+		}
+
+		if text, ok := md.GetDisclaimerText(id, "ar"); ok {
+			// Wrap on the logical text so each line is reordered independently
+			for _, line := range l.doc.WrapText(text, size, false, width) {
+				l.ensureSpace(mealPlanPDFRowHeight)
+				l.arabic(l.right(), l.y, size, false, line)
+				l.y -= mealPlanPDFRowHeight - 2
+			}
+			l.y -= 4
+		}
+	}
+}
+
+// footers stamps page numbers once the total page count is known
+func (l *mealPlanPDFLayout) footers() {
+	total := l.doc.PageCount()
+	generated := "Generated " + time.Now().Format("2 Jan 2006")
+	for i := 0; i < total; i++ {
+		l.doc.SetPage(i)
+		l.doc.Line(l.left(), mealPlanPDFFooterY+10, l.right(), mealPlanPDFFooterY+10, 0.3)
+		l.doc.Text(l.left(), mealPlanPDFFooterY, mealPlanPDFBodySize-1, false, generated)
+		l.doc.TextRight(l.right(), mealPlanPDFFooterY, mealPlanPDFBodySize-1, false, fmt.Sprintf("Page %d of %d", i+1, total))
+	}
+}
+
+// formatPDFQuantity prints a quantity without trailing zeros, e.g. "1.5 cup"
+func formatPDFQuantity(quantity float64, unit string) string {
+	rounded := math.Round(quantity*100) / 100
+	value := strconv.FormatFloat(rounded, 'f', -1, 64)
+	if unit == "" {
+		return value
+	}
+	return value + " " + unit
+}
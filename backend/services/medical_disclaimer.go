@@ -483,6 +483,24 @@ func (md *MedicalDisclaimer) GetDisclaimers() map[string]DisclaimerConfig {
 	return disclaimers
 }
 
+// GetDisclaimerText returns the text of a disclaimer in the requested
+// language, falling back to the default language
+func (md *MedicalDisclaimer) GetDisclaimerText(id, language string) (string, bool) {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+
+	disclaimer, exists := md.disclaimers[id]
+	if !exists {
+		return "", false
+	}
+
+	if text, ok := disclaimer.Languages[language]; ok {
+		return text, true
+	}
+	text, ok := disclaimer.Languages[md.defaultLanguage]
+	return text, ok
+}
+
 // GetAuditLog returns recent audit log entries
 func (md *MedicalDisclaimer) GetAuditLog(limit int) []DisclaimerAudit {
 	md.mu.RLock()
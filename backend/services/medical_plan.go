@@ -1,107 +1,41 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"nutrition-platform/models"
+
 	"github.com/google/uuid"
 )
 
 // MedicalPlan represents a medical or health plan
-type MedicalPlan struct {
-	ID                string                 `json:"id"`
-	UserID            string                 `json:"user_id"`
-	Name              string                 `json:"name"`
-	Description       string                 `json:"description"`
-	Type              string                 `json:"type"`     // diet, exercise, medication, therapy, etc.
-	Category          string                 `json:"category"` // weight_loss, muscle_gain, diabetes, heart_health, etc.
-	Duration          int                    `json:"duration"` // duration in days
-	StartDate         *time.Time             `json:"start_date,omitempty"`
-	EndDate           *time.Time             `json:"end_date,omitempty"`
-	Goals             []string               `json:"goals"`
-	Restrictions      []string               `json:"restrictions"`
-	Recommendations   []string               `json:"recommendations"`
-	MealPlan          *MealPlanDetails       `json:"meal_plan,omitempty"`
-	ExercisePlan      *ExercisePlanDetails   `json:"exercise_plan,omitempty"`
-	SupplementPlan    *SupplementPlanDetails `json:"supplement_plan,omitempty"`
-	MonitoringMetrics []string               `json:"monitoring_metrics"`
-	Checkpoints       []Checkpoint           `json:"checkpoints"`
-	Notes             string                 `json:"notes,omitempty"`
-	CreatedBy         string                 `json:"created_by"` // doctor, nutritionist, self, etc.
-	IsActive          bool                   `json:"is_active"`
-	IsPublic          bool                   `json:"is_public"`
-	Tags              []string               `json:"tags"`
-	Rating            float64                `json:"rating"`
-	RatingCount       int                    `json:"rating_count"`
-	CreatedAt         time.Time              `json:"created_at"`
-	UpdatedAt         time.Time              `json:"updated_at"`
-}
+type MedicalPlan = models.MedicalPlan
 
 // MealPlanDetails represents meal planning details within a medical plan
-type MealPlanDetails struct {
-	CaloriesPerDay int      `json:"calories_per_day"`
-	MealsPerDay    int      `json:"meals_per_day"`
-	ProteinRatio   float64  `json:"protein_ratio"`
-	CarbRatio      float64  `json:"carb_ratio"`
-	FatRatio       float64  `json:"fat_ratio"`
-	FoodCategories []string `json:"food_categories"`
-	AvoidedFoods   []string `json:"avoided_foods"`
-	PreferredFoods []string `json:"preferred_foods"`
-	MealTiming     []string `json:"meal_timing"`
-	HydrationGoal  int      `json:"hydration_goal"` // ml per day
-}
+type MealPlanDetails = models.MealPlanDetails
 
 // ExercisePlanDetails represents exercise planning details within a medical plan
-type ExercisePlanDetails struct {
-	WorkoutsPerWeek int      `json:"workouts_per_week"`
-	SessionDuration int      `json:"session_duration"` // minutes
-	IntensityLevel  string   `json:"intensity_level"`  // low, moderate, high
-	ExerciseTypes   []string `json:"exercise_types"`
-	TargetMuscles   []string `json:"target_muscles"`
-	EquipmentNeeded []string `json:"equipment_needed"`
-	RestDays        []string `json:"rest_days"`
-	ProgressionPlan string   `json:"progression_plan"`
-}
+type ExercisePlanDetails = models.ExercisePlanDetails
 
 // SupplementPlanDetails represents supplement planning details within a medical plan
-type SupplementPlanDetails struct {
-	RecommendedSupplements []RecommendedSupplement `json:"recommended_supplements"`
-	Timing                 string                  `json:"timing"`
-	Duration               int                     `json:"duration"` // days
-	Notes                  string                  `json:"notes"`
-}
+type SupplementPlanDetails = models.SupplementPlanDetails
 
 // RecommendedSupplement represents a supplement recommendation
-type RecommendedSupplement struct {
-	Name       string `json:"name"`
-	Dosage     string `json:"dosage"`
-	Frequency  string `json:"frequency"`
-	Timing     string `json:"timing"`
-	Purpose    string `json:"purpose"`
-	IsOptional bool   `json:"is_optional"`
-}
+type RecommendedSupplement = models.RecommendedSupplement
 
 // Checkpoint represents a progress checkpoint in a medical plan
-type Checkpoint struct {
-	ID          string                 `json:"id"`
-	Day         int                    `json:"day"`
-	Title       string                 `json:"title"`
-	Description string                 `json:"description"`
-	Metrics     map[string]interface{} `json:"metrics"`
-	Completed   bool                   `json:"completed"`
-	CompletedAt *time.Time             `json:"completed_at,omitempty"`
-	Notes       string                 `json:"notes,omitempty"`
-}
+type Checkpoint = models.Checkpoint
 
-// MedicalPlanData represents the structure of medical-plans.json
+// MedicalPlanData represents the structure of medical-plans.json, which
+// ImportJSONStores reads when moving medical plans into the database
 type MedicalPlanData struct {
 	MedicalPlans []MedicalPlan `json:"medical_plans"`
 	Metadata     Metadata      `json:"metadata"`
 }
 
-const medicalPlansFile = "backend/data/medical-plans.json"
-
 // CreateMedicalPlan creates a new medical plan
 func CreateMedicalPlan(plan *MedicalPlan) error {
 	// Generate ID and timestamps
@@ -163,31 +97,21 @@ func CreateMedicalPlan(plan *MedicalPlan) error {
 		plan.Checkpoints = generateDefaultCheckpoints(plan.Duration)
 	}
 
-	return AppendJSON(medicalPlansFile, plan)
+	return medicalPlanStore.CreateMedicalPlan(context.Background(), plan)
 }
 
 // GetMedicalPlansByUserID retrieves all medical plans for a specific user
 func GetMedicalPlansByUserID(userID string) ([]MedicalPlan, error) {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
+	plans, err := medicalPlanStore.GetMedicalPlansByUserID(context.Background(), userID)
 	if err != nil {
 		return nil, err
 	}
-
-	var userPlans []MedicalPlan
-	for _, plan := range data.MedicalPlans {
-		if plan.UserID == userID {
-			userPlans = append(userPlans, plan)
-		}
-	}
-
-	return userPlans, nil
+	return derefMedicalPlans(plans), nil
 }
 
 // GetActiveMedicalPlansByUserID retrieves active medical plans for a user
 func GetActiveMedicalPlansByUserID(userID string) ([]MedicalPlan, error) {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
+	plans, err := GetMedicalPlansByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -195,8 +119,8 @@ func GetActiveMedicalPlansByUserID(userID string) ([]MedicalPlan, error) {
 	var activePlans []MedicalPlan
 	now := time.Now()
 
-	for _, plan := range data.MedicalPlans {
-		if plan.UserID == userID && plan.IsActive {
+	for _, plan := range plans {
+		if plan.IsActive {
 			// Check if plan is within date range
 			if plan.StartDate != nil && plan.StartDate.After(now) {
 				continue
@@ -213,102 +137,63 @@ func GetActiveMedicalPlansByUserID(userID string) ([]MedicalPlan, error) {
 
 // GetPublicMedicalPlans retrieves public medical plans
 func GetPublicMedicalPlans() ([]MedicalPlan, error) {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
+	plans, err := medicalPlanStore.GetPublicMedicalPlans(context.Background())
 	if err != nil {
 		return nil, err
 	}
-
-	var publicPlans []MedicalPlan
-	for _, plan := range data.MedicalPlans {
-		if plan.IsPublic {
-			publicPlans = append(publicPlans, plan)
-		}
-	}
-
-	return publicPlans, nil
+	return derefMedicalPlans(plans), nil
 }
 
 // GetMedicalPlanByID retrieves a specific medical plan by ID
 func GetMedicalPlanByID(planID string) (*MedicalPlan, error) {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, plan := range data.MedicalPlans {
-		if plan.ID == planID {
-			return &plan, nil
-		}
-	}
-
-	return nil, fmt.Errorf("medical plan not found")
+	return medicalPlanStore.GetMedicalPlanByID(context.Background(), planID)
 }
 
 // UpdateMedicalPlan updates an existing medical plan
 func UpdateMedicalPlan(planID string, updatedPlan *MedicalPlan) error {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
+	ctx := context.Background()
+	plan, err := medicalPlanStore.GetMedicalPlanByID(ctx, planID)
 	if err != nil {
 		return err
 	}
 
-	for i, plan := range data.MedicalPlans {
-		if plan.ID == planID {
-			// Preserve original ID, created time, and rating info
-			updatedPlan.ID = plan.ID
-			updatedPlan.CreatedAt = plan.CreatedAt
-			updatedPlan.Rating = plan.Rating
-			updatedPlan.RatingCount = plan.RatingCount
-			updatedPlan.UpdatedAt = time.Now()
-
-			// Update end date if duration changed
-			if updatedPlan.Duration > 0 && updatedPlan.StartDate != nil {
-				endDate := updatedPlan.StartDate.AddDate(0, 0, updatedPlan.Duration)
-				updatedPlan.EndDate = &endDate
-			}
+	// Preserve original ID, owner, created time, and rating info
+	updatedPlan.ID = plan.ID
+	updatedPlan.UserID = plan.UserID
+	updatedPlan.CreatedAt = plan.CreatedAt
+	updatedPlan.Rating = plan.Rating
+	updatedPlan.RatingCount = plan.RatingCount
+	updatedPlan.UpdatedAt = time.Now()
 
-			data.MedicalPlans[i] = *updatedPlan
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(medicalPlansFile, data)
-		}
+	// Update end date if duration changed
+	if updatedPlan.Duration > 0 && updatedPlan.StartDate != nil {
+		endDate := updatedPlan.StartDate.AddDate(0, 0, updatedPlan.Duration)
+		updatedPlan.EndDate = &endDate
 	}
 
-	return fmt.Errorf("medical plan not found")
+	return medicalPlanStore.UpdateMedicalPlan(ctx, updatedPlan)
 }
 
 // DeleteMedicalPlan deletes a medical plan
 func DeleteMedicalPlan(planID string, userID string) error {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
+	ctx := context.Background()
+	plan, err := medicalPlanStore.GetMedicalPlanByID(ctx, planID)
 	if err != nil {
 		return err
 	}
 
-	for i, plan := range data.MedicalPlans {
-		if plan.ID == planID {
-			// Check if user owns this plan
-			if plan.UserID != userID {
-				return fmt.Errorf("unauthorized: plan belongs to another user")
-			}
-
-			// Remove plan from slice
-			data.MedicalPlans = append(data.MedicalPlans[:i], data.MedicalPlans[i+1:]...)
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(medicalPlansFile, data)
-		}
+	// Check if user owns this plan
+	if plan.UserID != userID {
+		return fmt.Errorf("unauthorized: plan belongs to another user")
 	}
 
-	return fmt.Errorf("medical plan not found")
+	return medicalPlanStore.DeleteMedicalPlan(ctx, planID, userID)
 }
 
 // SearchMedicalPlans searches medical plans by various criteria
 func SearchMedicalPlans(userID, query string, filters map[string]interface{}, includePublic bool) ([]MedicalPlan, error) {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
+	// Only the user's own plans, plus public ones when asked for
+	plans, err := medicalPlanStore.GetVisibleMedicalPlans(context.Background(), userID, includePublic)
 	if err != nil {
 		return nil, err
 	}
@@ -316,12 +201,7 @@ func SearchMedicalPlans(userID, query string, filters map[string]interface{}, in
 	var results []MedicalPlan
 	queryLower := strings.ToLower(query)
 
-	for _, plan := range data.MedicalPlans {
-		// Check access permissions
-		if plan.UserID != userID && (!includePublic || !plan.IsPublic) {
-			continue
-		}
-
+	for _, plan := range derefMedicalPlans(plans) {
 		// Text search
 		if query != "" {
 			matchesQuery := false
@@ -431,83 +311,52 @@ func RateMedicalPlan(planID string, rating float64) error {
 		return fmt.Errorf("rating must be between 1.0 and 5.0")
 	}
 
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
-	if err != nil {
-		return err
-	}
-
-	for i, plan := range data.MedicalPlans {
-		if plan.ID == planID {
-			// Calculate new average rating
-			totalRating := plan.Rating * float64(plan.RatingCount)
-			totalRating += rating
-			data.MedicalPlans[i].RatingCount++
-			data.MedicalPlans[i].Rating = totalRating / float64(data.MedicalPlans[i].RatingCount)
-			data.MedicalPlans[i].UpdatedAt = time.Now()
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(medicalPlansFile, data)
-		}
-	}
-
-	return fmt.Errorf("medical plan not found")
+	return medicalPlanStore.AddMedicalPlanRating(context.Background(), planID, rating)
 }
 
 // UpdateCheckpoint updates a checkpoint in a medical plan
 func UpdateCheckpoint(planID, checkpointID string, userID string, completed bool, notes string, metrics map[string]interface{}) error {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
-	if err != nil {
-		return err
+	ctx := context.Background()
+	plan, err := medicalPlanStore.GetMedicalPlanByID(ctx, planID)
+	if err != nil || plan.UserID != userID {
+		return fmt.Errorf("medical plan not found or unauthorized")
 	}
 
-	for i, plan := range data.MedicalPlans {
-		if plan.ID == planID && plan.UserID == userID {
-			for j, checkpoint := range plan.Checkpoints {
-				if checkpoint.ID == checkpointID {
-					data.MedicalPlans[i].Checkpoints[j].Completed = completed
-					data.MedicalPlans[i].Checkpoints[j].Notes = notes
-
-					if metrics != nil {
-						data.MedicalPlans[i].Checkpoints[j].Metrics = metrics
-					}
+	for j, checkpoint := range plan.Checkpoints {
+		if checkpoint.ID == checkpointID {
+			plan.Checkpoints[j].Completed = completed
+			plan.Checkpoints[j].Notes = notes
 
-					if completed {
-						now := time.Now()
-						data.MedicalPlans[i].Checkpoints[j].CompletedAt = &now
-					} else {
-						data.MedicalPlans[i].Checkpoints[j].CompletedAt = nil
-					}
-
-					data.MedicalPlans[i].UpdatedAt = time.Now()
-					data.Metadata.UpdatedAt = time.Now()
+			if metrics != nil {
+				plan.Checkpoints[j].Metrics = metrics
+			}
 
-					return WriteJSON(medicalPlansFile, data)
-				}
+			if completed {
+				now := time.Now()
+				plan.Checkpoints[j].CompletedAt = &now
+			} else {
+				plan.Checkpoints[j].CompletedAt = nil
 			}
-			return fmt.Errorf("checkpoint not found")
+
+			plan.UpdatedAt = time.Now()
+			return medicalPlanStore.UpdateMedicalPlan(ctx, plan)
 		}
 	}
 
-	return fmt.Errorf("medical plan not found or unauthorized")
+	return fmt.Errorf("checkpoint not found")
 }
 
 // GetMedicalPlansByCategory retrieves medical plans by category
 func GetMedicalPlansByCategory(category string, includePublic bool, userID string) ([]MedicalPlan, error) {
-	var data MedicalPlanData
-	err := ReadJSON(medicalPlansFile, &data)
+	plans, err := medicalPlanStore.GetVisibleMedicalPlans(context.Background(), userID, includePublic)
 	if err != nil {
 		return nil, err
 	}
 
 	var categoryPlans []MedicalPlan
-	for _, plan := range data.MedicalPlans {
+	for _, plan := range plans {
 		if plan.Category == category {
-			// Check access permissions
-			if plan.UserID == userID || (includePublic && plan.IsPublic) {
-				categoryPlans = append(categoryPlans, plan)
-			}
+			categoryPlans = append(categoryPlans, *plan)
 		}
 	}
 
@@ -557,3 +406,11 @@ func generateDefaultCheckpoints(duration int) []Checkpoint {
 
 	return checkpoints
 }
+
+func derefMedicalPlans(plans []*MedicalPlan) []MedicalPlan {
+	values := make([]MedicalPlan, 0, len(plans))
+	for _, plan := range plans {
+		values = append(values, *plan)
+	}
+	return values
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/database"
+	apperrors "nutrition-platform/errors"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+	"nutrition-platform/security"
+)
+
+const (
+	// RecoveryCodeCount is how many one-time recovery codes a user gets
+	RecoveryCodeCount = 10
+	// MFAMaxFailedAttempts wrong codes in a row lock verification for
+	// MFALockoutDuration
+	MFAMaxFailedAttempts = 5
+	MFALockoutDuration   = 15 * time.Minute
+)
+
+var (
+	// ErrMFAAlreadyEnabled is returned when enrolling a user who already
+	// has two-factor authentication
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnrolled is returned when there is no enrollment to confirm,
+	// verify against or disable
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not set up")
+	// ErrInvalidMFACode is returned for wrong, expired or reused codes
+	ErrInvalidMFACode = errors.New("invalid two-factor authentication code")
+	// ErrMFALocked is returned while verification is locked after too many
+	// wrong codes
+	ErrMFALocked = errors.New("too many invalid two-factor authentication codes; try again later")
+)
+
+// MFAService manages TOTP two-factor authentication (RFC 6238) and recovery
+// codes. TOTP secrets are stored encrypted with AES-GCM; recovery codes are
+// stored as hashes. Wrong codes are recorded as authentication failures in
+// the security log.
+type MFAService struct {
+	repo             *repositories.MFARepository
+	issuer           string
+	secrets          *secretBox
+	requireForAdmins bool
+	events           SecurityEventLogger
+}
+
+// NewMFAService creates a new MFAService instance. events may be nil.
+func NewMFAService(db *sql.DB, mfaConfig config.MFAConfig, events SecurityEventLogger) *MFAService {
+	return &MFAService{
+		repo:             repositories.NewMFARepository(database.NewDatabase(db)),
+		issuer:           mfaConfig.Issuer,
+		secrets:          newSecretBox(mfaConfig.EncryptionKey),
+		requireForAdmins: mfaConfig.RequireForAdmins,
+		events:           events,
+	}
+}
+
+// RequiresMFA reports whether an account must sign in with a second factor:
+// coaches always, admins when configured
+func (s *MFAService) RequiresMFA(role string, isAdmin bool) bool {
+	return role == models.RoleCoach || (isAdmin && s.requireForAdmins)
+}
+
+// IsEnabled reports whether a user has confirmed two-factor authentication
+func (s *MFAService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnrolled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.Enabled, nil
+}
+
+// Status summarizes a user's two-factor settings
+func (s *MFAService) Status(ctx context.Context, userID, role string, isAdmin bool) (*models.MFAStatus, error) {
+	status := &models.MFAStatus{Required: s.RequiresMFA(role, isAdmin)}
+
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnrolled) {
+			return status, nil
+		}
+		return nil, err
+	}
+
+	status.Enabled = mfa.Enabled
+	status.Pending = !mfa.Enabled
+	status.EnabledAt = mfa.EnabledAt
+	if mfa.Enabled {
+		if status.RecoveryCodesRemaining, err = s.repo.CountUnusedRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginEnrollment creates a new secret for a user. It is not used for
+// sign-in until ConfirmEnrollment is called with a code generated from it;
+// calling BeginEnrollment again replaces an unconfirmed secret.
+func (s *MFAService) BeginEnrollment(ctx context.Context, userID, accountName string) (*models.MFAEnrollment, error) {
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := s.secrets.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SavePendingSecret(ctx, userID, ciphertext, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if accountName == "" {
+		accountName = userID
+	}
+	return &models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(s.issuer, accountName, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user proves
+// their authenticator works, and returns their recovery codes. The codes
+// are only ever returned here and by RegenerateRecoveryCodes.
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, err := s.matchTOTP(mfa, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.repo.EnableMFA(ctx, userID, step, hashes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return codes, nil
+}
+
+// Verify checks a second factor for a user with two-factor authentication
+// enabled. code is either a current TOTP code, which is accepted once, or
+// an unused recovery code, which is consumed. ipAddress is recorded with
+// failures.
+func (s *MFAService) Verify(ctx context.Context, userID, code, ipAddress string) error {
+	mfa, err := s.getMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !mfa.Enabled {
+		return ErrMFANotEnrolled
+	}
+	now := time.Now()
+	if mfa.IsLocked(now) {
+		return ErrMFALocked
+	}
+
+	accepted, err := s.useCode(ctx, mfa, code, now)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		if err := s.repo.RecordFailure(ctx, userID, MFAMaxFailedAttempts, now.Add(MFALockoutDuration)); err != nil {
+			return err
+		}
+		s.logFailure(userID, ipAddress)
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// useCode accepts a TOTP code or consumes a recovery code and reports
+// whether either worked
+func (s *MFAService) useCode(ctx context.Context, mfa *models.UserMFA, code string, now time.Time) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == security.TOTPDigits {
+		step, err := s.matchTOTP(mfa, code)
+		if err != nil {
+			if errors.Is(err, ErrInvalidMFACode) {
+				return false, nil
+			}
+			return false, err
+		}
+		return s.repo.UseTimeStep(ctx, mfa.UserID, step)
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, mfa.UserID, hashRecoveryCode(code), now)
+	if err != nil || !used {
+		return false, err
+	}
+	return true, s.repo.ResetFailures(ctx, mfa.UserID)
+}
+
+func (s *MFAService) logFailure(userID, ipAddress string) {
+	if s.events == nil {
+		return
+	}
+	event := apperrors.NewSecurityEvent("authentication_failure", "medium", "Invalid two-factor authentication code")
+	event.IPAddress = ipAddress
+	event.AddDetail("user_id", userID)
+	event.AddDetail("factor", "totp")
+	s.events.LogSecurityEvent(event)
+}
+
+// Disable turns two-factor authentication off after checking a code
+func (s *MFAService) Disable(ctx context.Context, userID, code, ipAddress string) error {
+	if err := s.Verify(ctx, userID, code, ipAddress); err != nil {
+		return err
+	}
+	return s.repo.DeleteMFA(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a
+// code and returns the new ones
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID, code, ipAddress string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code, ipAddress); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes, time.Now()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *MFAService) getMFA(ctx context.Context, userID string) (*models.UserMFA, error) {
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil {
+		if err.Error() == "mfa settings not found" {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return mfa, nil
+}
+
+// matchTOTP returns the time step a code is valid for. Steps at or before
+// the last accepted one are rejected so a code cannot be replayed.
+func (s *MFAService) matchTOTP(mfa *models.UserMFA, code string) (int64, error) {
+	secret, err := s.secrets.open(mfa.SecretCiphertext)
+	if err != nil {
+		return 0, err
+	}
+	step, ok, err := security.ValidateTOTP(secret, code, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if !ok || step <= mfa.LastUsedStep {
+		return 0, ErrInvalidMFACode
+	}
+	return step, nil
+}
+
+// generateRecoveryCodes returns RecoveryCodeCount codes formatted as
+// xxxxx-xxxxx and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	buf := make([]byte, 5)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := hex.EncodeToString(buf)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, which users add or drop
+// when typing codes
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
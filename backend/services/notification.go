@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Notification channel names
+const (
+	NotificationChannelInApp = "in_app"
+	NotificationChannelEmail = "email"
+	NotificationChannelPush  = "push"
+)
+
+// channelPreferences maps channels to the preference that switches them
+// off. Channels without an entry are always used.
+var channelPreferences = map[string]string{
+	NotificationChannelEmail: "email_notifications",
+	NotificationChannelPush:  "push_notifications",
+}
+
+// NotificationChannel delivers notifications to users. Push delivery plugs
+// in by registering a channel named NotificationChannelPush.
+type NotificationChannel interface {
+	Name() string
+	Send(ctx context.Context, notification *models.Notification) error
+}
+
+// NotificationService sends notifications through the registered channels
+// and serves the in-app inbox. The in-app channel is always registered.
+type NotificationService struct {
+	repo        *repositories.NotificationRepository
+	preferences *UserPreferencesService
+
+	mu       sync.RWMutex
+	channels []NotificationChannel
+}
+
+// NewNotificationService creates a new NotificationService instance
+func NewNotificationService(db *sql.DB) *NotificationService {
+	repo := repositories.NewNotificationRepository(database.NewDatabase(db))
+	return &NotificationService{
+		repo:        repo,
+		preferences: NewUserPreferencesService(db),
+		channels:    []NotificationChannel{NewInAppChannel(repo)},
+	}
+}
+
+// RegisterChannel adds a channel, replacing any channel with the same name
+func (s *NotificationService) RegisterChannel(channel NotificationChannel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.channels {
+		if existing.Name() == channel.Name() {
+			s.channels[i] = channel
+			return
+		}
+	}
+	s.channels = append(s.channels, channel)
+}
+
+// Notify sends a notification through every channel the user has not
+// switched off. Nothing is sent when notifications_enabled is off. A
+// failing channel does not stop the others; their errors are returned
+// together.
+func (s *NotificationService) Notify(ctx context.Context, notification *models.Notification) error {
+	preferences, err := s.userPreferences(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+	if !preferenceEnabled(preferences, "notifications_enabled") {
+		return nil
+	}
+
+	if notification.ID == "" {
+		notification.ID = uuid.New().String()
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now().UTC()
+	}
+
+	s.mu.RLock()
+	channels := append([]NotificationChannel(nil), s.channels...)
+	s.mu.RUnlock()
+
+	var errs []error
+	for _, channel := range channels {
+		if key, ok := channelPreferences[channel.Name()]; ok && !preferenceEnabled(preferences, key) {
+			continue
+		}
+		if err := channel.Send(ctx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("%s channel: %w", channel.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListNotifications returns a page of the user's inbox, newest first
+func (s *NotificationService) ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]*models.Notification, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListNotifications(ctx, userID, unreadOnly, limit, offset)
+}
+
+// UnreadCount returns how many notifications the user has not read
+func (s *NotificationService) UnreadCount(ctx context.Context, userID string) (int, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
+// MarkRead marks one notification as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id string) error {
+	return s.repo.MarkRead(ctx, id, userID, time.Now())
+}
+
+// MarkAllRead marks the whole inbox as read and returns how many
+// notifications changed
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	return s.repo.MarkAllRead(ctx, userID, time.Now())
+}
+
+// DeleteNotification removes a notification from the inbox
+func (s *NotificationService) DeleteNotification(ctx context.Context, userID, id string) error {
+	return s.repo.DeleteNotification(ctx, id, userID)
+}
+
+// userPreferences returns the user's preferences. Preferences are stored
+// under numeric user IDs; other IDs get the defaults.
+func (s *NotificationService) userPreferences(ctx context.Context, userID string) (map[string]interface{}, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return DefaultPreferences(), nil
+	}
+	return s.preferences.GetPreferences(ctx, id)
+}
+
+// preferenceEnabled reads a boolean preference, treating a missing value as
+// on
+func preferenceEnabled(preferences map[string]interface{}, key string) bool {
+	enabled, ok := preferences[key].(bool)
+	return !ok || enabled
+}
+
+// InAppChannel keeps notifications in the user's inbox
+type InAppChannel struct {
+	repo *repositories.NotificationRepository
+}
+
+// NewInAppChannel creates the in-app inbox channel
+func NewInAppChannel(repo *repositories.NotificationRepository) *InAppChannel {
+	return &InAppChannel{repo: repo}
+}
+
+// Name returns NotificationChannelInApp
+func (c *InAppChannel) Name() string {
+	return NotificationChannelInApp
+}
+
+// Send adds the notification to the inbox
+func (c *InAppChannel) Send(ctx context.Context, notification *models.Notification) error {
+	return c.repo.CreateNotification(ctx, notification)
+}
+
+// EmailChannel emails notifications to the user's account address
+type EmailChannel struct {
+	repo   *repositories.NotificationRepository
+	mailer Mailer
+}
+
+// NewEmailChannel creates an email channel that sends with mailer
+func NewEmailChannel(db *sql.DB, mailer Mailer) *EmailChannel {
+	return &EmailChannel{
+		repo:   repositories.NewNotificationRepository(database.NewDatabase(db)),
+		mailer: mailer,
+	}
+}
+
+// Name returns NotificationChannelEmail
+func (c *EmailChannel) Name() string {
+	return NotificationChannelEmail
+}
+
+// Send emails the notification's title and body
+func (c *EmailChannel) Send(ctx context.Context, notification *models.Notification) error {
+	email, err := c.repo.GetUserEmail(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+	return c.mailer.Send(ctx, EmailMessage{
+		To:      email,
+		Subject: notification.Title,
+		Body:    notification.Body,
+	})
+}
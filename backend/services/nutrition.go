@@ -1,17 +1,93 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+
+	"nutrition-platform/config"
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
 )
 
 // NutritionService handles nutrition-related operations
 type NutritionService struct {
-	db *sql.DB
+	db          *sql.DB
+	mealPlans   *repositories.MealPlanRepository
+	pdfRenderer *MealPlanPDFRenderer
 }
 
 // NewNutritionService creates a new NutritionService instance
-func NewNutritionService(db *sql.DB) *NutritionService {
+func NewNutritionService(db *sql.DB, exportConfig config.ExportConfig) *NutritionService {
 	return &NutritionService{
-		db: db,
+		db:          db,
+		mealPlans:   repositories.NewMealPlanRepository(database.NewDatabase(db)),
+		pdfRenderer: NewMealPlanPDFRenderer(exportConfig.PDFFontPath, NewMedicalDisclaimer()),
+	}
+}
+
+// GenerateMealPlanPDF renders one of the user's stored meal plans as a PDF
+func (s *NutritionService) GenerateMealPlanPDF(ctx context.Context, planID, userID int) ([]byte, error) {
+	plan, err := s.mealPlans.GetMealPlanByID(ctx, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pdfRenderer.Render(plan)
+}
+
+// ListMealPlans returns a user's meal plans, newest first
+func (s *NutritionService) ListMealPlans(ctx context.Context, userID, limit, offset int) ([]*models.MealPlan, error) {
+	return s.mealPlans.GetMealPlansByUserID(ctx, userID, limit, offset)
+}
+
+// GetMealPlan returns one of the user's meal plans
+func (s *NutritionService) GetMealPlan(ctx context.Context, planID, userID int) (*models.MealPlan, error) {
+	return s.mealPlans.GetMealPlanByID(ctx, planID, userID)
+}
+
+// UpdateMealPlan applies a partial update to one of the user's meal plans.
+// Replacing the days recalculates the plan's totals.
+func (s *NutritionService) UpdateMealPlan(ctx context.Context, planID, userID int, req *models.UpdateMealPlanRequest) (*models.MealPlan, error) {
+	plan, err := s.mealPlans.GetMealPlanByID(ctx, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name must not be empty", ErrInvalidMealPlanRequest)
+		}
+		plan.Name = name
+	}
+	if req.NameAr != nil {
+		plan.NameAr = req.NameAr
+	}
+	if req.Description != nil {
+		plan.Description = req.Description
+	}
+	if req.Days != nil {
+		for i, day := range req.Days {
+			if day.DayNumber != i+1 {
+				return nil, fmt.Errorf("%w: days must be numbered from 1 in order", ErrInvalidMealPlanRequest)
+			}
+		}
+		plan.Days = req.Days
 	}
+	if req.IsActive != nil {
+		plan.IsActive = *req.IsActive
+	}
+
+	if err := s.mealPlans.UpdateMealPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// DeleteMealPlan deletes one of the user's meal plans
+func (s *NutritionService) DeleteMealPlan(ctx context.Context, planID, userID int) error {
+	return s.mealPlans.DeleteMealPlan(ctx, planID, userID)
 }
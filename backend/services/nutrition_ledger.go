@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+// Sources that contribute to the nutrition ledger
+const (
+	LedgerSourceMeal   = "meal"
+	LedgerSourceFood   = "food"
+	LedgerSourceRecipe = "recipe"
+	LedgerSourceWater  = "water"
+)
+
+// maxLedgerDays bounds range queries so a single request stays cheap
+const maxLedgerDays = 92
+
+// LedgerEntry is one item counted towards a day's intake
+type LedgerEntry struct {
+	Source     string               `json:"source"`
+	ID         string               `json:"id"`
+	Name       string               `json:"name,omitempty"`
+	MealType   string               `json:"meal_type,omitempty"`
+	ConsumedAt time.Time            `json:"consumed_at"`
+	Quantity   float64              `json:"quantity,omitempty"`
+	Unit       string               `json:"unit,omitempty"`
+	Nutrition  models.NutritionInfo `json:"nutrition"`
+	WaterMl    int                  `json:"water_ml,omitempty"`
+}
+
+// LedgerBudget compares the consumed amount of one nutrient with its target.
+// Remaining is negative when the target was exceeded.
+type LedgerBudget struct {
+	Target    float64 `json:"target"`
+	Consumed  float64 `json:"consumed"`
+	Remaining float64 `json:"remaining"`
+	Progress  float64 `json:"progress_percent"`
+}
+
+// DailyLedger is everything a user ate and drank on one local calendar day
+type DailyLedger struct {
+	Date       string                          `json:"date"`
+	Timezone   string                          `json:"timezone"`
+	Totals     models.NutritionInfo            `json:"totals"`
+	WaterMl    int                             `json:"water_ml"`
+	BySource   map[string]models.NutritionInfo `json:"by_source"`
+	ByMealType map[string]models.NutritionInfo `json:"by_meal_type"`
+	GoalID     *int                            `json:"goal_id,omitempty"`
+	Budgets    map[string]LedgerBudget         `json:"budgets"`
+	Entries    []LedgerEntry                   `json:"entries"`
+}
+
+// LedgerSummary aggregates daily ledgers over a date range
+type LedgerSummary struct {
+	StartDate     string               `json:"start_date"`
+	EndDate       string               `json:"end_date"`
+	Days          int                  `json:"days"`
+	DaysLogged    int                  `json:"days_logged"`
+	EntriesLogged int                  `json:"entries_logged"`
+	Totals        models.NutritionInfo `json:"totals"`
+	WaterMl       int                  `json:"water_ml"`
+	DailyAverages models.NutritionInfo `json:"daily_averages"`
+	AverageWater  int                  `json:"average_water_ml"`
+	DaysOnTarget  int                  `json:"days_on_calorie_target"`
+	Ledgers       []*DailyLedger       `json:"ledgers"`
+}
+
+// NutritionLedgerService combines JSON-store meals, logged foods and recipes
+// and water intake into per-day totals measured against the active goal
+type NutritionLedgerService struct {
+	logs        *repositories.FoodLogRepository
+	meals       *repositories.MealEntryRepository
+	water       *repositories.WaterIntakeRepository
+	users       *repositories.UserRepository
+	preferences *UserPreferencesService
+}
+
+// NewNutritionLedgerService creates a new NutritionLedgerService instance
+func NewNutritionLedgerService(db *sql.DB) *NutritionLedgerService {
+	wrapped := database.NewDatabase(db)
+	return &NutritionLedgerService{
+		logs:        repositories.NewFoodLogRepository(wrapped),
+		meals:       repositories.NewMealEntryRepository(wrapped),
+		water:       repositories.NewWaterIntakeRepository(wrapped),
+		users:       repositories.NewUserRepository(wrapped),
+		preferences: NewUserPreferencesService(db),
+	}
+}
+
+// GetDailyLedger builds the ledger for one calendar day. The year, month and
+// day of date are taken as a day in the user's preferred time zone; a zero
+// date means today there.
+func (s *NutritionLedgerService) GetDailyLedger(ctx context.Context, userID int, date time.Time) (*DailyLedger, error) {
+	ledgers, err := s.buildLedgers(ctx, userID, date, 1)
+	if err != nil {
+		return nil, err
+	}
+	return ledgers[0], nil
+}
+
+// GetLedgerSummary builds the ledgers for the given number of days ending on
+// endDate, interpreted like the date of GetDailyLedger, and aggregates them
+func (s *NutritionLedgerService) GetLedgerSummary(ctx context.Context, userID int, endDate time.Time, days int) (*LedgerSummary, error) {
+	if days < 1 || days > maxLedgerDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", maxLedgerDays)
+	}
+
+	ledgers, err := s.buildLedgers(ctx, userID, endDate, days)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &LedgerSummary{
+		StartDate: ledgers[0].Date,
+		EndDate:   ledgers[len(ledgers)-1].Date,
+		Days:      days,
+		Ledgers:   ledgers,
+	}
+	for _, ledger := range ledgers {
+		if len(ledger.Entries) > 0 {
+			summary.DaysLogged++
+		}
+		summary.EntriesLogged += len(ledger.Entries)
+		summary.Totals.Add(ledger.Totals)
+		summary.WaterMl += ledger.WaterMl
+		if budget, ok := ledger.Budgets["calories"]; ok && ledger.Totals.Calories > 0 && budget.Remaining >= 0 {
+			summary.DaysOnTarget++
+		}
+	}
+
+	// Averages only count days with something logged so gaps do not drag
+	// them towards zero
+	if summary.DaysLogged > 0 {
+		summary.DailyAverages = summary.Totals.Scaled(1 / float64(summary.DaysLogged)).Rounded()
+		summary.AverageWater = summary.WaterMl / summary.DaysLogged
+	}
+	summary.Totals = summary.Totals.Rounded()
+
+	return summary, nil
+}
+
+// buildLedgers loads every source once for the days ending on lastDay and
+// splits the entries into local calendar days
+func (s *NutritionLedgerService) buildLedgers(ctx context.Context, userID int, lastDay time.Time, days int) ([]*DailyLedger, error) {
+	loc := s.preferences.GetTimezone(ctx, userID)
+	if lastDay.IsZero() {
+		lastDay = time.Now().In(loc)
+	}
+	first := time.Date(lastDay.Year(), lastDay.Month(), lastDay.Day()-(days-1), 0, 0, 0, 0, loc)
+	end := first.AddDate(0, 0, days)
+
+	goals, err := s.users.GetActiveNutritionGoals(uint(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	ledgers := make([]*DailyLedger, days)
+	for i := range ledgers {
+		day := first.AddDate(0, 0, i)
+		ledgers[i] = &DailyLedger{
+			Date:       day.Format("2006-01-02"),
+			Timezone:   loc.String(),
+			BySource:   map[string]models.NutritionInfo{},
+			ByMealType: map[string]models.NutritionInfo{},
+			Entries:    []LedgerEntry{},
+		}
+		if goal := goalForDay(goals, day); goal != nil {
+			ledgers[i].GoalID = &goal.ID
+		}
+	}
+
+	entries, err := s.collectEntries(ctx, userID, first, end)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		local := entry.ConsumedAt.In(loc)
+		index := daysBetween(first, time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc))
+		if index < 0 || index >= days {
+			continue
+		}
+
+		ledger := ledgers[index]
+		ledger.Entries = append(ledger.Entries, entry)
+		ledger.WaterMl += entry.WaterMl
+		if entry.Source == LedgerSourceWater {
+			continue
+		}
+		ledger.Totals.Add(entry.Nutrition)
+		bySource := ledger.BySource[entry.Source]
+		bySource.Add(entry.Nutrition)
+		ledger.BySource[entry.Source] = bySource
+		mealType := entry.MealType
+		if mealType == "" {
+			mealType = "other"
+		}
+		byMealType := ledger.ByMealType[mealType]
+		byMealType.Add(entry.Nutrition)
+		ledger.ByMealType[mealType] = byMealType
+	}
+
+	for i, ledger := range ledgers {
+		ledger.Totals = ledger.Totals.Rounded()
+		for key, n := range ledger.BySource {
+			ledger.BySource[key] = n.Rounded()
+		}
+		for key, n := range ledger.ByMealType {
+			ledger.ByMealType[key] = n.Rounded()
+		}
+		ledger.Budgets = ledgerBudgets(goalForDay(goals, first.AddDate(0, 0, i)), ledger)
+	}
+
+	return ledgers, nil
+}
+
+// collectEntries gathers the entries of every source consumed in [from, to)
+func (s *NutritionLedgerService) collectEntries(ctx context.Context, userID int, from, to time.Time) ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+
+	// Meals created through the meals API
+	meals, err := s.meals.GetMealEntriesByDateRange(ctx, strconv.Itoa(userID), from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, meal := range meals {
+		entries = append(entries, LedgerEntry{
+			Source:     LedgerSourceMeal,
+			ID:         meal.ID,
+			Name:       meal.Name,
+			MealType:   meal.MealType,
+			ConsumedAt: meal.CreatedAt,
+			Nutrition: models.NutritionInfo{
+				Calories:      float64(meal.Calories),
+				Protein:       meal.Protein,
+				Carbohydrates: meal.Carbs,
+				Fat:           meal.Fat,
+				Fiber:         meal.Fiber,
+				Sugar:         meal.Sugar,
+				Sodium:        meal.Sodium,
+			},
+		})
+	}
+
+	logs, err := s.logs.GetFoodLogsByDateRange(ctx, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	for _, log := range logs {
+		entry := LedgerEntry{
+			Source:     LedgerSourceFood,
+			ID:         strconv.Itoa(log.ID),
+			Name:       log.FoodName,
+			MealType:   log.MealType,
+			ConsumedAt: log.LoggedAt,
+			Quantity:   log.InputQuantity,
+			Unit:       log.InputUnit,
+			Nutrition:  log.Nutrition,
+		}
+		if log.RecipeID != nil {
+			entry.Source = LedgerSourceRecipe
+		}
+		entries = append(entries, entry)
+	}
+
+	water, err := s.water.GetWaterIntakeByDateRange(ctx, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range water {
+		entries = append(entries, LedgerEntry{
+			Source:     LedgerSourceWater,
+			ID:         strconv.Itoa(w.ID),
+			ConsumedAt: w.Date,
+			Quantity:   float64(w.AmountMl),
+			Unit:       UnitMilliliter,
+			WaterMl:    w.AmountMl,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ConsumedAt.Before(entries[j].ConsumedAt)
+	})
+
+	return entries, nil
+}
+
+// goalForDay picks the newest active goal whose date window covers day.
+// goals must be ordered newest first.
+func goalForDay(goals []*models.NutritionGoal, day time.Time) *models.NutritionGoal {
+	dayEnd := day.AddDate(0, 0, 1)
+	for _, goal := range goals {
+		if goal.StartDate != nil && !goal.StartDate.Before(dayEnd) {
+			continue
+		}
+		if goal.EndDate != nil && goal.EndDate.Before(day) {
+			continue
+		}
+		return goal
+	}
+	return nil
+}
+
+// ledgerBudgets compares the day's totals with every target set on the goal
+func ledgerBudgets(goal *models.NutritionGoal, ledger *DailyLedger) map[string]LedgerBudget {
+	budgets := map[string]LedgerBudget{}
+	if goal == nil {
+		return budgets
+	}
+
+	add := func(key string, target, consumed float64) {
+		budget := LedgerBudget{
+			Target:    target,
+			Consumed:  consumed,
+			Remaining: roundTo(target-consumed, 1),
+		}
+		if target > 0 {
+			budget.Progress = roundTo(consumed/target*100, 1)
+		}
+		budgets[key] = budget
+	}
+
+	if goal.DailyCalories != nil {
+		add("calories", float64(*goal.DailyCalories), ledger.Totals.Calories)
+	}
+	if goal.ProteinGrams != nil {
+		add("protein", *goal.ProteinGrams, ledger.Totals.Protein)
+	}
+	if goal.CarbsGrams != nil {
+		add("carbs", *goal.CarbsGrams, ledger.Totals.Carbohydrates)
+	}
+	if goal.FatGrams != nil {
+		add("fat", *goal.FatGrams, ledger.Totals.Fat)
+	}
+	if goal.FiberGrams != nil {
+		add("fiber", *goal.FiberGrams, ledger.Totals.Fiber)
+	}
+	if goal.SugarGrams != nil {
+		add("sugar", *goal.SugarGrams, ledger.Totals.Sugar)
+	}
+	if goal.SodiumMg != nil {
+		add("sodium", float64(*goal.SodiumMg), ledger.Totals.Sodium)
+	}
+	if goal.WaterMl != nil {
+		add("water_ml", float64(*goal.WaterMl), float64(ledger.WaterMl))
+	}
+
+	return budgets
+}
+
+func roundTo(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// daysBetween counts calendar days from a to b, both local midnights. It
+// rounds so DST transitions do not shift the result.
+func daysBetween(a, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / 24))
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordResetTokenTTL is how long a reset link stays valid
+const PasswordResetTokenTTL = time.Hour
+
+// ErrInvalidResetToken is returned for unknown, expired or already used tokens
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetService issues and redeems password reset tokens
+type PasswordResetService struct {
+	repo     *repositories.PasswordResetRepository
+	mailer   Mailer
+	resetURL string
+}
+
+// NewPasswordResetService creates a new PasswordResetService instance
+func NewPasswordResetService(db *sql.DB, mailer Mailer, emailConfig config.EmailConfig) *PasswordResetService {
+	return &PasswordResetService{
+		repo:     repositories.NewPasswordResetRepository(database.NewDatabase(db)),
+		mailer:   mailer,
+		resetURL: emailConfig.ResetURL,
+	}
+}
+
+// RequestReset emails a reset link to the account with the given address.
+// Unknown addresses are ignored without error so callers cannot use the
+// endpoint to discover which emails are registered.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email, requestIP string) error {
+	userID, err := s.repo.GetUserIDByEmail(ctx, email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		return err
+	}
+
+	// Only the most recent link may be used
+	if err := s.repo.InvalidateUserTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	record := &models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(PasswordResetTokenTTL),
+		RequestIP: requestIP,
+	}
+	if err := s.repo.CreateToken(ctx, record); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, EmailMessage{
+		To:      email,
+		Subject: "Reset your password",
+		Body:    s.resetEmailBody(token),
+	})
+}
+
+// ResetPassword redeems a token, sets the new password and signs the user
+// out of every session
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	record, err := s.repo.GetTokenByHash(ctx, hashResetToken(token))
+	if err != nil {
+		if err.Error() == "password reset token not found" {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if !record.IsUsable(time.Now()) {
+		return ErrInvalidResetToken
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	redeemed, err := s.repo.RedeemToken(ctx, record, string(passwordHash), time.Now())
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return ErrInvalidResetToken
+	}
+
+	log.Printf("Password reset completed for user %s", record.UserID)
+	return nil
+}
+
+// CleanupExpiredTokens removes reset tokens that can no longer be redeemed
+func (s *PasswordResetService) CleanupExpiredTokens(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredTokens(ctx, time.Now())
+}
+
+func (s *PasswordResetService) resetEmailBody(token string) string {
+	link := s.resetURL + "?token=" + url.QueryEscape(token)
+	return fmt.Sprintf(`We received a request to reset your password.
+
+Open the link below to choose a new password. It expires in %d minutes and can only be used once.
+
+%s
+
+If you did not request this, you can ignore this email; your password will not change.`,
+		int(PasswordResetTokenTTL.Minutes()), link)
+}
+
+func generateResetToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
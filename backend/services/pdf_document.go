@@ -0,0 +1,909 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A4 page dimensions in PDF points
+const (
+	PDFPageWidthA4  = 595.28
+	PDFPageHeightA4 = 841.89
+)
+
+// PDFDocument is a small PDF 1.4 writer used for printable exports.
+// It supports multiple pages, text in the standard Helvetica fonts and,
+// when a TrueType font is supplied, Unicode text (including shaped Arabic)
+// through an embedded Identity-H encoded font. Exports only need text,
+// rules and a subset TrueType font, so this stays in-tree rather than
+// adding a PDF library and its font stack as a dependency.
+type PDFDocument struct {
+	pageWidth  float64
+	pageHeight float64
+	pages      []*bytes.Buffer
+	current    int
+	font       *TrueTypeFont
+	usedGlyphs map[uint16]rune
+}
+
+// NewPDFDocument creates an empty document. font may be nil, in which case
+// only Latin-1 text can be rendered.
+func NewPDFDocument(pageWidth, pageHeight float64, font *TrueTypeFont) *PDFDocument {
+	return &PDFDocument{
+		pageWidth:  pageWidth,
+		pageHeight: pageHeight,
+		current:    -1,
+		font:       font,
+		usedGlyphs: make(map[uint16]rune),
+	}
+}
+
+// SupportsUnicode reports whether the document can render non-Latin text
+func (d *PDFDocument) SupportsUnicode() bool {
+	return d.font != nil
+}
+
+// PageWidth returns the page width in points
+func (d *PDFDocument) PageWidth() float64 { return d.pageWidth }
+
+// PageHeight returns the page height in points
+func (d *PDFDocument) PageHeight() float64 { return d.pageHeight }
+
+// AddPage appends a new blank page and makes it current
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.current = len(d.pages) - 1
+}
+
+// PageCount returns the number of pages added so far
+func (d *PDFDocument) PageCount() int {
+	return len(d.pages)
+}
+
+// SetPage makes an existing page current so more content can be drawn on it
+func (d *PDFDocument) SetPage(index int) {
+	if index >= 0 && index < len(d.pages) {
+		d.current = index
+	}
+}
+
+// Text draws text with its baseline starting at (x, y). Coordinates are
+// measured from the bottom-left corner of the page.
+func (d *PDFDocument) Text(x, y, size float64, bold bool, text string) {
+	if d.current < 0 || text == "" {
+		return
+	}
+	page := d.pages[d.current]
+	fontName, encoded := d.encode(text, bold)
+	if encoded == "" {
+		return
+	}
+	fmt.Fprintf(page, "BT /%s %.2f Tf %.2f %.2f Td %s Tj ET\n", fontName, size, x, y, encoded)
+}
+
+// TextRight draws text so that it ends at x
+func (d *PDFDocument) TextRight(x, y, size float64, bold bool, text string) {
+	d.Text(x-d.TextWidth(text, size, bold), y, size, bold, text)
+}
+
+// TextWidth returns the rendered width of text in points
+func (d *PDFDocument) TextWidth(text string, size float64, bold bool) float64 {
+	var units float64
+	if d.font != nil {
+		for _, r := range text {
+			if gid, ok := d.font.GlyphIndex(r); ok {
+				units += d.font.Advance(gid)
+			}
+		}
+	} else {
+		for _, r := range text {
+			if r > 0xFF {
+				continue
+			}
+			units += helveticaWidth(byte(r), bold)
+		}
+	}
+	return units * size / 1000
+}
+
+// Line draws a straight line between two points
+func (d *PDFDocument) Line(x1, y1, x2, y2, width float64) {
+	if d.current < 0 {
+		return
+	}
+	fmt.Fprintf(d.pages[d.current], "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// FillRect fills a rectangle with a gray level between 0 (black) and 1 (white)
+func (d *PDFDocument) FillRect(x, y, w, h, gray float64) {
+	if d.current < 0 {
+		return
+	}
+	fmt.Fprintf(d.pages[d.current], "q %.3f g %.2f %.2f %.2f %.2f re f Q\n", gray, x, y, w, h)
+}
+
+// Rect strokes the outline of a rectangle
+func (d *PDFDocument) Rect(x, y, w, h, width float64) {
+	if d.current < 0 {
+		return
+	}
+	fmt.Fprintf(d.pages[d.current], "%.2f w %.2f %.2f %.2f %.2f re S\n", width, x, y, w, h)
+}
+
+// WrapText splits text into lines that fit within maxWidth
+func (d *PDFDocument) WrapText(text string, size float64, bold bool, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			candidate := line + " " + word
+			if d.TextWidth(candidate, size, bold) > maxWidth {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// encode converts text into a PDF string operand for the appropriate font
+func (d *PDFDocument) encode(text string, bold bool) (string, string) {
+	if d.font != nil {
+		var buf strings.Builder
+		for _, r := range text {
+			gid, ok := d.font.GlyphIndex(r)
+			if !ok {
+				continue
+			}
+			d.usedGlyphs[gid] = r
+			fmt.Fprintf(&buf, "%04X", gid)
+		}
+		if buf.Len() == 0 {
+			return "", ""
+		}
+		return "FU", "<" + buf.String() + ">"
+	}
+
+	var buf strings.Builder
+	for _, r := range text {
+		if r > 0xFF {
+			continue
+		}
+		switch r {
+		case '(', ')', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		default:
+			if r < 0x20 || (r >= 0x7F && r < 0xA0) {
+				continue
+			}
+			buf.WriteByte(byte(r))
+		}
+	}
+	if buf.Len() == 0 {
+		return "", ""
+	}
+	fontName := "F1"
+	if bold {
+		fontName = "F2"
+	}
+	return fontName, "(" + buf.String() + ")"
+}
+
+// Bytes serializes the document
+func (d *PDFDocument) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Object numbers are allocated up front so pages can reference fonts
+	catalogID := w.reserve()
+	pagesID := w.reserve()
+	helveticaID := w.reserve()
+	helveticaBoldID := w.reserve()
+	var unicodeFontID int
+	if d.font != nil {
+		unicodeFontID = w.reserve()
+	}
+
+	pageIDs := make([]int, len(d.pages))
+	contentIDs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = w.reserve()
+		contentIDs[i] = w.reserve()
+	}
+
+	w.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	w.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageIDs)))
+
+	w.object(helveticaID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	w.object(helveticaBoldID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	fonts := fmt.Sprintf("/F1 %d 0 R /F2 %d 0 R", helveticaID, helveticaBoldID)
+	if d.font != nil {
+		if err := d.writeUnicodeFont(w, unicodeFontID); err != nil {
+			return nil, err
+		}
+		fonts += fmt.Sprintf(" /FU %d 0 R", unicodeFontID)
+	}
+
+	for i, page := range d.pages {
+		w.object(pageIDs[i], fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pagesID, d.pageWidth, d.pageHeight, fonts, contentIDs[i]))
+		if err := w.stream(contentIDs[i], "", page.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.finish(catalogID), nil
+}
+
+// writeUnicodeFont writes the Type0 font, its descendant CID font, the
+// descriptor, the embedded font program and a ToUnicode map
+func (d *PDFDocument) writeUnicodeFont(w *pdfWriter, fontID int) error {
+	cidFontID := w.reserve()
+	descriptorID := w.reserve()
+	fileID := w.reserve()
+	toUnicodeID := w.reserve()
+
+	f := d.font
+
+	glyphs := make([]int, 0, len(d.usedGlyphs))
+	for gid := range d.usedGlyphs {
+		glyphs = append(glyphs, int(gid))
+	}
+	sort.Ints(glyphs)
+
+	program, err := f.Subset(glyphs)
+	if err != nil {
+		return err
+	}
+	baseName := subsetTag(glyphs) + "+" + f.PostScriptName()
+
+	w.object(fontID, fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		baseName, cidFontID, toUnicodeID))
+
+	var widths strings.Builder
+	for _, gid := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, int(f.Advance(uint16(gid))))
+	}
+
+	w.object(cidFontID, fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		baseName, descriptorID, strings.TrimSpace(widths.String())))
+
+	bbox := f.BoundingBox()
+	w.object(descriptorID, fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		baseName, bbox[0], bbox[1], bbox[2], bbox[3], f.Ascent(), f.Descent(), f.Ascent(), fileID))
+
+	if err := w.stream(fileID, fmt.Sprintf("/Length1 %d", len(program)), program); err != nil {
+		return err
+	}
+
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	cmap.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	cmap.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	cmap.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(glyphs); start += 100 {
+		end := start + 100
+		if end > len(glyphs) {
+			end = len(glyphs)
+		}
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, gid := range glyphs[start:end] {
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", gid, utf16Hex(d.usedGlyphs[uint16(gid)]))
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+
+	return w.stream(toUnicodeID, "", cmap.Bytes())
+}
+
+// subsetTag derives the six-letter prefix PDF requires on the name of a
+// subset font from the glyphs it keeps
+func subsetTag(glyphs []int) string {
+	h := sha1.New()
+	for _, gid := range glyphs {
+		binary.Write(h, binary.BigEndian, uint16(gid))
+	}
+	sum := h.Sum(nil)
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	return string(tag)
+}
+
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
+
+// pdfWriter tracks object offsets while the document is serialized
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) reserve() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *pdfWriter) object(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (w *pdfWriter) stream(id int, extra string, data []byte) error {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("failed to compress PDF stream: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress PDF stream: %w", err)
+	}
+
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode %s>>\nstream\n", id, compressed.Len(), extra)
+	w.buf.Write(compressed.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+	return nil
+}
+
+func (w *pdfWriter) finish(rootID int) []byte {
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, rootID, xref)
+	return w.buf.Bytes()
+}
+
+// TrueTypeFont holds the parts of a TrueType font needed to embed it in a PDF
+type TrueTypeFont struct {
+	tables     map[string][]byte
+	longLoca   bool
+	numGlyphs  int
+	name       string
+	unitsPerEm float64
+	advances   []uint16
+	cmap       map[rune]uint16
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+}
+
+// LoadTrueTypeFont reads and parses a .ttf file
+func LoadTrueTypeFont(path string) (*TrueTypeFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font %s: %w", path, err)
+	}
+	return ParseTrueTypeFont(data)
+}
+
+// ParseTrueTypeFont parses the head, hhea, maxp, hmtx, cmap and name tables
+func ParseTrueTypeFont(data []byte) (*TrueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("font data too short")
+	}
+
+	tables := make(map[string][]byte)
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + i*16
+		if rec+16 > len(data) {
+			return nil, fmt.Errorf("truncated font table directory")
+		}
+		tag := string(data[rec : rec+4])
+		offset := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("font table %s out of range", tag)
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+
+	// glyf and loca rule out CFF-flavoured OpenType fonts, which cannot be
+	// embedded as FontFile2
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap", "glyf", "loca"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("font is missing required table %s", tag)
+		}
+	}
+
+	f := &TrueTypeFont{tables: tables, name: "EmbeddedFont"}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, fmt.Errorf("invalid head table")
+	}
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, fmt.Errorf("invalid unitsPerEm")
+	}
+	for i := 0; i < 4; i++ {
+		f.bbox[i] = int16(binary.BigEndian.Uint16(head[36+i*2:]))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+
+	hhea := tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, fmt.Errorf("invalid hhea table")
+	}
+	f.ascent = int16(binary.BigEndian.Uint16(hhea[4:]))
+	f.descent = int16(binary.BigEndian.Uint16(hhea[6:]))
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	maxp := tables["maxp"]
+	if len(maxp) < 6 {
+		return nil, fmt.Errorf("invalid maxp table")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	f.numGlyphs = numGlyphs
+
+	hmtx := tables["hmtx"]
+	if numHMetrics == 0 || len(hmtx) < numHMetrics*4 {
+		return nil, fmt.Errorf("invalid hmtx table")
+	}
+	f.advances = make([]uint16, numGlyphs)
+	for i := 0; i < numGlyphs; i++ {
+		if i < numHMetrics {
+			f.advances[i] = binary.BigEndian.Uint16(hmtx[i*4:])
+		} else {
+			f.advances[i] = f.advances[numHMetrics-1]
+		}
+	}
+
+	locaEntry := 2
+	if f.longLoca {
+		locaEntry = 4
+	}
+	if len(tables["loca"]) < (numGlyphs+1)*locaEntry {
+		return nil, fmt.Errorf("invalid loca table")
+	}
+
+	cmap, err := parseCmap(tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.cmap = cmap
+
+	if name, ok := tables["name"]; ok {
+		if ps := parsePostScriptName(name); ps != "" {
+			f.name = ps
+		}
+	}
+
+	return f, nil
+}
+
+// subsetTables are the tables kept in an embedded font program, enough for
+// it to remain a valid standalone font. Layout tables (GSUB, GPOS, kern, ...)
+// are dropped because text is shaped before it reaches the PDF.
+var subsetTables = []string{"OS/2", "cmap", "cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "post", "prep"}
+
+// Subset returns a font program that keeps the outlines of glyph 0, the
+// given glyphs and the components they are built from. Every other glyph
+// is emptied, so glyph IDs stay the same and content streams can use them
+// unchanged.
+func (f *TrueTypeFont) Subset(glyphs []int) ([]byte, error) {
+	keep := make(map[int]bool)
+	pending := append([]int{0}, glyphs...)
+	for len(pending) > 0 {
+		gid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if keep[gid] || gid >= f.numGlyphs {
+			continue
+		}
+		keep[gid] = true
+		components, err := compositeComponents(f.glyph(gid))
+		if err != nil {
+			return nil, fmt.Errorf("glyph %d: %w", gid, err)
+		}
+		for _, component := range components {
+			if !keep[component] {
+				pending = append(pending, component)
+			}
+		}
+	}
+
+	var glyf bytes.Buffer
+	loca := make([]byte, (f.numGlyphs+1)*4)
+	for gid := 0; gid < f.numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(loca[gid*4:], uint32(glyf.Len()))
+		if keep[gid] {
+			glyf.Write(f.glyph(gid))
+			// Glyph offsets stay 4-byte aligned
+			for glyf.Len()%4 != 0 {
+				glyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[f.numGlyphs*4:], uint32(glyf.Len()))
+
+	tables := make(map[string][]byte, len(subsetTables))
+	for _, tag := range subsetTables {
+		if table, ok := f.tables[tag]; ok {
+			tables[tag] = table
+		}
+	}
+	tables["glyf"] = glyf.Bytes()
+	tables["loca"] = loca
+
+	// The rewritten loca uses 32-bit offsets, and the whole-font checksum
+	// adjustment no longer applies
+	head := append([]byte{}, f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	tables["head"] = head
+
+	return writeTrueTypeFont(tables), nil
+}
+
+// glyph returns the glyf data of a glyph, empty for glyphs without outlines
+func (f *TrueTypeFont) glyph(gid int) []byte {
+	loca := f.tables["loca"]
+	var start, end int
+	if f.longLoca {
+		start = int(binary.BigEndian.Uint32(loca[gid*4:]))
+		end = int(binary.BigEndian.Uint32(loca[gid*4+4:]))
+	} else {
+		start = int(binary.BigEndian.Uint16(loca[gid*2:])) * 2
+		end = int(binary.BigEndian.Uint16(loca[gid*2+2:])) * 2
+	}
+	glyf := f.tables["glyf"]
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// Composite glyph flags from the TrueType glyf table
+const (
+	glyfArgsAreWords   = 0x0001
+	glyfHaveScale      = 0x0008
+	glyfMoreComponents = 0x0020
+	glyfHaveXYScale    = 0x0040
+	glyfHaveTwoByTwo   = 0x0080
+)
+
+// compositeComponents lists the glyphs a composite glyph is built from
+func compositeComponents(glyph []byte) ([]int, error) {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil, nil
+	}
+
+	var components []int
+	pos := 10
+	for {
+		if pos+4 > len(glyph) {
+			return nil, fmt.Errorf("truncated composite glyph")
+		}
+		flags := binary.BigEndian.Uint16(glyph[pos:])
+		components = append(components, int(binary.BigEndian.Uint16(glyph[pos+2:])))
+		pos += 4
+		if flags&glyfArgsAreWords != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&glyfHaveScale != 0:
+			pos += 2
+		case flags&glyfHaveXYScale != 0:
+			pos += 4
+		case flags&glyfHaveTwoByTwo != 0:
+			pos += 8
+		}
+		if flags&glyfMoreComponents == 0 {
+			return components, nil
+		}
+	}
+}
+
+// writeTrueTypeFont serializes tables into a font file with a sorted,
+// checksummed table directory
+func writeTrueTypeFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	header := make([]byte, 12+numTables*16)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(numTables*16-searchRange))
+
+	var body bytes.Buffer
+	for i, tag := range tags {
+		table := tables[tag]
+		rec := header[12+i*16:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], tableChecksum(table))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(header)+body.Len()))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(table)))
+		body.Write(table)
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+	return append(header, body.Bytes()...)
+}
+
+func tableChecksum(table []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(table); i += 4 {
+		var word [4]byte
+		copy(word[:], table[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// GlyphIndex maps a rune to a glyph, reporting false when the font lacks it
+func (f *TrueTypeFont) GlyphIndex(r rune) (uint16, bool) {
+	gid, ok := f.cmap[r]
+	return gid, ok && gid != 0
+}
+
+// Advance returns the advance width of a glyph in 1/1000 em units
+func (f *TrueTypeFont) Advance(gid uint16) float64 {
+	if int(gid) >= len(f.advances) {
+		return 0
+	}
+	return float64(f.advances[gid]) * 1000 / f.unitsPerEm
+}
+
+// PostScriptName returns a PDF-safe name for the font
+func (f *TrueTypeFont) PostScriptName() string {
+	return f.name
+}
+
+// BoundingBox returns the font bounding box in 1/1000 em units
+func (f *TrueTypeFont) BoundingBox() [4]int {
+	var box [4]int
+	for i, v := range f.bbox {
+		box[i] = int(float64(v) * 1000 / f.unitsPerEm)
+	}
+	return box
+}
+
+// Ascent returns the typographic ascent in 1/1000 em units
+func (f *TrueTypeFont) Ascent() int {
+	return int(float64(f.ascent) * 1000 / f.unitsPerEm)
+}
+
+// Descent returns the typographic descent in 1/1000 em units
+func (f *TrueTypeFont) Descent() int {
+	return int(float64(f.descent) * 1000 / f.unitsPerEm)
+}
+
+// parseCmap prefers a full Unicode (format 12) subtable and falls back to
+// the BMP (format 4) subtable
+func parseCmap(table []byte) (map[rune]uint16, error) {
+	if len(table) < 4 {
+		return nil, fmt.Errorf("invalid cmap table")
+	}
+	numSubtables := int(binary.BigEndian.Uint16(table[2:]))
+	var format4, format12 []byte
+	for i := 0; i < numSubtables; i++ {
+		rec := 4 + i*8
+		if rec+8 > len(table) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(table[rec:])
+		encoding := binary.BigEndian.Uint16(table[rec+2:])
+		offset := int(binary.BigEndian.Uint32(table[rec+4:]))
+		if offset+2 > len(table) {
+			continue
+		}
+		sub := table[offset:]
+		isUnicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !isUnicode {
+			continue
+		}
+		switch binary.BigEndian.Uint16(sub) {
+		case 4:
+			if format4 == nil {
+				format4 = sub
+			}
+		case 12:
+			if format12 == nil {
+				format12 = sub
+			}
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		return parseCmapFormat12(format12)
+	case format4 != nil:
+		return parseCmapFormat4(format4)
+	}
+	return nil, fmt.Errorf("font has no supported Unicode cmap subtable")
+}
+
+func parseCmapFormat4(sub []byte) (map[rune]uint16, error) {
+	if len(sub) < 14 {
+		return nil, fmt.Errorf("invalid cmap format 4 subtable")
+	}
+	segCount := int(binary.BigEndian.Uint16(sub[6:])) / 2
+	endCodes := 14
+	startCodes := endCodes + segCount*2 + 2
+	idDeltas := startCodes + segCount*2
+	idRangeOffsets := idDeltas + segCount*2
+	if idRangeOffsets+segCount*2 > len(sub) {
+		return nil, fmt.Errorf("truncated cmap format 4 subtable")
+	}
+
+	cmap := make(map[rune]uint16)
+	for i := 0; i < segCount; i++ {
+		end := int(binary.BigEndian.Uint16(sub[endCodes+i*2:]))
+		start := int(binary.BigEndian.Uint16(sub[startCodes+i*2:]))
+		delta := int(binary.BigEndian.Uint16(sub[idDeltas+i*2:]))
+		rangeOffsetPos := idRangeOffsets + i*2
+		rangeOffset := int(binary.BigEndian.Uint16(sub[rangeOffsetPos:]))
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			var gid int
+			if rangeOffset == 0 {
+				gid = (c + delta) & 0xFFFF
+			} else {
+				addr := rangeOffsetPos + rangeOffset + 2*(c-start)
+				if addr+2 > len(sub) {
+					continue
+				}
+				gid = int(binary.BigEndian.Uint16(sub[addr:]))
+				if gid != 0 {
+					gid = (gid + delta) & 0xFFFF
+				}
+			}
+			if gid != 0 {
+				cmap[rune(c)] = uint16(gid)
+			}
+		}
+	}
+	return cmap, nil
+}
+
+func parseCmapFormat12(sub []byte) (map[rune]uint16, error) {
+	if len(sub) < 16 {
+		return nil, fmt.Errorf("invalid cmap format 12 subtable")
+	}
+	numGroups := int(binary.BigEndian.Uint32(sub[12:]))
+	if 16+numGroups*12 > len(sub) {
+		return nil, fmt.Errorf("truncated cmap format 12 subtable")
+	}
+
+	cmap := make(map[rune]uint16)
+	for i := 0; i < numGroups; i++ {
+		group := sub[16+i*12:]
+		start := binary.BigEndian.Uint32(group)
+		end := binary.BigEndian.Uint32(group[4:])
+		startGlyph := binary.BigEndian.Uint32(group[8:])
+		for c := start; c <= end && c <= 0x10FFFF; c++ {
+			gid := startGlyph + (c - start)
+			if gid != 0 && gid <= 0xFFFF {
+				cmap[rune(c)] = uint16(gid)
+			}
+		}
+	}
+	return cmap, nil
+}
+
+// parsePostScriptName extracts name ID 6 and strips characters PDF names cannot hold
+func parsePostScriptName(table []byte) string {
+	if len(table) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count; i++ {
+		rec := 6 + i*12
+		if rec+12 > len(table) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(table[rec:])
+		nameID := binary.BigEndian.Uint16(table[rec+6:])
+		length := int(binary.BigEndian.Uint16(table[rec+8:]))
+		offset := int(binary.BigEndian.Uint16(table[rec+10:]))
+		if nameID != 6 || storage+offset+length > len(table) {
+			continue
+		}
+		raw := table[storage+offset : storage+offset+length]
+
+		var name strings.Builder
+		if platform == 3 || platform == 0 {
+			for j := 0; j+1 < len(raw); j += 2 {
+				name.WriteRune(rune(binary.BigEndian.Uint16(raw[j:])))
+			}
+		} else {
+			name.Write(raw)
+		}
+		return sanitizePDFName(name.String())
+	}
+	return ""
+}
+
+func sanitizePDFName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// helveticaWidth returns standard Helvetica glyph widths for WinAnsi bytes
+func helveticaWidth(c byte, bold bool) float64 {
+	if c < 32 || c > 126 {
+		return 556
+	}
+	if bold {
+		return float64(helveticaBoldWidths[c-32])
+	}
+	return float64(helveticaWidths[c-32])
+}
+
+var helveticaWidths = [95]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]uint16{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,540 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+const (
+	// Brzycki tracks tested maxes best up to this many reps and overshoots
+	// beyond, where Epley takes over
+	brzyckiMaxReps = 10
+	// Sets of more reps say too little about a single rep to estimate a
+	// one-rep max
+	maxOneRepMaxReps = 15
+
+	// DefaultPersonalRecordPageSize is how many records a page of PR history
+	// holds unless asked otherwise
+	DefaultPersonalRecordPageSize = 20
+	maxPersonalRecordPageSize     = 100
+
+	// Stats list this many of the latest records, and compare the records
+	// beaten in the last period of this many days with the one before
+	recentPersonalRecordsLimit = 10
+	personalRecordTrendDays    = 30
+)
+
+// Personal record trends
+const (
+	PersonalRecordTrendImproving  = "improving"
+	PersonalRecordTrendSteady     = "steady"
+	PersonalRecordTrendPlateauing = "plateauing"
+)
+
+// ErrInvalidPersonalRecordRequest is returned for unknown record types and
+// out of range pages
+var ErrInvalidPersonalRecordRequest = errors.New("invalid personal record request")
+
+var (
+	setDistancePattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(kilometers?|kilometres?|km|miles?|mi|meters?|metres?|m)\b`)
+	setClockPattern    = regexp.MustCompile(`(\d+):(\d{1,2})(?::(\d{1,2}))?`)
+	setDurationPattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(hours?|hrs?|h|minutes?|mins?|seconds?|secs?|s)\b`)
+)
+
+// PersonalRecordService detects personal records in completed workouts.
+// Each set logged is checked against the user's bests for the exercise:
+//
+//   - estimated one-rep max, from the weight and reps of a set
+//   - heaviest weight lifted
+//   - rep-max: the most reps at each weight, bodyweight included
+//   - best time: the fastest over each distance, or the longest timed hold
+//   - longest distance
+//
+// A record is stored each time a best is beaten, which makes the records
+// the exercise's PR history. The first time an exercise is logged its sets
+// only set the baseline; records beaten after that notify the user and are
+// published as webhook events.
+type PersonalRecordService struct {
+	repo          *repositories.PersonalRecordRepository
+	notifications *NotificationService
+}
+
+// NewPersonalRecordService creates a new PersonalRecordService instance.
+// notifications may be nil, in which case no notifications are sent.
+func NewPersonalRecordService(db *sql.DB, notifications *NotificationService) *PersonalRecordService {
+	return &PersonalRecordService{
+		repo:          repositories.NewPersonalRecordRepository(database.NewDatabase(db)),
+		notifications: notifications,
+	}
+}
+
+// DetectRecords stores the records a completed session sets and returns
+// them. Sessions that are not completed set none.
+func (s *PersonalRecordService) DetectRecords(ctx context.Context, session *models.UserWorkoutSession) ([]*models.PersonalRecord, error) {
+	return s.detectRecords(ctx, session, nil)
+}
+
+// RedetectRecords replaces the records a session set with those it sets
+// after being edited, so a corrected set or a session no longer completed
+// stops holding records. Records the session already held are not
+// announced again.
+func (s *PersonalRecordService) RedetectRecords(ctx context.Context, session *models.UserWorkoutSession) ([]*models.PersonalRecord, error) {
+	previous, err := s.repo.ListSessionRecords(ctx, session.UserID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.DeleteSessionRecords(ctx, session.UserID, session.ID); err != nil {
+		return nil, err
+	}
+
+	announced := make(map[string]bool, len(previous))
+	for _, record := range previous {
+		announced[sessionRecordKey(record)] = true
+	}
+	return s.detectRecords(ctx, session, announced)
+}
+
+// detectRecords stores and returns the records session sets, announcing
+// the beaten ones unless they are in announced
+func (s *PersonalRecordService) detectRecords(ctx context.Context, session *models.UserWorkoutSession, announced map[string]bool) ([]*models.PersonalRecord, error) {
+	records := []*models.PersonalRecord{}
+	if session.Status != "completed" {
+		return records, nil
+	}
+
+	date := time.Now().UTC()
+	if session.CompletedDate != nil {
+		date = *session.CompletedDate
+	}
+	var sessionID *string
+	if session.ID != "" {
+		id := session.ID
+		sessionID = &id
+	}
+
+	for _, exercise := range session.ExercisesCompleted {
+		exerciseID := personalRecordExerciseID(exercise)
+		candidates := sessionBests(exercise)
+		if exerciseID == "" || len(candidates) == 0 {
+			continue
+		}
+
+		history, err := s.repo.ListExerciseRecords(ctx, session.UserID, exerciseID)
+		if err != nil {
+			return nil, err
+		}
+		bests := currentBests(history)
+
+		for _, candidate := range candidates {
+			key := personalRecordKey(candidate)
+			if best, ok := bests[key]; ok {
+				if !beatsRecord(candidate, best) {
+					continue
+				}
+				previous := best.Value
+				candidate.PreviousValue = &previous
+			}
+
+			now := time.Now().UTC()
+			candidate.ExerciseID = exerciseID
+			candidate.ExerciseName = exercise.ExerciseName
+			candidate.Date = date
+			candidate.WorkoutSessionID = sessionID
+			candidate.CreatedAt = now
+			candidate.UpdatedAt = now
+			if err := s.repo.CreatePersonalRecord(ctx, session.UserID, candidate); err != nil {
+				return nil, err
+			}
+			if id, err := strconv.ParseUint(session.UserID, 10, 64); err == nil {
+				candidate.UserID = uint(id)
+			}
+			bests[key] = candidate
+			records = append(records, candidate)
+		}
+	}
+
+	for _, record := range records {
+		if record.PreviousValue != nil && !announced[sessionRecordKey(record)] {
+			s.announce(ctx, session.UserID, record)
+		}
+	}
+	return records, nil
+}
+
+// ListRecords returns a page of the user's PR history, newest first
+func (s *PersonalRecordService) ListRecords(ctx context.Context, userID string, req models.ListPersonalRecordsRequest) (*models.PersonalRecordListResponse, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultPersonalRecordPageSize
+	}
+	if req.Page < 1 || req.Limit < 1 || req.Limit > maxPersonalRecordPageSize {
+		return nil, fmt.Errorf("%w: page must be positive and limit between 1 and %d", ErrInvalidPersonalRecordRequest, maxPersonalRecordPageSize)
+	}
+	if req.RecordType != nil && !isPersonalRecordType(*req.RecordType) {
+		return nil, fmt.Errorf("%w: unknown record type %q", ErrInvalidPersonalRecordRequest, *req.RecordType)
+	}
+
+	records, total, err := s.repo.ListPersonalRecords(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.PersonalRecordListResponse{
+		Records: make([]models.PersonalRecord, 0, len(records)),
+		Total:   total,
+		Page:    req.Page,
+		Limit:   req.Limit,
+		HasNext: req.Page*req.Limit < total,
+	}
+	for _, record := range records {
+		response.Records = append(response.Records, *record)
+	}
+	return response, nil
+}
+
+// GetStats summarizes the user's records. RecordsByExercise holds the
+// current bests for each exercise; the trend compares the records beaten
+// in the last 30 days with the 30 days before.
+func (s *PersonalRecordService) GetStats(ctx context.Context, userID string) (*models.PersonalRecordStats, error) {
+	records, err := s.repo.ListUserRecords(ctx, userID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.PersonalRecordStats{
+		TotalRecords:       len(records),
+		RecentRecords:      []models.PersonalRecord{},
+		RecordsByType:      map[string]int{},
+		RecordsByExercise:  map[string][]models.PersonalRecord{},
+		RecentAchievements: []models.PersonalRecord{},
+		ProgressTrend:      PersonalRecordTrendSteady,
+	}
+
+	byExercise := map[string][]*models.PersonalRecord{}
+	recentStart := time.Now().AddDate(0, 0, -personalRecordTrendDays)
+	earlierStart := recentStart.AddDate(0, 0, -personalRecordTrendDays)
+	var recentBeaten, earlierBeaten int
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		stats.RecordsByType[record.RecordType]++
+		byExercise[record.ExerciseID] = append(byExercise[record.ExerciseID], record)
+		if len(stats.RecentRecords) < recentPersonalRecordsLimit {
+			stats.RecentRecords = append(stats.RecentRecords, *record)
+		}
+		if record.PreviousValue == nil {
+			continue
+		}
+		switch {
+		case !record.Date.Before(recentStart):
+			recentBeaten++
+			stats.RecentAchievements = append(stats.RecentAchievements, *record)
+		case !record.Date.Before(earlierStart):
+			earlierBeaten++
+		}
+	}
+
+	for exerciseID, history := range byExercise {
+		bests := currentBests(history)
+		current := make([]models.PersonalRecord, 0, len(bests))
+		for _, best := range bests {
+			current = append(current, *best)
+		}
+		sort.Slice(current, func(i, j int) bool {
+			return personalRecordKey(&current[i]) < personalRecordKey(&current[j])
+		})
+		stats.RecordsByExercise[exerciseID] = current
+	}
+
+	switch {
+	case recentBeaten > earlierBeaten:
+		stats.ProgressTrend = PersonalRecordTrendImproving
+	case recentBeaten < earlierBeaten:
+		stats.ProgressTrend = PersonalRecordTrendPlateauing
+	}
+	return stats, nil
+}
+
+// DeleteSessionRecords removes the records a workout session set, so the
+// bests before it apply again
+func (s *PersonalRecordService) DeleteSessionRecords(ctx context.Context, userID, sessionID string) error {
+	_, err := s.repo.DeleteSessionRecords(ctx, userID, sessionID)
+	return err
+}
+
+// announce tells the user about a beaten record. The record is already
+// stored, so failures are logged rather than returned.
+func (s *PersonalRecordService) announce(ctx context.Context, userID string, record *models.PersonalRecord) {
+	PublishWebhookEvent(ctx, userID, models.WebhookEventPersonalRecord, record)
+	if s.notifications == nil {
+		return
+	}
+
+	notification := &models.Notification{
+		UserID: userID,
+		Type:   models.NotificationTypePersonalRecord,
+		Title:  "New personal record: " + personalRecordExerciseName(record),
+		Body:   describePersonalRecord(record),
+		Data: map[string]interface{}{
+			"record_id":      record.ID,
+			"exercise_id":    record.ExerciseID,
+			"record_type":    record.RecordType,
+			"value":          record.Value,
+			"previous_value": *record.PreviousValue,
+			"unit":           record.Unit,
+		},
+	}
+	if err := s.notifications.Notify(ctx, notification); err != nil {
+		log.Printf("Failed to send personal record notification: %v", err)
+	}
+}
+
+// setPerformance is what one logged set did
+type setPerformance struct {
+	reps    int
+	seconds float64
+	metres  float64
+}
+
+// sessionBests returns the best of each record type among an exercise's
+// sets
+func sessionBests(exercise models.CompletedExercise) []*models.PersonalRecord {
+	bests := map[string]*models.PersonalRecord{}
+	consider := func(candidate *models.PersonalRecord) {
+		key := personalRecordKey(candidate)
+		if best, ok := bests[key]; !ok || beatsRecord(candidate, best) {
+			bests[key] = candidate
+		}
+	}
+
+	for i, entry := range exercise.RepsCompleted {
+		set := parseSetPerformance(entry)
+		load := setLoad(exercise, i)
+
+		if set.reps > 0 {
+			reps := set.reps
+			weight := load
+			if load > 0 {
+				consider(&models.PersonalRecord{RecordType: string(models.RecordWeight), Value: load, Unit: "kg", Reps: &reps, Weight: &weight})
+				if oneRepMax := estimateOneRepMax(load, reps); oneRepMax > 0 {
+					consider(&models.PersonalRecord{RecordType: string(models.RecordEstimated1RM), Value: roundTo(oneRepMax, 1), Unit: "kg", Reps: &reps, Weight: &weight})
+				}
+			}
+			consider(&models.PersonalRecord{RecordType: string(models.RecordReps), Value: float64(reps), Unit: "reps", Reps: &reps, Weight: &weight})
+		}
+
+		if set.seconds > 0 {
+			record := &models.PersonalRecord{RecordType: string(models.RecordTime), Value: set.seconds, Unit: "s"}
+			if set.metres > 0 {
+				metres := set.metres
+				record.Distance = &metres
+			}
+			if set.metres > 0 || set.reps == 0 {
+				consider(record)
+			}
+		}
+
+		if set.metres > 0 {
+			consider(&models.PersonalRecord{RecordType: string(models.RecordDistance), Value: set.metres, Unit: "m"})
+		}
+	}
+
+	records := make([]*models.PersonalRecord, 0, len(bests))
+	for _, best := range bests {
+		records = append(records, best)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return personalRecordKey(records[i]) < personalRecordKey(records[j])
+	})
+	return records
+}
+
+// parseSetPerformance reads a logged set like "8", "8 reps", "45s",
+// "1:30", "400 m" or "5 km 24:30"
+func parseSetPerformance(entry string) setPerformance {
+	var set setPerformance
+
+	for _, match := range setDistancePattern.FindAllStringSubmatch(entry, -1) {
+		value, _ := strconv.ParseFloat(match[1], 64)
+		switch unit := strings.ToLower(match[2]); {
+		case strings.HasPrefix(unit, "k"):
+			value *= 1000
+		case strings.HasPrefix(unit, "mi"):
+			value *= 1609.344
+		}
+		set.metres += value
+	}
+	entry = setDistancePattern.ReplaceAllString(entry, " ")
+
+	for _, match := range setClockPattern.FindAllStringSubmatch(entry, -1) {
+		parts := []float64{}
+		for _, part := range match[1:] {
+			if part != "" {
+				value, _ := strconv.ParseFloat(part, 64)
+				parts = append(parts, value)
+			}
+		}
+		// m:ss, or h:mm:ss
+		seconds := parts[0]*60 + parts[1]
+		if len(parts) == 3 {
+			seconds = parts[0]*3600 + parts[1]*60 + parts[2]
+		}
+		set.seconds += seconds
+	}
+	entry = setClockPattern.ReplaceAllString(entry, " ")
+
+	for _, match := range setDurationPattern.FindAllStringSubmatch(entry, -1) {
+		value, _ := strconv.ParseFloat(match[1], 64)
+		switch unit := strings.ToLower(match[2]); {
+		case strings.HasPrefix(unit, "h"):
+			value *= 3600
+		case strings.HasPrefix(unit, "m"):
+			value *= 60
+		}
+		set.seconds += value
+	}
+	entry = setDurationPattern.ReplaceAllString(entry, " ")
+
+	if match := programNumberPattern.FindString(entry); match != "" {
+		reps, _ := strconv.ParseFloat(match, 64)
+		set.reps = int(reps)
+	}
+	set.metres = roundTo(set.metres, 1)
+	set.seconds = roundTo(set.seconds, 1)
+	return set
+}
+
+// setLoad returns the weight of the set at index in kilograms, 0 for
+// bodyweight. A set without its own weight takes the last one logged.
+func setLoad(exercise models.CompletedExercise, index int) float64 {
+	if len(exercise.WeightUsed) == 0 {
+		return 0
+	}
+	if index >= len(exercise.WeightUsed) {
+		index = len(exercise.WeightUsed) - 1
+	}
+	return parseProgramLoad(&exercise.WeightUsed[index])
+}
+
+// estimateOneRepMax estimates a one-rep max from a set: the weight itself
+// for a single, Brzycki up to ten reps and Epley up to fifteen. 0 for sets
+// with more reps.
+func estimateOneRepMax(weight float64, reps int) float64 {
+	switch {
+	case reps == 1:
+		return weight
+	case reps > 1 && reps <= brzyckiMaxReps:
+		return weight * 36 / (37 - float64(reps))
+	case reps > brzyckiMaxReps && reps <= maxOneRepMaxReps:
+		return weight * (1 + float64(reps)/30)
+	}
+	return 0
+}
+
+// currentBests folds an exercise's PR history into the best for each
+// record, keyed by personalRecordKey
+func currentBests(history []*models.PersonalRecord) map[string]*models.PersonalRecord {
+	bests := map[string]*models.PersonalRecord{}
+	for _, record := range history {
+		key := personalRecordKey(record)
+		if best, ok := bests[key]; !ok || beatsRecord(record, best) {
+			bests[key] = record
+		}
+	}
+	return bests
+}
+
+// personalRecordKey says which best a record competes with: rep-maxes are
+// kept per weight and times per distance
+func personalRecordKey(record *models.PersonalRecord) string {
+	switch {
+	case record.RecordType == string(models.RecordReps) && record.Weight != nil:
+		return fmt.Sprintf("%s@%g", record.RecordType, *record.Weight)
+	case record.RecordType == string(models.RecordTime) && record.Distance != nil:
+		return fmt.Sprintf("%s@%g", record.RecordType, *record.Distance)
+	}
+	return record.RecordType
+}
+
+// sessionRecordKey identifies a record by exercise, kind and value, so a
+// re-detected record can be matched with the one it replaces
+func sessionRecordKey(record *models.PersonalRecord) string {
+	return fmt.Sprintf("%s/%s/%g", record.ExerciseID, personalRecordKey(record), record.Value)
+}
+
+// beatsRecord reports whether record beats best. A time over a distance is
+// better the lower it is; everything else the higher.
+func beatsRecord(record, best *models.PersonalRecord) bool {
+	if record.RecordType == string(models.RecordTime) && record.Distance != nil {
+		return record.Value < best.Value
+	}
+	return record.Value > best.Value
+}
+
+func describePersonalRecord(record *models.PersonalRecord) string {
+	previous := *record.PreviousValue
+	switch models.PersonalRecordType(record.RecordType) {
+	case models.RecordEstimated1RM:
+		return fmt.Sprintf("Estimated one-rep max of %g kg, up from %g kg", record.Value, previous)
+	case models.RecordWeight:
+		return fmt.Sprintf("Heaviest lift of %g kg, up from %g kg", record.Value, previous)
+	case models.RecordReps:
+		if record.Weight != nil && *record.Weight > 0 {
+			return fmt.Sprintf("%g reps at %g kg, up from %g", record.Value, *record.Weight, previous)
+		}
+		return fmt.Sprintf("%g reps, up from %g", record.Value, previous)
+	case models.RecordTime:
+		if record.Distance != nil {
+			return fmt.Sprintf("%g m in %s, down from %s", *record.Distance, formatDuration(record.Value), formatDuration(previous))
+		}
+		return fmt.Sprintf("Held for %s, up from %s", formatDuration(record.Value), formatDuration(previous))
+	case models.RecordDistance:
+		return fmt.Sprintf("Longest distance of %g m, up from %g m", record.Value, previous)
+	}
+	return fmt.Sprintf("%g %s, previously %g", record.Value, record.Unit, previous)
+}
+
+// formatDuration formats seconds as m:ss, or h:mm:ss from an hour on
+func formatDuration(seconds float64) string {
+	total := int(seconds + 0.5)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// personalRecordExerciseID keys exercises logged without an ID by name
+func personalRecordExerciseID(exercise models.CompletedExercise) string {
+	if exercise.ExerciseID != "" {
+		return exercise.ExerciseID
+	}
+	return strings.ToLower(strings.TrimSpace(exercise.ExerciseName))
+}
+
+func personalRecordExerciseName(record *models.PersonalRecord) string {
+	if record.ExerciseName != "" {
+		return record.ExerciseName
+	}
+	return record.ExerciseID
+}
+
+func isPersonalRecordType(recordType string) bool {
+	switch models.PersonalRecordType(recordType) {
+	case models.RecordEstimated1RM, models.RecordWeight, models.RecordReps, models.RecordTime, models.RecordDistance:
+		return true
+	}
+	return false
+}
@@ -15,6 +15,7 @@ import (
 type ProgressService struct {
 	measurementRepo *repositories.BodyMeasurementRepository
 	weightRepo      *repositories.WeightRepository
+	composition     *BodyCompositionService
 }
 
 func NewProgressService(db *sql.DB) *ProgressService {
@@ -22,6 +23,7 @@ func NewProgressService(db *sql.DB) *ProgressService {
 	return &ProgressService{
 		measurementRepo: repositories.NewBodyMeasurementRepository(dbWrapper),
 		weightRepo:      repositories.NewWeightRepository(dbWrapper),
+		composition:     NewBodyCompositionService(db),
 	}
 }
 
@@ -38,6 +40,9 @@ func (s *ProgressService) LogMeasurement(ctx context.Context, userID uint, measu
 		measurement.MeasurementDate = time.Now().Truncate(24 * time.Hour)
 	}
 
+	// Derive body fat, lean and fat mass, ratios and FFMI
+	s.composition.ApplyToMeasurement(userID, measurement)
+
 	// Create measurement
 	err := s.measurementRepo.CreateBodyMeasurement(ctx, measurement)
 	if err != nil {
@@ -246,6 +251,7 @@ func (s *ProgressService) UpdateMeasurement(ctx context.Context, userID uint, me
 
 	// Ensure user ID matches
 	measurement.UserID = uint(userID)
+	s.composition.ApplyToMeasurement(userID, measurement)
 
 	// Update measurement
 	err := s.measurementRepo.UpdateBodyMeasurement(ctx, measurement)
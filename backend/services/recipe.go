@@ -1,17 +1,270 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
 )
 
+// ErrInvalidRecipe is wrapped by errors caused by the submitted recipe
+var ErrInvalidRecipe = errors.New("invalid recipe")
+
 // RecipeService handles recipe-related operations
 type RecipeService struct {
-	db *sql.DB
+	db         *sql.DB
+	recipes    *repositories.RecipeRepository
+	calculator *RecipeNutritionCalculator
 }
 
 // NewRecipeService creates a new RecipeService instance
 func NewRecipeService(db *sql.DB) *RecipeService {
+	wrapped := database.NewDatabase(db)
 	return &RecipeService{
-		db: db,
+		db:      db,
+		recipes: repositories.NewRecipeRepository(wrapped),
+		calculator: NewRecipeNutritionCalculator(
+			repositories.NewFoodRepository(db),
+			repositories.NewFoodLogRepository(wrapped),
+		),
+	}
+}
+
+// GetRecipe retrieves a recipe by ID
+func (s *RecipeService) GetRecipe(ctx context.Context, id string) (*models.Recipe, error) {
+	return s.recipes.GetRecipeByID(ctx, id)
+}
+
+// CreateRecipe stores a new recipe with nutrition computed from its
+// ingredients. Ingredient lines such as "2 cups cooked rice" are parsed and
+// added to the structured ingredients.
+func (s *RecipeService) CreateRecipe(ctx context.Context, userID string, req models.CreateRecipeRequest) (*models.Recipe, *RecipeNutritionReport, error) {
+	ingredients, err := mergeIngredientLines(req.Ingredients, req.IngredientLines)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recipe := &models.Recipe{
+		ID:              uuid.New().String(),
+		Name:            strings.TrimSpace(req.Name),
+		NameAr:          req.NameAr,
+		Description:     req.Description,
+		DescriptionAr:   req.DescriptionAr,
+		Cuisine:         req.Cuisine,
+		Country:         req.Country,
+		DifficultyLevel: req.DifficultyLevel,
+		PrepTimeMinutes: req.PrepTimeMinutes,
+		CookTimeMinutes: req.CookTimeMinutes,
+		Servings:        req.Servings,
+		Ingredients:     ingredients,
+		Instructions:    req.Instructions,
+		DietaryTags:     req.DietaryTags,
+		Allergens:       req.Allergens,
+		IsHalal:         req.IsHalal,
+		IsKosher:        req.IsKosher,
+		ImageURL:        req.ImageURL,
+		VideoURL:        req.VideoURL,
+		CreatedBy:       &userID,
+	}
+	recipe.TotalTimeMinutes = totalRecipeTime(recipe)
+
+	if err := validateRecipe(recipe); err != nil {
+		return nil, nil, err
+	}
+
+	report, err := s.applyNutrition(ctx, userID, recipe)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.recipes.CreateRecipe(ctx, recipe); err != nil {
+		return nil, nil, err
+	}
+
+	return recipe, report, nil
+}
+
+// UpdateRecipe applies the given changes to a recipe created by userID and
+// recomputes its nutrition
+func (s *RecipeService) UpdateRecipe(ctx context.Context, userID, id string, req models.UpdateRecipeRequest) (*models.Recipe, *RecipeNutritionReport, error) {
+	recipe, err := s.recipes.GetRecipeByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if recipe.CreatedBy == nil || *recipe.CreatedBy != userID {
+		return nil, nil, fmt.Errorf("recipe not found")
+	}
+
+	if req.Name != nil {
+		recipe.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.NameAr != nil {
+		recipe.NameAr = req.NameAr
+	}
+	if req.Description != nil {
+		recipe.Description = req.Description
+	}
+	if req.DescriptionAr != nil {
+		recipe.DescriptionAr = req.DescriptionAr
+	}
+	if req.Cuisine != nil {
+		recipe.Cuisine = req.Cuisine
+	}
+	if req.Country != nil {
+		recipe.Country = req.Country
+	}
+	if req.DifficultyLevel != nil {
+		recipe.DifficultyLevel = req.DifficultyLevel
+	}
+	if req.PrepTimeMinutes != nil {
+		recipe.PrepTimeMinutes = req.PrepTimeMinutes
+	}
+	if req.CookTimeMinutes != nil {
+		recipe.CookTimeMinutes = req.CookTimeMinutes
+	}
+	if req.Servings != nil {
+		recipe.Servings = req.Servings
+	}
+	if req.Ingredients != nil || req.IngredientLines != nil {
+		ingredients, err := mergeIngredientLines(req.Ingredients, req.IngredientLines)
+		if err != nil {
+			return nil, nil, err
+		}
+		recipe.Ingredients = ingredients
+	}
+	if req.Instructions != nil {
+		recipe.Instructions = req.Instructions
+	}
+	if req.DietaryTags != nil {
+		recipe.DietaryTags = req.DietaryTags
+	}
+	if req.Allergens != nil {
+		recipe.Allergens = req.Allergens
+	}
+	if req.IsHalal != nil {
+		recipe.IsHalal = *req.IsHalal
+	}
+	if req.IsKosher != nil {
+		recipe.IsKosher = *req.IsKosher
+	}
+	if req.ImageURL != nil {
+		recipe.ImageURL = req.ImageURL
+	}
+	if req.VideoURL != nil {
+		recipe.VideoURL = req.VideoURL
+	}
+	recipe.TotalTimeMinutes = totalRecipeTime(recipe)
+
+	if err := validateRecipe(recipe); err != nil {
+		return nil, nil, err
+	}
+
+	report, err := s.applyNutrition(ctx, userID, recipe)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.recipes.UpdateRecipe(ctx, recipe); err != nil {
+		return nil, nil, err
+	}
+
+	return recipe, report, nil
+}
+
+// DeleteRecipe removes a recipe created by userID
+func (s *RecipeService) DeleteRecipe(ctx context.Context, userID, id string) error {
+	return s.recipes.DeleteRecipe(ctx, id, userID)
+}
+
+// CalculateNutrition computes a stored recipe's nutrition without saving it
+func (s *RecipeService) CalculateNutrition(ctx context.Context, userID, id string) (*RecipeNutritionReport, error) {
+	recipe, err := s.recipes.GetRecipeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.calculator.Calculate(ctx, userID, recipe)
+}
+
+// applyNutrition replaces the recipe's nutrition with the computed values.
+// Author-entered macros are discarded; a recipe with no matched ingredient
+// has no nutrition rather than a misleading zero.
+func (s *RecipeService) applyNutrition(ctx context.Context, userID string, recipe *models.Recipe) (*RecipeNutritionReport, error) {
+	report, err := s.calculator.Calculate(ctx, userID, recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	recipe.NutritionPerServing = nil
+	if len(report.Matched) > 0 {
+		perServing := report.PerServing
+		recipe.NutritionPerServing = &perServing
+	}
+
+	return report, nil
+}
+
+// mergeIngredientLines appends parsed free-text lines to structured ingredients
+func mergeIngredientLines(ingredients []models.RecipeIngredient, lines []string) ([]models.RecipeIngredient, error) {
+	merged := append([]models.RecipeIngredient{}, ingredients...)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parsed, err := ParseIngredientLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecipe, err)
+		}
+		ingredient := models.RecipeIngredient{
+			Name:   parsed.Name,
+			Amount: parsed.Quantity,
+			Unit:   parsed.Unit,
+		}
+		if i := strings.Index(line, ","); i >= 0 {
+			ingredient.Preparation = strings.TrimSpace(line[i+1:])
+		}
+		merged = append(merged, ingredient)
+	}
+	return merged, nil
+}
+
+func validateRecipe(recipe *models.Recipe) error {
+	if len(recipe.Name) < 3 || len(recipe.Name) > 200 {
+		return fmt.Errorf("%w: name must be between 3 and 200 characters", ErrInvalidRecipe)
+	}
+	if len(recipe.Ingredients) == 0 {
+		return fmt.Errorf("%w: at least one ingredient is required", ErrInvalidRecipe)
+	}
+	if recipe.Servings != nil && (*recipe.Servings < 1 || *recipe.Servings > 50) {
+		return fmt.Errorf("%w: servings must be between 1 and 50", ErrInvalidRecipe)
+	}
+	for _, ingredient := range recipe.Ingredients {
+		if strings.TrimSpace(ingredient.Name) == "" {
+			return fmt.Errorf("%w: ingredient name is required", ErrInvalidRecipe)
+		}
+		if ingredient.Amount < 0 {
+			return fmt.Errorf("%w: ingredient amount cannot be negative", ErrInvalidRecipe)
+		}
+	}
+	return nil
+}
+
+func totalRecipeTime(recipe *models.Recipe) *int {
+	if recipe.PrepTimeMinutes == nil && recipe.CookTimeMinutes == nil {
+		return recipe.TotalTimeMinutes
+	}
+	total := 0
+	if recipe.PrepTimeMinutes != nil {
+		total += *recipe.PrepTimeMinutes
+	}
+	if recipe.CookTimeMinutes != nil {
+		total += *recipe.CookTimeMinutes
 	}
+	return &total
 }
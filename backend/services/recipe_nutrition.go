@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+// minIngredientMatchScore is the lowest name similarity accepted when
+// matching an ingredient to a food
+const minIngredientMatchScore = 0.5
+
+// maxIngredientCandidates bounds how many foods are scored per ingredient
+const maxIngredientCandidates = 200
+
+// ParsedIngredient is an ingredient line split into amount, unit and food name
+type ParsedIngredient struct {
+	Line     string  `json:"line"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	Name     string  `json:"name"`
+}
+
+// IngredientMatch is an ingredient resolved to a food and weight
+type IngredientMatch struct {
+	Ingredient string               `json:"ingredient"`
+	FoodID     uint                 `json:"food_id"`
+	FoodName   string               `json:"food_name"`
+	Grams      float64              `json:"grams"`
+	Score      float64              `json:"match_score"`
+	Nutrition  models.NutritionInfo `json:"nutrition"`
+}
+
+// UnmatchedIngredient is an ingredient left out of the nutrition totals
+type UnmatchedIngredient struct {
+	Ingredient string `json:"ingredient"`
+	Reason     string `json:"reason"`
+}
+
+// RecipeNutritionReport is the nutrition computed from a recipe's ingredients
+type RecipeNutritionReport struct {
+	Servings   int                   `json:"servings"`
+	Total      models.NutritionInfo  `json:"total"`
+	PerServing models.NutritionInfo  `json:"per_serving"`
+	Matched    []IngredientMatch     `json:"matched"`
+	Unmatched  []UnmatchedIngredient `json:"unmatched"`
+	Complete   bool                  `json:"complete"`
+}
+
+// unicodeFractions maps vulgar fraction characters to ASCII fractions
+var unicodeFractions = strings.NewReplacer(
+	"½", " 1/2", "⅓", " 1/3", "⅔", " 2/3", "¼", " 1/4", "¾", " 3/4", "⅛", " 1/8",
+)
+
+var (
+	parenthesesPattern = regexp.MustCompile(`\([^)]*\)`)
+	// glued amounts such as "150g" or "1.5kg"
+	gluedAmountPattern = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)(\p{L}+)$`)
+	rangePattern       = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)[-–](\d+(?:[.,]\d+)?)$`)
+)
+
+// ingredientStopwords are preparation and size words that do not identify a food
+var ingredientStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "of": true, "or": true, "the": true, "to": true,
+	"fresh": true, "freshly": true, "chopped": true, "diced": true, "sliced": true, "minced": true,
+	"finely": true, "roughly": true, "large": true, "small": true, "medium": true, "taste": true,
+	"about": true, "approximately": true, "for": true, "serving": true, "optional": true,
+	"peeled": true, "grated": true, "crushed": true, "halved": true, "cubed": true, "trimmed": true,
+}
+
+// ParseIngredientLine splits a free-text line such as "2 cups cooked rice",
+// "150g chicken breast" or "1 1/2 tbsp olive oil, divided" into its parts.
+// Lines without a unit are counted in pieces ("2 eggs").
+func ParseIngredientLine(line string) (*ParsedIngredient, error) {
+	parsed := &ParsedIngredient{Line: strings.TrimSpace(line)}
+
+	text := strings.TrimLeft(parsed.Line, "-*•· \t")
+	text = parenthesesPattern.ReplaceAllString(text, " ")
+	text = unicodeFractions.Replace(text)
+	// Preparation notes follow a comma: "chicken breast, diced"
+	if i := strings.Index(text, ","); i >= 0 {
+		text = text[:i]
+	}
+
+	var tokens []string
+	for _, field := range strings.Fields(text) {
+		if m := gluedAmountPattern.FindStringSubmatch(field); m != nil {
+			tokens = append(tokens, m[1], m[2])
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+
+	quantity, consumed := parseIngredientQuantity(tokens)
+	if consumed == 0 {
+		return nil, fmt.Errorf("no quantity in %q", parsed.Line)
+	}
+	tokens = tokens[consumed:]
+
+	parsed.Quantity = quantity
+	parsed.Unit = UnitPiece
+	// Two-word units come first so "ملعقة كبيرة" is not read as a name
+	if len(tokens) >= 2 {
+		if unit, err := NormalizeUnit(tokens[0] + " " + tokens[1]); err == nil {
+			parsed.Unit = unit
+			tokens = tokens[2:]
+		}
+	}
+	if parsed.Unit == UnitPiece && len(tokens) >= 1 {
+		if unit, err := NormalizeUnit(tokens[0]); err == nil {
+			parsed.Unit = unit
+			tokens = tokens[1:]
+		}
+	}
+	if len(tokens) > 0 && strings.EqualFold(tokens[0], "of") {
+		tokens = tokens[1:]
+	}
+
+	parsed.Name = strings.TrimSpace(strings.Join(tokens, " "))
+	if parsed.Name == "" {
+		return nil, fmt.Errorf("no ingredient name in %q", parsed.Line)
+	}
+
+	return parsed, nil
+}
+
+// parseIngredientQuantity reads a leading amount: "2", "1.5", "1/2",
+// "1 1/2" or a range "2-3" (averaged). It returns how many tokens it used.
+func parseIngredientQuantity(tokens []string) (float64, int) {
+	if len(tokens) == 0 {
+		return 0, 0
+	}
+
+	if m := rangePattern.FindStringSubmatch(tokens[0]); m != nil {
+		low, _ := parseIngredientNumber(m[1])
+		high, _ := parseIngredientNumber(m[2])
+		return (low + high) / 2, 1
+	}
+
+	whole, ok := parseIngredientNumber(tokens[0])
+	if !ok {
+		return 0, 0
+	}
+	if strings.Contains(tokens[0], "/") || len(tokens) < 2 || !strings.Contains(tokens[1], "/") {
+		return whole, 1
+	}
+	if fraction, ok := parseIngredientNumber(tokens[1]); ok {
+		return whole + fraction, 2
+	}
+	return whole, 1
+}
+
+func parseIngredientNumber(s string) (float64, bool) {
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+// ingredientTokens lowercases a name, drops stopwords and reduces plurals
+// so "Tomatoes, chopped" and "tomato" compare equal
+func ingredientTokens(name string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	seen := map[string]bool{}
+	var tokens []string
+	for _, f := range fields {
+		if ingredientStopwords[f] || len([]rune(f)) < 2 {
+			continue
+		}
+		f = singularize(f)
+		if !seen[f] {
+			seen[f] = true
+			tokens = append(tokens, f)
+		}
+	}
+	return tokens
+}
+
+func singularize(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case len(word) > 4 && strings.HasSuffix(word, "oes"):
+		return strings.TrimSuffix(word, "es")
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// ingredientMatchScore rates how well a food name describes an ingredient.
+// Covering every ingredient word matters most; extra words in the food name
+// ("Rice, white, cooked") cost less.
+func ingredientMatchScore(ingredient []string, foodName string) float64 {
+	food := ingredientTokens(foodName)
+	if len(ingredient) == 0 || len(food) == 0 {
+		return 0
+	}
+
+	inFood := map[string]bool{}
+	for _, t := range food {
+		inFood[t] = true
+	}
+	shared := 0
+	for _, t := range ingredient {
+		if inFood[t] {
+			shared++
+		}
+	}
+
+	recall := float64(shared) / float64(len(ingredient))
+	precision := float64(shared) / float64(len(food))
+	if recall < 0.5 {
+		return 0
+	}
+	return 0.7*recall + 0.3*precision
+}
+
+// RecipeNutritionCalculator computes recipe nutrition from its ingredients
+type RecipeNutritionCalculator struct {
+	foods *repositories.FoodRepository
+	logs  *repositories.FoodLogRepository
+}
+
+// NewRecipeNutritionCalculator creates a calculator over the given repositories
+func NewRecipeNutritionCalculator(foods *repositories.FoodRepository, logs *repositories.FoodLogRepository) *RecipeNutritionCalculator {
+	return &RecipeNutritionCalculator{foods: foods, logs: logs}
+}
+
+// Calculate matches every ingredient to a food visible to userID, converts
+// its amount to grams and sums the nutrition. Optional ingredients and
+// ingredients that cannot be matched or weighed are reported as unmatched
+// and left out of the totals.
+func (c *RecipeNutritionCalculator) Calculate(ctx context.Context, userID string, recipe *models.Recipe) (*RecipeNutritionReport, error) {
+	servings := 1
+	if recipe.Servings != nil && *recipe.Servings > 0 {
+		servings = *recipe.Servings
+	}
+
+	report := &RecipeNutritionReport{
+		Servings:  servings,
+		Matched:   []IngredientMatch{},
+		Unmatched: []UnmatchedIngredient{},
+	}
+
+	for _, ingredient := range recipe.Ingredients {
+		label := ingredientLabel(ingredient)
+		if ingredient.Optional {
+			report.Unmatched = append(report.Unmatched, UnmatchedIngredient{Ingredient: label, Reason: "optional ingredients are not counted"})
+			continue
+		}
+
+		parsed, err := resolveIngredient(ingredient)
+		if err != nil {
+			report.Unmatched = append(report.Unmatched, UnmatchedIngredient{Ingredient: label, Reason: err.Error()})
+			continue
+		}
+
+		match, reason, err := c.matchIngredient(ctx, userID, parsed)
+		if err != nil {
+			return nil, err
+		}
+		if match == nil {
+			report.Unmatched = append(report.Unmatched, UnmatchedIngredient{Ingredient: label, Reason: reason})
+			continue
+		}
+
+		match.Ingredient = label
+		report.Matched = append(report.Matched, *match)
+		report.Total.Add(match.Nutrition)
+	}
+
+	report.PerServing = report.Total.Scaled(1 / float64(servings)).Rounded()
+	report.Total = report.Total.Rounded()
+	report.Complete = len(report.Unmatched) == 0
+
+	return report, nil
+}
+
+func (c *RecipeNutritionCalculator) matchIngredient(ctx context.Context, userID string, parsed *ParsedIngredient) (*IngredientMatch, string, error) {
+	tokens := ingredientTokens(parsed.Name)
+	if len(tokens) == 0 {
+		return nil, "no food name found", nil
+	}
+
+	// Search on word stems so plurals in food names ("berries") still match
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		if len(t) > 4 {
+			t = strings.TrimSuffix(t, "y")
+		}
+		terms[i] = t
+	}
+	candidates, err := c.foods.FindFoodsByNameTerms(userID, terms, maxIngredientCandidates)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var best *models.Food
+	bestScore := 0.0
+	for _, food := range candidates {
+		score := ingredientMatchScore(tokens, food.Name)
+		// Prefer the user's own foods, then verified ones, on equal names
+		if food.UserID != nil && strconv.FormatUint(uint64(*food.UserID), 10) == userID {
+			score += 0.02
+		} else if food.Verified {
+			score += 0.01
+		}
+		if score > bestScore {
+			best, bestScore = food, score
+		}
+	}
+	if best == nil || bestScore < minIngredientMatchScore {
+		return nil, "no matching food found", nil
+	}
+
+	portions, err := c.logs.GetFoodPortions(ctx, best.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	grams, err := ConvertToGrams(parsed.Quantity, parsed.Unit, best, portions)
+	if err != nil {
+		return nil, err.Error(), nil
+	}
+
+	return &IngredientMatch{
+		FoodID:    best.ID,
+		FoodName:  best.Name,
+		Grams:     roundTo(grams, 1),
+		Score:     roundTo(bestScore, 2),
+		Nutrition: *best.CalculateNutrition(grams),
+	}, "", nil
+}
+
+// resolveIngredient uses the structured amount when the author gave one and
+// parses the name as a free-text line otherwise
+func resolveIngredient(ingredient models.RecipeIngredient) (*ParsedIngredient, error) {
+	if ingredient.Amount <= 0 {
+		return ParseIngredientLine(ingredient.Name)
+	}
+
+	unit := UnitPiece
+	if strings.TrimSpace(ingredient.Unit) != "" {
+		canonical, err := NormalizeUnit(ingredient.Unit)
+		if err != nil {
+			return nil, err
+		}
+		unit = canonical
+	}
+
+	return &ParsedIngredient{
+		Line:     ingredientLabel(ingredient),
+		Quantity: ingredient.Amount,
+		Unit:     unit,
+		Name:     ingredient.Name,
+	}, nil
+}
+
+// ingredientLabel renders an ingredient the way the author would write it
+func ingredientLabel(ingredient models.RecipeIngredient) string {
+	if ingredient.Amount <= 0 {
+		return ingredient.Name
+	}
+	amount := strconv.FormatFloat(ingredient.Amount, 'f', -1, 64)
+	return strings.TrimSpace(strings.Join(strings.Fields(amount+" "+ingredient.Unit+" "+ingredient.Name), " "))
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nutrition-platform/database"
+	apperrors "nutrition-platform/errors"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenTTL is how long a refresh token, and the session it extends,
+// stays valid after it is issued
+const RefreshTokenTTL = 24 * time.Hour
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown or expired refresh
+	// tokens and for tokens of sessions that were signed out
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token that was already
+	// rotated is presented again. Its whole family has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// SecurityEventLogger records security events. security.SecurityLogger
+// implements it.
+type SecurityEventLogger interface {
+	LogSecurityEvent(event *apperrors.SecurityEvent)
+}
+
+// RefreshTokenService issues rotating refresh tokens. Each session is a token
+// family: every refresh consumes the presented token and issues its
+// successor, and presenting a consumed token again is treated as theft,
+// revoking the session and every token in it.
+type RefreshTokenService struct {
+	repo     *repositories.RefreshTokenRepository
+	sessions *repositories.SessionRepository
+	events   SecurityEventLogger
+}
+
+// NewRefreshTokenService creates a new RefreshTokenService instance. events
+// may be nil, in which case reuse is only written to the standard logger.
+func NewRefreshTokenService(db *sql.DB, events SecurityEventLogger) *RefreshTokenService {
+	wrapped := database.NewDatabase(db)
+	return &RefreshTokenService{
+		repo:     repositories.NewRefreshTokenRepository(wrapped),
+		sessions: repositories.NewSessionRepository(wrapped),
+		events:   events,
+	}
+}
+
+// StartSession creates a session for a user who just signed in and returns
+// it with the raw refresh token, which is not stored anywhere. mfaVerified
+// records whether they used a second factor.
+func (s *RefreshTokenService) StartSession(ctx context.Context, userID, accessToken, deviceInfo, ipAddress, userAgent string, mfaVerified bool) (*Session, string, error) {
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		AccessToken:  accessToken,
+		RefreshToken: hashRefreshToken(raw),
+		DeviceInfo:   deviceInfo,
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+		IsActive:     true,
+		MFAVerified:  mfaVerified,
+		ExpiresAt:    now.Add(RefreshTokenTTL),
+		CreatedAt:    now,
+		LastUsedAt:   now,
+	}
+	token := newRefreshToken(session.ID, userID, session.RefreshToken, now)
+
+	if err := s.repo.CreateFamily(ctx, session, token); err != nil {
+		return nil, "", err
+	}
+	return session, raw, nil
+}
+
+// Rotate redeems a refresh token for its successor. issueAccessToken is
+// called with the session to create the access token issued alongside it,
+// which is recorded on the session. The returned session carries the new
+// token's expiry.
+func (s *RefreshTokenService) Rotate(ctx context.Context, refreshToken, ipAddress, userAgent string, issueAccessToken func(session *Session) (string, error)) (*Session, string, error) {
+	record, err := s.repo.GetTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if err.Error() == "refresh token not found" {
+			return nil, "", ErrInvalidRefreshToken
+		}
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if record.UsedAt != nil {
+		return nil, "", s.revokeReusedFamily(ctx, record, ipAddress, userAgent, now)
+	}
+	if !record.IsUsable(now) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	// An unused token is the session's current one
+	current, err := s.sessions.GetSessionByRefreshToken(ctx, record.TokenHash, now)
+	if err != nil {
+		if err.Error() == "session not found or expired" {
+			return nil, "", s.rotationRaceLost(ctx, record, ipAddress, userAgent, now)
+		}
+		return nil, "", err
+	}
+	accessToken, err := issueAccessToken(current)
+	if err != nil {
+		return nil, "", err
+	}
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	next := newRefreshToken(record.SessionID, record.UserID, hashRefreshToken(raw), now)
+
+	rotated, err := s.repo.RotateToken(ctx, record.ID, next, accessToken, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if !rotated {
+		return nil, "", s.rotationRaceLost(ctx, record, ipAddress, userAgent, now)
+	}
+
+	session, err := s.sessions.GetSessionByRefreshToken(ctx, next.TokenHash, now)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, raw, nil
+}
+
+// Revoke signs out the session a refresh token belongs to, revoking the
+// whole family. Unknown tokens are ignored.
+func (s *RefreshTokenService) Revoke(ctx context.Context, refreshToken string) error {
+	record, err := s.repo.GetTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if err.Error() == "refresh token not found" {
+			return nil
+		}
+		return err
+	}
+	return s.repo.RevokeFamily(ctx, record.SessionID, time.Now())
+}
+
+// rotationRaceLost handles a token that was usable when read but changed
+// before it could be rotated: either another request consumed it first,
+// which is reuse, or the family was revoked in the meantime
+func (s *RefreshTokenService) rotationRaceLost(ctx context.Context, record *models.RefreshToken, ipAddress, userAgent string, now time.Time) error {
+	latest, err := s.repo.GetTokenByHash(ctx, record.TokenHash)
+	if err != nil {
+		return err
+	}
+	if latest.UsedAt != nil && latest.RevokedAt == nil {
+		return s.revokeReusedFamily(ctx, latest, ipAddress, userAgent, now)
+	}
+	return ErrInvalidRefreshToken
+}
+
+// revokeReusedFamily revokes the family of a token presented after it was
+// consumed, records the security event and returns ErrRefreshTokenReused
+func (s *RefreshTokenService) revokeReusedFamily(ctx context.Context, record *models.RefreshToken, ipAddress, userAgent string, now time.Time) error {
+	if err := s.repo.RevokeFamily(ctx, record.SessionID, now); err != nil {
+		return err
+	}
+
+	event := apperrors.NewSecurityEvent("refresh_token_reuse", "high",
+		"Rotated refresh token presented again; session revoked")
+	event.IPAddress = ipAddress
+	event.UserAgent = userAgent
+	event.AddDetail("session_id", record.SessionID)
+	event.AddDetail("user_id", record.UserID)
+	event.AddDetail("token_id", record.ID)
+	event.AddDetail("token_used_at", record.UsedAt.UTC().Format(time.RFC3339))
+	if s.events != nil {
+		s.events.LogSecurityEvent(event)
+	} else {
+		log.Printf("Security event %s: %s (session %s, ip %s)", event.Type, event.Message, record.SessionID, ipAddress)
+	}
+
+	return fmt.Errorf("%w: session %s revoked", ErrRefreshTokenReused, record.SessionID)
+}
+
+func newRefreshToken(sessionID, userID, tokenHash string, now time.Time) *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(RefreshTokenTTL),
+		CreatedAt: now,
+	}
+}
+
+func generateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashRefreshToken is how refresh tokens are stored: sessions and
+// refresh_tokens hold this hash, never the token itself
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
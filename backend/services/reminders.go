@@ -0,0 +1,576 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ReminderLookback is how far back the scheduler looks for occurrences
+	// it has not sent when it starts, so a restart does not drop reminders
+	ReminderLookback = 15 * time.Minute
+
+	// Occurrences are remembered for this long to stop them being sent twice
+	reminderDispatchRetention = 7 * 24 * time.Hour
+	reminderClockLayout       = "15:04"
+)
+
+// ErrInvalidReminderRequest is returned for unknown reminder types, bad
+// clock times and other malformed reminder requests
+var ErrInvalidReminderRequest = errors.New("invalid reminder request")
+
+// reminderPreferences maps reminder types to the preference that switches
+// them off
+var reminderPreferences = map[string]string{
+	models.ReminderMeal:       "meal_reminders",
+	models.ReminderWater:      "water_reminders",
+	models.ReminderWeighIn:    "weigh_in_reminders",
+	models.ReminderWorkout:    "workout_reminders",
+	models.ReminderSupplement: "supplement_reminders",
+	models.ReminderMedication: "medication_reminders",
+}
+
+// defaultReminderTitles are used when a reminder is created without a title
+var defaultReminderTitles = map[string]string{
+	models.ReminderMeal:       "Time for your meal",
+	models.ReminderWater:      "Time to drink some water",
+	models.ReminderWeighIn:    "Time to weigh in",
+	models.ReminderWorkout:    "Time for your workout",
+	models.ReminderSupplement: "Time to take your supplements",
+	models.ReminderMedication: "Time to take your medication",
+}
+
+// namedReminderTimes turns the words used in medication administration
+// times and supplement timings into clock times
+var namedReminderTimes = []struct {
+	word  string
+	times []string
+}{
+	{"breakfast", []string{"08:00"}},
+	{"morning", []string{"08:00"}},
+	{"lunch", []string{"13:00"}},
+	{"noon", []string{"13:00"}},
+	{"midday", []string{"13:00"}},
+	{"afternoon", []string{"13:00"}},
+	{"dinner", []string{"19:00"}},
+	{"evening", []string{"19:00"}},
+	{"night", []string{"22:00"}},
+	{"bed", []string{"22:00"}},
+	{"meal", []string{"08:00", "13:00", "19:00"}},
+}
+
+// ReminderService manages reminders and sends them at the user's local
+// time. Users schedule meal, water, weigh-in and other reminders
+// themselves; medication and supplement reminders follow the administration
+// times and timings stored with them. Each occurrence is sent once through
+// the NotificationService, and occurrences inside the user's quiet hours are
+// held until the quiet hours end.
+type ReminderService struct {
+	repo          *repositories.ReminderRepository
+	medications   *repositories.MedicationRepository
+	supplements   *repositories.SupplementRepository
+	notifications *NotificationService
+	interval      time.Duration
+}
+
+// NewReminderService creates a new ReminderService instance
+func NewReminderService(db *sql.DB, notifications *NotificationService, reminderConfig config.ReminderConfig) *ReminderService {
+	interval := reminderConfig.PollInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	wrapped := database.NewDatabase(db)
+	return &ReminderService{
+		repo:          repositories.NewReminderRepository(wrapped),
+		medications:   repositories.NewMedicationRepository(wrapped),
+		supplements:   repositories.NewSupplementRepository(wrapped),
+		notifications: notifications,
+		interval:      interval,
+	}
+}
+
+// CreateReminder schedules a custom reminder
+func (s *ReminderService) CreateReminder(ctx context.Context, userID string, req models.CreateReminderRequest) (*models.Reminder, error) {
+	if !models.IsValidReminderType(req.Type) {
+		return nil, fmt.Errorf("%w: unknown reminder type %q", ErrInvalidReminderRequest, req.Type)
+	}
+	times, err := normalizeReminderTimes(req.Times)
+	if err != nil {
+		return nil, err
+	}
+	days, err := normalizeReminderDays(req.DaysOfWeek)
+	if err != nil {
+		return nil, err
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = defaultReminderTitles[req.Type]
+	}
+	if len(title) > 200 {
+		return nil, fmt.Errorf("%w: title must be at most 200 characters", ErrInvalidReminderRequest)
+	}
+
+	now := time.Now().UTC()
+	reminder := &models.Reminder{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Type:       req.Type,
+		Title:      title,
+		Message:    strings.TrimSpace(req.Message),
+		Times:      times,
+		DaysOfWeek: days,
+		Active:     true,
+		Source:     models.ReminderSourceCustom,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.repo.CreateReminder(ctx, reminder); err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// ListReminders returns the user's custom reminders followed by those
+// derived from their medications and supplements
+func (s *ReminderService) ListReminders(ctx context.Context, userID string) ([]*models.Reminder, error) {
+	reminders, err := s.repo.ListReminders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	derived, err := s.scheduleReminders(ctx, userID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return append(reminders, derived...), nil
+}
+
+// UpdateReminder changes a custom reminder
+func (s *ReminderService) UpdateReminder(ctx context.Context, userID, id string, req models.UpdateReminderRequest) (*models.Reminder, error) {
+	reminder, err := s.repo.GetReminder(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			title = defaultReminderTitles[reminder.Type]
+		}
+		if len(title) > 200 {
+			return nil, fmt.Errorf("%w: title must be at most 200 characters", ErrInvalidReminderRequest)
+		}
+		reminder.Title = title
+	}
+	if req.Message != nil {
+		reminder.Message = strings.TrimSpace(*req.Message)
+	}
+	if req.Times != nil {
+		if reminder.Times, err = normalizeReminderTimes(req.Times); err != nil {
+			return nil, err
+		}
+	}
+	if req.DaysOfWeek != nil {
+		if reminder.DaysOfWeek, err = normalizeReminderDays(*req.DaysOfWeek); err != nil {
+			return nil, err
+		}
+	}
+	if req.Active != nil {
+		reminder.Active = *req.Active
+	}
+	reminder.UpdatedAt = time.Now().UTC()
+
+	if err := s.repo.UpdateReminder(ctx, reminder); err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// DeleteReminder deletes a custom reminder
+func (s *ReminderService) DeleteReminder(ctx context.Context, userID, id string) error {
+	return s.repo.DeleteReminder(ctx, id, userID)
+}
+
+// Start sends due reminders every PollInterval until ctx is done
+func (s *ReminderService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		from := time.Now().Add(-ReminderLookback)
+		for {
+			to := time.Now()
+			if _, err := s.DispatchDue(ctx, from, to); err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Reminder dispatch failed: %v", err)
+				}
+			} else {
+				from = to
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// DispatchDue sends the reminder occurrences that fall after from and no
+// later than to, and returns how many were sent. Occurrences already sent,
+// by this or another instance, are skipped.
+func (s *ReminderService) DispatchDue(ctx context.Context, from, to time.Time) (int, error) {
+	reminders, err := s.repo.ListActiveReminders(ctx)
+	if err != nil {
+		return 0, err
+	}
+	derived, err := s.scheduleReminders(ctx, "", to)
+	if err != nil {
+		return 0, err
+	}
+	reminders = append(reminders, derived...)
+
+	settings := make(map[string]*reminderSettings)
+	sent := 0
+	for _, reminder := range reminders {
+		userSettings, ok := settings[reminder.UserID]
+		if !ok {
+			if userSettings, err = s.loadReminderSettings(ctx, reminder.UserID); err != nil {
+				return sent, err
+			}
+			settings[reminder.UserID] = userSettings
+		}
+		if !userSettings.allows(reminder.Type) {
+			continue
+		}
+
+		for _, at := range reminderOccurrences(reminder, userSettings.location, userSettings.quiet, from, to) {
+			key := reminder.ID + "@" + at.Format("2006-01-02T15:04")
+			claimed, err := s.repo.ClaimDispatch(ctx, reminder.UserID, key, at)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				continue
+			}
+			if err := s.notifications.Notify(ctx, reminderNotification(reminder, at)); err != nil {
+				log.Printf("Failed to send reminder %s to user %s: %v", reminder.ID, reminder.UserID, err)
+				continue
+			}
+			sent++
+		}
+	}
+
+	if _, err := s.repo.DeleteDispatchesBefore(ctx, to.Add(-reminderDispatchRetention)); err != nil {
+		return sent, err
+	}
+	return sent, nil
+}
+
+// scheduleReminders builds the reminders that follow the medication
+// administration times and supplement timings of one user, or of every user
+// when userID is empty. When at is set, medications and supplements outside
+// their start and end dates at that time are left out.
+func (s *ReminderService) scheduleReminders(ctx context.Context, userID string, at time.Time) ([]*models.Reminder, error) {
+	medications, err := s.medications.ListScheduledUserMedications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	supplements, err := s.supplements.ListScheduledSupplements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders := []*models.Reminder{}
+	for _, medication := range medications {
+		times := scheduleTimes(medication.AdministrationTime)
+		if len(times) == 0 || !withinDates(at, medication.StartDate, medication.EndDate) {
+			continue
+		}
+		title := defaultReminderTitles[models.ReminderMedication]
+		if medication.CustomMedicationName != nil && *medication.CustomMedicationName != "" {
+			title = "Time to take " + *medication.CustomMedicationName
+		}
+		reminders = append(reminders, &models.Reminder{
+			ID:         models.ReminderSourceMedication + ":" + medication.ID,
+			UserID:     medication.UserID,
+			Type:       models.ReminderMedication,
+			Title:      title,
+			Message:    medication.Dosage,
+			Times:      times,
+			DaysOfWeek: []int{},
+			Active:     true,
+			Source:     models.ReminderSourceMedication,
+			SourceID:   medication.ID,
+		})
+	}
+	for _, supplement := range supplements {
+		times := scheduleTimes([]string{supplement.Timing})
+		if len(times) == 0 || !withinDates(at, supplement.StartDate, supplement.EndDate) {
+			continue
+		}
+		reminders = append(reminders, &models.Reminder{
+			ID:         models.ReminderSourceSupplement + ":" + supplement.ID,
+			UserID:     supplement.UserID,
+			Type:       models.ReminderSupplement,
+			Title:      "Time to take " + supplement.Name,
+			Message:    supplement.Dosage,
+			Times:      times,
+			DaysOfWeek: []int{},
+			Active:     true,
+			Source:     models.ReminderSourceSupplement,
+			SourceID:   supplement.ID,
+			CreatedAt:  supplement.CreatedAt,
+			UpdatedAt:  supplement.UpdatedAt,
+		})
+	}
+	return reminders, nil
+}
+
+// reminderSettings are the preferences that decide whether and when a
+// user's reminders are sent
+type reminderSettings struct {
+	preferences map[string]interface{}
+	location    *time.Location
+	quiet       quietHours
+}
+
+func (s *ReminderService) loadReminderSettings(ctx context.Context, userID string) (*reminderSettings, error) {
+	preferences, err := s.notifications.userPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	location := time.UTC
+	if name, ok := preferences["timezone"].(string); ok {
+		if loaded, err := time.LoadLocation(name); err == nil {
+			location = loaded
+		}
+	}
+
+	var quiet quietHours
+	start, startOK := preferences["quiet_hours_start"].(string)
+	end, endOK := preferences["quiet_hours_end"].(string)
+	if startOK && endOK {
+		quiet.start, startOK = parseClock(start)
+		quiet.end, endOK = parseClock(end)
+		if !startOK || !endOK {
+			quiet = quietHours{}
+		}
+	}
+
+	return &reminderSettings{preferences: preferences, location: location, quiet: quiet}, nil
+}
+
+// allows reports whether the user wants reminders of this type
+func (r *reminderSettings) allows(reminderType string) bool {
+	if !preferenceEnabled(r.preferences, "notifications_enabled") {
+		return false
+	}
+	key, ok := reminderPreferences[reminderType]
+	return !ok || preferenceEnabled(r.preferences, key)
+}
+
+// quietHours is a daily window, in minutes after local midnight, in which
+// reminders are held. The window may wrap past midnight; start == end means
+// no quiet hours.
+type quietHours struct {
+	start, end int
+}
+
+// release returns when a reminder scheduled at at may be sent: at itself,
+// or the end of the quiet hours it falls in
+func (q quietHours) release(at time.Time) time.Time {
+	if q.start == q.end {
+		return at
+	}
+
+	minute := at.Hour()*60 + at.Minute()
+	days := 0
+	switch {
+	case q.start < q.end && minute >= q.start && minute < q.end:
+	case q.start > q.end && minute >= q.start:
+		days = 1
+	case q.start > q.end && minute < q.end:
+	default:
+		return at
+	}
+	return time.Date(at.Year(), at.Month(), at.Day()+days, q.end/60, q.end%60, 0, 0, at.Location())
+}
+
+// reminderOccurrences returns when the reminder is sent after from and no
+// later than to. Occurrences held by quiet hours are moved to their end, and
+// several occurrences held to the same time are sent once.
+func reminderOccurrences(reminder *models.Reminder, location *time.Location, quiet quietHours, from, to time.Time) []time.Time {
+	localFrom := from.In(location)
+	localTo := to.In(location)
+
+	var occurrences []time.Time
+	// Start a day early: quiet hours can hold yesterday's reminders into
+	// the window
+	day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day()-1, 0, 0, 0, 0, location)
+	for !day.After(localTo) {
+		if runsOnWeekday(reminder.DaysOfWeek, day.Weekday()) {
+			for _, clock := range reminder.Times {
+				minute, ok := parseClock(clock)
+				if !ok {
+					continue
+				}
+				at := time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, location)
+				at = quiet.release(at)
+				if at.After(from) && !at.After(to) && !containsTime(occurrences, at) {
+					occurrences = append(occurrences, at)
+				}
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, location)
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Before(occurrences[j]) })
+	return occurrences
+}
+
+func reminderNotification(reminder *models.Reminder, at time.Time) *models.Notification {
+	data := map[string]interface{}{
+		"reminder_id":   reminder.ID,
+		"reminder_type": reminder.Type,
+		"source":        reminder.Source,
+		"scheduled_for": at.Format(time.RFC3339),
+	}
+	if reminder.SourceID != "" {
+		data["source_id"] = reminder.SourceID
+	}
+
+	return &models.Notification{
+		UserID: reminder.UserID,
+		Type:   models.NotificationTypeReminder,
+		Title:  reminder.Title,
+		Body:   reminder.Message,
+		Data:   data,
+	}
+}
+
+// parseClock parses a "HH:MM" time of day into minutes after midnight
+func parseClock(value string) (int, bool) {
+	t, err := time.Parse(reminderClockLayout, strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// scheduleTimes turns free-form medication administration times and
+// supplement timings, such as "08:00", "morning" or "with meals", into
+// sorted clock times. Values it does not recognise are ignored.
+func scheduleTimes(values []string) []string {
+	times := []string{}
+	for _, value := range values {
+		for _, part := range strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+			return r == ',' || r == ';' || r == '/'
+		}) {
+			part = strings.TrimSpace(part)
+			if minute, ok := parseClock(part); ok {
+				times = appendClock(times, minute)
+				continue
+			}
+			for _, named := range namedReminderTimes {
+				if !strings.Contains(part, named.word) {
+					continue
+				}
+				for _, clock := range named.times {
+					if !containsString(times, clock) {
+						times = append(times, clock)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(times)
+	return times
+}
+
+// normalizeReminderTimes validates clock times and returns them as sorted,
+// de-duplicated "HH:MM" strings
+func normalizeReminderTimes(values []string) ([]string, error) {
+	times := []string{}
+	for _, value := range values {
+		minute, ok := parseClock(value)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a time of day in HH:MM format", ErrInvalidReminderRequest, value)
+		}
+		times = appendClock(times, minute)
+	}
+	if len(times) == 0 {
+		return nil, fmt.Errorf("%w: at least one time is required", ErrInvalidReminderRequest)
+	}
+	sort.Strings(times)
+	return times, nil
+}
+
+func normalizeReminderDays(values []int) ([]int, error) {
+	days := []int{}
+	seen := make(map[int]bool)
+	for _, day := range values {
+		if day < 0 || day > 6 {
+			return nil, fmt.Errorf("%w: days_of_week must be between 0 (Sunday) and 6", ErrInvalidReminderRequest)
+		}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Ints(days)
+	return days, nil
+}
+
+func appendClock(times []string, minute int) []string {
+	clock := fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+	if containsString(times, clock) {
+		return times
+	}
+	return append(times, clock)
+}
+
+func runsOnWeekday(days []int, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		if day == int(weekday) {
+			return true
+		}
+	}
+	return false
+}
+
+func withinDates(at time.Time, start, end *time.Time) bool {
+	if at.IsZero() {
+		return true
+	}
+	if start != nil && start.After(at) {
+		return false
+	}
+	return end == nil || !end.Before(at)
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, existing := range times {
+		if existing.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
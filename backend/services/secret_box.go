@@ -0,0 +1,54 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// secretBox encrypts secrets that have to be stored recoverably, such as
+// TOTP and webhook signing secrets, with AES-256-GCM keyed by the SHA-256
+// of a configured key
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+func newSecretBox(key string) *secretBox {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		// A 32-byte key is always valid for AES-256
+		panic(fmt.Sprintf("failed to create secret cipher: %v", err))
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create secret cipher: %v", err))
+	}
+	return &secretBox{aead: aead}
+}
+
+// seal returns the base64 of a random nonce followed by the ciphertext
+func (b *secretBox) seal(secret string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *secretBox) open(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt secret: malformed ciphertext")
+	}
+	nonce, sealed := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(secret), nil
+}
@@ -15,6 +15,8 @@ import (
 	"sync"
 	"time"
 
+	"nutrition-platform/security"
+
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 )
@@ -523,9 +525,8 @@ func (sm *SecretsManager) generateOAuthSecret(length int) string {
 
 // generateWebhookSecret generates a webhook secret
 func (sm *SecretsManager) generateWebhookSecret(length int) string {
-	secret := make([]byte, length)
-	rand.Read(secret)
-	return fmt.Sprintf("%x", secret)
+	secret, _ := security.GenerateWebhookSecret(length)
+	return secret
 }
 
 // encrypt encrypts a value using AES-GCM
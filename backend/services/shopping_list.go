@@ -0,0 +1,646 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+const (
+	// maxShoppingListDays bounds the date range a list can cover
+	maxShoppingListDays = 31
+	// shoppingListPlanLimit bounds how many meal plans are searched for a date range
+	shoppingListPlanLimit = 100
+)
+
+// Shopping list export formats
+const (
+	ShoppingListFormatText = "text"
+	ShoppingListFormatCSV  = "csv"
+)
+
+// Shopping list aisles, in the order a list is printed
+const (
+	CategoryProduce      = "produce"
+	CategoryMeatSeafood  = "meat_seafood"
+	CategoryDairyEggs    = "dairy_eggs"
+	CategoryBakeryGrains = "bakery_grains"
+	CategoryPantry       = "pantry"
+	CategorySpices       = "spices"
+	CategoryOther        = "other"
+)
+
+var (
+	// ErrInvalidShoppingListRequest is wrapped by errors caused by the request
+	ErrInvalidShoppingListRequest = errors.New("invalid shopping list request")
+	// ErrEmptyShoppingList is returned when the selection contains no ingredients
+	ErrEmptyShoppingList = errors.New("no planned meals or recipes to shop for")
+)
+
+var shoppingCategoryOrder = []string{
+	CategoryProduce, CategoryMeatSeafood, CategoryDairyEggs, CategoryBakeryGrains,
+	CategoryPantry, CategorySpices, CategoryOther,
+}
+
+var shoppingCategoryLabels = map[string]string{
+	CategoryProduce:      "Produce",
+	CategoryMeatSeafood:  "Meat & seafood",
+	CategoryDairyEggs:    "Dairy & eggs",
+	CategoryBakeryGrains: "Bakery & grains",
+	CategoryPantry:       "Pantry",
+	CategorySpices:       "Spices",
+	CategoryOther:        "Other",
+}
+
+var (
+	produceKeywords = []string{"tomato", "onion", "garlic", "potato", "carrot", "cucumber", "lettuce", "spinach", "kale",
+		"cabbage", "broccoli", "cauliflower", "zucchini", "eggplant", "aubergine", "okra", "mushroom", "celery", "leek",
+		"pepper", "capsicum", "chili", "ginger", "parsley", "cilantro", "coriander", "mint", "dill", "basil", "lemon",
+		"lime", "orange", "apple", "banana", "grape", "berry", "strawberry", "blueberry", "mango", "pomegranate", "date",
+		"fig", "avocado", "pea", "corn", "beetroot", "radish", "pumpkin", "squash", "fruit", "vegetable", "salad", "herb"}
+	bakeryGrainKeywords = []string{"rice", "quinoa", "oat", "bulgur", "freekeh", "couscous", "bread", "pita", "tortilla",
+		"pasta", "noodle", "spaghetti", "vermicelli", "flour", "semolina", "barley", "cracker", "bun", "roll"}
+	pantryKeywords = []string{"oil", "vinegar", "sugar", "honey", "syrup", "molasses", "tahini", "lentil", "chickpea",
+		"bean", "tomato paste", "tomato sauce", "sauce", "paste", "stock", "broth", "chicken stock", "beef stock",
+		"vegetable stock", "peanut butter", "almond", "walnut", "cashew", "pistachio", "peanut", "nut", "seed", "raisin",
+		"coconut milk", "canned", "jam", "baking powder", "baking soda", "yeast", "cocoa", "chocolate", "coffee", "tea",
+		"water"}
+	spiceKeywords = []string{"salt", "black pepper", "white pepper", "chili powder", "chili flake", "cumin", "cinnamon",
+		"paprika", "turmeric", "cardamom", "saffron", "sumac", "zaatar", "oregano", "thyme", "rosemary", "bay leaf",
+		"nutmeg", "clove", "allspice", "spice", "seasoning", "vanilla", "dried mint", "ground coriander", "coriander seed"}
+)
+
+// shoppingCategoryRules are checked together; the longest matching keyword
+// wins so "peanut butter" is pantry while "butter" is dairy, and ties go to
+// the earlier rule
+var shoppingCategoryRules = []struct {
+	category string
+	keywords []string
+}{
+	{CategorySpices, spiceKeywords},
+	{CategoryMeatSeafood, concatKeywords(meatKeywords, seafoodKeywords)},
+	{CategoryDairyEggs, concatKeywords(dairyKeywords, eggKeywords)},
+	{CategoryPantry, pantryKeywords},
+	{CategoryBakeryGrains, bakeryGrainKeywords},
+	{CategoryProduce, produceKeywords},
+}
+
+// ShoppingListService builds shopping lists from meal plans and recipes
+type ShoppingListService struct {
+	lists   *repositories.ShoppingListRepository
+	plans   *repositories.MealPlanRepository
+	recipes *repositories.RecipeRepository
+}
+
+// NewShoppingListService creates a new ShoppingListService instance
+func NewShoppingListService(db *sql.DB) *ShoppingListService {
+	wrapped := database.NewDatabase(db)
+	return &ShoppingListService{
+		lists:   repositories.NewShoppingListRepository(wrapped),
+		plans:   repositories.NewMealPlanRepository(wrapped),
+		recipes: repositories.NewRecipeRepository(wrapped),
+	}
+}
+
+// CreateShoppingList gathers the ingredients of the selected meal plan days
+// and recipes, merges duplicates into one line per ingredient and unit
+// dimension, and stores the list grouped by aisle. Without a meal_plan_id
+// the date range covers every active plan of the user.
+func (s *ShoppingListService) CreateShoppingList(ctx context.Context, userID int, req models.CreateShoppingListRequest) (*models.ShoppingList, error) {
+	start, end, err := parseShoppingRange(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if req.MealPlanID == nil && start == nil && len(req.Recipes) == 0 {
+		return nil, fmt.Errorf("%w: meal_plan_id, a date range or recipes are required", ErrInvalidShoppingListRequest)
+	}
+
+	builder := newShoppingListBuilder()
+	recipeCache := map[string]*models.Recipe{}
+	var planName string
+
+	var plans []*models.MealPlan
+	if req.MealPlanID != nil {
+		plan, err := s.plans.GetMealPlanByID(ctx, *req.MealPlanID, userID)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+		planName = plan.Name
+	} else if start != nil {
+		all, err := s.plans.GetMealPlansByUserID(ctx, userID, shoppingListPlanLimit, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, plan := range all {
+			if plan.IsActive {
+				plans = append(plans, plan)
+			}
+		}
+	}
+
+	for _, plan := range plans {
+		for _, day := range plan.Days {
+			if !planDayInRange(plan, day, start, end) {
+				continue
+			}
+			for _, meal := range day.Meals {
+				if err := s.addPlannedMeal(ctx, builder, recipeCache, meal); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, planned := range req.Recipes {
+		recipe, err := s.loadRecipe(ctx, recipeCache, planned.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+		if recipe == nil {
+			return nil, fmt.Errorf("%w: recipe %s not found", ErrInvalidShoppingListRequest, planned.RecipeID)
+		}
+		if planned.Servings < 0 {
+			return nil, fmt.Errorf("%w: servings must be positive", ErrInvalidShoppingListRequest)
+		}
+		servings := planned.Servings
+		if servings == 0 {
+			servings = float64(recipeServings(recipe))
+		}
+		builder.addRecipe(recipe, servings)
+	}
+
+	items := builder.items()
+	if len(items) == 0 {
+		return nil, ErrEmptyShoppingList
+	}
+
+	list := &models.ShoppingList{
+		UserID:     userID,
+		Name:       strings.TrimSpace(req.Name),
+		MealPlanID: req.MealPlanID,
+		StartDate:  start,
+		EndDate:    end,
+		Items:      items,
+	}
+	if list.Name == "" {
+		list.Name = defaultShoppingListName(planName, start, end)
+	}
+
+	if err := s.lists.CreateShoppingList(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetShoppingList returns a list with its items
+func (s *ShoppingListService) GetShoppingList(ctx context.Context, id, userID int) (*models.ShoppingList, error) {
+	return s.lists.GetShoppingList(ctx, id, userID)
+}
+
+// ListShoppingLists returns a user's lists without their items, newest first
+func (s *ShoppingListService) ListShoppingLists(ctx context.Context, userID, limit, offset int) ([]*models.ShoppingList, error) {
+	return s.lists.GetShoppingListsByUserID(ctx, userID, limit, offset)
+}
+
+// SetItemChecked ticks an item off the list, or back on
+func (s *ShoppingListService) SetItemChecked(ctx context.Context, listID, itemID, userID int, checked bool) error {
+	return s.lists.SetItemChecked(ctx, listID, itemID, userID, checked)
+}
+
+// DeleteShoppingList removes a list and its items
+func (s *ShoppingListService) DeleteShoppingList(ctx context.Context, id, userID int) error {
+	return s.lists.DeleteShoppingList(ctx, id, userID)
+}
+
+// ExportShoppingList renders a list as plain text or CSV and returns the
+// content with its MIME type
+func (s *ShoppingListService) ExportShoppingList(ctx context.Context, id, userID int, format string) ([]byte, string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = ShoppingListFormatText
+	}
+	if format != ShoppingListFormatText && format != ShoppingListFormatCSV {
+		return nil, "", fmt.Errorf("%w: unsupported export format %q", ErrInvalidShoppingListRequest, format)
+	}
+
+	list, err := s.lists.GetShoppingList(ctx, id, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if format == ShoppingListFormatCSV {
+		content, err := ShoppingListCSV(list)
+		return content, "text/csv; charset=utf-8", err
+	}
+	return []byte(ShoppingListText(list)), "text/plain; charset=utf-8", nil
+}
+
+// ShoppingListText renders a list grouped by aisle with a checkbox per item
+func ShoppingListText(list *models.ShoppingList) string {
+	var b strings.Builder
+	b.WriteString(list.Name)
+	b.WriteString("\n")
+
+	for _, group := range groupShoppingItems(list.Items) {
+		b.WriteString("\n")
+		b.WriteString(shoppingCategoryLabels[group.category])
+		b.WriteString("\n")
+		for _, item := range group.items {
+			box := "[ ]"
+			if item.Checked {
+				box = "[x]"
+			}
+			line := item.Name
+			if amount := formatShoppingAmount(item); amount != "" {
+				line = amount + " " + item.Name
+			}
+			if item.Optional {
+				line += " (optional)"
+			}
+			b.WriteString(box + " " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// ShoppingListCSV renders a list as CSV with one row per item
+func ShoppingListCSV(list *models.ShoppingList) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"category", "item", "quantity", "unit", "checked", "optional", "used_in"}); err != nil {
+		return nil, fmt.Errorf("failed to write shopping list csv: %w", err)
+	}
+
+	for _, group := range groupShoppingItems(list.Items) {
+		for _, item := range group.items {
+			quantity := ""
+			if item.Quantity != nil {
+				quantity = strconv.FormatFloat(*item.Quantity, 'f', -1, 64)
+			}
+			record := []string{
+				shoppingCategoryLabels[group.category],
+				item.Name,
+				quantity,
+				item.Unit,
+				strconv.FormatBool(item.Checked),
+				strconv.FormatBool(item.Optional),
+				strings.Join(item.Sources, "; "),
+			}
+			if err := w.Write(record); err != nil {
+				return nil, fmt.Errorf("failed to write shopping list csv: %w", err)
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write shopping list csv: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// CategorizeIngredient returns the aisle an ingredient is shopped in
+func CategorizeIngredient(name string) string {
+	phrase := " " + strings.Join(ingredientTokens(name), " ") + " "
+	best, bestLen := CategoryOther, 0
+	for _, rule := range shoppingCategoryRules {
+		for _, keyword := range rule.keywords {
+			kw := strings.Join(ingredientTokens(keyword), " ")
+			if kw == "" || len(kw) <= bestLen {
+				continue
+			}
+			if strings.Contains(phrase, " "+kw+" ") {
+				best, bestLen = rule.category, len(kw)
+			}
+		}
+	}
+	return best
+}
+
+func (s *ShoppingListService) addPlannedMeal(ctx context.Context, builder *shoppingListBuilder, cache map[string]*models.Recipe, meal models.PlannedMeal) error {
+	for _, item := range meal.Items {
+		// The main dish of a recipe meal is counted in servings of the recipe
+		if meal.RecipeID != nil && item.FoodID == nil && item.Unit == UnitServing {
+			recipe, err := s.loadRecipe(ctx, cache, *meal.RecipeID)
+			if err != nil {
+				return err
+			}
+			if recipe != nil {
+				builder.addRecipe(recipe, item.Quantity)
+				continue
+			}
+		}
+
+		source := meal.Name.En
+		if source == "" {
+			source = meal.MealType
+		}
+		builder.add(item.Name.En, item.Quantity, item.Unit, false, source)
+	}
+	return nil
+}
+
+// loadRecipe returns nil without an error for recipes that no longer exist
+func (s *ShoppingListService) loadRecipe(ctx context.Context, cache map[string]*models.Recipe, id string) (*models.Recipe, error) {
+	if recipe, ok := cache[id]; ok {
+		return recipe, nil
+	}
+	recipe, err := s.recipes.GetRecipeByID(ctx, id)
+	if err != nil {
+		if err.Error() != "recipe not found" {
+			return nil, err
+		}
+		recipe = nil
+	}
+	cache[id] = recipe
+	return recipe, nil
+}
+
+func recipeServings(recipe *models.Recipe) int {
+	if recipe.Servings != nil && *recipe.Servings > 0 {
+		return *recipe.Servings
+	}
+	return 1
+}
+
+// shoppingEntry accumulates one ingredient in one unit dimension
+type shoppingEntry struct {
+	name     string
+	tokens   string
+	amount   float64
+	unit     string
+	measured bool
+	optional bool
+	sources  []string
+}
+
+type shoppingListBuilder struct {
+	entries map[string]*shoppingEntry
+	order   []string
+}
+
+func newShoppingListBuilder() *shoppingListBuilder {
+	return &shoppingListBuilder{entries: map[string]*shoppingEntry{}}
+}
+
+// addRecipe adds a recipe's ingredients scaled from its own servings to the
+// planned servings
+func (b *shoppingListBuilder) addRecipe(recipe *models.Recipe, servings float64) {
+	factor := servings / float64(recipeServings(recipe))
+	for _, ingredient := range recipe.Ingredients {
+		parsed, err := resolveIngredient(ingredient)
+		if err != nil {
+			// Lines such as "salt to taste" are listed without an amount
+			b.add(ingredient.Name, 0, "", ingredient.Optional, recipe.Name)
+			continue
+		}
+		b.add(parsed.Name, parsed.Quantity*factor, parsed.Unit, ingredient.Optional, recipe.Name)
+	}
+}
+
+// add merges an amount into the entry for the same ingredient and unit
+// dimension: masses are summed in grams and volumes in milliliters
+func (b *shoppingListBuilder) add(name string, quantity float64, unit string, optional bool, source string) {
+	tokens := strings.Join(ingredientTokens(name), " ")
+	if tokens == "" {
+		return
+	}
+
+	canonical, err := NormalizeUnit(unit)
+	if err != nil {
+		canonical = strings.ToLower(strings.TrimSpace(unit))
+	}
+	measured := quantity > 0
+	switch {
+	case !measured:
+		canonical = ""
+	case IsMassUnit(canonical):
+		quantity *= gramsPerUnit[canonical]
+		canonical = UnitGram
+	case IsVolumeUnit(canonical):
+		quantity *= millilitersPerUnit[canonical]
+		canonical = UnitMilliliter
+	case canonical == "":
+		canonical = UnitPiece
+	}
+
+	key := tokens + "|" + canonical
+	entry, ok := b.entries[key]
+	if !ok {
+		entry = &shoppingEntry{
+			name:     strings.TrimSpace(name),
+			tokens:   tokens,
+			unit:     canonical,
+			measured: measured,
+			optional: true,
+		}
+		if !measured {
+			entry.name = tokens
+		}
+		b.entries[key] = entry
+		b.order = append(b.order, key)
+	}
+
+	entry.amount += quantity
+	entry.optional = entry.optional && optional
+	if source != "" && !containsString(entry.sources, source) {
+		entry.sources = append(entry.sources, source)
+	}
+}
+
+// items returns the merged entries sorted by aisle and name. An ingredient
+// listed without an amount is dropped when another line already buys it.
+func (b *shoppingListBuilder) items() []models.ShoppingListItem {
+	measured := map[string]*shoppingEntry{}
+	for _, key := range b.order {
+		if entry := b.entries[key]; entry.measured {
+			if _, ok := measured[entry.tokens]; !ok {
+				measured[entry.tokens] = entry
+			}
+		}
+	}
+
+	var items []models.ShoppingListItem
+	for _, key := range b.order {
+		entry := b.entries[key]
+		if !entry.measured {
+			if other, ok := measured[entry.tokens]; ok {
+				for _, source := range entry.sources {
+					if !containsString(other.sources, source) {
+						other.sources = append(other.sources, source)
+					}
+				}
+				continue
+			}
+		}
+
+		item := models.ShoppingListItem{
+			Name:     entry.name,
+			Category: CategorizeIngredient(entry.name),
+			Optional: entry.optional,
+			Sources:  entry.sources,
+		}
+		if entry.measured {
+			quantity, unit := shoppingQuantity(entry.amount, entry.unit)
+			item.Quantity = &quantity
+			item.Unit = unit
+		}
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		ci, cj := categoryRank(items[i].Category), categoryRank(items[j].Category)
+		if ci != cj {
+			return ci < cj
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	return items
+}
+
+// shoppingQuantity rounds a summed amount to what is bought: whole grams
+// and milliliters, kilograms and liters from 1000 up, and whole pieces
+func shoppingQuantity(amount float64, unit string) (float64, string) {
+	switch unit {
+	case UnitGram:
+		if amount >= 1000 {
+			return roundTo(amount/1000, 2), UnitKilogram
+		}
+		return math.Ceil(amount), UnitGram
+	case UnitMilliliter:
+		if amount >= 1000 {
+			return roundTo(amount/1000, 2), UnitLiter
+		}
+		return math.Ceil(amount), UnitMilliliter
+	case UnitPiece:
+		return math.Ceil(amount - 0.01), UnitPiece
+	}
+	return roundTo(amount, 2), unit
+}
+
+func formatShoppingAmount(item models.ShoppingListItem) string {
+	if item.Quantity == nil {
+		return ""
+	}
+	amount := strconv.FormatFloat(*item.Quantity, 'f', -1, 64)
+	switch item.Unit {
+	case "", UnitPiece:
+		return amount
+	case UnitServing:
+		if *item.Quantity == 1 {
+			return amount + " serving"
+		}
+		return amount + " servings"
+	}
+	return amount + " " + item.Unit
+}
+
+type shoppingGroup struct {
+	category string
+	items    []models.ShoppingListItem
+}
+
+// groupShoppingItems groups items by aisle in print order, keeping the
+// stored order within each aisle
+func groupShoppingItems(items []models.ShoppingListItem) []shoppingGroup {
+	byCategory := map[string][]models.ShoppingListItem{}
+	for _, item := range items {
+		category := item.Category
+		if _, ok := shoppingCategoryLabels[category]; !ok {
+			category = CategoryOther
+		}
+		byCategory[category] = append(byCategory[category], item)
+	}
+
+	var groups []shoppingGroup
+	for _, category := range shoppingCategoryOrder {
+		if len(byCategory[category]) > 0 {
+			groups = append(groups, shoppingGroup{category: category, items: byCategory[category]})
+		}
+	}
+	return groups
+}
+
+func categoryRank(category string) int {
+	for i, c := range shoppingCategoryOrder {
+		if c == category {
+			return i
+		}
+	}
+	return len(shoppingCategoryOrder)
+}
+
+// parseShoppingRange parses an optional inclusive date range
+func parseShoppingRange(startDate, endDate string) (*time.Time, *time.Time, error) {
+	if startDate == "" && endDate == "" {
+		return nil, nil, nil
+	}
+	if startDate == "" || endDate == "" {
+		return nil, nil, fmt.Errorf("%w: start_date and end_date must be given together", ErrInvalidShoppingListRequest)
+	}
+
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrInvalidShoppingListRequest)
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrInvalidShoppingListRequest)
+	}
+	if end.Before(start) {
+		return nil, nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidShoppingListRequest)
+	}
+	if daysBetween(start, end)+1 > maxShoppingListDays {
+		return nil, nil, fmt.Errorf("%w: a list covers at most %d days", ErrInvalidShoppingListRequest, maxShoppingListDays)
+	}
+	return &start, &end, nil
+}
+
+// planDayInRange reports whether a plan day falls in the range. Days without
+// a date are placed from the plan's start date.
+func planDayInRange(plan *models.MealPlan, day models.MealPlanDay, start, end *time.Time) bool {
+	if start == nil {
+		return true
+	}
+	date := day.Date
+	if date == nil {
+		if plan.StartDate == nil {
+			return false
+		}
+		d := plan.StartDate.AddDate(0, 0, day.DayNumber-1)
+		date = &d
+	}
+	return !date.Before(*start) && !date.After(*end)
+}
+
+func defaultShoppingListName(planName string, start, end *time.Time) string {
+	switch {
+	case start != nil:
+		return fmt.Sprintf("Shopping list %s – %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	case planName != "":
+		return "Shopping list for " + planName
+	}
+	return "Shopping list"
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"database/sql"
+
+	"nutrition-platform/database"
+	"nutrition-platform/repositories"
+)
+
+// Repositories behind the package-level meal, supplement, medical plan and
+// session functions. They replace the JSON files under ./data so several
+// server instances can share the same data.
+var (
+	mealStore        *repositories.MealEntryRepository
+	supplementStore  *repositories.SupplementRepository
+	medicalPlanStore *repositories.MedicalPlanRepository
+	sessionStore     *repositories.SessionRepository
+)
+
+// InitializeSQLStorage points the meal, supplement, medical plan and session
+// functions at the database. It must run before any of them is called.
+func InitializeSQLStorage(db *sql.DB) {
+	wrapped := database.NewDatabase(db)
+	mealStore = repositories.NewMealEntryRepository(wrapped)
+	supplementStore = repositories.NewSupplementRepository(wrapped)
+	medicalPlanStore = repositories.NewMedicalPlanRepository(wrapped)
+	sessionStore = repositories.NewSessionRepository(wrapped)
+}
@@ -1,53 +1,26 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"nutrition-platform/models"
+
 	"github.com/google/uuid"
 )
 
 // Supplement represents a dietary supplement
-type Supplement struct {
-	ID           string     `json:"id"`
-	UserID       string     `json:"user_id"`
-	Name         string     `json:"name"`
-	Brand        string     `json:"brand"`
-	Description  string     `json:"description"`
-	Category     string     `json:"category"` // vitamin, mineral, protein, herbal, etc.
-	Form         string     `json:"form"`     // tablet, capsule, powder, liquid
-	Dosage       string     `json:"dosage"`
-	Frequency    string     `json:"frequency"` // daily, weekly, as needed
-	Timing       string     `json:"timing"`    // morning, evening, with meals, etc.
-	Ingredients  []string   `json:"ingredients"`
-	Benefits     []string   `json:"benefits"`
-	SideEffects  []string   `json:"side_effects,omitempty"`
-	Warnings     []string   `json:"warnings,omitempty"`
-	IsHalal      bool       `json:"is_halal"`
-	IsVegetarian bool       `json:"is_vegetarian"`
-	IsVegan      bool       `json:"is_vegan"`
-	IsOrganic    bool       `json:"is_organic"`
-	Price        float64    `json:"price,omitempty"`
-	Currency     string     `json:"currency,omitempty"`
-	ImageURL     string     `json:"image_url,omitempty"`
-	Tags         []string   `json:"tags"`
-	Notes        string     `json:"notes,omitempty"`
-	StartDate    *time.Time `json:"start_date,omitempty"`
-	EndDate      *time.Time `json:"end_date,omitempty"`
-	IsActive     bool       `json:"is_active"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-}
+type Supplement = models.Supplement
 
-// SupplementData represents the structure of supplements.json
+// SupplementData represents the structure of supplements.json, which
+// ImportJSONStores reads when moving supplements into the database
 type SupplementData struct {
 	Supplements []Supplement `json:"supplements"`
 	Metadata    Metadata     `json:"metadata"`
 }
 
-const supplementsFile = "backend/data/supplements.json"
-
 // CreateSupplement creates a new supplement entry
 func CreateSupplement(supplement *Supplement) error {
 	// Generate ID and timestamps
@@ -96,31 +69,26 @@ func CreateSupplement(supplement *Supplement) error {
 	}
 	supplement.IsActive = true
 
-	return AppendJSON(supplementsFile, supplement)
+	return supplementStore.CreateSupplement(context.Background(), supplement)
 }
 
 // GetSupplementsByUserID retrieves all supplements for a specific user
 func GetSupplementsByUserID(userID string) ([]Supplement, error) {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
+	supplements, err := supplementStore.GetSupplementsByUserID(context.Background(), userID)
 	if err != nil {
 		return nil, err
 	}
 
-	var userSupplements []Supplement
-	for _, supplement := range data.Supplements {
-		if supplement.UserID == userID {
-			userSupplements = append(userSupplements, supplement)
-		}
+	userSupplements := make([]Supplement, 0, len(supplements))
+	for _, supplement := range supplements {
+		userSupplements = append(userSupplements, *supplement)
 	}
-
 	return userSupplements, nil
 }
 
 // GetActiveSupplementsByUserID retrieves active supplements for a user
 func GetActiveSupplementsByUserID(userID string) ([]Supplement, error) {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
+	supplements, err := GetSupplementsByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -128,8 +96,8 @@ func GetActiveSupplementsByUserID(userID string) ([]Supplement, error) {
 	var activeSupplements []Supplement
 	now := time.Now()
 
-	for _, supplement := range data.Supplements {
-		if supplement.UserID == userID && supplement.IsActive {
+	for _, supplement := range supplements {
+		if supplement.IsActive {
 			// Check if supplement is within date range
 			if supplement.StartDate != nil && supplement.StartDate.After(now) {
 				continue
@@ -146,102 +114,56 @@ func GetActiveSupplementsByUserID(userID string) ([]Supplement, error) {
 
 // GetSupplementByID retrieves a specific supplement by ID
 func GetSupplementByID(supplementID string) (*Supplement, error) {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, supplement := range data.Supplements {
-		if supplement.ID == supplementID {
-			return &supplement, nil
-		}
-	}
-
-	return nil, fmt.Errorf("supplement not found")
+	return supplementStore.GetSupplementByID(context.Background(), supplementID)
 }
 
 // UpdateSupplement updates an existing supplement
 func UpdateSupplement(supplementID string, updatedSupplement *Supplement) error {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
+	ctx := context.Background()
+	supplement, err := supplementStore.GetSupplementByID(ctx, supplementID)
 	if err != nil {
 		return err
 	}
 
-	for i, supplement := range data.Supplements {
-		if supplement.ID == supplementID {
-			// Preserve original ID and created time
-			updatedSupplement.ID = supplement.ID
-			updatedSupplement.CreatedAt = supplement.CreatedAt
-			updatedSupplement.UpdatedAt = time.Now()
-
-			// Auto-detect dietary restrictions
-			updatedSupplement.IsHalal = isHalalSupplement(updatedSupplement.Ingredients)
-			updatedSupplement.IsVegetarian = isVegetarianSupplement(updatedSupplement.Ingredients)
-			updatedSupplement.IsVegan = isVeganSupplement(updatedSupplement.Ingredients)
-
-			data.Supplements[i] = *updatedSupplement
-			data.Metadata.UpdatedAt = time.Now()
+	// Preserve original ID, owner and created time
+	updatedSupplement.ID = supplement.ID
+	updatedSupplement.UserID = supplement.UserID
+	updatedSupplement.CreatedAt = supplement.CreatedAt
+	updatedSupplement.UpdatedAt = time.Now()
 
-			return WriteJSON(supplementsFile, data)
-		}
-	}
+	// Auto-detect dietary restrictions
+	updatedSupplement.IsHalal = isHalalSupplement(updatedSupplement.Ingredients)
+	updatedSupplement.IsVegetarian = isVegetarianSupplement(updatedSupplement.Ingredients)
+	updatedSupplement.IsVegan = isVeganSupplement(updatedSupplement.Ingredients)
 
-	return fmt.Errorf("supplement not found")
+	return supplementStore.UpdateSupplement(ctx, updatedSupplement)
 }
 
 // DeleteSupplement deletes a supplement
 func DeleteSupplement(supplementID string, userID string) error {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
+	ctx := context.Background()
+	supplement, err := supplementStore.GetSupplementByID(ctx, supplementID)
 	if err != nil {
 		return err
 	}
 
-	for i, supplement := range data.Supplements {
-		if supplement.ID == supplementID {
-			// Check if user owns this supplement
-			if supplement.UserID != userID {
-				return fmt.Errorf("unauthorized: supplement belongs to another user")
-			}
-
-			// Remove supplement from slice
-			data.Supplements = append(data.Supplements[:i], data.Supplements[i+1:]...)
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(supplementsFile, data)
-		}
+	// Check if user owns this supplement
+	if supplement.UserID != userID {
+		return fmt.Errorf("unauthorized: supplement belongs to another user")
 	}
 
-	return fmt.Errorf("supplement not found")
+	return supplementStore.DeleteSupplement(ctx, supplementID, userID)
 }
 
 // ToggleSupplementStatus toggles the active status of a supplement
 func ToggleSupplementStatus(supplementID string, userID string) error {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
-	if err != nil {
-		return err
-	}
-
-	for i, supplement := range data.Supplements {
-		if supplement.ID == supplementID && supplement.UserID == userID {
-			data.Supplements[i].IsActive = !supplement.IsActive
-			data.Supplements[i].UpdatedAt = time.Now()
-			data.Metadata.UpdatedAt = time.Now()
-
-			return WriteJSON(supplementsFile, data)
-		}
-	}
-
-	return fmt.Errorf("supplement not found or unauthorized")
+	return supplementStore.ToggleSupplementActive(context.Background(), supplementID, userID)
 }
 
 // SearchSupplements searches supplements by various criteria
 func SearchSupplements(userID, query string, filters map[string]interface{}) ([]Supplement, error) {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
+	// Only search user's own supplements
+	supplements, err := GetSupplementsByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -249,12 +171,7 @@ func SearchSupplements(userID, query string, filters map[string]interface{}) ([]
 	var results []Supplement
 	queryLower := strings.ToLower(query)
 
-	for _, supplement := range data.Supplements {
-		// Only search user's own supplements
-		if supplement.UserID != userID {
-			continue
-		}
-
+	for _, supplement := range supplements {
 		// Text search
 		if query != "" {
 			matchesQuery := false
@@ -375,15 +292,14 @@ func matchesSupplementFilters(supplement Supplement, filters map[string]interfac
 
 // GetSupplementsByCategory retrieves supplements by category for a user
 func GetSupplementsByCategory(userID, category string) ([]Supplement, error) {
-	var data SupplementData
-	err := ReadJSON(supplementsFile, &data)
+	supplements, err := GetSupplementsByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	var categorySupplements []Supplement
-	for _, supplement := range data.Supplements {
-		if supplement.UserID == userID && supplement.Category == category {
+	for _, supplement := range supplements {
+		if supplement.Category == category {
 			categorySupplements = append(categorySupplements, supplement)
 		}
 	}
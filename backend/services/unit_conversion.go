@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"nutrition-platform/models"
+)
+
+// Canonical units accepted when logging food
+const (
+	UnitGram       = "g"
+	UnitKilogram   = "kg"
+	UnitOunce      = "oz"
+	UnitPound      = "lb"
+	UnitMilliliter = "ml"
+	UnitLiter      = "l"
+	UnitCup        = "cup"
+	UnitTablespoon = "tbsp"
+	UnitTeaspoon   = "tsp"
+	UnitPiece      = "piece"
+	UnitServing    = "serving"
+)
+
+// gramsPerUnit converts mass units to grams
+var gramsPerUnit = map[string]float64{
+	UnitGram:     1,
+	UnitKilogram: 1000,
+	UnitOunce:    28.349523125,
+	UnitPound:    453.59237,
+}
+
+// millilitersPerUnit converts volume units to milliliters (US customary)
+var millilitersPerUnit = map[string]float64{
+	UnitMilliliter: 1,
+	UnitLiter:      1000,
+	UnitCup:        236.5882365,
+	UnitTablespoon: 14.78676478125,
+	UnitTeaspoon:   4.92892159375,
+}
+
+// unitAliases maps the spellings users type to canonical units
+var unitAliases = map[string]string{
+	"g": UnitGram, "gr": UnitGram, "gram": UnitGram, "grams": UnitGram, "غ": UnitGram, "غرام": UnitGram, "جرام": UnitGram,
+	"kg": UnitKilogram, "kilo": UnitKilogram, "kilogram": UnitKilogram, "kilograms": UnitKilogram, "كجم": UnitKilogram, "كيلو": UnitKilogram,
+	"oz": UnitOunce, "ounce": UnitOunce, "ounces": UnitOunce,
+	"lb": UnitPound, "lbs": UnitPound, "pound": UnitPound, "pounds": UnitPound,
+	"ml": UnitMilliliter, "milliliter": UnitMilliliter, "milliliters": UnitMilliliter, "millilitre": UnitMilliliter, "مل": UnitMilliliter,
+	"l": UnitLiter, "liter": UnitLiter, "liters": UnitLiter, "litre": UnitLiter, "لتر": UnitLiter,
+	"cup": UnitCup, "cups": UnitCup, "كوب": UnitCup,
+	"tbsp": UnitTablespoon, "tablespoon": UnitTablespoon, "tablespoons": UnitTablespoon, "ملعقة كبيرة": UnitTablespoon,
+	"tsp": UnitTeaspoon, "teaspoon": UnitTeaspoon, "teaspoons": UnitTeaspoon, "ملعقة صغيرة": UnitTeaspoon,
+	"piece": UnitPiece, "pieces": UnitPiece, "pc": UnitPiece, "pcs": UnitPiece, "item": UnitPiece, "whole": UnitPiece, "حبة": UnitPiece, "قطعة": UnitPiece,
+	"serving": UnitServing, "servings": UnitServing, "portion": UnitServing, "حصة": UnitServing,
+}
+
+// defaultGramsPerMilliliter is used for volume units when a food has no
+// measured density. It matches water and is close for milk, juices and soups.
+const defaultGramsPerMilliliter = 1.0
+
+// NormalizeUnit returns the canonical spelling of a unit
+func NormalizeUnit(unit string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(unit), ".")))
+	if canonical, ok := unitAliases[key]; ok {
+		return canonical, nil
+	}
+	return "", fmt.Errorf("unsupported unit: %s", unit)
+}
+
+// IsMassUnit reports whether a canonical unit measures weight
+func IsMassUnit(unit string) bool {
+	_, ok := gramsPerUnit[unit]
+	return ok
+}
+
+// IsVolumeUnit reports whether a canonical unit measures volume
+func IsVolumeUnit(unit string) bool {
+	_, ok := millilitersPerUnit[unit]
+	return ok
+}
+
+// ConvertToGrams converts a quantity of food into grams. Per-food portions
+// take priority over generic factors, so a food can define what "1 cup" or
+// "1 piece" weighs. Volumes without a portion use the food's "ml" portion as
+// a density, or water density when none is set.
+func ConvertToGrams(quantity float64, unit string, food *models.Food, portions []models.FoodPortion) (float64, error) {
+	if quantity < 0 {
+		return 0, fmt.Errorf("quantity cannot be negative")
+	}
+
+	canonical, err := NormalizeUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+
+	if grams, ok := portionGrams(canonical, portions); ok {
+		return quantity * grams, nil
+	}
+
+	if factor, ok := gramsPerUnit[canonical]; ok {
+		return quantity * factor, nil
+	}
+
+	if ml, ok := millilitersPerUnit[canonical]; ok {
+		density := defaultGramsPerMilliliter
+		if perMl, ok := portionGrams(UnitMilliliter, portions); ok {
+			density = perMl
+		}
+		return quantity * ml * density, nil
+	}
+
+	if canonical == UnitServing && food != nil {
+		if grams, err := servingGrams(food, portions); err == nil {
+			return quantity * grams, nil
+		}
+	}
+
+	name := "this food"
+	if food != nil {
+		name = food.Name
+	}
+	return 0, fmt.Errorf("no gram weight known for one %s of %s", canonical, name)
+}
+
+// portionGrams finds the gram weight of one unit in a food's portion list
+func portionGrams(unit string, portions []models.FoodPortion) (float64, bool) {
+	for _, p := range portions {
+		canonical, err := NormalizeUnit(p.Unit)
+		if err != nil || canonical != unit || p.Grams <= 0 {
+			continue
+		}
+		return p.Grams, true
+	}
+	return 0, false
+}
+
+// servingGrams derives the weight of one serving from the food's
+// ServingSize text, e.g. "30", "30 g", "1 cup" or "2 tbsp"
+func servingGrams(food *models.Food, portions []models.FoodPortion) (float64, error) {
+	amount, unit, err := parseServingSize(food.ServingSize)
+	if err != nil {
+		return 0, err
+	}
+	if unit == "" {
+		unit = food.ServingUnit
+	}
+	if unit == "" {
+		return 0, fmt.Errorf("serving size of %s has no unit", food.Name)
+	}
+
+	canonical, err := NormalizeUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+	if canonical == UnitServing {
+		return 0, fmt.Errorf("serving size of %s is defined in servings", food.Name)
+	}
+
+	return ConvertToGrams(amount, canonical, food, portions)
+}
+
+// parseServingSize splits a serving size such as "1.5 cups" into its amount
+// and unit text
+func parseServingSize(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ','
+	})
+	if end == -1 {
+		end = len(s)
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(s[:end], ",", "."), 64)
+	if err != nil || amount <= 0 {
+		return 0, "", fmt.Errorf("invalid serving size: %q", s)
+	}
+
+	unit := strings.TrimSpace(s[end:])
+	// Drop descriptions like "1 cup (240ml)"
+	if i := strings.Index(unit, "("); i >= 0 {
+		unit = strings.TrimSpace(unit[:i])
+	}
+
+	return amount, unit, nil
+}
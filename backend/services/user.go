@@ -1,21 +1,34 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
 )
 
 // UserService handles user-related operations
 type UserService struct {
-	db *sql.DB
+	db    *sql.DB
+	users *repositories.UserRepository
 }
 
 // NewUserService creates a new UserService instance
 func NewUserService(db *sql.DB) *UserService {
 	return &UserService{
-		db: db,
+		db:    db,
+		users: repositories.NewUserRepository(database.NewDatabase(db)),
 	}
 }
 
+// GetAccount returns the email, name, role and admin flag of an active user,
+// as access tokens are issued with
+func (s *UserService) GetAccount(ctx context.Context, userID string) (*models.UserAccount, error) {
+	return s.users.GetUserAccount(ctx, userID)
+}
+
 // Stub methods - to be implemented in Priority 2
 func (s *UserService) GetUserByID(userID string) (interface{}, error) {
 	return nil, nil
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/repositories"
+)
+
+// Measurement systems accepted by the units preference
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// preferenceRule describes the default value and validation of a preference
+type preferenceRule struct {
+	defaultValue interface{}
+	validate     func(value interface{}) error
+}
+
+// preferenceRules lists every preference a user may store
+var preferenceRules = map[string]preferenceRule{
+	"language":              {defaultValue: "en", validate: preferenceOneOf("en", "ar")},
+	"timezone":              {defaultValue: "UTC", validate: preferenceTimezone},
+	"units":                 {defaultValue: UnitsMetric, validate: preferenceOneOf(UnitsMetric, UnitsImperial)},
+	"theme":                 {defaultValue: "light", validate: preferenceOneOf("light", "dark", "system")},
+	"dark_mode":             {defaultValue: false, validate: preferenceBool},
+	"notifications_enabled": {defaultValue: true, validate: preferenceBool},
+	"email_notifications":   {defaultValue: true, validate: preferenceBool},
+	"push_notifications":    {defaultValue: true, validate: preferenceBool},
+	"meal_reminders":        {defaultValue: true, validate: preferenceBool},
+	"water_reminders":       {defaultValue: true, validate: preferenceBool},
+	"workout_reminders":     {defaultValue: true, validate: preferenceBool},
+	"weigh_in_reminders":    {defaultValue: true, validate: preferenceBool},
+	"supplement_reminders":  {defaultValue: true, validate: preferenceBool},
+	"medication_reminders":  {defaultValue: true, validate: preferenceBool},
+	// Reminders are held during quiet hours, local "HH:MM" times in the
+	// user's timezone; equal times turn quiet hours off
+	"quiet_hours_start": {defaultValue: "22:00", validate: preferenceClock},
+	"quiet_hours_end":   {defaultValue: "07:00", validate: preferenceClock},
+}
+
+// UserPreferencesService stores and validates per-user preferences
+type UserPreferencesService struct {
+	repo *repositories.UserPreferencesRepository
+}
+
+// NewUserPreferencesService creates a new UserPreferencesService instance
+func NewUserPreferencesService(db *sql.DB) *UserPreferencesService {
+	return &UserPreferencesService{
+		repo: repositories.NewUserPreferencesRepository(database.NewDatabase(db)),
+	}
+}
+
+// DefaultPreferences returns the preferences applied to users who have not changed them
+func DefaultPreferences() map[string]interface{} {
+	defaults := make(map[string]interface{}, len(preferenceRules))
+	for key, rule := range preferenceRules {
+		defaults[key] = rule.defaultValue
+	}
+	return defaults
+}
+
+// GetPreferences returns the user's stored preferences merged over the defaults
+func (s *UserPreferencesService) GetPreferences(ctx context.Context, userID int) (map[string]interface{}, error) {
+	stored, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := DefaultPreferences()
+	for key, raw := range stored {
+		rule, ok := preferenceRules[key]
+		if !ok {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			continue
+		}
+		// Skip values that no longer pass validation, e.g. a removed option
+		if rule.validate(value) != nil {
+			continue
+		}
+		preferences[key] = value
+	}
+
+	return preferences, nil
+}
+
+// ValidatePreferences checks a partial update. A null value is allowed for
+// any known key and resets it to its default.
+func (s *UserPreferencesService) ValidatePreferences(patch map[string]interface{}) error {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rule, ok := preferenceRules[key]
+		if !ok {
+			return fmt.Errorf("unknown preference: %s", key)
+		}
+		if patch[key] == nil {
+			continue
+		}
+		if err := rule.validate(patch[key]); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdatePreferences applies a partial update: keys not present in the patch
+// are left untouched, null values reset a key to its default. The merged
+// preferences are returned.
+func (s *UserPreferencesService) UpdatePreferences(ctx context.Context, userID int, patch map[string]interface{}) (map[string]interface{}, error) {
+	if err := s.ValidatePreferences(patch); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	var reset []string
+	for key, value := range patch {
+		if value == nil {
+			reset = append(reset, key)
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode preference %s: %w", key, err)
+		}
+		values[key] = string(encoded)
+	}
+
+	if err := s.repo.UpdatePreferences(ctx, userID, values, reset); err != nil {
+		return nil, err
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+// DeletePreferences removes everything a user has stored, restoring the defaults
+func (s *UserPreferencesService) DeletePreferences(ctx context.Context, userID int) error {
+	return s.repo.DeletePreferences(ctx, userID)
+}
+
+// GetUnits returns the user's measurement system, falling back to metric
+func (s *UserPreferencesService) GetUnits(ctx context.Context, userID int) string {
+	preferences, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return UnitsMetric
+	}
+	if units, ok := preferences["units"].(string); ok {
+		return units
+	}
+	return UnitsMetric
+}
+
+// GetTimezone returns the user's time zone, falling back to UTC
+func (s *UserPreferencesService) GetTimezone(ctx context.Context, userID int) *time.Location {
+	preferences, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return time.UTC
+	}
+	name, _ := preferences["timezone"].(string)
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func preferenceOneOf(allowed ...string) func(interface{}) error {
+	return func(value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		for _, a := range allowed {
+			if s == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %v", allowed)
+	}
+}
+
+func preferenceBool(value interface{}) error {
+	if _, ok := value.(bool); !ok {
+		return fmt.Errorf("must be a boolean")
+	}
+	return nil
+}
+
+func preferenceTimezone(value interface{}) error {
+	s, ok := value.(string)
+	// LoadLocation also accepts "Local", which would store the server's zone
+	if !ok || s == "" || s == "Local" {
+		return fmt.Errorf("must be an IANA time zone name")
+	}
+	if _, err := time.LoadLocation(s); err != nil {
+		return fmt.Errorf("unknown time zone %q", s)
+	}
+	return nil
+}
+
+func preferenceClock(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("must be a string")
+	}
+	if _, ok := parseClock(s); !ok {
+		return fmt.Errorf("must be a time of day in HH:MM format")
+	}
+	return nil
+}
@@ -0,0 +1,572 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+	"nutrition-platform/security"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// WebhookSecretLength is the number of random bytes in a signing secret
+	WebhookSecretLength = 20
+	// WebhookRetryBaseDelay is the wait before the first retry; each later
+	// retry waits twice as long, up to WebhookRetryMaxDelay
+	WebhookRetryBaseDelay = 30 * time.Second
+	WebhookRetryMaxDelay  = 6 * time.Hour
+
+	webhookBatchSize = 50
+)
+
+// Headers sent with every webhook. The signature is
+// security.SignRequest over POST, the URL path, the timestamp, the body and
+// the content-type header, so receivers can verify it with the request
+// signing middleware and their subscription's secret.
+const (
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Timestamp"
+	WebhookSignatureHeader = "X-Signature"
+)
+
+var (
+	// ErrInvalidWebhookRequest is returned for bad URLs, unknown event types
+	// and other malformed subscription requests
+	ErrInvalidWebhookRequest = errors.New("invalid webhook request")
+	// ErrWebhookDeliveryPending is returned when replaying a delivery that
+	// is still queued
+	ErrWebhookDeliveryPending = errors.New("webhook delivery is still pending")
+	// ErrWebhookAddressBlocked is returned when a webhook URL resolves to a
+	// loopback, private or otherwise internal address
+	ErrWebhookAddressBlocked = errors.New("webhook address is not publicly routable")
+)
+
+// WebhookService manages webhook subscriptions and delivers user activity
+// events to them. Publish queues one delivery per matching subscription;
+// Start sends queued deliveries in the background, retrying failures with
+// exponential backoff until MaxAttempts, after which they are dead-lettered
+// and can be replayed.
+type WebhookService struct {
+	repo         *repositories.WebhookRepository
+	secrets      *secretBox
+	client       *http.Client
+	maxAttempts  int
+	interval     time.Duration
+	allowPrivate bool
+	wake         chan struct{}
+}
+
+// NewWebhookService creates a new WebhookService instance
+func NewWebhookService(db *sql.DB, webhookConfig config.WebhookConfig) *WebhookService {
+	maxAttempts := webhookConfig.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	timeout := webhookConfig.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	interval := webhookConfig.PollInterval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	return &WebhookService{
+		repo:         repositories.NewWebhookRepository(database.NewDatabase(db)),
+		secrets:      newSecretBox(webhookConfig.EncryptionKey),
+		client:       newWebhookClient(timeout, webhookConfig.AllowPrivateNetworks),
+		maxAttempts:  maxAttempts,
+		interval:     interval,
+		allowPrivate: webhookConfig.AllowPrivateNetworks,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// newWebhookClient returns the client deliveries are sent with. Unless
+// allowPrivate is set, every address it connects to is checked after DNS
+// resolution, so hostnames pointing at internal services, including ones
+// rebound after the URL was validated, are refused. Redirects are never
+// followed; the 3xx response fails the attempt.
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection on our behalf, past the check above
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// WebhookRetryDelay returns how long to wait after the given number of
+// failed attempts
+func WebhookRetryDelay(attempts int) time.Duration {
+	delay := WebhookRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= WebhookRetryMaxDelay {
+			return WebhookRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// CreateSubscription creates a subscription for userID, tied to apiKeyID
+// when it was created with an API key. The signing secret is only returned
+// here.
+func (s *WebhookService) CreateSubscription(ctx context.Context, userID, apiKeyID string, req models.CreateWebhookRequest) (*models.WebhookSubscriptionWithSecret, error) {
+	webhookURL, err := s.validateWebhookURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := security.GenerateWebhookSecret(WebhookSecretLength)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := s.secrets.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	sub := &models.WebhookSubscription{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		APIKeyID:         apiKeyID,
+		URL:              webhookURL,
+		Events:           events,
+		Description:      strings.TrimSpace(req.Description),
+		SecretCiphertext: ciphertext,
+		Active:           true,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookSubscriptionWithSecret{WebhookSubscription: sub, Secret: secret}, nil
+}
+
+// ListSubscriptions returns a user's subscriptions, or only the API key's
+// when apiKeyID is set
+func (s *WebhookService) ListSubscriptions(ctx context.Context, userID, apiKeyID string) ([]*models.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx, userID, apiKeyID)
+}
+
+// GetSubscription retrieves a subscription. With apiKeyID set, only that
+// key's subscriptions are visible.
+func (s *WebhookService) GetSubscription(ctx context.Context, userID, apiKeyID, id string) (*models.WebhookSubscription, error) {
+	sub, err := s.repo.GetSubscription(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKeyID != "" && sub.APIKeyID != apiKeyID {
+		return nil, fmt.Errorf("webhook subscription not found")
+	}
+	return sub, nil
+}
+
+// UpdateSubscription changes a subscription's URL, events, description or
+// active flag
+func (s *WebhookService) UpdateSubscription(ctx context.Context, userID, apiKeyID, id string, req models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	sub, err := s.GetSubscription(ctx, userID, apiKeyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if sub.URL, err = s.validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+	}
+	if req.Events != nil {
+		if sub.Events, err = normalizeWebhookEvents(req.Events); err != nil {
+			return nil, err
+		}
+	}
+	if req.Description != nil {
+		sub.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+	sub.UpdatedAt = time.Now().UTC()
+
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// DeleteSubscription deletes a subscription and its delivery log
+func (s *WebhookService) DeleteSubscription(ctx context.Context, userID, apiKeyID, id string) error {
+	if _, err := s.GetSubscription(ctx, userID, apiKeyID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(ctx, id, userID)
+}
+
+// ListDeliveries returns a subscription's delivery log, newest first,
+// optionally filtered by status
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, apiKeyID, subscriptionID, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	if status != "" && status != models.WebhookDeliveryPending &&
+		status != models.WebhookDeliverySucceeded && status != models.WebhookDeliveryDead {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidWebhookRequest, status)
+	}
+	if _, err := s.GetSubscription(ctx, userID, apiKeyID, subscriptionID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListDeliveries(ctx, subscriptionID, status, limit, offset)
+}
+
+// ReplayDelivery queues a delivered or dead-lettered delivery to be sent
+// again with its attempts reset
+func (s *WebhookService) ReplayDelivery(ctx context.Context, userID, apiKeyID, subscriptionID, deliveryID string) (*models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(ctx, userID, apiKeyID, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	requeued, err := s.repo.Requeue(ctx, deliveryID, subscriptionID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := s.repo.GetDelivery(ctx, deliveryID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !requeued {
+		return nil, ErrWebhookDeliveryPending
+	}
+
+	s.notify()
+	return delivery, nil
+}
+
+// ReplayDeadLetters queues every dead-lettered delivery of a subscription
+// to be sent again and returns how many were queued
+func (s *WebhookService) ReplayDeadLetters(ctx context.Context, userID, apiKeyID, subscriptionID string) (int64, error) {
+	if _, err := s.GetSubscription(ctx, userID, apiKeyID, subscriptionID); err != nil {
+		return 0, err
+	}
+
+	count, err := s.repo.RequeueDead(ctx, subscriptionID, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		s.notify()
+	}
+	return count, nil
+}
+
+// Publish queues an event for every active subscription of userID that
+// receives eventType. data becomes the payload's data field.
+func (s *WebhookService) Publish(ctx context.Context, userID, eventType string, data interface{}) error {
+	if !models.IsValidWebhookEvent(eventType) {
+		return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhookRequest, eventType)
+	}
+
+	now := time.Now().UTC()
+	subs, err := s.repo.ListDeliverableSubscriptions(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+
+	event := models.WebhookEvent{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: now,
+		Data:       data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	deliveries := []*models.WebhookDelivery{}
+	for _, sub := range subs {
+		if !sub.Subscribes(eventType) {
+			continue
+		}
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  &now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := s.repo.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// Start sends due deliveries every PollInterval, and as soon as events are
+// published, until ctx is done
+func (s *WebhookService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Webhook delivery failed: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// DeliverDue sends the deliveries that are due and returns how many were
+// attempted
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		now := time.Now().UTC()
+		due, err := s.repo.ListDueDeliveries(ctx, now, webhookBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+
+		for _, d := range due {
+			// Hold the delivery for longer than an attempt can take so
+			// other instances skip it
+			claimed, err := s.repo.ClaimDelivery(ctx, d.ID, now, now.Add(s.client.Timeout+time.Minute))
+			if err != nil {
+				return attempted, err
+			}
+			if !claimed {
+				continue
+			}
+			if err := s.attempt(ctx, d); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+
+		if len(due) < webhookBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome
+func (s *WebhookService) attempt(ctx context.Context, d *models.DueWebhookDelivery) error {
+	statusCode, sendErr := s.send(ctx, d)
+
+	now := time.Now().UTC()
+	delivery := d.WebhookDelivery
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.UpdatedAt = now
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= s.maxAttempts:
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+		delivery.LastError = sendErr.Error()
+	default:
+		next := now.Add(WebhookRetryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = sendErr.Error()
+	}
+
+	return s.repo.RecordAttempt(ctx, &delivery)
+}
+
+// send posts the payload and returns the response status code
+func (s *WebhookService) send(ctx context.Context, d *models.DueWebhookDelivery) (int, error) {
+	secret, err := s.secrets.open(d.SecretCiphertext)
+	if err != nil {
+		return 0, err
+	}
+	target, err := url.Parse(d.URL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	path := target.Path
+	if path == "" {
+		path = "/"
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := security.SignRequest(secret, http.MethodPost, path, timestamp, string(d.Payload),
+		map[string]string{"content-type": "application/json"})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "NutritionPlatform-Webhooks/1.0")
+	req.Header.Set(WebhookIDHeader, d.ID)
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, signature)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// validateWebhookURL requires an absolute https URL. Hosts given as IP
+// addresses must be public; hostnames are checked when delivering.
+func (s *WebhookService) validateWebhookURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	target, err := url.Parse(raw)
+	if s.allowPrivate {
+		if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+			return "", fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhookRequest)
+		}
+		return raw, nil
+	}
+
+	if err != nil || target.Scheme != "https" || target.Hostname() == "" {
+		return "", fmt.Errorf("%w: url must be an absolute https URL", ErrInvalidWebhookRequest)
+	}
+	host := target.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+		return "", fmt.Errorf("%w: url must not point at a private or loopback address", ErrInvalidWebhookRequest)
+	}
+	return raw, nil
+}
+
+// normalizeWebhookEvents validates and de-duplicates event types, keeping
+// their order
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	normalized := []string{}
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !models.IsValidWebhookEvent(event) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhookRequest, event)
+		}
+		if !containsString(normalized, event) {
+			normalized = append(normalized, event)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("%w: at least one event type is required", ErrInvalidWebhookRequest)
+	}
+	return normalized, nil
+}
+
+var (
+	defaultWebhooksMu sync.RWMutex
+	defaultWebhooks   *WebhookService
+)
+
+// SetDefaultWebhookService sets the service PublishWebhookEvent queues
+// events with
+func SetDefaultWebhookService(service *WebhookService) {
+	defaultWebhooksMu.Lock()
+	defer defaultWebhooksMu.Unlock()
+	defaultWebhooks = service
+}
+
+// PublishWebhookEvent queues an event with the default webhook service.
+// Failures are logged rather than returned so that recording the activity
+// never fails because of webhooks. It does nothing until
+// SetDefaultWebhookService is called.
+func PublishWebhookEvent(ctx context.Context, userID, eventType string, data interface{}) {
+	defaultWebhooksMu.RLock()
+	service := defaultWebhooks
+	defaultWebhooksMu.RUnlock()
+	if service == nil {
+		return
+	}
+
+	if err := service.Publish(ctx, userID, eventType, data); err != nil {
+		log.Printf("Failed to publish %s webhook event: %v", eventType, err)
+	}
+}
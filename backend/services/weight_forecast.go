@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+)
+
+const (
+	// DefaultForecastWindowDays is how far back a forecast looks unless
+	// asked otherwise
+	DefaultForecastWindowDays = 90
+	minForecastWindowDays     = 14
+	maxForecastWindowDays     = 365
+
+	// Least data for a trend worth projecting
+	minForecastMeasurements = 4
+	minForecastSpanDays     = 7
+	// Fewer weigh-ins than this leave a wide interval
+	sparseForecastMeasurements = 8
+
+	// The most recent stretch is checked for a plateau
+	plateauWindowDays      = 14
+	minPlateauMeasurements = 4
+	minPlateauSpanDays     = 7
+	// A stretch with no significant trend and a weekly change below this is
+	// a plateau
+	plateauWeeklyChange = 0.2
+
+	// Goals further out than this are not given a date
+	maxForecastHorizonDays = 730
+	// How far the trend is projected when there is no goal date to stop at
+	defaultProjectionWeeks = 12
+	// A goal this close to the trend weight counts as reached
+	goalReachedTolerance = 0.1
+	// Losing more than this share of body weight a week risks muscle loss
+	maxWeeklyLossShare = 0.01
+)
+
+// ErrInsufficientForecastData is returned when there are too few weigh-ins
+// to fit a trend
+var ErrInsufficientForecastData = errors.New("not enough weigh-ins to forecast weight")
+
+// ErrInvalidForecastRequest is returned for out of range windows and
+// targets
+var ErrInvalidForecastRequest = errors.New("invalid weight forecast request")
+
+// WeightForecastService projects a user's weight trend and estimates when
+// they will reach a target weight. The trend is a Theil-Sen fit, the median
+// of the slopes between every pair of weigh-ins, so a single water-heavy
+// morning or mistyped entry barely moves it. Its 95% interval comes from
+// Kendall's rank statistic and bounds the goal date: the steeper end gives
+// the earliest date and the shallower end the latest. The last two weeks
+// are fitted on their own to spot a plateau the longer trend still hides.
+type WeightForecastService struct {
+	measurements *repositories.BodyMeasurementRepository
+}
+
+// NewWeightForecastService creates a new WeightForecastService instance
+func NewWeightForecastService(db *sql.DB) *WeightForecastService {
+	return &WeightForecastService{
+		measurements: repositories.NewBodyMeasurementRepository(database.NewDatabase(db)),
+	}
+}
+
+// Forecast fits the user's weigh-ins over the last days days, 90 when zero,
+// and projects the trend. With a target weight it also estimates when the
+// target is reached.
+func (s *WeightForecastService) Forecast(ctx context.Context, userID int64, days int, targetWeight *float64) (*models.WeightForecast, error) {
+	if days == 0 {
+		days = DefaultForecastWindowDays
+	}
+	if days < minForecastWindowDays || days > maxForecastWindowDays {
+		return nil, fmt.Errorf("%w: days must be between %d and %d", ErrInvalidForecastRequest, minForecastWindowDays, maxForecastWindowDays)
+	}
+	if targetWeight != nil && *targetWeight <= 0 {
+		return nil, fmt.Errorf("%w: target weight must be positive", ErrInvalidForecastRequest)
+	}
+
+	points, err := s.measurements.GetWeightTrend(ctx, userID, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weight trend: %w", err)
+	}
+	if len(points) < minForecastMeasurements {
+		return nil, ErrInsufficientForecastData
+	}
+	first, last := points[0], points[len(points)-1]
+	if last.Date.Sub(first.Date).Hours()/24 < minForecastSpanDays {
+		return nil, ErrInsufficientForecastData
+	}
+
+	fit, ok := fitTrend(points)
+	if !ok {
+		return nil, ErrInsufficientForecastData
+	}
+
+	trendWeight := fit.at(last.Date)
+	forecast := &models.WeightForecast{
+		UserID:           userID,
+		Days:             days,
+		Measurements:     len(points),
+		FirstDate:        first.Date,
+		LastDate:         last.Date,
+		LatestWeight:     last.Value,
+		TrendWeight:      roundTo(trendWeight, 1),
+		WeeklyChange:     roundTo(fit.slope*7, 2),
+		WeeklyChangeLow:  roundTo(fit.low*7, 2),
+		WeeklyChangeHigh: roundTo(fit.high*7, 2),
+	}
+
+	var remaining float64
+	goalOpen := false
+	if targetWeight != nil {
+		remaining = *targetWeight - trendWeight
+		goalOpen = math.Abs(remaining) > goalReachedTolerance
+	}
+	// Holding steady is only a plateau when there is somewhere to go, either
+	// a goal still open or a trend the user was clearly on
+	if goalOpen || !fit.includesZero() {
+		forecast.Plateau = detectPlateau(points)
+	}
+
+	recommendations := []string{}
+	risks := []string{}
+	if len(points) < sparseForecastMeasurements {
+		risks = append(risks, fmt.Sprintf("Only %d weigh-ins in the last %d days; weighing in a few times a week narrows the forecast", len(points), days))
+	}
+	if fit.slope < 0 && -fit.slope*7 > trendWeight*maxWeeklyLossShare {
+		risks = append(risks, "Losing more than 1% of body weight a week risks losing muscle as well as fat")
+	}
+	if forecast.Plateau != nil {
+		recommendations = append(recommendations, fmt.Sprintf("Weight has held steady for %d days; compare your intake with your estimated energy expenditure", forecast.Plateau.Days))
+	}
+
+	var projectTo time.Time
+	forecast.Predictions.Confidence = roundTo(fit.share(fit.slope), 2)
+	if targetWeight != nil {
+		goal := &models.WeightGoalPrediction{
+			CurrentWeight: forecast.TrendWeight,
+			TargetWeight:  *targetWeight,
+		}
+		if !goalOpen {
+			reached := last.Date
+			goal.TargetDate = &reached
+			goal.OnPace = true
+			goal.Confidence = 1
+		} else {
+			goal.Confidence = roundTo(fit.share(remaining), 2)
+			steeper, shallower := fit.high, fit.low
+			if remaining < 0 {
+				steeper, shallower = fit.low, fit.high
+			}
+			goal.TargetDate = reachDate(last.Date, remaining, fit.slope)
+			goal.TargetDateEarliest = reachDate(last.Date, remaining, steeper)
+			goal.TargetDateLatest = reachDate(last.Date, remaining, shallower)
+			goal.OnPace = goal.TargetDate != nil && forecast.Plateau == nil
+
+			switch {
+			case fit.slope*remaining < 0 && !fit.includesZero():
+				risks = append(risks, "The trend is moving away from the target weight")
+			case goal.TargetDate == nil:
+				recommendations = append(recommendations, "At the current trend the target is more than two years away; a larger calorie adjustment would bring it closer")
+			case forecast.Plateau != nil:
+				risks = append(risks, "The goal date assumes the earlier pace resumes after the plateau")
+			case goal.TargetDateLatest == nil:
+				risks = append(risks, "The slower end of the trend does not reach the target, so the date could slip considerably")
+			}
+			if goal.TargetDate != nil {
+				projectTo = *goal.TargetDate
+			}
+		}
+		forecast.Predictions.TargetDate = goal.TargetDate
+		forecast.Predictions.Confidence = goal.Confidence
+		forecast.Goal = goal
+	}
+	if projectTo.IsZero() {
+		projectTo = last.Date.AddDate(0, 0, defaultProjectionWeeks*7)
+	}
+	forecast.Predictions.WeightPrediction = fit.project(last.Date, projectTo)
+	forecast.Predictions.Recommendations = recommendations
+	forecast.Predictions.RiskFactors = risks
+	if forecast.Goal != nil {
+		forecast.Goal.PredictedWeight = forecast.Predictions.WeightPrediction
+		forecast.Goal.Recommendations = recommendations
+	}
+	forecast.Summary = forecastSummary(forecast, fit)
+
+	return forecast, nil
+}
+
+// trendFit is a Theil-Sen line through weigh-ins, with slopes in weight per
+// day since origin
+type trendFit struct {
+	origin    time.Time
+	intercept float64
+	slope     float64
+	low       float64
+	high      float64
+	slopes    []float64
+}
+
+func fitTrend(points []*models.WeightTrendPoint) (trendFit, bool) {
+	fit := trendFit{origin: points[0].Date}
+	x := make([]float64, len(points))
+	for i, p := range points {
+		x[i] = p.Date.Sub(fit.origin).Hours() / 24
+	}
+
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			// Two weigh-ins at the same moment say nothing about the slope
+			if dx := x[j] - x[i]; dx > 1e-6 {
+				fit.slopes = append(fit.slopes, (points[j].Value-points[i].Value)/dx)
+			}
+		}
+	}
+	if len(fit.slopes) == 0 {
+		return fit, false
+	}
+	sort.Float64s(fit.slopes)
+	fit.slope = median(fit.slopes)
+
+	residuals := make([]float64, len(points))
+	for i, p := range points {
+		residuals[i] = p.Value - fit.slope*x[i]
+	}
+	sort.Float64s(residuals)
+	fit.intercept = median(residuals)
+
+	// The interval takes the slopes ranked C/2 either side of the median,
+	// C being 1.96 standard deviations of Kendall's S
+	n := float64(len(points))
+	c := 1.96 * math.Sqrt(n*(n-1)*(2*n+5)/18)
+	count := float64(len(fit.slopes))
+	fit.low = fit.slopes[clampIndex(int(math.Round((count-c)/2))-1, len(fit.slopes))]
+	fit.high = fit.slopes[clampIndex(int(math.Round((count+c)/2)), len(fit.slopes))]
+
+	return fit, true
+}
+
+func (f trendFit) at(t time.Time) float64 {
+	return f.intercept + f.slope*t.Sub(f.origin).Hours()/24
+}
+
+func (f trendFit) includesZero() bool {
+	return f.low <= 0 && f.high >= 0
+}
+
+// share is the fraction of pairwise slopes heading the way direction
+// points, how sure the data is of moving that way
+func (f trendFit) share(direction float64) float64 {
+	if direction == 0 {
+		return 0
+	}
+	agree := 0
+	for _, slope := range f.slopes {
+		if slope*direction > 0 {
+			agree++
+		}
+	}
+	return float64(agree) / float64(len(f.slopes))
+}
+
+// project returns the trend weekly from from, through to
+func (f trendFit) project(from, to time.Time) []models.WeightTrendPoint {
+	var points []models.WeightTrendPoint
+	for t := from; ; t = t.AddDate(0, 0, 7) {
+		if t.After(to) {
+			t = to
+		}
+		weight := roundTo(f.at(t), 1)
+		points = append(points, models.WeightTrendPoint{Date: t, Weight: weight, Value: weight})
+		if !t.Before(to) {
+			return points
+		}
+	}
+}
+
+// detectPlateau fits the last two weeks on their own and reports a plateau
+// when they show no significant change
+func detectPlateau(points []*models.WeightTrendPoint) *models.WeightPlateau {
+	last := points[len(points)-1].Date
+	start := sort.Search(len(points), func(i int) bool {
+		return !points[i].Date.Before(last.AddDate(0, 0, -plateauWindowDays))
+	})
+	recent := points[start:]
+	if len(recent) < minPlateauMeasurements || last.Sub(recent[0].Date).Hours()/24 < minPlateauSpanDays {
+		return nil
+	}
+
+	fit, ok := fitTrend(recent)
+	if !ok || !fit.includesZero() || math.Abs(fit.slope*7) >= plateauWeeklyChange {
+		return nil
+	}
+	return &models.WeightPlateau{
+		Since:        recent[0].Date,
+		Days:         int(math.Round(last.Sub(recent[0].Date).Hours() / 24)),
+		WeeklyChange: roundTo(fit.slope*7, 2),
+	}
+}
+
+// reachDate is when a trend of slope per day covers remaining, or nil when
+// it heads the other way or takes longer than the forecast horizon
+func reachDate(from time.Time, remaining, slope float64) *time.Time {
+	if slope*remaining <= 0 {
+		return nil
+	}
+	days := remaining / slope
+	if days > maxForecastHorizonDays {
+		return nil
+	}
+	date := from.Add(time.Duration(days * 24 * float64(time.Hour)))
+	return &date
+}
+
+func forecastSummary(forecast *models.WeightForecast, fit trendFit) string {
+	goal := forecast.Goal
+	switch {
+	case goal != nil && math.Abs(goal.TargetWeight-goal.CurrentWeight) <= goalReachedTolerance:
+		return fmt.Sprintf("Reached the target of %.1f", goal.TargetWeight)
+	case forecast.Plateau != nil:
+		return fmt.Sprintf("Holding at %.1f for the last %d days", forecast.TrendWeight, forecast.Plateau.Days)
+	case goal != nil && goal.TargetDate != nil:
+		return fmt.Sprintf("On pace for %.1f by %s", goal.TargetWeight, goal.TargetDate.Format("Jan 2, 2006"))
+	case goal != nil:
+		return fmt.Sprintf("Not on pace to reach %.1f at the current trend", goal.TargetWeight)
+	case fit.includesZero():
+		return fmt.Sprintf("Holding steady around %.1f", forecast.TrendWeight)
+	case fit.slope < 0:
+		return fmt.Sprintf("Trending down %.2f a week", -forecast.WeeklyChange)
+	default:
+		return fmt.Sprintf("Trending up %.2f a week", forecast.WeeklyChange)
+	}
+}
+
+// median of already sorted values
+func median(sorted []float64) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
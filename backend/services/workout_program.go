@@ -0,0 +1,788 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrition-platform/database"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLoadIncrement is the load, in kilograms, added when an exercise
+	// moves up and no increment was given at assignment
+	DefaultLoadIncrement = 2.5
+
+	defaultProgramWeeks = 8
+	defaultProgramSets  = 3
+
+	// Every deloadWeekInterval-th week of an assignment is a deload week:
+	// fewer sets at a lighter load and no progression
+	deloadWeekInterval = 4
+	deloadSetFactor    = 0.6
+	deloadLoadFactor   = 0.9
+
+	// Missing the target reps this many sessions in a row deloads the
+	// exercise
+	stallLimit = 2
+
+	// Bodyweight exercises progress by adding sets up to this many
+	maxProgressionSets = 5
+
+	// Load kept after an injury; severe injuries cut it harder
+	injuryLoadFactor       = 0.8
+	severeInjuryLoadFactor = 0.5
+	severeInjurySeverity   = 7
+)
+
+// ErrInvalidProgramAssignment is returned for malformed assignment requests
+var ErrInvalidProgramAssignment = errors.New("invalid program assignment")
+
+// ErrProgramAlreadyAssigned is returned when the user is already following
+// the program
+var ErrProgramAlreadyAssigned = errors.New("program is already assigned")
+
+// defaultTrainingDays spreads a program's days per week over the week
+// (0 = Sunday)
+var defaultTrainingDays = map[int][]int{
+	1: {1},
+	2: {1, 4},
+	3: {1, 3, 5},
+	4: {1, 2, 4, 5},
+	5: {1, 2, 3, 4, 5},
+	6: {1, 2, 3, 4, 5, 6},
+	7: {0, 1, 2, 3, 4, 5, 6},
+}
+
+var programNumberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// WorkoutProgramService runs workout programs for users. Assigning a program
+// lays its sessions out over the user's training days for a number of weeks
+// and takes a starting prescription for every exercise from the program.
+// Each logged session then moves those prescriptions on:
+//
+//   - double progression: reps climb to the top of the rep range, then the
+//     load goes up and reps start again from the bottom (bodyweight
+//     exercises add a set instead)
+//   - RPE: a hard session (RPE 9+) holds the prescription, an easy one
+//     (RPE 6 or less) progresses twice as fast, and missing reps at RPE 10
+//     takes the load down a step
+//   - deload: every fourth week is lighter, and missing reps two sessions
+//     running drops the load by 10%
+//   - injury regression: an injury reported against an exercise, or a body
+//     part it trains, swaps it for its next easier variant and cuts the load
+//
+// Every change is recorded as a ProgressionAdjustment with the rule that
+// made it.
+type WorkoutProgramService struct {
+	repo *repositories.WorkoutProgramRepository
+}
+
+// NewWorkoutProgramService creates a new WorkoutProgramService instance
+func NewWorkoutProgramService(db *sql.DB) *WorkoutProgramService {
+	return &WorkoutProgramService{
+		repo: repositories.NewWorkoutProgramRepository(database.NewDatabase(db)),
+	}
+}
+
+// AssignProgram puts the user on a program and schedules its sessions
+func (s *WorkoutProgramService) AssignProgram(ctx context.Context, userID string, req models.AssignProgramRequest) (*models.ProgramAssignmentDetail, error) {
+	if strings.TrimSpace(req.ProgramID) == "" {
+		return nil, fmt.Errorf("%w: program_id is required", ErrInvalidProgramAssignment)
+	}
+	if req.LoadIncrement < 0 {
+		return nil, fmt.Errorf("%w: load_increment must not be negative", ErrInvalidProgramAssignment)
+	}
+
+	program, err := s.repo.GetProgram(ctx, req.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := s.repo.ListProgramSessions(ctx, program.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("%w: program has no sessions", ErrInvalidProgramAssignment)
+	}
+
+	weeks := req.Weeks
+	if weeks == 0 && program.DurationWeeks != nil {
+		weeks = *program.DurationWeeks
+	}
+	if weeks == 0 {
+		weeks = defaultProgramWeeks
+	}
+	if weeks < 1 || weeks > 52 {
+		return nil, fmt.Errorf("%w: weeks must be between 1 and 52", ErrInvalidProgramAssignment)
+	}
+
+	days, err := normalizeTrainingDays(req.TrainingDays)
+	if err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		perWeek := len(templates)
+		if program.DaysPerWeek != nil && *program.DaysPerWeek > 0 {
+			perWeek = *program.DaysPerWeek
+		}
+		if perWeek > 7 {
+			perWeek = 7
+		}
+		days = defaultTrainingDays[perWeek]
+	}
+
+	if _, err := s.repo.GetActiveAssignment(ctx, userID, program.ID); err == nil {
+		return nil, ErrProgramAlreadyAssigned
+	} else if err.Error() != "program assignment not found" {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	start := now
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	increment := req.LoadIncrement
+	if increment == 0 {
+		increment = DefaultLoadIncrement
+	}
+
+	assignment := &models.ProgramAssignment{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		ProgramID:    program.ID,
+		StartDate:    start,
+		Weeks:        weeks,
+		TrainingDays: days,
+		Status:       models.ProgramAssignmentActive,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	targets := programTargets(assignment.ID, templates, req.StartingLoads, increment, now)
+	sessions := scheduleProgramSessions(assignment, templates)
+
+	if err := s.repo.CreateAssignment(ctx, assignment, targets, sessions); err != nil {
+		return nil, err
+	}
+
+	planProgramSessions(sessions, templates, targets)
+	return &models.ProgramAssignmentDetail{
+		ProgramAssignment: assignment,
+		Program:           program,
+		Targets:           targets,
+		Sessions:          sessions,
+	}, nil
+}
+
+// ListAssignments returns the programs the user has been assigned
+func (s *WorkoutProgramService) ListAssignments(ctx context.Context, userID string) ([]*models.ProgramAssignment, error) {
+	return s.repo.ListAssignments(ctx, userID)
+}
+
+// GetAssignment returns an assignment with its current targets and its
+// schedule. Sessions still to come list the exercises as they should be
+// done now.
+func (s *WorkoutProgramService) GetAssignment(ctx context.Context, userID, id string) (*models.ProgramAssignmentDetail, error) {
+	assignment, err := s.repo.GetAssignment(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	program, err := s.repo.GetProgram(ctx, assignment.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := s.repo.ListProgramSessions(ctx, assignment.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := s.repo.ListTargets(ctx, assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.repo.ListScheduledSessions(ctx, assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	planProgramSessions(sessions, templates, targets)
+	return &models.ProgramAssignmentDetail{
+		ProgramAssignment: assignment,
+		Program:           program,
+		Targets:           targets,
+		Sessions:          sessions,
+	}, nil
+}
+
+// CancelAssignment stops an active assignment
+func (s *WorkoutProgramService) CancelAssignment(ctx context.Context, userID, id string) error {
+	assignment, err := s.repo.GetAssignment(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if assignment.Status != models.ProgramAssignmentActive {
+		return fmt.Errorf("%w: assignment is %s", ErrInvalidProgramAssignment, assignment.Status)
+	}
+	return s.repo.UpdateAssignmentStatus(ctx, id, userID, models.ProgramAssignmentCancelled)
+}
+
+// ListAdjustments returns a page of the changes made to an assignment's
+// targets, newest first
+func (s *WorkoutProgramService) ListAdjustments(ctx context.Context, userID, id string, limit, offset int) ([]*models.ProgressionAdjustment, error) {
+	if _, err := s.repo.GetAssignment(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListAdjustments(ctx, id, limit, offset)
+}
+
+// RecordSession applies a logged workout to the user's active assignment of
+// its program: the next scheduled session is marked done (or skipped) and
+// the exercise targets are adjusted. Sessions outside a program, or already
+// recorded, are ignored.
+func (s *WorkoutProgramService) RecordSession(ctx context.Context, session *models.UserWorkoutSession) error {
+	if session.WorkoutProgramID == nil || *session.WorkoutProgramID == "" {
+		return nil
+	}
+	switch session.Status {
+	case "completed", "partial", "skipped":
+	default:
+		return nil
+	}
+
+	assignment, err := s.repo.GetActiveAssignment(ctx, session.UserID, *session.WorkoutProgramID)
+	if err != nil {
+		if err.Error() == "program assignment not found" {
+			return nil
+		}
+		return err
+	}
+	sessions, err := s.repo.ListScheduledSessions(ctx, assignment.ID)
+	if err != nil {
+		return err
+	}
+
+	var scheduled *models.ProgramScheduledSession
+	remaining := 0
+	for _, candidate := range sessions {
+		if session.ID != "" && candidate.UserWorkoutSessionID == session.ID {
+			return nil
+		}
+		if candidate.Status != models.ProgramSessionScheduled {
+			continue
+		}
+		remaining++
+		if scheduled == nil && (session.WorkoutSessionID == nil || *session.WorkoutSessionID == candidate.WorkoutSessionID) {
+			scheduled = candidate
+		}
+	}
+
+	now := time.Now().UTC()
+	var changed []*models.ProgramExerciseTarget
+	var adjustments []*models.ProgressionAdjustment
+
+	if session.Status == "skipped" {
+		if scheduled == nil {
+			return nil
+		}
+		scheduled.Status = models.ProgramSessionSkipped
+	} else {
+		targets, err := s.repo.ListTargets(ctx, assignment.ID)
+		if err != nil {
+			return err
+		}
+		deload := scheduled != nil && scheduled.Deload
+		changed, adjustments = progressTargets(targets, session, deload, now)
+		if scheduled != nil {
+			scheduled.Status = models.ProgramSessionCompleted
+		}
+	}
+
+	if scheduled != nil {
+		scheduled.UserWorkoutSessionID = session.ID
+		scheduled.CompletedAt = &now
+		remaining--
+	}
+	for _, adjustment := range adjustments {
+		adjustment.AssignmentID = assignment.ID
+		adjustment.UserWorkoutSessionID = session.ID
+	}
+
+	if err := s.repo.SaveProgress(ctx, scheduled, changed, adjustments); err != nil {
+		return err
+	}
+	if scheduled != nil && remaining == 0 {
+		return s.repo.UpdateAssignmentStatus(ctx, assignment.ID, assignment.UserID, models.ProgramAssignmentCompleted)
+	}
+	return nil
+}
+
+// progressTargets adjusts the targets for a logged session and returns the
+// targets that changed together with the adjustments explaining them.
+// Injuries are handled before progression, and injured exercises do not
+// progress in the same session.
+func progressTargets(targets []*models.ProgramExerciseTarget, session *models.UserWorkoutSession, deload bool, now time.Time) ([]*models.ProgramExerciseTarget, []*models.ProgressionAdjustment) {
+	var changed []*models.ProgramExerciseTarget
+	var adjustments []*models.ProgressionAdjustment
+	touched := map[string]bool{}
+
+	record := func(target *models.ProgramExerciseTarget, rule, description string, before models.ProgramExerciseTarget) {
+		adjustments = append(adjustments, &models.ProgressionAdjustment{
+			ID:          uuid.New().String(),
+			ExerciseID:  target.ExerciseID,
+			Rule:        rule,
+			Description: description,
+			SetsBefore:  before.Sets,
+			SetsAfter:   target.Sets,
+			RepsBefore:  before.TargetReps,
+			RepsAfter:   target.TargetReps,
+			LoadBefore:  before.Load,
+			LoadAfter:   target.Load,
+			CreatedAt:   now,
+		})
+	}
+	touch := func(target *models.ProgramExerciseTarget) {
+		if !touched[target.ExerciseID] {
+			touched[target.ExerciseID] = true
+			target.UpdatedAt = now
+			changed = append(changed, target)
+		}
+	}
+
+	injured := map[string]bool{}
+	for _, target := range targets {
+		injury := worstInjury(target, session.InjuriesReported)
+		if injury == nil {
+			continue
+		}
+		injured[target.ExerciseID] = true
+		before := *target
+		regressTarget(target, injury.Severity)
+		touch(target)
+
+		description := fmt.Sprintf("Reported %s injury (severity %d)", strings.ToLower(injury.BodyPart), injury.Severity)
+		if target.Variant != before.Variant {
+			description += ": switched to " + target.Variant
+		}
+		record(target, models.ProgressionRuleInjuryRegression, description, before)
+	}
+
+	if deload {
+		return changed, adjustments
+	}
+
+	byID := make(map[string]*models.ProgramExerciseTarget, len(targets))
+	for _, target := range targets {
+		byID[target.ExerciseID] = target
+	}
+
+	for _, completed := range session.ExercisesCompleted {
+		target := byID[completed.ExerciseID]
+		if target == nil || injured[target.ExerciseID] {
+			continue
+		}
+		before := *target
+		rule, description := progressTarget(target, completed, exerciseRPE(completed, session))
+		if target.FailedSessions != before.FailedSessions || rule != "" {
+			touch(target)
+		}
+		if rule != "" {
+			record(target, rule, description, before)
+		}
+	}
+
+	return changed, adjustments
+}
+
+// progressTarget applies double progression, RPE and stall deloads to one
+// exercise and returns the rule that changed it, if any
+func progressTarget(target *models.ProgramExerciseTarget, completed models.CompletedExercise, rpe int) (string, string) {
+	reps := completedReps(completed)
+	sets := len(reps)
+	if completed.SetsCompleted > sets {
+		sets = completed.SetsCompleted
+	}
+
+	missed := sets < target.Sets
+	topOfRange := sets >= target.Sets
+	for _, r := range reps {
+		if r < target.TargetReps {
+			missed = true
+		}
+		if r < target.RepMax {
+			topOfRange = false
+		}
+	}
+	if len(reps) == 0 {
+		missed = true
+		topOfRange = false
+	}
+
+	if missed {
+		target.FailedSessions++
+		if rpe >= 10 && target.Load > 0 {
+			target.Load = math.Max(0, roundLoad(target.Load-target.LoadIncrement))
+			target.FailedSessions = 0
+			return models.ProgressionRuleRPE, "Missed reps at RPE 10: load reduced"
+		}
+		if target.FailedSessions >= stallLimit {
+			target.FailedSessions = 0
+			target.TargetReps = target.RepMin
+			if target.Load > 0 {
+				target.Load = floorToIncrement(target.Load*deloadLoadFactor, target.LoadIncrement)
+			} else if target.Sets > 1 {
+				target.Sets--
+			}
+			return models.ProgressionRuleDeload, fmt.Sprintf("Missed reps %d sessions in a row: deloaded", stallLimit)
+		}
+		return "", ""
+	}
+
+	target.FailedSessions = 0
+	if rpe >= 9 {
+		return "", ""
+	}
+	step := 1
+	if rpe > 0 && rpe <= 6 {
+		step = 2
+	}
+	rule := models.ProgressionRuleDouble
+	if step > 1 {
+		rule = models.ProgressionRuleRPE
+	}
+
+	if topOfRange {
+		if target.Load > 0 {
+			target.Load = roundLoad(target.Load + float64(step)*target.LoadIncrement)
+			target.TargetReps = target.RepMin
+			return rule, fmt.Sprintf("Top of the rep range reached: load increased to %g", target.Load)
+		}
+		if target.Sets < maxProgressionSets {
+			target.Sets++
+			target.TargetReps = target.RepMin
+			return rule, fmt.Sprintf("Top of the rep range reached: sets increased to %d", target.Sets)
+		}
+		return "", ""
+	}
+
+	next := target.TargetReps + step
+	if next > target.RepMax {
+		next = target.RepMax
+	}
+	if next == target.TargetReps {
+		return "", ""
+	}
+	target.TargetReps = next
+	return rule, fmt.Sprintf("Target reps hit: reps increased to %d", next)
+}
+
+// regressTarget steps an injured exercise down to its next easier variant,
+// if it has one, and cuts the load
+func regressTarget(target *models.ProgramExerciseTarget, severity int) {
+	if target.RegressionLevel < len(target.Regressions) {
+		target.Variant = target.Regressions[target.RegressionLevel]
+		target.RegressionLevel++
+	}
+
+	factor := injuryLoadFactor
+	if severity >= severeInjurySeverity {
+		factor = severeInjuryLoadFactor
+	}
+	if target.Load > 0 {
+		target.Load = floorToIncrement(target.Load*factor, target.LoadIncrement)
+	} else if severity >= severeInjurySeverity && target.Sets > 1 {
+		target.Sets--
+	}
+	target.TargetReps = target.RepMin
+	target.FailedSessions = 0
+}
+
+// worstInjury returns the most severe injury reported against the
+// exercise, or against a body part it trains when no exercise was named
+func worstInjury(target *models.ProgramExerciseTarget, injuries []models.ReportedInjury) *models.ReportedInjury {
+	var worst *models.ReportedInjury
+	for i := range injuries {
+		injury := &injuries[i]
+		if injury.ExerciseID != "" {
+			if injury.ExerciseID != target.ExerciseID {
+				continue
+			}
+		} else if !trainsBodyPart(target, injury.BodyPart) {
+			continue
+		}
+		if worst == nil || injury.Severity > worst.Severity {
+			worst = injury
+		}
+	}
+	return worst
+}
+
+func trainsBodyPart(target *models.ProgramExerciseTarget, bodyPart string) bool {
+	part := strings.ToLower(strings.TrimSpace(bodyPart))
+	if part == "" {
+		return false
+	}
+	for _, muscle := range target.TargetMuscles {
+		muscle = strings.ToLower(muscle)
+		if strings.Contains(muscle, part) || strings.Contains(part, muscle) {
+			return true
+		}
+	}
+	return false
+}
+
+// exerciseRPE reads the exercise's difficulty rating as RPE, falling back to
+// the session's perceived exertion. 0 means unknown.
+func exerciseRPE(completed models.CompletedExercise, session *models.UserWorkoutSession) int {
+	if completed.DifficultyRating >= 1 && completed.DifficultyRating <= 10 {
+		return completed.DifficultyRating
+	}
+	if session.PerceivedExertion != nil {
+		return *session.PerceivedExertion
+	}
+	return 0
+}
+
+func completedReps(completed models.CompletedExercise) []int {
+	reps := make([]int, 0, len(completed.RepsCompleted))
+	for _, value := range completed.RepsCompleted {
+		match := programNumberPattern.FindString(value)
+		if match == "" {
+			reps = append(reps, 0)
+			continue
+		}
+		n, _ := strconv.ParseFloat(match, 64)
+		reps = append(reps, int(n))
+	}
+	return reps
+}
+
+// programTargets takes the starting prescription for every exercise from
+// the program's sessions. An exercise in several sessions keeps the first
+// prescription found; regressions come from the sessions' easier and
+// injury-specific modifications.
+func programTargets(assignmentID string, templates []*models.WorkoutSession, startingLoads map[string]float64, increment float64, now time.Time) []*models.ProgramExerciseTarget {
+	var targets []*models.ProgramExerciseTarget
+	byID := map[string]*models.ProgramExerciseTarget{}
+
+	for _, template := range templates {
+		for _, exercise := range template.MainExercises {
+			if exercise.ExerciseID == "" || byID[exercise.ExerciseID] != nil {
+				continue
+			}
+			repMin, repMax := parseRepRange(exercise.Reps)
+			sets := exercise.Sets
+			if sets <= 0 {
+				sets = defaultProgramSets
+			}
+			load := parseProgramLoad(exercise.Weight)
+			if starting, ok := startingLoads[exercise.ExerciseID]; ok && starting >= 0 {
+				load = starting
+			}
+
+			muscles := make([]string, 0, len(exercise.TargetMuscles))
+			for _, muscle := range exercise.TargetMuscles {
+				muscles = append(muscles, strings.ToLower(muscle))
+			}
+
+			target := &models.ProgramExerciseTarget{
+				AssignmentID:  assignmentID,
+				ExerciseID:    exercise.ExerciseID,
+				ExerciseName:  exercise.ExerciseName,
+				Sets:          sets,
+				RepMin:        repMin,
+				RepMax:        repMax,
+				TargetReps:    repMin,
+				Load:          load,
+				LoadIncrement: increment,
+				Regressions:   []string{},
+				TargetMuscles: muscles,
+				UpdatedAt:     now,
+			}
+			byID[exercise.ExerciseID] = target
+			targets = append(targets, target)
+		}
+	}
+
+	for _, template := range templates {
+		for _, modification := range template.Modifications {
+			target := byID[modification.ExerciseID]
+			if target == nil || modification.Description == "" {
+				continue
+			}
+			if modification.ModificationType != "easier" && modification.ModificationType != "injury_specific" {
+				continue
+			}
+			if !containsString(target.Regressions, modification.Description) {
+				target.Regressions = append(target.Regressions, modification.Description)
+			}
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ExerciseName < targets[j].ExerciseName })
+	return targets
+}
+
+// scheduleProgramSessions walks the assignment's weeks day by day and
+// places the program's sessions, in order and repeating, on its training
+// days
+func scheduleProgramSessions(assignment *models.ProgramAssignment, templates []*models.WorkoutSession) []*models.ProgramScheduledSession {
+	training := map[int]bool{}
+	for _, day := range assignment.TrainingDays {
+		training[day] = true
+	}
+
+	var sessions []*models.ProgramScheduledSession
+	next := 0
+	for offset := 0; offset < assignment.Weeks*7; offset++ {
+		date := assignment.StartDate.AddDate(0, 0, offset)
+		if !training[int(date.Weekday())] {
+			continue
+		}
+		template := templates[next%len(templates)]
+		next++
+
+		week := offset/7 + 1
+		sessions = append(sessions, &models.ProgramScheduledSession{
+			ID:               uuid.New().String(),
+			AssignmentID:     assignment.ID,
+			WorkoutSessionID: template.ID,
+			Name:             template.Name,
+			Week:             week,
+			ScheduledDate:    date,
+			Deload:           week%deloadWeekInterval == 0,
+			Status:           models.ProgramSessionScheduled,
+		})
+	}
+	return sessions
+}
+
+// planProgramSessions fills in the exercises of sessions still to come from
+// the current targets, lightened in deload weeks
+func planProgramSessions(sessions []*models.ProgramScheduledSession, templates []*models.WorkoutSession, targets []*models.ProgramExerciseTarget) {
+	templatesByID := make(map[string]*models.WorkoutSession, len(templates))
+	for _, template := range templates {
+		templatesByID[template.ID] = template
+	}
+	targetsByID := make(map[string]*models.ProgramExerciseTarget, len(targets))
+	for _, target := range targets {
+		targetsByID[target.ExerciseID] = target
+	}
+
+	for _, session := range sessions {
+		template := templatesByID[session.WorkoutSessionID]
+		if session.Status != models.ProgramSessionScheduled || template == nil {
+			continue
+		}
+		session.Exercises = []models.PlannedExercise{}
+		for _, exercise := range template.MainExercises {
+			target := targetsByID[exercise.ExerciseID]
+			if target == nil {
+				continue
+			}
+			planned := models.PlannedExercise{
+				ExerciseID:   target.ExerciseID,
+				ExerciseName: target.ExerciseName,
+				Variant:      target.Variant,
+				Sets:         target.Sets,
+				Reps:         target.TargetReps,
+				RepRange:     fmt.Sprintf("%d-%d", target.RepMin, target.RepMax),
+				Load:         target.Load,
+				RestSeconds:  exercise.RestSeconds,
+			}
+			if session.Deload {
+				planned.Sets = int(math.Max(1, math.Round(float64(target.Sets)*deloadSetFactor)))
+				planned.Load = floorToIncrement(target.Load*deloadLoadFactor, target.LoadIncrement)
+			}
+			session.Exercises = append(session.Exercises, planned)
+		}
+	}
+}
+
+// parseRepRange reads prescriptions like "8-12", "10" or "30 seconds"
+func parseRepRange(reps string) (int, int) {
+	numbers := programNumberPattern.FindAllString(reps, 2)
+	if len(numbers) == 0 {
+		return 8, 12
+	}
+	low, _ := strconv.ParseFloat(numbers[0], 64)
+	high := low
+	if len(numbers) == 2 {
+		high, _ = strconv.ParseFloat(numbers[1], 64)
+	}
+	if high < low {
+		low, high = high, low
+	}
+	if low < 1 {
+		low = 1
+	}
+	if high < low {
+		high = low
+	}
+	return int(low), int(high)
+}
+
+// parseProgramLoad reads weights like "60", "60kg" or "135 lbs" as
+// kilograms; anything without a number is bodyweight
+func parseProgramLoad(weight *string) float64 {
+	if weight == nil {
+		return 0
+	}
+	match := programNumberPattern.FindString(*weight)
+	if match == "" {
+		return 0
+	}
+	load, _ := strconv.ParseFloat(match, 64)
+	lower := strings.ToLower(*weight)
+	if strings.Contains(lower, "lb") || strings.Contains(lower, "pound") {
+		load *= 0.45359237
+	}
+	return roundLoad(load)
+}
+
+func normalizeTrainingDays(days []int) ([]int, error) {
+	seen := map[int]bool{}
+	normalized := make([]int, 0, len(days))
+	for _, day := range days {
+		if day < 0 || day > 6 {
+			return nil, fmt.Errorf("%w: training days must be between 0 (Sunday) and 6", ErrInvalidProgramAssignment)
+		}
+		if !seen[day] {
+			seen[day] = true
+			normalized = append(normalized, day)
+		}
+	}
+	sort.Ints(normalized)
+	return normalized, nil
+}
+
+// floorToIncrement rounds a reduced load down to something that can be
+// loaded with the exercise's increment
+func floorToIncrement(load, increment float64) float64 {
+	if increment <= 0 {
+		return roundLoad(load)
+	}
+	return roundLoad(math.Floor(load/increment+1e-9) * increment)
+}
+
+func roundLoad(load float64) float64 {
+	return math.Round(load*100) / 100
+}
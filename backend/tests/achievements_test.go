@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openAchievementDB(t *testing.T) *sql.DB {
+	return openMigratedDB(t,
+		"012_create_weight_logs_table.sql",
+		"014_create_user_preferences_table.sql",
+		"031_create_achievements.sql",
+	)
+}
+
+func streakFor(streaks []models.Streak, activity string) models.Streak {
+	for _, streak := range streaks {
+		if streak.Activity == activity {
+			return streak
+		}
+	}
+	return models.Streak{}
+}
+
+func TestAchievements_StreaksFollowLocalDays(t *testing.T) {
+	db := openAchievementDB(t)
+	ctx := context.Background()
+	_, err := services.NewUserPreferencesService(db).UpdatePreferences(ctx, 1, map[string]interface{}{"timezone": "Pacific/Auckland"})
+	require.NoError(t, err)
+	achievements := services.NewAchievementService(db)
+
+	loc, err := time.LoadLocation("Pacific/Auckland")
+	require.NoError(t, err)
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	noon := func(daysAgo int) time.Time {
+		return today.AddDate(0, 0, -daysAgo).Add(12 * time.Hour)
+	}
+
+	result, err := achievements.Record(ctx, "1", models.ActivityMeal, noon(10), nil)
+	require.NoError(t, err)
+	require.Len(t, result.NewBadges, 1)
+	assert.Equal(t, "first-meal", result.NewBadges[0].Badge.ID)
+
+	for _, daysAgo := range []int{2, 1} {
+		result, err = achievements.Record(ctx, "1", models.ActivityMeal, noon(daysAgo), nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.NewBadges)
+	}
+
+	// Early morning in Auckland is still the previous day in UTC, but counts
+	// toward the user's today
+	breakfast := today.Add(8 * time.Hour)
+	require.NotEqual(t, breakfast.UTC().Format("2006-01-02"), breakfast.Format("2006-01-02"))
+	result, err = achievements.Record(ctx, "1", models.ActivityMeal, breakfast, nil)
+	require.NoError(t, err)
+	assert.Equal(t, today.Format("2006-01-02"), result.Day)
+	meals := streakFor(result.Streaks, models.ActivityMeal)
+	assert.Equal(t, 3, meals.Current)
+	assert.Equal(t, 3, meals.Best)
+
+	// A second meal the same day leaves the streak alone
+	result, err = achievements.Record(ctx, "1", models.ActivityMeal, noon(0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, streakFor(result.Streaks, models.ActivityAny).Current)
+
+	// A streak whose last day is before yesterday has lapsed
+	_, err = achievements.Record(ctx, "1", models.ActivityWeighIn, noon(5), float(80))
+	require.NoError(t, err)
+	overview, err := achievements.GetAchievements(ctx, "1")
+	require.NoError(t, err)
+	weighIns := streakFor(overview.Streaks, models.ActivityWeighIn)
+	assert.Equal(t, 0, weighIns.Current)
+	assert.Equal(t, 1, weighIns.Best)
+	assert.Equal(t, 3, streakFor(overview.Streaks, models.ActivityMeal).Current)
+	assert.Len(t, overview.Badges, 2)
+
+	_, err = achievements.Record(ctx, "1", "sleep", time.Now(), nil)
+	assert.True(t, errors.Is(err, services.ErrInvalidAchievementRequest))
+}
+
+func TestAchievements_MilestonesAchievedByLogging(t *testing.T) {
+	db := openAchievementDB(t)
+	ctx := context.Background()
+	achievements := services.NewAchievementService(db)
+
+	// A weight milestone starts from the latest weigh-in
+	_, err := db.Exec(`INSERT INTO weight_logs (user_id, weight, unit, created_at) VALUES (1, 200, 'lb', $1), (1, 90, 'kg', $2)`,
+		time.Now().Add(-48*time.Hour), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	weight, err := achievements.CreateMilestone(ctx, "1", &models.CreateMilestoneRequest{
+		Type:        models.MilestoneTypeWeight,
+		Title:       "Down to 85",
+		TargetValue: float(85),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, weight.StartValue)
+	assert.Equal(t, 90.0, *weight.StartValue)
+	assert.Equal(t, uint(1), weight.UserID)
+
+	result, err := achievements.Record(ctx, "1", models.ActivityWeighIn, time.Now(), float(87))
+	require.NoError(t, err)
+	assert.Empty(t, result.AchievedMilestones)
+
+	upcoming, err := achievements.GetUpcoming(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, upcoming.Milestones, 1)
+	assert.Equal(t, 60.0, upcoming.Milestones[0].ProgressPercent)
+
+	result, err = achievements.Record(ctx, "1", models.ActivityWeighIn, time.Now(), float(84.8))
+	require.NoError(t, err)
+	require.Len(t, result.AchievedMilestones, 1)
+	assert.Equal(t, weight.ID, result.AchievedMilestones[0].ID)
+	assert.False(t, result.AchievedMilestones[0].AchievedAt.IsZero())
+
+	// Workout milestones count completed workouts
+	workouts, err := achievements.CreateMilestone(ctx, "1", &models.CreateMilestoneRequest{
+		Type:        models.MilestoneTypeWorkout,
+		Title:       "Two workouts",
+		TargetValue: float(2),
+	})
+	require.NoError(t, err)
+	assert.False(t, workouts.IsAchieved)
+	_, err = achievements.Record(ctx, "1", models.ActivityWorkout, time.Now(), nil)
+	require.NoError(t, err)
+	result, err = achievements.Record(ctx, "1", models.ActivityWorkout, time.Now(), nil)
+	require.NoError(t, err)
+	require.Len(t, result.AchievedMilestones, 1)
+	assert.Equal(t, workouts.ID, result.AchievedMilestones[0].ID)
+
+	// Only custom milestones are marked achieved by hand
+	_, err = achievements.AchieveMilestone(ctx, "1", weight.ID)
+	assert.True(t, errors.Is(err, services.ErrInvalidAchievementRequest))
+	custom, err := achievements.CreateMilestone(ctx, "1", &models.CreateMilestoneRequest{
+		Type:  models.MilestoneTypeCustom,
+		Title: "Ran a 5k",
+	})
+	require.NoError(t, err)
+	custom, err = achievements.AchieveMilestone(ctx, "1", custom.ID)
+	require.NoError(t, err)
+	assert.True(t, custom.IsAchieved)
+
+	_, err = achievements.CreateMilestone(ctx, "1", &models.CreateMilestoneRequest{Type: models.MilestoneTypeWeight, Title: "No target"})
+	assert.True(t, errors.Is(err, services.ErrInvalidAchievementRequest))
+	_, err = achievements.AchieveMilestone(ctx, "2", custom.ID)
+	assert.EqualError(t, err, "milestone not found")
+
+	overview, err := achievements.GetAchievements(ctx, "1")
+	require.NoError(t, err)
+	// first-weigh-in and first-workout, plus three milestones
+	assert.Equal(t, 5, overview.Analytics.TotalAchievements)
+	assert.Equal(t, 100.0, overview.Analytics.CompletionRate)
+	assert.Equal(t, 1, overview.Analytics.AchievementsByType[models.MilestoneTypeWeight])
+	assert.Len(t, overview.Analytics.RecentAchievements, 3)
+
+	require.NoError(t, achievements.DeleteMilestone(ctx, "1", custom.ID))
+	assert.EqualError(t, achievements.DeleteMilestone(ctx, "1", custom.ID), "milestone not found")
+}
+
+func TestAchievements_ConsistencyAndUpcomingBadges(t *testing.T) {
+	db := openAchievementDB(t)
+	ctx := context.Background()
+	achievements := services.NewAchievementService(db)
+
+	now := time.Now().UTC()
+	daysAgo := func(n int) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.UTC).AddDate(0, 0, -n)
+	}
+	record := func(activity string, days ...int) {
+		for _, n := range days {
+			_, err := achievements.Record(ctx, "1", activity, daysAgo(n), nil)
+			require.NoError(t, err)
+		}
+	}
+	record(models.ActivityMeal, 13, 12, 11, 10, 9, 8, 7)
+	record(models.ActivityWorkout, 2, 1, 0)
+	record(models.ActivityWeighIn, 1)
+	record(models.ActivityProgressPhoto, 10)
+
+	consistency, err := achievements.GetConsistency(ctx, "1", 14)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, consistency.NutritionConsistency)
+	assert.Equal(t, 50.0, consistency.WorkoutConsistency)
+	assert.Equal(t, 50.0, consistency.MeasurementConsistency)
+	assert.Equal(t, 100.0, consistency.PhotoConsistency)
+	assert.Equal(t, 62.5, consistency.OverallConsistency)
+	assert.Equal(t, 3, consistency.StreakDays)
+	assert.Equal(t, 7, consistency.BestStreak)
+
+	_, err = achievements.GetConsistency(ctx, "1", 400)
+	assert.True(t, errors.Is(err, services.ErrInvalidAchievementRequest))
+
+	// A week of meals earned the streak badge; the five-day workout streak
+	// is closest
+	overview, err := achievements.GetAchievements(ctx, "1")
+	require.NoError(t, err)
+	earned := map[string]bool{}
+	for _, badge := range overview.Badges {
+		earned[badge.Badge.ID] = true
+	}
+	assert.True(t, earned["meal-streak-7"])
+	assert.True(t, earned["first-photo"])
+
+	upcoming, err := achievements.GetUpcoming(ctx, "1")
+	require.NoError(t, err)
+	require.NotEmpty(t, upcoming.Badges)
+	assert.Equal(t, "workout-streak-5", upcoming.Badges[0].Badge.ID)
+	assert.Equal(t, 3, upcoming.Badges[0].Current)
+	assert.Equal(t, 60.0, upcoming.Badges[0].ProgressPercent)
+	for _, badge := range upcoming.Badges {
+		assert.False(t, earned[badge.Badge.ID], badge.Badge.ID)
+	}
+}
@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"nutrition-platform/alerting"
+	"nutrition-platform/errors"
+	"nutrition-platform/security"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	name          string
+	mu            sync.Mutex
+	notifications []*alerting.Notification
+}
+
+func (n *recordingNotifier) Name() string { return n.name }
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification *alerting.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *recordingNotifier) sent() []*alerting.Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*alerting.Notification(nil), n.notifications...)
+}
+
+func TestAlertRouter_DeduplicatesAndRoutesBySeverity(t *testing.T) {
+	logs := &recordingNotifier{name: "logs"}
+	pager := &recordingNotifier{name: "pager"}
+	router := alerting.NewRouter(alerting.Config{
+		RepeatInterval: time.Hour,
+		Routes: []alerting.Route{
+			{MinSeverity: alerting.SeverityInfo, Notifiers: []alerting.Notifier{logs}},
+			{MinSeverity: alerting.SeverityCritical, Notifiers: []alerting.Notifier{pager, logs}},
+		},
+	})
+
+	memory := alerting.Alert{Source: "performance", Name: "high_memory", Severity: alerting.SeverityWarning, Summary: "High memory"}
+	for i := 0; i < 3; i++ {
+		router.Fire(memory)
+	}
+	// Different labels are a different incident
+	router.Fire(alerting.Alert{
+		Source: "health_check", Name: "health_check_failed", Severity: alerting.SeverityCritical,
+		Summary: "Redis down", Labels: map[string]string{"check": "redis"},
+	})
+	router.Flush()
+
+	require.Len(t, logs.sent(), 2, "the repeated warning is sent once, and logs only once per notification")
+	assert.Equal(t, 1, logs.sent()[0].Alerts[0].Occurrences)
+	require.Len(t, pager.sent(), 1)
+	assert.Equal(t, "health_check_failed", pager.sent()[0].Alerts[0].Name)
+
+	// A worse report of the same incident is sent despite the repeat interval
+	memory.Severity = alerting.SeverityCritical
+	router.Fire(memory)
+	router.Flush()
+	require.Len(t, pager.sent(), 2)
+	assert.Equal(t, 3, pager.sent()[1].Alerts[0].Occurrences)
+
+	// Resolving lets the incident alert again right away
+	router.Resolve("performance", "high_memory", nil)
+	router.Fire(memory)
+	router.Flush()
+	assert.Len(t, pager.sent(), 3)
+
+	stats := router.Stats()
+	assert.Equal(t, int64(6), stats.Received)
+	assert.Equal(t, int64(2), stats.Deduplicated)
+}
+
+func TestAlertRouter_GroupsAlerts(t *testing.T) {
+	notifier := &recordingNotifier{name: "test"}
+	router := alerting.NewRouter(alerting.Config{
+		GroupWait: time.Hour,
+		Routes:    []alerting.Route{{Notifiers: []alerting.Notifier{notifier}}},
+	})
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		router.Fire(alerting.Alert{
+			Source: "security", Name: "rate_limit_abuse", Severity: alerting.SeverityWarning,
+			Summary: "Rate limit abuse", Labels: map[string]string{"ip_address": ip},
+		})
+	}
+	router.Fire(alerting.Alert{Source: "security", Name: "high_severity_event", Severity: alerting.SeverityError, Summary: "Injection attempt"})
+	assert.Empty(t, notifier.sent(), "groups wait for GroupWait")
+
+	router.Close()
+	sent := notifier.sent()
+	require.Len(t, sent, 2)
+	byGroup := map[string]*alerting.Notification{}
+	for _, n := range sent {
+		byGroup[n.GroupKey] = n
+	}
+	abuse := byGroup["source=security|name=rate_limit_abuse"]
+	require.NotNil(t, abuse)
+	assert.Len(t, abuse.Alerts, 3)
+	assert.Equal(t, "[WARNING] Rate limit abuse and 2 more", abuse.Title())
+
+	// A closed router drops alerts
+	router.Fire(alerting.Alert{Source: "security", Name: "late", Summary: "Late"})
+	router.Flush()
+	assert.Len(t, notifier.sent(), 2)
+}
+
+func TestAlertRouter_Silences(t *testing.T) {
+	notifier := &recordingNotifier{name: "test"}
+	router := alerting.NewRouter(alerting.Config{
+		Routes: []alerting.Route{{Notifiers: []alerting.Notifier{notifier}}},
+	})
+
+	_, err := router.AddSilence(alerting.Silence{EndsAt: time.Now().Add(time.Hour)})
+	assert.ErrorIs(t, err, alerting.ErrInvalidSilence)
+	_, err = router.AddSilence(alerting.Silence{Matchers: map[string]string{"source": "health_check"}, EndsAt: time.Now().Add(-time.Minute)})
+	assert.ErrorIs(t, err, alerting.ErrInvalidSilence)
+
+	silence, err := router.AddSilence(alerting.Silence{
+		Matchers: map[string]string{"source": "health_check", "check": "redis"},
+		EndsAt:   time.Now().Add(time.Hour),
+		Comment:  "redis maintenance",
+	})
+	require.NoError(t, err)
+	require.Len(t, router.Silences(), 1)
+
+	redis := alerting.Alert{Source: "health_check", Name: "health_check_failed", Summary: "Redis down", Labels: map[string]string{"check": "redis"}}
+	router.Fire(redis)
+	router.Fire(alerting.Alert{Source: "health_check", Name: "health_check_failed", Summary: "Database down", Labels: map[string]string{"check": "database"}})
+	router.Flush()
+	require.Len(t, notifier.sent(), 1)
+	assert.Equal(t, "Database down", notifier.sent()[0].Alerts[0].Summary)
+	assert.Equal(t, int64(1), router.Stats().Silenced)
+
+	require.NoError(t, router.RemoveSilence(silence.ID))
+	assert.ErrorIs(t, router.RemoveSilence(silence.ID), alerting.ErrSilenceNotFound)
+	router.Fire(redis)
+	router.Flush()
+	assert.Len(t, notifier.sent(), 2)
+}
+
+func TestAlertRouter_EscalatesPersistentIncidents(t *testing.T) {
+	notifier := &recordingNotifier{name: "test"}
+	router := alerting.NewRouter(alerting.Config{
+		EscalateAfter: 50 * time.Millisecond,
+		Routes:        []alerting.Route{{Notifiers: []alerting.Notifier{notifier}}},
+	})
+
+	alert := alerting.Alert{Source: "performance", Name: "high_goroutines", Severity: alerting.SeverityWarning, Summary: "High goroutines"}
+	router.Fire(alert)
+	router.Fire(alert)
+	time.Sleep(60 * time.Millisecond)
+	router.Fire(alert)
+	router.Flush()
+
+	sent := notifier.sent()
+	require.Len(t, sent, 2)
+	assert.False(t, sent[0].Alerts[0].Escalated)
+	assert.Equal(t, alerting.SeverityError, sent[1].Severity)
+	assert.True(t, sent[1].Alerts[0].Escalated)
+	assert.Equal(t, 2, sent[1].Alerts[0].Occurrences)
+}
+
+func TestAlertNotifiers_WebhookAndFile(t *testing.T) {
+	var received alerting.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+	router := alerting.NewRouter(alerting.Config{
+		Routes: []alerting.Route{
+			{MinSeverity: alerting.SeverityInfo, Notifiers: []alerting.Notifier{alerting.NewFileNotifier(path)}},
+			{MinSeverity: alerting.SeverityError, Notifiers: []alerting.Notifier{alerting.NewWebhookNotifier(server.URL)}},
+		},
+	})
+	router.Fire(alerting.Alert{Source: "api", Name: "high_response_time", Severity: alerting.SeverityWarning, Summary: "Slow"})
+	router.Fire(alerting.Alert{Source: "api", Name: "high_error_rate", Severity: alerting.SeverityCritical, Summary: "Errors", Value: 12, Threshold: 5})
+	router.Flush()
+
+	require.Len(t, received.Alerts, 1)
+	assert.Equal(t, alerting.SeverityCritical, received.Severity)
+	assert.Equal(t, "high_error_rate", received.Alerts[0].Name)
+	assert.Equal(t, float64(12), received.Alerts[0].Value)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var n alerting.Notification
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &n))
+		names = append(names, n.Alerts[0].Name)
+	}
+	assert.ElementsMatch(t, []string{"high_response_time", "high_error_rate"}, names)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	err = alerting.NewWebhookNotifier(failing.URL).Notify(context.Background(), &received)
+	assert.EqualError(t, err, "webhook returned status 502")
+}
+
+func TestSecurityLogger_RoutesAlertsThroughRouter(t *testing.T) {
+	notifier := &recordingNotifier{name: "test"}
+	router := alerting.NewRouter(alerting.Config{
+		Routes: []alerting.Route{{Notifiers: []alerting.Notifier{notifier}}},
+	})
+	previous := alerting.Default()
+	alerting.SetDefault(router)
+	t.Cleanup(func() { alerting.SetDefault(previous) })
+
+	logger, err := security.NewSecurityLogger(filepath.Join(t.TempDir(), "security.log"))
+	require.NoError(t, err)
+	defer logger.Close()
+
+	// The rule trips from the fifth failure on; the router sends it once
+	for i := 0; i < 8; i++ {
+		logger.LogSecurityEvent(&errors.SecurityEvent{
+			Type: "authentication_failure", Severity: "medium", Message: "bad password",
+			IPAddress: "10.0.0.9", Timestamp: time.Now(),
+		})
+	}
+	router.Flush()
+
+	sent := notifier.sent()
+	require.Len(t, sent, 1)
+	alert := sent[0].Alerts[0]
+	assert.Equal(t, "security", alert.Source)
+	assert.Equal(t, "high_authentication_failures", alert.Name)
+	assert.Equal(t, alerting.SeverityWarning, alert.Severity)
+	assert.Equal(t, map[string]string{"event_type": "authentication_failure", "ip_address": "10.0.0.9"}, alert.Labels)
+	assert.Equal(t, int64(3), router.Stats().Deduplicated)
+}
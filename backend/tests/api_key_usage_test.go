@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"nutrition-platform/middleware"
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingAlertNotifier struct {
+	mu     sync.Mutex
+	events []*services.UsageAlertEvent
+}
+
+func (n *recordingAlertNotifier) NotifyUsageAlert(ctx context.Context, event *services.UsageAlertEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func (n *recordingAlertNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.events)
+}
+
+func setupAPIKeyUsage(t *testing.T) (*services.APIKeyService, *services.AnalyticsService, *recordingAlertNotifier) {
+	db := openAPIKeyDB(t)
+
+	_, err := db.Exec(`
+		CREATE TABLE usage_alerts (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, condition TEXT NOT NULL, threshold REAL NOT NULL,
+			enabled INTEGER DEFAULT 1, last_triggered DATETIME, metadata TEXT DEFAULT '{}',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`)
+	require.NoError(t, err)
+	migration, err := os.ReadFile("../migrations/022_add_usage_alert_scope.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+
+	analyticsService := services.NewAnalyticsService(db)
+	t.Cleanup(analyticsService.Stop)
+	notifier := &recordingAlertNotifier{}
+	analyticsService.SetAlertNotifier(notifier)
+
+	return services.NewAPIKeyService(db), analyticsService, notifier
+}
+
+func TestAnalyticsService_APIKeyUsageDashboard(t *testing.T) {
+	apiKeyService, analyticsService, _ := setupAPIKeyUsage(t)
+	ctx := context.Background()
+
+	created, err := apiKeyService.CreateAPIKey(ctx, "test-user", &models.CreateAPIKeyRequest{
+		Name:       "Dashboard Key",
+		Scopes:     []models.APIKeyScope{models.ScopeNutrition},
+		DailyQuota: 100,
+	})
+	require.NoError(t, err)
+	key := created.APIKey
+	_, err = apiKeyService.ConsumeQuota(ctx, key)
+	require.NoError(t, err)
+
+	for i := int64(1); i <= 10; i++ {
+		analyticsService.RecordAPIUsage(key.ID, "/api/v1/partner/nutrition/foods", "GET", 200, i*10, "127.0.0.1", "test")
+	}
+	analyticsService.RecordAPIUsage(key.ID, "/api/v1/partner/nutrition/foods/:id", "GET", 404, 500, "127.0.0.1", "test")
+	analyticsService.RecordAPIUsage(key.ID, "/api/v1/partner/nutrition/foods/:id", "GET", 500, 900, "127.0.0.1", "test")
+
+	_, err = analyticsService.GetAPIKeyUsage(ctx, key, 0)
+	assert.ErrorIs(t, err, services.ErrInvalidUsageReport)
+
+	dashboard, err := analyticsService.GetAPIKeyUsage(ctx, key, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), dashboard.TotalRequests)
+	assert.Equal(t, int64(2), dashboard.ErrorRequests)
+	assert.InDelta(t, 16.67, dashboard.ErrorRate, 0.01)
+	assert.Equal(t, int64(60), dashboard.Latency.P50)
+	assert.Equal(t, int64(900), dashboard.Latency.P95)
+	assert.Equal(t, int64(900), dashboard.Latency.Max)
+	assert.Equal(t, map[string]int64{"200": 10, "404": 1, "500": 1}, dashboard.StatusCodes)
+
+	require.Len(t, dashboard.Endpoints, 2)
+	assert.Equal(t, "/api/v1/partner/nutrition/foods", dashboard.Endpoints[0].Endpoint)
+	assert.Equal(t, int64(10), dashboard.Endpoints[0].Requests)
+	assert.Equal(t, float64(0), dashboard.Endpoints[0].ErrorRate)
+	assert.Equal(t, float64(100), dashboard.Endpoints[1].ErrorRate)
+
+	require.Len(t, dashboard.DailyStats, 7)
+	today := dashboard.DailyStats[6]
+	assert.Equal(t, time.Now().UTC().Format("2006-01-02"), today.Date)
+	assert.Equal(t, int64(12), today.TotalRequests)
+	assert.Equal(t, int64(10), today.MinResponseTime)
+	assert.Equal(t, int64(0), dashboard.DailyStats[0].TotalRequests)
+
+	require.NotNil(t, dashboard.Quota)
+	assert.Equal(t, 1, dashboard.Quota.Used)
+	assert.Equal(t, 99, dashboard.Quota.Remaining)
+}
+
+func TestAPIKeyAuth_RecordsRejectedRequests(t *testing.T) {
+	apiKeyService, analyticsService, _ := setupAPIKeyUsage(t)
+	ctx := context.Background()
+
+	e := echo.New()
+	partner := e.Group("/partner", middleware.APIKeyAuth(apiKeyService, analyticsService, nil))
+	partner.GET("/foods", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": "success"})
+	})
+	get := func(rawKey string) int {
+		req := httptest.NewRequest(http.MethodGet, "/partner/foods", nil)
+		req.Header.Set("X-API-Key", rawKey)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for name, req := range map[string]*models.CreateAPIKeyRequest{
+		"rate limit":  {Name: "Rate Limited Key", Scopes: []models.APIKeyScope{models.ScopeNutrition}, RateLimit: 1},
+		"daily quota": {Name: "Quota Key", Scopes: []models.APIKeyScope{models.ScopeNutrition}, DailyQuota: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			created, err := apiKeyService.CreateAPIKey(ctx, "test-user", req)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, get(created.Key))
+			assert.Equal(t, http.StatusTooManyRequests, get(created.Key))
+
+			dashboard, err := analyticsService.GetAPIKeyUsage(ctx, created.APIKey, 1)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), dashboard.TotalRequests)
+			assert.Equal(t, map[string]int64{"200": 1, "429": 1}, dashboard.StatusCodes)
+			require.Len(t, dashboard.Endpoints, 1)
+			assert.Equal(t, "/partner/foods", dashboard.Endpoints[0].Endpoint)
+		})
+	}
+}
+
+func TestAnalyticsService_UsageAlerts(t *testing.T) {
+	apiKeyService, analyticsService, notifier := setupAPIKeyUsage(t)
+	ctx := context.Background()
+
+	created, err := apiKeyService.CreateAPIKey(ctx, "test-user", &models.CreateAPIKeyRequest{
+		Name:   "Alert Key",
+		Scopes: []models.APIKeyScope{models.ScopeNutrition},
+	})
+	require.NoError(t, err)
+	keyID := created.APIKey.ID
+
+	_, err = analyticsService.CreateAlert(ctx, keyID, &models.CreateUsageAlertRequest{
+		Name: "Errors", Condition: "cpu_usage", Threshold: 5,
+	})
+	assert.ErrorIs(t, err, services.ErrInvalidUsageAlert)
+	_, err = analyticsService.CreateAlert(ctx, keyID, &models.CreateUsageAlertRequest{
+		Name: "Errors", Condition: models.UsageAlertErrorRate, Threshold: 150,
+	})
+	assert.ErrorIs(t, err, services.ErrInvalidUsageAlert)
+
+	alert, err := analyticsService.CreateAlert(ctx, keyID, &models.CreateUsageAlertRequest{
+		Name: "Errors", Condition: models.UsageAlertErrorRate, Threshold: 20, WindowMinutes: 15,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 60, alert.CooldownMinutes)
+
+	// Too few requests to judge an error rate
+	for i := 0; i < 5; i++ {
+		analyticsService.RecordAPIUsage(keyID, "/api/v1/partner/nutrition/foods", "GET", 500, 20, "127.0.0.1", "test")
+	}
+	events, err := analyticsService.EvaluateAlerts(ctx, keyID)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	for i := 0; i < 5; i++ {
+		analyticsService.RecordAPIUsage(keyID, "/api/v1/partner/nutrition/foods", "GET", 200, 20, "127.0.0.1", "test")
+	}
+	// The alert fires once, from this call or the evaluation RecordAPIUsage
+	// started in the background, and then waits out its cooldown
+	_, err = analyticsService.EvaluateAlerts(ctx, keyID)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return notifier.count() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, float64(50), notifier.events[0].Value)
+	assert.Equal(t, alert.ID, notifier.events[0].Alert.ID)
+
+	events, err = analyticsService.EvaluateAlerts(ctx, keyID)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, 1, notifier.count())
+
+	alerts, err := analyticsService.GetAlerts(ctx, keyID)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.NotNil(t, alerts[0].LastTriggered)
+
+	require.NoError(t, analyticsService.DeleteAlert(ctx, keyID, alert.ID))
+	assert.EqualError(t, analyticsService.DeleteAlert(ctx, keyID, alert.ID), "usage alert not found")
+}
@@ -22,7 +22,7 @@ func TestAuthHandler_Register(t *testing.T) {
 	// Setup
 	e := echo.New()
 	jwtManager := security.NewJWTManager()
-	authHandler := handlers.NewAuthHandler(nil, jwtManager)
+	authHandler := handlers.NewAuthHandler(nil, jwtManager, nil, newRefreshTokenService(t, nil), newMFAService(t, nil))
 
 	tests := []struct {
 		name           string
@@ -187,7 +187,7 @@ func TestAuthHandler_Login(t *testing.T) {
 	// Setup
 	e := echo.New()
 	jwtManager := security.NewJWTManager()
-	authHandler := handlers.NewAuthHandler(nil, jwtManager)
+	authHandler := handlers.NewAuthHandler(nil, jwtManager, nil, newRefreshTokenService(t, nil), newMFAService(t, nil))
 
 	tests := []struct {
 		name           string
@@ -273,7 +273,7 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 	// Setup
 	e := echo.New()
 	jwtManager := security.NewJWTManager()
-	authHandler := handlers.NewAuthHandler(nil, jwtManager)
+	authHandler := handlers.NewAuthHandler(nil, jwtManager, nil, newRefreshTokenService(t, nil), newMFAService(t, nil))
 
 	tests := []struct {
 		name           string
@@ -347,7 +347,7 @@ func TestAuthHandler_Logout(t *testing.T) {
 	// Setup
 	e := echo.New()
 	jwtManager := security.NewJWTManager()
-	authHandler := handlers.NewAuthHandler(nil, jwtManager)
+	authHandler := handlers.NewAuthHandler(nil, jwtManager, nil, newRefreshTokenService(t, nil), newMFAService(t, nil))
 
 	tests := []struct {
 		name           string
@@ -649,7 +649,7 @@ func TestAuth_Performance(t *testing.T) {
 
 	e := echo.New()
 	jwtManager := security.NewJWTManager()
-	authHandler := handlers.NewAuthHandler(nil, jwtManager)
+	authHandler := handlers.NewAuthHandler(nil, jwtManager, nil, newRefreshTokenService(t, nil), newMFAService(t, nil))
 
 	// Test concurrent login requests
 	concurrency := 50
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeBarcode(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+		valid    bool
+	}{
+		{"4006381333931", "4006381333931", true},   // EAN-13
+		{"036000291452", "0036000291452", true},    // UPC-A widened to EAN-13
+		{"0 36000 29145 2", "0036000291452", true}, // printed with spaces
+		{"96385074", "96385074", true},             // EAN-8
+		{"4006381333932", "", false},               // wrong check digit
+		{"036000291453", "", false},
+		{"40063813339", "", false}, // wrong length
+		{"40063813339A1", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			barcode, err := services.NormalizeBarcode(tt.code)
+			if !tt.valid {
+				assert.ErrorIs(t, err, services.ErrInvalidBarcode)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, barcode)
+		})
+	}
+}
+
+func setupFoodCatalog(t *testing.T) *sql.DB {
+	db := openMigratedDB(t)
+	_, err := db.Exec(`CREATE TABLE foods (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, name TEXT NOT NULL, brand TEXT,
+		description TEXT, bar_code TEXT, serving_size TEXT, calories REAL, protein REAL, carbs REAL,
+		fat REAL, saturated_fat REAL, fiber REAL, sugar REAL, sodium INTEGER, cholesterol REAL,
+		potassium REAL, source_type TEXT, verified BOOLEAN DEFAULT 0,
+		created_at DATETIME, updated_at DATETIME
+	)`)
+	require.NoError(t, err)
+
+	applyMigrations(t, db, "018_add_food_catalog_provenance.sql")
+
+	return db
+}
+
+const offCSV = "code\tproduct_name\tbrands\tserving_size\tstates_tags\tenergy-kcal_100g\tproteins_100g\tcarbohydrates_100g\tfat_100g\tsugars_100g\tsalt_100g\n" +
+	"4006381333931\tDark chocolate\tAcme,Acme Foods\t25 g\ten:checked,en:complete\t546\t7.8\t46\t38\t24\t0.03\n" +
+	"036000291452\tOat biscuits\t\t2 biscuits (30 g)\ten:to-be-checked\t450\t7\t65\t17\t20\t1\n" +
+	"4006381333932\tBad check digit\t\t\t\t100\t1\t1\t1\t1\t0\n" +
+	"96385074\t\t\t\t\t100\t1\t1\t1\t1\t0\n"
+
+func TestFoodImport_CSVWithProvenance(t *testing.T) {
+	db := setupFoodCatalog(t)
+	ctx := context.Background()
+	importer := services.NewFoodImportService(db)
+
+	result, err := importer.Import(ctx, strings.NewReader(offCSV), services.FoodImportOptions{Format: services.FoodImportFormatCSV})
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Read)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 2, result.Skipped)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 4, result.Errors[0].Line)
+
+	lookup, err := services.NewFoodBarcodeService(db).LookupBarcode(ctx, "7", "4006381333931")
+	require.NoError(t, err)
+	assert.Equal(t, services.BarcodeSourceCatalog, lookup.Source)
+	assert.True(t, lookup.Verified)
+	assert.Equal(t, "Dark chocolate", lookup.Food.Name)
+	assert.Equal(t, "Acme", *lookup.Food.Brand)
+	assert.Equal(t, 12, lookup.Food.Sodium) // 0.03 g salt
+	assert.Equal(t, "openfoodfacts", *lookup.Food.DataSource)
+
+	// UPC-A codes are found with or without the leading zero
+	lookup, err = services.NewFoodBarcodeService(db).LookupBarcode(ctx, "7", "0036000291452")
+	require.NoError(t, err)
+	assert.False(t, lookup.Verified)
+	assert.Equal(t, 400, lookup.Food.Sodium)
+
+	// Re-importing is idempotent and unverified data never replaces verified data
+	update := "code,product_name,energy-kcal_100g\n4006381333931,Renamed chocolate,500\n036000291452,Oat biscuits,440\n"
+	result, err = importer.Import(ctx, strings.NewReader(update), services.FoodImportOptions{Format: services.FoodImportFormatCSV})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Kept)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM foods").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestFoodImport_JSONL(t *testing.T) {
+	db := setupFoodCatalog(t)
+
+	jsonl := `{"code":"4006381333931","product_name":"Dark chocolate","states_tags":["en:checked"],"nutriments":{"energy_100g":2284,"proteins_100g":"7.8","fat_100g":38,"sodium_100g":0.01}}
+not json
+{"code":4006381333931}
+`
+	result, err := services.NewFoodImportService(db).Import(context.Background(), strings.NewReader(jsonl), services.FoodImportOptions{Format: services.FoodImportFormatJSONL})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Read)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 2, result.Skipped)
+
+	lookup, err := services.NewFoodBarcodeService(db).LookupBarcode(context.Background(), "7", "4006381333931")
+	require.NoError(t, err)
+	assert.InDelta(t, 545.9, lookup.Food.Calories, 0.01) // from kJ
+	assert.InDelta(t, 7.8, lookup.Food.Protein, 0.001)
+	assert.Equal(t, 10, lookup.Food.Sodium)
+}
+
+func TestBarcodeLookup_UserFoodTakesPrecedence(t *testing.T) {
+	db := setupFoodCatalog(t)
+	ctx := context.Background()
+
+	_, err := services.NewFoodImportService(db).Import(ctx, strings.NewReader(offCSV), services.FoodImportOptions{Format: services.FoodImportFormatCSV})
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO foods (user_id, name, bar_code, serving_size, calories, protein, carbs, fat, saturated_fat,
+		fiber, sugar, sodium, cholesterol, potassium, source_type, verified, created_at, updated_at)
+		VALUES (7, 'My chocolate', '4006381333931', '20 g', 530, 8, 45, 37, 0, 0, 24, 10, 0, 0, 'user', 0,
+		CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`)
+	require.NoError(t, err)
+
+	barcodes := services.NewFoodBarcodeService(db)
+	lookup, err := barcodes.LookupBarcode(ctx, "7", "4006381333931")
+	require.NoError(t, err)
+	assert.Equal(t, services.BarcodeSourceUser, lookup.Source)
+	assert.Equal(t, "My chocolate", lookup.Food.Name)
+
+	// Other users still get the catalog product
+	lookup, err = barcodes.LookupBarcode(ctx, "8", "4006381333931")
+	require.NoError(t, err)
+	assert.Equal(t, services.BarcodeSourceCatalog, lookup.Source)
+
+	_, err = barcodes.LookupBarcode(ctx, "7", "5000000000005")
+	require.Error(t, err)
+	assert.Equal(t, "food not found", err.Error())
+
+	_, err = barcodes.LookupBarcode(ctx, "7", "12345")
+	assert.ErrorIs(t, err, services.ErrInvalidBarcode)
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestBodyComposition_Formulas(t *testing.T) {
+	calculator := services.NewBodyCompositionService(nil)
+
+	male := calculator.Calculate(models.BodyCompositionInput{
+		Sex:    "male",
+		Height: float(180),
+		Weight: float(85),
+		Neck:   float(40),
+		Waist:  float(90),
+		Hips:   float(100),
+	})
+	require.Len(t, male.Estimates, 3)
+	assert.Equal(t, models.BodyFatMethodUSNavy, male.Estimates[0].Method)
+	assert.InDelta(t, 18.4, male.Estimates[0].BodyFatPercentage, 0.1)
+	assert.Equal(t, models.BodyFatMethodRFM, male.Estimates[1].Method)
+	assert.Equal(t, 24.0, male.Estimates[1].BodyFatPercentage)
+	assert.Equal(t, models.BodyFatMethodYMCA, male.Estimates[2].Method)
+	assert.InDelta(t, 17.7, male.Estimates[2].BodyFatPercentage, 0.1)
+
+	require.NotNil(t, male.BodyFatMethod)
+	assert.Equal(t, models.BodyFatMethodUSNavy, *male.BodyFatMethod)
+	assert.InDelta(t, 69.4, *male.LeanMass, 0.1)
+	assert.InDelta(t, 15.6, *male.FatMass, 0.1)
+	assert.InDelta(t, 21.4, *male.FFMI, 0.1)
+	// 1.8 m is the height FFMI is normalized to
+	assert.Equal(t, *male.FFMI, *male.NormalizedFFMI)
+	assert.Equal(t, 0.9, *male.WaistToHipRatio)
+	assert.Equal(t, 0.5, *male.WaistToHeightRatio)
+
+	// Women's Navy formula needs hips; without a neck RFM comes first
+	female := calculator.Calculate(models.BodyCompositionInput{
+		Sex:    "female",
+		Height: float(165),
+		Weight: float(62),
+		Waist:  float(75),
+		Hips:   float(100),
+	})
+	require.Len(t, female.Estimates, 2)
+	assert.Equal(t, models.BodyFatMethodRFM, *female.BodyFatMethod)
+	assert.Equal(t, 32.0, *female.BodyFatPercentage)
+
+	female = calculator.Calculate(models.BodyCompositionInput{
+		Sex:    "female",
+		Height: float(165),
+		Neck:   float(33),
+		Waist:  float(75),
+		Hips:   float(100),
+	})
+	assert.Equal(t, models.BodyFatMethodUSNavy, *female.BodyFatMethod)
+	assert.InDelta(t, 29.4, *female.BodyFatPercentage, 0.1)
+	// Without a weight there is no mass to split
+	assert.Nil(t, female.LeanMass)
+	assert.Nil(t, female.FFMI)
+
+	// Without a sex only the ratios are derived, unless body fat is entered
+	unknown := calculator.Calculate(models.BodyCompositionInput{
+		Height: float(170),
+		Weight: float(70),
+		Neck:   float(36),
+		Waist:  float(80),
+		Hips:   float(95),
+	})
+	assert.Empty(t, unknown.Estimates)
+	assert.Nil(t, unknown.BodyFatPercentage)
+	assert.NotNil(t, unknown.WaistToHipRatio)
+
+	entered := calculator.Calculate(models.BodyCompositionInput{
+		Weight:            float(70),
+		Waist:             float(80),
+		BodyFatPercentage: float(20),
+	})
+	assert.Equal(t, models.BodyFatMethodUserEntered, *entered.BodyFatMethod)
+	assert.Equal(t, 56.0, *entered.LeanMass)
+
+	// Implausible tape measurements are dropped
+	implausible := calculator.Calculate(models.BodyCompositionInput{
+		Sex:    "male",
+		Height: float(180),
+		Neck:   float(40),
+		Waist:  float(41),
+	})
+	assert.Empty(t, implausible.Estimates)
+}
+
+func TestBodyComposition_LogMeasurement(t *testing.T) {
+	db := openWeightForecastDB(t)
+	createProfileTable(t, db)
+	progress := services.NewProgressService(db)
+	ctx := context.Background()
+
+	// Height comes from the profile when the measurement has none
+	measurement, err := progress.LogMeasurement(ctx, 1, &models.BodyMeasurement{
+		Weight: float(85),
+		Neck:   float(40),
+		Waist:  float(90),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, measurement.BodyFatMethod)
+	assert.Equal(t, models.BodyFatMethodUSNavy, *measurement.BodyFatMethod)
+	assert.InDelta(t, 18.4, *measurement.BodyFatPercentage, 0.1)
+	assert.Nil(t, measurement.Height)
+
+	measurements, _, err := progress.GetMeasurementHistory(ctx, 1, 1, 10, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, measurements, 1)
+	stored := measurements[0]
+	assert.Equal(t, models.BodyFatMethodUSNavy, *stored.BodyFatMethod)
+	assert.Equal(t, *measurement.LeanMass, *stored.LeanMass)
+	assert.Equal(t, *measurement.FFMI, *stored.FFMI)
+	assert.Equal(t, 0.5, *stored.WaistToHeightRatio)
+	assert.Nil(t, stored.WaistToHipRatio)
+
+	// A derived value follows the tape when it changes
+	stored.Waist = float(100)
+	updated, err := progress.UpdateMeasurement(ctx, 1, stored)
+	require.NoError(t, err)
+	assert.Equal(t, models.BodyFatMethodUSNavy, *updated.BodyFatMethod)
+	assert.Greater(t, *updated.BodyFatPercentage, 18.4)
+
+	// An entered value is kept
+	entered, err := progress.LogMeasurement(ctx, 1, &models.BodyMeasurement{
+		MeasurementDate:   time.Now().Add(time.Hour),
+		Weight:            float(84),
+		Neck:              float(40),
+		Waist:             float(90),
+		BodyFatPercentage: float(15),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.BodyFatMethodUserEntered, *entered.BodyFatMethod)
+	assert.Equal(t, 15.0, *entered.BodyFatPercentage)
+	assert.Equal(t, 71.4, *entered.LeanMass)
+
+	entered.Waist = float(95)
+	updated, err = progress.UpdateMeasurement(ctx, 1, entered)
+	require.NoError(t, err)
+	assert.Equal(t, models.BodyFatMethodUserEntered, *updated.BodyFatMethod)
+	assert.Equal(t, 15.0, *updated.BodyFatPercentage)
+}
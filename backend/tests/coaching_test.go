@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"nutrition-platform/config"
+	"nutrition-platform/middleware"
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openCoachingDB(t *testing.T) *sql.DB {
+	return openMigratedDB(t, "025_create_coach_clients.sql")
+}
+
+func TestCoaching_InviteAcceptAndGrant(t *testing.T) {
+	db := openCoachingDB(t)
+	service := services.NewCoachingService(db)
+	ctx := context.Background()
+
+	invitation, err := service.Invite(ctx, "coach-1", models.CreateCoachInvitationRequest{
+		Email: "Client@Example.com",
+		Permissions: []string{
+			models.CoachPermissionReadFoodLogs,
+			models.CoachPermissionReadMeasurements,
+			models.CoachPermissionReadFoodLogs,
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, invitation.Token)
+	assert.Equal(t, "client@example.com", invitation.Relationship.InviteEmail)
+	assert.Equal(t, []string{models.CoachPermissionReadFoodLogs, models.CoachPermissionReadMeasurements},
+		invitation.Relationship.RequestedPermissions)
+
+	var stored int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM coach_clients WHERE invite_token_hash = ?", invitation.Token).Scan(&stored))
+	assert.Zero(t, stored, "the invitation token must only be stored hashed")
+
+	// Pending invitations grant nothing
+	ok, err := service.HasPermission(ctx, "coach-1", "client-1", models.CoachPermissionReadFoodLogs)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Clients can only narrow what was requested
+	_, err = service.Accept(ctx, "client-1", models.AcceptCoachInvitationRequest{
+		Token:       invitation.Token,
+		Permissions: []string{models.CoachPermissionViewProgressPhotos},
+	})
+	assert.ErrorIs(t, err, services.ErrInvalidCoachingRequest)
+
+	_, err = service.Accept(ctx, "coach-1", models.AcceptCoachInvitationRequest{Token: invitation.Token})
+	assert.ErrorIs(t, err, services.ErrInvalidCoachingRequest)
+
+	rel, err := service.Accept(ctx, "client-1", models.AcceptCoachInvitationRequest{
+		Token:       invitation.Token,
+		Permissions: []string{models.CoachPermissionReadMeasurements},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.CoachClientActive, rel.Status)
+	assert.Equal(t, "client-1", rel.ClientID)
+	assert.Equal(t, []string{models.CoachPermissionReadMeasurements}, rel.Permissions)
+
+	// The token works once
+	_, err = service.Accept(ctx, "client-2", models.AcceptCoachInvitationRequest{Token: invitation.Token})
+	assert.ErrorIs(t, err, services.ErrCoachInvitationInvalid)
+
+	ok, err = service.HasPermission(ctx, "coach-1", "client-1", models.CoachPermissionReadMeasurements)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = service.HasPermission(ctx, "coach-1", "client-1", models.CoachPermissionReadFoodLogs)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = service.HasPermission(ctx, "coach-2", "client-1", models.CoachPermissionReadMeasurements)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The client can grant more than was requested later on
+	rel, err = service.UpdatePermissions(ctx, "client-1", rel.ID, models.UpdateCoachPermissionsRequest{
+		Permissions: []string{models.CoachPermissionEditMealPlans, models.CoachPermissionReadFoodLogs},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.CoachPermissionEditMealPlans, models.CoachPermissionReadFoodLogs}, rel.Permissions)
+	ok, err = service.HasPermission(ctx, "coach-1", "client-1", models.CoachPermissionReadMeasurements)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Only the client manages grants
+	_, err = service.UpdatePermissions(ctx, "coach-1", rel.ID, models.UpdateCoachPermissionsRequest{
+		Permissions: []string{models.CoachPermissionReadMeasurements},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "coach client relationship not found", err.Error())
+
+	_, err = service.UpdatePermissions(ctx, "client-1", rel.ID, models.UpdateCoachPermissionsRequest{
+		Permissions: []string{"read_everything"},
+	})
+	assert.ErrorIs(t, err, services.ErrInvalidCoachingRequest)
+
+	coaches, err := service.ListCoaches(ctx, "client-1")
+	require.NoError(t, err)
+	require.Len(t, coaches, 1)
+	clients, err := service.ListClients(ctx, "coach-1")
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	assert.Equal(t, "client-1", clients[0].ClientID)
+}
+
+func TestCoaching_SecondInvitationFromSameCoachIsRejected(t *testing.T) {
+	service := services.NewCoachingService(openCoachingDB(t))
+	ctx := context.Background()
+	req := models.CreateCoachInvitationRequest{Permissions: []string{models.CoachPermissionReadWorkouts}}
+
+	first, err := service.Invite(ctx, "coach-1", req)
+	require.NoError(t, err)
+	second, err := service.Invite(ctx, "coach-1", req)
+	require.NoError(t, err)
+
+	_, err = service.Accept(ctx, "client-1", models.AcceptCoachInvitationRequest{Token: first.Token})
+	require.NoError(t, err)
+	_, err = service.Accept(ctx, "client-1", models.AcceptCoachInvitationRequest{Token: second.Token})
+	assert.ErrorIs(t, err, services.ErrAlreadyCoached)
+
+	_, err = service.Invite(ctx, "coach-1", models.CreateCoachInvitationRequest{})
+	assert.ErrorIs(t, err, services.ErrInvalidCoachingRequest)
+}
+
+func TestCoaching_EndRelationshipRevokesGrants(t *testing.T) {
+	service := services.NewCoachingService(openCoachingDB(t))
+	ctx := context.Background()
+
+	invitation, err := service.Invite(ctx, "coach-1", models.CreateCoachInvitationRequest{
+		Permissions: []string{models.CoachPermissionViewProgressPhotos},
+	})
+	require.NoError(t, err)
+	rel, err := service.Accept(ctx, "client-1", models.AcceptCoachInvitationRequest{Token: invitation.Token})
+	require.NoError(t, err)
+
+	err = service.EndRelationship(ctx, "someone-else", rel.ID)
+	require.Error(t, err)
+	assert.Equal(t, "coach client relationship not found", err.Error())
+
+	require.NoError(t, service.EndRelationship(ctx, "client-1", rel.ID))
+	ok, err := service.HasPermission(ctx, "coach-1", "client-1", models.CoachPermissionViewProgressPhotos)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	coaches, err := service.ListCoaches(ctx, "client-1")
+	require.NoError(t, err)
+	assert.Empty(t, coaches)
+
+	err = service.EndRelationship(ctx, "coach-1", rel.ID)
+	require.Error(t, err)
+	assert.Equal(t, "coach client relationship not found", err.Error())
+
+	// Coaches can withdraw invitations that were never accepted
+	pending, err := service.Invite(ctx, "coach-1", models.CreateCoachInvitationRequest{
+		Permissions: []string{models.CoachPermissionReadFoodLogs},
+	})
+	require.NoError(t, err)
+	require.NoError(t, service.EndRelationship(ctx, "coach-1", pending.Relationship.ID))
+	_, err = service.Accept(ctx, "client-1", models.AcceptCoachInvitationRequest{Token: pending.Token})
+	assert.ErrorIs(t, err, services.ErrCoachInvitationInvalid)
+}
+
+func TestCoaching_AccessLog(t *testing.T) {
+	service := services.NewCoachingService(openCoachingDB(t))
+	ctx := context.Background()
+
+	for _, entry := range []*models.CoachAccessLogEntry{
+		{CoachID: "coach-1", ClientID: "client-1", Permission: models.CoachPermissionReadFoodLogs, Method: "GET", Path: "/api/v1/nutrition/ledger", Allowed: true, IPAddress: "10.0.0.1"},
+		{CoachID: "coach-2", ClientID: "client-1", Permission: models.CoachPermissionReadMeasurements, Method: "GET", Path: "/api/v1/progress/measurements", Allowed: false},
+		{CoachID: "coach-1", ClientID: "client-2", Permission: models.CoachPermissionReadFoodLogs, Method: "GET", Path: "/api/v1/nutrition/ledger", Allowed: true},
+	} {
+		require.NoError(t, service.RecordAccess(ctx, entry))
+		assert.NotEmpty(t, entry.ID)
+	}
+
+	entries, err := service.AccessLog(ctx, "client-1", "", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	entries, err = service.AccessLog(ctx, "client-1", "coach-2", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.False(t, entries[0].Allowed)
+	assert.Equal(t, "/api/v1/progress/measurements", entries[0].Path)
+}
+
+func TestClientAccess_CoachNeedsGrantForRoute(t *testing.T) {
+	db := openCoachingDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE meal_plans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, name TEXT NOT NULL, name_ar TEXT,
+			description TEXT, start_date TEXT, end_date TEXT, days TEXT NOT NULL DEFAULT '[]',
+			total_calories REAL, total_protein REAL, total_carbs REAL, total_fat REAL, is_active BOOLEAN,
+			created_at DATETIME, updated_at DATETIME
+		);
+		INSERT INTO meal_plans (user_id, name, is_active, created_at, updated_at)
+		VALUES (7, 'Cutting week', 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);`)
+	require.NoError(t, err)
+
+	coaching := services.NewCoachingService(db)
+	nutrition := services.NewNutritionService(db, config.ExportConfig{})
+	ctx := context.Background()
+
+	// Stands in for JWTAuth: the caller's ID and role come from headers
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", c.Request().Header.Get("X-User"))
+			c.Set("role", c.Request().Header.Get("X-Role"))
+			return next(c)
+		}
+	})
+	e.PATCH("/meal-plans/:id", func(c echo.Context) error {
+		userID, err := strconv.Atoi(c.Get("user_id").(string))
+		require.NoError(t, err)
+		planID, err := strconv.Atoi(c.Param("id"))
+		require.NoError(t, err)
+		var req models.UpdateMealPlanRequest
+		require.NoError(t, c.Bind(&req))
+		plan, err := nutrition.UpdateMealPlan(c.Request().Context(), planID, userID, &req)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"status": "success", "data": plan})
+	}, middleware.ClientAccess(coaching, models.CoachPermissionEditMealPlans))
+	e.POST("/workouts", func(c echo.Context) error {
+		return c.JSON(http.StatusCreated, map[string]string{"status": "success"})
+	}, middleware.ClientAccess(coaching, models.CoachPermissionReadWorkouts))
+
+	send := func(method, target, body, userID, role string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-User", userID)
+		req.Header.Set("X-Role", role)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	planName := func() string {
+		plan, err := nutrition.GetMealPlan(ctx, 1, 7)
+		require.NoError(t, err)
+		return plan.Name
+	}
+
+	invitation, err := coaching.Invite(ctx, "coach-1", models.CreateCoachInvitationRequest{
+		Email:       "client@example.com",
+		Permissions: []string{models.CoachPermissionReadFoodLogs, models.CoachPermissionReadWorkouts},
+	})
+	require.NoError(t, err)
+	rel, err := coaching.Accept(ctx, "7", models.AcceptCoachInvitationRequest{Token: invitation.Token})
+	require.NoError(t, err)
+
+	rename := `{"name": "Coach's plan"}`
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPatch, "/meal-plans/1?client_id=7", rename, "coach-1", models.RoleCoach))
+	assert.Equal(t, "Cutting week", planName())
+
+	// Read grants never allow writes
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/workouts?client_id=7", `{}`, "coach-1", models.RoleCoach))
+
+	// Only coaches can act for someone else
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPatch, "/meal-plans/1?client_id=7", rename, "8", "user"))
+
+	_, err = coaching.UpdatePermissions(ctx, "7", rel.ID, models.UpdateCoachPermissionsRequest{
+		Permissions: []string{models.CoachPermissionEditMealPlans},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/meal-plans/1?client_id=7", rename, "coach-1", models.RoleCoach))
+	assert.Equal(t, "Coach's plan", planName())
+
+	entries, err := coaching.AccessLog(ctx, "7", "coach-1", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	allowed := 0
+	for _, entry := range entries {
+		if entry.Allowed {
+			allowed++
+		}
+	}
+	assert.Equal(t, 1, allowed)
+}
+
+func TestRejectClientAccess_RefusesClientID(t *testing.T) {
+	e := echo.New()
+	e.GET("/notifications", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": "success"})
+	}, middleware.RejectClientAccess())
+
+	send := func(target string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("/notifications"))
+	assert.Equal(t, http.StatusForbidden, send("/notifications?client_id=7"))
+}
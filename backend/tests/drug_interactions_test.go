@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDrugInteractions(t *testing.T) *sql.DB {
+	db := openMigratedDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE foods (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, name TEXT NOT NULL, brand TEXT,
+			description TEXT, bar_code TEXT, serving_size TEXT, calories REAL, protein REAL, carbs REAL,
+			fat REAL, saturated_fat REAL, fiber REAL, sugar REAL, sodium INTEGER, cholesterol REAL,
+			potassium REAL, source_type TEXT, verified BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE recipes (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, name_ar TEXT, description TEXT, description_ar TEXT,
+			cuisine TEXT, country TEXT, difficulty_level TEXT, prep_time_minutes INTEGER,
+			cook_time_minutes INTEGER, total_time_minutes INTEGER, servings INTEGER,
+			ingredients TEXT NOT NULL DEFAULT '[]', instructions TEXT NOT NULL DEFAULT '[]',
+			nutrition_per_serving TEXT DEFAULT '{}', dietary_tags TEXT DEFAULT '[]', allergens TEXT DEFAULT '[]',
+			is_halal INTEGER DEFAULT 1, is_kosher INTEGER DEFAULT 0, image_url TEXT, video_url TEXT,
+			rating REAL DEFAULT 0, rating_count INTEGER DEFAULT 0, created_by TEXT, verified INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE user_food_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, food_id INTEGER, quantity REAL NOT NULL,
+			unit TEXT, meal_type TEXT, consumed_at DATETIME, calories REAL, protein REAL, carbs REAL,
+			fat REAL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE medications (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, name_ar TEXT, generic_name TEXT, brand_names TEXT DEFAULT '[]',
+			drug_class TEXT, category TEXT
+		);
+		CREATE TABLE user_medications (
+			id TEXT PRIMARY KEY, user_id TEXT, medication_id TEXT, custom_medication_name TEXT, dosage TEXT,
+			frequency TEXT, administration_time TEXT DEFAULT '[]', start_date TEXT, end_date TEXT,
+			prescribed_by TEXT, reason_for_taking TEXT, side_effects_experienced TEXT DEFAULT '[]',
+			is_active INTEGER DEFAULT 1, adherence_notes TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE user_supplements (
+			id TEXT PRIMARY KEY, user_id TEXT, vitamin_mineral_id TEXT, supplement_name TEXT, brand TEXT,
+			dosage TEXT, form TEXT, frequency TEXT, taken_with_meals INTEGER DEFAULT 1, start_date TEXT,
+			end_date TEXT, reason_for_taking TEXT, prescribed_by TEXT, cost_per_month REAL,
+			effectiveness_rating INTEGER, side_effects TEXT, is_active INTEGER DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`)
+	require.NoError(t, err)
+
+	applyMigrations(t, db, "016_add_food_log_units.sql", "017_create_nutrition_ledger_sources.sql")
+
+	// Nutrients per 100g
+	_, err = db.Exec(`INSERT INTO foods (id, name, serving_size, calories, protein, carbs, fat, saturated_fat, fiber, sugar,
+		sodium, cholesterol, potassium, source_type, verified) VALUES
+		(1, 'Grapefruit, raw', '1 fruit', 42, 0.8, 10.7, 0.1, 0, 1.6, 6.9, 0, 0, 135, 'global', 1),
+		(2, 'Spinach, boiled', '100 g', 23, 3, 3.8, 0.3, 0, 2.4, 0.4, 70, 0, 466, 'global', 1),
+		(3, 'Apple', '1 medium', 52, 0.3, 14, 0.2, 0, 2.4, 10.4, 1, 0, 107, 'global', 1)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO recipes (id, name, servings, ingredients, nutrition_per_serving) VALUES
+		('r-saag', 'Saag chicken', 2, '[{"name":"chicken thighs","amount":400,"unit":"g"},{"name":"spinach","amount":300,"unit":"g"}]',
+		 '{"calories":420,"protein":38,"carbohydrates":9,"fat":24}')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO medications (id, name, generic_name, brand_names) VALUES
+		('med-warfarin', 'Coumadin 5 mg', 'warfarin sodium', '["Coumadin", "Jantoven"]')`)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestDrugInteractions_FlagsLoggedFoodsAndSupplements(t *testing.T) {
+	db := setupDrugInteractions(t)
+	ctx := context.Background()
+	interactions := services.NewDrugInteractionService(db, nil)
+	logs := services.NewFoodLogService(db)
+
+	statin := "Atorvastatin 20mg"
+	_, _, err := interactions.AddMedication(ctx, 7, models.CreateUserMedicationRequest{
+		CustomMedicationName: &statin, Dosage: "20 mg", Frequency: "daily",
+	})
+	require.NoError(t, err)
+
+	warfarinID := "med-warfarin"
+	_, _, err = interactions.AddMedication(ctx, 7, models.CreateUserMedicationRequest{
+		MedicationID: &warfarinID, Dosage: "5 mg", Frequency: "daily",
+	})
+	require.NoError(t, err)
+
+	// A stopped medication is not checked
+	ended := time.Now().AddDate(0, 0, -10)
+	lisinopril := "Lisinopril"
+	_, _, err = interactions.AddMedication(ctx, 7, models.CreateUserMedicationRequest{
+		CustomMedicationName: &lisinopril, Dosage: "10 mg", Frequency: "daily", EndDate: &ended,
+	})
+	require.NoError(t, err)
+
+	entry, err := logs.LogFood(ctx, 7, services.LogFoodInput{FoodID: 1, MealType: "breakfast", Quantity: 150, Unit: "g"})
+	require.NoError(t, err)
+	found, err := interactions.CheckFoodLog(ctx, 7, entry)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Atorvastatin 20mg", found[0].Medication)
+	assert.Equal(t, "Grapefruit, raw", found[0].Food)
+	assert.Equal(t, services.InteractionSeverityMajor, found[0].Severity)
+	assert.Contains(t, found[0].Reference, "Food and Drug Administration")
+
+	// Recipes are checked by their ingredients; the catalog's brand names
+	// identify the drug
+	entry, err = logs.LogRecipe(ctx, 7, services.LogRecipeInput{RecipeID: "r-saag", MealType: "dinner", Servings: 1})
+	require.NoError(t, err)
+	found, err = interactions.CheckFoodLog(ctx, 7, entry)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Coumadin 5 mg", found[0].Medication)
+	assert.Equal(t, services.InteractionSeverityModerate, found[0].Severity)
+
+	entry, err = logs.LogFood(ctx, 7, services.LogFoodInput{FoodID: 3, MealType: "snack", Quantity: 180, Unit: "g"})
+	require.NoError(t, err)
+	found, err = interactions.CheckFoodLog(ctx, 7, entry)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	_, supplementFindings, err := interactions.AddSupplement(ctx, 7, models.CreateUserSupplementRequest{
+		SupplementName: "Vitamin K2 (menaquinone-7)", Dosage: "100 mcg", Frequency: "daily",
+	})
+	require.NoError(t, err)
+	require.Len(t, supplementFindings, 1)
+	assert.Equal(t, services.InteractionSeverityMajor, supplementFindings[0].Severity)
+
+	// Potassium only interacts with the lisinopril, which was stopped
+	_, supplementFindings, err = interactions.AddSupplement(ctx, 7, models.CreateUserSupplementRequest{
+		SupplementName: "Potassium citrate", Dosage: "99 mg", Frequency: "daily",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, supplementFindings)
+
+	report, err := interactions.GetReport(ctx, 7, 7, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Atorvastatin 20mg", "Coumadin 5 mg"}, report.UserMedications)
+	require.Len(t, report.FoodInteractions, 2)
+	assert.Equal(t, services.InteractionSeverityMajor, report.FoodInteractions[0].Severity)
+	assert.Equal(t, "Saag chicken", report.FoodInteractions[1].Food)
+	require.Len(t, report.SupplementInteractions, 1)
+	assert.Len(t, report.Warnings, 2)
+	assert.Len(t, report.Recommendations, 3)
+	assert.Contains(t, report.Disclaimer, "MEDICAL DISCLAIMER")
+
+	// Other users see nothing
+	report, err = interactions.GetReport(ctx, 8, 0, "ar")
+	require.NoError(t, err)
+	assert.Empty(t, report.UserMedications)
+	assert.Empty(t, report.FoodInteractions)
+	assert.Contains(t, report.Disclaimer, "إخلاء مسؤولية طبية")
+}
+
+func TestDrugInteractions_InvalidRequests(t *testing.T) {
+	db := setupDrugInteractions(t)
+	ctx := context.Background()
+	interactions := services.NewDrugInteractionService(db, nil)
+
+	_, _, err := interactions.AddMedication(ctx, 7, models.CreateUserMedicationRequest{Dosage: "5 mg", Frequency: "daily"})
+	assert.ErrorIs(t, err, services.ErrInvalidMedicationRequest)
+
+	unknown := "med-unknown"
+	_, _, err = interactions.AddMedication(ctx, 7, models.CreateUserMedicationRequest{MedicationID: &unknown, Dosage: "5 mg", Frequency: "daily"})
+	assert.ErrorIs(t, err, services.ErrInvalidMedicationRequest)
+
+	_, _, err = interactions.AddSupplement(ctx, 7, models.CreateUserSupplementRequest{SupplementName: "Iron", Frequency: "daily"})
+	assert.ErrorIs(t, err, services.ErrInvalidMedicationRequest)
+
+	_, err = interactions.GetReport(ctx, 7, 365, "")
+	assert.ErrorIs(t, err, services.ErrInvalidMedicationRequest)
+}
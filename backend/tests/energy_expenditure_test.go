@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEnergyExpenditure(t *testing.T) *sql.DB {
+	db := setupNutritionLedger(t)
+	migration, err := os.ReadFile("../migrations/012_create_weight_logs_table.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO foods (id, name) VALUES (1, 'Mixed meals')`)
+	require.NoError(t, err)
+	return db
+}
+
+func createProfileTable(t *testing.T, db *sql.DB) {
+	_, err := db.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY, username TEXT, email TEXT, age INTEGER, gender TEXT, height REAL, weight REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO users (id, username, email, age, gender, height, weight)
+		VALUES (1, 'sam', 'sam@example.com', 35, 'male', 180, 90)`)
+	require.NoError(t, err)
+}
+
+// logDeficit logs four weeks ending today of someone spending 2500 kcal a
+// day and eating about 2000: one day a week unlogged, one day barely
+// logged, and a noisy weigh-in every other day
+func logDeficit(t *testing.T, db *sql.DB) {
+	now := time.Now().UTC()
+	first := time.Date(now.Year(), now.Month(), now.Day()-27, 0, 0, 0, 0, time.UTC)
+	dailyLoss := 500 / 7700.0
+
+	for i := 0; i < 28; i++ {
+		day := first.AddDate(0, 0, i)
+		if i%7 != 3 {
+			calories := 1900.0
+			if i%2 == 0 {
+				calories = 2100
+			}
+			if i == 12 {
+				calories = 400
+			}
+			_, err := db.Exec(`INSERT INTO user_food_logs (user_id, food_id, quantity, unit, meal_type, consumed_at, calories)
+				VALUES (1, 1, 100, 'g', 'lunch', $1, $2)`, day.Add(12*time.Hour), calories)
+			require.NoError(t, err)
+		}
+		if i%2 == 0 {
+			noise := -0.2
+			if i%4 == 0 {
+				noise = 0.2
+			}
+			_, err := db.Exec(`INSERT INTO weight_logs (user_id, weight, unit, created_at, updated_at) VALUES (1, $1, 'kg', $2, $2)`,
+				90-dailyLoss*float64(i)+noise, day.Add(7*time.Hour))
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestEnergyExpenditure_AdaptiveEstimateFromTrend(t *testing.T) {
+	db := setupEnergyExpenditure(t)
+	logDeficit(t, db)
+	energy := services.NewEnergyExpenditureService(db)
+
+	estimate, err := energy.EstimateTDEE(context.Background(), 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, services.DefaultTDEEWindowDays, estimate.Days)
+	assert.Equal(t, models.TDEEMethodAdaptive, estimate.Method)
+	require.NotNil(t, estimate.AdaptiveTDEE)
+	assert.Nil(t, estimate.FormulaTDEE)
+	assert.InDelta(t, 2500, estimate.TDEE, 100)
+	assert.Less(t, estimate.TDEELow, estimate.TDEE)
+	assert.Greater(t, estimate.TDEEHigh, estimate.TDEE)
+	assert.NotEqual(t, models.TDEEConfidenceLow, estimate.Confidence)
+
+	// Unlogged days are left out, not counted as zero, and the barely
+	// logged day does not drag the average down
+	assert.Equal(t, 23, estimate.LoggedDays)
+	assert.Equal(t, 1, estimate.PartialDays)
+	assert.InDelta(t, 2000, estimate.AverageIntake, 10)
+	assert.True(t, estimate.Daily[12].Partial)
+	assert.Nil(t, estimate.Daily[3].Calories)
+
+	assert.Equal(t, 14, estimate.WeighIns)
+	require.NotNil(t, estimate.WeeklyChange)
+	assert.InDelta(t, -0.45, *estimate.WeeklyChange, 0.05)
+	require.NotNil(t, estimate.TrendWeight)
+	assert.Nil(t, estimate.Daily[1].Weight)
+	assert.NotNil(t, estimate.Daily[1].TrendWeight)
+
+	_, err = energy.EstimateTDEE(context.Background(), 1, 7)
+	assert.ErrorIs(t, err, services.ErrInvalidEnergyRequest)
+}
+
+func TestEnergyExpenditure_BlendsWithFormula(t *testing.T) {
+	db := setupEnergyExpenditure(t)
+	energy := services.NewEnergyExpenditureService(db)
+	ctx := context.Background()
+
+	// Without logs or a profile there is nothing to go on
+	_, err := energy.EstimateTDEE(ctx, 1, 0)
+	assert.ErrorIs(t, err, services.ErrInsufficientEnergyData)
+
+	// A profile alone gives the formula with a wide band
+	createProfileTable(t, db)
+	estimate, err := energy.EstimateTDEE(ctx, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, models.TDEEMethodFormula, estimate.Method)
+	assert.Equal(t, models.TDEEConfidenceLow, estimate.Confidence)
+	assert.Equal(t, 2550, estimate.TDEE)
+
+	// ...which is not used to set a goal
+	_, _, err = energy.ApplyToGoal(ctx, 1, models.AdaptiveGoalRequest{Adjustment: -500})
+	assert.ErrorIs(t, err, services.ErrInsufficientEnergyData)
+
+	logDeficit(t, db)
+	estimate, err = energy.EstimateTDEE(ctx, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, models.TDEEMethodBlended, estimate.Method)
+	require.NotNil(t, estimate.AdaptiveTDEE)
+	require.NotNil(t, estimate.FormulaTDEE)
+	// The formula uses the trend weight, not the profile's
+	assert.Less(t, *estimate.FormulaTDEE, 2550)
+	low, high := *estimate.AdaptiveTDEE, *estimate.FormulaTDEE
+	if low > high {
+		low, high = high, low
+	}
+	assert.GreaterOrEqual(t, estimate.TDEE, low-10)
+	assert.LessOrEqual(t, estimate.TDEE, high+10)
+}
+
+func TestEnergyExpenditure_ApplyToGoal(t *testing.T) {
+	db := setupEnergyExpenditure(t)
+	logDeficit(t, db)
+	energy := services.NewEnergyExpenditureService(db)
+	ctx := context.Background()
+
+	// Without an active goal one is created
+	goal, estimate, err := energy.ApplyToGoal(ctx, 1, models.AdaptiveGoalRequest{Adjustment: -500})
+	require.NoError(t, err)
+	assert.NotZero(t, goal.ID)
+	require.NotNil(t, goal.DailyCalories)
+	assert.Equal(t, estimate.TDEE-500, *goal.DailyCalories)
+	assert.Equal(t, estimate.TDEELow-500, *goal.DailyCaloriesLow)
+	assert.Equal(t, estimate.TDEEHigh-500, *goal.DailyCaloriesHigh)
+	assert.Equal(t, estimate.TDEE, *goal.EstimatedTDEE)
+
+	// An existing goal keeps its other targets
+	_, err = db.Exec(`INSERT INTO nutrition_goals (user_id, daily_calories, protein_grams, is_active) VALUES (1, 1800, 150, 1)`)
+	require.NoError(t, err)
+	var goalID int
+	require.NoError(t, db.QueryRow(`SELECT MAX(id) FROM nutrition_goals`).Scan(&goalID))
+
+	goal, estimate, err = energy.ApplyToGoal(ctx, 1, models.AdaptiveGoalRequest{GoalID: &goalID, Adjustment: -1500})
+	require.NoError(t, err)
+	assert.Equal(t, goalID, goal.ID)
+
+	var calories, low, high, tdee int
+	var protein float64
+	var confidence string
+	require.NoError(t, db.QueryRow(`SELECT daily_calories, daily_calories_low, daily_calories_high, estimated_tdee,
+		tdee_confidence, protein_grams FROM nutrition_goals WHERE id = $1`, goalID).
+		Scan(&calories, &low, &high, &tdee, &confidence, &protein))
+	// Targets never go below 1200
+	assert.Equal(t, 1200, calories)
+	assert.Equal(t, 1200, low)
+	assert.GreaterOrEqual(t, high, calories)
+	assert.Equal(t, estimate.TDEE, tdee)
+	assert.Equal(t, estimate.Confidence, confidence)
+	assert.Equal(t, 150.0, protein)
+
+	missing := 999
+	_, _, err = energy.ApplyToGoal(ctx, 1, models.AdaptiveGoalRequest{GoalID: &missing})
+	require.Error(t, err)
+	assert.Equal(t, "nutrition goal not found", err.Error())
+	_, _, err = energy.ApplyToGoal(ctx, 1, models.AdaptiveGoalRequest{Adjustment: 2000})
+	assert.ErrorIs(t, err, services.ErrInvalidEnergyRequest)
+}
@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupJSONStores(t *testing.T) *sql.DB {
+	db := openMigratedDB(t,
+		"020_create_meal_supplement_plan_session_tables.sql",
+		"023_create_refresh_tokens.sql",
+		"024_create_user_mfa.sql",
+	)
+
+	services.InitializeSQLStorage(db)
+	return db
+}
+
+func TestJSONStores_BackedBySQL(t *testing.T) {
+	setupJSONStores(t)
+
+	meal := &services.Meal{
+		UserID: "7", Name: "Shakshuka", MealType: "brunch", Calories: 380, Protein: 21,
+		Ingredients: []string{"eggs", "tomatoes", "cumin"}, Tags: []string{"vegetarian"},
+	}
+	require.NoError(t, services.CreateMeal(meal))
+	assert.Equal(t, "snack", meal.MealType)
+	assert.True(t, meal.IsHalal)
+
+	today, err := services.GetMealsByDate("7", time.Now())
+	require.NoError(t, err)
+	require.Len(t, today, 1)
+	assert.Equal(t, []string{"eggs", "tomatoes", "cumin"}, today[0].Ingredients)
+
+	meal.Ingredients = append(meal.Ingredients, "chorizo")
+	require.NoError(t, services.UpdateMeal(meal.ID, meal))
+	stored, err := services.GetMealByID(meal.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.IsHalal)
+	assert.Equal(t, "7", stored.UserID)
+
+	assert.EqualError(t, services.DeleteMeal(meal.ID, "8"), "unauthorized: meal belongs to another user")
+	require.NoError(t, services.DeleteMeal(meal.ID, "7"))
+	_, err = services.GetMealByID(meal.ID)
+	assert.EqualError(t, err, "meal not found")
+
+	supplement := &services.Supplement{UserID: "7", Name: "Fish oil", Ingredients: []string{"fish oil", "gelatin"}}
+	require.NoError(t, services.CreateSupplement(supplement))
+	require.NoError(t, services.ToggleSupplementStatus(supplement.ID, "7"))
+	active, err := services.GetActiveSupplementsByUserID("7")
+	require.NoError(t, err)
+	assert.Empty(t, active)
+	stats, err := services.GetSupplementStats("7")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats["total_supplements"])
+	assert.Equal(t, map[string]int{"halal": 0, "vegetarian": 0, "vegan": 0, "organic": 0}, stats["dietary_info"])
+
+	plan := &services.MedicalPlan{
+		UserID: "7", Name: "Lower blood pressure", Category: "blood_pressure", Duration: 30, IsPublic: true,
+		MealPlan: &services.MealPlanDetails{CaloriesPerDay: 1900, AvoidedFoods: []string{"pickles"}},
+	}
+	require.NoError(t, services.CreateMedicalPlan(plan))
+	require.NoError(t, services.RateMedicalPlan(plan.ID, 5))
+	require.NoError(t, services.RateMedicalPlan(plan.ID, 4))
+	require.NotEmpty(t, plan.Checkpoints)
+	require.NoError(t, services.UpdateCheckpoint(plan.ID, plan.Checkpoints[0].ID, "7", true, "on track", nil))
+
+	stored2, err := services.GetMedicalPlanByID(plan.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 4.5, stored2.Rating, 0.001)
+	assert.Equal(t, 2, stored2.RatingCount)
+	assert.True(t, stored2.Checkpoints[0].Completed)
+	assert.Equal(t, []string{"pickles"}, stored2.MealPlan.AvoidedFoods)
+	assert.Nil(t, stored2.ExercisePlan)
+
+	public, err := services.SearchMedicalPlans("8", "blood", nil, true)
+	require.NoError(t, err)
+	assert.Len(t, public, 1)
+	own, err := services.SearchMedicalPlans("8", "blood", nil, false)
+	require.NoError(t, err)
+	assert.Empty(t, own)
+
+	session, err := services.CreateSession("7", "access-1", "refresh-1", "phone", "127.0.0.1", "test")
+	require.NoError(t, err)
+	found, err := services.GetSessionByRefreshToken("refresh-1")
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, found.ID)
+
+	require.NoError(t, services.UpdateSessionTokens(session.ID, "access-2", "refresh-2"))
+	_, err = services.GetSessionByAccessToken("access-1")
+	assert.Error(t, err)
+	require.NoError(t, services.InvalidateAllUserSessions("7"))
+	sessions, err := services.GetUserSessions("7")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	sessionStats, err := services.GetSessionStats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sessionStats["inactive_sessions"])
+}
+
+func TestJSONStores_ImportIsIdempotent(t *testing.T) {
+	db := setupJSONStores(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"meals.json": `{"meals": [
+			{"id": "m-1", "user_id": "7", "name": "Lentil soup", "calories": 320, "meal_type": "dinner",
+			 "ingredients": ["red lentils"], "tags": [], "created_at": "2024-03-01T18:30:00Z", "updated_at": "2024-03-01T18:30:00Z"},
+			{"id": "mediterranean_chicken", "name": {"en": "Mediterranean Chicken Bowl"}, "calories": 450}
+		], "metadata": {"version": "1.0"}}`,
+		"supplements.json": `{"supplements": [{"id": "s-1", "name": "Vitamin D3", "dosage": "1000 IU"}]}`,
+		"sessions.json": `{"sessions": [{"id": "sess-1", "user_id": "7", "access_token": "a", "refresh_token": "r",
+			"is_active": true, "expires_at": "2099-01-01T00:00:00Z", "created_at": "2024-03-01T00:00:00Z",
+			"last_used_at": "2024-03-02T00:00:00Z"}]}`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	results, err := services.ImportJSONStores(ctx, db, dir)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	meals := results[0]
+	assert.Equal(t, 2, meals.Read)
+	assert.Equal(t, 1, meals.Imported)
+	assert.Equal(t, 1, meals.Skipped)
+	require.Len(t, meals.Errors, 1)
+	assert.Equal(t, "mediterranean_chicken", meals.Errors[0].ID)
+
+	assert.Equal(t, 1, results[1].Skipped, "supplement without user_id")
+	assert.Contains(t, results[1].Errors[0].Reason, "missing user_id")
+	assert.True(t, results[2].Missing)
+	assert.Equal(t, 1, results[3].Imported)
+
+	meal, err := services.GetMealByID("m-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Lentil soup", meal.Name)
+	assert.True(t, meal.CreatedAt.Equal(time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)))
+	session, err := services.GetSessionByRefreshToken("r")
+	require.NoError(t, err)
+	assert.Equal(t, "sess-1", session.ID)
+
+	// A second run finds everything already imported
+	results, err = services.ImportJSONStores(ctx, db, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, results[0].Imported)
+	assert.Equal(t, 1, results[0].Existing)
+	assert.Equal(t, 1, results[3].Existing)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM meals").Scan(&count))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMealPlanCatalog(t *testing.T) *sql.DB {
+	db := openMigratedDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE foods (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, name TEXT NOT NULL, brand TEXT,
+			description TEXT, bar_code TEXT, serving_size TEXT, calories REAL, protein REAL, carbs REAL,
+			fat REAL, saturated_fat REAL, fiber REAL, sugar REAL, sodium INTEGER, cholesterol REAL,
+			potassium REAL, source_type TEXT, verified BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE recipes (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, name_ar TEXT, description TEXT, description_ar TEXT,
+			cuisine TEXT, country TEXT, difficulty_level TEXT, prep_time_minutes INTEGER,
+			cook_time_minutes INTEGER, total_time_minutes INTEGER, servings INTEGER,
+			ingredients TEXT NOT NULL DEFAULT '[]', instructions TEXT NOT NULL DEFAULT '[]',
+			nutrition_per_serving TEXT DEFAULT '{}', dietary_tags TEXT DEFAULT '[]', allergens TEXT DEFAULT '[]',
+			is_halal INTEGER DEFAULT 1, is_kosher INTEGER DEFAULT 0, image_url TEXT, video_url TEXT,
+			rating REAL DEFAULT 0, rating_count INTEGER DEFAULT 0, created_by TEXT, verified INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE meal_plans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, name TEXT NOT NULL, name_ar TEXT,
+			description TEXT, start_date TEXT, end_date TEXT, days TEXT NOT NULL DEFAULT '[]',
+			total_calories REAL, total_protein REAL, total_carbs REAL, total_fat REAL, is_active BOOLEAN,
+			created_at DATETIME, updated_at DATETIME
+		);
+		CREATE TABLE nutrition_goals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, daily_calories INTEGER, protein_grams REAL,
+			carbs_grams REAL, fat_grams REAL, fiber_grams REAL, sugar_grams REAL, sodium_mg INTEGER,
+			water_ml INTEGER, is_active BOOLEAN, start_date DATETIME, end_date DATETIME,
+			created_at DATETIME, updated_at DATETIME, daily_calories_low INTEGER, daily_calories_high INTEGER,
+			estimated_tdee INTEGER, tdee_confidence TEXT, tdee_updated_at DATETIME
+		);`)
+	require.NoError(t, err)
+
+	// Nutrients per 100g
+	_, err = db.Exec(`INSERT INTO foods (name, serving_size, calories, protein, carbs, fat, saturated_fat, fiber, sugar, sodium,
+		cholesterol, potassium, source_type, verified) VALUES
+		('Chicken breast, grilled', '100 g', 165, 31, 0, 3.6, 1, 0, 0, 74, 85, 256, 'global', 1),
+		('Rice, white, cooked', '100 g', 130, 2.7, 28, 0.3, 0, 0.4, 0, 1, 0, 35, 'global', 1),
+		('Olive oil', '100 g', 884, 0, 0, 100, 14, 0, 0, 2, 0, 1, 'global', 1),
+		('Greek yogurt', '100 g', 97, 9, 3.6, 5, 3, 0, 3.6, 35, 13, 141, 'global', 1),
+		('Lentils, boiled', '100 g', 116, 9, 20, 0.4, 0, 8, 1.8, 2, 0, 369, 'global', 1),
+		('Almonds', '100 g', 579, 21, 22, 50, 3.8, 12.5, 4.4, 1, 0, 733, 'global', 1),
+		('Banana', '100 g', 89, 1.1, 23, 0.3, 0.1, 2.6, 12, 1, 0, 358, 'global', 1),
+		('Pork chop', '100 g', 231, 25, 0, 14, 5, 0, 0, 62, 80, 350, 'global', 1)`)
+	require.NoError(t, err)
+
+	recipes := []struct {
+		id, name    string
+		ingredients []string
+		allergens   []string
+		halal       bool
+		nutrition   models.NutritionInfo
+	}{
+		{"r-oats", "Overnight oats", []string{"oats", "milk", "honey"}, []string{"gluten", "dairy"}, true,
+			models.NutritionInfo{Calories: 380, Protein: 14, Carbohydrates: 60, Fat: 9}},
+		{"r-eggs", "Shakshuka", []string{"eggs", "tomatoes", "peppers"}, []string{"eggs"}, true,
+			models.NutritionInfo{Calories: 320, Protein: 18, Carbohydrates: 14, Fat: 21}},
+		{"r-kabsa", "Chicken kabsa", []string{"chicken", "basmati rice", "spices"}, nil, true,
+			models.NutritionInfo{Calories: 620, Protein: 42, Carbohydrates: 68, Fat: 18}},
+		{"r-salmon", "Baked salmon with quinoa", []string{"salmon", "quinoa", "lemon"}, []string{"fish"}, true,
+			models.NutritionInfo{Calories: 540, Protein: 38, Carbohydrates: 40, Fat: 22}},
+		{"r-mujaddara", "Mujaddara", []string{"lentils", "rice", "onions", "olive oil"}, nil, true,
+			models.NutritionInfo{Calories: 480, Protein: 18, Carbohydrates: 78, Fat: 11}},
+		{"r-pasta", "Pasta carbonara", []string{"spaghetti", "bacon", "eggs", "parmesan"}, []string{"gluten", "eggs"}, true,
+			models.NutritionInfo{Calories: 650, Protein: 25, Carbohydrates: 70, Fat: 28}},
+		{"r-stew", "Beef stew", []string{"beef", "potatoes", "red wine"}, nil, false,
+			models.NutritionInfo{Calories: 560, Protein: 40, Carbohydrates: 30, Fat: 26}},
+		{"r-salad", "Grilled chicken salad", []string{"chicken breast", "lettuce", "olive oil"}, nil, true,
+			models.NutritionInfo{Calories: 420, Protein: 45, Carbohydrates: 12, Fat: 21}},
+		{"r-soup", "Lentil soup", []string{"red lentils", "carrots", "cumin"}, nil, true,
+			models.NutritionInfo{Calories: 290, Protein: 17, Carbohydrates: 45, Fat: 5}},
+		{"r-foul", "Foul medames", []string{"fava beans", "olive oil", "lemon"}, nil, true,
+			models.NutritionInfo{Calories: 350, Protein: 16, Carbohydrates: 42, Fat: 13}},
+		{"r-kofta", "Beef kofta with bulgur", []string{"ground beef", "bulgur", "parsley"}, []string{"gluten"}, true,
+			models.NutritionInfo{Calories: 590, Protein: 40, Carbohydrates: 48, Fat: 25}},
+		{"r-hummus", "Hummus plate", []string{"chickpeas", "tahini", "olive oil"}, []string{"sesame"}, true,
+			models.NutritionInfo{Calories: 300, Protein: 10, Carbohydrates: 30, Fat: 16}},
+	}
+	for _, r := range recipes {
+		ingredients := make([]models.RecipeIngredient, len(r.ingredients))
+		for i, name := range r.ingredients {
+			ingredients[i] = models.RecipeIngredient{Name: name, Amount: 1, Unit: "serving"}
+		}
+		ingredientsJSON, _ := json.Marshal(ingredients)
+		allergensJSON, _ := json.Marshal(append([]string{}, r.allergens...))
+		nutritionJSON, _ := json.Marshal(r.nutrition)
+		_, err := db.Exec(`INSERT INTO recipes (id, name, ingredients, allergens, is_halal, nutrition_per_serving, servings)
+			VALUES ($1, $2, $3, $4, $5, $6, 1)`, r.id, r.name, string(ingredientsJSON), string(allergensJSON), r.halal, string(nutritionJSON))
+		require.NoError(t, err)
+	}
+
+	return db
+}
+
+func TestMealPlanOptimizer_MeetsTargetsWithinTolerance(t *testing.T) {
+	db := setupMealPlanCatalog(t)
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	targets := services.MealPlanTargets{Calories: 2200, Protein: 150, Carbs: 230, Fat: 70}
+	generated, err := services.NewMealPlanOptimizer(db, nil).GeneratePlan(ctx, 7, services.MealPlanRequest{
+		StartDate:  start,
+		Targets:    targets,
+		Halal:      true,
+		MaxRepeats: 3,
+	})
+	require.NoError(t, err)
+
+	plan := generated.Plan
+	require.NotZero(t, plan.ID)
+	require.Len(t, plan.Days, 7)
+	assert.Equal(t, start, *plan.StartDate)
+	assert.Equal(t, start.AddDate(0, 0, 6), *plan.EndDate)
+	assert.Equal(t, 7, generated.DaysWithinTolerance)
+
+	mains := map[string]int{}
+	for i, day := range plan.Days {
+		fit := generated.Days[i]
+		assert.True(t, fit.WithinTolerance, "day %d: %v", day.DayNumber, fit.Deviation)
+		totals := day.Totals()
+		assert.InDelta(t, targets.Calories, totals.Calories, targets.Calories*0.1)
+		assert.InDelta(t, targets.Protein, totals.Protein, targets.Protein*0.1)
+		assert.InDelta(t, targets.Carbs, totals.Carbohydrates, targets.Carbs*0.1)
+		assert.InDelta(t, targets.Fat, totals.Fat, targets.Fat*0.1)
+
+		seen := map[string]bool{}
+		for _, meal := range day.Meals {
+			main := meal.Items[0].Name.En
+			assert.False(t, seen[main], "%s served twice on day %d", main, day.DayNumber)
+			seen[main] = true
+			mains[main]++
+
+			for _, item := range meal.Items {
+				// Not halal: pork, and the stew and carbonara recipes
+				assert.NotContains(t, []string{"Pork chop", "Beef stew", "Pasta carbonara"}, item.Name.En)
+			}
+		}
+	}
+	for main, count := range mains {
+		assert.LessOrEqual(t, count, 3, main)
+	}
+
+	// The plan is persisted with its days
+	var stored string
+	require.NoError(t, db.QueryRow(`SELECT days FROM meal_plans WHERE id = $1 AND user_id = 7`, plan.ID).Scan(&stored))
+	var days []models.MealPlanDay
+	require.NoError(t, json.Unmarshal([]byte(stored), &days))
+	assert.Len(t, days, 7)
+}
+
+func TestMealPlanOptimizer_RespectsAllergiesAndRestrictions(t *testing.T) {
+	db := setupMealPlanCatalog(t)
+
+	generated, err := services.NewMealPlanOptimizer(db, nil).GeneratePlan(context.Background(), 7, services.MealPlanRequest{
+		Days:         3,
+		Targets:      services.MealPlanTargets{Calories: 1800},
+		Allergies:    []string{"nuts", "eggs"},
+		Restrictions: []string{"vegetarian", "gluten-free"},
+		Halal:        true,
+	})
+	require.NoError(t, err)
+	require.Len(t, generated.Plan.Days, 3)
+
+	excluded := []string{"Almonds", "Shakshuka", "Pasta carbonara", "Chicken kabsa", "Baked salmon with quinoa",
+		"Chicken breast, grilled", "Overnight oats", "Pork chop", "Beef stew"}
+	for _, day := range generated.Plan.Days {
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				assert.NotContains(t, excluded, item.Name.En)
+			}
+		}
+	}
+}
+
+func TestMealPlanOptimizer_TargetsFromNutritionGoal(t *testing.T) {
+	db := setupMealPlanCatalog(t)
+	optimizer := services.NewMealPlanOptimizer(db, nil)
+
+	_, err := optimizer.GeneratePlan(context.Background(), 7, services.MealPlanRequest{})
+	assert.ErrorIs(t, err, services.ErrInvalidMealPlanRequest)
+
+	_, err = db.Exec(`INSERT INTO nutrition_goals (user_id, daily_calories, protein_grams, is_active, created_at, updated_at)
+		VALUES (7, 2000, 140, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`)
+	require.NoError(t, err)
+
+	generated, err := optimizer.GeneratePlan(context.Background(), 7, services.MealPlanRequest{Days: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2000.0, generated.Targets.Calories)
+	assert.Equal(t, 140.0, generated.Targets.Protein)
+	assert.Equal(t, 225.0, generated.Targets.Carbs) // 45% of calories
+
+	_, err = optimizer.GeneratePlan(context.Background(), 7, services.MealPlanRequest{Days: 1, Restrictions: []string{"paleo"}})
+	assert.ErrorIs(t, err, services.ErrInvalidMealPlanRequest)
+}
@@ -0,0 +1,408 @@
+package tests
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Glyphs of the test font: .notdef, printable ASCII, the forms مرحبا is
+// drawn with, a Cyrillic letter no meal plan uses and an unmapped glyph
+// that the initial meem is composed from
+const (
+	pdfTestFirstArabicGlyph = 96
+	pdfTestUnusedGlyph      = 101
+	pdfTestComponentGlyph   = 102
+	pdfTestNumGlyphs        = 103
+)
+
+var pdfTestArabicForms = []rune{0xFE8E, 0xFE92, 0xFEA3, 0xFEAE, 0xFEE3}
+
+// buildPDFTestFont assembles a minimal TrueType font whose glyph outlines
+// are replaced by markers, so the subset embedded in a PDF can be checked
+func buildPDFTestFont(t *testing.T) string {
+	glyphs := make([][]byte, pdfTestNumGlyphs)
+	for gid := range glyphs {
+		var glyph bytes.Buffer
+		contours := int16(1)
+		if gid == pdfTestFirstArabicGlyph+4 {
+			contours = -1
+		}
+		binary.Write(&glyph, binary.BigEndian, contours)
+		glyph.Write(make([]byte, 8))
+		if contours < 0 {
+			// One component with byte offsets and no more to follow
+			binary.Write(&glyph, binary.BigEndian, []uint16{0, pdfTestComponentGlyph, 0})
+		}
+		fmt.Fprintf(&glyph, "GLYPH%03d", gid)
+		for glyph.Len()%4 != 0 {
+			glyph.WriteByte(0)
+		}
+		glyphs[gid] = glyph.Bytes()
+	}
+
+	var glyf bytes.Buffer
+	loca := make([]byte, (pdfTestNumGlyphs+1)*2)
+	for gid, glyph := range glyphs {
+		binary.BigEndian.PutUint16(loca[gid*2:], uint16(glyf.Len()/2))
+		glyf.Write(glyph)
+	}
+	binary.BigEndian.PutUint16(loca[pdfTestNumGlyphs*2:], uint16(glyf.Len()/2))
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	binary.BigEndian.PutUint16(head[42:], 800)
+
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(hhea[4:], 800)
+	binary.BigEndian.PutUint16(hhea[6:], uint16(0xFF38)) // -200
+	binary.BigEndian.PutUint16(hhea[34:], pdfTestNumGlyphs)
+
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	binary.BigEndian.PutUint16(maxp[4:], pdfTestNumGlyphs)
+
+	hmtx := make([]byte, pdfTestNumGlyphs*4)
+	for gid := 0; gid < pdfTestNumGlyphs; gid++ {
+		binary.BigEndian.PutUint16(hmtx[gid*4:], 500)
+	}
+
+	// cmap format 4 segments: ASCII as one range, then one per character
+	type segment struct{ start, end, gid int }
+	segments := []segment{{0x20, 0x7E, 1}, {0x0436, 0x0436, pdfTestUnusedGlyph}}
+	for i, r := range pdfTestArabicForms {
+		segments = append(segments, segment{int(r), int(r), pdfTestFirstArabicGlyph + i})
+	}
+	segments = append(segments, segment{0xFFFF, 0xFFFF, 0})
+
+	var format4 bytes.Buffer
+	segCount := len(segments)
+	binary.Write(&format4, binary.BigEndian, []uint16{4, uint16(16 + segCount*8), 0, uint16(segCount * 2), 0, 0, 0})
+	for _, seg := range segments {
+		binary.Write(&format4, binary.BigEndian, uint16(seg.end))
+	}
+	binary.Write(&format4, binary.BigEndian, uint16(0))
+	for _, seg := range segments {
+		binary.Write(&format4, binary.BigEndian, uint16(seg.start))
+	}
+	for _, seg := range segments {
+		delta := 1
+		if seg.gid != 0 {
+			delta = seg.gid - seg.start
+		}
+		binary.Write(&format4, binary.BigEndian, uint16(delta))
+	}
+	format4.Write(make([]byte, segCount*2))
+
+	var cmap bytes.Buffer
+	binary.Write(&cmap, binary.BigEndian, []uint16{0, 1, 3, 1})
+	binary.Write(&cmap, binary.BigEndian, uint32(12))
+	cmap.Write(format4.Bytes())
+
+	tables := []struct {
+		tag  string
+		data []byte
+	}{
+		{"cmap", cmap.Bytes()},
+		{"glyf", glyf.Bytes()},
+		{"head", head},
+		{"hhea", hhea},
+		{"hmtx", hmtx},
+		{"loca", loca},
+		{"maxp", maxp},
+	}
+
+	var font bytes.Buffer
+	binary.Write(&font, binary.BigEndian, uint32(0x00010000))
+	binary.Write(&font, binary.BigEndian, []uint16{uint16(len(tables)), 0, 0, 0})
+	offset := 12 + len(tables)*16
+	for _, table := range tables {
+		font.WriteString(table.tag)
+		binary.Write(&font, binary.BigEndian, []uint32{0, uint32(offset), uint32(len(table.data))})
+		offset += (len(table.data) + 3) &^ 3
+	}
+	for _, table := range tables {
+		font.Write(table.data)
+		for font.Len()%4 != 0 {
+			font.WriteByte(0)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "arabic.ttf")
+	require.NoError(t, os.WriteFile(path, font.Bytes(), 0o644))
+	return path
+}
+
+func pdfTestPlan() *models.MealPlan {
+	nameAr := "مرحبا"
+	plan := &models.MealPlan{ID: 1, UserID: 1, Name: "Cutting plan", NameAr: &nameAr}
+	for day := 1; day <= 7; day++ {
+		var meals []models.PlannedMeal
+		for _, mealType := range []string{"breakfast", "lunch", "dinner"} {
+			meals = append(meals, models.PlannedMeal{
+				MealType: mealType,
+				Name:     models.BilingualText{En: "Chicken and rice", Ar: "دجاج وأرز"},
+				Items: []models.PlannedMealItem{
+					{Name: models.BilingualText{En: "Chicken breast", Ar: "صدر دجاج"}, Quantity: 150, Unit: "g", Category: "protein",
+						Nutrition: models.NutritionInfo{Calories: 248, Protein: 46.5, Fat: 5.4}},
+					{Name: models.BilingualText{En: "White rice", Ar: "أرز أبيض"}, Quantity: 1, Unit: "cup", Category: "grains",
+						Nutrition: models.NutritionInfo{Calories: 205, Protein: 4.3, Carbohydrates: 44.5, Fat: 0.4}},
+				},
+			})
+		}
+		plan.Days = append(plan.Days, models.MealPlanDay{DayNumber: day, Meals: meals})
+	}
+	plan.TotalCalories = 7 * 3 * 453
+	return plan
+}
+
+// parsedPDF indexes a rendered PDF through its cross-reference table
+type parsedPDF struct {
+	data    []byte
+	offsets []int
+}
+
+func parsePDF(t *testing.T, data []byte) *parsedPDF {
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+
+	start := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	require.NotNil(t, start, "missing startxref")
+	xref, err := strconv.Atoi(string(start[1]))
+	require.NoError(t, err)
+
+	header := regexp.MustCompile(`^xref\n0 (\d+)\n`).FindSubmatch(data[xref:])
+	require.NotNil(t, header, "startxref does not point at the xref table")
+	size, _ := strconv.Atoi(string(header[1]))
+	entries := data[xref+len(header[0]):]
+	require.GreaterOrEqual(t, len(entries), size*20)
+	assert.Equal(t, "0000000000 65535 f \n", string(entries[:20]))
+
+	pdf := &parsedPDF{data: data, offsets: make([]int, size)}
+	for id := 1; id < size; id++ {
+		entry := string(entries[id*20 : id*20+20])
+		require.Regexp(t, `^\d{10} 00000 n \n$`, entry)
+		pdf.offsets[id], _ = strconv.Atoi(entry[:10])
+	}
+	assert.Contains(t, string(entries[size*20:]), fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>", size))
+	return pdf
+}
+
+// object returns the dictionary of an object and its inflated stream, if any
+func (p *parsedPDF) object(t *testing.T, id int) (string, []byte) {
+	body := p.data[p.offsets[id]:]
+	prefix := fmt.Sprintf("%d 0 obj\n", id)
+	require.True(t, bytes.HasPrefix(body, []byte(prefix)), "xref offset of object %d is wrong", id)
+	body = body[len(prefix):]
+
+	streamAt := bytes.Index(body, []byte(">>\nstream\n"))
+	endAt := bytes.Index(body, []byte("\nendobj\n"))
+	require.NotEqual(t, -1, endAt)
+	if streamAt == -1 || streamAt > endAt {
+		return string(body[:endAt]), nil
+	}
+
+	dict := string(body[:streamAt+2])
+	length := regexp.MustCompile(`/Length (\d+)`).FindStringSubmatch(dict)
+	require.NotNil(t, length)
+	n, _ := strconv.Atoi(length[1])
+	raw := body[streamAt+len(">>\nstream\n"):]
+	require.True(t, bytes.HasPrefix(raw[n:], []byte("\nendstream\nendobj\n")), "stream length of object %d is wrong", id)
+
+	zr, err := zlib.NewReader(bytes.NewReader(raw[:n]))
+	require.NoError(t, err)
+	inflated, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return dict, inflated
+}
+
+func (p *parsedPDF) ref(t *testing.T, dict, key string) int {
+	match := regexp.MustCompile(`/` + key + ` (\d+) 0 R`).FindStringSubmatch(dict)
+	require.NotNil(t, match, "missing /%s", key)
+	id, _ := strconv.Atoi(match[1])
+	return id
+}
+
+// readFontTables parses a font's table directory, checking each table's
+// offset and checksum
+func readFontTables(t *testing.T, font []byte) map[string][]byte {
+	require.GreaterOrEqual(t, len(font), 12)
+	assert.Equal(t, uint32(0x00010000), binary.BigEndian.Uint32(font))
+	numTables := int(binary.BigEndian.Uint16(font[4:]))
+
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		rec := font[12+i*16:]
+		tag := string(rec[:4])
+		checksum := binary.BigEndian.Uint32(rec[4:])
+		offset := int(binary.BigEndian.Uint32(rec[8:]))
+		length := int(binary.BigEndian.Uint32(rec[12:]))
+		assert.Zero(t, offset%4, "table %s is not aligned", tag)
+		require.LessOrEqual(t, offset+length, len(font), "table %s is out of range", tag)
+		table := font[offset : offset+length]
+
+		var sum uint32
+		for j := 0; j < len(table); j += 4 {
+			var word [4]byte
+			copy(word[:], table[j:])
+			sum += binary.BigEndian.Uint32(word[:])
+		}
+		assert.Equal(t, checksum, sum, "checksum of table %s", tag)
+		tables[tag] = table
+	}
+	return tables
+}
+
+func TestMealPlanPDF_RequiresArabicFont(t *testing.T) {
+	_, err := services.NewMealPlanPDFRenderer("", nil).Render(pdfTestPlan())
+	assert.True(t, errors.Is(err, services.ErrMealPlanPDFFontUnavailable))
+
+	_, err = services.NewMealPlanPDFRenderer(filepath.Join(t.TempDir(), "missing.ttf"), nil).Render(pdfTestPlan())
+	assert.True(t, errors.Is(err, services.ErrMealPlanPDFFontUnavailable))
+}
+
+func TestMealPlanPDF_WellFormedWithSubsetFont(t *testing.T) {
+	data, err := services.NewMealPlanPDFRenderer(buildPDFTestFont(t), nil).Render(pdfTestPlan())
+	require.NoError(t, err)
+
+	pdf := parsePDF(t, data)
+	for id := 1; id < len(pdf.offsets); id++ {
+		pdf.object(t, id)
+	}
+
+	catalog, _ := pdf.object(t, 1)
+	pages, _ := pdf.object(t, pdf.ref(t, catalog, "Pages"))
+	count := regexp.MustCompile(`/Count (\d+)`).FindStringSubmatch(pages)
+	require.NotNil(t, count)
+	pageCount, _ := strconv.Atoi(count[1])
+	assert.Greater(t, pageCount, 1)
+
+	firstPage := regexp.MustCompile(`/Kids \[(\d+) 0 R`).FindStringSubmatch(pages)
+	require.NotNil(t, firstPage)
+	pageID, _ := strconv.Atoi(firstPage[1])
+	page, _ := pdf.object(t, pageID)
+	_, content := pdf.object(t, pdf.ref(t, page, "Contents"))
+
+	// The plan name is drawn in visual order with its contextual forms:
+	// final alef, medial beh, initial hah, final reh, initial meem
+	assert.Contains(t, string(content), "<00600061006200630064> Tj")
+
+	font, _ := pdf.object(t, pdf.ref(t, page, "FU"))
+	assert.Regexp(t, `/BaseFont /[A-Z]{6}\+EmbeddedFont `, font)
+	_, toUnicode := pdf.object(t, pdf.ref(t, font, "ToUnicode"))
+	assert.Contains(t, string(toUnicode), "<0064> <FEE3>")
+
+	descendant := regexp.MustCompile(`/DescendantFonts \[(\d+) 0 R\]`).FindStringSubmatch(font)
+	require.NotNil(t, descendant)
+	cidFontID, _ := strconv.Atoi(descendant[1])
+	cidFont, _ := pdf.object(t, cidFontID)
+	assert.NotContains(t, cidFont, fmt.Sprintf(" %d [", pdfTestUnusedGlyph))
+
+	descriptor, _ := pdf.object(t, pdf.ref(t, cidFont, "FontDescriptor"))
+	fileDict, program := pdf.object(t, pdf.ref(t, descriptor, "FontFile2"))
+	assert.Contains(t, fileDict, fmt.Sprintf("/Length1 %d", len(program)))
+
+	// The subset keeps glyph IDs, so unused glyphs are emptied rather than
+	// removed
+	tables := readFontTables(t, program)
+	var tags []string
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	assert.ElementsMatch(t, []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp"}, tags)
+	assert.Equal(t, uint16(1), binary.BigEndian.Uint16(tables["head"][50:]), "loca is rewritten with 32-bit offsets")
+	loca := tables["loca"]
+	require.Len(t, loca, (pdfTestNumGlyphs+1)*4)
+	glyphLength := func(gid int) uint32 {
+		return binary.BigEndian.Uint32(loca[gid*4+4:]) - binary.BigEndian.Uint32(loca[gid*4:])
+	}
+	assert.Zero(t, glyphLength(pdfTestUnusedGlyph))
+	assert.NotZero(t, glyphLength(pdfTestComponentGlyph))
+
+	assert.Contains(t, string(program), "GLYPH000")
+	for i := range pdfTestArabicForms {
+		assert.Contains(t, string(program), fmt.Sprintf("GLYPH%03d", pdfTestFirstArabicGlyph+i))
+	}
+	assert.Contains(t, string(program), fmt.Sprintf("GLYPH%03d", pdfTestComponentGlyph), "components of used composite glyphs are kept")
+	assert.NotContains(t, string(program), fmt.Sprintf("GLYPH%03d", pdfTestUnusedGlyph))
+}
+
+// TestTrueTypeSubset_RealFont subsets the Go Regular font (see
+// testdata/Go-Regular.LICENSE) and checks the result is still a font that
+// parses with the requested outlines intact
+func TestTrueTypeSubset_RealFont(t *testing.T) {
+	font, err := services.LoadTrueTypeFont(filepath.Join("testdata", "Go-Regular.ttf"))
+	require.NoError(t, err)
+
+	used, ok := font.GlyphIndex('é')
+	require.True(t, ok)
+	unused, ok := font.GlyphIndex('Z')
+	require.True(t, ok)
+
+	program, err := font.Subset([]int{int(used)})
+	require.NoError(t, err)
+
+	tables := readFontTables(t, program)
+	for _, tag := range []string{"OS/2", "cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "post"} {
+		assert.Contains(t, tables, tag)
+	}
+	assert.NotContains(t, tables, "GPOS", "layout tables are dropped")
+
+	// Go Regular uses 16-bit loca offsets, which the subset widens
+	assert.Equal(t, uint16(1), binary.BigEndian.Uint16(tables["head"][50:]))
+	loca, glyf := tables["loca"], tables["glyf"]
+	glyph := func(gid int) []byte {
+		start := binary.BigEndian.Uint32(loca[gid*4:])
+		end := binary.BigEndian.Uint32(loca[gid*4+4:])
+		require.LessOrEqual(t, end, uint32(len(glyf)))
+		return glyf[start:end]
+	}
+	assert.NotEmpty(t, glyph(int(used)))
+	assert.NotEmpty(t, glyph(0), ".notdef is kept")
+	assert.Empty(t, glyph(int(unused)))
+
+	subset, err := services.ParseTrueTypeFont(program)
+	require.NoError(t, err)
+	assert.Equal(t, font.PostScriptName(), subset.PostScriptName())
+	gid, ok := subset.GlyphIndex('é')
+	assert.True(t, ok)
+	assert.Equal(t, used, gid)
+	assert.Equal(t, font.Advance(used), subset.Advance(gid))
+	assert.Equal(t, font.BoundingBox(), subset.BoundingBox())
+	assert.Less(t, len(program), 148672/2)
+}
+
+func TestVisualArabic_JoiningForms(t *testing.T) {
+	// Letters join on both sides where they can; reh and alef never join
+	// the letter after them
+	assert.Equal(t, "\uFE8E\uFE92\uFEA3\uFEAE\uFEE3", services.VisualArabic("مرحبا"))
+	// Lam followed by alef becomes the lam-alef ligature
+	assert.Equal(t, "\uFEE1\uFEFC\uFEB3", services.VisualArabic("سلام"))
+	// Numbers keep their order and sit to the left of the words before them
+	assert.Equal(t, "2 \uFE94\uFE92\uFE9F\uFEED", services.VisualArabic("وجبة 2"))
+	// Harakat are dropped
+	assert.Equal(t, "\uFE8E\uFE92\uFEA3\uFEAE\uFEE3", services.VisualArabic("مَرْحَبًا"))
+	assert.Equal(t, "Day total", services.VisualArabic("Day total"))
+	// Hamza has no joined forms, so it stays isolated after a joining letter
+	assert.Equal(t, "\uFE80\uFEF2\uFEB7", services.VisualArabic("شيء"))
+	// Digits and Latin letters inside a word keep their order
+	assert.Equal(t, "\uFECD100", services.VisualArabic("100غ"))
+	assert.Equal(t, "\uFECD1.5", services.VisualArabic("1.5غ"))
+	assert.Equal(t, "B12\uFEE6\uFEF4\uFEE3\uFE8E\uFE98\uFEF4\uFED3", services.VisualArabic("فيتامينB12"))
+	assert.Equal(t, "\uFECD 100", services.VisualArabic("100 غ"))
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/models"
+	"nutrition-platform/security"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMFAService(t *testing.T, events services.SecurityEventLogger) *services.MFAService {
+	return services.NewMFAService(openRefreshTokenDB(t), config.MFAConfig{
+		Issuer:        "Nutrition Platform",
+		EncryptionKey: "test-mfa-key",
+	}, events)
+}
+
+func totpAt(t *testing.T, secret string, step int64) string {
+	code, err := security.TOTPCode(secret, step)
+	require.NoError(t, err)
+	return code
+}
+
+func TestTOTP_RFC6238Vectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to six digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := security.TOTPCode(secret, security.TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "T=%d", unix)
+	}
+
+	now := time.Unix(1234567890, 0)
+	step, ok, err := security.ValidateTOTP(secret, "005924", now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.True(t, ok, "one step of clock drift is accepted")
+	assert.Equal(t, security.TOTPStep(now), step)
+	_, ok, _ = security.ValidateTOTP(secret, "005924", now.Add(2*time.Minute))
+	assert.False(t, ok)
+
+	uri := security.TOTPProvisioningURI("Nutrition Platform", "ana@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Nutrition%20Platform:ana@example.com?"), uri)
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Nutrition+Platform")
+}
+
+func TestMFA_EnrollVerifyAndRecover(t *testing.T) {
+	events := &recordingSecurityEvents{}
+	db := openRefreshTokenDB(t)
+	service := services.NewMFAService(db, config.MFAConfig{Issuer: "Nutrition Platform", EncryptionKey: "k"}, events)
+	ctx := context.Background()
+
+	enabled, err := service.IsEnabled(ctx, "7")
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	enrollment, err := service.BeginEnrollment(ctx, "7", "ana@example.com")
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+	// The secret is stored encrypted
+	var stored string
+	require.NoError(t, db.QueryRow("SELECT secret_ciphertext FROM user_mfa WHERE user_id = $1", "7").Scan(&stored))
+	assert.NotContains(t, stored, enrollment.Secret)
+
+	// Pending enrollments do not count until confirmed
+	step := security.TOTPStep(time.Now())
+	first := totpAt(t, enrollment.Secret, step)
+	assert.ErrorIs(t, service.Verify(ctx, "7", first, ""), services.ErrMFANotEnrolled)
+	_, err = service.ConfirmEnrollment(ctx, "7", "000000")
+	assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+
+	codes, err := service.ConfirmEnrollment(ctx, "7", first)
+	require.NoError(t, err)
+	require.Len(t, codes, services.RecoveryCodeCount)
+	_, err = service.BeginEnrollment(ctx, "7", "ana@example.com")
+	assert.ErrorIs(t, err, services.ErrMFAAlreadyEnabled)
+
+	// The code that confirmed enrollment cannot be replayed; the next one works once
+	assert.ErrorIs(t, service.Verify(ctx, "7", first, ""), services.ErrInvalidMFACode)
+	next := totpAt(t, enrollment.Secret, step+1)
+	require.NoError(t, service.Verify(ctx, "7", next, ""))
+	assert.ErrorIs(t, service.Verify(ctx, "7", next, ""), services.ErrInvalidMFACode)
+
+	// Recovery codes are hashed, tolerate formatting and work once
+	var plaintext int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM mfa_recovery_codes WHERE code_hash = $1", codes[0]).Scan(&plaintext))
+	assert.Zero(t, plaintext)
+	require.NoError(t, service.Verify(ctx, "7", strings.ToUpper(strings.ReplaceAll(codes[0], "-", " ")), ""))
+	assert.ErrorIs(t, service.Verify(ctx, "7", codes[0], ""), services.ErrInvalidMFACode)
+
+	status, err := service.Status(ctx, "7", models.RoleCoach, false)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.True(t, status.Required)
+	assert.Equal(t, services.RecoveryCodeCount-1, status.RecoveryCodesRemaining)
+
+	fresh, err := service.RegenerateRecoveryCodes(ctx, "7", codes[1], "")
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.Verify(ctx, "7", codes[2], ""), services.ErrInvalidMFACode, "old codes are discarded")
+	require.NoError(t, service.Disable(ctx, "7", fresh[0], ""))
+	enabled, err = service.IsEnabled(ctx, "7")
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	assert.Len(t, events.events, 4, "each wrong code is an authentication failure")
+	assert.Equal(t, "authentication_failure", events.events[0].Type)
+}
+
+func TestMFA_LocksAfterRepeatedFailures(t *testing.T) {
+	events := &recordingSecurityEvents{}
+	service := newMFAService(t, events)
+	ctx := context.Background()
+
+	enrollment, err := service.BeginEnrollment(ctx, "7", "")
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.ProvisioningURI, "Nutrition%20Platform:7")
+	step := security.TOTPStep(time.Now())
+	_, err = service.ConfirmEnrollment(ctx, "7", totpAt(t, enrollment.Secret, step))
+	require.NoError(t, err)
+
+	for i := 0; i < services.MFAMaxFailedAttempts; i++ {
+		assert.ErrorIs(t, service.Verify(ctx, "7", "not-a-code", "203.0.113.5"), services.ErrInvalidMFACode)
+	}
+	// Even the right code is refused while locked
+	assert.ErrorIs(t, service.Verify(ctx, "7", totpAt(t, enrollment.Secret, step+1), "203.0.113.5"), services.ErrMFALocked)
+
+	require.Len(t, events.events, services.MFAMaxFailedAttempts)
+	assert.Equal(t, "203.0.113.5", events.events[0].IPAddress)
+	assert.Equal(t, "7", events.events[0].Details["user_id"])
+}
+
+func TestMFA_Policy(t *testing.T) {
+	service := services.NewMFAService(openRefreshTokenDB(t), config.MFAConfig{RequireForAdmins: true}, nil)
+	assert.True(t, service.RequiresMFA(models.RoleCoach, false))
+	assert.True(t, service.RequiresMFA(models.RoleUser, true))
+	assert.False(t, service.RequiresMFA(models.RoleUser, false))
+
+	optional := newMFAService(t, nil)
+	assert.False(t, optional.RequiresMFA(models.RoleUser, true))
+}
@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// openMigratedDB opens an in-memory SQLite database, closed when the test
+// ends, with the named files from ../migrations applied in order
+func openMigratedDB(t *testing.T, migrations ...string) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// Every connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	applyMigrations(t, db, migrations...)
+	return db
+}
+
+// applyMigrations runs the named files from ../migrations against db, for
+// fixtures that first create tables the migrations depend on
+func applyMigrations(t *testing.T, db *sql.DB, migrations ...string) {
+	for _, name := range migrations {
+		migration, err := os.ReadFile("../migrations/" + name)
+		require.NoError(t, err)
+		_, err = db.Exec(string(migration))
+		require.NoError(t, err, name)
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupNutritionLedger(t *testing.T) *sql.DB {
+	db := openMigratedDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE foods (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE recipes (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, name_ar TEXT, description TEXT, description_ar TEXT,
+			cuisine TEXT, country TEXT, difficulty_level TEXT, prep_time_minutes INTEGER,
+			cook_time_minutes INTEGER, total_time_minutes INTEGER, servings INTEGER,
+			ingredients TEXT NOT NULL DEFAULT '[]', instructions TEXT NOT NULL DEFAULT '[]',
+			nutrition_per_serving TEXT DEFAULT '{}', dietary_tags TEXT DEFAULT '[]', allergens TEXT DEFAULT '[]',
+			is_halal INTEGER DEFAULT 1, is_kosher INTEGER DEFAULT 0, image_url TEXT, video_url TEXT,
+			rating REAL DEFAULT 0, rating_count INTEGER DEFAULT 0, created_by TEXT, verified INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE user_food_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, food_id INTEGER, quantity REAL NOT NULL,
+			unit TEXT, meal_type TEXT, consumed_at DATETIME, calories REAL, protein REAL, carbs REAL,
+			fat REAL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`)
+	require.NoError(t, err)
+
+	applyMigrations(t, db,
+		"014_create_user_preferences_table.sql",
+		"016_add_food_log_units.sql",
+		"017_create_nutrition_ledger_sources.sql",
+		"020_create_meal_supplement_plan_session_tables.sql",
+		"029_add_adaptive_calorie_targets.sql",
+	)
+
+	return db
+}
+
+func TestNutritionLedger_DailyTotalsAgainstGoal(t *testing.T) {
+	db := setupNutritionLedger(t)
+	ctx := context.Background()
+
+	_, err := services.NewUserPreferencesService(db).UpdatePreferences(ctx, 1, map[string]interface{}{"timezone": "Asia/Riyadh"})
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO foods (id, name) VALUES (1, 'Oats')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO recipes (id, name, nutrition_per_serving) VALUES ('r-1', 'Lentil soup', $1)`,
+		`{"calories": 300, "protein": 18, "carbohydrates": 40, "fat": 6, "fiber": 10}`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO nutrition_goals (user_id, daily_calories, protein_grams, water_ml, is_active)
+		VALUES (1, 2000, 100, 2000, 1)`)
+	require.NoError(t, err)
+
+	// 22:30 UTC on the 16th is already the 17th in Riyadh
+	riyadh, err := time.LoadLocation("Asia/Riyadh")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_food_logs (user_id, food_id, quantity, unit, input_quantity, input_unit,
+		meal_type, consumed_at, calories, protein, carbs, fat, fiber, sugar, sodium)
+		VALUES (1, 1, 80, 'g', 80, 'g', 'breakfast', $1, 300, 10, 54, 5, 8, 1, 2)`,
+		time.Date(2026, 10, 16, 22, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	_, err = services.NewFoodLogService(db).LogRecipe(ctx, 1, services.LogRecipeInput{
+		RecipeID:   "r-1",
+		MealType:   "lunch",
+		Servings:   1.5,
+		ConsumedAt: time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO water_intake (user_id, amount_ml, date) VALUES ('1', 750, $1)`,
+		time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	ledger, err := services.NewNutritionLedgerService(db).GetDailyLedger(ctx, 1, time.Date(2026, 10, 17, 0, 0, 0, 0, riyadh))
+	require.NoError(t, err)
+
+	assert.Equal(t, "2026-10-17", ledger.Date)
+	assert.Equal(t, "Asia/Riyadh", ledger.Timezone)
+	require.Len(t, ledger.Entries, 3)
+	assert.InDelta(t, 750, ledger.Totals.Calories, 0.01)
+	assert.InDelta(t, 37, ledger.Totals.Protein, 0.01)
+	assert.Equal(t, 750, ledger.WaterMl)
+	assert.InDelta(t, 300, ledger.BySource[services.LedgerSourceFood].Calories, 0.01)
+	assert.InDelta(t, 450, ledger.BySource[services.LedgerSourceRecipe].Calories, 0.01)
+	assert.InDelta(t, 450, ledger.ByMealType["lunch"].Calories, 0.01)
+
+	assert.InDelta(t, 1250, ledger.Budgets["calories"].Remaining, 0.01)
+	assert.InDelta(t, 63, ledger.Budgets["protein"].Remaining, 0.01)
+	assert.InDelta(t, 37.5, ledger.Budgets["water_ml"].Progress, 0.01)
+	_, hasFat := ledger.Budgets["fat"]
+	assert.False(t, hasFat, "targets not set on the goal have no budget")
+
+	// The early log belongs to the 17th, so the 16th is empty
+	previous, err := services.NewNutritionLedgerService(db).GetDailyLedger(ctx, 1, time.Date(2026, 10, 16, 0, 0, 0, 0, riyadh))
+	require.NoError(t, err)
+	assert.Empty(t, previous.Entries)
+	assert.InDelta(t, 2000, previous.Budgets["calories"].Remaining, 0.01)
+}
+
+func TestNutritionLedger_RecipeWithoutNutritionIsRejected(t *testing.T) {
+	db := setupNutritionLedger(t)
+
+	_, err := db.Exec(`INSERT INTO recipes (id, name) VALUES ('r-2', 'Draft recipe')`)
+	require.NoError(t, err)
+
+	_, err = services.NewFoodLogService(db).LogRecipe(context.Background(), 1, services.LogRecipeInput{
+		RecipeID: "r-2",
+		MealType: "dinner",
+		Servings: 1,
+	})
+	assert.ErrorIs(t, err, services.ErrInvalidFoodLog)
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var resetTokenPattern = regexp.MustCompile(`token=([0-9a-f]{64})`)
+
+func setupPasswordReset(t *testing.T) (*sql.DB, *services.PasswordResetService, string) {
+	db := openMigratedDB(t)
+	_, err := db.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		updated_at DATETIME
+	)`)
+	require.NoError(t, err)
+
+	applyMigrations(t, db,
+		"015_create_password_reset_tokens_table.sql",
+		"020_create_meal_supplement_plan_session_tables.sql",
+		"023_create_refresh_tokens.sql",
+		"024_create_user_mfa.sql",
+	)
+
+	_, err = db.Exec(`INSERT INTO users (id, email, password_hash) VALUES (1, 'reset@example.com', 'old-hash')`)
+	require.NoError(t, err)
+
+	outbox := filepath.Join(t.TempDir(), "outbox.log")
+	emailConfig := config.EmailConfig{Provider: "file", OutboxPath: outbox, ResetURL: "https://app.example.com/reset"}
+	service := services.NewPasswordResetService(db, services.NewMailer(emailConfig), emailConfig)
+
+	return db, service, outbox
+}
+
+func lastResetToken(t *testing.T, outbox string) string {
+	data, err := os.ReadFile(outbox)
+	require.NoError(t, err)
+	matches := resetTokenPattern.FindAllStringSubmatch(string(data), -1)
+	require.NotEmpty(t, matches)
+	return matches[len(matches)-1][1]
+}
+
+func TestPasswordReset_UnknownEmailSendsNothing(t *testing.T) {
+	_, service, outbox := setupPasswordReset(t)
+
+	err := service.RequestReset(context.Background(), "nobody@example.com", "127.0.0.1")
+	require.NoError(t, err)
+
+	_, err = os.Stat(outbox)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPasswordReset_TokenIsSingleUse(t *testing.T) {
+	db, service, outbox := setupPasswordReset(t)
+	ctx := context.Background()
+
+	require.NoError(t, service.RequestReset(ctx, "reset@example.com", "127.0.0.1"))
+	token := lastResetToken(t, outbox)
+
+	var stored string
+	require.NoError(t, db.QueryRow("SELECT token_hash FROM password_reset_tokens").Scan(&stored))
+	assert.NotEqual(t, token, stored, "raw token must not be stored")
+
+	_, err := db.Exec(`INSERT INTO sessions (id, user_id, access_token, refresh_token, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		"session-1", "1", "access", "refresh", time.Now().Add(time.Hour).UTC())
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO refresh_tokens (id, session_id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		"token-1", "session-1", "1", "refresh-hash", time.Now().Add(time.Hour).UTC())
+	require.NoError(t, err)
+
+	require.NoError(t, service.ResetPassword(ctx, token, "new-password"))
+
+	var hash string
+	require.NoError(t, db.QueryRow("SELECT password_hash FROM users WHERE id = 1").Scan(&hash))
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")))
+
+	// Existing logins are signed out
+	var active bool
+	require.NoError(t, db.QueryRow("SELECT is_active FROM sessions WHERE id = 'session-1'").Scan(&active))
+	assert.False(t, active)
+	var revokedAt sql.NullTime
+	require.NoError(t, db.QueryRow("SELECT revoked_at FROM refresh_tokens WHERE id = 'token-1'").Scan(&revokedAt))
+	assert.True(t, revokedAt.Valid, "refresh tokens of the signed-out sessions are revoked")
+
+	err = service.ResetPassword(ctx, token, "another-password")
+	assert.Equal(t, services.ErrInvalidResetToken, err)
+}
+
+func TestPasswordReset_NewRequestInvalidatesPreviousToken(t *testing.T) {
+	_, service, outbox := setupPasswordReset(t)
+	ctx := context.Background()
+
+	require.NoError(t, service.RequestReset(ctx, "reset@example.com", "127.0.0.1"))
+	first := lastResetToken(t, outbox)
+	require.NoError(t, service.RequestReset(ctx, "reset@example.com", "127.0.0.1"))
+	second := lastResetToken(t, outbox)
+
+	assert.Equal(t, services.ErrInvalidResetToken, service.ResetPassword(ctx, first, "new-password"))
+	assert.NoError(t, service.ResetPassword(ctx, second, "new-password"))
+}
+
+func TestPasswordReset_ExpiredToken(t *testing.T) {
+	db, service, outbox := setupPasswordReset(t)
+	ctx := context.Background()
+
+	require.NoError(t, service.RequestReset(ctx, "reset@example.com", "127.0.0.1"))
+	token := lastResetToken(t, outbox)
+
+	_, err := db.Exec("UPDATE password_reset_tokens SET expires_at = $1", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	assert.Equal(t, services.ErrInvalidResetToken, service.ResetPassword(ctx, token, "new-password"))
+	assert.Equal(t, services.ErrInvalidResetToken, service.ResetPassword(ctx, "not-a-real-token", "new-password"))
+}
+
+func TestPasswordReset_FailedResetKeepsTokenAndPassword(t *testing.T) {
+	db, service, outbox := setupPasswordReset(t)
+	ctx := context.Background()
+
+	require.NoError(t, service.RequestReset(ctx, "reset@example.com", "127.0.0.1"))
+	token := lastResetToken(t, outbox)
+	_, err := db.Exec(`INSERT INTO sessions (id, user_id, access_token, refresh_token, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		"session-1", "1", "access", "refresh", time.Now().Add(time.Hour).UTC())
+	require.NoError(t, err)
+
+	// Signing out fails after the password was written: nothing is kept
+	_, err = db.Exec(`ALTER TABLE sessions RENAME TO sessions_moved`)
+	require.NoError(t, err)
+	assert.Error(t, service.ResetPassword(ctx, token, "new-password"))
+
+	var hash string
+	require.NoError(t, db.QueryRow("SELECT password_hash FROM users WHERE id = 1").Scan(&hash))
+	assert.Equal(t, "old-hash", hash)
+	var usedAt sql.NullTime
+	require.NoError(t, db.QueryRow("SELECT used_at FROM password_reset_tokens").Scan(&usedAt))
+	assert.False(t, usedAt.Valid, "token must not be burned by a failed reset")
+
+	// The same link works once the failure is gone
+	_, err = db.Exec(`ALTER TABLE sessions_moved RENAME TO sessions`)
+	require.NoError(t, err)
+	require.NoError(t, service.ResetPassword(ctx, token, "new-password"))
+	var active bool
+	require.NoError(t, db.QueryRow("SELECT is_active FROM sessions WHERE id = 'session-1'").Scan(&active))
+	assert.False(t, active)
+}
@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openPersonalRecordDB(t *testing.T) *sql.DB {
+	return openMigratedDB(t,
+		"014_create_user_preferences_table.sql",
+		"027_create_reminders_and_notifications.sql",
+		"032_create_personal_records.sql",
+	)
+}
+
+// logPRSessions detects records in two completed sessions a week apart:
+// the first sets the baselines, the second beats some of them
+func logPRSessions(t *testing.T, records *services.PersonalRecordService) ([]*models.PersonalRecord, []*models.PersonalRecord) {
+	ctx := context.Background()
+	firstDate := time.Now().UTC().AddDate(0, 0, -14)
+	first, err := records.DetectRecords(ctx, &models.UserWorkoutSession{
+		ID:            "w1",
+		UserID:        "1",
+		Status:        "completed",
+		CompletedDate: &firstDate,
+		ExercisesCompleted: []models.CompletedExercise{
+			{ExerciseID: "squat", ExerciseName: "Back Squat", RepsCompleted: []string{"5", "5", "3"}, WeightUsed: []string{"100kg", "100kg", "110kg"}},
+			{ExerciseID: "plank", ExerciseName: "Plank", RepsCompleted: []string{"45s", "60 seconds"}},
+			{ExerciseID: "run", ExerciseName: "Run", RepsCompleted: []string{"5 km 25:00"}},
+			{ExerciseName: "Push-up", RepsCompleted: []string{"20 reps"}},
+		},
+	})
+	require.NoError(t, err)
+
+	secondDate := time.Now().UTC().AddDate(0, 0, -7)
+	second, err := records.DetectRecords(ctx, &models.UserWorkoutSession{
+		ID:            "w2",
+		UserID:        "1",
+		Status:        "completed",
+		CompletedDate: &secondDate,
+		ExercisesCompleted: []models.CompletedExercise{
+			{ExerciseID: "squat", ExerciseName: "Back Squat", RepsCompleted: []string{"6", "5"}, WeightUsed: []string{"100kg", "225 lb"}},
+			{ExerciseID: "plank", ExerciseName: "Plank", RepsCompleted: []string{"1:30"}},
+			{ExerciseID: "run", ExerciseName: "Run", RepsCompleted: []string{"5km in 24:30"}},
+			{ExerciseName: "Push-up", RepsCompleted: []string{"18"}},
+		},
+	})
+	require.NoError(t, err)
+	return first, second
+}
+
+func findRecord(records []*models.PersonalRecord, exerciseID string, recordType models.PersonalRecordType) *models.PersonalRecord {
+	for _, record := range records {
+		if record.ExerciseID == exerciseID && record.RecordType == string(recordType) {
+			return record
+		}
+	}
+	return nil
+}
+
+func TestPersonalRecords_DetectedFromCompletedSessions(t *testing.T) {
+	db := openPersonalRecordDB(t)
+	ctx := context.Background()
+	notifications := services.NewNotificationService(db)
+	records := services.NewPersonalRecordService(db, notifications)
+
+	first, second := logPRSessions(t, records)
+
+	// Squat: heaviest weight, estimated 1RM and a rep-max at each weight;
+	// plank: longest hold; run: time over 5 km and distance; push-ups are
+	// keyed by name
+	require.Len(t, first, 8)
+	oneRepMax := findRecord(first, "squat", models.RecordEstimated1RM)
+	require.NotNil(t, oneRepMax)
+	// Brzycki: 110 kg for 3 beats 100 kg for 5
+	assert.Equal(t, 116.5, oneRepMax.Value)
+	assert.Equal(t, 3, *oneRepMax.Reps)
+	assert.Equal(t, 110.0, findRecord(first, "squat", models.RecordWeight).Value)
+	assert.Equal(t, 60.0, findRecord(first, "plank", models.RecordTime).Value)
+	run := findRecord(first, "run", models.RecordTime)
+	assert.Equal(t, 1500.0, run.Value)
+	assert.Equal(t, 5000.0, *run.Distance)
+	assert.Equal(t, 20.0, findRecord(first, "push-up", models.RecordReps).Value)
+	for _, record := range first {
+		assert.Nil(t, record.PreviousValue)
+		assert.Equal(t, "w1", *record.WorkoutSessionID)
+	}
+
+	// More reps at 100 kg, a first set at 225 lb, a faster 5 km and a longer
+	// hold; the lighter estimated 1RMs and fewer push-ups are no records
+	require.Len(t, second, 4)
+	beaten := 0
+	for _, record := range second {
+		if record.PreviousValue != nil {
+			beaten++
+		}
+	}
+	assert.Equal(t, 3, beaten)
+	faster := findRecord(second, "run", models.RecordTime)
+	require.NotNil(t, faster)
+	assert.Equal(t, 1470.0, faster.Value)
+	assert.Equal(t, 1500.0, *faster.PreviousValue)
+	assert.Equal(t, 90.0, findRecord(second, "plank", models.RecordTime).Value)
+	assert.Nil(t, findRecord(second, "squat", models.RecordEstimated1RM))
+	assert.Nil(t, findRecord(second, "push-up", models.RecordReps))
+
+	// Only beaten records are announced
+	inbox, err := notifications.ListNotifications(ctx, "1", false, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, inbox, 3)
+	bodies := []string{}
+	for _, notification := range inbox {
+		assert.Equal(t, models.NotificationTypePersonalRecord, notification.Type)
+		bodies = append(bodies, notification.Body)
+	}
+	assert.Contains(t, bodies, "5000 m in 24:30, down from 25:00")
+	assert.Contains(t, bodies, "6 reps at 100 kg, up from 5")
+
+	// Sessions that were not completed set nothing
+	none, err := records.DetectRecords(ctx, &models.UserWorkoutSession{
+		UserID:             "1",
+		Status:             "partial",
+		ExercisesCompleted: []models.CompletedExercise{{ExerciseID: "squat", RepsCompleted: []string{"1"}, WeightUsed: []string{"200kg"}}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestPersonalRecords_HistoryAndStats(t *testing.T) {
+	db := openPersonalRecordDB(t)
+	ctx := context.Background()
+	records := services.NewPersonalRecordService(db, nil)
+	logPRSessions(t, records)
+
+	squat := "squat"
+	reps := string(models.RecordReps)
+	page, err := records.ListRecords(ctx, "1", models.ListPersonalRecordsRequest{ExerciseID: &squat, RecordType: &reps, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 4, page.Total)
+	assert.True(t, page.HasNext)
+	require.Len(t, page.Records, 3)
+	// Newest first
+	assert.Equal(t, "w2", *page.Records[0].WorkoutSessionID)
+	assert.Equal(t, "w1", *page.Records[2].WorkoutSessionID)
+
+	invalid := "speed"
+	_, err = records.ListRecords(ctx, "1", models.ListPersonalRecordsRequest{RecordType: &invalid})
+	assert.True(t, errors.Is(err, services.ErrInvalidPersonalRecordRequest))
+
+	stats, err := records.GetStats(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 12, stats.TotalRecords)
+	assert.Equal(t, 5, stats.RecordsByType[reps])
+	assert.Len(t, stats.RecentAchievements, 3)
+	assert.Equal(t, services.PersonalRecordTrendImproving, stats.ProgressTrend)
+	// Estimated 1RM, weight, and rep-maxes at 100 kg, 102.06 kg and 110 kg
+	assert.Len(t, stats.RecordsByExercise["squat"], 5)
+
+	// Deleting a workout brings back the bests from before it
+	require.NoError(t, records.DeleteSessionRecords(ctx, "1", "w2"))
+	stats, err = records.GetStats(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 8, stats.TotalRecords)
+	for _, best := range stats.RecordsByExercise["plank"] {
+		assert.Equal(t, 60.0, best.Value)
+	}
+	assert.Equal(t, services.PersonalRecordTrendSteady, stats.ProgressTrend)
+}
+
+func TestPersonalRecords_RedetectedAfterEdit(t *testing.T) {
+	db := openPersonalRecordDB(t)
+	ctx := context.Background()
+	notifications := services.NewNotificationService(db)
+	records := services.NewPersonalRecordService(db, notifications)
+	logPRSessions(t, records)
+
+	inboxSize := func() int {
+		inbox, err := notifications.ListNotifications(ctx, "1", false, 20, 0)
+		require.NoError(t, err)
+		return len(inbox)
+	}
+	require.Equal(t, 3, inboxSize())
+
+	secondDate := time.Now().UTC().AddDate(0, 0, -7)
+	edited := &models.UserWorkoutSession{
+		ID:            "w2",
+		UserID:        "1",
+		Status:        "completed",
+		CompletedDate: &secondDate,
+		ExercisesCompleted: []models.CompletedExercise{
+			{ExerciseID: "squat", ExerciseName: "Back Squat", RepsCompleted: []string{"6", "5"}, WeightUsed: []string{"100kg", "225 lb"}},
+			{ExerciseID: "plank", ExerciseName: "Plank", RepsCompleted: []string{"50s"}},
+			{ExerciseID: "run", ExerciseName: "Run", RepsCompleted: []string{"5km in 24:30"}},
+		},
+	}
+
+	// The corrected plank no longer beats 60 s; the other records stay
+	// and are not announced again
+	redetected, err := records.RedetectRecords(ctx, edited)
+	require.NoError(t, err)
+	assert.Len(t, redetected, 3)
+	assert.Nil(t, findRecord(redetected, "plank", models.RecordTime))
+	assert.Equal(t, 1470.0, findRecord(redetected, "run", models.RecordTime).Value)
+	assert.Equal(t, 3, inboxSize())
+	stats, err := records.GetStats(ctx, "1")
+	require.NoError(t, err)
+	for _, best := range stats.RecordsByExercise["plank"] {
+		assert.Equal(t, 60.0, best.Value)
+	}
+
+	// A new best added in the edit is announced
+	edited.ExercisesCompleted[1].RepsCompleted = []string{"2:00"}
+	redetected, err = records.RedetectRecords(ctx, edited)
+	require.NoError(t, err)
+	assert.Equal(t, 120.0, findRecord(redetected, "plank", models.RecordTime).Value)
+	assert.Equal(t, 4, inboxSize())
+
+	// A session no longer completed holds no records
+	edited.Status = "partial"
+	redetected, err = records.RedetectRecords(ctx, edited)
+	require.NoError(t, err)
+	assert.Empty(t, redetected)
+	stats, err = records.GetStats(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 8, stats.TotalRecords)
+}
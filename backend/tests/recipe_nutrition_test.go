@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIngredientLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		quantity float64
+		unit     string
+		name     string
+	}{
+		{"2 cups cooked rice", 2, services.UnitCup, "cooked rice"},
+		{"150 g chicken breast", 150, services.UnitGram, "chicken breast"},
+		{"150g chicken breast, diced", 150, services.UnitGram, "chicken breast"},
+		{"1 1/2 tbsp olive oil", 1.5, services.UnitTablespoon, "olive oil"},
+		{"½ cup of milk", 0.5, services.UnitCup, "milk"},
+		{"2-3 eggs", 2.5, services.UnitPiece, "eggs"},
+		{"- 1.5 kg potatoes (peeled)", 1.5, services.UnitKilogram, "potatoes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			parsed, err := services.ParseIngredientLine(tt.line)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.quantity, parsed.Quantity, 0.001)
+			assert.Equal(t, tt.unit, parsed.Unit)
+			assert.Equal(t, tt.name, parsed.Name)
+		})
+	}
+
+	_, err := services.ParseIngredientLine("salt to taste")
+	assert.Error(t, err)
+	_, err = services.ParseIngredientLine("200 g")
+	assert.Error(t, err)
+}
+
+func setupRecipeNutrition(t *testing.T) *sql.DB {
+	db := openMigratedDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE foods (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, name TEXT NOT NULL, brand TEXT,
+			description TEXT, bar_code TEXT, serving_size TEXT, calories REAL, protein REAL, carbs REAL,
+			fat REAL, saturated_fat REAL, fiber REAL, sugar REAL, sodium INTEGER, cholesterol REAL,
+			potassium REAL, source_type TEXT, verified BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE recipes (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, name_ar TEXT, description TEXT, description_ar TEXT,
+			cuisine TEXT, country TEXT, difficulty_level TEXT, prep_time_minutes INTEGER,
+			cook_time_minutes INTEGER, total_time_minutes INTEGER, servings INTEGER,
+			ingredients TEXT NOT NULL DEFAULT '[]', instructions TEXT NOT NULL DEFAULT '[]',
+			nutrition_per_serving TEXT DEFAULT '{}', dietary_tags TEXT DEFAULT '[]', allergens TEXT DEFAULT '[]',
+			is_halal INTEGER DEFAULT 1, is_kosher INTEGER DEFAULT 0, image_url TEXT, video_url TEXT,
+			rating REAL DEFAULT 0, rating_count INTEGER DEFAULT 0, created_by TEXT, verified INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE user_food_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, food_id INTEGER, quantity REAL NOT NULL,
+			unit TEXT, meal_type TEXT, consumed_at DATETIME, calories REAL, protein REAL, carbs REAL,
+			fat REAL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`)
+	require.NoError(t, err)
+
+	applyMigrations(t, db, "016_add_food_log_units.sql")
+
+	// Nutrients per 100g
+	_, err = db.Exec(`INSERT INTO foods (id, user_id, name, serving_size, calories, protein, carbs, fat, saturated_fat,
+		fiber, sugar, sodium, cholesterol, potassium, source_type, verified) VALUES
+		(1, NULL, 'Rice, white, cooked', '1 cup', 130, 2.7, 28, 0.3, 0, 0.4, 0, 1, 0, 35, 'global', 1),
+		(2, NULL, 'Chicken breast, grilled', '100 g', 165, 31, 0, 3.6, 1, 0, 0, 74, 85, 256, 'global', 1),
+		(3, NULL, 'Egg, whole', '1 large', 143, 12.6, 0.7, 9.5, 3.1, 0, 0.4, 142, 372, 138, 'global', 1),
+		(4, NULL, 'Chicken nuggets', '6 pieces', 296, 15, 16, 19, 3, 1, 0, 550, 45, 270, 'global', 0)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO food_portions (food_id, unit, grams) VALUES (1, 'cup', 158), (3, 'piece', 50)`)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestRecipeNutrition_CreateComputesPerServing(t *testing.T) {
+	db := setupRecipeNutrition(t)
+	ctx := context.Background()
+	recipes := services.NewRecipeService(db)
+
+	servings := 2
+	authorMacros := &models.NutritionInfo{Calories: 9999}
+	recipe, report, err := recipes.CreateRecipe(ctx, "7", models.CreateRecipeRequest{
+		Name:     "Chicken rice bowl",
+		Servings: &servings,
+		IngredientLines: []string{
+			"2 cups cooked rice",
+			"150 g chicken breast, sliced",
+			"2 eggs",
+			"1 pinch of saffron",
+		},
+		Ingredients:         []models.RecipeIngredient{{Name: "sesame seeds", Amount: 1, Unit: "tbsp", Optional: true}},
+		NutritionPerServing: authorMacros,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, recipe.ID)
+	assert.Len(t, recipe.Ingredients, 5)
+	assert.Equal(t, "sliced", recipe.Ingredients[2].Preparation)
+
+	require.Len(t, report.Matched, 3)
+	assert.Equal(t, uint(1), report.Matched[0].FoodID)
+	assert.InDelta(t, 316, report.Matched[0].Grams, 0.01)
+	assert.Equal(t, uint(2), report.Matched[1].FoodID)
+	assert.Equal(t, uint(3), report.Matched[2].FoodID)
+	assert.InDelta(t, 100, report.Matched[2].Grams, 0.01)
+
+	// saffron is unknown and the optional sesame seeds are not counted
+	require.Len(t, report.Unmatched, 2)
+	assert.False(t, report.Complete)
+
+	// 316g rice + 150g chicken + 100g egg, split over two servings
+	expected := (130*3.16 + 165*1.5 + 143) / 2
+	assert.InDelta(t, expected, report.PerServing.Calories, 0.1)
+	require.NotNil(t, recipe.NutritionPerServing)
+	assert.InDelta(t, expected, recipe.NutritionPerServing.Calories, 0.1)
+
+	stored, err := recipes.GetRecipe(ctx, recipe.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.NutritionPerServing)
+	assert.InDelta(t, expected, stored.NutritionPerServing.Calories, 0.1)
+}
+
+func TestRecipeNutrition_UpdateRecomputes(t *testing.T) {
+	db := setupRecipeNutrition(t)
+	ctx := context.Background()
+	recipes := services.NewRecipeService(db)
+
+	recipe, _, err := recipes.CreateRecipe(ctx, "7", models.CreateRecipeRequest{
+		Name:            "Grilled chicken",
+		IngredientLines: []string{"200 g chicken breast"},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 330, recipe.NutritionPerServing.Calories, 0.1)
+
+	servings := 4
+	updated, report, err := recipes.UpdateRecipe(ctx, "7", recipe.ID, models.UpdateRecipeRequest{Servings: &servings})
+	require.NoError(t, err)
+	assert.True(t, report.Complete)
+	assert.InDelta(t, 82.5, updated.NutritionPerServing.Calories, 0.1)
+
+	// Only unknown ingredients leave the recipe without nutrition
+	updated, report, err = recipes.UpdateRecipe(ctx, "7", recipe.ID, models.UpdateRecipeRequest{
+		IngredientLines: []string{"3 dragon fruits"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, report.Matched)
+	assert.Nil(t, updated.NutritionPerServing)
+
+	// Other users cannot edit the recipe
+	_, _, err = recipes.UpdateRecipe(ctx, "8", recipe.ID, models.UpdateRecipeRequest{Servings: &servings})
+	require.Error(t, err)
+	assert.Equal(t, "recipe not found", err.Error())
+
+	_, _, err = recipes.CreateRecipe(ctx, "7", models.CreateRecipeRequest{Name: "Empty"})
+	assert.ErrorIs(t, err, services.ErrInvalidRecipe)
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	apperrors "nutrition-platform/errors"
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSecurityEvents struct {
+	mu     sync.Mutex
+	events []*apperrors.SecurityEvent
+}
+
+func (r *recordingSecurityEvents) LogSecurityEvent(event *apperrors.SecurityEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func openRefreshTokenDB(t *testing.T) *sql.DB {
+	return openMigratedDB(t,
+		"020_create_meal_supplement_plan_session_tables.sql",
+		"023_create_refresh_tokens.sql",
+		"024_create_user_mfa.sql",
+	)
+}
+
+func newRefreshTokenService(t *testing.T, events services.SecurityEventLogger) *services.RefreshTokenService {
+	return services.NewRefreshTokenService(openRefreshTokenDB(t), events)
+}
+
+func accessTokenFor(token string) func(*services.Session) (string, error) {
+	return func(session *services.Session) (string, error) { return token + "-" + session.UserID, nil }
+}
+
+func TestRefreshTokens_RotateAndStoreOnlyHashes(t *testing.T) {
+	db := openRefreshTokenDB(t)
+	service := services.NewRefreshTokenService(db, nil)
+	ctx := context.Background()
+
+	session, first, err := service.StartSession(ctx, "7", "access-1", "phone", "10.0.0.1", "test", false)
+	require.NoError(t, err)
+	assert.Len(t, first, 64)
+
+	rotated, second, err := service.Rotate(ctx, first, "10.0.0.1", "test", accessTokenFor("access-2"))
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, session.ID, rotated.ID)
+	assert.Equal(t, "access-2-7", rotated.AccessToken)
+
+	_, third, err := service.Rotate(ctx, second, "10.0.0.1", "test", accessTokenFor("access-3"))
+	require.NoError(t, err)
+
+	// Neither table holds a raw token
+	var stored string
+	require.NoError(t, db.QueryRow("SELECT refresh_token FROM sessions WHERE id = $1", session.ID).Scan(&stored))
+	assert.NotEqual(t, third, stored)
+	assert.Len(t, stored, 64)
+	var raw int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM refresh_tokens WHERE token_hash IN ($1, $2, $3)", first, second, third).Scan(&raw))
+	assert.Zero(t, raw)
+	var family int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM refresh_tokens WHERE session_id = $1", session.ID).Scan(&family))
+	assert.Equal(t, 3, family)
+
+	_, _, err = service.Rotate(ctx, "not-a-token", "10.0.0.1", "test", accessTokenFor("x"))
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+}
+
+func TestRefreshTokens_ReuseRevokesFamily(t *testing.T) {
+	events := &recordingSecurityEvents{}
+	service := newRefreshTokenService(t, events)
+	ctx := context.Background()
+
+	stolen, first, err := service.StartSession(ctx, "7", "access-1", "", "10.0.0.1", "test", false)
+	require.NoError(t, err)
+	other, otherToken, err := service.StartSession(ctx, "7", "access-other", "", "10.0.0.1", "test", false)
+	require.NoError(t, err)
+	_, second, err := service.Rotate(ctx, first, "10.0.0.1", "test", accessTokenFor("access-2"))
+	require.NoError(t, err)
+
+	// The attacker replays the first token
+	_, _, err = service.Rotate(ctx, first, "203.0.113.5", "curl", accessTokenFor("evil"))
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+
+	// The legitimate successor is revoked with it
+	_, _, err = service.Rotate(ctx, second, "10.0.0.1", "test", accessTokenFor("access-3"))
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+
+	require.Len(t, events.events, 1)
+	event := events.events[0]
+	assert.Equal(t, "refresh_token_reuse", event.Type)
+	assert.Equal(t, "high", event.Severity)
+	assert.Equal(t, "203.0.113.5", event.IPAddress)
+	assert.Equal(t, stolen.ID, event.Details["session_id"])
+	assert.Equal(t, "7", event.Details["user_id"])
+
+	// Other sessions of the same user are untouched
+	rotated, _, err := service.Rotate(ctx, otherToken, "10.0.0.1", "test", accessTokenFor("access-other-2"))
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, rotated.ID)
+}
+
+func TestRefreshTokens_ConcurrentRotationAllowsOneWinner(t *testing.T) {
+	events := &recordingSecurityEvents{}
+	service := newRefreshTokenService(t, events)
+	ctx := context.Background()
+
+	_, token, err := service.StartSession(ctx, "7", "access-1", "", "10.0.0.1", "test", false)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = service.Rotate(ctx, token, "10.0.0.1", "test", accessTokenFor("access-2"))
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.NotEmpty(t, events.events)
+}
+
+func TestRefreshTokens_RevokeSignsOut(t *testing.T) {
+	service := newRefreshTokenService(t, nil)
+	ctx := context.Background()
+
+	_, token, err := service.StartSession(ctx, "7", "access-1", "", "10.0.0.1", "test", false)
+	require.NoError(t, err)
+	require.NoError(t, service.Revoke(ctx, token))
+	require.NoError(t, service.Revoke(ctx, "unknown"))
+
+	_, _, err = service.Rotate(ctx, token, "10.0.0.1", "test", accessTokenFor("access-2"))
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+}
+
+func TestUserService_AccountForRefreshedTokens(t *testing.T) {
+	db := openMigratedDB(t, "001_initial_schema_sqlite.sql", "033_add_user_roles.sql")
+	users := services.NewUserService(db)
+	ctx := context.Background()
+
+	_, err := db.Exec(`INSERT INTO users (id, username, email, password_hash, first_name, role, is_admin)
+		VALUES ('user-1', 'ada', 'ada@example.com', 'hash', 'Ada', 'coach', 1),
+		       ('user-2', 'bob', 'bob@example.com', 'hash', NULL, 'user', 0)`)
+	require.NoError(t, err)
+
+	account, err := users.GetAccount(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.UserAccount{
+		ID: "user-1", Email: "ada@example.com", FirstName: "Ada", Role: "coach", IsAdmin: true,
+	}, account)
+
+	account, err = users.GetAccount(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, "user", account.Role)
+	assert.False(t, account.IsAdmin)
+
+	// Deactivated accounts cannot refresh their tokens
+	_, err = db.Exec(`UPDATE users SET is_active = 0 WHERE id = 'user-2'`)
+	require.NoError(t, err)
+	_, err = users.GetAccount(ctx, "user-2")
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+}
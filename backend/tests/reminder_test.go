@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openReminderDB(t *testing.T) *sql.DB {
+	return openMigratedDB(t,
+		"001_initial_schema_sqlite.sql",
+		"014_create_user_preferences_table.sql",
+		"020_create_meal_supplement_plan_session_tables.sql",
+		"027_create_reminders_and_notifications.sql",
+	)
+}
+
+func newReminderServices(db *sql.DB) (*services.ReminderService, *services.NotificationService) {
+	notifications := services.NewNotificationService(db)
+	return services.NewReminderService(db, notifications, config.ReminderConfig{}), notifications
+}
+
+// recordingChannel is a notification channel that keeps what it is sent
+type recordingChannel struct {
+	name string
+	err  error
+
+	mu   sync.Mutex
+	sent []*models.Notification
+}
+
+func (c *recordingChannel) Name() string { return c.name }
+
+func (c *recordingChannel) Send(ctx context.Context, notification *models.Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, notification)
+	return c.err
+}
+
+func (c *recordingChannel) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sent)
+}
+
+func setPreferences(t *testing.T, db *sql.DB, userID int, patch map[string]interface{}) {
+	_, err := services.NewUserPreferencesService(db).UpdatePreferences(context.Background(), userID, patch)
+	require.NoError(t, err)
+}
+
+func TestReminders_TimezoneAndQuietHours(t *testing.T) {
+	db := openReminderDB(t)
+	reminders, notifications := newReminderServices(db)
+	ctx := context.Background()
+
+	setPreferences(t, db, 7, map[string]interface{}{
+		"timezone":          "America/New_York",
+		"quiet_hours_start": "22:00",
+		"quiet_hours_end":   "07:00",
+	})
+
+	// 23:30 and 02:00 fall in the quiet hours and are both held until 07:00
+	reminder, err := reminders.CreateReminder(ctx, "7", models.CreateReminderRequest{
+		Type:  models.ReminderWater,
+		Times: []string{"23:30", "12:00", "2:00", "12:00"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"02:00", "12:00", "23:30"}, reminder.Times)
+	assert.Equal(t, "Time to drink some water", reminder.Title)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, newYork)
+	to := from.Add(24 * time.Hour)
+
+	sent, err := reminders.DispatchDue(ctx, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	inbox, err := notifications.ListNotifications(ctx, "7", false, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, inbox, 2)
+	var scheduled []string
+	for _, n := range inbox {
+		assert.Equal(t, models.NotificationTypeReminder, n.Type)
+		assert.Equal(t, reminder.ID, n.Data["reminder_id"])
+		scheduled = append(scheduled, n.Data["scheduled_for"].(string))
+	}
+	assert.ElementsMatch(t, []string{"2026-03-02T07:00:00-05:00", "2026-03-02T12:00:00-05:00"}, scheduled)
+
+	// Overlapping runs do not send an occurrence twice
+	sent, err = reminders.DispatchDue(ctx, from.Add(-time.Hour), to)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	// Switching the reminder type off stops it
+	setPreferences(t, db, 7, map[string]interface{}{"water_reminders": false})
+	sent, err = reminders.DispatchDue(ctx, to, to.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+}
+
+func TestReminders_MedicationAndSupplementSchedules(t *testing.T) {
+	db := openReminderDB(t)
+	reminders, notifications := newReminderServices(db)
+	ctx := context.Background()
+
+	_, err := db.Exec(`INSERT INTO user_medications (id, user_id, custom_medication_name, dosage, frequency, administration_time, is_active)
+		VALUES ('med-1', '8', 'Metformin', '500mg', 'twice daily', '["08:00", "20:00"]', 1),
+		       ('med-2', '8', 'Old prescription', '5mg', 'daily', '["09:00"]', 0)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO supplements (id, user_id, name, dosage, timing, is_active)
+		VALUES ('sup-1', '8', 'Vitamin D', '1000 IU', 'Morning, with breakfast', TRUE),
+		       ('sup-2', '8', 'Magnesium', '200mg', 'as needed', TRUE)`)
+	require.NoError(t, err)
+	setPreferences(t, db, 8, map[string]interface{}{"quiet_hours_start": "00:00", "quiet_hours_end": "00:00"})
+
+	listed, err := reminders.ListReminders(ctx, "8")
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, models.ReminderSourceMedication, listed[0].Source)
+	assert.Equal(t, "med-1", listed[0].SourceID)
+	assert.Equal(t, "Time to take Metformin", listed[0].Title)
+	assert.Equal(t, []string{"08:00", "20:00"}, listed[0].Times)
+	assert.Equal(t, models.ReminderSourceSupplement, listed[1].Source)
+	assert.Equal(t, []string{"08:00"}, listed[1].Times)
+
+	// Derived reminders are not edited through the reminder API
+	_, err = reminders.UpdateReminder(ctx, "8", listed[0].ID, models.UpdateReminderRequest{})
+	require.Error(t, err)
+	assert.Equal(t, "reminder not found", err.Error())
+
+	from := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	sent, err := reminders.DispatchDue(ctx, from, from.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 3, sent)
+
+	setPreferences(t, db, 8, map[string]interface{}{"supplement_reminders": false})
+	sent, err = reminders.DispatchDue(ctx, from.Add(24*time.Hour), from.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	unread, err := notifications.UnreadCount(ctx, "8")
+	require.NoError(t, err)
+	assert.Equal(t, 5, unread)
+}
+
+func TestReminders_DaysOfWeekAndValidation(t *testing.T) {
+	db := openReminderDB(t)
+	reminders, _ := newReminderServices(db)
+	ctx := context.Background()
+
+	for _, req := range []models.CreateReminderRequest{
+		{Type: "snack", Times: []string{"10:00"}},
+		{Type: models.ReminderMeal},
+		{Type: models.ReminderMeal, Times: []string{"25:00"}},
+		{Type: models.ReminderMeal, Times: []string{"10:00"}, DaysOfWeek: []int{7}},
+	} {
+		_, err := reminders.CreateReminder(ctx, "9", req)
+		assert.ErrorIs(t, err, services.ErrInvalidReminderRequest, req)
+	}
+
+	// Weekly weigh-in on Mondays; quiet hours do not cover 07:30
+	reminder, err := reminders.CreateReminder(ctx, "9", models.CreateReminderRequest{
+		Type:       models.ReminderWeighIn,
+		Times:      []string{"07:30"},
+		DaysOfWeek: []int{1, 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, reminder.DaysOfWeek)
+
+	// 2026-05-04 is a Monday
+	monday := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	sent, err := reminders.DispatchDue(ctx, monday, monday.Add(7*24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	inactive := false
+	_, err = reminders.UpdateReminder(ctx, "9", reminder.ID, models.UpdateReminderRequest{Active: &inactive})
+	require.NoError(t, err)
+	sent, err = reminders.DispatchDue(ctx, monday.Add(7*24*time.Hour), monday.Add(14*24*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	require.NoError(t, reminders.DeleteReminder(ctx, "9", reminder.ID))
+	assert.Error(t, reminders.DeleteReminder(ctx, "9", reminder.ID))
+}
+
+func TestNotifications_ChannelsAndInbox(t *testing.T) {
+	db := openReminderDB(t)
+	notifications := services.NewNotificationService(db)
+	ctx := context.Background()
+
+	email := &recordingChannel{name: services.NotificationChannelEmail}
+	push := &recordingChannel{name: services.NotificationChannelPush, err: errors.New("device unreachable")}
+	notifications.RegisterChannel(email)
+	notifications.RegisterChannel(push)
+
+	// A failing channel does not stop the inbox or other channels
+	err := notifications.Notify(ctx, &models.Notification{UserID: "10", Type: "test", Title: "First"})
+	assert.ErrorContains(t, err, "device unreachable")
+	assert.Equal(t, 1, email.count())
+	assert.Equal(t, 1, push.count())
+
+	setPreferences(t, db, 10, map[string]interface{}{"email_notifications": false, "push_notifications": false})
+	require.NoError(t, notifications.Notify(ctx, &models.Notification{UserID: "10", Type: "test", Title: "Second"}))
+	assert.Equal(t, 1, email.count())
+	assert.Equal(t, 1, push.count())
+
+	setPreferences(t, db, 10, map[string]interface{}{"notifications_enabled": false})
+	require.NoError(t, notifications.Notify(ctx, &models.Notification{UserID: "10", Type: "test", Title: "Muted"}))
+
+	inbox, err := notifications.ListNotifications(ctx, "10", false, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, inbox, 2)
+
+	require.NoError(t, notifications.MarkRead(ctx, "10", inbox[0].ID))
+	assert.Error(t, notifications.MarkRead(ctx, "11", inbox[1].ID))
+	unread, err := notifications.ListNotifications(ctx, "10", true, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, unread, 1)
+	assert.Equal(t, inbox[1].ID, unread[0].ID)
+
+	marked, err := notifications.MarkAllRead(ctx, "10")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+	count, err := notifications.UnreadCount(ctx, "10")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	require.NoError(t, notifications.DeleteNotification(ctx, "10", inbox[0].ID))
+	assert.Error(t, notifications.DeleteNotification(ctx, "10", inbox[0].ID))
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/repositories"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupShoppingLists(t *testing.T) *sql.DB {
+	db := openMigratedDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE recipes (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, name_ar TEXT, description TEXT, description_ar TEXT,
+			cuisine TEXT, country TEXT, difficulty_level TEXT, prep_time_minutes INTEGER,
+			cook_time_minutes INTEGER, total_time_minutes INTEGER, servings INTEGER,
+			ingredients TEXT NOT NULL DEFAULT '[]', instructions TEXT NOT NULL DEFAULT '[]',
+			nutrition_per_serving TEXT DEFAULT '{}', dietary_tags TEXT DEFAULT '[]', allergens TEXT DEFAULT '[]',
+			is_halal INTEGER DEFAULT 1, is_kosher INTEGER DEFAULT 0, image_url TEXT, video_url TEXT,
+			rating REAL DEFAULT 0, rating_count INTEGER DEFAULT 0, created_by TEXT, verified INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE meal_plans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, name TEXT NOT NULL, name_ar TEXT,
+			description TEXT, start_date TEXT, end_date TEXT, days TEXT NOT NULL DEFAULT '[]',
+			total_calories REAL, total_protein REAL, total_carbs REAL, total_fat REAL, is_active BOOLEAN,
+			created_at DATETIME, updated_at DATETIME
+		);`)
+	require.NoError(t, err)
+
+	applyMigrations(t, db, "019_create_shopping_lists.sql")
+
+	insertRecipe := func(id, name string, servings int, ingredients []models.RecipeIngredient) {
+		encoded, err := json.Marshal(ingredients)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO recipes (id, name, servings, ingredients) VALUES ($1, $2, $3, $4)`,
+			id, name, servings, string(encoded))
+		require.NoError(t, err)
+	}
+
+	insertRecipe("r-kabsa", "Chicken kabsa", 4, []models.RecipeIngredient{
+		{Name: "chicken thighs", Amount: 1, Unit: "kg"},
+		{Name: "basmati rice", Amount: 2, Unit: "cups"},
+		{Name: "onions", Amount: 2},
+		{Name: "black pepper", Amount: 1, Unit: "tsp"},
+		{Name: "salt to taste"},
+	})
+	insertRecipe("r-salad", "Fattoush", 2, []models.RecipeIngredient{
+		{Name: "1 onion, sliced"},
+		{Name: "tomatoes", Amount: 300, Unit: "g"},
+		{Name: "olive oil", Amount: 2, Unit: "tbsp"},
+		{Name: "salt", Amount: 0.5, Unit: "tsp"},
+		{Name: "pomegranate seeds", Amount: 50, Unit: "g", Optional: true},
+	})
+
+	return db
+}
+
+func insertShoppingMealPlan(t *testing.T, db *sql.DB, userID int, start time.Time, active bool) int {
+	kabsa, salad := "r-kabsa", "r-salad"
+	yogurt := uint(9)
+	var days []models.MealPlanDay
+	for i := 0; i < 3; i++ {
+		days = append(days, models.MealPlanDay{
+			DayNumber: i + 1,
+			Meals: []models.PlannedMeal{
+				{
+					MealType: "lunch",
+					Name:     models.BilingualText{En: "Chicken kabsa"},
+					RecipeID: &kabsa,
+					Items:    []models.PlannedMealItem{{Name: models.BilingualText{En: "Chicken kabsa"}, Quantity: 1, Unit: "serving"}},
+				},
+				{
+					MealType: "dinner",
+					Name:     models.BilingualText{En: "Fattoush"},
+					RecipeID: &salad,
+					Items: []models.PlannedMealItem{
+						{Name: models.BilingualText{En: "Fattoush"}, Quantity: 1, Unit: "serving"},
+						{FoodID: &yogurt, Name: models.BilingualText{En: "Greek yogurt"}, Quantity: 150, Unit: "g", Category: "side"},
+					},
+				},
+			},
+		})
+	}
+	encoded, err := json.Marshal(days)
+	require.NoError(t, err)
+
+	result, err := db.Exec(`INSERT INTO meal_plans (user_id, name, start_date, end_date, days, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		userID, "Three day plan", start.Format("2006-01-02"), start.AddDate(0, 0, 2).Format("2006-01-02"),
+		string(encoded), active, time.Now(), time.Now())
+	require.NoError(t, err)
+	id, err := result.LastInsertId()
+	require.NoError(t, err)
+	return int(id)
+}
+
+func findShoppingItem(list *models.ShoppingList, name string) *models.ShoppingListItem {
+	for i := range list.Items {
+		if list.Items[i].Name == name {
+			return &list.Items[i]
+		}
+	}
+	return nil
+}
+
+func TestShoppingList_MergesPlanIngredientsByCategory(t *testing.T) {
+	db := setupShoppingLists(t)
+	ctx := context.Background()
+	service := services.NewShoppingListService(db)
+
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	insertShoppingMealPlan(t, db, 7, start, true)
+	insertShoppingMealPlan(t, db, 7, start, false)
+
+	// Days two and three of the active plan; the inactive plan is ignored
+	list, err := service.CreateShoppingList(ctx, 7, models.CreateShoppingListRequest{
+		StartDate: "2026-03-03",
+		EndDate:   "2026-03-04",
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, list.ID)
+	assert.Equal(t, "Shopping list 2026-03-03 – 2026-03-04", list.Name)
+
+	// One kabsa serving is a quarter of the recipe, one fattoush serving half
+	chicken := findShoppingItem(list, "chicken thighs")
+	require.NotNil(t, chicken)
+	assert.Equal(t, services.CategoryMeatSeafood, chicken.Category)
+	assert.Equal(t, "g", chicken.Unit)
+	assert.InDelta(t, 500, *chicken.Quantity, 0.001)
+
+	// "2 onions" and "1 onion, sliced" merge across recipes
+	onion := findShoppingItem(list, "onions")
+	require.NotNil(t, onion)
+	assert.Equal(t, services.CategoryProduce, onion.Category)
+	assert.Equal(t, "piece", onion.Unit)
+	assert.InDelta(t, 2, *onion.Quantity, 0.001)
+	assert.ElementsMatch(t, []string{"Chicken kabsa", "Fattoush"}, onion.Sources)
+
+	// Volumes are summed in milliliters
+	oil := findShoppingItem(list, "olive oil")
+	require.NotNil(t, oil)
+	assert.Equal(t, services.CategoryPantry, oil.Category)
+	assert.Equal(t, "ml", oil.Unit)
+	assert.InDelta(t, 30, *oil.Quantity, 0.001)
+
+	// "salt to taste" is covered by the measured salt
+	salt := findShoppingItem(list, "salt")
+	require.NotNil(t, salt)
+	assert.Equal(t, services.CategorySpices, salt.Category)
+	assert.ElementsMatch(t, []string{"Chicken kabsa", "Fattoush"}, salt.Sources)
+
+	pepper := findShoppingItem(list, "black pepper")
+	require.NotNil(t, pepper)
+	assert.Equal(t, services.CategorySpices, pepper.Category)
+
+	seeds := findShoppingItem(list, "pomegranate seeds")
+	require.NotNil(t, seeds)
+	assert.True(t, seeds.Optional)
+
+	// Side foods keep their planned grams
+	yogurt := findShoppingItem(list, "Greek yogurt")
+	require.NotNil(t, yogurt)
+	assert.Equal(t, services.CategoryDairyEggs, yogurt.Category)
+	assert.InDelta(t, 300, *yogurt.Quantity, 0.001)
+
+	// Items are stored in aisle order
+	assert.Equal(t, services.CategoryProduce, list.Items[0].Category)
+
+	stored, err := service.GetShoppingList(ctx, list.ID, 7)
+	require.NoError(t, err)
+	assert.Len(t, stored.Items, len(list.Items))
+
+	_, err = service.GetShoppingList(ctx, list.ID, 8)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, repositories.ErrShoppingListNotFound)
+}
+
+func TestShoppingList_RecipesTickOffAndExport(t *testing.T) {
+	db := setupShoppingLists(t)
+	ctx := context.Background()
+	service := services.NewShoppingListService(db)
+
+	planID := insertShoppingMealPlan(t, db, 7, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), true)
+
+	// A whole plan plus an extra batch of kabsa for eight
+	list, err := service.CreateShoppingList(ctx, 7, models.CreateShoppingListRequest{
+		Name:       "Week 10",
+		MealPlanID: &planID,
+		Recipes:    []models.PlannedRecipe{{RecipeID: "r-kabsa", Servings: 8}},
+	})
+	require.NoError(t, err)
+
+	chicken := findShoppingItem(list, "chicken thighs")
+	require.NotNil(t, chicken)
+	assert.Equal(t, "kg", chicken.Unit)
+	assert.InDelta(t, 2.75, *chicken.Quantity, 0.001)
+
+	require.NoError(t, service.SetItemChecked(ctx, list.ID, chicken.ID, 7, true))
+	err = service.SetItemChecked(ctx, list.ID, chicken.ID, 8, true)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, repositories.ErrShoppingListItemNotFound)
+
+	text, contentType, err := service.ExportShoppingList(ctx, list.ID, 7, "text")
+	require.NoError(t, err)
+	assert.Contains(t, contentType, "text/plain")
+	assert.True(t, strings.HasPrefix(string(text), "Week 10\n"))
+	assert.Contains(t, string(text), "Meat & seafood\n[x] 2.75 kg chicken thighs\n")
+	assert.Contains(t, string(text), "[ ] 75 g pomegranate seeds (optional)")
+
+	csvContent, contentType, err := service.ExportShoppingList(ctx, list.ID, 7, "csv")
+	require.NoError(t, err)
+	assert.Contains(t, contentType, "text/csv")
+	lines := strings.Split(strings.TrimSpace(string(csvContent)), "\n")
+	assert.Equal(t, "category,item,quantity,unit,checked,optional,used_in", lines[0])
+	assert.Contains(t, string(csvContent), "Meat & seafood,chicken thighs,2.75,kg,true,false,Chicken kabsa")
+	assert.Len(t, lines, len(list.Items)+1)
+
+	_, _, err = service.ExportShoppingList(ctx, list.ID, 7, "pdf")
+	assert.ErrorIs(t, err, services.ErrInvalidShoppingListRequest)
+
+	require.NoError(t, service.DeleteShoppingList(ctx, list.ID, 7))
+	_, err = service.GetShoppingList(ctx, list.ID, 7)
+	require.Error(t, err)
+}
+
+func TestShoppingList_InvalidRequests(t *testing.T) {
+	db := setupShoppingLists(t)
+	ctx := context.Background()
+	service := services.NewShoppingListService(db)
+
+	_, err := service.CreateShoppingList(ctx, 7, models.CreateShoppingListRequest{})
+	assert.ErrorIs(t, err, services.ErrInvalidShoppingListRequest)
+
+	_, err = service.CreateShoppingList(ctx, 7, models.CreateShoppingListRequest{StartDate: "2026-03-04", EndDate: "2026-03-01"})
+	assert.ErrorIs(t, err, services.ErrInvalidShoppingListRequest)
+
+	_, err = service.CreateShoppingList(ctx, 7, models.CreateShoppingListRequest{StartDate: "2026-01-01", EndDate: "2026-03-01"})
+	assert.ErrorIs(t, err, services.ErrInvalidShoppingListRequest)
+
+	_, err = service.CreateShoppingList(ctx, 7, models.CreateShoppingListRequest{Recipes: []models.PlannedRecipe{{RecipeID: "missing"}}})
+	assert.ErrorIs(t, err, services.ErrInvalidShoppingListRequest)
+
+	// No plan covers the range
+	_, err = service.CreateShoppingList(ctx, 7, models.CreateShoppingListRequest{StartDate: "2026-03-01", EndDate: "2026-03-07"})
+	assert.ErrorIs(t, err, services.ErrEmptyShoppingList)
+
+	assert.Equal(t, services.CategoryPantry, services.CategorizeIngredient("peanut butter"))
+	assert.Equal(t, services.CategoryDairyEggs, services.CategorizeIngredient("unsalted butter"))
+	assert.Equal(t, services.CategoryPantry, services.CategorizeIngredient("chicken stock"))
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"testing"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertToGrams_StandardUnits(t *testing.T) {
+	food := &models.Food{Name: "Milk"}
+
+	tests := []struct {
+		quantity float64
+		unit     string
+		expected float64
+	}{
+		{150, "g", 150},
+		{1.5, "kg", 1500},
+		{2, "oz", 56.699},
+		{1, "lbs", 453.592},
+		{250, "ml", 250},
+		{1, "Cup", 236.588},
+		{2, "tablespoons", 29.574},
+		{1, "tsp", 4.929},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			grams, err := services.ConvertToGrams(tt.quantity, tt.unit, food, nil)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expected, grams, 0.001)
+		})
+	}
+}
+
+func TestConvertToGrams_FoodPortions(t *testing.T) {
+	food := &models.Food{Name: "Cooked rice", ServingSize: "1 cup"}
+	portions := []models.FoodPortion{
+		{Unit: "cup", Grams: 158},
+		{Unit: "ml", Grams: 0.67},
+		{Unit: "piece", Grams: 50},
+	}
+
+	grams, err := services.ConvertToGrams(2, "cups", food, portions)
+	require.NoError(t, err)
+	assert.InDelta(t, 316, grams, 0.001)
+
+	// Density applies to volumes without their own portion
+	grams, err = services.ConvertToGrams(1, "tbsp", food, portions)
+	require.NoError(t, err)
+	assert.InDelta(t, 14.787*0.67, grams, 0.001)
+
+	grams, err = services.ConvertToGrams(3, "pcs", food, portions)
+	require.NoError(t, err)
+	assert.InDelta(t, 150, grams, 0.001)
+
+	// Serving size is resolved through the cup portion
+	grams, err = services.ConvertToGrams(1, "serving", food, portions)
+	require.NoError(t, err)
+	assert.InDelta(t, 158, grams, 0.001)
+}
+
+func TestConvertToGrams_ServingSizeText(t *testing.T) {
+	food := &models.Food{Name: "Oats", ServingSize: "40 g (1/2 cup)"}
+	grams, err := services.ConvertToGrams(1.5, "serving", food, nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 60, grams, 0.001)
+
+	food = &models.Food{Name: "Yogurt", ServingSize: "170", ServingUnit: "g"}
+	grams, err = services.ConvertToGrams(1, "serving", food, nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 170, grams, 0.001)
+}
+
+func TestConvertToGrams_Errors(t *testing.T) {
+	food := &models.Food{Name: "Apple"}
+
+	_, err := services.ConvertToGrams(1, "handful", food, nil)
+	assert.Error(t, err)
+
+	_, err = services.ConvertToGrams(1, "piece", food, nil)
+	assert.EqualError(t, err, "no gram weight known for one piece of Apple")
+
+	_, err = services.ConvertToGrams(1, "serving", food, nil)
+	assert.Error(t, err)
+}
+
+func TestFood_CalculateNutritionFromGrams(t *testing.T) {
+	food := &models.Food{Name: "Chicken breast", Calories: 165, Protein: 31, Fat: 3.6}
+
+	grams, err := services.ConvertToGrams(6, "oz", food, nil)
+	require.NoError(t, err)
+
+	nutrition := food.CalculateNutrition(grams)
+	assert.InDelta(t, 280.66, nutrition.Calories, 0.01)
+	assert.InDelta(t, 52.73, nutrition.Protein, 0.01)
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserPreferences_PatchMergesOverDefaults(t *testing.T) {
+	db := openMigratedDB(t, "014_create_user_preferences_table.sql")
+	ctx := context.Background()
+	preferences := services.NewUserPreferencesService(db)
+
+	stored, err := preferences.GetPreferences(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, services.DefaultPreferences(), stored)
+
+	merged, err := preferences.UpdatePreferences(ctx, 1, map[string]interface{}{
+		"language": "ar",
+		"units":    services.UnitsImperial,
+		"timezone": "Asia/Riyadh",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ar", merged["language"])
+	assert.Equal(t, services.UnitsImperial, merged["units"])
+	assert.Equal(t, true, merged["notifications_enabled"])
+
+	// Keys left out of a patch keep their stored values; null resets a key
+	merged, err = preferences.UpdatePreferences(ctx, 1, map[string]interface{}{
+		"dark_mode": true,
+		"units":     nil,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ar", merged["language"])
+	assert.Equal(t, true, merged["dark_mode"])
+	assert.Equal(t, services.UnitsMetric, merged["units"])
+	assert.Equal(t, services.UnitsMetric, preferences.GetUnits(ctx, 1))
+	assert.Equal(t, "Asia/Riyadh", preferences.GetTimezone(ctx, 1).String())
+
+	// Other users keep the defaults
+	other, err := preferences.GetPreferences(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "en", other["language"])
+
+	require.NoError(t, preferences.DeletePreferences(ctx, 1))
+	stored, err = preferences.GetPreferences(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, services.DefaultPreferences(), stored)
+}
+
+func TestUserPreferences_Validation(t *testing.T) {
+	db := openMigratedDB(t, "014_create_user_preferences_table.sql")
+	ctx := context.Background()
+	preferences := services.NewUserPreferencesService(db)
+
+	for name, patch := range map[string]map[string]interface{}{
+		"unknown key":            {"favourite_colour": "blue"},
+		"option not allowed":     {"language": "fr"},
+		"string for boolean":     {"dark_mode": "true"},
+		"number for string":      {"theme": 1},
+		"server local time zone": {"timezone": "Local"},
+		"unknown time zone":      {"timezone": "Mars/Olympus_Mons"},
+		"empty time zone":        {"timezone": ""},
+		"clock out of range":     {"quiet_hours_start": "25:00"},
+		"clock without minutes":  {"quiet_hours_end": "7"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, preferences.ValidatePreferences(patch))
+		})
+	}
+
+	assert.NoError(t, preferences.ValidatePreferences(map[string]interface{}{
+		"timezone":          "America/New_York",
+		"quiet_hours_start": "23:30",
+		"theme":             nil,
+	}))
+
+	// A patch with one invalid key stores nothing
+	_, err := preferences.UpdatePreferences(ctx, 1, map[string]interface{}{
+		"language": "ar",
+		"timezone": "Local",
+	})
+	require.Error(t, err)
+	stored, err := preferences.GetPreferences(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "en", stored["language"])
+
+	// Values stored before a rule tightened fall back to the default
+	_, err = db.Exec(`INSERT INTO user_preferences (user_id, pref_key, value) VALUES (1, 'timezone', '"Local"')`)
+	require.NoError(t, err)
+	stored, err = preferences.GetPreferences(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", stored["timezone"])
+	assert.Equal(t, time.UTC, preferences.GetTimezone(ctx, 1))
+}
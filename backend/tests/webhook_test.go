@@ -0,0 +1,393 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nutrition-platform/config"
+	"nutrition-platform/middleware"
+	"nutrition-platform/models"
+	"nutrition-platform/security"
+	"nutrition-platform/services"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver records requests and answers with the status codes in
+// statuses, then 200
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	received []receivedWebhook
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, receivedWebhook{header: req.Header.Clone(), body: body})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) requests() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+func openWebhookDB(t *testing.T) *sql.DB {
+	db := openAPIKeyDB(t)
+	applyMigrations(t, db, "026_create_webhooks.sql")
+	return db
+}
+
+func newWebhookService(db *sql.DB, maxAttempts int) *services.WebhookService {
+	return services.NewWebhookService(db, config.WebhookConfig{
+		EncryptionKey:        "test-webhook-key",
+		MaxAttempts:          maxAttempts,
+		Timeout:              5 * time.Second,
+		AllowPrivateNetworks: true,
+	})
+}
+
+// makeDeliveriesDue moves every pending delivery's next attempt into the past
+func makeDeliveriesDue(t *testing.T, db *sql.DB) {
+	_, err := db.Exec("UPDATE webhook_deliveries SET next_attempt_at = ? WHERE status = ?",
+		time.Now().UTC().Add(-time.Second), models.WebhookDeliveryPending)
+	require.NoError(t, err)
+}
+
+func TestWebhooks_SignedDeliveryRetriesAndDeadLetter(t *testing.T) {
+	db := openWebhookDB(t)
+	service := newWebhookService(db, 2)
+	ctx := context.Background()
+
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub, err := service.CreateSubscription(ctx, "user-1", "", models.CreateWebhookRequest{
+		URL:    server.URL + "/hooks/nutrition",
+		Events: []string{models.WebhookEventMealLogged},
+	})
+	require.NoError(t, err)
+	require.Len(t, sub.Secret, 2*services.WebhookSecretLength)
+
+	var stored string
+	require.NoError(t, db.QueryRow("SELECT secret_ciphertext FROM webhook_subscriptions WHERE id = ?", sub.ID).Scan(&stored))
+	assert.NotContains(t, stored, sub.Secret)
+
+	require.NoError(t, service.Publish(ctx, "user-1", models.WebhookEventMealLogged, map[string]interface{}{"calories": 420}))
+	// Other users' events and event types the subscription did not ask for
+	// are not delivered
+	require.NoError(t, service.Publish(ctx, "user-2", models.WebhookEventMealLogged, nil))
+	require.NoError(t, service.Publish(ctx, "user-1", models.WebhookEventWeightLogged, nil))
+
+	attempted, err := service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	requests := receiver.requests()
+	require.Len(t, requests, 1)
+	got := requests[0]
+	assert.Equal(t, models.WebhookEventMealLogged, got.header.Get(services.WebhookEventHeader))
+	expected := security.SignRequest(sub.Secret, http.MethodPost, "/hooks/nutrition",
+		got.header.Get(services.WebhookTimestampHeader), string(got.body),
+		map[string]string{"content-type": "application/json"})
+	assert.Equal(t, expected, got.header.Get(services.WebhookSignatureHeader))
+
+	var event models.WebhookEvent
+	require.NoError(t, json.Unmarshal(got.body, &event))
+	assert.Equal(t, models.WebhookEventMealLogged, event.Type)
+	assert.Equal(t, "user-1", event.UserID)
+	assert.Equal(t, map[string]interface{}{"calories": float64(420)}, event.Data)
+
+	// The failed attempt is retried after the backoff, not straight away
+	deliveries, err := service.ListDeliveries(ctx, "user-1", "", sub.ID, "", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].LastStatusCode)
+	require.NotNil(t, deliveries[0].NextAttemptAt)
+	assert.WithinDuration(t, time.Now().Add(services.WebhookRetryBaseDelay), *deliveries[0].NextAttemptAt, 5*time.Second)
+
+	attempted, err = service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+
+	// The second failure is the last attempt
+	makeDeliveriesDue(t, db)
+	_, err = service.DeliverDue(ctx)
+	require.NoError(t, err)
+
+	dead, err := service.ListDeliveries(ctx, "user-1", "", sub.ID, models.WebhookDeliveryDead, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, "502")
+
+	_, err = service.ReplayDelivery(ctx, "user-2", "", sub.ID, dead[0].ID)
+	require.Error(t, err)
+	assert.Equal(t, "webhook subscription not found", err.Error())
+
+	replayed, err := service.ReplayDeadLetters(ctx, "user-1", "", sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), replayed)
+	_, err = service.ReplayDelivery(ctx, "user-1", "", sub.ID, dead[0].ID)
+	assert.ErrorIs(t, err, services.ErrWebhookDeliveryPending)
+
+	_, err = service.DeliverDue(ctx)
+	require.NoError(t, err)
+	delivered, err := service.ListDeliveries(ctx, "user-1", "", sub.ID, models.WebhookDeliverySucceeded, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, delivered, 1)
+	assert.NotNil(t, delivered[0].DeliveredAt)
+	assert.Len(t, receiver.requests(), 3)
+
+	// Delivered events can be sent again
+	_, err = service.ReplayDelivery(ctx, "user-1", "", sub.ID, delivered[0].ID)
+	require.NoError(t, err)
+	_, err = service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Len(t, receiver.requests(), 4)
+}
+
+func TestWebhooks_APIKeySubscriptions(t *testing.T) {
+	db := openWebhookDB(t)
+	service := newWebhookService(db, 3)
+	apiKeys := services.NewAPIKeyService(db)
+	ctx := context.Background()
+
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	key, err := apiKeys.CreateAPIKey(ctx, "user-1", &models.CreateAPIKeyRequest{
+		Name:   "Partner",
+		Scopes: []models.APIKeyScope{models.ScopeReadOnly},
+	})
+	require.NoError(t, err)
+
+	keySub, err := service.CreateSubscription(ctx, "user-1", key.APIKey.ID, models.CreateWebhookRequest{
+		URL:    server.URL + "/partner",
+		Events: []string{models.WebhookEventWorkoutCompleted},
+	})
+	require.NoError(t, err)
+	userSub, err := service.CreateSubscription(ctx, "user-1", "", models.CreateWebhookRequest{
+		URL:    server.URL + "/own",
+		Events: []string{models.WebhookEventWorkoutCompleted, models.WebhookEventWorkoutCompleted},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.WebhookEventWorkoutCompleted}, userSub.Events)
+
+	// A key only sees its own subscriptions; the user sees all of them
+	subs, err := service.ListSubscriptions(ctx, "user-1", key.APIKey.ID)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, keySub.ID, subs[0].ID)
+	_, err = service.GetSubscription(ctx, "user-1", key.APIKey.ID, userSub.ID)
+	require.Error(t, err)
+	subs, err = service.ListSubscriptions(ctx, "user-1", "")
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+
+	require.NoError(t, service.Publish(ctx, "user-1", models.WebhookEventWorkoutCompleted, nil))
+	attempted, err := service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempted)
+
+	// Revoking the key stops its subscriptions
+	require.NoError(t, apiKeys.RevokeAPIKey(ctx, "user-1", key.APIKey.ID))
+	require.NoError(t, service.Publish(ctx, "user-1", models.WebhookEventWorkoutCompleted, nil))
+	attempted, err = service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	// So does deactivating a subscription
+	inactive := false
+	_, err = service.UpdateSubscription(ctx, "user-1", "", userSub.ID, models.UpdateWebhookRequest{Active: &inactive})
+	require.NoError(t, err)
+	require.NoError(t, service.Publish(ctx, "user-1", models.WebhookEventWorkoutCompleted, nil))
+	attempted, err = service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+}
+
+func TestWebhooks_Validation(t *testing.T) {
+	service := newWebhookService(openWebhookDB(t), 3)
+	ctx := context.Background()
+
+	for _, req := range []models.CreateWebhookRequest{
+		{URL: "ftp://example.com/hook", Events: []string{models.WebhookEventMealLogged}},
+		{URL: "/relative", Events: []string{models.WebhookEventMealLogged}},
+		{URL: "https://example.com/hook"},
+		{URL: "https://example.com/hook", Events: []string{"meal.deleted"}},
+	} {
+		_, err := service.CreateSubscription(ctx, "user-1", "", req)
+		assert.ErrorIs(t, err, services.ErrInvalidWebhookRequest, req)
+	}
+
+	assert.ErrorIs(t, service.Publish(ctx, "user-1", "meal.deleted", nil), services.ErrInvalidWebhookRequest)
+}
+
+func TestWebhooks_RefusesPrivateTargets(t *testing.T) {
+	db := openWebhookDB(t)
+	service := services.NewWebhookService(db, config.WebhookConfig{
+		EncryptionKey: "test-webhook-key",
+		MaxAttempts:   3,
+		Timeout:       5 * time.Second,
+	})
+	ctx := context.Background()
+
+	for _, target := range []string{
+		"http://example.com/hook",
+		"https://localhost/hook",
+		"https://127.0.0.1/hook",
+		"https://[::1]/hook",
+		"https://10.0.0.5/hook",
+		"https://192.168.1.10/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://0.0.0.0/hook",
+	} {
+		_, err := service.CreateSubscription(ctx, "user-1", "", models.CreateWebhookRequest{
+			URL:    target,
+			Events: []string{models.WebhookEventMealLogged},
+		})
+		assert.ErrorIs(t, err, services.ErrInvalidWebhookRequest, target)
+	}
+
+	sub, err := service.CreateSubscription(ctx, "user-1", "", models.CreateWebhookRequest{
+		URL:    "https://hooks.example.com/nutrition",
+		Events: []string{models.WebhookEventMealLogged},
+	})
+	require.NoError(t, err)
+
+	// A hostname that resolves to an internal address passes validation, so
+	// the address is checked again when connecting
+	receiver := &webhookReceiver{}
+	server := httptest.NewTLSServer(receiver)
+	defer server.Close()
+	_, err = db.Exec("UPDATE webhook_subscriptions SET url = ? WHERE id = ?", server.URL+"/hook", sub.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, service.Publish(ctx, "user-1", models.WebhookEventMealLogged, nil))
+	attempted, err := service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	assert.Empty(t, receiver.requests())
+
+	deliveries, err := service.ListDeliveries(ctx, "user-1", "", sub.ID, "", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].LastError, services.ErrWebhookAddressBlocked.Error())
+}
+
+func TestWebhooks_DoesNotFollowRedirects(t *testing.T) {
+	db := openWebhookDB(t)
+	service := newWebhookService(db, 3)
+	ctx := context.Background()
+
+	receiver := &webhookReceiver{}
+	internal := httptest.NewServer(receiver)
+	defer internal.Close()
+	redirector := httptest.NewServer(http.RedirectHandler(internal.URL+"/admin", http.StatusTemporaryRedirect))
+	defer redirector.Close()
+
+	sub, err := service.CreateSubscription(ctx, "user-1", "", models.CreateWebhookRequest{
+		URL:    redirector.URL + "/hook",
+		Events: []string{models.WebhookEventMealLogged},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, service.Publish(ctx, "user-1", models.WebhookEventMealLogged, nil))
+	_, err = service.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, receiver.requests())
+
+	deliveries, err := service.ListDeliveries(ctx, "user-1", "", sub.ID, "", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Equal(t, http.StatusTemporaryRedirect, deliveries[0].LastStatusCode)
+}
+
+func TestWebhooks_PartnerKeysNeedWebhooksScope(t *testing.T) {
+	// Stands in for APIKeyAuth: the key's scopes come from a header
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := &models.APIKey{}
+			for _, scope := range strings.Split(c.Request().Header.Get("X-Scopes"), ",") {
+				key.Scopes = append(key.Scopes, models.APIKeyScope(scope))
+			}
+			c.Set(middleware.APIKeyContextKey, key)
+			return next(c)
+		}
+	})
+	ok := func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": "success"})
+	}
+	webhooks := e.Group("/partner/webhooks", middleware.RequireAPIKeyScopes(models.ScopeWebhooks))
+	webhooks.GET("", ok)
+	webhooks.POST("", ok)
+	webhooks.DELETE("/:id", ok)
+	webhooks.POST("/:id/replay", ok)
+
+	send := func(method, target, scopes string) int {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-Scopes", scopes)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Keys for other APIs cannot touch webhooks at all
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/partner/webhooks", "nutrition,read_write"))
+
+	readOnly := "webhooks,read_only"
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/partner/webhooks", readOnly))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/partner/webhooks", readOnly))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodDelete, "/partner/webhooks/1", readOnly))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/partner/webhooks/1/replay", readOnly))
+
+	readWrite := "webhooks,read_write"
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/partner/webhooks", readWrite))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/partner/webhooks/1/replay", readWrite))
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	assert.Equal(t, services.WebhookRetryBaseDelay, services.WebhookRetryDelay(1))
+	assert.Equal(t, 2*services.WebhookRetryBaseDelay, services.WebhookRetryDelay(2))
+	assert.Equal(t, 8*services.WebhookRetryBaseDelay, services.WebhookRetryDelay(4))
+	assert.Equal(t, services.WebhookRetryMaxDelay, services.WebhookRetryDelay(30))
+}
+
+func TestSignRequest_IgnoresHeaderOrder(t *testing.T) {
+	headers := map[string]string{"content-type": "application/json", "x-api-key": "key", "x-request-id": "1"}
+	first := security.SignRequest("secret", "POST", "/hook", "1700000000", "{}", headers)
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, first, security.SignRequest("secret", "POST", "/hook", "1700000000", "{}", headers))
+	}
+	assert.NotEqual(t, first, security.SignRequest("other", "POST", "/hook", "1700000000", "{}", headers))
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openWeightForecastDB(t *testing.T) *sql.DB {
+	return openMigratedDB(t, "030_create_body_measurements.sql")
+}
+
+// logWeighIns logs a weigh-in every other morning for the given number of
+// days ending today, weight giving the true weight that many days in
+func logWeighIns(t *testing.T, db *sql.DB, days int, weight func(day int) float64) {
+	now := time.Now().UTC()
+	first := time.Date(now.Year(), now.Month(), now.Day()-days+1, 7, 0, 0, 0, time.UTC)
+	noise := []float64{-0.3, 0.2, 0.1, -0.2, 0.3, -0.1, 0}
+	for i := 0; i < days; i += 2 {
+		_, err := db.Exec(`INSERT INTO body_measurements (user_id, measurement_date, weight) VALUES (1, $1, $2)`,
+			first.AddDate(0, 0, i), weight(i)+noise[i/2%len(noise)])
+		require.NoError(t, err)
+	}
+	// A weigh-in with no weight is skipped
+	_, err := db.Exec(`INSERT INTO body_measurements (user_id, measurement_date, body_fat_percentage) VALUES (1, $1, 20)`, first)
+	require.NoError(t, err)
+}
+
+func TestWeightForecast_GoalDateWithInterval(t *testing.T) {
+	db := openWeightForecastDB(t)
+	// Eight weeks losing half a kilo a week, with one heavy outlier
+	logWeighIns(t, db, 56, func(day int) float64 {
+		if day == 30 {
+			return 96
+		}
+		return 92 - 0.5*float64(day)/7
+	})
+	forecaster := services.NewWeightForecastService(db)
+
+	target := 86.0
+	forecast, err := forecaster.Forecast(context.Background(), 1, 0, &target)
+	require.NoError(t, err)
+	assert.Equal(t, services.DefaultForecastWindowDays, forecast.Days)
+	assert.Equal(t, 28, forecast.Measurements)
+	// The outlier barely moves the robust fit
+	assert.InDelta(t, -0.5, forecast.WeeklyChange, 0.05)
+	assert.Less(t, forecast.WeeklyChangeLow, forecast.WeeklyChange)
+	assert.Greater(t, forecast.WeeklyChangeHigh, forecast.WeeklyChange)
+	assert.InDelta(t, 88.1, forecast.TrendWeight, 0.2)
+	assert.Nil(t, forecast.Plateau)
+
+	goal := forecast.Goal
+	require.NotNil(t, goal)
+	require.NotNil(t, goal.TargetDate)
+	require.NotNil(t, goal.TargetDateEarliest)
+	require.NotNil(t, goal.TargetDateLatest)
+	assert.True(t, goal.OnPace)
+	weeks := goal.TargetDate.Sub(forecast.LastDate).Hours() / 24 / 7
+	assert.InDelta(t, 4.2, weeks, 0.5)
+	assert.True(t, goal.TargetDateEarliest.Before(*goal.TargetDate))
+	assert.True(t, goal.TargetDateLatest.After(*goal.TargetDate))
+	assert.Greater(t, goal.Confidence, 0.8)
+	assert.True(t, strings.HasPrefix(forecast.Summary, "On pace for 86.0 by "), forecast.Summary)
+
+	// The projection runs weekly to the goal date
+	require.NotEmpty(t, goal.PredictedWeight)
+	end := goal.PredictedWeight[len(goal.PredictedWeight)-1]
+	assert.Equal(t, *goal.TargetDate, end.Date)
+	assert.InDelta(t, 86, end.Weight, 0.1)
+	assert.Equal(t, goal.TargetDate, forecast.Predictions.TargetDate)
+	assert.Equal(t, goal.PredictedWeight, forecast.Predictions.WeightPrediction)
+}
+
+func TestWeightForecast_DetectsPlateau(t *testing.T) {
+	db := openWeightForecastDB(t)
+	// Six weeks of loss, then three weeks at the same weight
+	logWeighIns(t, db, 63, func(day int) float64 {
+		if day > 42 {
+			day = 42
+		}
+		return 90 - 0.5*float64(day)/7
+	})
+	forecaster := services.NewWeightForecastService(db)
+
+	target := 80.0
+	forecast, err := forecaster.Forecast(context.Background(), 1, 0, &target)
+	require.NoError(t, err)
+	require.NotNil(t, forecast.Plateau)
+	assert.GreaterOrEqual(t, forecast.Plateau.Days, 12)
+	assert.Less(t, forecast.Plateau.WeeklyChange, 0.2)
+	assert.Greater(t, forecast.Plateau.WeeklyChange, -0.2)
+	assert.False(t, forecast.Goal.OnPace)
+	assert.True(t, strings.HasPrefix(forecast.Summary, "Holding at "), forecast.Summary)
+	assert.NotEmpty(t, forecast.Predictions.Recommendations)
+	assert.Contains(t, forecast.Predictions.RiskFactors, "The goal date assumes the earlier pace resumes after the plateau")
+}
+
+func TestWeightForecast_TargetsAndErrors(t *testing.T) {
+	db := openWeightForecastDB(t)
+	forecaster := services.NewWeightForecastService(db)
+	ctx := context.Background()
+
+	_, err := forecaster.Forecast(ctx, 1, 0, nil)
+	assert.ErrorIs(t, err, services.ErrInsufficientForecastData)
+	_, err = forecaster.Forecast(ctx, 1, 7, nil)
+	assert.ErrorIs(t, err, services.ErrInvalidForecastRequest)
+	negative := -1.0
+	_, err = forecaster.Forecast(ctx, 1, 0, &negative)
+	assert.ErrorIs(t, err, services.ErrInvalidForecastRequest)
+
+	// Four weeks gaining a kilo a week
+	logWeighIns(t, db, 28, func(day int) float64 {
+		return 70 + float64(day)/7
+	})
+
+	forecast, err := forecaster.Forecast(ctx, 1, 0, nil)
+	require.NoError(t, err)
+	assert.Nil(t, forecast.Goal)
+	assert.Nil(t, forecast.Predictions.TargetDate)
+	assert.Len(t, forecast.Predictions.WeightPrediction, 13)
+	assert.True(t, strings.HasPrefix(forecast.Summary, "Trending up "), forecast.Summary)
+
+	// A lower target is never reached on this trend
+	lower := 65.0
+	forecast, err = forecaster.Forecast(ctx, 1, 0, &lower)
+	require.NoError(t, err)
+	assert.Nil(t, forecast.Goal.TargetDate)
+	assert.False(t, forecast.Goal.OnPace)
+	assert.Less(t, forecast.Goal.Confidence, 0.2)
+	assert.Contains(t, forecast.Predictions.RiskFactors, "The trend is moving away from the target weight")
+	assert.Equal(t, "Not on pace to reach 65.0 at the current trend", forecast.Summary)
+
+	// A target at the trend weight is already reached
+	reached := forecast.TrendWeight
+	forecast, err = forecaster.Forecast(ctx, 1, 0, &reached)
+	require.NoError(t, err)
+	assert.True(t, forecast.Goal.OnPace)
+	assert.Equal(t, forecast.LastDate, *forecast.Goal.TargetDate)
+	assert.Equal(t, 1.0, forecast.Goal.Confidence)
+}
@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"nutrition-platform/models"
+	"nutrition-platform/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openWorkoutProgramDB(t *testing.T) *sql.DB {
+	db := openMigratedDB(t, "001_initial_schema_sqlite.sql", "028_create_program_assignments.sql")
+
+	// A two-day strength program: squats in both sessions, push-ups with
+	// easier variants, and Romanian deadlifts
+	_, err := db.Exec(`INSERT INTO workout_programs (id, name, duration_weeks, days_per_week)
+		VALUES ('prog-1', 'Strength Basics', 8, 2), ('prog-empty', 'Empty', 4, 3)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO workout_sessions (id, workout_program_id, session_number, name, main_exercises, modifications) VALUES
+		('sess-a', 'prog-1', 1, 'Day A',
+		 '[{"exercise_id":"squat","exercise_name":"Back Squat","sets":3,"reps":"5","weight":"100kg","rest_seconds":180,"target_muscles":["Quadriceps","Glutes"]},
+		   {"exercise_id":"pushup","exercise_name":"Push-up","sets":3,"reps":"8-12","rest_seconds":90,"target_muscles":["Chest","Triceps"]}]',
+		 '[{"exercise_id":"pushup","modification_type":"harder","description":"Decline push-up"},
+		   {"exercise_id":"pushup","modification_type":"easier","description":"Incline push-up"},
+		   {"exercise_id":"pushup","modification_type":"injury_specific","description":"Knee push-up"}]'),
+		('sess-b', 'prog-1', 2, 'Day B',
+		 '[{"exercise_id":"squat","exercise_name":"Back Squat","sets":5,"reps":"3","weight":"110kg","rest_seconds":180,"target_muscles":["Quadriceps"]},
+		   {"exercise_id":"rdl","exercise_name":"Romanian Deadlift","sets":3,"reps":"8-10","weight":"60 kg","rest_seconds":120,"target_muscles":["Hamstrings","Lower back"]}]',
+		 '[]')`)
+	require.NoError(t, err)
+	return db
+}
+
+func assignStrengthProgram(t *testing.T, programs *services.WorkoutProgramService, userID string) *models.ProgramAssignmentDetail {
+	// 2026-05-04 is a Monday
+	start := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	detail, err := programs.AssignProgram(context.Background(), userID, models.AssignProgramRequest{
+		ProgramID: "prog-1",
+		StartDate: &start,
+	})
+	require.NoError(t, err)
+	return detail
+}
+
+func programTarget(t *testing.T, detail *models.ProgramAssignmentDetail, exerciseID string) *models.ProgramExerciseTarget {
+	for _, target := range detail.Targets {
+		if target.ExerciseID == exerciseID {
+			return target
+		}
+	}
+	t.Fatalf("no target for %s", exerciseID)
+	return nil
+}
+
+func loggedProgramSession(id, userID, sessionID string, rpe int, exercises ...models.CompletedExercise) *models.UserWorkoutSession {
+	program := "prog-1"
+	return &models.UserWorkoutSession{
+		ID:                 id,
+		UserID:             userID,
+		WorkoutProgramID:   &program,
+		WorkoutSessionID:   &sessionID,
+		PerceivedExertion:  &rpe,
+		ExercisesCompleted: exercises,
+		Status:             "completed",
+	}
+}
+
+func TestWorkoutProgram_AssignSchedulesSessionsAndDeloads(t *testing.T) {
+	db := openWorkoutProgramDB(t)
+	programs := services.NewWorkoutProgramService(db)
+	ctx := context.Background()
+
+	detail := assignStrengthProgram(t, programs, "21")
+	assert.Equal(t, []int{1, 4}, detail.TrainingDays)
+	assert.Equal(t, 8, detail.Weeks)
+	require.Len(t, detail.Sessions, 16)
+	assert.Equal(t, "Day A", detail.Sessions[0].Name)
+	assert.Equal(t, "2026-05-04", detail.Sessions[0].ScheduledDate.Format("2006-01-02"))
+	assert.Equal(t, "Day B", detail.Sessions[1].Name)
+	assert.Equal(t, "2026-05-07", detail.Sessions[1].ScheduledDate.Format("2006-01-02"))
+
+	// The first prescription of an exercise wins; easier variants become
+	// its regressions
+	require.Len(t, detail.Targets, 3)
+	squat := programTarget(t, detail, "squat")
+	assert.Equal(t, 3, squat.Sets)
+	assert.Equal(t, 5, squat.TargetReps)
+	assert.Equal(t, 100.0, squat.Load)
+	assert.Equal(t, services.DefaultLoadIncrement, squat.LoadIncrement)
+	pushup := programTarget(t, detail, "pushup")
+	assert.Equal(t, []string{"Incline push-up", "Knee push-up"}, pushup.Regressions)
+	assert.Equal(t, 8, pushup.RepMin)
+	assert.Equal(t, 12, pushup.RepMax)
+	assert.Zero(t, pushup.Load)
+
+	// Week 4 is a deload week
+	deload := detail.Sessions[6]
+	assert.Equal(t, 4, deload.Week)
+	assert.True(t, deload.Deload)
+	require.NotEmpty(t, deload.Exercises)
+	assert.Equal(t, 2, deload.Exercises[0].Sets)
+	assert.Equal(t, 90.0, deload.Exercises[0].Load)
+	assert.False(t, detail.Sessions[5].Deload)
+
+	_, err := programs.AssignProgram(ctx, "21", models.AssignProgramRequest{ProgramID: "prog-1"})
+	assert.ErrorIs(t, err, services.ErrProgramAlreadyAssigned)
+
+	for _, req := range []models.AssignProgramRequest{
+		{},
+		{ProgramID: "prog-1", Weeks: 60},
+		{ProgramID: "prog-1", TrainingDays: []int{7}},
+		{ProgramID: "prog-1", LoadIncrement: -1},
+		{ProgramID: "prog-empty"},
+	} {
+		_, err := programs.AssignProgram(ctx, "22", req)
+		assert.ErrorIs(t, err, services.ErrInvalidProgramAssignment, req)
+	}
+	_, err = programs.AssignProgram(ctx, "22", models.AssignProgramRequest{ProgramID: "missing"})
+	require.Error(t, err)
+	assert.Equal(t, "workout program not found", err.Error())
+}
+
+func TestWorkoutProgram_DoubleProgressionRPEAndStalls(t *testing.T) {
+	db := openWorkoutProgramDB(t)
+	programs := services.NewWorkoutProgramService(db)
+	ctx := context.Background()
+	assignment := assignStrengthProgram(t, programs, "23")
+
+	// Top of the squat's range adds load; push-ups climb a rep
+	err := programs.RecordSession(ctx, loggedProgramSession("log-1", "23", "sess-a", 8,
+		models.CompletedExercise{ExerciseID: "squat", SetsCompleted: 3, RepsCompleted: []string{"5", "5", "5"}},
+		models.CompletedExercise{ExerciseID: "pushup", SetsCompleted: 3, RepsCompleted: []string{"8", "8", "8"}},
+	))
+	require.NoError(t, err)
+
+	// Recording the same workout again changes nothing
+	require.NoError(t, programs.RecordSession(ctx, loggedProgramSession("log-1", "23", "sess-a", 8,
+		models.CompletedExercise{ExerciseID: "squat", SetsCompleted: 3, RepsCompleted: []string{"5", "5", "5"}},
+	)))
+
+	detail, err := programs.GetAssignment(ctx, "23", assignment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 102.5, programTarget(t, detail, "squat").Load)
+	assert.Equal(t, 9, programTarget(t, detail, "pushup").TargetReps)
+	assert.Equal(t, models.ProgramSessionCompleted, detail.Sessions[0].Status)
+	assert.Equal(t, "log-1", detail.Sessions[0].UserWorkoutSessionID)
+	assert.Equal(t, models.ProgramSessionScheduled, detail.Sessions[1].Status)
+	assert.Equal(t, 102.5, detail.Sessions[1].Exercises[0].Load)
+
+	// Missing reps on Romanian deadlifts twice running deloads them
+	for _, id := range []string{"log-2", "log-3"} {
+		require.NoError(t, programs.RecordSession(ctx, loggedProgramSession(id, "23", "sess-b", 8,
+			models.CompletedExercise{ExerciseID: "rdl", SetsCompleted: 3, RepsCompleted: []string{"8", "7", "6"}},
+		)))
+	}
+	detail, err = programs.GetAssignment(ctx, "23", assignment.ID)
+	require.NoError(t, err)
+	rdl := programTarget(t, detail, "rdl")
+	assert.Equal(t, 52.5, rdl.Load)
+	assert.Equal(t, 8, rdl.TargetReps)
+	assert.Zero(t, rdl.FailedSessions)
+
+	// An easy set of push-ups (RPE 5) progresses twice as fast; a hard squat
+	// session (RPE 9) holds
+	require.NoError(t, programs.RecordSession(ctx, loggedProgramSession("log-4", "23", "sess-a", 9,
+		models.CompletedExercise{ExerciseID: "squat", SetsCompleted: 3, RepsCompleted: []string{"5", "5", "5"}},
+		models.CompletedExercise{ExerciseID: "pushup", SetsCompleted: 3, RepsCompleted: []string{"9", "9", "9"}, DifficultyRating: 5},
+	)))
+	detail, err = programs.GetAssignment(ctx, "23", assignment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 102.5, programTarget(t, detail, "squat").Load)
+	assert.Equal(t, 11, programTarget(t, detail, "pushup").TargetReps)
+
+	adjustments, err := programs.ListAdjustments(ctx, "23", assignment.ID, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, adjustments, 4)
+	rules := map[string]int{}
+	for _, adjustment := range adjustments {
+		rules[adjustment.Rule]++
+	}
+	assert.Equal(t, map[string]int{
+		models.ProgressionRuleDouble: 2,
+		models.ProgressionRuleDeload: 1,
+		models.ProgressionRuleRPE:    1,
+	}, rules)
+
+	_, err = programs.ListAdjustments(ctx, "24", assignment.ID, 0, 0)
+	require.Error(t, err)
+	assert.Equal(t, "program assignment not found", err.Error())
+}
+
+func TestWorkoutProgram_InjuriesRegressExercises(t *testing.T) {
+	db := openWorkoutProgramDB(t)
+	programs := services.NewWorkoutProgramService(db)
+	ctx := context.Background()
+	assignment := assignStrengthProgram(t, programs, "25")
+
+	session := loggedProgramSession("log-1", "25", "sess-a", 7,
+		models.CompletedExercise{ExerciseID: "squat", SetsCompleted: 3, RepsCompleted: []string{"5", "5", "5"}},
+		models.CompletedExercise{ExerciseID: "pushup", SetsCompleted: 3, RepsCompleted: []string{"8", "8", "8"}},
+	)
+	session.InjuriesReported = []models.ReportedInjury{
+		{BodyPart: "Chest", Severity: 8},
+		{BodyPart: "knee", Severity: 4, ExerciseID: "squat"},
+	}
+	require.NoError(t, programs.RecordSession(ctx, session))
+
+	detail, err := programs.GetAssignment(ctx, "25", assignment.ID)
+	require.NoError(t, err)
+	pushup := programTarget(t, detail, "pushup")
+	assert.Equal(t, "Incline push-up", pushup.Variant)
+	assert.Equal(t, 1, pushup.RegressionLevel)
+	assert.Equal(t, 2, pushup.Sets)
+	squat := programTarget(t, detail, "squat")
+	assert.Equal(t, 80.0, squat.Load)
+	assert.Empty(t, squat.Variant)
+	// Romanian deadlifts train neither the chest nor were named
+	assert.Equal(t, 60.0, programTarget(t, detail, "rdl").Load)
+	assert.Equal(t, "Incline push-up", detail.Sessions[2].Exercises[1].Variant)
+
+	adjustments, err := programs.ListAdjustments(ctx, "25", assignment.ID, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, adjustments, 2)
+	for _, adjustment := range adjustments {
+		assert.Equal(t, models.ProgressionRuleInjuryRegression, adjustment.Rule)
+		assert.Equal(t, "log-1", adjustment.UserWorkoutSessionID)
+	}
+
+	require.NoError(t, programs.CancelAssignment(ctx, "25", assignment.ID))
+	assert.ErrorIs(t, programs.CancelAssignment(ctx, "25", assignment.ID), services.ErrInvalidProgramAssignment)
+
+	// Workouts logged against a cancelled program are ignored
+	require.NoError(t, programs.RecordSession(ctx, loggedProgramSession("log-2", "25", "sess-b", 7)))
+}
+
+func TestWorkoutProgram_CompletesAfterLastSession(t *testing.T) {
+	db := openWorkoutProgramDB(t)
+	programs := services.NewWorkoutProgramService(db)
+	ctx := context.Background()
+
+	start := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	detail, err := programs.AssignProgram(ctx, "26", models.AssignProgramRequest{
+		ProgramID:     "prog-1",
+		StartDate:     &start,
+		Weeks:         1,
+		TrainingDays:  []int{3, 1, 3},
+		StartingLoads: map[string]float64{"squat": 60},
+		LoadIncrement: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, detail.TrainingDays)
+	require.Len(t, detail.Sessions, 2)
+	assert.Equal(t, 60.0, programTarget(t, detail, "squat").Load)
+
+	skipped := loggedProgramSession("log-1", "26", "sess-a", 0)
+	skipped.Status = "skipped"
+	require.NoError(t, programs.RecordSession(ctx, skipped))
+	require.NoError(t, programs.RecordSession(ctx, loggedProgramSession("log-2", "26", "sess-b", 7,
+		models.CompletedExercise{ExerciseID: "squat", SetsCompleted: 3, RepsCompleted: []string{"5", "5", "5"}},
+	)))
+
+	detail, err = programs.GetAssignment(ctx, "26", detail.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProgramAssignmentCompleted, detail.Status)
+	assert.Equal(t, models.ProgramSessionSkipped, detail.Sessions[0].Status)
+	assert.Equal(t, models.ProgramSessionCompleted, detail.Sessions[1].Status)
+	assert.Equal(t, 65.0, programTarget(t, detail, "squat").Load)
+
+	// The program can be assigned again once finished
+	_, err = programs.AssignProgram(ctx, "26", models.AssignProgramRequest{ProgramID: "prog-1"})
+	assert.NoError(t, err)
+}